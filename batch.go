@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// batchEntry 为目录中待发布的一篇文章。
+type batchEntry struct {
+	path  string
+	fm    publisher.FrontMatter
+	title string
+	cover string
+	// markdown 非空时作为正文，不再读取 path（如 import 转换后的正文）。
+	markdown string
+	// err 非空表示读取或校验失败，不参与发布。
+	err error
+}
+
+type batchArticle struct {
+	Path  string `json:"path"`
+	Title string `json:"title"`
+}
+
+type batchDraft struct {
+	MediaID  string         `json:"media_id"`
+	Articles []batchArticle `json:"articles"`
+}
+
+type batchFailure struct {
+	Path    string `json:"path"`
+	Title   string `json:"title,omitempty"`
+	Stage   string `json:"stage"`
+	Error   string `json:"error"`
+	ErrCode int    `json:"errcode,omitempty"`
+}
+
+// batchResult 为 publish batch 写出的结果文件。
+type batchResult struct {
+	Dir        string         `json:"dir"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Drafts     []batchDraft   `json:"drafts"`
+	Failures   []batchFailure `json:"failures"`
+}
+
+// runPublishBatch 发布目录下的全部 Markdown：按 front matter 分组为多图文草稿，并发上传，
+// 最后写出 media_id 与失败项的结果文件。
+func runPublishBatch(args []string) error {
+	fs := newFlagSet("publish batch", "<dir> [flags]",
+		"Publish every .md file under dir. Title, cover, author, group and order are read from\n"+
+			"the YAML front matter (title falls back to the first # heading). Articles with the same\n"+
+			"group share a draft; the rest are packed in path order, up to 8 articles per draft.")
+	defaultCover := fs.String("cover", "", "cover image for articles without a cover in front matter")
+	defaultAuthor := fs.String("author", "", "author for articles without an author in front matter")
+	opts := addBatchFlags(fs, publisher.MaxDraftArticles, "publish-result.json")
+	dirs, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(dirs) != 1 {
+		fs.Usage()
+		return invalidf("exactly one directory is required")
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	dir := dirs[0]
+
+	entries, err := collectBatch(dir, *defaultCover, *defaultAuthor)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return invalidf("no markdown files under %s", dir)
+	}
+	return publishBatch(dir, entries, opts)
+}
+
+// batchOptions 为 publish batch 与 import 共用的分组、并发与发布参数。
+type batchOptions struct {
+	configPath  *string
+	perDraft    *int
+	concurrency *int
+	resultPath  *string
+	dryRun      *bool
+	lock        lockFlags
+}
+
+func addBatchFlags(fs *flag.FlagSet, perDraft int, resultPath string) batchOptions {
+	opts := batchOptions{
+		configPath:  fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)"),
+		perDraft:    fs.Int("per-draft", perDraft, "max articles per draft for ungrouped articles (1-8)"),
+		concurrency: fs.Int("concurrency", 3, "articles uploaded in parallel"),
+		resultPath:  fs.String("result", resultPath, "path of the JSON result file"),
+		dryRun:      fs.Bool("dry-run", false, "print how articles would be grouped without uploading"),
+		lock:        addLockFlags(fs),
+	}
+	fs.BoolVar(&verbose, "v", false, "enable info logs")
+	return opts
+}
+
+func (o batchOptions) validate() error {
+	if *o.perDraft < 1 || *o.perDraft > publisher.MaxDraftArticles {
+		return invalidf("--per-draft must be between 1 and %d", publisher.MaxDraftArticles)
+	}
+	if *o.concurrency < 1 {
+		*o.concurrency = 1
+	}
+	return nil
+}
+
+// publishBatch 按 front matter 分组发布 entries，写出结果文件；dir 为文章来源，记录在结果中。
+func publishBatch(dir string, entries []*batchEntry, opts batchOptions) error {
+	result := batchResult{Dir: dir, StartedAt: time.Now(), Drafts: []batchDraft{}, Failures: []batchFailure{}}
+	var valid []*batchEntry
+	for _, e := range entries {
+		if e.err != nil {
+			result.Failures = append(result.Failures, batchFailure{Path: e.path, Title: e.title, Stage: "parse", Error: e.err.Error()})
+			continue
+		}
+		valid = append(valid, e)
+	}
+	groups := groupBatch(valid, *opts.perDraft)
+
+	if *opts.dryRun {
+		plan := make([]batchDraft, 0, len(groups))
+		for _, g := range groups {
+			var d batchDraft
+			for _, e := range g {
+				d.Articles = append(d.Articles, batchArticle{Path: e.path, Title: e.title})
+			}
+			plan = append(plan, d)
+		}
+		output(map[string]any{"dir": dir, "drafts": plan, "failures": result.Failures}, func() {
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "DRAFT\tINDEX\tPATH\tTITLE\tCOVER")
+			for i, g := range groups {
+				for j, e := range g {
+					fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", i+1, j, e.path, truncate(e.title, 30), e.cover)
+				}
+			}
+			w.Flush()
+			for _, f := range result.Failures {
+				fmt.Fprintf(os.Stderr, "skip %s: %s\n", f.Path, f.Error)
+			}
+			fmt.Fprintf(os.Stderr, "%d articles in %d drafts, %d skipped\n", len(valid), len(groups), len(result.Failures))
+		})
+		return nil
+	}
+
+	cfg, err := publisher.LoadConfig(publisher.ResolveConfigPath(*opts.configPath))
+	if err != nil {
+		return err
+	}
+	p, err := publisher.New(cfg, nil, verbose, log.Default())
+	if err != nil {
+		return err
+	}
+	release, err := opts.lock.acquire(cfg)
+	if err != nil {
+		return err
+	}
+	defer release()
+	// Ctrl-C 时停止剩余的上传，已完成的部分仍写入结果文件。
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	prepared := prepareBatch(ctx, p, valid, *opts.concurrency)
+
+	history := publisher.NewPublishHistory(cfg.PublishHistoryPath)
+	for _, g := range groups {
+		var articles []publisher.DraftArticle
+		var members []*batchEntry
+		for _, e := range g {
+			if r := prepared[e]; r.err != nil {
+				result.Failures = append(result.Failures, batchFailure{Path: e.path, Title: e.title, Stage: "upload", Error: r.err.Error(), ErrCode: publisher.WeChatErrorCode(r.err)})
+			} else {
+				articles = append(articles, r.article)
+				members = append(members, e)
+			}
+		}
+		if len(articles) == 0 {
+			continue
+		}
+		mediaID, err := p.AddDraft(ctx, articles)
+		draft := batchDraft{MediaID: mediaID}
+		titles := make([]string, 0, len(members))
+		for _, e := range members {
+			rec := publisher.PublishRecord{Title: e.title, MediaID: mediaID, CoverPath: e.cover, Account: cfg.AccountID(), Status: publisher.PublishSucceeded}
+			if err != nil {
+				rec.Status, rec.Error, rec.ErrCode = publisher.PublishFailed, err.Error(), publisher.WeChatErrorCode(err)
+				result.Failures = append(result.Failures, batchFailure{Path: e.path, Title: e.title, Stage: "draft", Error: err.Error(), ErrCode: rec.ErrCode})
+			}
+			if _, herr := history.Append(rec); herr != nil {
+				log.Printf("[cli] record history failed: %v", herr)
+			}
+			draft.Articles = append(draft.Articles, batchArticle{Path: e.path, Title: e.title})
+			titles = append(titles, e.title)
+		}
+		notice := publisher.PublishNotice{Title: strings.Join(titles, " / "), MediaID: mediaID, Account: cfg.AccountID(), Time: time.Now()}
+		if err != nil {
+			notice.Error = err.Error()
+			fmt.Fprintf(os.Stderr, "draft of %d articles failed: %v\n", len(members), err)
+		} else {
+			result.Drafts = append(result.Drafts, draft)
+			fmt.Fprintf(os.Stderr, "draft %s: %d articles\n", mediaID, len(members))
+		}
+		notifyPublish(ctx, cfg, p, notice)
+	}
+
+	result.FinishedAt = time.Now()
+	output(result, func() {})
+	if err := writeBatchResult(*opts.resultPath, result); err != nil {
+		return err
+	}
+	published := 0
+	for _, d := range result.Drafts {
+		published += len(d.Articles)
+	}
+	fmt.Fprintf(os.Stderr, "published %d of %d articles in %d drafts, %d failed, took %s; result written to %s\n",
+		published, len(entries), len(result.Drafts), len(result.Failures), result.FinishedAt.Sub(result.StartedAt).Round(time.Second), *opts.resultPath)
+	if len(result.Failures) > 0 {
+		return fmt.Errorf("%d articles failed, see %s", len(result.Failures), *opts.resultPath)
+	}
+	return nil
+}
+
+// collectBatch 递归读取 dir 下的 .md 文件（跳过以 . 开头的目录），按路径排序。
+func collectBatch(dir, defaultCover, defaultAuthor string) ([]*batchEntry, error) {
+	var entries []*batchEntry
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := strings.ToLower(filepath.Ext(path)); ext != ".md" && ext != ".markdown" {
+			return nil
+		}
+		entries = append(entries, readBatchEntry(path, defaultCover, defaultAuthor))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].path < entries[j].path })
+	return entries, nil
+}
+
+func readBatchEntry(path, defaultCover, defaultAuthor string) *batchEntry {
+	e := &batchEntry{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		e.err = err
+		return e
+	}
+	var body []byte
+	e.fm, body = publisher.ParseFrontMatter(data)
+	e.title = strings.TrimSpace(e.fm.Title)
+	if e.title == "" {
+		for _, line := range strings.Split(string(body), "\n") {
+			if t, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+				e.title = strings.TrimSpace(t)
+				break
+			}
+		}
+	}
+	if e.fm.Author == "" {
+		e.fm.Author = defaultAuthor
+	}
+	e.cover = defaultCover
+	if c := strings.TrimSpace(e.fm.Cover); c != "" {
+		e.cover = c
+		if !filepath.IsAbs(c) {
+			e.cover = filepath.Join(filepath.Dir(path), c)
+		}
+	}
+	switch {
+	case e.title == "":
+		e.err = errors.New("missing title: set title in front matter or add a # heading")
+	case e.cover == "":
+		e.err = errors.New("missing cover: set cover in front matter or pass --cover")
+	default:
+		if _, err := os.Stat(e.cover); err != nil {
+			e.err = fmt.Errorf("cover: %w", err)
+		}
+	}
+	return e
+}
+
+// groupBatch 把文章分成草稿：同 group 的文章放在一起（超过 8 篇时拆分），
+// 其余按 order、路径排序后每 perDraft 篇一条；草稿按首篇出现的先后排列。
+func groupBatch(entries []*batchEntry, perDraft int) [][]*batchEntry {
+	byGroup := map[string][]*batchEntry{}
+	var order []string
+	for _, e := range entries {
+		key := e.fm.Group
+		if key == "" {
+			key = "\x00" + e.path
+		}
+		if _, ok := byGroup[key]; !ok {
+			order = append(order, key)
+		}
+		byGroup[key] = append(byGroup[key], e)
+	}
+
+	var groups [][]*batchEntry
+	var loose []*batchEntry
+	flushLoose := func() {
+		sort.SliceStable(loose, func(i, j int) bool { return loose[i].fm.Order < loose[j].fm.Order })
+		for len(loose) > 0 {
+			n := min(perDraft, len(loose))
+			groups = append(groups, loose[:n])
+			loose = loose[n:]
+		}
+	}
+	for _, key := range order {
+		members := byGroup[key]
+		if strings.HasPrefix(key, "\x00") {
+			loose = append(loose, members...)
+			continue
+		}
+		flushLoose()
+		sort.SliceStable(members, func(i, j int) bool { return members[i].fm.Order < members[j].fm.Order })
+		if len(members) > publisher.MaxDraftArticles {
+			log.Printf("[cli] group %q has %d articles, splitting into drafts of %d", key, len(members), publisher.MaxDraftArticles)
+		}
+		for len(members) > 0 {
+			n := min(publisher.MaxDraftArticles, len(members))
+			groups = append(groups, members[:n])
+			members = members[n:]
+		}
+	}
+	flushLoose()
+	return groups
+}
+
+type preparedArticle struct {
+	article publisher.DraftArticle
+	err     error
+}
+
+// prepareBatch 以 concurrency 个 goroutine 上传图片与封面，每完成一篇在 stderr 输出进度。
+func prepareBatch(ctx context.Context, p *publisher.Publisher, entries []*batchEntry, concurrency int) map[*batchEntry]preparedArticle {
+	results := make(map[*batchEntry]preparedArticle, len(entries))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan *batchEntry)
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range jobs {
+				var r preparedArticle
+				if err := ctx.Err(); err != nil {
+					r.err = err
+				} else {
+					params := publisher.PublishParams{MarkdownPath: e.path, Markdown: e.markdown, Title: e.title, CoverPath: e.cover, Author: e.fm.Author, Digest: e.fm.Digest}
+					r.article, r.err = p.PrepareArticle(ctx, params)
+				}
+				mu.Lock()
+				results[e] = r
+				status := "ok"
+				if r.err != nil {
+					status = "failed: " + r.err.Error()
+				}
+				fmt.Fprintf(os.Stderr, "[%d/%d] %s %s\n", len(results), len(entries), e.path, status)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, e := range entries {
+		jobs <- e
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func writeBatchResult(path string, result batchResult) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,151 @@
+// Package client 为 Web 服务接口（见 GET /api/openapi.json）的 Go 客户端，覆盖 session、上传、发布与发布任务。
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client 调用服务接口。BaseURL 为服务地址（含 base_path，如 https://example.com/wechat），
+// Token 为登录令牌（未启用登录时留空），可由 Login 获取；
+// APIKey 在未启用登录但配置了 sessions.bind_owner 时标识调用方（至少 16 个字符），创建的 session 只有同一 key 可以访问。
+type Client struct {
+	BaseURL string
+	Token   string
+	APIKey  string
+	http    *http.Client
+}
+
+// New 创建客户端；client 为空时使用 2 分钟超时（生成首稿可能较慢）。
+func New(baseURL string, client *http.Client) *Client {
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Minute}
+	}
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), http: client}
+}
+
+// APIError 为接口返回的错误；限流（429）时 RetryAfter 为建议的等待时间，
+// 上传校验失败时 Code 为错误码（如 too_large、unsupported_type）。
+type APIError struct {
+	StatusCode int
+	Message    string
+	Code       string
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound 判断错误是否为 404。
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Me 为当前用户信息，Auth 为 false 表示服务未启用登录。
+type Me struct {
+	Auth      bool       `json:"auth"`
+	User      string     `json:"user,omitempty"`
+	Admin     bool       `json:"admin,omitempty"`
+	Roles     []string   `json:"roles,omitempty"`
+	Tokens    int        `json:"tokens_today,omitempty"`
+	Cost      float64    `json:"cost_today,omitempty"`
+	Token     string     `json:"token,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Login 用用户名密码登录，成功后保存令牌供后续请求使用。
+func (c *Client) Login(ctx context.Context, username, password string) (*Me, error) {
+	var me Me
+	body := map[string]string{"username": username, "password": password}
+	if err := c.do(ctx, http.MethodPost, "/api/login", body, &me); err != nil {
+		return nil, err
+	}
+	c.Token = me.Token
+	return &me, nil
+}
+
+// Me 返回当前用户与当天的模型用量。
+func (c *Client) Me(ctx context.Context) (*Me, error) {
+	var me Me
+	if err := c.do(ctx, http.MethodGet, "/api/me", nil, &me); err != nil {
+		return nil, err
+	}
+	return &me, nil
+}
+
+// do 发送 JSON 请求并把响应解码到 out（可空）。
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, out)
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if c.APIKey != "" {
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+	return req, nil
+}
+
+func (c *Client) send(req *http.Request, out any) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return readError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// readError 把错误响应转为 APIError：服务一般返回纯文本，限流与上传校验失败时返回带 error 字段的 JSON。
+func readError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	var body struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		apiErr.Message, apiErr.Code = body.Error, body.Code
+	}
+	if sec, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(sec) * time.Second
+	}
+	return apiErr
+}
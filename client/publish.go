@@ -0,0 +1,189 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// 发布任务状态。
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// PublishRequest 为发布参数：CoverPath 为上传封面返回的 Path，AICover 为 true 时由模型生成封面；
+// Title、Digest、Markdown 为空时使用稿件中的内容。
+type PublishRequest struct {
+	SessionID string `json:"session_id"`
+	CoverPath string `json:"cover_path,omitempty"`
+	Author    string `json:"author,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	Markdown  string `json:"markdown,omitempty"`
+	AICover   bool   `json:"ai_cover,omitempty"`
+	// UpdateMediaID 非空时更新该草稿的第 UpdateIndex 篇文章，不新建草稿。
+	UpdateMediaID string `json:"update_media_id,omitempty"`
+	UpdateIndex   int    `json:"update_index,omitempty"`
+}
+
+// PublishResult 为发布成功后的草稿信息。
+type PublishResult struct {
+	MediaID   string `json:"media_id"`
+	Title     string `json:"title"`
+	CoverPath string `json:"cover_path"`
+	Updated   bool   `json:"updated,omitempty"`
+}
+
+// Job 为异步发布任务及其进度。
+type Job struct {
+	ID          string         `json:"job_id"`
+	SessionID   string         `json:"session_id"`
+	Status      string         `json:"status"`
+	Stage       string         `json:"stage,omitempty"`
+	Progress    string         `json:"progress,omitempty"`
+	ImagesDone  int            `json:"images_done,omitempty"`
+	ImagesTotal int            `json:"images_total,omitempty"`
+	Result      *PublishResult `json:"result,omitempty"`
+	Error       string         `json:"error,omitempty"`
+	// ErrCode 为失败原因中微信接口的 errcode。
+	ErrCode   int       `json:"errcode,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// JobError 为发布任务失败，ErrCode 为微信接口的 errcode（不是微信接口错误时为 0）。
+type JobError struct {
+	JobID   string
+	Message string
+	ErrCode int
+}
+
+func (e *JobError) Error() string {
+	return fmt.Sprintf("publish job %s failed: %s", e.JobID, e.Message)
+}
+
+// Finished 判断任务是否已结束（成功或失败）。
+func (j *Job) Finished() bool {
+	return j.Status == JobDone || j.Status == JobFailed
+}
+
+// Publish 提交发布任务并立即返回，用 GetJob 或 WaitJob 查询结果。
+func (c *Client) Publish(ctx context.Context, req PublishRequest) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodPost, "/api/publish", req, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Schedule 创建定时发布，到点后由服务提交发布任务。
+func (c *Client) Schedule(ctx context.Context, req PublishRequest, at time.Time) (*publisher.ScheduledPublish, error) {
+	body := struct {
+		PublishRequest
+		ScheduleAt string `json:"schedule_at"`
+	}{req, at.Format(time.RFC3339)}
+	var item publisher.ScheduledPublish
+	if err := c.do(ctx, http.MethodPost, "/api/publish", body, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// CancelSchedule 取消尚未执行的定时发布。
+func (c *Client) CancelSchedule(ctx context.Context, id string) (*publisher.ScheduledPublish, error) {
+	var item publisher.ScheduledPublish
+	if err := c.do(ctx, http.MethodDelete, "/api/schedules/"+url.PathEscape(id), nil, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// GetJob 查询发布任务进度。
+func (c *Client) GetJob(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodGet, "/api/jobs/"+url.PathEscape(id), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// WaitJob 每隔 interval（默认 2 秒）查询一次，直到任务结束或 ctx 结束；任务失败时同时返回任务与 *JobError。
+func (c *Client) WaitJob(ctx context.Context, id string, interval time.Duration) (*Job, error) {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job, err := c.GetJob(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status == JobFailed {
+			return job, &JobError{JobID: job.ID, Message: job.Error, ErrCode: job.ErrCode}
+		}
+		if job.Finished() {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return job, errors.Join(ctx.Err(), fmt.Errorf("publish job %s still %s", job.ID, job.Status))
+		case <-ticker.C:
+		}
+	}
+}
+
+// ListPublishesOptions 为发布记录的过滤条件，零值字段不参与过滤；Since、Until 按日期（含当天）过滤。
+type ListPublishesOptions struct {
+	SessionID string
+	User      string
+	Status    string
+	Account   string
+	Query     string
+	Since     time.Time
+	Until     time.Time
+	// Limit 为最多返回的条数，0 时使用服务端默认值（50），负数表示不限。
+	Limit int
+}
+
+// ListPublishes 按时间倒序列出发布记录；写作者只能看到自己的记录。
+func (c *Client) ListPublishes(ctx context.Context, opts ListPublishesOptions) ([]publisher.PublishRecord, error) {
+	q := url.Values{}
+	for key, value := range map[string]string{"session_id": opts.SessionID, "user": opts.User, "status": opts.Status, "account": opts.Account, "q": opts.Query} {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	if !opts.Since.IsZero() {
+		q.Set("since", opts.Since.Format("2006-01-02"))
+	}
+	if !opts.Until.IsZero() {
+		q.Set("until", opts.Until.Format("2006-01-02"))
+	}
+	switch {
+	case opts.Limit > 0:
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	case opts.Limit < 0:
+		q.Set("limit", "0")
+	}
+	path := "/api/publishes"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var resp struct {
+		Publishes []publisher.PublishRecord `json:"publishes"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Publishes, nil
+}
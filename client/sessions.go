@@ -0,0 +1,218 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+)
+
+// CreateSessionRequest 为新建 session 的参数，字段含义见 POST /api/sessions。
+type CreateSessionRequest struct {
+	Topic         string                    `json:"topic"`
+	Outline       []string                  `json:"outline,omitempty"`
+	Words         int                       `json:"words,omitempty"`
+	Constraints   []string                  `json:"constraints,omitempty"`
+	Style         string                    `json:"style,omitempty"`
+	Audience      string                    `json:"audience,omitempty"`
+	Tone          string                    `json:"tone,omitempty"`
+	Taboo         []string                  `json:"taboo,omitempty"`
+	Sampling      *generator.SamplingParams `json:"sampling,omitempty"`
+	Stream        bool                      `json:"stream,omitempty"`
+	Phase         string                    `json:"phase,omitempty"`
+	Variants      int                       `json:"variants,omitempty"`
+	ReferenceURLs []string                  `json:"reference_urls,omitempty"`
+	Research      bool                      `json:"research,omitempty"`
+	ResearchQuery string                    `json:"research_query,omitempty"`
+	Rewrite       bool                      `json:"rewrite,omitempty"`
+	Source        string                    `json:"source,omitempty"`
+	SourceTitle   string                    `json:"source_title,omitempty"`
+	SourceURL     string                    `json:"source_url,omitempty"`
+	Translate     bool                      `json:"translate,omitempty"`
+	SourceLang    string                    `json:"source_lang,omitempty"`
+	SeriesID      string                    `json:"series_id,omitempty"`
+	IdeaID        string                    `json:"idea_id,omitempty"`
+}
+
+// Session 为 session 的当前稿件与修订历史；Outline、Variants 等仅在对应模式下返回。
+// Generating 表示有生成或修订正在执行，此时修改请求返回 409（APIError.Code 为 generation_in_progress）。
+type Session struct {
+	SessionID      string                       `json:"session_id"`
+	Draft          generator.Draft              `json:"draft"`
+	History        []generator.Turn             `json:"history"`
+	Outline        *generator.Outline           `json:"outline,omitempty"`
+	Variants       []generator.Draft            `json:"variants,omitempty"`
+	References     []generator.Reference        `json:"references,omitempty"`
+	Research       []generator.SearchResult     `json:"research,omitempty"`
+	Originality    *generator.OriginalityReport `json:"originality,omitempty"`
+	HistorySummary string                       `json:"history_summary,omitempty"`
+	Generating     bool                         `json:"is_generating"`
+}
+
+// SessionSummary 为 session 列表中的一项。
+type SessionSummary struct {
+	ID        string    `json:"id"`
+	Topic     string    `json:"topic"`
+	Title     string    `json:"title,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Turns     int       `json:"turns"`
+	Archived  bool      `json:"archived,omitempty"`
+	Owner     string    `json:"owner,omitempty"`
+	Status    string    `json:"status"`
+}
+
+// SessionList 为一页 session。
+type SessionList struct {
+	Sessions []SessionSummary `json:"sessions"`
+	Total    int              `json:"total"`
+	Page     int              `json:"page"`
+	PageSize int              `json:"page_size"`
+}
+
+// ListSessionsOptions 为列表的过滤与分页参数，零值表示使用服务端默认值。
+type ListSessionsOptions struct {
+	Query    string
+	Status   string
+	Page     int
+	PageSize int
+	Archived bool
+}
+
+// CreateSession 新建 session 并生成首稿（Stream 为 true 时只创建）。
+func (c *Client) CreateSession(ctx context.Context, req CreateSessionRequest) (*Session, error) {
+	var sess Session
+	if err := c.do(ctx, http.MethodPost, "/api/sessions", req, &sess); err != nil {
+		return nil, err
+	}
+	return &sess, nil
+}
+
+// GetSession 返回 session 的当前稿件与修订历史。
+func (c *Client) GetSession(ctx context.Context, id string) (*Session, error) {
+	var sess Session
+	if err := c.do(ctx, http.MethodGet, "/api/sessions/"+url.PathEscape(id), nil, &sess); err != nil {
+		return nil, err
+	}
+	return &sess, nil
+}
+
+// ReviseSession 按修改意见修订稿件。
+func (c *Client) ReviseSession(ctx context.Context, id, comment string) (*Session, error) {
+	var sess Session
+	body := map[string]string{"comment": comment}
+	if err := c.do(ctx, http.MethodPost, "/api/sessions/"+url.PathEscape(id), body, &sess); err != nil {
+		return nil, err
+	}
+	return &sess, nil
+}
+
+// DeleteSession 删除 session。
+func (c *Client) DeleteSession(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/sessions/"+url.PathEscape(id), nil, nil)
+}
+
+// Heartbeat 续期 session，避免长时间编辑时被清理。
+func (c *Client) Heartbeat(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/api/heartbeat/"+url.PathEscape(id), nil, nil)
+}
+
+// ListSessions 按更新时间倒序分页列出 session。
+func (c *Client) ListSessions(ctx context.Context, opts ListSessionsOptions) (*SessionList, error) {
+	q := url.Values{}
+	if opts.Query != "" {
+		q.Set("q", opts.Query)
+	}
+	if opts.Status != "" {
+		q.Set("status", opts.Status)
+	}
+	if opts.Page > 0 {
+		q.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts.PageSize > 0 {
+		q.Set("page_size", strconv.Itoa(opts.PageSize))
+	}
+	if opts.Archived {
+		q.Set("archived", "true")
+	}
+	path := "/api/sessions"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var list SessionList
+	if err := c.do(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Version 为稿件的一个版本，Version 从 1 开始，对应第 Version 轮修订。
+type Version struct {
+	Version   int       `json:"version"`
+	Kind      string    `json:"kind"`
+	Comment   string    `json:"comment"`
+	Title     string    `json:"title"`
+	WordCount int       `json:"word_count"`
+	Provider  string    `json:"provider,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// VersionList 为 session 的全部版本，Current 为与当前稿件一致的版本（0 表示没有）。
+type VersionList struct {
+	Versions []Version `json:"versions"`
+	Current  int       `json:"current"`
+}
+
+// Diff 为两个版本的比较结果，To 为 0 表示当前稿件。
+type Diff struct {
+	From    int    `json:"from"`
+	To      int    `json:"to"`
+	Format  string `json:"format"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+	Diff    string `json:"diff"`
+}
+
+// Versions 列出 session 的全部版本。
+func (c *Client) Versions(ctx context.Context, id string) (*VersionList, error) {
+	var list VersionList
+	if err := c.do(ctx, http.MethodGet, "/api/sessions/"+url.PathEscape(id)+"/versions", nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// DiffVersions 比较版本 from 与 to（0 表示当前稿件）；format 为 unified 或 html，空为 unified。
+func (c *Client) DiffVersions(ctx context.Context, id string, from, to int, format string) (*Diff, error) {
+	q := url.Values{}
+	if from > 0 {
+		q.Set("from", strconv.Itoa(from))
+	}
+	if to > 0 {
+		q.Set("to", strconv.Itoa(to))
+	}
+	if format != "" {
+		q.Set("format", format)
+	}
+	path := "/api/sessions/" + url.PathEscape(id) + "/diff"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var diff Diff
+	if err := c.do(ctx, http.MethodGet, path, nil, &diff); err != nil {
+		return nil, err
+	}
+	return &diff, nil
+}
+
+// Rollback 把稿件恢复为版本 to，记录为新的一轮。
+func (c *Client) Rollback(ctx context.Context, id string, to int) (*Session, error) {
+	var sess Session
+	path := "/api/sessions/" + url.PathEscape(id) + "/rollback?to=" + strconv.Itoa(to)
+	if err := c.do(ctx, http.MethodPost, path, nil, &sess); err != nil {
+		return nil, err
+	}
+	return &sess, nil
+}
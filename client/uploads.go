@@ -0,0 +1,192 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// 上传用途。
+const (
+	UsageCover  = "cover"
+	UsageInline = "inline"
+	// UsageDocument 上传 Word (.docx)、HTML 或 Jupyter notebook 文档，服务端转为 Markdown 写入 session 稿件。
+	UsageDocument = "document"
+)
+
+// Upload 为上传结果；发布时以 Path 作为 cover_path。
+type Upload struct {
+	Path     string `json:"path"`
+	URL      string `json:"url"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	Usage    string `json:"usage,omitempty"`
+	Alt      string `json:"alt,omitempty"`
+	Caption  string `json:"caption,omitempty"`
+	// Width/Height 为图片尺寸，仅 EditUpload 返回。
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// 以下仅 UsageDocument 返回：转换得到的稿件、保存的配图与被去掉的图片。
+	Title    string   `json:"title,omitempty"`
+	Markdown string   `json:"markdown,omitempty"`
+	Images   []Upload `json:"images,omitempty"`
+	Skipped  []string `json:"skipped,omitempty"`
+}
+
+// Upload 为 session 上传封面、配图或文档，usage 为 UsageCover、UsageInline 或 UsageDocument。
+func (c *Client) Upload(ctx context.Context, sessionID, filename string, r io.Reader, usage string) (*Upload, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		err := writeUploadForm(mw, sessionID, filename, r, usage)
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	req, err := c.newRequest(ctx, http.MethodPost, "/api/uploads", pr)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	var up Upload
+	if err := c.send(req, &up); err != nil {
+		return nil, err
+	}
+	return &up, nil
+}
+
+// UploadFile 上传本地文件。
+func (c *Client) UploadFile(ctx context.Context, sessionID, path, usage string) (*Upload, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return c.Upload(ctx, sessionID, filepath.Base(path), f, usage)
+}
+
+func writeUploadForm(mw *multipart.Writer, sessionID, filename string, r io.Reader, usage string) error {
+	if err := mw.WriteField("session_id", sessionID); err != nil {
+		return err
+	}
+	if usage != "" {
+		if err := mw.WriteField("usage", usage); err != nil {
+			return err
+		}
+	}
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, r)
+	return err
+}
+
+// Crop 为裁剪区域（像素，旋转后图片的坐标）。
+type Crop struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// EditUploadRequest 描述图片编辑，按旋转、裁剪、缩放的顺序执行。
+type EditUploadRequest struct {
+	SessionID string `json:"session_id"`
+	Rotate    int    `json:"rotate,omitempty"`
+	Crop      *Crop  `json:"crop,omitempty"`
+	// Aspect 为比例预设 2.35:1 或 1:1。
+	Aspect string `json:"aspect,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	Usage  string `json:"usage,omitempty"`
+}
+
+// EditUpload 编辑已上传的图片（name 为 Upload.Path 的最后一段），返回新的上传文件。
+func (c *Client) EditUpload(ctx context.Context, name string, req EditUploadRequest) (*Upload, error) {
+	var up Upload
+	if err := c.do(ctx, http.MethodPost, "/api/uploads/"+url.PathEscape(name)+"/edit", req, &up); err != nil {
+		return nil, err
+	}
+	return &up, nil
+}
+
+// ResumableUpload 为分片上传的状态。
+type ResumableUpload struct {
+	ID        string    `json:"upload_id"`
+	SessionID string    `json:"session_id"`
+	Filename  string    `json:"filename"`
+	Usage     string    `json:"usage,omitempty"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	ChunkSize int64     `json:"chunk_size"`
+	Progress  float64   `json:"progress"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UploadLarge 以分片方式上传本地文件（大图或 MP4 视频），单个分片失败时查询服务端位置后续传，
+// 最多连续重试 3 次；progress 非空时在每个分片完成后调用。
+func (c *Client) UploadLarge(ctx context.Context, sessionID, path, usage string, progress func(sent, total int64)) (*Upload, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var up ResumableUpload
+	in := map[string]any{"session_id": sessionID, "filename": filepath.Base(path), "size": info.Size(), "usage": usage}
+	if err := c.do(ctx, http.MethodPost, "/api/uploads/resumable", in, &up); err != nil {
+		return nil, err
+	}
+	base := "/api/uploads/resumable/" + url.PathEscape(up.ID)
+	failures := 0
+	for up.Offset < up.Size {
+		n := min(up.ChunkSize, up.Size-up.Offset)
+		err := c.putChunk(ctx, base, f, up.Offset, n, &up)
+		if err != nil {
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.StatusCode != http.StatusConflict && apiErr.StatusCode < 500 {
+				return nil, err
+			}
+			if failures++; failures > 3 || ctx.Err() != nil {
+				return nil, err
+			}
+			if err := c.do(ctx, http.MethodGet, base, nil, &up); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		failures = 0
+		if progress != nil {
+			progress(up.Offset, up.Size)
+		}
+	}
+	var result Upload
+	if err := c.do(ctx, http.MethodPost, base+"/complete", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// putChunk 上传 f 中从 offset 开始的 n 字节，成功时更新 up。
+func (c *Client) putChunk(ctx context.Context, base string, f *os.File, offset, n int64, up *ResumableUpload) error {
+	req, err := c.newRequest(ctx, http.MethodPut, base+"?offset="+strconv.FormatInt(offset, 10), io.NewSectionReader(f, offset, n))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = n
+	req.Header.Set("Content-Type", "application/octet-stream")
+	return c.send(req, up)
+}
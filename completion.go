@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// subcommands 为按第一个参数分派的命令及其子命令；"" 表示命令本身也接受参数（如 publish）。
+var subcommands = map[string][]string{
+	"publish":  {"", "batch"},
+	"import":   {"hugo", "obsidian", "feishu", "docx", "html", "ipynb"},
+	"draft":    {"list", "update", "delete"},
+	"material": {"list"},
+	"comment":  {"list", "open", "close", "elect", "unelect", "reply"},
+	"masssend": {"send", "preview", "status", "list"},
+	"tag":      {"list", "create", "rename", "delete", "add", "remove"},
+	"preview":  {"", "send"},
+	"quota":    {"show", "clear"},
+	"schedule": {"add", "list", "cancel"},
+	"stats":    {"", "collect"},
+	"cover":    {"gen"},
+	"user":     {"add", "passwd", "admin", "roles", "delete", "list"},
+	"secrets":  {"keygen", "encrypt", "decrypt"},
+	"docs":     {"man"},
+}
+
+// completion 与 docs 需要遍历 commands，在 init 中注册以避免初始化循环。
+func init() {
+	commands = append(commands,
+		command{"completion", "print a shell completion script (bash, zsh, fish or powershell)", runCompletion},
+		command{"docs", "generate man pages", runDocs},
+	)
+}
+
+// completionShells 为 completion 支持的 shell。
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// cmdSpec 描述一个命令或子命令的用法与参数，用于生成补全脚本与 man 手册。
+type cmdSpec struct {
+	path    string // 如 "draft list"
+	usage   string
+	summary string
+	fs      *flag.FlagSet
+}
+
+// describing 为 true 时 newFlagSet 的 -h 不输出用法，而是以 cmdSpec panic，由 describeCommand 接住。
+var describing bool
+
+// describeCommand 以 args 加 -h 运行命令，取得其参数集；各命令在解析参数前没有副作用。
+func describeCommand(cmd command, args ...string) (spec cmdSpec, ok bool) {
+	describing = true
+	defer func() {
+		describing = false
+		if r := recover(); r != nil {
+			s, isSpec := r.(cmdSpec)
+			if !isSpec {
+				panic(r)
+			}
+			spec, ok = s, true
+		}
+	}()
+	_ = cmd.run(append(args, "-h"))
+	return spec, false
+}
+
+// commandSpecs 按命令表顺序返回全部命令与子命令的描述。
+func commandSpecs() []cmdSpec {
+	var specs []cmdSpec
+	for _, cmd := range commands {
+		subs, ok := subcommands[cmd.name]
+		if !ok {
+			subs = []string{""}
+		}
+		for _, sub := range subs {
+			var args []string
+			if sub != "" {
+				args = []string{sub}
+			}
+			if spec, ok := describeCommand(cmd, args...); ok {
+				specs = append(specs, spec)
+			}
+		}
+	}
+	return specs
+}
+
+func (s cmdSpec) flags() []*flag.Flag {
+	var out []*flag.Flag
+	s.fs.VisitAll(func(f *flag.Flag) { out = append(out, f) })
+	return out
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// flagDescription 返回参数说明的第一行。
+func flagDescription(f *flag.Flag) string {
+	desc, _, _ := strings.Cut(f.Usage, "\n")
+	return desc
+}
+
+// firstArgs 返回命令后第一个参数的候选：子命令、completion 的 shell 或 help 的命令名。
+func firstArgs(name string) []string {
+	switch name {
+	case "completion":
+		return completionShells
+	case "help":
+		return commandNames()
+	}
+	var out []string
+	for _, sub := range subcommands[name] {
+		if sub != "" {
+			out = append(out, sub)
+		}
+	}
+	return out
+}
+
+func commandNames() []string {
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		names[i] = cmd.name
+	}
+	return names
+}
+
+// subcommandKeys 返回带子命令的参数集路径，如 "draft list"。
+func subcommandKeys(specs []cmdSpec) []string {
+	var keys []string
+	for _, s := range specs {
+		if strings.Contains(s.path, " ") {
+			keys = append(keys, s.path)
+		}
+	}
+	return keys
+}
+
+// runCompletion 处理 `completion` 子命令：输出 shell 补全脚本。
+func runCompletion(args []string) error {
+	bin := filepath.Base(os.Args[0])
+	fs := newFlagSet("completion", "bash|zsh|fish|powershell [flags]", fmt.Sprintf("Print the shell completion script for commands, subcommands and flags.\n"+
+		"  bash:       source <(%[1]s completion bash)   (add to ~/.bashrc)\n"+
+		"  zsh:        %[1]s completion zsh > \"${fpath[1]}/_%[1]s\"\n"+
+		"  fish:       %[1]s completion fish > ~/.config/fish/completions/%[1]s.fish\n"+
+		"  powershell: %[1]s completion powershell | Out-String | Invoke-Expression   (add to $PROFILE)", bin))
+	name := fs.String("name", bin, "name of the installed binary the script completes")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: %s completion bash|zsh|fish|powershell [--name binary]", os.Args[0])
+	}
+	if *name == "" || strings.ContainsAny(*name, " \t'\"$`\\") {
+		return errors.New("--name must be a plain command name")
+	}
+	specs := commandSpecs()
+	var buf bytes.Buffer
+	switch positional[0] {
+	case "bash":
+		writeBashCompletion(&buf, *name, specs)
+	case "zsh":
+		writeZshCompletion(&buf, *name, specs)
+	case "fish":
+		writeFishCompletion(&buf, *name, specs)
+	case "powershell":
+		writePowerShellCompletion(&buf, *name, specs)
+	default:
+		return fmt.Errorf("usage: %s completion bash|zsh|fish|powershell [--name binary]", os.Args[0])
+	}
+	output(map[string]any{"shell": positional[0], "script": buf.String()}, func() {
+		os.Stdout.Write(buf.Bytes())
+	})
+	return nil
+}
+
+// shellIdent 把命令名转换为可用作 shell 函数名的标识符。
+func shellIdent(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// flagArg 返回补全与文档中的参数写法：单字母参数用 -v，其余用 --name。
+func flagArg(f *flag.Flag) string {
+	if len(f.Name) == 1 {
+		return "-" + f.Name
+	}
+	return "--" + f.Name
+}
+
+func flagNames(s cmdSpec) []string {
+	var names []string
+	for _, f := range s.flags() {
+		names = append(names, flagArg(f))
+	}
+	return names
+}
+
+// writeBashCompletion 输出 bash 补全脚本；参数值与位置参数交给默认的文件名补全。
+func writeBashCompletion(w io.Writer, name string, specs []cmdSpec) {
+	fn := "_" + shellIdent(name)
+	fmt.Fprintf(w, "# bash completion for %s\n# source <(%s completion bash)\n\n", name, name)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintf(w, "    local cur=${COMP_WORDS[COMP_CWORD]} i cmd= next= pos=0 words= flags=\n")
+	fmt.Fprintf(w, "    for ((i = 1; i < COMP_CWORD; i++)); do\n")
+	fmt.Fprintf(w, "        if [[ -z $cmd ]]; then\n")
+	fmt.Fprintf(w, "            [[ ${COMP_WORDS[i]} == -* ]] && continue\n")
+	fmt.Fprintf(w, "            cmd=${COMP_WORDS[i]} pos=$i\n")
+	fmt.Fprintf(w, "        elif ((i == pos + 1)); then\n")
+	fmt.Fprintf(w, "            next=${COMP_WORDS[i]}\n")
+	fmt.Fprintf(w, "        fi\n")
+	fmt.Fprintf(w, "    done\n")
+	fmt.Fprintf(w, "    if [[ -z $cmd ]]; then\n")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"%s help --json\" -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	fmt.Fprintf(w, "        return\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "    local key=$cmd\n")
+	fmt.Fprintf(w, "    case \"$cmd $next\" in\n")
+	fmt.Fprintf(w, "    \"%s\") key=\"$cmd $next\" ;;\n", strings.Join(subcommandKeys(specs), "\" | \""))
+	fmt.Fprintf(w, "    esac\n")
+	fmt.Fprintf(w, "    if [[ $cur == -* ]]; then\n")
+	fmt.Fprintf(w, "        case $key in\n")
+	for _, s := range specs {
+		fmt.Fprintf(w, "        \"%s\") flags=\"%s\" ;;\n", s.path, strings.Join(flagNames(s), " "))
+	}
+	fmt.Fprintf(w, "        esac\n")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n")
+	fmt.Fprintf(w, "        return\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "    if ((COMP_CWORD == pos + 1)); then\n")
+	fmt.Fprintf(w, "        case $cmd in\n")
+	for _, c := range append(commandNames(), "help") {
+		if args := firstArgs(c); len(args) > 0 {
+			fmt.Fprintf(w, "        %s) words=\"%s\" ;;\n", c, strings.Join(args, " "))
+		}
+	}
+	fmt.Fprintf(w, "        esac\n")
+	fmt.Fprintf(w, "        [[ -n $words ]] && COMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "complete -o bashdefault -o default -F %s %s\n", fn, name)
+}
+
+// zshQuote 转义单引号字符串中的 '；arguments 为 true 时同时转义 _arguments 说明中的 []:\。
+func zshQuote(s string, arguments bool) string {
+	if arguments {
+		s = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+	}
+	return strings.ReplaceAll(s, "'", `'\''`)
+}
+
+// writeZshCompletion 输出 zsh 补全脚本，命令与参数附带说明。
+func writeZshCompletion(w io.Writer, name string, specs []cmdSpec) {
+	fn := "_" + shellIdent(name)
+	fmt.Fprintf(w, "#compdef %s\n# zsh completion for %s\n# %s completion zsh > \"${fpath[1]}/_%s\"\n\n", name, name, name, name)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintf(w, "    local i cmd= next= pos=0 n\n")
+	fmt.Fprintf(w, "    local -a args\n")
+	fmt.Fprintf(w, "    for ((i = 2; i < CURRENT; i++)); do\n")
+	fmt.Fprintf(w, "        if [[ -z $cmd ]]; then\n")
+	fmt.Fprintf(w, "            [[ ${words[i]} == -* ]] && continue\n")
+	fmt.Fprintf(w, "            cmd=${words[i]} pos=$i\n")
+	fmt.Fprintf(w, "        elif ((i == pos + 1)); then\n")
+	fmt.Fprintf(w, "            next=${words[i]}\n")
+	fmt.Fprintf(w, "        fi\n")
+	fmt.Fprintf(w, "    done\n")
+	fmt.Fprintf(w, "    if [[ -z $cmd ]]; then\n")
+	fmt.Fprintf(w, "        if [[ ${words[CURRENT]} == -* ]]; then\n")
+	fmt.Fprintf(w, "            compadd -- --json\n")
+	fmt.Fprintf(w, "            return\n")
+	fmt.Fprintf(w, "        fi\n")
+	fmt.Fprintf(w, "        args=(\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "            '%s:%s'\n", cmd.name, zshQuote(cmd.summary, false))
+	}
+	fmt.Fprintf(w, "            'help:show the flags of a command'\n")
+	fmt.Fprintf(w, "        )\n")
+	fmt.Fprintf(w, "        _describe -t commands command args\n")
+	fmt.Fprintf(w, "        return\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "    if ((CURRENT == pos + 1)) && [[ ${words[CURRENT]} != -* ]]; then\n")
+	fmt.Fprintf(w, "        case $cmd in\n")
+	for _, c := range append(commandNames(), "help") {
+		if args := firstArgs(c); len(args) > 0 {
+			fmt.Fprintf(w, "        %s) args=(%s) ;;\n", c, strings.Join(args, " "))
+		}
+	}
+	fmt.Fprintf(w, "        esac\n")
+	fmt.Fprintf(w, "        if ((${#args})); then\n")
+	fmt.Fprintf(w, "            compadd -a args\n")
+	fmt.Fprintf(w, "            return\n")
+	fmt.Fprintf(w, "        fi\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "    local key=$cmd\n")
+	fmt.Fprintf(w, "    n=$pos\n")
+	fmt.Fprintf(w, "    case \"$cmd $next\" in\n")
+	fmt.Fprintf(w, "    '%s') key=\"$cmd $next\" n=$((pos + 1)) ;;\n", strings.Join(subcommandKeys(specs), "' | '"))
+	fmt.Fprintf(w, "    esac\n")
+	fmt.Fprintf(w, "    # let _arguments start after the command (or subcommand)\n")
+	fmt.Fprintf(w, "    shift $((n - 1)) words\n")
+	fmt.Fprintf(w, "    ((CURRENT -= n - 1))\n")
+	fmt.Fprintf(w, "    case $key in\n")
+	for _, s := range specs {
+		fmt.Fprintf(w, "    '%s')\n", s.path)
+		fmt.Fprintf(w, "        _arguments \\\n")
+		for _, f := range s.flags() {
+			desc := zshQuote(flagDescription(f), true)
+			if isBoolFlag(f) {
+				fmt.Fprintf(w, "            '%s[%s]' \\\n", flagArg(f), desc)
+			} else {
+				fmt.Fprintf(w, "            '%s=[%s]:%s:_files' \\\n", flagArg(f), desc, f.Name)
+			}
+		}
+		fmt.Fprintf(w, "            '*:file:_files'\n")
+		fmt.Fprintf(w, "        ;;\n")
+	}
+	fmt.Fprintf(w, "    esac\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "if [[ $funcstack[1] == %s ]]; then\n    %s \"$@\"\nelse\n    compdef %s %s\nfi\n", fn, fn, fn, name)
+}
+
+// fishQuote 返回 fish 的单引号字符串。
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// writeFishCompletion 输出 fish 补全脚本。
+func writeFishCompletion(w io.Writer, name string, specs []cmdSpec) {
+	fmt.Fprintf(w, "# fish completion for %s\n# %s completion fish > ~/.config/fish/completions/%s.fish\n\n", name, name, name)
+	fmt.Fprintf(w, "complete -c %s -n __fish_use_subcommand -l json -d %s\n", name, fishQuote("print the result as a single JSON object"))
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "complete -c %s -n __fish_use_subcommand -f -a %s -d %s\n", name, cmd.name, fishQuote(cmd.summary))
+	}
+	fmt.Fprintf(w, "complete -c %s -n __fish_use_subcommand -f -a help -d %s\n", name, fishQuote("show the flags of a command"))
+	for _, c := range append(commandNames(), "help") {
+		args := firstArgs(c)
+		if len(args) == 0 {
+			continue
+		}
+		cond := fmt.Sprintf("__fish_seen_subcommand_from %s; and not __fish_seen_subcommand_from %s", c, strings.Join(args, " "))
+		fmt.Fprintf(w, "complete -c %s -n %s -f -a %s\n", name, fishQuote(cond), fishQuote(strings.Join(args, " ")))
+	}
+	for _, s := range specs {
+		cmd, sub, _ := strings.Cut(s.path, " ")
+		cond := "__fish_seen_subcommand_from " + cmd
+		if sub != "" {
+			cond += "; and __fish_seen_subcommand_from " + sub
+		} else if args := firstArgs(cmd); len(args) > 0 {
+			cond += "; and not __fish_seen_subcommand_from " + strings.Join(args, " ")
+		}
+		for _, f := range s.flags() {
+			opt := "-l " + f.Name
+			if len(f.Name) == 1 {
+				opt = "-s " + f.Name
+			}
+			if !isBoolFlag(f) {
+				opt += " -r"
+			}
+			fmt.Fprintf(w, "complete -c %s -n %s %s -d %s\n", name, fishQuote(cond), opt, fishQuote(flagDescription(f)))
+		}
+	}
+}
+
+// psQuote 返回 PowerShell 的单引号字符串。
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func psList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, s := range items {
+		quoted[i] = psQuote(s)
+	}
+	return "@(" + strings.Join(quoted, ", ") + ")"
+}
+
+// writePowerShellCompletion 输出 PowerShell 补全脚本；没有候选时 PowerShell 回退为路径补全。
+func writePowerShellCompletion(w io.Writer, name string, specs []cmdSpec) {
+	fmt.Fprintf(w, "# powershell completion for %s\n# %s completion powershell | Out-String | Invoke-Expression\n\n", name, name)
+	fmt.Fprintf(w, "Register-ArgumentCompleter -Native -CommandName %s -ScriptBlock {\n", psQuote(name))
+	fmt.Fprintf(w, "    param($wordToComplete, $commandAst, $cursorPosition)\n")
+	fmt.Fprintf(w, "    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | Where-Object { $_.Extent.EndOffset -lt $cursorPosition } | ForEach-Object { $_.ToString() })\n")
+	fmt.Fprintf(w, "    $cmd = ''; $next = ''; $pos = -1\n")
+	fmt.Fprintf(w, "    for ($i = 0; $i -lt $words.Count; $i++) {\n")
+	fmt.Fprintf(w, "        if ($cmd -eq '') {\n")
+	fmt.Fprintf(w, "            if ($words[$i] -like '-*') { continue }\n")
+	fmt.Fprintf(w, "            $cmd = $words[$i]; $pos = $i\n")
+	fmt.Fprintf(w, "        } elseif ($i -eq $pos + 1) {\n")
+	fmt.Fprintf(w, "            $next = $words[$i]\n")
+	fmt.Fprintf(w, "        }\n")
+	fmt.Fprintf(w, "    }\n")
+	fmt.Fprintf(w, "    $candidates = @()\n")
+	fmt.Fprintf(w, "    if ($cmd -eq '') {\n")
+	fmt.Fprintf(w, "        $candidates = %s\n", psList(append(commandNames(), "help", "--json")))
+	fmt.Fprintf(w, "    } elseif ($wordToComplete -like '-*') {\n")
+	fmt.Fprintf(w, "        $key = $cmd\n")
+	fmt.Fprintf(w, "        if (%s -contains \"$cmd $next\") { $key = \"$cmd $next\" }\n", psList(subcommandKeys(specs)))
+	fmt.Fprintf(w, "        $candidates = switch ($key) {\n")
+	for _, s := range specs {
+		fmt.Fprintf(w, "            %s { %s }\n", psQuote(s.path), psList(flagNames(s)))
+	}
+	fmt.Fprintf(w, "        }\n")
+	fmt.Fprintf(w, "    } elseif ($words.Count -eq $pos + 1) {\n")
+	fmt.Fprintf(w, "        $candidates = switch ($cmd) {\n")
+	for _, c := range append(commandNames(), "help") {
+		if args := firstArgs(c); len(args) > 0 {
+			fmt.Fprintf(w, "            %s { %s }\n", psQuote(c), psList(args))
+		}
+	}
+	fmt.Fprintf(w, "        }\n")
+	fmt.Fprintf(w, "    }\n")
+	fmt.Fprintf(w, "    @($candidates) | Where-Object { $_ -and $_ -like \"$wordToComplete*\" } | ForEach-Object {\n")
+	fmt.Fprintf(w, "        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)\n")
+	fmt.Fprintf(w, "    }\n")
+	fmt.Fprintf(w, "}\n")
+}
@@ -0,0 +1,261 @@
+package cover
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// 公众号封面按 2.35:1 裁剪，默认输出 900x383。
+const (
+	DefaultWidth    = 900
+	DefaultHeight   = 383
+	DefaultFontSize = 48
+)
+
+// Options 描述封面生成参数；Background 为空时使用纯色/渐变模板。
+type Options struct {
+	Title      string
+	Background string // 背景图片路径（可选）
+	BgColor    string // 纯色背景，如 #1f2937
+	GradientTo string // 渐变终止色（可选，从 BgColor 纵向渐变到该色）
+	FontPath   string // TTF/OTF 字体路径；中文标题必须提供支持 CJK 的字体
+	FontSize   float64
+	Color      string // 标题颜色，默认白色
+	Width      int
+	Height     int
+	// SafeMargin 为左右安全边距占宽度的比例，保证标题在 1:1 分享卡片裁剪时仍可见。
+	SafeMargin float64
+}
+
+func (o *Options) applyDefaults() {
+	if o.Width <= 0 {
+		o.Width = DefaultWidth
+	}
+	if o.Height <= 0 {
+		o.Height = DefaultHeight
+	}
+	if o.FontSize <= 0 {
+		o.FontSize = DefaultFontSize
+	}
+	if o.BgColor == "" {
+		o.BgColor = "#1f2937"
+	}
+	if o.Color == "" {
+		o.Color = "#ffffff"
+	}
+	if o.SafeMargin <= 0 || o.SafeMargin >= 0.5 {
+		// 中心 1:1 区域约占 2.35:1 宽度的 43%，左右各留约 28%。
+		o.SafeMargin = 0.28
+	}
+}
+
+// Render 根据 Options 绘制封面。
+func Render(opts Options) (*image.RGBA, error) {
+	opts.applyDefaults()
+	if strings.TrimSpace(opts.Title) == "" {
+		return nil, errors.New("cover title is required")
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	if opts.Background != "" {
+		if err := drawBackgroundImage(dst, opts.Background); err != nil {
+			return nil, err
+		}
+		// 压暗背景，保证文字可读。
+		draw.Draw(dst, dst.Bounds(), &image.Uniform{color.RGBA{0, 0, 0, 96}}, image.Point{}, draw.Over)
+	} else {
+		from, err := parseHexColor(opts.BgColor)
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if opts.GradientTo != "" {
+			if to, err = parseHexColor(opts.GradientTo); err != nil {
+				return nil, err
+			}
+		}
+		drawGradient(dst, from, to)
+	}
+
+	face, err := loadFace(opts.FontPath, opts.FontSize)
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	fg, err := parseHexColor(opts.Color)
+	if err != nil {
+		return nil, err
+	}
+	drawTitle(dst, face, fg, opts)
+	return dst, nil
+}
+
+// Generate 绘制封面并以 JPEG 写入 outPath。
+func Generate(opts Options, outPath string) error {
+	img, err := Render(opts)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(outPath); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return jpeg.Encode(f, img, &jpeg.Options{Quality: 90})
+}
+
+func drawBackgroundImage(dst *image.RGBA, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("decode background: %w", err)
+	}
+	// 等比缩放后居中裁剪，铺满目标区域。
+	sb := src.Bounds()
+	db := dst.Bounds()
+	scale := max(float64(db.Dx())/float64(sb.Dx()), float64(db.Dy())/float64(sb.Dy()))
+	cw := int(float64(db.Dx()) / scale)
+	ch := int(float64(db.Dy()) / scale)
+	x0 := sb.Min.X + (sb.Dx()-cw)/2
+	y0 := sb.Min.Y + (sb.Dy()-ch)/2
+	xdraw.CatmullRom.Scale(dst, db, src, image.Rect(x0, y0, x0+cw, y0+ch), draw.Src, nil)
+	return nil
+}
+
+func drawGradient(dst *image.RGBA, from, to color.RGBA) {
+	b := dst.Bounds()
+	h := b.Dy()
+	for y := 0; y < h; y++ {
+		t := 0.0
+		if h > 1 {
+			t = float64(y) / float64(h-1)
+		}
+		c := color.RGBA{
+			R: lerp(from.R, to.R, t),
+			G: lerp(from.G, to.G, t),
+			B: lerp(from.B, to.B, t),
+			A: 255,
+		}
+		draw.Draw(dst, image.Rect(b.Min.X, b.Min.Y+y, b.Max.X, b.Min.Y+y+1), &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+}
+
+func lerp(a, b uint8, t float64) uint8 {
+	return uint8(float64(a) + (float64(b)-float64(a))*t)
+}
+
+func loadFace(fontPath string, size float64) (font.Face, error) {
+	if fontPath == "" {
+		// 内置字体仅含 ASCII，中文需通过 font_path 指定字体文件。
+		return basicfont.Face7x13, nil
+	}
+	data, err := os.ReadFile(fontPath)
+	if err != nil {
+		return nil, fmt.Errorf("read font: %w", err)
+	}
+	var fnt *opentype.Font
+	if coll, err := opentype.ParseCollection(data); err == nil && coll.NumFonts() > 0 {
+		fnt, err = coll.Font(0)
+		if err != nil {
+			return nil, fmt.Errorf("parse font: %w", err)
+		}
+	} else {
+		fnt, err = opentype.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse font: %w", err)
+		}
+	}
+	return opentype.NewFace(fnt, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// drawTitle 在安全区内按宽度折行并垂直居中绘制标题。
+func drawTitle(dst *image.RGBA, face font.Face, fg color.RGBA, opts Options) {
+	b := dst.Bounds()
+	margin := int(float64(b.Dx()) * opts.SafeMargin)
+	maxWidth := fixed.I(b.Dx() - 2*margin)
+	lines := wrapText(face, strings.TrimSpace(opts.Title), maxWidth)
+
+	metrics := face.Metrics()
+	lineHeight := (metrics.Ascent + metrics.Descent).Ceil() * 5 / 4
+	total := lineHeight * len(lines)
+	y := b.Min.Y + (b.Dy()-total)/2 + metrics.Ascent.Ceil()
+
+	d := &font.Drawer{Dst: dst, Src: &image.Uniform{fg}, Face: face}
+	for _, line := range lines {
+		w := d.MeasureString(line)
+		d.Dot = fixed.Point26_6{
+			X: fixed.I(b.Min.X) + (fixed.I(b.Dx())-w)/2,
+			Y: fixed.I(y),
+		}
+		d.DrawString(line)
+		y += lineHeight
+	}
+}
+
+// wrapText 按字符折行，兼顾中文无空格的情况。
+func wrapText(face font.Face, text string, maxWidth fixed.Int26_6) []string {
+	var lines []string
+	var cur []rune
+	for _, r := range text {
+		if r == '\n' {
+			lines = append(lines, string(cur))
+			cur = cur[:0]
+			continue
+		}
+		next := append(cur, r)
+		if len(cur) > 0 && font.MeasureString(face, string(next)) > maxWidth {
+			lines = append(lines, string(cur))
+			cur = []rune{r}
+			continue
+		}
+		cur = next
+	}
+	if len(cur) > 0 {
+		lines = append(lines, string(cur))
+	}
+	return lines
+}
+
+func parseHexColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	var r, g, b uint8
+	switch len(s) {
+	case 6:
+		if _, err := fmt.Sscanf(s, "%02x%02x%02x", &r, &g, &b); err != nil {
+			return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+		}
+	case 3:
+		if _, err := fmt.Sscanf(s, "%1x%1x%1x", &r, &g, &b); err != nil {
+			return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+		}
+		r, g, b = r*17, g*17, b*17
+	default:
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.RGBA{R: r, G: g, B: b, A: 255}, nil
+}
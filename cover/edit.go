@@ -0,0 +1,122 @@
+package cover
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Aspects 为裁剪比例预设：2.35:1 为公众号封面（头条），1:1 为次条与分享卡片。
+var Aspects = map[string]float64{
+	"2.35:1": 2.35,
+	"1:1":    1,
+}
+
+// EditOptions 描述一次图片编辑，按旋转、裁剪、缩放的顺序执行。
+type EditOptions struct {
+	Rotate int             // 顺时针旋转角度：0、90、180 或 270
+	Crop   image.Rectangle // 裁剪区域（旋转后图片的坐标），为空时不裁剪
+	Aspect string          // 比例预设（见 Aspects），在裁剪区域（或整张图）内居中取该比例的最大区域
+	Width  int             // 缩放后的宽度；只设置宽或高时按比例计算另一边
+	Height int
+}
+
+// Edit 按 opts 编辑图片并返回新图片。
+func Edit(src image.Image, opts EditOptions) (*image.RGBA, error) {
+	img := toRGBA(src)
+	switch opts.Rotate {
+	case 0:
+	case 90, 180, 270:
+		img = rotate(img, opts.Rotate)
+	default:
+		return nil, fmt.Errorf("rotate must be 0, 90, 180 or 270, got %d", opts.Rotate)
+	}
+
+	rect := img.Bounds()
+	if !opts.Crop.Empty() {
+		crop := opts.Crop.Add(rect.Min)
+		if !crop.In(rect) {
+			return nil, fmt.Errorf("crop %v out of image bounds %dx%d", opts.Crop, rect.Dx(), rect.Dy())
+		}
+		rect = crop
+	}
+	if opts.Aspect != "" {
+		ratio, ok := Aspects[opts.Aspect]
+		if !ok {
+			return nil, fmt.Errorf("unknown aspect %q (want 2.35:1 or 1:1)", opts.Aspect)
+		}
+		rect = fitAspect(rect, ratio)
+	}
+	if rect != img.Bounds() {
+		img = toRGBA(img.SubImage(rect))
+	}
+
+	w, h := opts.Width, opts.Height
+	if w < 0 || h < 0 {
+		return nil, errors.New("width and height must not be negative")
+	}
+	if w == 0 && h == 0 {
+		return img, nil
+	}
+	b := img.Bounds()
+	if w == 0 {
+		w = max(1, b.Dx()*h/b.Dy())
+	}
+	if h == 0 {
+		h = max(1, b.Dy()*w/b.Dx())
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst, nil
+}
+
+// fitAspect 返回 r 内居中、宽高比为 ratio 的最大区域。
+func fitAspect(r image.Rectangle, ratio float64) image.Rectangle {
+	w, h := r.Dx(), r.Dy()
+	if float64(w) > float64(h)*ratio {
+		w = max(1, int(float64(h)*ratio+0.5))
+	} else {
+		h = max(1, int(float64(w)/ratio+0.5))
+	}
+	x0 := r.Min.X + (r.Dx()-w)/2
+	y0 := r.Min.Y + (r.Dy()-h)/2
+	return image.Rect(x0, y0, x0+w, y0+h)
+}
+
+// toRGBA 把图片复制为以 (0,0) 为原点的 RGBA。
+func toRGBA(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	return dst
+}
+
+// rotate 顺时针旋转 90/180/270 度。
+func rotate(src *image.RGBA, deg int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if deg != 180 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch deg {
+			case 90:
+				dx, dy = h-1-y, x
+			case 180:
+				dx, dy = w-1-x, h-1-y
+			case 270:
+				dx, dy = y, w-1-x
+			}
+			si := src.PixOffset(x, y)
+			di := dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
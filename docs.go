@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// runDocs 处理 `docs man` 子命令：为程序与每个命令生成 man 手册（section 1）。
+func runDocs(args []string) error {
+	if len(args) == 0 || args[0] != "man" {
+		return fmt.Errorf("usage: %s docs man [--dir man] [--name binary]", os.Args[0])
+	}
+	bin := filepath.Base(os.Args[0])
+	fs := newFlagSet("docs man", "[flags]", fmt.Sprintf("Write man pages for the program and each command into a directory, e.g.\n"+
+		"  %[1]s docs man --dir /usr/local/share/man/man1 && man %[1]s-publish", bin))
+	dir := fs.String("dir", "man", "output directory")
+	name := fs.String("name", bin, "name of the installed binary used in the pages")
+	_ = fs.Parse(args[1:])
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+	specs := commandSpecs()
+	date := time.Now().Format("2006-01-02")
+	var files []string
+	write := func(file string, render func(io.Writer)) error {
+		var buf bytes.Buffer
+		render(&buf)
+		path := filepath.Join(*dir, file)
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+		files = append(files, path)
+		return nil
+	}
+	if err := write(*name+".1", func(w io.Writer) { writeRootManPage(w, *name, date) }); err != nil {
+		return err
+	}
+	for _, cmd := range commands {
+		var cmdSpecs []cmdSpec
+		for _, s := range specs {
+			if s.path == cmd.name || strings.HasPrefix(s.path, cmd.name+" ") {
+				cmdSpecs = append(cmdSpecs, s)
+			}
+		}
+		if err := write(*name+"-"+cmd.name+".1", func(w io.Writer) { writeCommandManPage(w, *name, cmd, cmdSpecs, date) }); err != nil {
+			return err
+		}
+	}
+
+	output(map[string]any{"dir": *dir, "files": files}, func() {
+		for _, f := range files {
+			fmt.Println(f)
+		}
+	})
+	return nil
+}
+
+// roff 转义文本：反斜杠与连字符，以及行首的 . 与 '。
+func roff(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, ".") || strings.HasPrefix(l, "'") {
+			lines[i] = `\&` + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func writeRootManPage(w io.Writer, name, date string) {
+	fmt.Fprintf(w, ".TH \"%s\" \"1\" \"%s\" \"%s\" \"User Commands\"\n", strings.ToUpper(roff(name)), date, roff(name))
+	fmt.Fprintf(w, ".SH NAME\n%s \\- generate articles with an LLM and publish them to WeChat official account drafts\n", roff(name))
+	fmt.Fprintf(w, ".SH SYNOPSIS\n.B %s\n[\\fB\\-\\-json\\fR] \\fIcommand\\fR [\\fIflags\\fR]\n", roff(name))
+	fmt.Fprintf(w, ".SH DESCRIPTION\nRun \\fB%s help\\fR \\fIcommand\\fR or see the page of each command for its flags.\n", roff(name))
+	fmt.Fprintf(w, "Flags may also be written with a single dash.\n")
+	fmt.Fprintf(w, ".SH COMMANDS\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, ".TP\n.BR %s\\-%s (1)\n%s\n", roff(name), roff(cmd.name), roff(cmd.summary))
+	}
+	fmt.Fprintf(w, ".SH OPTIONS\n.TP\n\\fB\\-\\-json\\fR\nprint the result as a single JSON object on stdout; also accepted after the command\n")
+}
+
+func writeCommandManPage(w io.Writer, name string, cmd command, specs []cmdSpec, date string) {
+	page := name + "-" + cmd.name
+	fmt.Fprintf(w, ".TH \"%s\" \"1\" \"%s\" \"%s\" \"User Commands\"\n", strings.ToUpper(roff(page)), date, roff(name))
+	fmt.Fprintf(w, ".SH NAME\n%s \\- %s\n", roff(page), roff(cmd.summary))
+	fmt.Fprintf(w, ".SH SYNOPSIS\n")
+	for i, s := range specs {
+		if i > 0 {
+			fmt.Fprintf(w, ".br\n")
+		}
+		fmt.Fprintf(w, "\\fB%s %s\\fR %s\n", roff(name), roff(s.path), roff(s.usage))
+	}
+	for _, s := range specs {
+		if len(specs) > 1 {
+			fmt.Fprintf(w, ".SH \"%s\"\n", strings.ToUpper(roff(s.path)))
+		} else {
+			fmt.Fprintf(w, ".SH DESCRIPTION\n")
+		}
+		fmt.Fprintf(w, "%s\n", roff(s.summary))
+		if len(specs) > 1 {
+			fmt.Fprintf(w, ".PP\n")
+		} else {
+			fmt.Fprintf(w, ".SH OPTIONS\n")
+		}
+		for _, f := range s.flags() {
+			writeManFlag(w, f)
+		}
+	}
+	fmt.Fprintf(w, ".SH SEE ALSO\n.BR %s (1)\n", roff(name))
+}
+
+// writeManFlag 按 flag.PrintDefaults 的格式写出参数名、值类型、说明与非零默认值。
+func writeManFlag(w io.Writer, f *flag.Flag) {
+	kind, usage := flag.UnquoteUsage(f)
+	fmt.Fprintf(w, ".TP\n\\fB%s\\fR", roff(flagArg(f)))
+	if kind != "" {
+		fmt.Fprintf(w, " \\fI%s\\fR", roff(kind))
+	}
+	fmt.Fprintf(w, "\n%s", roff(usage))
+	if f.DefValue != "" && f.DefValue != "0" && f.DefValue != "false" {
+		if kind == "string" {
+			fmt.Fprintf(w, " (default %s)", roff(fmt.Sprintf("%q", f.DefValue)))
+		} else {
+			fmt.Fprintf(w, " (default %s)", roff(f.DefValue))
+		}
+	}
+	fmt.Fprintln(w)
+}
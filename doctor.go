@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// 诊断结果。
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// doctorCheck 为 doctor 的一项检查；Hint 为失败或警告时的处理建议，IPs 为查询到的服务器 IP。
+type doctorCheck struct {
+	Name   string   `json:"name"`
+	Status string   `json:"status"`
+	Detail string   `json:"detail"`
+	Hint   string   `json:"hint,omitempty"`
+	IPs    []string `json:"ips,omitempty"`
+}
+
+// apiHost 为微信接口域名，wecomAPIHost 为企业微信接口域名。
+const (
+	apiHost      = "api.weixin.qq.com"
+	wecomAPIHost = "qyapi.weixin.qq.com"
+)
+
+// runDoctor 诊断到微信接口的网络、凭据与 IP 白名单，并给出处理建议。
+func runDoctor(args []string) error {
+	fs := newFlagSet("doctor", "[flags]",
+		"Diagnose the connection to the WeChat API: DNS of api.weixin.qq.com, access_token with the\n"+
+			"configured app_id/app_secret, the IP whitelist (errcode 40164), API and callback server IPs,\n"+
+			"interface permissions and the draft quota. For account_type wecom only DNS, the token and the\n"+
+			"trusted IPs (errcode 60020) are checked. Exits non-zero when a check fails.")
+	configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+	ipURL := fs.String("ip-url", "", "URL that returns this machine's public IP as plain text (e.g. https://api.ipify.org), to compare the egress IP with the whitelist")
+	fs.BoolVar(&verbose, "v", false, "enable info logs")
+	fs.Parse(args)
+
+	cfg, err := publisher.LoadConfig(publisher.ResolveConfigPath(*configPath))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var checks []doctorCheck
+	add := func(name, status, detail, hint string) *doctorCheck {
+		checks = append(checks, doctorCheck{Name: name, Status: status, Detail: detail, Hint: hint})
+		return &checks[len(checks)-1]
+	}
+
+	wecom := cfg.AccountType == publisher.AccountWeCom
+	host := apiHost
+	if wecom {
+		host = wecomAPIHost
+	}
+	resolved, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		add("dns", checkFail, err.Error(), "check the DNS server and proxy settings of this machine; "+host+" must be resolvable")
+	} else {
+		add("dns", checkOK, host+" -> "+strings.Join(resolved, ", "), "")
+	}
+
+	egress := ""
+	if *ipURL != "" {
+		if egress, err = fetchEgressIP(ctx, *ipURL); err != nil {
+			add("egress_ip", checkWarn, err.Error(), "check --ip-url, or omit it; the token check still reports the IP WeChat sees")
+		}
+	}
+
+	tokenErr := publisher.CheckAccessToken(nil, cfg)
+	seen := publisher.WhitelistIP(tokenErr)
+	switch {
+	case tokenErr == nil:
+		add("token", checkOK, "access_token retrieved for "+cfg.AccountID(), "")
+	case seen != "" && wecom:
+		add("token", checkFail, tokenErr.Error(), fmt.Sprintf("add %s to 企业可信IP of the app (work.weixin.qq.com → 应用管理 → the app → 企业可信IP)", seen))
+	case seen != "":
+		add("token", checkFail, tokenErr.Error(), fmt.Sprintf("add %s to the IP whitelist (mp.weixin.qq.com → 设置与开发 → 基本配置 → IP白名单) and retry in a few minutes", seen))
+	default:
+		add("token", checkFail, tokenErr.Error(), tokenHint(tokenErr))
+	}
+	if egress != "" {
+		switch {
+		case tokenErr == nil:
+			add("egress_ip", checkOK, egress+" is allowed by the IP whitelist", "")
+		case seen == "":
+			add("egress_ip", checkSkip, egress+" (token failed for another reason)", "")
+		case seen == egress:
+			add("egress_ip", checkFail, egress+" is not in the IP whitelist", "")
+		default:
+			add("egress_ip", checkWarn, fmt.Sprintf("%s differs from %s seen by WeChat", egress, seen),
+				"requests leave through a proxy or a NAT pool; whitelist every egress IP of the pool")
+		}
+	}
+
+	if tokenErr != nil || wecom {
+		reason := "requires access_token"
+		if wecom {
+			reason = "official accounts only"
+		}
+		for _, name := range []string{"api_domain_ip", "callback_ip", "permissions", "quota"} {
+			add(name, checkSkip, reason, "")
+		}
+		return reportDoctor(checks)
+	}
+	p, err := publisher.New(cfg, nil, verbose, log.Default())
+	if err != nil {
+		return err
+	}
+
+	if ips, err := p.APIDomainIPs(ctx); err != nil {
+		add("api_domain_ip", checkWarn, err.Error(), "")
+	} else {
+		var unknown []string
+		for _, ip := range resolved {
+			if !slices.Contains(ips, ip) {
+				unknown = append(unknown, ip)
+			}
+		}
+		if len(resolved) > 0 && len(unknown) == len(resolved) {
+			add("api_domain_ip", checkWarn, fmt.Sprintf("%s resolves to %s, which is not among the %d WeChat API IPs", apiHost, strings.Join(unknown, ", "), len(ips)),
+				"DNS is hijacked or a transparent proxy is in the way; use a trusted DNS server or allow direct access to "+apiHost)
+		} else {
+			add("api_domain_ip", checkOK, fmt.Sprintf("%d WeChat API IPs", len(ips)), "").IPs = ips
+		}
+	}
+
+	if ips, err := p.CallbackIPs(ctx); err != nil {
+		add("callback_ip", checkWarn, err.Error(), "")
+	} else {
+		add("callback_ip", checkOK, fmt.Sprintf("%d callback server IPs (see --json); allow them in the firewall if the account pushes events to this server", len(ips)), "").IPs = ips
+	}
+
+	if menu, err := p.CurrentSelfMenu(ctx); err != nil {
+		add("permissions", checkWarn, err.Error(), "the account may lack interface permissions; check 接口权限 in the WeChat console (draft and publish need a verified account)")
+	} else {
+		state := "closed"
+		if menu.Open {
+			state = "open"
+		}
+		add("permissions", checkOK, fmt.Sprintf("API access works (custom menu %s, %d buttons)", state, menu.Buttons), "")
+	}
+
+	if q, err := p.GetQuota(ctx, "/cgi-bin/draft/add"); err != nil {
+		add("quota", checkWarn, err.Error(), "")
+	} else if q.Remain == 0 {
+		add("quota", checkFail, fmt.Sprintf("draft/add used %d of %d today", q.Used, q.DailyLimit), fmt.Sprintf("run '%s quota clear' (10 times a month) or wait until tomorrow", os.Args[0]))
+	} else {
+		add("quota", checkOK, fmt.Sprintf("draft/add %d of %d remaining today", q.Remain, q.DailyLimit), "")
+	}
+
+	return reportDoctor(checks)
+}
+
+// tokenHint 返回获取 access_token 失败时的处理建议。
+func tokenHint(err error) string {
+	var netErr net.Error
+	switch code := publisher.WeChatErrorCode(err); {
+	case code == 40013:
+		return "app_id is invalid; copy the AppID from 设置与开发 → 基本配置"
+	case code == 40001 || code == 40125:
+		return "app_secret is wrong or has been reset; update app_secret in the config"
+	case code == 40164:
+		return "this machine's IP is not in the IP whitelist (设置与开发 → 基本配置 → IP白名单)"
+	case code == 41004:
+		return "app_secret is empty"
+	case code == publisher.ErrCodeQuotaExceeded:
+		return fmt.Sprintf("the token quota is used up; run '%s quota clear' or wait until tomorrow", os.Args[0])
+	case code == 89503 || code == 89501:
+		return "the IP needs the account admin's confirmation; confirm the call in the WeChat console"
+	case errors.As(err, &netErr):
+		return "cannot reach " + apiHost + "; check the firewall, HTTPS_PROXY and the system clock"
+	}
+	return ""
+}
+
+// fetchEgressIP 通过返回纯文本 IP 的服务获取本机的公网出口 IP。
+func fetchEgressIP(ctx context.Context, rawURL string) (string, error) {
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("invalid --ip-url %q", rawURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return "", err
+	}
+	ip := strings.TrimSpace(string(body))
+	if resp.StatusCode != http.StatusOK || net.ParseIP(ip) == nil {
+		return "", fmt.Errorf("--ip-url returned status %d and %q, not an IP", resp.StatusCode, truncate(ip, 40))
+	}
+	return ip, nil
+}
+
+// reportDoctor 输出检查结果，有失败项时返回错误。
+func reportDoctor(checks []doctorCheck) error {
+	failed := 0
+	for _, c := range checks {
+		if c.Status == checkFail {
+			failed++
+		}
+	}
+	output(map[string]any{"checks": checks, "ok": failed == 0}, func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, c := range checks {
+			fmt.Fprintf(w, "[%s]\t%s\t%s\n", c.Status, c.Name, c.Detail)
+		}
+		w.Flush()
+		for _, c := range checks {
+			if c.Hint != "" {
+				fmt.Printf("%s: %s\n", c.Name, c.Hint)
+			}
+		}
+	})
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// exportMeta 为导出文章的 front matter，title/cover/author/digest 与 publish 读取的字段一致，可直接重新发布。
+type exportMeta struct {
+	Title     string `yaml:"title"`
+	Author    string `yaml:"author,omitempty"`
+	Digest    string `yaml:"digest,omitempty"`
+	Cover     string `yaml:"cover,omitempty"`
+	Date      string `yaml:"date"`
+	Source    string `yaml:"source"`
+	MediaID   string `yaml:"media_id"`
+	URL       string `yaml:"url,omitempty"`
+	SourceURL string `yaml:"source_url,omitempty"`
+	ThumbURL  string `yaml:"thumb_url,omitempty"`
+}
+
+// exportedArticle 为一篇导出结果。
+type exportedArticle struct {
+	Source   string   `json:"source"`
+	MediaID  string   `json:"media_id"`
+	Index    int      `json:"index"`
+	Title    string   `json:"title"`
+	Date     string   `json:"date"`
+	Path     string   `json:"path,omitempty"`
+	Images   int      `json:"images"`
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// runExport 把已发布的图文和草稿导出为 Markdown 存档：<out>/<source>/<日期>-<标题>/index.md，图片下载到同目录的 images/。
+func runExport(args []string) error {
+	fs := newFlagSet("export", "[flags]",
+		"Export published articles and drafts to a local markdown archive. Each article is written to\n"+
+			"<out>/<published|drafts>/<date>-<title>/index.md with its images and cover downloaded next\n"+
+			"to it; the front matter keeps title, author, digest and cover, so the file can be\n"+
+			"published again with the publish command.")
+	configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+	outDir := fs.String("out", "", "archive directory (default wechat-archive-<YYYYMMDD>)")
+	source := fs.String("source", "all", "what to export: published, drafts or all")
+	since := fs.String("since", "", "only export articles updated on or after this date (YYYY-MM-DD)")
+	limit := fs.Int("limit", 0, "export at most this many articles from each source (0 for all)")
+	noImages := fs.Bool("no-images", false, "keep image links instead of downloading images and covers")
+	fs.BoolVar(&verbose, "v", false, "enable info logs")
+	if rest, err := parseInterspersed(fs, args); err != nil {
+		return err
+	} else if len(rest) > 0 {
+		fs.Usage()
+		return invalidf("unexpected argument %q", rest[0])
+	}
+	var sources []string
+	switch *source {
+	case "published", "drafts":
+		sources = []string{*source}
+	case "all":
+		sources = []string{"published", "drafts"}
+	default:
+		return invalidf("--source must be published, drafts or all")
+	}
+	if *limit < 0 {
+		return invalidf("--limit must not be negative")
+	}
+	var sinceTime time.Time
+	if *since != "" {
+		var err error
+		if sinceTime, err = time.ParseInLocation("2006-01-02", *since, time.Local); err != nil {
+			return invalidf("invalid --since %q: use YYYY-MM-DD", *since)
+		}
+	}
+	if *outDir == "" {
+		*outDir = "wechat-archive-" + time.Now().Format("20060102")
+	}
+	p, err := newPublisher(*configPath)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	client := &http.Client{Timeout: 30 * time.Second}
+	var fetch func(url string) ([]byte, string, error)
+	if !*noImages {
+		fetch = func(url string) ([]byte, string, error) {
+			return publisher.DownloadImage(ctx, client, url)
+		}
+	}
+	var results []exportedArticle
+	failed := 0
+	for _, src := range sources {
+		list := p.ListPublished
+		if src == "drafts" {
+			list = p.ListDraftContents
+		}
+		dir := filepath.Join(*outDir, src)
+		used := map[string]bool{}
+		count := 0
+	pages:
+		for offset := 0; ; {
+			ctxPage, cancel := context.WithTimeout(ctx, time.Minute)
+			items, total, err := list(ctxPage, offset, 20)
+			cancel()
+			if err != nil {
+				return fmt.Errorf("list %s: %w", src, err)
+			}
+			for _, it := range items {
+				// 列表按更新时间倒序，早于 --since 的条目之后都不再需要。
+				if !sinceTime.IsZero() && it.UpdateTime.Before(sinceTime) {
+					break pages
+				}
+				for i, art := range it.Articles {
+					if art.IsDeleted {
+						continue
+					}
+					if *limit > 0 && count >= *limit {
+						break pages
+					}
+					count++
+					res := exportArticle(ctx, client, dir, used, src, it, i, fetch)
+					if res.Error != "" {
+						failed++
+						fmt.Fprintf(os.Stderr, "%s %s[%d] failed: %s\n", src, it.MediaID, i, res.Error)
+					} else {
+						for _, w := range res.Warnings {
+							fmt.Fprintf(os.Stderr, "%s: %s\n", res.Path, w)
+						}
+					}
+					results = append(results, res)
+				}
+			}
+			offset += len(items)
+			if len(items) == 0 || offset >= total {
+				break
+			}
+		}
+	}
+	if results == nil {
+		results = []exportedArticle{}
+	}
+	output(map[string]any{"out": *outDir, "articles": results, "failed": failed}, func() {
+		for _, r := range results {
+			if r.Error == "" {
+				fmt.Printf("%s  %s  %s (%d images)\n", r.Date, r.Path, truncate(r.Title, 40), r.Images)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "exported %d of %d articles to %s\n", len(results)-failed, len(results), *outDir)
+	})
+	if failed > 0 {
+		return fmt.Errorf("%d of %d articles failed to export", failed, len(results))
+	}
+	return nil
+}
+
+// exportArticle 把草稿或发布记录中的第 index 篇图文写入 dir 下的独立目录，目录名重复时加 -2、-3。
+func exportArticle(ctx context.Context, client *http.Client, dir string, used map[string]bool, source string, it publisher.DraftItem, index int, fetch func(string) ([]byte, string, error)) exportedArticle {
+	art := it.Articles[index]
+	date := it.UpdateTime.Format("2006-01-02")
+	res := exportedArticle{Source: source, MediaID: it.MediaID, Index: index, Title: art.Title, Date: date}
+	name := date + "-" + exportSlug(art.Title)
+	path := filepath.Join(dir, name)
+	for i := 2; used[path]; i++ {
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d", name, i))
+	}
+	used[path] = true
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	doc, err := publisher.ConvertWechatHTML(art.Content, path, fetch)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Images, res.Warnings = len(doc.Images), doc.Warnings
+
+	meta := exportMeta{
+		Title: art.Title, Author: art.Author, Digest: art.Digest, Date: it.UpdateTime.Format(time.RFC3339),
+		Source: source, MediaID: it.MediaID, URL: art.URL, SourceURL: art.ContentSourceURL, ThumbURL: art.ThumbURL,
+	}
+	if fetch != nil && art.ThumbURL != "" {
+		if data, ext, err := publisher.DownloadImage(ctx, client, art.ThumbURL); err != nil {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("cover %s: %v", art.ThumbURL, err))
+		} else if err := os.WriteFile(filepath.Join(path, "cover"+ext), data, 0o644); err != nil {
+			res.Error = err.Error()
+			return res
+		} else {
+			meta.Cover = "cover" + ext
+		}
+	}
+	head, err := yaml.Marshal(meta)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	file := filepath.Join(path, "index.md")
+	if err := os.WriteFile(file, []byte("---\n"+string(head)+"---\n\n"+doc.Markdown), 0o644); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Path = file
+	return res
+}
+
+// exportSlug 把标题转为目录名：保留字母与数字（含中文），其余字符合并为 "-"，最长 40 个字符。
+func exportSlug(title string) string {
+	var sb strings.Builder
+	dash := false
+	n := 0
+	for _, r := range strings.ToLower(title) {
+		if n >= 40 {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+			dash = false
+			n++
+		} else if !dash && sb.Len() > 0 {
+			sb.WriteByte('-')
+			dash = true
+			n++
+		}
+	}
+	slug := strings.TrimRight(sb.String(), "-")
+	if slug == "" {
+		return "untitled"
+	}
+	return slug
+}
@@ -3,18 +3,80 @@ package generator
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 )
 
 // Agent 负责根据 Spec 和历史/反馈生成或修订稿件。
+// chain 为按优先级排列的服务商，首个为主模型，其余在超时/429/5xx 时依次回退。
 type Agent struct {
-	llm LLMClient
+	chain       []NamedLLM
+	budget      *Budget
+	search      SearchProvider
+	searchLimit int
+	allowHTML   bool
+	history     HistorySettings
+	// sensitive 为敏感词过滤器（可空），sensitiveAuto 为 true 时命中后自动改写。
+	sensitive     *SensitiveFilter
+	sensitiveAuto bool
+	// keepReasoning 为 true 时把推理模型的思考过程记录到 Turn.Reasoning。
+	keepReasoning bool
+	// maxHistory 为每个 session 的修订轮数上限，0 表示不限制。
+	maxHistory int
+	// defaultStyle 为新 session 未指定风格时使用的风格预设。
+	defaultStyle string
 }
 
 func NewAgent(llm LLMClient) (*Agent, error) {
 	if llm == nil {
 		return nil, errors.New("llm client is required")
 	}
-	return &Agent{llm: llm}, nil
+	return &Agent{chain: []NamedLLM{{Name: "primary", Client: llm}}}, nil
+}
+
+// NewAgentChain 使用有序的服务商回退链创建 Agent。
+func NewAgentChain(chain []NamedLLM) (*Agent, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("llm client is required")
+	}
+	for _, b := range chain {
+		if b.Client == nil {
+			return nil, fmt.Errorf("llm client %s is nil", b.Name)
+		}
+	}
+	return &Agent{chain: append([]NamedLLM(nil), chain...)}, nil
+}
+
+// SetBudget 启用 session/全局用量预算；nil 表示不限制。
+func (a *Agent) SetBudget(b *Budget) {
+	a.budget = b
+}
+
+// SetDefaultStyle 设置新 session 未指定风格时使用的风格预设。
+func (a *Agent) SetDefaultStyle(key string) {
+	a.defaultStyle = key
+}
+
+// SetKeepReasoning 设置是否在 Turn 中记录推理模型的思考过程。
+func (a *Agent) SetKeepReasoning(keep bool) {
+	a.keepReasoning = keep
+}
+
+// Budget 返回当前预算（可能为 nil）。
+func (a *Agent) Budget() *Budget {
+	return a.budget
+}
+
+// Ping 向主模型发送一个极短的请求，用于健康检查；不回退、不计入预算。返回主模型名称。
+func (a *Agent) Ping(ctx context.Context) (string, error) {
+	b := a.chain[0]
+	maxTokens := 16
+	_, err := b.Client.Complete(ctx, Prompt{
+		System:   "Reply with the single word: pong",
+		User:     "ping",
+		Sampling: &SamplingParams{MaxTokens: &maxTokens},
+	})
+	return b.Name, err
 }
 
 // Generate 根据是否存在 prevDraft 决定首稿或修订流程。
@@ -25,10 +87,116 @@ func (a *Agent) Generate(ctx context.Context, spec Spec, prevDraft *Draft, histo
 	} else {
 		prompt = BuildRevisionPrompt(spec, *prevDraft, comment, history)
 	}
+	return a.generateDraft(ctx, prompt, spec)
+}
+
+// GenerateStream 与 Generate 相同，但通过 onChunk 实时回传模型输出。
+// 流式输出直接面向用户展示，因此仍使用 Markdown 而非 JSON 模式。
+func (a *Agent) GenerateStream(ctx context.Context, spec Spec, prevDraft *Draft, history []Turn, comment string, onChunk func(string)) (Draft, error) {
+	var prompt Prompt
+	if prevDraft == nil {
+		prompt = BuildInitialPrompt(spec)
+	} else {
+		prompt = BuildRevisionPrompt(spec, *prevDraft, comment, history)
+	}
+
+	raw, provider, err := a.stream(ctx, prompt, onChunk)
+	if err != nil {
+		return Draft{}, err
+	}
+	return a.finish(ctx, raw, spec, provider)
+}
+
+// PlaceImages 让模型把尚未出现在稿件中的配图插入合适位置。
+func (a *Agent) PlaceImages(ctx context.Context, spec Spec, prev Draft, images []ImageRef) (Draft, error) {
+	raw, provider, err := a.complete(ctx, BuildImagePlacementPrompt(prev, images))
+	if err != nil {
+		return Draft{}, err
+	}
+	draft, err := postProcessFrom(raw, spec, provider)
+	if err != nil {
+		return Draft{}, err
+	}
+	return a.validate(ctx, spec, draft)
+}
+
+// Outline 生成结构化大纲，供用户编辑确认后再展开全文。
+func (a *Agent) Outline(ctx context.Context, spec Spec) (Outline, error) {
+	raw, _, err := a.complete(ctx, BuildOutlinePrompt(spec))
+	if err != nil {
+		return Outline{}, err
+	}
+	return parseOutline(raw)
+}
+
+// Expand 按已确认的大纲生成全文。
+func (a *Agent) Expand(ctx context.Context, spec Spec, outline Outline) (Draft, error) {
+	return a.generateDraft(ctx, BuildExpandPrompt(spec, outline), spec)
+}
+
+// Polish 对稿件做润色，不改变结构与事实。
+func (a *Agent) Polish(ctx context.Context, spec Spec, prev Draft, focus string) (Draft, error) {
+	raw, provider, err := a.complete(ctx, BuildPolishPrompt(spec, prev, focus))
+	if err != nil {
+		return Draft{}, err
+	}
+	draft, err := postProcessFrom(raw, spec, provider)
+	if err != nil {
+		return Draft{}, err
+	}
+	return a.validate(ctx, spec, draft)
+}
+
+// RegenerateSection 只重写 heading 对应的小节，并拼回原稿，其余内容保持不变。
+func (a *Agent) RegenerateSection(ctx context.Context, spec Spec, prev Draft, heading, comment string) (Draft, error) {
+	sec, err := FindSection(prev.Markdown, heading)
+	if err != nil {
+		return Draft{}, err
+	}
+	section := strings.TrimSpace(prev.Markdown[sec.Start:sec.End])
+	raw, provider, err := a.complete(ctx, BuildSectionPrompt(spec, prev, section, comment))
+	if err != nil {
+		return Draft{}, err
+	}
+	if strings.TrimSpace(raw) == "" {
+		return Draft{}, errors.New("model returned empty section")
+	}
+	draft := prev
+	draft.Markdown = spliceSection(prev.Markdown, sec, raw)
+	draft.Provider = provider
+	draft.WordCount = CountWords(draft.Markdown)
+	return a.validate(ctx, spec, draft)
+}
+
+// DescribeImage 调用视觉模型为图片生成 alt 文本与图注。
+func (a *Agent) DescribeImage(ctx context.Context, data []byte, mimeType string) (ImageCaption, error) {
+	for _, b := range a.chain {
+		if d, ok := b.Client.(ImageDescriber); ok {
+			return d.DescribeImage(ctx, data, mimeType)
+		}
+	}
+	return ImageCaption{}, ErrVisionUnsupported
+}
+
+// finish 后处理并校验模型输出，并在字数偏离目标时自动扩写/精简。
+func (a *Agent) finish(ctx context.Context, raw string, spec Spec, provider string) (Draft, error) {
+	draft, err := postProcessFrom(raw, spec, provider)
+	if err != nil {
+		return Draft{}, err
+	}
+	if draft, err = a.validate(ctx, spec, draft); err != nil {
+		return Draft{}, err
+	}
+	return a.enforceWordCount(ctx, spec, draft), nil
+}
 
-	raw, err := a.llm.Complete(ctx, prompt)
+// postProcessFrom 执行 PostProcess 并记录产出稿件的服务商。
+func postProcessFrom(raw string, spec Spec, provider string) (Draft, error) {
+	draft, err := PostProcess(raw, spec)
 	if err != nil {
 		return Draft{}, err
 	}
-	return PostProcess(raw, spec)
+	draft.Provider = provider
+	draft.WordCount = CountWords(draft.Markdown)
+	return draft, nil
 }
@@ -0,0 +1,226 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Usage 记录一次或累计的 token 用量与费用。
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+}
+
+// Tokens 返回总 token 数。
+func (u Usage) Tokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+func (u *Usage) add(o Usage) {
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.Cost += o.Cost
+}
+
+func (u *Usage) sub(o Usage) {
+	u.PromptTokens -= o.PromptTokens
+	u.CompletionTokens -= o.CompletionTokens
+	u.Cost -= o.Cost
+}
+
+// times 返回 k 倍的用量，用于按单次用量估算多次调用。
+func (u Usage) times(k int) Usage {
+	return Usage{PromptTokens: u.PromptTokens * k, CompletionTokens: u.CompletionTokens * k, Cost: u.Cost * float64(k)}
+}
+
+// BudgetExceededError 表示 session、全局（按天）或用户（按天）预算已用尽。
+type BudgetExceededError struct {
+	Scope           string // session / daily / user
+	RemainingTokens int    // -1 表示未限制
+	RemainingCost   float64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("llm %s budget exceeded", e.Scope)
+}
+
+// BudgetConfig 描述预算限制；0 表示不限制。UserDaily* 为每个用户每天的限额（启用登录时生效）。
+// 费用按每千 token 单价计算。
+type BudgetConfig struct {
+	SessionMaxTokens     int
+	SessionMaxCost       float64
+	DailyMaxTokens       int
+	DailyMaxCost         float64
+	UserDailyMaxTokens   int
+	UserDailyMaxCost     float64
+	PricePer1KPrompt     float64
+	PricePer1KCompletion float64
+}
+
+// Budget 维护全局与各用户按天的用量，并校验 session 级限制。
+type Budget struct {
+	cfg   BudgetConfig
+	mu    sync.Mutex
+	day   string
+	daily Usage
+	users map[string]Usage
+}
+
+func NewBudget(cfg BudgetConfig) *Budget {
+	return &Budget{cfg: cfg}
+}
+
+// Check 在调用模型前校验 session、当天全局与 user 当天的用量；user 为空时不校验用户限额。
+func (b *Budget) Check(user string, session Usage) error {
+	return b.CheckProjected(user, session, Usage{})
+}
+
+// CheckProjected 与 Check 相同，但把尚未发生的用量 extra 计入 session、当天全局与用户用量，
+// 用于在并行调用前预留预算。
+func (b *Budget) CheckProjected(user string, session, extra Usage) error {
+	if b == nil {
+		return nil
+	}
+	session.add(extra)
+	if exceeded(session, b.cfg.SessionMaxTokens, b.cfg.SessionMaxCost) {
+		return &BudgetExceededError{
+			Scope:           "session",
+			RemainingTokens: remainingTokens(session, b.cfg.SessionMaxTokens),
+			RemainingCost:   remainingCost(session, b.cfg.SessionMaxCost),
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rolloverLocked()
+	daily := b.daily
+	daily.add(extra)
+	if exceeded(daily, b.cfg.DailyMaxTokens, b.cfg.DailyMaxCost) {
+		return &BudgetExceededError{
+			Scope:           "daily",
+			RemainingTokens: remainingTokens(daily, b.cfg.DailyMaxTokens),
+			RemainingCost:   remainingCost(daily, b.cfg.DailyMaxCost),
+		}
+	}
+	used := b.users[user]
+	used.add(extra)
+	if user != "" && exceeded(used, b.cfg.UserDailyMaxTokens, b.cfg.UserDailyMaxCost) {
+		return &BudgetExceededError{
+			Scope:           "user",
+			RemainingTokens: remainingTokens(used, b.cfg.UserDailyMaxTokens),
+			RemainingCost:   remainingCost(used, b.cfg.UserDailyMaxCost),
+		}
+	}
+	return nil
+}
+
+// Record 计入一次调用的用量并按单价补全费用，返回带费用的用量。
+func (b *Budget) Record(user string, u Usage) Usage {
+	if b == nil {
+		return u
+	}
+	u.Cost = float64(u.PromptTokens)/1000*b.cfg.PricePer1KPrompt + float64(u.CompletionTokens)/1000*b.cfg.PricePer1KCompletion
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rolloverLocked()
+	b.daily.add(u)
+	if user != "" {
+		used := b.users[user]
+		used.add(u)
+		b.users[user] = used
+	}
+	return u
+}
+
+// Daily 返回当天累计用量。
+func (b *Budget) Daily() Usage {
+	if b == nil {
+		return Usage{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rolloverLocked()
+	return b.daily
+}
+
+// UserDaily 返回 user 当天累计用量。
+func (b *Budget) UserDaily(user string) Usage {
+	if b == nil {
+		return Usage{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rolloverLocked()
+	return b.users[user]
+}
+
+// CarryUsage 沿用 old 当天已累计的全局与用户用量，重新加载配置时预算不会因此清零。
+func (b *Budget) CarryUsage(old *Budget) {
+	if b == nil || old == nil || b == old {
+		return
+	}
+	old.mu.Lock()
+	day, daily := old.day, old.daily
+	users := make(map[string]Usage, len(old.users))
+	for k, v := range old.users {
+		users[k] = v
+	}
+	old.mu.Unlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.day, b.daily, b.users = day, daily, users
+}
+
+func (b *Budget) rolloverLocked() {
+	today := time.Now().Format("2006-01-02")
+	if b.day != today {
+		b.day = today
+		b.daily = Usage{}
+		b.users = map[string]Usage{}
+	}
+}
+
+func exceeded(u Usage, maxTokens int, maxCost float64) bool {
+	return (maxTokens > 0 && u.Tokens() >= maxTokens) || (maxCost > 0 && u.Cost >= maxCost)
+}
+
+func remainingTokens(u Usage, maxTokens int) int {
+	if maxTokens <= 0 {
+		return -1
+	}
+	return max(maxTokens-u.Tokens(), 0)
+}
+
+func remainingCost(u Usage, maxCost float64) float64 {
+	if maxCost <= 0 {
+		return -1
+	}
+	return max(maxCost-u.Cost, 0)
+}
+
+type usageSinkKey struct{}
+
+// WithUsageSink 返回携带用量回调的 context，LLMClient 实现在调用结束后上报 token 用量。
+func WithUsageSink(ctx context.Context, sink func(Usage)) context.Context {
+	return context.WithValue(ctx, usageSinkKey{}, sink)
+}
+
+// ReportUsage 供 LLMClient 实现上报用量；context 中无回调时忽略。
+func ReportUsage(ctx context.Context, u Usage) {
+	if sink, ok := ctx.Value(usageSinkKey{}).(func(Usage)); ok && sink != nil {
+		sink(u)
+	}
+}
+
+// estimateUsage 在服务商未返回用量时按字符数粗略估算（中文约 1 字 1 token）。
+func estimateUsage(prompt Prompt, output string) Usage {
+	in := utf8.RuneCountInString(prompt.System) + utf8.RuneCountInString(prompt.User)
+	for _, h := range prompt.History {
+		in += utf8.RuneCountInString(h.Content)
+	}
+	return Usage{PromptTokens: in, CompletionTokens: utf8.RuneCountInString(output)}
+}
@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffLine 为行级差异中的一行：Op 为 ' '（相同）、'-'（删除）或 '+'（新增）。
+type DiffLine struct {
+	Op   byte
+	Text string
+}
+
+// DiffLines 按行比较 a 与 b（最长公共子序列），返回从 a 变为 b 的逐行差异。
+func DiffLines(a, b string) []DiffLine {
+	x, y := splitLines(a), splitLines(b)
+	// 先去掉公共前后缀，缩小 LCS 表。
+	pre := 0
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+	out := make([]DiffLine, 0, len(x)+len(y))
+	for _, l := range x[:pre] {
+		out = append(out, DiffLine{' ', l})
+	}
+	mx, my := x[pre:len(x)-suf], y[pre:len(y)-suf]
+	// lcs[i][j] 为 mx[i:] 与 my[j:] 的最长公共子序列长度。
+	lcs := make([][]int, len(mx)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(my)+1)
+	}
+	for i := len(mx) - 1; i >= 0; i-- {
+		for j := len(my) - 1; j >= 0; j-- {
+			if mx[i] == my[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(mx) || j < len(my) {
+		switch {
+		case i < len(mx) && j < len(my) && mx[i] == my[j]:
+			out = append(out, DiffLine{' ', mx[i]})
+			i++
+			j++
+		case i < len(mx) && (j == len(my) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, DiffLine{'-', mx[i]})
+			i++
+		default:
+			out = append(out, DiffLine{'+', my[j]})
+			j++
+		}
+	}
+	for _, l := range x[len(x)-suf:] {
+		out = append(out, DiffLine{' ', l})
+	}
+	return out
+}
+
+// UnifiedDiff 把 DiffLines 的结果格式化为 unified diff（每处改动前后保留 context 行），无差异时返回空串。
+func UnifiedDiff(fromName, toName string, lines []DiffLine, context int) string {
+	// at[i] 为第 i 行之前两侧已出现的行数，用于计算 hunk 起始行号。
+	at := make([][2]int, len(lines)+1)
+	for i, l := range lines {
+		at[i+1] = at[i]
+		if l.Op != '+' {
+			at[i+1][0]++
+		}
+		if l.Op != '-' {
+			at[i+1][1]++
+		}
+	}
+	var sb strings.Builder
+	for i := 0; i < len(lines); {
+		if lines[i].Op == ' ' {
+			i++
+			continue
+		}
+		// 相隔不超过 2*context 行的改动合并为一个 hunk。
+		last := i
+		for j := i + 1; j < len(lines) && j-last <= 2*context+1; j++ {
+			if lines[j].Op != ' ' {
+				last = j
+			}
+		}
+		start, stop := max(i-context, 0), min(last+context+1, len(lines))
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n",
+			hunkRange(at[start][0], at[stop][0]-at[start][0]),
+			hunkRange(at[start][1], at[stop][1]-at[start][1]))
+		for _, l := range lines[start:stop] {
+			sb.WriteByte(l.Op)
+			sb.WriteString(l.Text)
+			sb.WriteByte('\n')
+		}
+		i = stop
+	}
+	return sb.String()
+}
+
+// hunkRange 按 unified diff 的约定格式化行范围：空范围使用其前一行的行号。
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
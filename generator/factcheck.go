@@ -0,0 +1,101 @@
+package generator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 事实核查结论。
+const (
+	VerdictOK           = "ok"
+	VerdictDubious      = "dubious"
+	VerdictUnverifiable = "unverifiable"
+)
+
+// ClaimCheck 为一条事实性陈述的核查结果。
+type ClaimCheck struct {
+	Claim      string `json:"claim"`
+	Verdict    string `json:"verdict"`
+	Note       string `json:"note,omitempty"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// Citation 为支撑陈述的参考来源。
+type Citation struct {
+	Title string `json:"title"`
+	URL   string `json:"url,omitempty"`
+	Note  string `json:"note,omitempty"`
+}
+
+// FactCheckReport 为一次事实核查的报告。
+type FactCheckReport struct {
+	Claims    []ClaimCheck `json:"claims"`
+	Citations []Citation   `json:"citations"`
+	// Flagged 为存疑或无法确认的陈述数量。
+	Flagged int `json:"flagged"`
+	// DraftHash 标识被核查的稿件版本，稿件变化后报告即过期。
+	DraftHash string    `json:"draft_hash"`
+	CheckedAt time.Time `json:"checked_at"`
+	Provider  string    `json:"provider,omitempty"`
+}
+
+// CitationsMarkdown 把参考来源渲染为“参考资料”小节。
+func (r FactCheckReport) CitationsMarkdown() string {
+	if len(r.Citations) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("## 参考资料\n\n")
+	for i, c := range r.Citations {
+		fmt.Fprintf(&sb, "%d. ", i+1)
+		if c.URL != "" {
+			fmt.Fprintf(&sb, "[%s](%s)", c.Title, c.URL)
+		} else {
+			sb.WriteString(c.Title)
+		}
+		if c.Note != "" {
+			sb.WriteString("：" + c.Note)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// draftHash 返回稿件正文的摘要，用于判断报告是否对应当前稿件。
+func draftHash(md string) string {
+	sum := sha256.Sum256([]byte(md))
+	return hex.EncodeToString(sum[:8])
+}
+
+// FactCheck 让模型核查稿件中的事实性陈述并整理参考来源。
+func (a *Agent) FactCheck(ctx context.Context, spec Spec, draft Draft) (FactCheckReport, error) {
+	raw, provider, err := a.complete(ctx, BuildFactCheckPrompt(spec, draft))
+	if err != nil {
+		return FactCheckReport{}, err
+	}
+	var report FactCheckReport
+	if err := json.Unmarshal([]byte(extractJSON(raw, '{', '}')), &report); err != nil {
+		return FactCheckReport{}, fmt.Errorf("parse fact check: %w", err)
+	}
+	for i := range report.Claims {
+		c := &report.Claims[i]
+		c.Verdict = strings.ToLower(strings.TrimSpace(c.Verdict))
+		switch c.Verdict {
+		case VerdictOK:
+		case VerdictDubious:
+			report.Flagged++
+		default:
+			c.Verdict = VerdictUnverifiable
+			report.Flagged++
+		}
+	}
+	report.DraftHash = draftHash(draft.Markdown)
+	report.CheckedAt = time.Now()
+	report.Provider = provider
+	return report, nil
+}
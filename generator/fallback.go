@@ -0,0 +1,152 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	openai "github.com/openai/openai-go"
+)
+
+// NamedLLM 是带名称的模型客户端，名称会记录到 Turn 中以标明稿件出处。
+// Timeout 为单次调用的超时，0 表示见 attemptContext。
+type NamedLLM struct {
+	Name    string
+	Client  LLMClient
+	Timeout time.Duration
+}
+
+// StatusError 表示非 SDK 实现返回的 HTTP 错误，便于判断是否可回退。
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	Msg        string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %d %s", e.Provider, e.StatusCode, e.Msg)
+}
+
+// IsLLMError 判断 err 是否为模型（或检索）服务返回的错误，或模型调用预算已用尽。
+func IsLLMError(err error) bool {
+	var apiErr *openai.Error
+	var stErr *StatusError
+	var budgetErr *BudgetExceededError
+	return errors.As(err, &apiErr) || errors.As(err, &stErr) || errors.As(err, &budgetErr)
+}
+
+// isRetryable 判断错误是否应切换到下一个服务商：超时、429 与 5xx。ctx 为调用方的 context，
+// attempt 为单次调用的 context：单次调用超时而调用方仍未结束时回退。
+func isRetryable(ctx, attempt context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		// 调用方已取消或整体超时，不再回退。
+		return false
+	}
+	if attempt.Err() != nil {
+		return true
+	}
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return retryableStatus(apiErr.StatusCode)
+	}
+	var stErr *StatusError
+	if errors.As(err, &stErr) {
+		return retryableStatus(stErr.StatusCode)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return false
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// attemptContext 返回回退链中第 i 个服务商单次调用的 context：配置了 Timeout 时使用该时长；
+// 否则调用方有截止时间且后面还有备用服务商时，与它们平分剩余时间，使主模型超时后仍有时间回退。
+func (a *Agent) attemptContext(ctx context.Context, i int) (context.Context, context.CancelFunc) {
+	if t := a.chain[i].Timeout; t > 0 {
+		return context.WithTimeout(ctx, t)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok || i == len(a.chain)-1 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Until(deadline)/time.Duration(len(a.chain)-i))
+}
+
+// complete 依次尝试回退链中的服务商，返回输出及产出该输出的服务商名称。
+func (a *Agent) complete(ctx context.Context, prompt Prompt) (string, string, error) {
+	var lastErr error
+	for i, b := range a.chain {
+		reported := false
+		actx, cancel := a.attemptContext(ctx, i)
+		cctx := WithUsageSink(actx, func(u Usage) {
+			reported = true
+			ReportUsage(ctx, u)
+		})
+		raw, err := b.Client.Complete(cctx, prompt)
+		retry := isRetryable(ctx, actx, err)
+		cancel()
+		if err == nil {
+			if !reported {
+				ReportUsage(ctx, estimateUsage(prompt, raw))
+			}
+			content, reasoning := SplitReasoning(raw)
+			ReportReasoning(ctx, reasoning)
+			return content, b.Name, nil
+		}
+		lastErr = err
+		if i == len(a.chain)-1 || !retry {
+			break
+		}
+		log.Printf("[llm] provider %s failed (%v); falling back to %s", b.Name, err, a.chain[i+1].Name)
+	}
+	return "", "", lastErr
+}
+
+// stream 与 complete 相同；一旦已向调用方输出内容则不再回退，避免重复片段。
+func (a *Agent) stream(ctx context.Context, prompt Prompt, onChunk func(string)) (string, string, error) {
+	var lastErr error
+	for i, b := range a.chain {
+		emitted, reported := false, false
+		actx, cancel := a.attemptContext(ctx, i)
+		cctx := WithUsageSink(actx, func(u Usage) {
+			reported = true
+			ReportUsage(ctx, u)
+		})
+		// 推理片段不转发给调用方，也不计入“已输出”。
+		filter := newThinkFilter(func(chunk string) {
+			emitted = true
+			if onChunk != nil {
+				onChunk(chunk)
+			}
+		})
+		raw, err := b.Client.Stream(cctx, prompt, filter.write)
+		retry := isRetryable(ctx, actx, err)
+		cancel()
+		if err == nil {
+			if !reported {
+				ReportUsage(ctx, estimateUsage(prompt, raw))
+			}
+			content, reasoning := SplitReasoning(raw)
+			ReportReasoning(ctx, reasoning)
+			return content, b.Name, nil
+		}
+		lastErr = err
+		if emitted || i == len(a.chain)-1 || !retry {
+			break
+		}
+		log.Printf("[llm] provider %s stream failed (%v); falling back to %s", b.Name, err, a.chain[i+1].Name)
+	}
+	return "", "", lastErr
+}
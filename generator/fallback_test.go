@@ -0,0 +1,108 @@
+package generator
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// hangingLLM 在 context 结束前一直不返回，模拟无响应的服务商。
+type hangingLLM struct{}
+
+func (hangingLLM) Complete(ctx context.Context, prompt Prompt) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func (hangingLLM) Stream(ctx context.Context, prompt Prompt, onChunk func(string)) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+// staticLLM 立即返回固定文本。
+type staticLLM string
+
+func (s staticLLM) Complete(ctx context.Context, prompt Prompt) (string, error) {
+	return string(s), nil
+}
+
+func (s staticLLM) Stream(ctx context.Context, prompt Prompt, onChunk func(string)) (string, error) {
+	onChunk(string(s))
+	return string(s), nil
+}
+
+func TestFallbackAfterPrimaryTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration // 主模型的单次超时，0 表示平分调用方的剩余时间
+		parent  time.Duration // 调用方的截止时间，0 表示没有
+	}{
+		{name: "share of deadline", parent: 400 * time.Millisecond},
+		{name: "provider timeout", timeout: 100 * time.Millisecond},
+		{name: "provider timeout within deadline", timeout: 100 * time.Millisecond, parent: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent, err := NewAgentChain([]NamedLLM{
+				{Name: "primary", Client: hangingLLM{}, Timeout: tt.timeout},
+				{Name: "backup", Client: staticLLM("ok")},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			newCtx := func() (context.Context, context.CancelFunc) {
+				if tt.parent > 0 {
+					return context.WithTimeout(context.Background(), tt.parent)
+				}
+				return context.WithCancel(context.Background())
+			}
+
+			ctx, cancel := newCtx()
+			defer cancel()
+			out, name, err := agent.complete(ctx, Prompt{User: "hi"})
+			if err != nil || out != "ok" || name != "backup" {
+				t.Fatalf("complete = %q, %q, %v; want ok from backup", out, name, err)
+			}
+
+			ctx, cancel = newCtx()
+			defer cancel()
+			var chunks string
+			out, name, err = agent.stream(ctx, Prompt{User: "hi"}, func(c string) { chunks += c })
+			if err != nil || out != "ok" || name != "backup" || chunks != "ok" {
+				t.Fatalf("stream = %q, %q, %v (chunks %q); want ok from backup", out, name, err, chunks)
+			}
+		})
+	}
+}
+
+// countingLLM 记录被调用的次数。
+type countingLLM struct{ calls int }
+
+func (c *countingLLM) Complete(ctx context.Context, prompt Prompt) (string, error) {
+	c.calls++
+	return "ok", nil
+}
+
+func (c *countingLLM) Stream(ctx context.Context, prompt Prompt, onChunk func(string)) (string, error) {
+	c.calls++
+	return "ok", nil
+}
+
+func TestNoFallbackWhenCallerCanceled(t *testing.T) {
+	backup := &countingLLM{}
+	agent, err := NewAgentChain([]NamedLLM{
+		{Name: "primary", Client: hangingLLM{}, Timeout: time.Second},
+		{Name: "backup", Client: backup},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, _, err := agent.complete(ctx, Prompt{User: "hi"}); err == nil {
+		t.Fatal("complete succeeded; want the cancellation error")
+	}
+	if backup.calls != 0 {
+		t.Fatalf("backup called %d times after the caller canceled", backup.calls)
+	}
+}
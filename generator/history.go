@@ -0,0 +1,121 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+// 修订历史压缩的默认阈值。
+const (
+	defaultHistoryMaxTurns   = 10
+	defaultHistoryKeepRecent = 4
+	defaultHistoryMaxRunes   = 3000
+)
+
+// TurnHistorySummary 标记提示词中代替早期轮次的“编辑历史摘要”，不会写入 Session.History。
+const TurnHistorySummary TurnKind = "编辑历史摘要"
+
+// HistorySettings 配置修订历史压缩：未压缩的反馈超过 MaxTurns 条或 MaxRunes 字时，
+// 把除最近 KeepRecent 条以外的轮次概括为一条“编辑历史摘要”。0 表示使用默认值。
+type HistorySettings struct {
+	MaxTurns   int
+	KeepRecent int
+	MaxRunes   int
+}
+
+func (h HistorySettings) withDefaults() HistorySettings {
+	if h.MaxTurns <= 0 {
+		h.MaxTurns = defaultHistoryMaxTurns
+	}
+	if h.KeepRecent <= 0 {
+		h.KeepRecent = defaultHistoryKeepRecent
+	}
+	if h.KeepRecent >= h.MaxTurns {
+		h.KeepRecent = h.MaxTurns - 1
+	}
+	if h.MaxRunes <= 0 {
+		h.MaxRunes = defaultHistoryMaxRunes
+	}
+	return h
+}
+
+// SetHistory 设置修订历史压缩阈值。
+func (a *Agent) SetHistory(cfg HistorySettings) {
+	a.history = cfg.withDefaults()
+}
+
+// SetMaxHistory 设置每个 session 的修订轮数上限，0 表示不限制。
+func (a *Agent) SetMaxHistory(n int) {
+	a.maxHistory = max(n, 0)
+}
+
+// HistoryLimitError 表示 session 的修订轮数已达上限，需要基于当前稿件新建 session 继续。
+type HistoryLimitError struct {
+	Max int
+}
+
+func (e *HistoryLimitError) Error() string {
+	return fmt.Sprintf("session reached the limit of %d revisions; start a new session from the current draft", e.Max)
+}
+
+// checkHistory 在记录新一轮之前检查修订轮数上限。
+func (s *Session) checkHistory() error {
+	if limit := s.agent.maxHistory; limit > 0 && len(s.History) >= limit {
+		return &HistoryLimitError{Max: limit}
+	}
+	return nil
+}
+
+// needsCompaction 判断未压缩的轮次是否超过阈值。
+func (h HistorySettings) needsCompaction(turns []Turn) bool {
+	n, runes := 0, 0
+	for _, t := range turns {
+		if t.Comment == "" {
+			continue
+		}
+		n++
+		runes += utf8.RuneCountInString(t.Comment)
+	}
+	return n > h.KeepRecent && (n > h.MaxTurns || runes > h.MaxRunes)
+}
+
+// SummarizeHistory 把 prev（上次的摘要，可空）与 turns 合并概括为新的编辑历史摘要。
+func (a *Agent) SummarizeHistory(ctx context.Context, spec Spec, prev string, turns []Turn) (string, error) {
+	raw, _, err := a.complete(ctx, BuildHistorySummaryPrompt(spec, prev, turns))
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(stripCodeFence(raw))
+	if summary == "" {
+		return "", errors.New("model returned empty history summary")
+	}
+	return summary, nil
+}
+
+// promptHistory 返回修订提示词使用的历史：超过阈值时先把较早的轮次压缩进 HistorySummary，
+// 返回的切片以一条“编辑历史摘要”开头，后接尚未压缩的轮次。压缩失败时退回完整历史。
+func (s *Session) promptHistory(ctx context.Context) []Turn {
+	recent := s.History[s.compacted:]
+	if h := s.agent.history.withDefaults(); h.needsCompaction(recent) {
+		cut := len(recent) - h.KeepRecent
+		summary, err := s.agent.SummarizeHistory(ctx, s.spec(), s.HistorySummary, recent[:cut])
+		if err != nil {
+			log.Printf("[History] session=%s compaction failed: %v", s.ID, err)
+			return s.History
+		}
+		s.HistorySummary = summary
+		s.compacted += cut
+		recent = s.History[s.compacted:]
+		log.Printf("[History] session=%s compacted %d turns", s.ID, s.compacted)
+	}
+	if s.HistorySummary == "" {
+		return recent
+	}
+	turns := make([]Turn, 0, len(recent)+1)
+	turns = append(turns, Turn{Comment: string(TurnHistorySummary) + "：\n" + s.HistorySummary, Kind: TurnHistorySummary})
+	return append(turns, recent...)
+}
@@ -0,0 +1,254 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCalendarPath 为未配置 calendar_path 时的内容日历文件。
+	DefaultCalendarPath = "calendar.json"
+	// maxIdeas 为单次选题的最大数量。
+	maxIdeas = 20
+	// calendarDateLayout 为计划发布日期格式。
+	calendarDateLayout = "2006-01-02"
+)
+
+// 内容日历条目状态。
+const (
+	IdeaPlanned  = "planned"
+	IdeaDrafting = "drafting"
+)
+
+// ErrIdeaNotFound 表示日历中不存在该选题。
+var ErrIdeaNotFound = errors.New("idea not found")
+
+// IdeaRequest 描述一次选题：面向的读者与领域。
+type IdeaRequest struct {
+	Audience string
+	Domain   string
+	Count    int
+	Style    string
+	// Start 为首篇计划发布日期，之后每隔 IntervalDays 天排一篇。
+	Start        time.Time
+	IntervalDays int
+	// User 为发起请求的用户，用量计入其当天预算（可空）。
+	User string
+}
+
+// Idea 为一个选题及其建议大纲；保存到内容日历后带有 ID 与状态。
+type Idea struct {
+	ID          string   `json:"id,omitempty"`
+	Title       string   `json:"title"`
+	Angle       string   `json:"angle,omitempty"`
+	Outline     []string `json:"outline,omitempty"`
+	Score       float64  `json:"score"`
+	Reason      string   `json:"reason,omitempty"`
+	PublishDate string   `json:"publish_date,omitempty"`
+	Status      string   `json:"status,omitempty"`
+	SessionID   string   `json:"session_id,omitempty"`
+}
+
+// GenerateIdeas 让模型按读者与领域给出打分的选题，按得分排序后依次排期。
+func (a *Agent) GenerateIdeas(ctx context.Context, req IdeaRequest) ([]Idea, error) {
+	if strings.TrimSpace(req.Audience) == "" && strings.TrimSpace(req.Domain) == "" {
+		return nil, errors.New("audience or domain is required")
+	}
+	if req.Count <= 0 || req.Count > maxIdeas {
+		return nil, fmt.Errorf("count must be between 1 and %d", maxIdeas)
+	}
+	if req.IntervalDays <= 0 {
+		req.IntervalDays = 7
+	}
+	if req.Start.IsZero() {
+		req.Start = time.Now().AddDate(0, 0, 1)
+	}
+	// 选题不属于任何 session，用量只计入当天的全局与用户预算。
+	if err := a.budget.Check(req.User, Usage{}); err != nil {
+		return nil, err
+	}
+	ctx = WithUsageSink(ctx, func(u Usage) { a.budget.Record(req.User, u) })
+
+	raw, _, err := a.complete(ctx, BuildIdeasPrompt(req))
+	if err != nil {
+		return nil, err
+	}
+	var ideas []Idea
+	if err := json.Unmarshal([]byte(extractJSON(raw, '[', ']')), &ideas); err != nil {
+		return nil, fmt.Errorf("parse ideas: %w", err)
+	}
+	kept := ideas[:0]
+	for _, idea := range ideas {
+		idea.Title = strings.TrimSpace(idea.Title)
+		if idea.Title == "" {
+			continue
+		}
+		idea.ID, idea.Status, idea.SessionID = "", "", ""
+		kept = append(kept, idea)
+	}
+	if len(kept) == 0 {
+		return nil, errors.New("model returned no ideas")
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Score > kept[j].Score })
+	if len(kept) > req.Count {
+		kept = kept[:req.Count]
+	}
+	for i := range kept {
+		kept[i].PublishDate = req.Start.AddDate(0, 0, i*req.IntervalDays).Format(calendarDateLayout)
+	}
+	return kept, nil
+}
+
+// CalendarStore 把内容日历保存为单个 JSON 文件。
+type CalendarStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewCalendarStore 创建内容日历存储，文件不存在时视为空日历。
+func NewCalendarStore(path string) *CalendarStore {
+	if path == "" {
+		path = DefaultCalendarPath
+	}
+	return &CalendarStore{path: path}
+}
+
+// List 返回全部条目，按计划发布日期排序。
+func (c *CalendarStore) List() ([]Idea, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.readLocked()
+}
+
+// Add 为选题分配 ID、标记为 planned 并写入日历，返回写入后的条目。
+func (c *CalendarStore) Add(ideas []Idea) ([]Idea, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	items, err := c.readLocked()
+	if err != nil {
+		return nil, err
+	}
+	base := time.Now().UnixNano()
+	added := make([]Idea, len(ideas))
+	for i, idea := range ideas {
+		idea.ID = strconv.FormatInt(base+int64(i), 36)
+		idea.Status = IdeaPlanned
+		added[i] = idea
+	}
+	if err := c.writeLocked(append(items, added...)); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// Get 按 ID 读取条目。
+func (c *CalendarStore) Get(id string) (Idea, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	items, err := c.readLocked()
+	if err != nil {
+		return Idea{}, err
+	}
+	for _, it := range items {
+		if it.ID == id {
+			return it, nil
+		}
+	}
+	return Idea{}, ErrIdeaNotFound
+}
+
+// Update 在锁内修改单个条目并保存。
+func (c *CalendarStore) Update(id string, fn func(*Idea) error) (Idea, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	items, err := c.readLocked()
+	if err != nil {
+		return Idea{}, err
+	}
+	for i := range items {
+		if items[i].ID != id {
+			continue
+		}
+		if err := fn(&items[i]); err != nil {
+			return Idea{}, err
+		}
+		if err := validatePublishDate(items[i].PublishDate); err != nil {
+			return Idea{}, err
+		}
+		updated := items[i]
+		return updated, c.writeLocked(items)
+	}
+	return Idea{}, ErrIdeaNotFound
+}
+
+// Delete 从日历中移除条目。
+func (c *CalendarStore) Delete(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	items, err := c.readLocked()
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		if items[i].ID == id {
+			return c.writeLocked(append(items[:i], items[i+1:]...))
+		}
+	}
+	return ErrIdeaNotFound
+}
+
+func (c *CalendarStore) readLocked() ([]Idea, error) {
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var items []Idea
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("calendar %s: %w", c.path, err)
+	}
+	return items, nil
+}
+
+func (c *CalendarStore) writeLocked(items []Idea) error {
+	sort.SliceStable(items, func(i, j int) bool { return items[i].PublishDate < items[j].PublishDate })
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(c.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create calendar dir: %w", err)
+		}
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func validatePublishDate(s string) error {
+	if s == "" {
+		return nil
+	}
+	if _, err := time.Parse(calendarDateLayout, s); err != nil {
+		return fmt.Errorf("invalid publish_date %q: use YYYY-MM-DD", s)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ImageGenerator 抽象文生图能力，用于生成封面等配图，便于替换/Mock。
+type ImageGenerator interface {
+	GenerateImage(ctx context.Context, prompt string) ([]byte, error)
+}
+
+// ImageSettings 提供给文生图实现的基础配置。
+type ImageSettings struct {
+	Provider string
+	Model    string
+	APIKey   string
+	BaseURL  string
+	Size     string
+}
+
+// BuildCoverImagePrompt 根据标题/摘要生成封面提示词。
+func BuildCoverImagePrompt(title, digest string) string {
+	var sb strings.Builder
+	sb.WriteString("为一篇微信公众号文章生成横版封面插画（宽高比约 2.35:1，主体居中）。\n")
+	sb.WriteString("要求：画面简洁、有质感，不要出现任何文字、字母或水印。\n")
+	sb.WriteString(fmt.Sprintf("文章标题：%s\n", strings.TrimSpace(title)))
+	if d := strings.TrimSpace(digest); d != "" {
+		sb.WriteString(fmt.Sprintf("文章摘要：%s\n", d))
+	}
+	return sb.String()
+}
@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	openai "github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// OpenAIImageGenerator implements ImageGenerator using the OpenAI Images API.
+type OpenAIImageGenerator struct {
+	Model string
+	Size  string
+	Opts  []option.RequestOption
+}
+
+func NewOpenAIImageGeneratorFromConfig(cfg *ImageSettings) (*OpenAIImageGenerator, error) {
+	if cfg == nil {
+		return nil, errors.New("image config is nil")
+	}
+	if cfg.APIKey == "" {
+		return nil, errors.New("openai api key missing; provide image.api_key or llm.api_key")
+	}
+	model := cfg.Model
+	if model == "" {
+		model = string(openai.ImageModelGPTImage1)
+	}
+	size := cfg.Size
+	if size == "" {
+		size = string(openai.ImageGenerateParamsSize1536x1024)
+	}
+	opts := []option.RequestOption{option.WithAPIKey(cfg.APIKey)}
+	if cfg.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(cfg.BaseURL))
+	}
+	return &OpenAIImageGenerator{Model: model, Size: size, Opts: opts}, nil
+}
+
+func (o *OpenAIImageGenerator) GenerateImage(ctx context.Context, prompt string) ([]byte, error) {
+	client := openai.NewClient(o.Opts...)
+
+	params := openai.ImageGenerateParams{
+		Prompt: prompt,
+		Model:  openai.ImageModel(o.Model),
+		N:      openai.Int(1),
+		Size:   openai.ImageGenerateParamsSize(o.Size),
+	}
+	// gpt-image-1 固定返回 base64，不接受 response_format。
+	if o.Model != string(openai.ImageModelGPTImage1) {
+		params.ResponseFormat = openai.ImageGenerateParamsResponseFormatB64JSON
+	}
+	resp, err := client.Images.Generate(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, errors.New("openai: empty image data")
+	}
+	img := resp.Data[0]
+	if img.B64JSON != "" {
+		return base64.StdEncoding.DecodeString(img.B64JSON)
+	}
+	if img.URL != "" {
+		return downloadImage(ctx, img.URL)
+	}
+	return nil, errors.New("openai: image has neither b64_json nor url")
+}
+
+func downloadImage(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download image: status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
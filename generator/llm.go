@@ -5,6 +5,8 @@ import "context"
 // LLMClient 抽象大模型客户端，便于替换/Mock。
 type LLMClient interface {
 	Complete(ctx context.Context, prompt Prompt) (string, error)
+	// Stream 与 Complete 相同，但在模型产出时逐段回调 onChunk，返回完整文本。
+	Stream(ctx context.Context, prompt Prompt, onChunk func(chunk string)) (string, error)
 }
 
 // LLMSettings 提供给具体实现的基础配置。
@@ -13,4 +15,25 @@ type LLMSettings struct {
 	Model    string
 	APIKey   string
 	BaseURL  string
+	// VisionModel 可选，图片理解使用的模型。
+	VisionModel string
+	// Azure OpenAI 专用：部署名、api-version 与认证方式（key / aad）。
+	Deployment string
+	APIVersion string
+	AuthMode   string
+	// Sampling 为默认采样参数，可被 session 级 Spec.Sampling 覆盖。
+	Sampling SamplingParams
+	// JSONMode 表示服务商支持 JSON 模式（OpenAI 的 response_format、Gemini 的 responseMimeType）；
+	// nil 时按服务商取默认值，见 jsonMode。
+	JSONMode *bool
+	// Fixtures 仅 mock 使用：预设响应文件（JSON/YAML），为空时使用内置响应。
+	Fixtures string
+}
+
+// jsonMode 返回是否使用服务商的 JSON 模式：配置了 JSONMode 时以配置为准，否则为 def。
+func (c *LLMSettings) jsonMode(def bool) bool {
+	if c.JSONMode != nil {
+		return *c.JSONMode
+	}
+	return def
 }
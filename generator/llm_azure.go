@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/openai/openai-go/azure"
+	"github.com/openai/openai-go/option"
+)
+
+// AzureDefaultAPIVersion 为未配置 api_version 时使用的版本。
+const AzureDefaultAPIVersion = "2024-10-21"
+
+// NewAzureOpenAILLM 创建 Azure OpenAI 客户端。
+// Azure 按部署名路由：Deployment（为空时取 Model）会写入 /openai/deployments/{name}/ 路径，
+// 并自动附加 api-version 查询参数。AuthMode 为 "aad" 时使用 Azure AD（DefaultAzureCredential），
+// 否则使用 api-key。
+func NewAzureOpenAILLM(cfg *LLMSettings) (*OpenAILLM, error) {
+	if cfg == nil {
+		return nil, errors.New("llm config is nil")
+	}
+	if cfg.BaseURL == "" {
+		return nil, errors.New("azure openai requires base_url (e.g. https://{resource}.openai.azure.com)")
+	}
+	deployment := cfg.Deployment
+	if deployment == "" {
+		deployment = cfg.Model
+	}
+	if deployment == "" {
+		return nil, errors.New("azure openai requires deployment (or model as deployment name)")
+	}
+	apiVersion := cfg.APIVersion
+	if apiVersion == "" {
+		apiVersion = AzureDefaultAPIVersion
+	}
+
+	opts := []option.RequestOption{azure.WithEndpoint(cfg.BaseURL, apiVersion)}
+	switch cfg.AuthMode {
+	case "", "key":
+		if cfg.APIKey == "" {
+			return nil, errors.New("azure openai api key missing; provide llm.api_key or set auth to aad")
+		}
+		opts = append(opts, azure.WithAPIKey(cfg.APIKey))
+	case "aad":
+		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, fmt.Errorf("azure ad credential: %w", err)
+		}
+		opts = append(opts, azure.WithTokenCredential(cred))
+	default:
+		return nil, fmt.Errorf("azure auth mode %s not supported (use key or aad)", cfg.AuthMode)
+	}
+
+	return &OpenAILLM{Model: deployment, VisionModel: cfg.VisionModel, Sampling: cfg.Sampling, JSONMode: cfg.jsonMode(true), Opts: opts}, nil
+}
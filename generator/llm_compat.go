@@ -0,0 +1,38 @@
+package generator
+
+import "errors"
+
+// OpenAI 兼容服务商的默认接入点与模型。
+const (
+	DeepSeekBaseURL      = "https://api.deepseek.com/v1"
+	DeepSeekDefaultModel = "deepseek-chat"
+	QwenBaseURL          = "https://dashscope.aliyuncs.com/compatible-mode/v1"
+	QwenDefaultModel     = "qwen-plus"
+)
+
+// NewDeepSeekLLM 创建 DeepSeek 客户端；base_url/model 为空时使用官方默认值。
+func NewDeepSeekLLM(cfg *LLMSettings) (*OpenAILLM, error) {
+	return newCompatLLM(cfg, DeepSeekBaseURL, DeepSeekDefaultModel, "deepseek api key missing; provide llm.api_key")
+}
+
+// NewQwenLLM 创建通义千问（DashScope 兼容模式）客户端。
+func NewQwenLLM(cfg *LLMSettings) (*OpenAILLM, error) {
+	return newCompatLLM(cfg, QwenBaseURL, QwenDefaultModel, "dashscope api key missing; provide llm.api_key")
+}
+
+func newCompatLLM(cfg *LLMSettings, baseURL, model, keyErr string) (*OpenAILLM, error) {
+	if cfg == nil {
+		return nil, errors.New("llm config is nil")
+	}
+	if cfg.APIKey == "" {
+		return nil, errors.New(keyErr)
+	}
+	settings := *cfg
+	if settings.BaseURL == "" {
+		settings.BaseURL = baseURL
+	}
+	if settings.Model == "" {
+		settings.Model = model
+	}
+	return NewOpenAILLMFromConfig(&settings)
+}
@@ -0,0 +1,325 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GeminiBaseURL 为 Gemini API 默认地址。
+const (
+	GeminiBaseURL      = "https://generativelanguage.googleapis.com/v1beta"
+	GeminiDefaultModel = "gemini-2.0-flash"
+)
+
+// GeminiLLM implements LLMClient using the Gemini generateContent REST API.
+type GeminiLLM struct {
+	Model       string
+	VisionModel string
+	Sampling    SamplingParams
+	APIKey      string
+	BaseURL     string
+	// JSONMode 为 true 时 JSON 请求设置 responseMimeType: application/json。
+	JSONMode bool
+	client   *http.Client
+}
+
+func NewGeminiLLMFromConfig(cfg *LLMSettings) (*GeminiLLM, error) {
+	if cfg == nil {
+		return nil, errors.New("llm config is nil")
+	}
+	if cfg.APIKey == "" {
+		return nil, errors.New("gemini api key missing; provide llm.api_key")
+	}
+	model := cfg.Model
+	if model == "" {
+		model = GeminiDefaultModel
+	}
+	base := strings.TrimRight(cfg.BaseURL, "/")
+	if base == "" {
+		base = GeminiBaseURL
+	}
+	return &GeminiLLM{
+		Model:       model,
+		VisionModel: cfg.VisionModel,
+		Sampling:    cfg.Sampling,
+		APIKey:      cfg.APIKey,
+		BaseURL:     base,
+		JSONMode:    cfg.jsonMode(true),
+		client:      &http.Client{Timeout: 180 * time.Second},
+	}, nil
+}
+
+type geminiPart struct {
+	Text       string            `json:"text,omitempty"`
+	InlineData *geminiInlineData `json:"inline_data,omitempty"`
+	// Thought 为 true 表示该片段是思考模型的推理摘要，不属于正文。
+	Thought bool `json:"thought,omitempty"`
+}
+
+type geminiInlineData struct {
+	MimeType string `json:"mime_type"`
+	Data     string `json:"data"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent          `json:"system_instruction,omitempty"`
+	Contents          []geminiContent         `json:"contents"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"topP,omitempty"`
+	MaxOutputTokens  *int     `json:"maxOutputTokens,omitempty"`
+	PresencePenalty  *float64 `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequencyPenalty,omitempty"`
+	ResponseMimeType string   `json:"responseMimeType,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content geminiContent `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata,omitempty"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error,omitempty"`
+}
+
+func (r geminiResponse) text() string {
+	text, _ := r.split()
+	return text
+}
+
+// split 分别拼接正文片段与推理片段（thought=true）。
+func (r geminiResponse) split() (text, thoughts string) {
+	if len(r.Candidates) == 0 {
+		return "", ""
+	}
+	var sb, tb strings.Builder
+	for _, p := range r.Candidates[0].Content.Parts {
+		if p.Thought {
+			tb.WriteString(p.Text)
+			continue
+		}
+		sb.WriteString(p.Text)
+	}
+	return sb.String(), tb.String()
+}
+
+// reportUsage 上报 usageMetadata（流式时为最后一个分片中的累计值）。
+func (r geminiResponse) reportUsage(ctx context.Context) {
+	if r.UsageMetadata == nil {
+		return
+	}
+	ReportUsage(ctx, Usage{PromptTokens: r.UsageMetadata.PromptTokenCount, CompletionTokens: r.UsageMetadata.CandidatesTokenCount})
+}
+
+// buildGeminiRequest 映射 Prompt：System 走 system_instruction，assistant 历史映射为 model 角色。
+func buildGeminiRequest(prompt Prompt, defaults SamplingParams) geminiRequest {
+	req := geminiRequest{}
+	sp := defaults.Merge(prompt.Sampling)
+	if sp != (SamplingParams{}) {
+		req.GenerationConfig = &geminiGenerationConfig{
+			Temperature:      sp.Temperature,
+			TopP:             sp.TopP,
+			MaxOutputTokens:  sp.MaxTokens,
+			PresencePenalty:  sp.PresencePenalty,
+			FrequencyPenalty: sp.FrequencyPenalty,
+		}
+	}
+	if prompt.JSON {
+		if req.GenerationConfig == nil {
+			req.GenerationConfig = &geminiGenerationConfig{}
+		}
+		req.GenerationConfig.ResponseMimeType = "application/json"
+	}
+	if prompt.System != "" {
+		req.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: prompt.System}}}
+	}
+	for _, h := range prompt.History {
+		role := "user"
+		if h.Role == "assistant" {
+			role = "model"
+		}
+		req.Contents = append(req.Contents, geminiContent{Role: role, Parts: []geminiPart{{Text: h.Content}}})
+	}
+	req.Contents = append(req.Contents, geminiContent{Role: "user", Parts: []geminiPart{{Text: prompt.User}}})
+	return req
+}
+
+// endpoint 返回接口地址；API key 通过 x-goog-api-key 请求头发送，不放在 URL 中，避免随 *url.Error 出现在错误信息与日志里。
+func (g *GeminiLLM) endpoint(model, method string, extra url.Values) string {
+	u := fmt.Sprintf("%s/models/%s:%s", g.BaseURL, url.PathEscape(model), method)
+	if len(extra) > 0 {
+		u += "?" + extra.Encode()
+	}
+	return u
+}
+
+func (g *GeminiLLM) post(ctx context.Context, endpoint string, body geminiRequest) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", g.APIKey)
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		var gr geminiResponse
+		raw, _ := io.ReadAll(resp.Body)
+		msg := strings.TrimSpace(string(raw))
+		if json.Unmarshal(raw, &gr) == nil && gr.Error != nil {
+			msg = gr.Error.Message
+		}
+		return nil, &StatusError{Provider: "gemini", StatusCode: resp.StatusCode, Msg: msg}
+	}
+	return resp, nil
+}
+
+func (g *GeminiLLM) generate(ctx context.Context, model string, body geminiRequest) (string, error) {
+	resp, err := g.post(ctx, g.endpoint(model, "generateContent", nil), body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var gr geminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&gr); err != nil {
+		return "", err
+	}
+	text, thoughts := gr.split()
+	if text == "" {
+		return "", errors.New("gemini: empty candidates")
+	}
+	gr.reportUsage(ctx)
+	ReportReasoning(ctx, thoughts)
+	return text, nil
+}
+
+// request 组装请求；未开启 JSONMode 时不设置 responseMimeType，由调用方从文本中解析 JSON。
+func (g *GeminiLLM) request(prompt Prompt) geminiRequest {
+	prompt.JSON = prompt.JSON && g.JSONMode
+	return buildGeminiRequest(prompt, g.Sampling)
+}
+
+// jsonModeRejected 判断请求是否因 responseMimeType 被拒绝：模型不支持 JSON 输出时返回 400；
+// 返回 true 时去掉 responseMimeType，供调用方重试一次。
+func (g *GeminiLLM) jsonModeRejected(body *geminiRequest, err error) bool {
+	var stErr *StatusError
+	if body.GenerationConfig == nil || body.GenerationConfig.ResponseMimeType == "" ||
+		!errors.As(err, &stErr) || stErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	log.Printf("[llm] %s rejected responseMimeType (%v); retrying without JSON mode", g.Model, err)
+	body.GenerationConfig.ResponseMimeType = ""
+	return true
+}
+
+func (g *GeminiLLM) Complete(ctx context.Context, prompt Prompt) (string, error) {
+	body := g.request(prompt)
+	text, err := g.generate(ctx, g.Model, body)
+	if g.jsonModeRejected(&body, err) {
+		text, err = g.generate(ctx, g.Model, body)
+	}
+	return text, err
+}
+
+func (g *GeminiLLM) Stream(ctx context.Context, prompt Prompt, onChunk func(chunk string)) (string, error) {
+	endpoint := g.endpoint(g.Model, "streamGenerateContent", url.Values{"alt": {"sse"}})
+	body := g.request(prompt)
+	resp, err := g.post(ctx, endpoint, body)
+	if g.jsonModeRejected(&body, err) {
+		resp, err = g.post(ctx, endpoint, body)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var sb, reasoning strings.Builder
+	var last geminiResponse
+	defer func() {
+		last.reportUsage(ctx)
+		ReportReasoning(ctx, reasoning.String())
+	}()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		var gr geminiResponse
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &gr); err != nil {
+			continue
+		}
+		if gr.Error != nil {
+			return "", fmt.Errorf("gemini: %s", gr.Error.Message)
+		}
+		if gr.UsageMetadata != nil {
+			last = gr
+		}
+		delta, thoughts := gr.split()
+		reasoning.WriteString(thoughts)
+		if delta == "" {
+			continue
+		}
+		sb.WriteString(delta)
+		if onChunk != nil {
+			onChunk(delta)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// DescribeImage 以 inline_data 发送图片，返回中文 alt/图注。
+func (g *GeminiLLM) DescribeImage(ctx context.Context, data []byte, mimeType string) (ImageCaption, error) {
+	model := g.VisionModel
+	if model == "" {
+		model = g.Model
+	}
+	body := geminiRequest{Contents: []geminiContent{{
+		Role: "user",
+		Parts: []geminiPart{
+			{Text: describeImagePrompt},
+			{InlineData: &geminiInlineData{MimeType: mimeType, Data: base64.StdEncoding.EncodeToString(data)}},
+		},
+	}}}
+	raw, err := g.generate(ctx, model, body)
+	if err != nil {
+		return ImageCaption{}, err
+	}
+	return parseImageCaption(raw)
+}
@@ -2,7 +2,11 @@ package generator
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"log"
+	"net/http"
+	"strings"
 
 	openai "github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
@@ -11,7 +15,12 @@ import (
 // OpenAILLM implements LLMClient using the official openai-go SDK (chat completions).
 type OpenAILLM struct {
 	Model string
-	Opts  []option.RequestOption
+	// VisionModel 用于图片理解；为空时使用 Model。
+	VisionModel string
+	Sampling    SamplingParams
+	// JSONMode 为 true 时 JSON 请求带上 response_format: json_object；不支持的 OpenAI 兼容服务商会返回 400。
+	JSONMode bool
+	Opts     []option.RequestOption
 }
 
 func NewOpenAILLMFromConfig(cfg *LLMSettings) (*OpenAILLM, error) {
@@ -28,12 +37,130 @@ func NewOpenAILLMFromConfig(cfg *LLMSettings) (*OpenAILLM, error) {
 	if cfg.BaseURL != "" {
 		opts = append(opts, option.WithBaseURL(cfg.BaseURL))
 	}
-	return &OpenAILLM{Model: cfg.Model, Opts: opts}, nil
+	// 官方接口支持 JSON 模式；自定义 base_url 的兼容服务（含 deepseek、qwen）默认不使用，需在配置中开启。
+	jsonMode := cfg.jsonMode(cfg.BaseURL == "")
+	return &OpenAILLM{Model: cfg.Model, VisionModel: cfg.VisionModel, Sampling: cfg.Sampling, JSONMode: jsonMode, Opts: opts}, nil
+}
+
+// jsonModeRejected 判断请求是否因 response_format 被拒绝：服务商不支持 JSON 模式时返回 400。
+func jsonModeRejected(params openai.ChatCompletionNewParams, err error) bool {
+	var apiErr *openai.Error
+	return params.ResponseFormat.OfJSONObject != nil && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest
 }
 
 func (o *OpenAILLM) Complete(ctx context.Context, prompt Prompt) (string, error) {
 	client := openai.NewClient(o.Opts...)
 
+	params := o.chatParams(prompt)
+	resp, err := client.Chat.Completions.New(ctx, params)
+	if jsonModeRejected(params, err) {
+		// 稿件提示词仍要求 JSON，由调用方从文本中解析。
+		log.Printf("[llm] %s rejected response_format (%v); retrying without JSON mode", o.Model, err)
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{}
+		resp, err = client.Chat.Completions.New(ctx, params)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", errors.New("openai: empty choices")
+	}
+	ReportUsage(ctx, Usage{PromptTokens: int(resp.Usage.PromptTokens), CompletionTokens: int(resp.Usage.CompletionTokens)})
+	msg := resp.Choices[0].Message
+	// DeepSeek-R1、QwQ 等通过扩展字段 reasoning_content 单独返回推理过程。
+	ReportReasoning(ctx, reasoningField(msg.JSON.ExtraFields["reasoning_content"].Raw()))
+	return msg.Content, nil
+}
+
+func (o *OpenAILLM) Stream(ctx context.Context, prompt Prompt, onChunk func(chunk string)) (string, error) {
+	params := o.chatParams(prompt)
+	params.StreamOptions.IncludeUsage = openai.Bool(true)
+	out, err := o.stream(ctx, params, onChunk)
+	if jsonModeRejected(params, err) {
+		log.Printf("[llm] %s rejected response_format (%v); retrying without JSON mode", o.Model, err)
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{}
+		out, err = o.stream(ctx, params, onChunk)
+	}
+	return out, err
+}
+
+func (o *OpenAILLM) stream(ctx context.Context, params openai.ChatCompletionNewParams, onChunk func(chunk string)) (string, error) {
+	client := openai.NewClient(o.Opts...)
+	stream := client.Chat.Completions.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	var sb, reasoning strings.Builder
+	defer func() { ReportReasoning(ctx, reasoning.String()) }()
+	for stream.Next() {
+		chunk := stream.Current()
+		if chunk.Usage.TotalTokens > 0 {
+			ReportUsage(ctx, Usage{PromptTokens: int(chunk.Usage.PromptTokens), CompletionTokens: int(chunk.Usage.CompletionTokens)})
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		reasoning.WriteString(reasoningField(chunk.Choices[0].Delta.JSON.ExtraFields["reasoning_content"].Raw()))
+		delta := chunk.Choices[0].Delta.Content
+		if delta == "" {
+			continue
+		}
+		sb.WriteString(delta)
+		if onChunk != nil {
+			onChunk(delta)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// isOSeriesModel 判断是否为 OpenAI o 系列推理模型（o1、o3-mini、o4-mini 等）。
+func isOSeriesModel(model string) bool {
+	m := strings.ToLower(model)
+	return len(m) >= 2 && m[0] == 'o' && m[1] >= '1' && m[1] <= '9'
+}
+
+// chatParams 组装请求参数，合并默认采样参数与 prompt 级覆盖。
+// o 系列推理模型不支持 temperature 等采样参数，且以 max_completion_tokens 限制输出（含推理 token）。
+func (o *OpenAILLM) chatParams(prompt Prompt) openai.ChatCompletionNewParams {
+	params := openai.ChatCompletionNewParams{
+		Model:    openai.ChatModel(o.Model),
+		Messages: buildMessages(prompt),
+	}
+	sp := o.Sampling.Merge(prompt.Sampling)
+	if isOSeriesModel(o.Model) {
+		params.Messages[0] = openai.DeveloperMessage(prompt.System)
+		if sp.MaxTokens != nil {
+			params.MaxCompletionTokens = openai.Int(int64(*sp.MaxTokens))
+		}
+		if prompt.JSON && o.JSONMode {
+			params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{OfJSONObject: &openai.ResponseFormatJSONObjectParam{}}
+		}
+		return params
+	}
+	if sp.Temperature != nil {
+		params.Temperature = openai.Float(*sp.Temperature)
+	}
+	if sp.TopP != nil {
+		params.TopP = openai.Float(*sp.TopP)
+	}
+	if sp.MaxTokens != nil {
+		params.MaxTokens = openai.Int(int64(*sp.MaxTokens))
+	}
+	if sp.PresencePenalty != nil {
+		params.PresencePenalty = openai.Float(*sp.PresencePenalty)
+	}
+	if sp.FrequencyPenalty != nil {
+		params.FrequencyPenalty = openai.Float(*sp.FrequencyPenalty)
+	}
+	if prompt.JSON && o.JSONMode {
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{OfJSONObject: &openai.ResponseFormatJSONObjectParam{}}
+	}
+	return params
+}
+
+func buildMessages(prompt Prompt) []openai.ChatCompletionMessageParamUnion {
 	msgs := []openai.ChatCompletionMessageParamUnion{
 		openai.SystemMessage(prompt.System),
 	}
@@ -50,16 +177,32 @@ func (o *OpenAILLM) Complete(ctx context.Context, prompt Prompt) (string, error)
 		}
 	}
 	msgs = append(msgs, openai.UserMessage(prompt.User))
+	return msgs
+}
 
+// DescribeImage 以 data URL 方式把图片发送给视觉模型，返回中文 alt/图注。
+func (o *OpenAILLM) DescribeImage(ctx context.Context, data []byte, mimeType string) (ImageCaption, error) {
+	client := openai.NewClient(o.Opts...)
+
+	model := o.VisionModel
+	if model == "" {
+		model = o.Model
+	}
+	dataURL := "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
 	resp, err := client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Model:    openai.ChatModel(o.Model),
-		Messages: msgs,
+		Model: openai.ChatModel(model),
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
+				openai.TextContentPart(describeImagePrompt),
+				openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: dataURL, Detail: "low"}),
+			}),
+		},
 	})
 	if err != nil {
-		return "", err
+		return ImageCaption{}, err
 	}
 	if len(resp.Choices) == 0 {
-		return "", errors.New("openai: empty choices")
+		return ImageCaption{}, errors.New("openai: empty choices")
 	}
-	return resp.Choices[0].Message.Content, nil
+	return parseImageCaption(resp.Choices[0].Message.Content)
 }
@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// compatServer 模拟不支持 response_format 的 OpenAI 兼容服务：带该参数的请求返回 400，其余返回 reply。
+func compatServer(t *testing.T, reply string, formats *[]bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		_, hasFormat := body["response_format"]
+		*formats = append(*formats, hasFormat)
+		w.Header().Set("Content-Type", "application/json")
+		if hasFormat {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"response_format is not supported","type":"invalid_request_error"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"id": "1", "object": "chat.completion", "model": "m",
+			"choices": []any{map[string]any{"index": 0, "finish_reason": "stop", "message": map[string]any{"role": "assistant", "content": reply}}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOpenAIJSONMode(t *testing.T) {
+	const reply = "好的，稿件如下：\n{\"title\":\"标题\",\"digest\":\"摘要\",\"markdown\":\"# 标题\\n\\n正文\"}"
+	no, yes := false, true
+	tests := []struct {
+		name     string
+		jsonMode *bool
+		want     []bool // 各次请求是否带 response_format
+	}{
+		{name: "compat default off", want: []bool{false}},
+		{name: "explicitly off", jsonMode: &no, want: []bool{false}},
+		{name: "on retries without response_format after 400", jsonMode: &yes, want: []bool{true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var formats []bool
+			srv := compatServer(t, reply, &formats)
+			llm, err := NewOpenAILLMFromConfig(&LLMSettings{APIKey: "k", Model: "m", BaseURL: srv.URL, JSONMode: tt.jsonMode})
+			if err != nil {
+				t.Fatal(err)
+			}
+			agent, err := NewAgent(llm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			draft, err := agent.generateDraft(context.Background(), BuildInitialPrompt(Spec{Topic: "测试"}), Spec{Topic: "测试"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if draft.Title != "标题" || draft.Digest != "摘要" {
+				t.Fatalf("draft = %q / %q; want the JSON parsed out of the reply", draft.Title, draft.Digest)
+			}
+			if !slices.Equal(formats, tt.want) {
+				t.Fatalf("requests with response_format = %v, want %v", formats, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAIJSONModeDefault(t *testing.T) {
+	official, _ := NewOpenAILLMFromConfig(&LLMSettings{APIKey: "k", Model: "m"})
+	deepseek, _ := NewDeepSeekLLM(&LLMSettings{APIKey: "k"})
+	azure, _ := NewAzureOpenAILLM(&LLMSettings{APIKey: "k", Model: "m", BaseURL: "https://x.openai.azure.com"})
+	if !official.JSONMode || deepseek.JSONMode || !azure.JSONMode {
+		t.Fatalf("JSONMode openai=%v deepseek=%v azure=%v; want true/false/true", official.JSONMode, deepseek.JSONMode, azure.JSONMode)
+	}
+}
@@ -0,0 +1,28 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// MockImageGenerator 生成一张纯色渐变图，便于本地调试，不调用外部模型。
+type MockImageGenerator struct{}
+
+func (m MockImageGenerator) GenerateImage(_ context.Context, _ string) ([]byte, error) {
+	const w, h = 900, 383
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		c := color.RGBA{R: 31, G: uint8(41 + y*80/h), B: uint8(55 + y*150/h), A: 255}
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -2,21 +2,256 @@ package generator
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // MockLLM 一个简单的占位实现，便于本地调试，不调用外部模型。
-type MockLLM struct{}
+// 零值只返回内置响应；用 NewMockLLM / NewMockLLMFromFile 创建时可按提示词返回预设响应，
+// 并模拟延迟与错误，便于离线演示与端到端测试。
+type MockLLM struct {
+	// Fixtures 按顺序匹配，第一条命中的生效；都未命中时使用内置响应。
+	Fixtures []MockFixture
+	// Latency 为每次调用的模拟延迟，可被单条 fixture 覆盖。
+	Latency time.Duration
+	// ErrorRate 为随机返回 503 的概率（0～1），用于演练回退与重试。
+	ErrorRate float64
+	state     *mockState
+}
+
+// MockFixture 为一条预设响应。
+type MockFixture struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Match 为正则，匹配以换行拼接的 system 与 user 提示词；为空时匹配全部。
+	Match string `json:"match,omitempty" yaml:"match,omitempty"`
+	// Responses 依次返回，用完后重复最后一条；Response 为只有一条时的简写。
+	Response  string   `json:"response,omitempty" yaml:"response,omitempty"`
+	Responses []string `json:"responses,omitempty" yaml:"responses,omitempty"`
+	// Error 非空时返回错误，Status 为 HTTP 状态码（默认 500；429/5xx 会触发回退）。
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
+	Status int    `json:"status,omitempty" yaml:"status,omitempty"`
+	// Times 大于 0 时只生效前 Times 次，之后继续匹配后面的 fixture。
+	Times int `json:"times,omitempty" yaml:"times,omitempty"`
+	// Latency 覆盖全局延迟，如 "1.5s"。
+	Latency string `json:"latency,omitempty" yaml:"latency,omitempty"`
+
+	re      *regexp.Regexp
+	latency time.Duration
+}
+
+// mockFixtureFile 为预设文件的结构。
+type mockFixtureFile struct {
+	Latency   string        `json:"latency,omitempty" yaml:"latency,omitempty"`
+	ErrorRate float64       `json:"error_rate,omitempty" yaml:"error_rate,omitempty"`
+	Fixtures  []MockFixture `json:"fixtures" yaml:"fixtures"`
+}
+
+type mockState struct {
+	mu   sync.Mutex
+	hits []int
+	rnd  *rand.Rand
+}
+
+// NewMockLLM 编译 fixtures 的匹配规则并创建可编排的 MockLLM。
+func NewMockLLM(fixtures []MockFixture, latency time.Duration, errorRate float64) (*MockLLM, error) {
+	if errorRate < 0 || errorRate > 1 {
+		return nil, fmt.Errorf("mock error_rate must be between 0 and 1")
+	}
+	fixtures = append([]MockFixture(nil), fixtures...)
+	for i := range fixtures {
+		fx := &fixtures[i]
+		label := fx.Name
+		if label == "" {
+			label = fmt.Sprintf("#%d", i)
+		}
+		if fx.Match != "" {
+			re, err := regexp.Compile(fx.Match)
+			if err != nil {
+				return nil, fmt.Errorf("mock fixture %s: %w", label, err)
+			}
+			fx.re = re
+		}
+		if fx.Latency != "" {
+			d, err := time.ParseDuration(fx.Latency)
+			if err != nil {
+				return nil, fmt.Errorf("mock fixture %s: %w", label, err)
+			}
+			fx.latency = d
+		}
+		if fx.Error == "" && fx.Response == "" && len(fx.Responses) == 0 {
+			return nil, fmt.Errorf("mock fixture %s: response or error is required", label)
+		}
+	}
+	return &MockLLM{
+		Fixtures:  fixtures,
+		Latency:   latency,
+		ErrorRate: errorRate,
+		state:     &mockState{hits: make([]int, len(fixtures)), rnd: rand.New(rand.NewSource(time.Now().UnixNano()))},
+	}, nil
+}
 
-func (m MockLLM) Complete(_ context.Context, prompt Prompt) (string, error) {
+// NewMockLLMFromFile 从 JSON 或 YAML（.yaml/.yml）文件加载预设响应。
+func NewMockLLMFromFile(path string) (*MockLLM, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read mock fixtures: %w", err)
+	}
+	var f mockFixtureFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &f)
+	default:
+		err = json.Unmarshal(data, &f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse mock fixtures %s: %w", path, err)
+	}
+	var latency time.Duration
+	if f.Latency != "" {
+		if latency, err = time.ParseDuration(f.Latency); err != nil {
+			return nil, fmt.Errorf("mock fixtures latency: %w", err)
+		}
+	}
+	return NewMockLLM(f.Fixtures, latency, f.ErrorRate)
+}
+
+// pick 返回命中的 fixture 及其是第几次命中（从 0 开始），并判断本次是否随机注入错误。
+func (m MockLLM) pick(prompt Prompt) (*MockFixture, int, bool) {
+	if m.state == nil {
+		return nil, 0, false
+	}
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+	inject := m.ErrorRate > 0 && m.state.rnd.Float64() < m.ErrorRate
+	text := prompt.System + "\n" + prompt.User
+	for i := range m.Fixtures {
+		fx := &m.Fixtures[i]
+		if fx.Times > 0 && m.state.hits[i] >= fx.Times {
+			continue
+		}
+		if fx.re != nil && !fx.re.MatchString(text) {
+			continue
+		}
+		n := m.state.hits[i]
+		m.state.hits[i]++
+		return fx, n, inject
+	}
+	return nil, 0, inject
+}
+
+func (m MockLLM) Complete(ctx context.Context, prompt Prompt) (string, error) {
+	fx, n, inject := m.pick(prompt)
+	delay := m.Latency
+	if fx != nil && fx.latency > 0 {
+		delay = fx.latency
+	}
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return "", ctx.Err()
+		case <-t.C:
+		}
+	}
+	if inject {
+		return "", &StatusError{Provider: "mock", StatusCode: http.StatusServiceUnavailable, Msg: "injected error"}
+	}
+	if fx == nil {
+		return mockBuiltinResponse(prompt)
+	}
+	if fx.Error != "" {
+		status := fx.Status
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		return "", &StatusError{Provider: "mock", StatusCode: status, Msg: fx.Error}
+	}
+	if len(fx.Responses) == 0 {
+		return fx.Response, nil
+	}
+	return fx.Responses[min(n, len(fx.Responses)-1)], nil
+}
+
+// mockBuiltinResponse 为未配置预设时的内置响应：按提示词特征返回固定 JSON 或回显 Markdown。
+func mockBuiltinResponse(prompt Prompt) (string, error) {
+	// 大纲请求返回固定的 JSON 结构。
+	if strings.Contains(prompt.System, `"sections"`) {
+		return `{"title": "自动生成示例标题", "sections": [{"heading": "问题从哪里来", "points": ["生活场景引入"]}, {"heading": "背后的原理", "points": ["解释机制"]}, {"heading": "可以怎么做", "points": ["温和的建议"]}]}`, nil
+	}
+	if strings.Contains(prompt.System, `"verdict"`) {
+		return `{"claims": [{"claim": "示例陈述", "verdict": "dubious", "note": "本地调试数据", "suggestion": "补充来源"}], "citations": [{"title": "示例来源", "url": "https://example.com", "note": "示例"}]}`, nil
+	}
+	if strings.Contains(prompt.System, `"terms"`) {
+		return `{"summary": "本地调试生成的前文摘要。", "terms": [{"term": "示例术语", "definition": "本地调试用的术语解释"}]}`, nil
+	}
+	if strings.Contains(prompt.System, `"angle"`) {
+		return `[{"title": "为什么越忙越想刷手机", "angle": "注意力与情绪调节", "outline": ["生活场景", "原理解释", "温和建议"], "score": 8.5, "reason": "贴近日常"}, {"title": "周一综合征是真的吗", "angle": "作息与情绪", "outline": ["常见感受", "研究发现"], "score": 7, "reason": "话题性强"}]`, nil
+	}
+	if strings.Contains(prompt.System, "编辑历史摘要") {
+		return "- 本地调试生成的编辑历史摘要", nil
+	}
+	// 金句摘录自下方默认稿件的原句。
+	if strings.Contains(prompt.System, "金句") {
+		return `[{"text": "这里是一段自动生成的摘要，概述全文要点。", "reason": "概括全文"}, {"text": "根据提示生成的内容：", "reason": "本地调试数据"}]`, nil
+	}
+	// 标题评分与备选标题。
+	if strings.Contains(prompt.System, `"clickability"`) {
+		return `[{"clickability": 6, "clarity": 8, "reason": "清晰直接"}, {"clickability": 8, "clarity": 6, "reason": "有悬念"}, {"clickability": 5, "clarity": 5, "reason": "较平淡"}]`, nil
+	}
+	if strings.Contains(prompt.System, "备选标题") {
+		return `["示例标题：从一个日常场景说起", "为什么我们总是这样？", "一篇示例文章"]`, nil
+	}
 	// 很简单地把用户输入/大纲拼接成 Markdown。
 	var sb strings.Builder
 	sb.WriteString("# 自动生成示例标题\n\n")
 	sb.WriteString("这里是一段自动生成的摘要，概述全文要点。\n\n")
 	sb.WriteString("## 正文\n\n")
 	sb.WriteString("根据提示生成的内容：\n\n")
-	sb.WriteString("```\n")
-	sb.WriteString(prompt.User)
-	sb.WriteString("\n```\n")
+	// 以引用块回显，避免提示词中的原稿标题与代码块影响稿件结构。
+	for _, line := range strings.Split(prompt.User, "\n") {
+		sb.WriteString("> " + line + "\n")
+	}
+	// JSON 模式下按稿件结构包装。
+	if prompt.JSON {
+		out, err := json.Marshal(structuredDraft{
+			Title:      "自动生成示例标题",
+			Digest:     "这里是一段自动生成的摘要，概述全文要点。",
+			Markdown:   sb.String(),
+			CoverHint:  "简洁的示例封面",
+			ImageHints: []string{"正文开头的示例配图"},
+		})
+		return string(out), err
+	}
 	return sb.String(), nil
 }
+
+// Stream 把 Complete 的结果按行回调，模拟流式输出。
+func (m MockLLM) Stream(ctx context.Context, prompt Prompt, onChunk func(chunk string)) (string, error) {
+	out, err := m.Complete(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	if onChunk != nil {
+		for _, line := range strings.SplitAfter(out, "\n") {
+			if line != "" {
+				onChunk(line)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (m MockLLM) DescribeImage(_ context.Context, _ []byte, _ string) (ImageCaption, error) {
+	return ImageCaption{Alt: "示例配图", Caption: "示例配图：本地调试生成的图注"}, nil
+}
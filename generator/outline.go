@@ -0,0 +1,49 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Outline 为大纲优先模式下模型给出、用户可编辑确认的文章结构。
+type Outline struct {
+	Title    string           `json:"title"`
+	Sections []OutlineSection `json:"sections"`
+}
+
+// OutlineSection 为大纲中的一个小节。
+type OutlineSection struct {
+	Heading string   `json:"heading"`
+	Points  []string `json:"points,omitempty"`
+}
+
+// Validate 检查大纲至少包含一个有标题的小节。
+func (o Outline) Validate() error {
+	if len(o.Sections) == 0 {
+		return errors.New("outline has no sections")
+	}
+	for i, sec := range o.Sections {
+		if strings.TrimSpace(sec.Heading) == "" {
+			return fmt.Errorf("outline section %d has empty heading", i+1)
+		}
+	}
+	return nil
+}
+
+// parseOutline 解析模型输出的大纲 JSON。
+func parseOutline(raw string) (Outline, error) {
+	var o Outline
+	if err := json.Unmarshal([]byte(extractJSON(raw, '{', '}')), &o); err != nil {
+		return Outline{}, fmt.Errorf("parse outline: %w", err)
+	}
+	o.Title = strings.TrimSpace(o.Title)
+	for i := range o.Sections {
+		o.Sections[i].Heading = strings.TrimSpace(o.Sections[i].Heading)
+	}
+	if err := o.Validate(); err != nil {
+		return Outline{}, err
+	}
+	return o, nil
+}
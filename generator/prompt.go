@@ -1,7 +1,6 @@
 package generator
 
 import (
-	"fmt"
 	"log"
 	"strings"
 )
@@ -11,6 +10,10 @@ type Prompt struct {
 	System  string
 	User    string
 	History []Message
+	// Sampling 为本次请求的采样参数覆盖（可空）。
+	Sampling *SamplingParams
+	// JSON 为 true 时要求服务商以 JSON 对象返回（支持时使用其 JSON 模式）。
+	JSON bool
 }
 
 // Message 用于少量历史（可选）。
@@ -78,92 +81,272 @@ var stylePresets = map[string]string{
 
 // BuildInitialPrompt 生成首稿提示词。
 func BuildInitialPrompt(spec Spec) Prompt {
-	var sb strings.Builder
-	sb.WriteString("你是一名专业中文内容创作者，请直接输出 Markdown，不要额外解释。\n")
-	sb.WriteString("要求：\n")
-	if spec.Words > 0 {
-		sb.WriteString(fmt.Sprintf("- 目标字数约 %d 字（允许 ±15%%，不得超过 %d 字）。\n", spec.Words, int(float64(spec.Words)*1.2)))
+	data := newPromptData(spec)
+	system := renderPrompt("initial_system.tmpl", data)
+	user := renderPrompt("initial_user.tmpl", data)
+	log.Printf("[Prompt][initial] style=%s constraints=%d\nsystem:\n%s\nuser:\n%s\n", data.StyleKey, len(spec.Constraints), system, user)
+
+	return Prompt{
+		System:   system,
+		User:     user,
+		History:  nil,
+		Sampling: data.Sampling,
 	}
-	sb.WriteString("- 每个段落前添加小标题（使用二级或三级标题）。\n")
-	styleKey := spec.Style
-	if styleKey == "" {
-		styleKey = "life-rational"
+}
+
+// BuildRevisionPrompt 生成修订提示词。
+func BuildRevisionPrompt(spec Spec, prev Draft, comment string, history []Turn) Prompt {
+	data := newPromptData(spec)
+	data.Draft = prev
+	data.Comment = comment
+
+	// 记录近期 turn 摘要（可空）。
+	var msgs []Message
+	for _, t := range history {
+		if t.Comment == "" {
+			continue
+		}
+		msgs = append(msgs, Message{Role: "user", Content: t.Comment})
 	}
-	stylePrompt := strings.TrimSpace(stylePresets[styleKey])
-	if stylePrompt != "" {
-		sb.WriteString("风格预设：\n")
-		sb.WriteString(stylePrompt)
-		sb.WriteString("\n")
+
+	return Prompt{
+		System:   renderPrompt("revision_system.tmpl", data),
+		User:     renderPrompt("revision_user.tmpl", data),
+		History:  msgs,
+		Sampling: data.Sampling,
 	}
+}
 
-	if len(spec.Constraints) > 0 {
-		sb.WriteString("写作指南：\n")
-		for _, c := range spec.Constraints {
-			sb.WriteString(fmt.Sprintf("- %s\n", c))
-		}
+// BuildImagePlacementPrompt 生成插图提示词：在不改动正文的前提下插入图片引用。
+func BuildImagePlacementPrompt(prev Draft, images []ImageRef) Prompt {
+	data := promptData{Draft: prev, Images: images}
+	return Prompt{
+		System: renderPrompt("placement_system.tmpl", data),
+		User:   renderPrompt("placement_user.tmpl", data),
 	}
+}
 
-	sb.WriteString("- 必须包含一级标题作为文章标题。\n")
-	if len(spec.Outline) > 0 {
-		sb.WriteString("- 结合以下背景信息进行写作：\n")
-		for i, item := range spec.Outline {
-			sb.WriteString(fmt.Sprintf("  %d. %s\n", i+1, item))
-		}
+// BuildOutlinePrompt 生成大纲提示词，要求模型以 JSON 返回结构化大纲。
+func BuildOutlinePrompt(spec Spec) Prompt {
+	data := newPromptData(spec)
+	return Prompt{
+		System:   renderPrompt("outline_system.tmpl", data),
+		User:     renderPrompt("outline_user.tmpl", data),
+		Sampling: data.Sampling,
 	}
-	sb.WriteString("请严格遵守以上要求和 Markdown 结构，禁止额外说明。\n")
+}
 
-	user := fmt.Sprintf("主题：%s\n请输出符合上述要求的完整 Markdown。", spec.Topic)
-	system := sb.String()
-	log.Printf("[Prompt][initial] style=%s constraints=%d\nsystem:\n%s\nuser:\n%s\n", styleKey, len(spec.Constraints), system, user)
+// BuildExpandPrompt 生成按已确认大纲展开全文的提示词。
+func BuildExpandPrompt(spec Spec, outline Outline) Prompt {
+	data := newPromptData(spec)
+	data.Outline = outline
+	return Prompt{
+		System:   renderPrompt("expand_system.tmpl", data),
+		User:     renderPrompt("expand_user.tmpl", data),
+		Sampling: data.Sampling,
+	}
+}
 
+// BuildPolishPrompt 生成润色提示词，focus 为可选的润色重点。
+func BuildPolishPrompt(spec Spec, prev Draft, focus string) Prompt {
+	data := newPromptData(spec)
+	data.Draft = prev
+	data.Comment = focus
 	return Prompt{
-		System:  system,
-		User:    user,
-		History: nil,
+		System:   renderPrompt("polish_system.tmpl", data),
+		User:     renderPrompt("polish_user.tmpl", data),
+		Sampling: data.Sampling,
 	}
 }
 
-// BuildRevisionPrompt 生成修订提示词。
-func BuildRevisionPrompt(spec Spec, prev Draft, comment string, history []Turn) Prompt {
-	var sb strings.Builder
-	sb.WriteString("你是一名专业编辑，基于用户反馈对稿件做最小必要改动，保持 Markdown 结构。\n")
-	sb.WriteString("- 维持标题层级和列表格式。\n")
-	sb.WriteString("- 如果反馈无效或不合理，说明原因并保持原文。\n")
-	sb.WriteString("- 每个段落前添加小标题（使用二级或三级标题）。\n")
-	if spec.Words > 0 {
-		sb.WriteString(fmt.Sprintf("- 目标字数约 %d 字（允许 ±15%%，不得超过 %d 字）。\n", spec.Words, int(float64(spec.Words)*1.2)))
+// BuildFactCheckPrompt 生成事实核查提示词。
+func BuildFactCheckPrompt(spec Spec, draft Draft) Prompt {
+	data := newPromptData(spec)
+	data.Draft = draft
+	return Prompt{
+		System: renderPrompt("factcheck_system.tmpl", data),
+		User:   renderPrompt("factcheck_user.tmpl", data),
 	}
-	styleKey := spec.Style
-	if styleKey == "" {
-		styleKey = "life-rational"
+}
+
+// BuildReferencePrompt 生成参考网页摘要提示词。
+func BuildReferencePrompt(title, text string) Prompt {
+	data := promptData{SourceTitle: title, Source: text}
+	return Prompt{
+		System: renderPrompt("reference_system.tmpl", data),
+		User:   renderPrompt("reference_user.tmpl", data),
 	}
-	stylePrompt := strings.TrimSpace(stylePresets[styleKey])
-	if stylePrompt != "" {
-		sb.WriteString("风格预设：\n")
-		sb.WriteString(stylePrompt)
-		sb.WriteString("\n")
+}
+
+// BuildRewritePrompt 生成按风格预设改写原文的提示词。
+func BuildRewritePrompt(spec Spec, src SourceDoc) Prompt {
+	data := newPromptData(spec)
+	data.SourceTitle = src.Title
+	data.Source = strings.TrimSpace(src.Text)
+	return Prompt{
+		System:   renderPrompt("rewrite_system.tmpl", data),
+		User:     renderPrompt("rewrite_user.tmpl", data),
+		Sampling: data.Sampling,
 	}
-	if len(spec.Constraints) > 0 {
-		sb.WriteString("写作指南：\n")
-		for _, c := range spec.Constraints {
-			sb.WriteString(fmt.Sprintf("- %s\n", c))
-		}
+}
+
+// BuildTranslatePrompt 生成把外文原文编译为中文稿件的提示词。
+func BuildTranslatePrompt(spec Spec, src SourceDoc) Prompt {
+	data := newPromptData(spec)
+	data.SourceTitle = src.Title
+	data.Source = strings.TrimSpace(src.Text)
+	data.SourceLang = src.Lang
+	return Prompt{
+		System:   renderPrompt("translate_system.tmpl", data),
+		User:     renderPrompt("translate_user.tmpl", data),
+		Sampling: data.Sampling,
 	}
+}
 
-	user := fmt.Sprintf("当前稿件：\n%s\n\n用户反馈：%s\n请输出修订后的完整 Markdown。", prev.Markdown, comment)
+// BuildSeriesSummaryPrompt 生成系列“前文回顾”摘要提示词。
+func BuildSeriesSummaryPrompt(spec Spec, draft Draft) Prompt {
+	data := newPromptData(spec)
+	data.Draft = draft
+	return Prompt{
+		System: renderPrompt("series_summary_system.tmpl", data),
+		User:   renderPrompt("series_summary_user.tmpl", data),
+	}
+}
 
-	// 记录近期 turn 摘要（可空）。
-	var msgs []Message
-	for _, t := range history {
-		if t.Comment == "" {
-			continue
+// BuildIdeasPrompt 生成选题提示词，Spec.Topic 为领域描述。
+func BuildIdeasPrompt(req IdeaRequest) Prompt {
+	data := newPromptData(Spec{Topic: req.Domain, Style: req.Style})
+	data.Audience = req.Audience
+	data.Count = req.Count
+	return Prompt{
+		System: renderPrompt("ideas_system.tmpl", data),
+		User:   renderPrompt("ideas_user.tmpl", data),
+	}
+}
+
+// BuildJSONRepairPrompt 生成修复 JSON 输出的提示词，problem 为校验错误。
+func BuildJSONRepairPrompt(raw, problem string) Prompt {
+	data := promptData{Source: strings.TrimSpace(raw), Comment: problem}
+	return Prompt{
+		System: renderPrompt("json_repair_system.tmpl", data),
+		User:   renderPrompt("json_repair_user.tmpl", data),
+		JSON:   true,
+	}
+}
+
+// BuildRepairPrompt 生成修正稿件格式问题的提示词，problems 为 ValidateMarkdown 的结果。
+func BuildRepairPrompt(prev Draft, problems []string, allowHTML bool) Prompt {
+	data := promptData{Draft: prev, Problems: problems, AllowHTML: allowHTML}
+	return Prompt{
+		System: renderPrompt("repair_system.tmpl", data),
+		User:   renderPrompt("repair_user.tmpl", data),
+	}
+}
+
+// BuildHistorySummaryPrompt 生成编辑历史摘要提示词：把 prev 与 turns 中的修改意见合并概括。
+func BuildHistorySummaryPrompt(spec Spec, prev string, turns []Turn) Prompt {
+	data := newPromptData(spec)
+	data.Summary = prev
+	for _, t := range turns {
+		if t.Comment != "" {
+			data.Comments = append(data.Comments, t.Comment)
 		}
-		msgs = append(msgs, Message{Role: "user", Content: t.Comment})
 	}
+	return Prompt{
+		System: renderPrompt("history_summary_system.tmpl", data),
+		User:   renderPrompt("history_summary_user.tmpl", data),
+	}
+}
+
+// BuildSensitivePrompt 生成改写敏感词句的提示词。
+func BuildSensitivePrompt(spec Spec, prev Draft, hits []SensitiveHit) Prompt {
+	data := newPromptData(spec)
+	data.Draft = prev
+	data.Sensitive = hits
+	return Prompt{
+		System:   renderPrompt("sensitive_system.tmpl", data),
+		User:     renderPrompt("sensitive_user.tmpl", data),
+		Sampling: data.Sampling,
+	}
+}
 
+// BuildLengthPrompt 生成字数调整提示词：shorten 为 true 时精简，否则扩写。
+func BuildLengthPrompt(spec Spec, prev Draft, shorten bool) Prompt {
+	data := newPromptData(spec)
+	data.Draft = prev
+	data.Shorten = shorten
 	return Prompt{
-		System:  sb.String(),
-		User:    user,
-		History: msgs,
+		System:   renderPrompt("length_system.tmpl", data),
+		User:     renderPrompt("length_user.tmpl", data),
+		Sampling: data.Sampling,
+	}
+}
+
+// BuildSectionPrompt 生成只重写单个小节的提示词。
+func BuildSectionPrompt(spec Spec, prev Draft, section, comment string) Prompt {
+	data := newPromptData(spec)
+	data.Draft = prev
+	data.Section = section
+	data.Comment = comment
+	return Prompt{
+		System:   renderPrompt("section_system.tmpl", data),
+		User:     renderPrompt("section_user.tmpl", data),
+		Sampling: data.Sampling,
+	}
+}
+
+// BuildTitlesPrompt 生成备选标题提示词。
+func BuildTitlesPrompt(spec Spec, draft Draft, n int) Prompt {
+	data := newPromptData(spec)
+	data.Draft = draft
+	data.Count = n
+	return Prompt{
+		System: renderPrompt("titles_system.tmpl", data),
+		User:   renderPrompt("titles_user.tmpl", data),
+	}
+}
+
+// BuildQuotesPrompt 生成金句提取提示词。
+func BuildQuotesPrompt(spec Spec, draft Draft, n int) Prompt {
+	data := newPromptData(spec)
+	data.Draft = draft
+	data.Count = n
+	return Prompt{
+		System: renderPrompt("quotes_system.tmpl", data),
+		User:   renderPrompt("quotes_user.tmpl", data),
+	}
+}
+
+// BuildTitleScorePrompt 生成标题评分提示词。
+func BuildTitleScorePrompt(spec Spec, draft Draft, titles []string) Prompt {
+	data := newPromptData(spec)
+	data.Draft = draft
+	data.Titles = titles
+	return Prompt{
+		System: renderPrompt("title_score_system.tmpl", data),
+		User:   renderPrompt("title_score_user.tmpl", data),
+	}
+}
+
+// newPromptData 解析风格预设、采样参数与字数上限，供模板使用。
+func newPromptData(spec Spec) promptData {
+	styleKey := spec.Style
+	if styleKey == "" {
+		styleKey = "life-rational"
+	}
+	data := promptData{
+		Spec:     spec,
+		StyleKey: styleKey,
+		MaxWords: int(float64(spec.Words) * 1.2),
+		Images:   spec.Images,
+		Sampling: spec.Sampling,
+	}
+	if st, ok := LookupStyle(styleKey); ok {
+		data.StylePrompt = strings.TrimSpace(st.Prompt)
+		if st.Sampling != nil {
+			merged := st.Sampling.Merge(spec.Sampling)
+			data.Sampling = &merged
+		}
 	}
+	return data
 }
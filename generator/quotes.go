@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxQuotes 为单次金句提取的最大数量。
+const maxQuotes = 3
+
+// 金句的使用位置。
+const (
+	QuoteForDigest = "digest"
+	QuoteForCover  = "cover"
+)
+
+// GoldenQuote 为从正文中摘出的一句金句。
+type GoldenQuote struct {
+	Text   string `json:"text"`
+	Reason string `json:"reason,omitempty"`
+	// DigestOK 为 false 表示超过摘要字数上限，用作摘要时会被截断。
+	DigestOK bool `json:"digest_ok"`
+}
+
+// ExtractQuotes 请模型从稿件中挑出 n 句最值得传播的原句；不在正文中的句子会被丢弃。
+func (a *Agent) ExtractQuotes(ctx context.Context, spec Spec, draft Draft, n int) ([]GoldenQuote, error) {
+	if n <= 0 || n > maxQuotes {
+		return nil, fmt.Errorf("count must be between 1 and %d", maxQuotes)
+	}
+	raw, _, err := a.complete(ctx, BuildQuotesPrompt(spec, draft, n))
+	if err != nil {
+		return nil, err
+	}
+	var quotes []GoldenQuote
+	if err := json.Unmarshal([]byte(extractJSON(raw, '[', ']')), &quotes); err != nil {
+		return nil, fmt.Errorf("parse quotes: %w", err)
+	}
+
+	body := string(normalizeForSimilarity(draft.Markdown))
+	seen := map[string]bool{}
+	var out []GoldenQuote
+	for _, q := range quotes {
+		q.Text = strings.Trim(strings.TrimSpace(q.Text), "“”\"「」")
+		key := string(normalizeForSimilarity(q.Text))
+		// 模型偶尔会改写或编造句子，只保留正文中确实出现的原句。
+		if key == "" || seen[key] || !strings.Contains(body, key) {
+			continue
+		}
+		seen[key] = true
+		q.Reason = strings.TrimSpace(q.Reason)
+		q.DigestOK = utf8.RuneCountInString(q.Text) <= maxDigestRunes
+		out = append(out, q)
+		if len(out) == n {
+			break
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("model returned no quotes found in the draft")
+	}
+	return out, nil
+}
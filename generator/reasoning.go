@@ -0,0 +1,103 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// thinkRe 匹配推理模型（如 DeepSeek-R1 开源部署、QwQ）写在正文开头的 <think>…</think>。
+var thinkRe = regexp.MustCompile(`(?s)^\s*<think>(.*?)</think>\s*`)
+
+type reasoningSinkKey struct{}
+
+// WithReasoningSink 返回携带推理内容回调的 context，LLMClient 与 Agent 取得推理过程后经此上报。
+func WithReasoningSink(ctx context.Context, sink func(string)) context.Context {
+	return context.WithValue(ctx, reasoningSinkKey{}, sink)
+}
+
+// ReportReasoning 上报一段推理内容；context 中无回调或内容为空时忽略。
+func ReportReasoning(ctx context.Context, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if sink, ok := ctx.Value(reasoningSinkKey{}).(func(string)); ok && sink != nil {
+		sink(text)
+	}
+}
+
+// SplitReasoning 把模型输出拆成正文与推理过程：支持开头的 <think>…</think>，
+// 以及部分部署省略开标签、只输出 …</think> 的情况。没有推理内容时原样返回。
+func SplitReasoning(raw string) (content, reasoning string) {
+	if m := thinkRe.FindStringSubmatchIndex(raw); m != nil {
+		return raw[m[1]:], strings.TrimSpace(raw[m[2]:m[3]])
+	}
+	if i := strings.Index(raw, thinkClose); i >= 0 && !strings.Contains(raw[:i], thinkOpen) {
+		return strings.TrimLeft(raw[i+len(thinkClose):], " \t\r\n"), strings.TrimSpace(raw[:i])
+	}
+	return raw, ""
+}
+
+// reasoningField 解析 OpenAI 兼容接口中的扩展字段（如 DeepSeek 的 reasoning_content），raw 为其 JSON 原文。
+func reasoningField(raw string) string {
+	if raw == "" || raw == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		return ""
+	}
+	return s
+}
+
+// thinkFilter 包装流式回调，丢弃开头 <think>…</think> 中的推理片段，只转发正文。
+type thinkFilter struct {
+	out     func(string)
+	pending strings.Builder
+	state   int // 0 判断中，1 推理中，2 正文
+}
+
+func newThinkFilter(out func(string)) *thinkFilter {
+	return &thinkFilter{out: out}
+}
+
+func (f *thinkFilter) write(chunk string) {
+	if f.state == 2 {
+		f.emit(chunk)
+		return
+	}
+	f.pending.WriteString(chunk)
+	buf := f.pending.String()
+	if f.state == 0 {
+		trimmed := strings.TrimLeft(buf, " \t\r\n")
+		switch {
+		case strings.HasPrefix(trimmed, thinkOpen):
+			f.state = 1
+		case strings.HasPrefix(thinkOpen, trimmed):
+			return
+		default:
+			f.state = 2
+			f.pending.Reset()
+			f.emit(buf)
+			return
+		}
+	}
+	if i := strings.Index(buf, thinkClose); i >= 0 {
+		f.state = 2
+		f.pending.Reset()
+		f.emit(strings.TrimLeft(buf[i+len(thinkClose):], " \t\r\n"))
+	}
+}
+
+func (f *thinkFilter) emit(s string) {
+	if s != "" && f.out != nil {
+		f.out(s)
+	}
+}
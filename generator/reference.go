@@ -0,0 +1,194 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// maxReferenceURLs 限制单个 session 的参考链接数量。
+	maxReferenceURLs = 5
+	// maxReferenceBytes 为单个页面读取的最大字节数。
+	maxReferenceBytes = 2 << 20
+	// maxReferenceRunes 为送去摘要的正文最大字数。
+	maxReferenceRunes = 8000
+)
+
+// Reference 为一个参考链接及其摘要；抓取或摘要失败时 Error 非空，不会进入提示词。
+type Reference struct {
+	URL     string `json:"url"`
+	Title   string `json:"title,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// referenceClient 拒绝连接内网/本机地址，避免被用来探测服务器所在网络。
+var referenceClient = &http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(_, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				ip := net.ParseIP(host)
+				if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+					return fmt.Errorf("refusing to fetch non-public address %s", host)
+				}
+				return nil
+			},
+		}).DialContext,
+	},
+}
+
+// FetchReadable 抓取网页并提取标题与正文文本（跳过脚本、导航、页脚等）。
+func FetchReadable(ctx context.Context, rawURL string) (title, text string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "", fmt.Errorf("invalid url %q", rawURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; auto-wechat-article-publisher)")
+	resp, err := referenceClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("fetch %s: status %d", u.Host, resp.StatusCode)
+	}
+
+	body := io.LimitReader(resp.Body, maxReferenceBytes)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "" || mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		title, text, err = extractReadable(body)
+	case strings.HasPrefix(mediaType, "text/"):
+		var data []byte
+		data, err = io.ReadAll(body)
+		text = string(data)
+	default:
+		return "", "", fmt.Errorf("unsupported content type %s", mediaType)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	text = truncateRunes(strings.TrimSpace(text), maxReferenceRunes)
+	if text == "" {
+		return "", "", errors.New("no readable text")
+	}
+	return title, text, nil
+}
+
+// extractReadable 从 HTML 中提取 <title> 与正文块级元素的文本。
+func extractReadable(r io.Reader) (string, string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", "", err
+	}
+	skip := map[string]bool{"script": true, "style": true, "noscript": true, "nav": true, "header": true, "footer": true, "aside": true, "form": true, "svg": true, "iframe": true}
+	block := map[string]bool{"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "li": true, "blockquote": true, "pre": true, "td": true, "br": true, "div": true, "section": true, "article": true}
+
+	var title string
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if skip[n.Data] {
+				return
+			}
+			if n.Data == "title" && title == "" && n.FirstChild != nil {
+				title = strings.TrimSpace(n.FirstChild.Data)
+				return
+			}
+		}
+		if n.Type == html.TextNode {
+			if t := strings.Join(strings.Fields(n.Data), " "); t != "" {
+				sb.WriteString(t)
+				sb.WriteString(" ")
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type == html.ElementNode && block[n.Data] {
+			sb.WriteString("\n")
+		}
+	}
+	walk(doc)
+
+	var lines []string
+	for _, line := range strings.Split(sb.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return title, strings.Join(lines, "\n"), nil
+}
+
+// LoadReferences 并行抓取并摘要参考链接；单个链接失败只记录在 Reference.Error 中，
+// 全部失败时返回错误。
+func (a *Agent) LoadReferences(ctx context.Context, urls []string) ([]Reference, error) {
+	if len(urls) > maxReferenceURLs {
+		return nil, fmt.Errorf("at most %d reference urls", maxReferenceURLs)
+	}
+	refs := make([]Reference, len(urls))
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			refs[i] = a.loadReference(ctx, strings.TrimSpace(u))
+		}(i, u)
+	}
+	wg.Wait()
+
+	ok := 0
+	for _, ref := range refs {
+		if ref.Error == "" {
+			ok++
+		} else {
+			log.Printf("[Reference] %s: %s", ref.URL, ref.Error)
+		}
+	}
+	if ok == 0 && len(refs) > 0 {
+		return refs, fmt.Errorf("all reference urls failed: %s", refs[0].Error)
+	}
+	return refs, nil
+}
+
+func (a *Agent) loadReference(ctx context.Context, u string) Reference {
+	ref := Reference{URL: u}
+	title, text, err := FetchReadable(ctx, u)
+	if err != nil {
+		ref.Error = err.Error()
+		return ref
+	}
+	ref.Title = title
+	summary, _, err := a.complete(ctx, BuildReferencePrompt(title, text))
+	if err != nil {
+		ref.Error = err.Error()
+		return ref
+	}
+	ref.Summary = strings.TrimSpace(summary)
+	return ref
+}
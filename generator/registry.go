@@ -0,0 +1,75 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ProviderFactory 根据配置创建某个服务商的 LLMClient。
+type ProviderFactory func(cfg LLMSettings) (LLMClient, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]ProviderFactory{}
+)
+
+// RegisterProvider 注册（或覆盖）一个服务商；name 不区分大小写。
+func RegisterProvider(name string, factory ProviderFactory) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || factory == nil {
+		panic("generator: RegisterProvider requires name and factory")
+	}
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = factory
+}
+
+// Providers 返回已注册的服务商名称（已排序）。
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewLLM 按 Provider 分发创建 LLMClient，切换模型只需修改配置。
+func NewLLM(cfg LLMSettings) (LLMClient, error) {
+	name := strings.ToLower(strings.TrimSpace(cfg.Provider))
+	if name == "" {
+		return nil, errors.New("llm provider is required; set llm.provider in config")
+	}
+	providersMu.RLock()
+	factory, ok := providers[name]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("llm provider %q not supported (available: %s)", cfg.Provider, strings.Join(Providers(), ", "))
+	}
+	client, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("llm provider %s: %w", name, err)
+	}
+	return client, nil
+}
+
+func init() {
+	RegisterProvider("openai", func(cfg LLMSettings) (LLMClient, error) { return NewOpenAILLMFromConfig(&cfg) })
+	RegisterProvider("deepseek", func(cfg LLMSettings) (LLMClient, error) { return NewDeepSeekLLM(&cfg) })
+	qwen := func(cfg LLMSettings) (LLMClient, error) { return NewQwenLLM(&cfg) }
+	RegisterProvider("qwen", qwen)
+	RegisterProvider("dashscope", qwen)
+	RegisterProvider("azure", func(cfg LLMSettings) (LLMClient, error) { return NewAzureOpenAILLM(&cfg) })
+	RegisterProvider("gemini", func(cfg LLMSettings) (LLMClient, error) { return NewGeminiLLMFromConfig(&cfg) })
+	RegisterProvider("mock", func(cfg LLMSettings) (LLMClient, error) {
+		if cfg.Fixtures == "" {
+			return MockLLM{}, nil
+		}
+		return NewMockLLMFromFile(cfg.Fixtures)
+	})
+}
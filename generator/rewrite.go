@@ -0,0 +1,201 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxSourceRunes 为改写原文的最大字数。
+	maxSourceRunes = 20000
+	// shingleSize 为相似度比对的片段长度（按去除标点后的字符计）。
+	shingleSize = 8
+	// maxRewriteSimilarity 为判定“原创”的重合度上限。
+	maxRewriteSimilarity = 0.3
+	// copiedSentenceRatio 为单句与原文重合片段占比超过该值时视为照搬。
+	copiedSentenceRatio = 0.6
+	// maxCopiedSentences 为报告中最多列出的照搬句子数。
+	maxCopiedSentences = 10
+)
+
+// SourceDoc 为改写/翻译的原文，Text 为 Markdown 或纯文本。
+type SourceDoc struct {
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Text  string `json:"text"`
+	// Lang 为原文语言（如 en、ja），仅翻译时使用，为空表示自动识别。
+	Lang string `json:"lang,omitempty"`
+}
+
+// OriginalityReport 为改写稿与原文的相似度报告。
+type OriginalityReport struct {
+	// Similarity 为稿件中与原文重合的 8 字片段占比（0～1）。
+	Similarity float64 `json:"similarity"`
+	// LongestCopy 为与原文连续相同的最长字数（不计标点空白）。
+	LongestCopy int `json:"longest_copy"`
+	// Copied 列出与原文高度重合的句子。
+	Copied []string `json:"copied,omitempty"`
+	// Original 为 true 表示重合度低于阈值。
+	Original  bool   `json:"original"`
+	DraftHash string `json:"draft_hash"`
+}
+
+// FetchSource 抓取网页正文作为改写原文。
+func FetchSource(ctx context.Context, rawURL string) (SourceDoc, error) {
+	title, text, err := FetchReadable(ctx, rawURL)
+	if err != nil {
+		return SourceDoc{}, err
+	}
+	return SourceDoc{Title: title, URL: rawURL, Text: text}, nil
+}
+
+// Validate 校验原文非空且不超长。
+func (d SourceDoc) Validate() error {
+	text := strings.TrimSpace(d.Text)
+	if text == "" {
+		return errors.New("source text is empty")
+	}
+	if n := utf8.RuneCountInString(text); n > maxSourceRunes {
+		return fmt.Errorf("source text too long: %d runes (max %d)", n, maxSourceRunes)
+	}
+	return nil
+}
+
+// label 返回原文在历史中的展示名称。
+func (d SourceDoc) label() string {
+	switch {
+	case d.Title != "":
+		return d.Title
+	case d.URL != "":
+		return d.URL
+	default:
+		return truncateRunes(firstLine(d.Text), 30)
+	}
+}
+
+// Rewrite 按风格预设把原文改写为新稿件。
+func (a *Agent) Rewrite(ctx context.Context, spec Spec, src SourceDoc) (Draft, error) {
+	if err := src.Validate(); err != nil {
+		return Draft{}, err
+	}
+	return a.generateDraft(ctx, BuildRewritePrompt(spec, src), spec)
+}
+
+// Translate 把外文原文翻译并本地化为所选风格的中文稿件。
+func (a *Agent) Translate(ctx context.Context, spec Spec, src SourceDoc) (Draft, error) {
+	if err := src.Validate(); err != nil {
+		return Draft{}, err
+	}
+	return a.generateDraft(ctx, BuildTranslatePrompt(spec, src), spec)
+}
+
+// CheckOriginality 比较稿件与原文的 8 字片段重合度，给出相似度报告。
+func CheckOriginality(source, draft string) OriginalityReport {
+	report := OriginalityReport{DraftHash: draftHash(draft)}
+	src := shingles(normalizeForSimilarity(source))
+	text := normalizeForSimilarity(draft)
+	if len(src) == 0 || len(text) < shingleSize {
+		report.Original = true
+		return report
+	}
+
+	hits, run := 0, 0
+	total := len(text) - shingleSize + 1
+	for i := 0; i < total; i++ {
+		if src[string(text[i:i+shingleSize])] {
+			hits++
+			run++
+			if l := run + shingleSize - 1; l > report.LongestCopy {
+				report.LongestCopy = l
+			}
+		} else {
+			run = 0
+		}
+	}
+	report.Similarity = float64(hits) / float64(total)
+
+	for _, sentence := range splitSentences(draft) {
+		norm := normalizeForSimilarity(sentence)
+		if len(norm) < 2*shingleSize {
+			continue
+		}
+		n, hit := len(norm)-shingleSize+1, 0
+		for i := 0; i < n; i++ {
+			if src[string(norm[i:i+shingleSize])] {
+				hit++
+			}
+		}
+		if float64(hit)/float64(n) >= copiedSentenceRatio {
+			report.Copied = append(report.Copied, sentence)
+			if len(report.Copied) == maxCopiedSentences {
+				break
+			}
+		}
+	}
+	report.Original = report.Similarity <= maxRewriteSimilarity
+	return report
+}
+
+// normalizeForSimilarity 去掉 Markdown 链接地址、HTML、标点与空白，只保留小写字母、数字与汉字。
+func normalizeForSimilarity(md string) []rune {
+	text := mdImageRe.ReplaceAllString(md, "$1")
+	text = mdLinkRe.ReplaceAllString(text, "$1")
+	text = htmlTagRe.ReplaceAllString(text, "")
+	out := make([]rune, 0, len(text))
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			out = append(out, unicode.ToLower(r))
+		}
+	}
+	return out
+}
+
+func shingles(text []rune) map[string]bool {
+	set := make(map[string]bool)
+	for i := 0; i+shingleSize <= len(text); i++ {
+		set[string(text[i:i+shingleSize])] = true
+	}
+	return set
+}
+
+// splitSentences 按中英文句末标点与换行切分句子（跳过标题与代码块）。
+func splitSentences(md string) []string {
+	var out []string
+	inFence := false
+	for _, line := range strings.Split(md, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		start := 0
+		for i, r := range line {
+			if strings.ContainsRune("。！？!?；;", r) {
+				end := i + utf8.RuneLen(r)
+				if s := strings.TrimSpace(line[start:end]); s != "" {
+					out = append(out, s)
+				}
+				start = end
+			}
+		}
+		if s := strings.TrimSpace(line[start:]); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(strings.TrimLeft(s, "# "))
+}
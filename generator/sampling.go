@@ -0,0 +1,33 @@
+package generator
+
+// SamplingParams 为可选的采样参数；nil 表示沿用模型默认值。
+type SamplingParams struct {
+	Temperature      *float64 `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty" yaml:"top_p,omitempty"`
+	MaxTokens        *int     `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty" yaml:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty" yaml:"frequency_penalty,omitempty"`
+}
+
+// Merge 以 override 中已设置的字段覆盖当前值，返回新副本。
+func (p SamplingParams) Merge(override *SamplingParams) SamplingParams {
+	if override == nil {
+		return p
+	}
+	if override.Temperature != nil {
+		p.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		p.TopP = override.TopP
+	}
+	if override.MaxTokens != nil {
+		p.MaxTokens = override.MaxTokens
+	}
+	if override.PresencePenalty != nil {
+		p.PresencePenalty = override.PresencePenalty
+	}
+	if override.FrequencyPenalty != nil {
+		p.FrequencyPenalty = override.FrequencyPenalty
+	}
+	return p
+}
@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// SearchResult 为一条检索结果摘要。
+type SearchResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+}
+
+// SearchProvider 为可插拔的联网检索服务。
+type SearchProvider interface {
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+}
+
+// SearchSettings 为检索服务配置。
+type SearchSettings struct {
+	Provider string
+	APIKey   string
+	BaseURL  string
+	// Limit 为每次检索保留的结果数，默认 5。
+	Limit int
+}
+
+// defaultSearchLimit 为未配置时每次检索保留的结果数。
+const defaultSearchLimit = 5
+
+// NewSearchProvider 按 provider 创建检索服务：bing / serpapi / tavily。
+func NewSearchProvider(cfg *SearchSettings) (SearchProvider, error) {
+	if cfg == nil {
+		return nil, errors.New("search config is nil")
+	}
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("search api key missing; provide search.api_key")
+	}
+	switch strings.ToLower(cfg.Provider) {
+	case "bing":
+		return newBingSearch(cfg), nil
+	case "serpapi":
+		return newSerpAPISearch(cfg), nil
+	case "tavily":
+		return newTavilySearch(cfg), nil
+	default:
+		return nil, fmt.Errorf("search provider %s not supported", cfg.Provider)
+	}
+}
+
+// SetSearch 启用写作前的联网检索；nil 表示关闭。
+func (a *Agent) SetSearch(p SearchProvider, limit int) {
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	a.search = p
+	a.searchLimit = limit
+}
+
+// HasSearch 返回是否配置了检索服务。
+func (a *Agent) HasSearch() bool {
+	return a.search != nil
+}
+
+// Research 检索主题相关的近期资料；query 为空时使用 spec 的主题。
+func (a *Agent) Research(ctx context.Context, spec Spec, query string) ([]SearchResult, error) {
+	if a.search == nil {
+		return nil, errors.New("search provider not configured; set search in config")
+	}
+	if query = strings.TrimSpace(query); query == "" {
+		query = spec.Topic
+	}
+	if query == "" {
+		return nil, errors.New("research query is empty")
+	}
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	results, err := a.search.Search(ctx, query, a.searchLimit)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	for i := range results {
+		results[i].Snippet = truncateRunes(strings.TrimSpace(results[i].Snippet), 300)
+	}
+	log.Printf("[Search] query=%q results=%d", query, len(results))
+	return results, nil
+}
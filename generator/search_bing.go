@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BingSearchBaseURL 为 Bing Web Search API 默认地址。
+const BingSearchBaseURL = "https://api.bing.microsoft.com/v7.0/search"
+
+// BingSearch implements SearchProvider using the Bing Web Search API.
+type BingSearch struct {
+	APIKey  string
+	BaseURL string
+	client  *http.Client
+}
+
+func newBingSearch(cfg *SearchSettings) *BingSearch {
+	base := strings.TrimRight(cfg.BaseURL, "/")
+	if base == "" {
+		base = BingSearchBaseURL
+	}
+	return &BingSearch{APIKey: cfg.APIKey, BaseURL: base, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (b *BingSearch) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	q := url.Values{}
+	q.Set("q", query)
+	q.Set("count", strconv.Itoa(limit))
+	q.Set("mkt", "zh-CN")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.BaseURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Ocp-Apim-Subscription-Key", b.APIKey)
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Provider: "bing", StatusCode: resp.StatusCode, Msg: resp.Status}
+	}
+	var out struct {
+		WebPages struct {
+			Value []struct {
+				Name    string `json:"name"`
+				URL     string `json:"url"`
+				Snippet string `json:"snippet"`
+			} `json:"value"`
+		} `json:"webPages"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("bing: decode: %w", err)
+	}
+	var results []SearchResult
+	for _, v := range out.WebPages.Value {
+		results = append(results, SearchResult{Title: v.Name, URL: v.URL, Snippet: v.Snippet})
+	}
+	return limitResults(results, limit), nil
+}
+
+func limitResults(results []SearchResult, limit int) []SearchResult {
+	if limit > 0 && len(results) > limit {
+		return results[:limit]
+	}
+	return results
+}
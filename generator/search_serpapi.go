@@ -0,0 +1,67 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SerpAPIBaseURL 为 SerpAPI 默认地址。
+const SerpAPIBaseURL = "https://serpapi.com/search.json"
+
+// SerpAPISearch implements SearchProvider using SerpAPI's Google engine.
+type SerpAPISearch struct {
+	APIKey  string
+	BaseURL string
+	client  *http.Client
+}
+
+func newSerpAPISearch(cfg *SearchSettings) *SerpAPISearch {
+	base := strings.TrimRight(cfg.BaseURL, "/")
+	if base == "" {
+		base = SerpAPIBaseURL
+	}
+	return &SerpAPISearch{APIKey: cfg.APIKey, BaseURL: base, client: &http.Client{Timeout: 20 * time.Second}}
+}
+
+func (s *SerpAPISearch) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	q := url.Values{}
+	q.Set("engine", "google")
+	q.Set("q", query)
+	q.Set("num", strconv.Itoa(limit))
+	q.Set("hl", "zh-cn")
+	q.Set("api_key", s.APIKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.BaseURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Error          string `json:"error"`
+		OrganicResults []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+		} `json:"organic_results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("serpapi: decode: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || out.Error != "" {
+		return nil, &StatusError{Provider: "serpapi", StatusCode: resp.StatusCode, Msg: out.Error}
+	}
+	var results []SearchResult
+	for _, v := range out.OrganicResults {
+		results = append(results, SearchResult{Title: v.Title, URL: v.Link, Snippet: v.Snippet})
+	}
+	return limitResults(results, limit), nil
+}
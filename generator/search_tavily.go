@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TavilyBaseURL 为 Tavily Search API 默认地址。
+const TavilyBaseURL = "https://api.tavily.com"
+
+// TavilySearch implements SearchProvider using the Tavily Search API.
+type TavilySearch struct {
+	APIKey  string
+	BaseURL string
+	client  *http.Client
+}
+
+func newTavilySearch(cfg *SearchSettings) *TavilySearch {
+	base := strings.TrimRight(cfg.BaseURL, "/")
+	if base == "" {
+		base = TavilyBaseURL
+	}
+	return &TavilySearch{APIKey: cfg.APIKey, BaseURL: base, client: &http.Client{Timeout: 20 * time.Second}}
+}
+
+func (t *TavilySearch) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	body, err := json.Marshal(map[string]any{
+		"query":        query,
+		"max_results":  limit,
+		"search_depth": "basic",
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.BaseURL+"/search", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.APIKey)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &StatusError{Provider: "tavily", StatusCode: resp.StatusCode, Msg: strings.TrimSpace(string(raw))}
+	}
+	var out struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("tavily: decode: %w", err)
+	}
+	var results []SearchResult
+	for _, v := range out.Results {
+		results = append(results, SearchResult{Title: v.Title, URL: v.URL, Snippet: v.Content})
+	}
+	return limitResults(results, limit), nil
+}
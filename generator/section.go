@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Section 为 Markdown 中以二级及以下标题开头的一个小节，Start/End 为字节偏移。
+type Section struct {
+	Heading string `json:"heading"`
+	Level   int    `json:"level"`
+	Start   int    `json:"-"`
+	End     int    `json:"-"`
+}
+
+var headingRe = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+
+// Sections 列出稿件中的小节（忽略代码块内的 # 行与一级标题）。
+// 小节结束于下一个同级或更高级标题之前。
+func Sections(md string) []Section {
+	type heading struct {
+		level int
+		text  string
+		start int
+	}
+	var heads []heading
+	inFence := false
+	offset := 0
+	for _, line := range strings.SplitAfter(md, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		} else if !inFence {
+			if m := headingRe.FindStringSubmatch(strings.TrimRight(line, "\r\n")); m != nil {
+				heads = append(heads, heading{level: len(m[1]), text: strings.TrimSpace(m[2]), start: offset})
+			}
+		}
+		offset += len(line)
+	}
+
+	var out []Section
+	for i, h := range heads {
+		if h.level == 1 {
+			continue
+		}
+		end := len(md)
+		for _, next := range heads[i+1:] {
+			if next.level <= h.level {
+				end = next.start
+				break
+			}
+		}
+		out = append(out, Section{Heading: h.text, Level: h.level, Start: h.start, End: end})
+	}
+	return out
+}
+
+// FindSection 按标题文本定位小节：优先完全匹配，其次唯一的包含匹配。
+func FindSection(md, heading string) (Section, error) {
+	want := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(heading), "#"))
+	if want == "" {
+		return Section{}, fmt.Errorf("heading is empty")
+	}
+	sections := Sections(md)
+	var exact, fuzzy []Section
+	for _, sec := range sections {
+		switch {
+		case sec.Heading == want:
+			exact = append(exact, sec)
+		case strings.Contains(strings.ToLower(sec.Heading), strings.ToLower(want)):
+			fuzzy = append(fuzzy, sec)
+		}
+	}
+	candidates := exact
+	if len(candidates) == 0 {
+		candidates = fuzzy
+	}
+	switch len(candidates) {
+	case 0:
+		return Section{}, fmt.Errorf("section %q not found", want)
+	case 1:
+		return candidates[0], nil
+	default:
+		return Section{}, fmt.Errorf("heading %q matches %d sections; use the full heading", want, len(candidates))
+	}
+}
+
+// spliceSection 用 replacement 替换小节内容，保留原小节末尾的空白，其余部分逐字节不变。
+// replacement 缺少标题行时沿用原标题。
+func spliceSection(md string, sec Section, replacement string) string {
+	body := md[sec.Start:sec.End]
+	trailing := body[len(strings.TrimRight(body, " \t\r\n")):]
+	replacement = strings.TrimSpace(stripCodeFence(replacement))
+	if !strings.HasPrefix(replacement, "#") {
+		headingLine, _, _ := strings.Cut(body, "\n")
+		replacement = strings.TrimRight(headingLine, "\r") + "\n\n" + replacement
+	}
+	if trailing == "" && sec.End < len(md) {
+		trailing = "\n"
+	}
+	return md[:sec.Start] + replacement + trailing + md[sec.End:]
+}
+
+// Paragraphs 按空行把稿件切分为段落（标题行单独算一段，代码块整体算一段），用于按段落定位批注。
+func Paragraphs(md string) []string {
+	var out []string
+	var cur []string
+	flush := func() {
+		if len(cur) > 0 {
+			out = append(out, strings.Join(cur, "\n"))
+			cur = nil
+		}
+	}
+	inFence := false
+	for _, line := range strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		fence := strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+		switch {
+		case inFence:
+			cur = append(cur, line)
+			if fence {
+				inFence = false
+			}
+		case fence:
+			flush()
+			cur = append(cur, line)
+			inFence = true
+		case trimmed == "":
+			flush()
+		case headingRe.MatchString(line):
+			flush()
+			out = append(out, line)
+		default:
+			cur = append(cur, line)
+		}
+	}
+	flush()
+	return out
+}
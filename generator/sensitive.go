@@ -0,0 +1,186 @@
+package generator
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// sensitiveContextRunes 为命中位置前后保留的上下文字数。
+const sensitiveContextRunes = 12
+
+//go:embed sensitive_words.txt
+var builtinSensitiveWords string
+
+// SensitiveHit 为一处敏感词命中；Line/Column 从 1 开始，Offset 为距正文开头的字数（rune）。
+type SensitiveHit struct {
+	Word    string `json:"word"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Offset  int    `json:"offset"`
+	Context string `json:"context"`
+}
+
+// SensitiveFilter 在稿件中查找敏感词，匹配时忽略大小写。
+type SensitiveFilter struct {
+	words []string
+	runes [][]rune
+}
+
+// NewSensitiveFilter 用词表创建过滤器，自动去除空白与重复项。
+func NewSensitiveFilter(words []string) *SensitiveFilter {
+	f := &SensitiveFilter{}
+	seen := map[string]bool{}
+	for _, w := range words {
+		w = strings.TrimSpace(w)
+		key := strings.ToLower(w)
+		if w == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		f.words = append(f.words, w)
+		f.runes = append(f.runes, lowerRunes(w))
+	}
+	return f
+}
+
+// LoadSensitiveFilter 读取 path 中的词表（每行一个，# 开头为注释）；builtin 为 true 时合并内置词表。
+// path 为空时只使用内置词表。
+func LoadSensitiveFilter(path string, builtin bool) (*SensitiveFilter, error) {
+	var words []string
+	if builtin {
+		words = parseWordList(builtinSensitiveWords)
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read sensitive words: %w", err)
+		}
+		words = append(words, parseWordList(string(data))...)
+	}
+	f := NewSensitiveFilter(words)
+	log.Printf("[Sensitive] loaded %d words", len(f.words))
+	return f, nil
+}
+
+func parseWordList(text string) []string {
+	var words []string
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words
+}
+
+// Words 返回词表。
+func (f *SensitiveFilter) Words() []string {
+	if f == nil {
+		return nil
+	}
+	return f.words
+}
+
+// Scan 返回 md 中的全部命中，按出现位置排序；被更长命中覆盖的短词不重复报告。
+func (f *SensitiveFilter) Scan(md string) []SensitiveHit {
+	if f == nil || len(f.runes) == 0 || md == "" {
+		return nil
+	}
+	text := []rune(md)
+	lower := lowerRunes(md)
+	type span struct{ start, end, word int }
+	var spans []span
+	for wi, w := range f.runes {
+		for i := 0; i+len(w) <= len(lower); i++ {
+			if runesEqual(lower[i:i+len(w)], w) {
+				spans = append(spans, span{i, i + len(w), wi})
+			}
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+
+	var hits []SensitiveHit
+	line, col, pos, covered := 1, 1, 0, 0
+	for _, sp := range spans {
+		if sp.end <= covered {
+			continue
+		}
+		covered = sp.end
+		for ; pos < sp.start; pos++ {
+			if text[pos] == '\n' {
+				line, col = line+1, 1
+			} else {
+				col++
+			}
+		}
+		from, to := max(0, sp.start-sensitiveContextRunes), min(len(text), sp.end+sensitiveContextRunes)
+		hits = append(hits, SensitiveHit{
+			Word:    string(text[sp.start:sp.end]),
+			Line:    line,
+			Column:  col,
+			Offset:  sp.start,
+			Context: strings.Join(strings.Fields(string(text[from:to])), " "),
+		})
+	}
+	return hits
+}
+
+func lowerRunes(s string) []rune {
+	r := []rune(s)
+	for i, c := range r {
+		r[i] = unicode.ToLower(c)
+	}
+	return r
+}
+
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// SetSensitive 设置敏感词过滤器；autoRephrase 为 true 时生成后若有命中自动请模型改写相关语句。
+func (a *Agent) SetSensitive(f *SensitiveFilter, autoRephrase bool) {
+	a.sensitive = f
+	a.sensitiveAuto = autoRephrase
+}
+
+// ScanSensitive 用已配置的词表检查稿件，未配置时返回 nil。
+func (a *Agent) ScanSensitive(md string) []SensitiveHit {
+	return a.sensitive.Scan(md)
+}
+
+// RephraseSensitive 让模型改写命中敏感词的语句，其余内容保持不变。
+func (a *Agent) RephraseSensitive(ctx context.Context, spec Spec, prev Draft, hits []SensitiveHit) (Draft, error) {
+	if len(hits) == 0 {
+		return Draft{}, errors.New("no sensitive words found")
+	}
+	raw, provider, err := a.complete(ctx, BuildSensitivePrompt(spec, prev, hits))
+	if err != nil {
+		return Draft{}, err
+	}
+	draft, err := postProcessFrom(raw, spec, provider)
+	if err != nil {
+		return Draft{}, err
+	}
+	draft.Digest, draft.CoverHint, draft.InlineImageHints = prev.Digest, prev.CoverHint, prev.InlineImageHints
+	return a.validate(ctx, spec, draft)
+}
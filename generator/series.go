@@ -0,0 +1,243 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSeriesDir 为未配置 series_dir 时的系列目录。
+const DefaultSeriesDir = "series"
+
+// ErrSeriesNotFound 表示系列不存在。
+var ErrSeriesNotFound = errors.New("series not found")
+
+// Series 为一组连续文章的共享上下文：定位、术语表与已发布各篇的摘要。
+type Series struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// Description 为系列定位、目标读者等说明。
+	Description string       `json:"description,omitempty"`
+	Terms       []SeriesTerm `json:"terms,omitempty"`
+	Parts       []SeriesPart `json:"parts,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// SeriesTerm 为系列内统一使用的术语及其解释。
+type SeriesTerm struct {
+	Term       string `json:"term"`
+	Definition string `json:"definition,omitempty"`
+}
+
+// SeriesPart 为系列中已完成的一篇文章。
+type SeriesPart struct {
+	Index     int       `json:"index"`
+	Title     string    `json:"title"`
+	Summary   string    `json:"summary"`
+	SessionID string    `json:"session_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NextIndex 返回下一篇的序号（从 1 开始）。
+func (s *Series) NextIndex() int {
+	return len(s.Parts) + 1
+}
+
+// AddPart 追加一篇并合并新术语（已有术语保持不变）。
+func (s *Series) AddPart(part SeriesPart, terms []SeriesTerm) {
+	part.Index = s.NextIndex()
+	if part.CreatedAt.IsZero() {
+		part.CreatedAt = time.Now()
+	}
+	s.Parts = append(s.Parts, part)
+	s.MergeTerms(terms)
+}
+
+// MergeTerms 合并术语，按术语名去重，已有的解释优先。
+func (s *Series) MergeTerms(terms []SeriesTerm) {
+	seen := make(map[string]bool, len(s.Terms))
+	for _, t := range s.Terms {
+		seen[strings.ToLower(t.Term)] = true
+	}
+	for _, t := range terms {
+		t.Term = strings.TrimSpace(t.Term)
+		if t.Term == "" || seen[strings.ToLower(t.Term)] {
+			continue
+		}
+		seen[strings.ToLower(t.Term)] = true
+		s.Terms = append(s.Terms, t)
+	}
+}
+
+// SeriesStore 把系列保存为 dir/<id>.json。
+type SeriesStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewSeriesStore 创建系列存储，目录不存在时在首次保存时创建。
+func NewSeriesStore(dir string) *SeriesStore {
+	if dir == "" {
+		dir = DefaultSeriesDir
+	}
+	return &SeriesStore{dir: dir}
+}
+
+// List 返回全部系列，按创建时间排序。
+func (st *SeriesStore) List() ([]Series, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	files, err := filepath.Glob(filepath.Join(st.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Series, 0, len(files))
+	for _, f := range files {
+		s, err := readSeries(f)
+		if err != nil {
+			return nil, fmt.Errorf("series %s: %w", filepath.Base(f), err)
+		}
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list, nil
+}
+
+// Get 按 id 读取系列。
+func (st *SeriesStore) Get(id string) (Series, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.getLocked(id)
+}
+
+// Save 校验并保存系列；ID 为空时新建。
+func (st *SeriesStore) Save(s Series) (Series, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.saveLocked(s)
+}
+
+// Update 在锁内读取、修改并保存系列，避免并发追加篇目时互相覆盖。
+func (st *SeriesStore) Update(id string, fn func(*Series) error) (Series, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s, err := st.getLocked(id)
+	if err != nil {
+		return Series{}, err
+	}
+	if err := fn(&s); err != nil {
+		return Series{}, err
+	}
+	return st.saveLocked(s)
+}
+
+// Delete 删除系列文件。
+func (st *SeriesStore) Delete(id string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	path, err := st.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return ErrSeriesNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (st *SeriesStore) getLocked(id string) (Series, error) {
+	path, err := st.path(id)
+	if err != nil {
+		return Series{}, err
+	}
+	s, err := readSeries(path)
+	if os.IsNotExist(err) {
+		return Series{}, ErrSeriesNotFound
+	}
+	return s, err
+}
+
+func (st *SeriesStore) saveLocked(s Series) (Series, error) {
+	s.Title = strings.TrimSpace(s.Title)
+	if s.Title == "" {
+		return Series{}, errors.New("series title is empty")
+	}
+	now := time.Now()
+	if s.ID == "" {
+		s.ID = strings.ReplaceAll(now.Format("20060102T150405.000000"), ".", "")
+		s.CreatedAt = now
+	}
+	s.UpdatedAt = now
+	path, err := st.path(s.ID)
+	if err != nil {
+		return Series{}, err
+	}
+	if err := os.MkdirAll(st.dir, 0o755); err != nil {
+		return Series{}, fmt.Errorf("create series dir: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return Series{}, err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return Series{}, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return Series{}, err
+	}
+	return s, nil
+}
+
+func (st *SeriesStore) path(id string) (string, error) {
+	if !styleKeyRe.MatchString(id) {
+		return "", fmt.Errorf("invalid series id %q", id)
+	}
+	return filepath.Join(st.dir, id+".json"), nil
+}
+
+func readSeries(path string) (Series, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Series{}, err
+	}
+	var s Series
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Series{}, err
+	}
+	return s, nil
+}
+
+// seriesSummary 为模型返回的单篇摘要与新术语。
+type seriesSummary struct {
+	Summary string       `json:"summary"`
+	Terms   []SeriesTerm `json:"terms"`
+}
+
+// SummarizeForSeries 让模型概括稿件要点并提取新术语，供系列后续篇目引用。
+func (a *Agent) SummarizeForSeries(ctx context.Context, spec Spec, draft Draft) (SeriesPart, []SeriesTerm, error) {
+	raw, _, err := a.complete(ctx, BuildSeriesSummaryPrompt(spec, draft))
+	if err != nil {
+		return SeriesPart{}, nil, err
+	}
+	var out seriesSummary
+	if err := json.Unmarshal([]byte(extractJSON(raw, '{', '}')), &out); err != nil {
+		return SeriesPart{}, nil, fmt.Errorf("parse series summary: %w", err)
+	}
+	if strings.TrimSpace(out.Summary) == "" {
+		return SeriesPart{}, nil, errors.New("model returned empty summary")
+	}
+	return SeriesPart{Title: draft.Title, Summary: strings.TrimSpace(out.Summary)}, out.Terms, nil
+}
@@ -2,20 +2,62 @@ package generator
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
 // Session 持有一次主题的多轮生成/修订上下文。
 type Session struct {
-	ID      string
+	ID string
+	// Owner 为创建 session 的用户，未启用登录时为空；模型用量同时计入该用户当天的预算。
+	Owner   string
 	Spec    Spec
 	Draft   Draft
 	History []Turn
-	agent   *Agent
+	// Usage 为本 session 累计的模型用量。
+	Usage Usage
+	// Outline 为大纲优先模式下当前的大纲（可空）。
+	Outline *Outline
+	// Variants 为并行生成的候选首稿，选定后写入 Draft。
+	Variants []Draft
+	// FactCheck 为最近一次事实核查报告（可空）。
+	FactCheck *FactCheckReport
+	// Source 为改写/翻译模式的原文（可空）。
+	Source *SourceDoc
+	// Translated 为 true 表示 Source 为外文原文，不做相似度比对。
+	Translated bool
+	// Originality 为最近一次改写稿与原文的相似度报告（可空）。
+	Originality *OriginalityReport
+	// HistorySummary 为早期修订轮次压缩后的编辑历史摘要（可空）。
+	HistorySummary string
+	// compacted 为已压缩进 HistorySummary 的 History 条数。
+	compacted int
+	// Quotes 为最近一次提取的金句，可选作摘要或封面文字。
+	Quotes []GoldenQuote
+	// reasoning 收集本轮模型调用的推理过程，记录 turn 时写入并清空。
+	reasoning []string
+	agent     *Agent
+	usageMu   sync.Mutex
+	// mu 保护 Spec 的写入：上传配图不经过 session 的执行锁，可能与正在进行的生成同时发生。
+	mu sync.Mutex
+}
+
+// SetAgent 切换 session 使用的 Agent，用于重新加载配置后让已有 session 使用新的模型与设置。
+// 调用方需保证该 session 没有正在执行的生成。
+func (s *Session) SetAgent(agent *Agent) {
+	s.agent = agent
 }
 
 // NewSession 创建 session，尚未生成稿件。
 func NewSession(id string, spec Spec, agent *Agent) *Session {
+	if spec.Style == "" && agent != nil {
+		spec.Style = agent.defaultStyle
+	}
 	return &Session{
 		ID:    id,
 		Spec:  spec,
@@ -23,34 +65,584 @@ func NewSession(id string, spec Spec, agent *Agent) *Session {
 	}
 }
 
+// SessionState 为 session 可持久化的状态快照。
+type SessionState struct {
+	ID             string
+	Owner          string `json:",omitempty"`
+	Spec           Spec
+	Draft          Draft
+	History        []Turn
+	Usage          Usage
+	Outline        *Outline           `json:",omitempty"`
+	Variants       []Draft            `json:",omitempty"`
+	FactCheck      *FactCheckReport   `json:",omitempty"`
+	Source         *SourceDoc         `json:",omitempty"`
+	Translated     bool               `json:",omitempty"`
+	Originality    *OriginalityReport `json:",omitempty"`
+	HistorySummary string             `json:",omitempty"`
+	Compacted      int                `json:",omitempty"`
+	Quotes         []GoldenQuote      `json:",omitempty"`
+}
+
+// State 返回当前状态快照，供持久化保存。
+func (s *Session) State() SessionState {
+	s.usageMu.Lock()
+	usage := s.Usage
+	s.usageMu.Unlock()
+	return SessionState{
+		ID:             s.ID,
+		Owner:          s.Owner,
+		Spec:           s.spec(),
+		Draft:          s.Draft,
+		History:        s.History,
+		Usage:          usage,
+		Outline:        s.Outline,
+		Variants:       s.Variants,
+		FactCheck:      s.FactCheck,
+		Source:         s.Source,
+		Translated:     s.Translated,
+		Originality:    s.Originality,
+		HistorySummary: s.HistorySummary,
+		Compacted:      s.compacted,
+		Quotes:         s.Quotes,
+	}
+}
+
+// RestoreSession 由快照重建 session。
+func RestoreSession(st SessionState, agent *Agent) *Session {
+	return &Session{
+		ID:             st.ID,
+		Owner:          st.Owner,
+		Spec:           st.Spec,
+		Draft:          st.Draft,
+		History:        st.History,
+		Usage:          st.Usage,
+		Outline:        st.Outline,
+		Variants:       st.Variants,
+		FactCheck:      st.FactCheck,
+		Source:         st.Source,
+		Translated:     st.Translated,
+		Originality:    st.Originality,
+		HistorySummary: st.HistorySummary,
+		compacted:      st.Compacted,
+		Quotes:         st.Quotes,
+		agent:          agent,
+	}
+}
+
 // Propose 生成首稿。
 func (s *Session) Propose(ctx context.Context) (Draft, error) {
-	draft, err := s.agent.Generate(ctx, s.Spec, nil, s.History, "")
+	// 记录首稿，使用中文备注便于前端展示
+	return s.run(ctx, "首稿", TurnInitial, func(ctx context.Context) (Draft, error) {
+		return s.agent.Generate(ctx, s.spec(), nil, s.History, "")
+	})
+}
+
+// Rewrite 按 session 的风格把原文改写为首稿，并生成相似度报告。
+func (s *Session) Rewrite(ctx context.Context, src SourceDoc) (Draft, error) {
+	if err := src.Validate(); err != nil {
+		return Draft{}, err
+	}
+	draft, err := s.run(ctx, "改写原文："+src.label(), TurnRewrite, func(ctx context.Context) (Draft, error) {
+		return s.agent.Rewrite(ctx, s.spec(), src)
+	})
 	if err != nil {
 		return Draft{}, err
 	}
-	s.Draft = draft
-	// 记录首稿，使用中文备注便于前端展示
-	s.appendTurn("首稿", draft, "首稿")
+	s.Source = &src
+	s.History[len(s.History)-1].Source = &src
+	s.CheckOriginality()
+	return draft, nil
+}
+
+// Translate 把外文原文翻译并本地化为中文首稿，原文随该轮记录在历史中。
+func (s *Session) Translate(ctx context.Context, src SourceDoc) (Draft, error) {
+	if err := src.Validate(); err != nil {
+		return Draft{}, err
+	}
+	draft, err := s.run(ctx, "翻译原文："+src.label(), TurnTranslate, func(ctx context.Context) (Draft, error) {
+		return s.agent.Translate(ctx, s.spec(), src)
+	})
+	if err != nil {
+		return Draft{}, err
+	}
+	s.Source = &src
+	s.Translated = true
+	s.History[len(s.History)-1].Source = &src
 	return draft, nil
 }
 
+// CheckOriginality 比较当前稿件与原文，更新并返回相似度报告；非改写 session 返回 nil。
+func (s *Session) CheckOriginality() *OriginalityReport {
+	if s.Source == nil || s.Translated {
+		return nil
+	}
+	if s.Originality == nil || s.Originality.DraftHash != draftHash(s.Draft.Markdown) {
+		report := CheckOriginality(s.Source.Text, s.Draft.Markdown)
+		s.Originality = &report
+	}
+	return s.Originality
+}
+
 // Revise 基于用户评论修订稿件。
 func (s *Session) Revise(ctx context.Context, comment string) (Draft, error) {
-	draft, err := s.agent.Generate(ctx, s.Spec, &s.Draft, s.History, comment)
+	return s.run(ctx, comment, TurnRevise, func(ctx context.Context) (Draft, error) {
+		return s.agent.Generate(ctx, s.spec(), &s.Draft, s.promptHistory(ctx), comment)
+	})
+}
+
+// ProposeStream 流式生成首稿，onChunk 接收模型增量输出。
+func (s *Session) ProposeStream(ctx context.Context, onChunk func(string)) (Draft, error) {
+	return s.run(ctx, "首稿", TurnInitial, func(ctx context.Context) (Draft, error) {
+		return s.agent.GenerateStream(ctx, s.spec(), nil, s.History, "", onChunk)
+	})
+}
+
+// Polish 润色稿件：只收紧语句、修正语病、理顺过渡，不改结构与事实。
+// focus 为可选的润色重点，例如“开头更抓人”。
+func (s *Session) Polish(ctx context.Context, focus string) (Draft, error) {
+	if s.Draft.Markdown == "" {
+		return Draft{}, errors.New("draft is empty; generate first")
+	}
+	comment := "润色"
+	if focus != "" {
+		comment += "：" + focus
+	}
+	return s.run(ctx, comment, TurnPolish, func(ctx context.Context) (Draft, error) {
+		return s.agent.Polish(ctx, s.spec(), s.Draft, focus)
+	})
+}
+
+// ReviseStream 流式修订稿件。
+func (s *Session) ReviseStream(ctx context.Context, comment string, onChunk func(string)) (Draft, error) {
+	return s.run(ctx, comment, TurnRevise, func(ctx context.Context) (Draft, error) {
+		return s.agent.GenerateStream(ctx, s.spec(), &s.Draft, s.promptHistory(ctx), comment, onChunk)
+	})
+}
+
+// PlaceImages 把已登记但未插入的配图交给模型排入正文。
+func (s *Session) PlaceImages(ctx context.Context) (Draft, error) {
+	if s.Draft.Markdown == "" {
+		return Draft{}, errors.New("draft is empty; generate first")
+	}
+	var pending []ImageRef
+	for _, img := range s.Images() {
+		if !strings.Contains(s.Draft.Markdown, img.Path) {
+			pending = append(pending, img)
+		}
+	}
+	if len(pending) == 0 {
+		return s.Draft, nil
+	}
+	return s.run(ctx, fmt.Sprintf("插入 %d 张配图", len(pending)), TurnImages, func(ctx context.Context) (Draft, error) {
+		return s.agent.PlaceImages(ctx, s.spec(), s.Draft, pending)
+	})
+}
+
+// RegenerateSection 重写单个小节；comment 为空时仅要求润色扩充该节。
+func (s *Session) RegenerateSection(ctx context.Context, heading, comment string) (Draft, error) {
+	if s.Draft.Markdown == "" {
+		return Draft{}, errors.New("draft is empty; generate first")
+	}
+	note := "重写小节：" + heading
+	if comment != "" {
+		note += "（" + comment + "）"
+	}
+	return s.run(ctx, note, TurnSection, func(ctx context.Context) (Draft, error) {
+		return s.agent.RegenerateSection(ctx, s.spec(), s.Draft, heading, comment)
+	})
+}
+
+// variantAngles 为多份候选首稿各自的切入角度，避免同一提示词生成几乎相同的候选。
+var variantAngles = []string{
+	"从一个具体的日常生活场景切入",
+	"从一个常见的误解切入，再逐步澄清",
+	"从一个值得追问的问题切入",
+	"从一个简短的小故事或案例切入",
+	"从一个反直觉的事实或数据切入",
+}
+
+// variantPrompt 返回第 i 份候选的首稿提示词：在用户提示词后附上该候选的切入角度。
+func variantPrompt(spec Spec, i int) Prompt {
+	prompt := BuildInitialPrompt(spec)
+	prompt.User += fmt.Sprintf("\n\n这是多份候选稿中的第 %d 份：请%s，开头、结构与举例都要与其他候选明显不同。", i+1, variantAngles[i%len(variantAngles)])
+	return prompt
+}
+
+// ProposeVariants 生成 n 份候选首稿，每份使用不同的切入角度，全部失败时返回第一个错误；
+// 候选保存在 Variants 中，需通过 PickVariant 选定。第一份单独生成，以其用量估算每份的花费，
+// 其余候选只在预算足够覆盖时并行生成，预算不足时返回已生成的候选。
+func (s *Session) ProposeVariants(ctx context.Context, n int) ([]Draft, error) {
+	if n < 1 {
+		return nil, errors.New("variants must be at least 1")
+	}
+	ctx, err := s.metered(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usage := func() Usage {
+		s.usageMu.Lock()
+		defer s.usageMu.Unlock()
+		return s.Usage
+	}
+
+	before := usage()
+	results := make([]Draft, n)
+	errs := make([]error, n)
+	spec := s.spec()
+	results[0], errs[0] = s.agent.generateDraft(ctx, variantPrompt(spec, 0), spec)
+
+	// 第 k 份候选开始前的用量按已用量加上 k-1 份的估算计算，与逐份校验的结果一致。
+	used := usage()
+	per := used
+	per.sub(before)
+	start := 1
+	for ; start < n; start++ {
+		if err := s.agent.budget.CheckProjected(s.Owner, used, per.times(start-1)); err != nil {
+			for i := start; i < n; i++ {
+				errs[i] = err
+			}
+			break
+		}
+	}
+	var wg sync.WaitGroup
+	for i := 1; i < start; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = s.agent.generateDraft(ctx, variantPrompt(spec, i), spec)
+		}(i)
+	}
+	wg.Wait()
+
+	var variants []Draft
+	var firstErr error
+	for i := range results {
+		if errs[i] != nil {
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		variants = append(variants, results[i])
+	}
+	if len(variants) == 0 {
+		return nil, firstErr
+	}
+	s.Variants = variants
+	return variants, nil
+}
+
+// PickVariant 选定第 index 份候选作为当前稿件，并记录为首稿。
+func (s *Session) PickVariant(index int) (Draft, error) {
+	if index < 0 || index >= len(s.Variants) {
+		return Draft{}, fmt.Errorf("variant %d out of range (have %d)", index, len(s.Variants))
+	}
+	draft := s.Variants[index]
+	draft.Sensitive = s.agent.ScanSensitive(draft.Markdown)
+	s.Draft = draft
+	s.appendTurn(fmt.Sprintf("选用候选 %d", index+1), draft, TurnInitial)
+	return draft, nil
+}
+
+// SuggestTitles 为当前稿件生成 n 个备选标题，score 为 true 时附带评分并排序。
+func (s *Session) SuggestTitles(ctx context.Context, n int, score bool) ([]TitleCandidate, error) {
+	if s.Draft.Markdown == "" {
+		return nil, errors.New("draft is empty; generate first")
+	}
+	ctx, err := s.metered(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.agent.SuggestTitles(ctx, s.spec(), s.Draft, n, score)
+}
+
+// ApplyTitle 把标题写入当前稿件（含正文一级标题），并记录一轮。
+func (s *Session) ApplyTitle(title string) (Draft, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return Draft{}, errors.New("title is empty")
+	}
+	if s.Draft.Markdown == "" {
+		return Draft{}, errors.New("draft is empty; generate first")
+	}
+	if err := s.checkHistory(); err != nil {
+		return Draft{}, err
+	}
+	draft := replaceTitle(s.Draft, title)
+	draft.Sensitive = s.agent.ScanSensitive(draft.Markdown)
+	s.Draft = draft
+	s.appendTurn("标题改为："+title, draft, TurnTitle)
+	return draft, nil
+}
+
+// ExtractQuotes 从当前稿件中提取 n 句金句并保存，供选作摘要或封面文字。
+func (s *Session) ExtractQuotes(ctx context.Context, n int) ([]GoldenQuote, error) {
+	if s.Draft.Markdown == "" {
+		return nil, errors.New("draft is empty; generate first")
+	}
+	ctx, err := s.metered(ctx)
+	if err != nil {
+		return nil, err
+	}
+	quotes, err := s.agent.ExtractQuotes(ctx, s.spec(), s.Draft, n)
 	if err != nil {
+		return nil, err
+	}
+	s.Quotes = quotes
+	return quotes, nil
+}
+
+// Quote 返回第 index 句金句（从 0 开始）。
+func (s *Session) Quote(index int) (GoldenQuote, error) {
+	if index < 0 || index >= len(s.Quotes) {
+		return GoldenQuote{}, fmt.Errorf("quote index %d out of range (have %d)", index, len(s.Quotes))
+	}
+	return s.Quotes[index], nil
+}
+
+// ApplyDigest 把摘要写入当前稿件（超过上限时截断），并记录一轮。
+func (s *Session) ApplyDigest(digest string) (Draft, error) {
+	digest = strings.TrimSpace(digest)
+	if digest == "" {
+		return Draft{}, errors.New("digest is empty")
+	}
+	if s.Draft.Markdown == "" {
+		return Draft{}, errors.New("draft is empty; generate first")
+	}
+	if err := s.checkHistory(); err != nil {
 		return Draft{}, err
 	}
+	draft := s.Draft
+	draft.Digest = truncateRunes(digest, maxDigestRunes)
 	s.Draft = draft
-	s.appendTurn(comment, draft, "修订")
+	s.appendTurn("摘要改为："+draft.Digest, draft, TurnDigest)
 	return draft, nil
 }
 
-func (s *Session) appendTurn(comment string, draft Draft, summary string) {
+// LoadReferences 抓取并摘要参考链接，成功的摘要写入 Spec.References。
+func (s *Session) LoadReferences(ctx context.Context, urls []string) ([]Reference, error) {
+	ctx, err := s.metered(ctx)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := s.agent.LoadReferences(ctx, urls)
+	if err != nil {
+		return refs, err
+	}
+	s.mu.Lock()
+	s.Spec.References = refs
+	s.mu.Unlock()
+	return refs, nil
+}
+
+// Research 联网检索主题资料并写入 Spec.Research，供后续生成引用。
+func (s *Session) Research(ctx context.Context, query string) ([]SearchResult, error) {
+	results, err := s.agent.Research(ctx, s.spec(), query)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.Spec.Research = results
+	s.mu.Unlock()
+	return results, nil
+}
+
+// SummarizeForSeries 概括当前稿件，返回可追加到系列的篇目与新术语。
+func (s *Session) SummarizeForSeries(ctx context.Context) (SeriesPart, []SeriesTerm, error) {
+	if s.Draft.Markdown == "" {
+		return SeriesPart{}, nil, errors.New("draft is empty; generate first")
+	}
+	ctx, err := s.metered(ctx)
+	if err != nil {
+		return SeriesPart{}, nil, err
+	}
+	part, terms, err := s.agent.SummarizeForSeries(ctx, s.spec(), s.Draft)
+	if err != nil {
+		return SeriesPart{}, nil, err
+	}
+	part.SessionID = s.ID
+	return part, terms, nil
+}
+
+// RunFactCheck 核查当前稿件并保存报告。
+func (s *Session) RunFactCheck(ctx context.Context) (FactCheckReport, error) {
+	if s.Draft.Markdown == "" {
+		return FactCheckReport{}, errors.New("draft is empty; generate first")
+	}
+	ctx, err := s.metered(ctx)
+	if err != nil {
+		return FactCheckReport{}, err
+	}
+	report, err := s.agent.FactCheck(ctx, s.spec(), s.Draft)
+	if err != nil {
+		return FactCheckReport{}, err
+	}
+	s.FactCheck = &report
+	return report, nil
+}
+
+// FactCheckCurrent 报告存在且对应当前稿件时返回 true。
+func (s *Session) FactCheckCurrent() bool {
+	return s.FactCheck != nil && s.FactCheck.DraftHash == draftHash(s.Draft.Markdown)
+}
+
+// AppendCitations 把最近一次核查的参考资料追加到稿件末尾。
+func (s *Session) AppendCitations() (Draft, error) {
+	if !s.FactCheckCurrent() {
+		return Draft{}, errors.New("fact check report missing or outdated; run fact check first")
+	}
+	section := s.FactCheck.CitationsMarkdown()
+	if section == "" {
+		return Draft{}, errors.New("fact check report has no citations")
+	}
+	if err := s.checkHistory(); err != nil {
+		return Draft{}, err
+	}
+	draft := s.Draft
+	draft.Markdown = strings.TrimRight(draft.Markdown, "\n") + "\n\n" + section
+	draft.WordCount = CountWords(draft.Markdown)
+	draft.Sensitive = s.agent.ScanSensitive(draft.Markdown)
+	s.Draft = draft
+	s.appendTurn("追加参考资料", draft, TurnRevise)
+	return draft, nil
+}
+
+// ProposeOutline 生成大纲并保存到 session，不产生稿件。
+func (s *Session) ProposeOutline(ctx context.Context) (Outline, error) {
+	ctx, err := s.metered(ctx)
+	if err != nil {
+		return Outline{}, err
+	}
+	outline, err := s.agent.Outline(ctx, s.spec())
+	if err != nil {
+		return Outline{}, err
+	}
+	s.Outline = &outline
+	return outline, nil
+}
+
+// Expand 按大纲生成全文；outline 非空时先以用户编辑后的大纲替换当前大纲。
+func (s *Session) Expand(ctx context.Context, outline *Outline) (Draft, error) {
+	if outline != nil {
+		if err := outline.Validate(); err != nil {
+			return Draft{}, err
+		}
+		s.Outline = outline
+	}
+	if s.Outline == nil {
+		return Draft{}, errors.New("outline is empty; generate outline first")
+	}
+	approved := *s.Outline
+	return s.run(ctx, "按大纲展开", TurnInitial, func(ctx context.Context) (Draft, error) {
+		return s.agent.Expand(ctx, s.spec(), approved)
+	})
+}
+
+// metered 校验预算并挂上用量回调，后续模型调用的用量计入本 session。
+func (s *Session) metered(ctx context.Context) (context.Context, error) {
+	if err := s.agent.budget.Check(s.Owner, s.Usage); err != nil {
+		return ctx, err
+	}
+	if s.agent.keepReasoning {
+		s.usageMu.Lock()
+		s.reasoning = nil
+		s.usageMu.Unlock()
+		ctx = WithReasoningSink(ctx, func(text string) {
+			s.usageMu.Lock()
+			s.reasoning = append(s.reasoning, text)
+			s.usageMu.Unlock()
+		})
+	}
+	return WithUsageSink(ctx, func(u Usage) {
+		u = s.agent.budget.Record(s.Owner, u)
+		s.usageMu.Lock()
+		s.Usage.add(u)
+		s.usageMu.Unlock()
+	}), nil
+}
+
+// run 执行一次模型调用：先校验预算，再累计用量，成功后更新稿件并记录 turn。
+func (s *Session) run(ctx context.Context, comment string, kind TurnKind, fn func(context.Context) (Draft, error)) (Draft, error) {
+	if err := s.checkHistory(); err != nil {
+		return Draft{}, err
+	}
+	ctx, err := s.metered(ctx)
+	if err != nil {
+		return Draft{}, err
+	}
+	draft, err := fn(ctx)
+	if err != nil {
+		return Draft{}, err
+	}
+	draft = s.screen(ctx, draft, s.agent.sensitiveAuto && kind != TurnSensitive)
+	s.Draft = draft
+	s.appendTurn(comment, draft, kind)
+	return draft, nil
+}
+
+// screen 标出稿件中的敏感词；rephrase 为 true 且有命中时先请模型改写，失败时保留原稿。
+func (s *Session) screen(ctx context.Context, draft Draft, rephrase bool) Draft {
+	draft.Sensitive = s.agent.ScanSensitive(draft.Markdown)
+	if !rephrase || len(draft.Sensitive) == 0 {
+		return draft
+	}
+	next, err := s.agent.RephraseSensitive(ctx, s.spec(), draft, draft.Sensitive)
+	if err != nil {
+		log.Printf("[Sensitive] session=%s rephrase failed: %v", s.ID, err)
+		return draft
+	}
+	next.Sensitive = s.agent.ScanSensitive(next.Markdown)
+	return next
+}
+
+// RephraseSensitive 改写当前稿件中命中敏感词的语句，记录为一轮。
+func (s *Session) RephraseSensitive(ctx context.Context) (Draft, error) {
+	if s.Draft.Markdown == "" {
+		return Draft{}, errors.New("draft is empty; generate first")
+	}
+	hits := s.agent.ScanSensitive(s.Draft.Markdown)
+	if len(hits) == 0 {
+		return Draft{}, errors.New("no sensitive words found")
+	}
+	return s.run(ctx, "敏感词改写", TurnSensitive, func(ctx context.Context) (Draft, error) {
+		return s.agent.RephraseSensitive(ctx, s.spec(), s.Draft, hits)
+	})
+}
+
+func (s *Session) appendTurn(comment string, draft Draft, kind TurnKind) {
+	s.usageMu.Lock()
+	reasoning := strings.Join(s.reasoning, "\n\n---\n\n")
+	s.reasoning = nil
+	s.usageMu.Unlock()
 	s.History = append(s.History, Turn{
 		Comment:   comment,
 		Draft:     draft,
-		Summary:   summary,
+		Summary:   string(kind),
+		Kind:      kind,
 		CreatedAt: time.Now(),
+		Provider:  draft.Provider,
+		Reasoning: reasoning,
 	})
 }
+
+// AddImage 登记一张正文配图，后续生成/修订会参考它插图。
+func (s *Session) AddImage(img ImageRef) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Spec.Images = append(s.Spec.Images, img)
+}
+
+// Images 返回已登记的正文配图。
+func (s *Session) Images() []ImageRef {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.Spec.Images)
+}
+
+// spec 返回 Spec 的快照供模型调用使用，避免与 AddImage 同时读写 Spec.Images。
+func (s *Session) spec() Spec {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Spec
+}
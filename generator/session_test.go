@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// variantLLM 返回固定的 JSON 稿件并上报固定用量，记录收到的用户提示词。
+type variantLLM struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (v *variantLLM) Complete(ctx context.Context, prompt Prompt) (string, error) {
+	v.mu.Lock()
+	v.prompts = append(v.prompts, prompt.User)
+	v.mu.Unlock()
+	ReportUsage(ctx, Usage{PromptTokens: 60, CompletionTokens: 40})
+	return `{"title":"标题","digest":"摘要","markdown":"# 标题\n\n正文"}`, nil
+}
+
+func (v *variantLLM) Stream(ctx context.Context, prompt Prompt, onChunk func(string)) (string, error) {
+	return v.Complete(ctx, prompt)
+}
+
+func TestProposeVariantsBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxTokens int
+		want      int
+	}{
+		{name: "unlimited", want: 5},
+		// 第一份用去 100 token，之后第 k 份开始前的预计用量为 100*k，低于 250 的只有两份。
+		{name: "session budget", maxTokens: 250, want: 3},
+		{name: "room for one", maxTokens: 100, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := &variantLLM{}
+			agent, err := NewAgent(llm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			agent.SetBudget(NewBudget(BudgetConfig{SessionMaxTokens: tt.maxTokens}))
+			sess := NewSession("s1", Spec{Topic: "测试"}, agent)
+			variants, err := sess.ProposeVariants(context.Background(), 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(variants) != tt.want || len(llm.prompts) != tt.want {
+				t.Fatalf("got %d variants from %d calls, want %d", len(variants), len(llm.prompts), tt.want)
+			}
+			seen := map[string]bool{}
+			for _, p := range llm.prompts {
+				if seen[p] {
+					t.Fatalf("two variants got the same prompt:\n%s", p)
+				}
+				seen[p] = true
+			}
+		})
+	}
+}
+
+func TestVariantPromptAngles(t *testing.T) {
+	for i, angle := range variantAngles {
+		if p := variantPrompt(Spec{Topic: "测试"}, i); !strings.Contains(p.User, angle) {
+			t.Fatalf("variant %d prompt lacks angle %q", i, angle)
+		}
+	}
+}
+
+// 上传配图与生成可能同时进行，用 go test -race 检查。
+func TestAddImageDuringGenerate(t *testing.T) {
+	agent, err := NewAgent(&variantLLM{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess := NewSession("s1", Spec{Topic: "测试"}, agent)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			sess.AddImage(ImageRef{Path: "uploads/a.png"})
+		}
+	}()
+	if _, err := sess.Propose(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	if n := len(sess.Images()); n != 20 {
+		t.Fatalf("got %d images, want 20", n)
+	}
+}
@@ -0,0 +1,105 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+const (
+	// maxDigestRunes 为摘要的最大字数（公众号摘要上限 120 字）。
+	maxDigestRunes = 120
+	// maxStructuredRepairs 为 JSON 输出校验失败后最多请求修复的次数。
+	maxStructuredRepairs = 2
+)
+
+// structuredDraft 为 JSON 模式下模型返回的稿件结构。
+type structuredDraft struct {
+	Title      string   `json:"title"`
+	Digest     string   `json:"digest"`
+	Markdown   string   `json:"markdown"`
+	CoverHint  string   `json:"cover_hint"`
+	ImageHints []string `json:"image_hints"`
+}
+
+// parseStructuredDraft 解析并校验 JSON 输出：markdown 必填，title 缺失时须能从正文一级标题取得。
+func parseStructuredDraft(raw string) (structuredDraft, error) {
+	var d structuredDraft
+	if err := json.Unmarshal([]byte(extractJSON(raw, '{', '}')), &d); err != nil {
+		return d, fmt.Errorf("invalid json: %w", err)
+	}
+	d.Title = strings.TrimSpace(d.Title)
+	d.Markdown = strings.TrimSpace(d.Markdown)
+	switch {
+	case d.Markdown == "":
+		return d, errors.New(`field "markdown" is required`)
+	case d.Title == "" && extractTitle(d.Markdown) == "":
+		return d, errors.New(`field "title" is required`)
+	}
+	return d, nil
+}
+
+// toDraft 转为 Draft：正文缺少一级标题时补上，摘要超长时截断。
+func (d structuredDraft) toDraft() Draft {
+	title := d.Title
+	if title == "" {
+		title = extractTitle(d.Markdown)
+	}
+	md := d.Markdown
+	if extractTitle(md) == "" {
+		md = "# " + title + "\n\n" + md
+	}
+	var hints []string
+	for _, h := range d.ImageHints {
+		if h = strings.TrimSpace(h); h != "" {
+			hints = append(hints, h)
+		}
+	}
+	return Draft{
+		Title:            title,
+		Digest:           truncateRunes(strings.TrimSpace(d.Digest), maxDigestRunes),
+		Markdown:         md,
+		CoverHint:        strings.TrimSpace(d.CoverHint),
+		InlineImageHints: hints,
+	}
+}
+
+// generateDraft 要求模型以 JSON 返回稿件并校验结构（服务商支持时使用其 JSON 模式），失败时把错误交给模型修复，
+// 最多 maxStructuredRepairs 次；服务商忽略 JSON 要求直接返回 Markdown 时按 Markdown 处理。
+func (a *Agent) generateDraft(ctx context.Context, prompt Prompt, spec Spec) (Draft, error) {
+	prompt.JSON = true
+	prompt.System = strings.TrimRight(prompt.System, "\n") + "\n" + renderPrompt("draft_json.tmpl", promptData{Count: maxDigestRunes})
+	raw, provider, err := a.complete(ctx, prompt)
+	if err != nil {
+		return Draft{}, err
+	}
+	// 未使用或不支持 JSON 模式的服务商可能在 JSON 前后附加说明文字，能从中解析出稿件时照常使用。
+	if !strings.HasPrefix(stripCodeFence(raw), "{") {
+		if _, perr := parseStructuredDraft(raw); perr != nil {
+			log.Printf("[structured] provider %s returned non-JSON output; falling back to markdown", provider)
+			return a.finish(ctx, raw, spec, provider)
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		d, perr := parseStructuredDraft(raw)
+		if perr == nil {
+			draft := d.toDraft()
+			draft.Provider = provider
+			draft.WordCount = CountWords(draft.Markdown)
+			if draft, err = a.validate(ctx, spec, draft); err != nil {
+				return Draft{}, err
+			}
+			return a.enforceWordCount(ctx, spec, draft), nil
+		}
+		if attempt == maxStructuredRepairs {
+			return Draft{}, fmt.Errorf("structured output still invalid after %d repairs: %w", maxStructuredRepairs, perr)
+		}
+		log.Printf("[structured] output invalid (%v); repair attempt %d", perr, attempt+1)
+		if raw, _, err = a.complete(ctx, BuildJSONRepairPrompt(raw, perr.Error())); err != nil {
+			return Draft{}, err
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StylePreset 为一个写作风格预设。
+type StylePreset struct {
+	Key    string `json:"key" yaml:"key"`
+	Name   string `json:"name" yaml:"name"`
+	Prompt string `json:"prompt" yaml:"prompt"`
+	// Sampling 为该风格默认的采样参数，session 中的 sampling 优先。
+	Sampling *SamplingParams `json:"sampling,omitempty" yaml:"sampling,omitempty"`
+	// Builtin 表示内置风格（来自 stylePresets）。
+	Builtin bool `json:"builtin" yaml:"-"`
+
+	file string
+}
+
+// DefaultStylesDir 为未配置 styles_dir 时使用的目录。
+const DefaultStylesDir = "styles"
+
+// ErrBuiltinStyle 表示试图删除没有自定义覆盖的内置风格。
+var ErrBuiltinStyle = errors.New("builtin style cannot be deleted")
+
+// ErrStyleNotFound 表示风格不存在。
+var ErrStyleNotFound = errors.New("style not found")
+
+var styleKeyRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// 内置风格的展示名称。
+var builtinStyleNames = map[string]string{
+	"life-rational": "生活化·理性",
+	"warm-healing":  "温和·治愈",
+	"novelistic":    "小说式",
+}
+
+var (
+	styleMu     sync.RWMutex
+	customStyle = map[string]StylePreset{}
+)
+
+// StyleSet 为从目录读取的自定义风格，调用 Use 后才替换当前使用的风格。
+type StyleSet struct {
+	dir    string
+	styles map[string]StylePreset
+}
+
+// ReadStyles 从 dir 读取自定义风格（*.yaml / *.yml / *.md），与内置风格同 key 时覆盖内置。
+// Markdown 文件使用 YAML front matter 声明 key/name/sampling，正文即风格提示词；
+// 未声明 key 时取文件名。目录不存在时视为没有自定义风格。读取不影响当前使用的风格。
+func ReadStyles(dir string) (*StyleSet, error) {
+	styles := map[string]StylePreset{}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read styles dir: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		st, ok, err := parseStyleFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("style %s: %w", e.Name(), err)
+		}
+		if ok {
+			styles[st.Key] = st
+		}
+	}
+	return &StyleSet{dir: dir, styles: styles}, nil
+}
+
+// Lookup 在这组自定义风格与内置风格中按 key 查找，用于使用前校验配置。
+func (s *StyleSet) Lookup(key string) (StylePreset, bool) {
+	return lookupStyle(s.styles, key)
+}
+
+// Use 让之后的生成使用这组自定义风格。
+func (s *StyleSet) Use() {
+	styleMu.Lock()
+	customStyle = s.styles
+	styleMu.Unlock()
+	log.Printf("[Style] loaded %d custom styles from %s", len(s.styles), s.dir)
+}
+
+// LoadStyles 从 dir 读取自定义风格并立即使用，见 ReadStyles。
+func LoadStyles(dir string) error {
+	set, err := ReadStyles(dir)
+	if err != nil {
+		return err
+	}
+	set.Use()
+	return nil
+}
+
+func parseStyleFile(path string) (StylePreset, bool, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".yaml" && ext != ".yml" && ext != ".md" {
+		return StylePreset{}, false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return StylePreset{}, false, err
+	}
+
+	var st StylePreset
+	if ext == ".md" {
+		meta, body := splitFrontMatter(data)
+		if len(meta) > 0 {
+			if err := yaml.Unmarshal(meta, &st); err != nil {
+				return StylePreset{}, false, err
+			}
+		}
+		st.Prompt = string(body)
+	} else if err := yaml.Unmarshal(data, &st); err != nil {
+		return StylePreset{}, false, err
+	}
+
+	if st.Key == "" {
+		st.Key = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if st.Name == "" {
+		st.Name = st.Key
+	}
+	st.Prompt = strings.TrimSpace(st.Prompt)
+	if st.Prompt == "" {
+		return StylePreset{}, false, fmt.Errorf("prompt is empty")
+	}
+	st.file = path
+	return st, true, nil
+}
+
+// splitFrontMatter 拆分 "---" 包裹的 YAML 头与正文；没有头时全部视为正文。
+func splitFrontMatter(data []byte) (meta, body []byte) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !bytes.HasPrefix(data, []byte("---")) {
+		return nil, data
+	}
+	rest := data[3:]
+	end := bytes.Index(rest, []byte("\n---"))
+	if end < 0 {
+		return nil, data
+	}
+	meta = rest[:end]
+	body = rest[end+4:]
+	if i := bytes.IndexByte(body, '\n'); i >= 0 {
+		body = body[i+1:]
+	} else {
+		body = nil
+	}
+	return meta, body
+}
+
+// WatchStyles 定期检查 dir 的文件变化并重新加载，实现热更新；ctx 结束时退出。
+func WatchStyles(ctx context.Context, dir string, interval time.Duration) {
+	last := styleDirSignature(dir)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sig := styleDirSignature(dir)
+			if sig == last {
+				continue
+			}
+			last = sig
+			if err := LoadStyles(dir); err != nil {
+				log.Printf("[Style] reload failed: %v", err)
+			}
+		}
+	}
+}
+
+func styleDirSignature(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s|%d|%d;", e.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return sb.String()
+}
+
+// Styles 返回全部可用风格：内置在前（按 key 排序），自定义在后。
+func Styles() []StylePreset {
+	styleMu.RLock()
+	defer styleMu.RUnlock()
+
+	var builtin, custom []StylePreset
+	for key, prompt := range stylePresets {
+		if _, ok := customStyle[key]; ok {
+			continue
+		}
+		builtin = append(builtin, StylePreset{Key: key, Name: builtinStyleNames[key], Prompt: prompt, Builtin: true})
+	}
+	for _, st := range customStyle {
+		custom = append(custom, st)
+	}
+	sort.Slice(builtin, func(i, j int) bool { return builtin[i].Key < builtin[j].Key })
+	sort.Slice(custom, func(i, j int) bool { return custom[i].Key < custom[j].Key })
+	return append(builtin, custom...)
+}
+
+// LookupStyle 按 key 查找风格，自定义风格优先。
+func LookupStyle(key string) (StylePreset, bool) {
+	styleMu.RLock()
+	defer styleMu.RUnlock()
+	return lookupStyle(customStyle, key)
+}
+
+func lookupStyle(custom map[string]StylePreset, key string) (StylePreset, bool) {
+	if st, ok := custom[key]; ok {
+		return st, true
+	}
+	if prompt, ok := stylePresets[key]; ok {
+		return StylePreset{Key: key, Name: builtinStyleNames[key], Prompt: prompt, Builtin: true}, true
+	}
+	return StylePreset{}, false
+}
+
+// 串行化风格文件的写入与删除。
+var styleFileMu sync.Mutex
+
+// SaveStyle 校验并把风格写入 dir/<key>.yaml，随后重新加载；已有同 key 的文件会被替换。
+func SaveStyle(dir string, st StylePreset) (StylePreset, error) {
+	st.Key = strings.TrimSpace(st.Key)
+	st.Name = strings.TrimSpace(st.Name)
+	st.Prompt = strings.TrimSpace(st.Prompt)
+	if !styleKeyRe.MatchString(st.Key) {
+		return StylePreset{}, fmt.Errorf("invalid style key %q: use letters, digits, '-' or '_'", st.Key)
+	}
+	if st.Prompt == "" {
+		return StylePreset{}, errors.New("prompt is empty")
+	}
+	if st.Name == "" {
+		st.Name = st.Key
+	}
+
+	styleFileMu.Lock()
+	defer styleFileMu.Unlock()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return StylePreset{}, fmt.Errorf("create styles dir: %w", err)
+	}
+	data, err := yaml.Marshal(st)
+	if err != nil {
+		return StylePreset{}, err
+	}
+	path := filepath.Join(dir, st.Key+".yaml")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return StylePreset{}, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return StylePreset{}, err
+	}
+	// 同 key 的旧文件（例如 .md）一并移除，避免加载时互相覆盖。
+	if old, ok := LookupStyle(st.Key); ok && old.file != "" && old.file != path {
+		if err := os.Remove(old.file); err != nil && !os.IsNotExist(err) {
+			return StylePreset{}, err
+		}
+	}
+	if err := LoadStyles(dir); err != nil {
+		return StylePreset{}, err
+	}
+	saved, _ := LookupStyle(st.Key)
+	return saved, nil
+}
+
+// DeleteStyle 删除自定义风格文件；内置风格的覆盖被删除后恢复为内置版本。
+func DeleteStyle(dir, key string) error {
+	styleFileMu.Lock()
+	defer styleFileMu.Unlock()
+
+	st, ok := LookupStyle(key)
+	if !ok {
+		return ErrStyleNotFound
+	}
+	if st.Builtin {
+		return ErrBuiltinStyle
+	}
+	if err := os.Remove(st.file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return LoadStyles(dir)
+}
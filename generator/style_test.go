@@ -0,0 +1,58 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadStylesAppliesOnlyOnUse(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "calm.yaml"), []byte("name: 平静\nprompt: 平静地写\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	set, err := ReadStyles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := set.Lookup("calm"); !ok {
+		t.Fatal("Lookup did not find the style just read")
+	}
+	if _, ok := LookupStyle("calm"); ok {
+		t.Fatal("style took effect before Use")
+	}
+	set.Use()
+	t.Cleanup(func() { (&StyleSet{styles: map[string]StylePreset{}}).Use() })
+	if _, ok := LookupStyle("calm"); !ok {
+		t.Fatal("style not in effect after Use")
+	}
+}
+
+func TestParsePromptTemplatesAppliesOnlyOnUse(t *testing.T) {
+	before := renderPrompt("initial_user.tmpl", promptData{Spec: Spec{Topic: "示例"}})
+
+	bad := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bad, "initial_user.tmpl"), []byte(`{{define "initial_user.tmpl"}}{{.Missing}}{{end}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParsePromptTemplates(bad); err == nil {
+		t.Fatal("broken template parsed without error")
+	}
+
+	good := t.TempDir()
+	if err := os.WriteFile(filepath.Join(good, "initial_user.tmpl"), []byte(`{{define "initial_user.tmpl"}}自定义：{{.Spec.Topic}}{{end}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := ParsePromptTemplates(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := renderPrompt("initial_user.tmpl", promptData{Spec: Spec{Topic: "示例"}}); got != before {
+		t.Fatalf("template took effect before Use: %q", got)
+	}
+	p.Use()
+	t.Cleanup(func() { _ = LoadPromptTemplates("") })
+	if got := renderPrompt("initial_user.tmpl", promptData{Spec: Spec{Topic: "示例"}}); got != "自定义：示例" {
+		t.Fatalf("after Use rendered %q", got)
+	}
+}
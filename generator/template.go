@@ -0,0 +1,134 @@
+package generator
+
+import (
+	"embed"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// 默认提示词模板，可通过 LoadPromptTemplates 从目录覆盖同名文件。
+//
+//go:embed prompts/*.tmpl
+var defaultPromptFS embed.FS
+
+var promptFuncs = template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}
+
+var (
+	promptMu       sync.RWMutex
+	promptTmpl     = template.Must(parsePromptTemplates(""))
+	defaultPrompts = template.Must(parsePromptTemplates(""))
+)
+
+// promptData 为模板可用的数据。
+type promptData struct {
+	Spec        Spec
+	StyleKey    string
+	StylePrompt string
+	MaxWords    int
+	Draft       Draft
+	Comment     string
+	Images      []ImageRef
+	Outline     Outline
+	Section     string
+	Count       int
+	Titles      []string
+	SourceTitle string
+	Source      string
+	SourceLang  string
+	Audience    string
+	Problems    []string
+	Summary     string
+	Comments    []string
+	Sensitive   []SensitiveHit
+	AllowHTML   bool
+	// Shorten 为 true 表示精简，否则扩写（字数调整模板使用）。
+	Shorten bool
+	// Sampling 为风格默认采样参数与 spec 覆盖合并后的结果，不参与渲染。
+	Sampling *SamplingParams
+}
+
+// promptTemplateNames 为加载自定义模板时试渲染的模板。
+var promptTemplateNames = []string{"initial_system.tmpl", "initial_user.tmpl", "revision_system.tmpl", "revision_user.tmpl", "placement_system.tmpl", "placement_user.tmpl", "outline_system.tmpl", "outline_user.tmpl", "expand_system.tmpl", "expand_user.tmpl", "section_system.tmpl", "section_user.tmpl", "titles_system.tmpl", "titles_user.tmpl", "title_score_system.tmpl", "title_score_user.tmpl", "polish_system.tmpl", "polish_user.tmpl", "length_system.tmpl", "length_user.tmpl", "factcheck_system.tmpl", "factcheck_user.tmpl", "reference_system.tmpl", "reference_user.tmpl", "rewrite_system.tmpl", "rewrite_user.tmpl", "translate_system.tmpl", "translate_user.tmpl", "series_summary_system.tmpl", "series_summary_user.tmpl", "ideas_system.tmpl", "ideas_user.tmpl", "draft_json.tmpl", "json_repair_system.tmpl", "json_repair_user.tmpl", "repair_system.tmpl", "repair_user.tmpl", "history_summary_system.tmpl", "history_summary_user.tmpl", "sensitive_system.tmpl", "sensitive_user.tmpl", "quotes_system.tmpl", "quotes_user.tmpl"}
+
+// PromptTemplates 为已解析并试渲染通过的提示词模板，调用 Use 后才替换当前使用的模板。
+type PromptTemplates struct {
+	t *template.Template
+}
+
+// ParsePromptTemplates 从 dir 读取 *.tmpl 覆盖内置模板（文件名相同即覆盖，例如 initial_system.tmpl），
+// dir 为空时为内置模板。读取时会试渲染以尽早发现错误，但不影响当前使用的模板。
+func ParsePromptTemplates(dir string) (*PromptTemplates, error) {
+	t, err := parsePromptTemplates(dir)
+	if err != nil {
+		return nil, err
+	}
+	sample := promptData{Spec: Spec{Topic: "示例", Words: 800, Outline: []string{"背景"}, Constraints: []string{"示例"}, Audience: "示例读者", Tone: "示例语气", Taboo: []string{"示例"}, Series: &Series{Title: "示例系列"}}, MaxWords: 960}
+	for _, name := range promptTemplateNames {
+		if err := t.ExecuteTemplate(&strings.Builder{}, name, sample); err != nil {
+			return nil, fmt.Errorf("prompt template %s: %w", name, err)
+		}
+	}
+	return &PromptTemplates{t: t}, nil
+}
+
+// Use 让之后渲染的提示词使用这些模板。
+func (p *PromptTemplates) Use() {
+	promptMu.Lock()
+	promptTmpl = p.t
+	promptMu.Unlock()
+}
+
+// LoadPromptTemplates 从 dir 加载模板并立即使用，见 ParsePromptTemplates；dir 为空时恢复内置模板。
+func LoadPromptTemplates(dir string) error {
+	p, err := ParsePromptTemplates(dir)
+	if err != nil {
+		return err
+	}
+	p.Use()
+	return nil
+}
+
+func parsePromptTemplates(dir string) (*template.Template, error) {
+	t, err := template.New("prompts").Funcs(promptFuncs).ParseFS(defaultPromptFS, "prompts/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return t, nil
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("prompts dir: %w", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return t, nil
+	}
+	return t.ParseFiles(files...)
+}
+
+// renderPrompt 渲染模板；自定义模板出错时记录日志并回退到内置模板。
+func renderPrompt(name string, data promptData) string {
+	promptMu.RLock()
+	t := promptTmpl
+	promptMu.RUnlock()
+
+	var sb strings.Builder
+	if err := t.ExecuteTemplate(&sb, name, data); err != nil {
+		log.Printf("[Prompt] render %s failed: %v; falling back to default", name, err)
+		sb.Reset()
+		if err := defaultPrompts.ExecuteTemplate(&sb, name, data); err != nil {
+			log.Printf("[Prompt] render default %s failed: %v", name, err)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
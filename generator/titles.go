@@ -0,0 +1,137 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxTitleRunes 为公众号标题的长度上限。
+const MaxTitleRunes = 64
+
+// TitleCandidate 为一个备选标题及其评分。未评分时各分值为 0。
+type TitleCandidate struct {
+	Title        string  `json:"title"`
+	Clickability int     `json:"clickability,omitempty"`
+	Clarity      int     `json:"clarity,omitempty"`
+	Reason       string  `json:"reason,omitempty"`
+	LengthOK     bool    `json:"length_ok"`
+	Score        float64 `json:"score"`
+}
+
+// SuggestTitles 生成 n 个备选标题；score 为 true 时再请模型从点击意愿、清晰度两方面打分并排序。
+// 超过 64 字符的标题排在最后。
+func (a *Agent) SuggestTitles(ctx context.Context, spec Spec, draft Draft, n int, score bool) ([]TitleCandidate, error) {
+	raw, _, err := a.complete(ctx, BuildTitlesPrompt(spec, draft, n))
+	if err != nil {
+		return nil, err
+	}
+	var titles []string
+	if err := json.Unmarshal([]byte(extractJSON(raw, '[', ']')), &titles); err != nil {
+		return nil, fmt.Errorf("parse titles: %w", err)
+	}
+
+	seen := map[string]bool{}
+	var out []TitleCandidate
+	for _, t := range titles {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, TitleCandidate{Title: t, LengthOK: utf8.RuneCountInString(t) <= MaxTitleRunes})
+	}
+	if len(out) == 0 {
+		return nil, errors.New("model returned no titles")
+	}
+
+	if score {
+		if err := a.scoreTitles(ctx, spec, draft, out); err != nil {
+			return nil, err
+		}
+	}
+	for i := range out {
+		if !out[i].LengthOK {
+			out[i].Score = 0
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].LengthOK != out[j].LengthOK {
+			return out[i].LengthOK
+		}
+		return out[i].Score > out[j].Score
+	})
+	return out, nil
+}
+
+// scoreTitles 为候选标题填充评分，综合分为两项均值。
+func (a *Agent) scoreTitles(ctx context.Context, spec Spec, draft Draft, cands []TitleCandidate) error {
+	titles := make([]string, len(cands))
+	for i, c := range cands {
+		titles[i] = c.Title
+	}
+	raw, _, err := a.complete(ctx, BuildTitleScorePrompt(spec, draft, titles))
+	if err != nil {
+		return err
+	}
+	var scored []TitleCandidate
+	if err := json.Unmarshal([]byte(extractJSON(raw, '[', ']')), &scored); err != nil {
+		return fmt.Errorf("parse title scores: %w", err)
+	}
+	byTitle := map[string]TitleCandidate{}
+	for _, s := range scored {
+		byTitle[strings.TrimSpace(s.Title)] = s
+	}
+	for i := range cands {
+		s, ok := byTitle[cands[i].Title]
+		if !ok && i < len(scored) {
+			s = scored[i]
+		}
+		cands[i].Clickability = clampScore(s.Clickability)
+		cands[i].Clarity = clampScore(s.Clarity)
+		cands[i].Reason = strings.TrimSpace(s.Reason)
+		cands[i].Score = float64(cands[i].Clickability+cands[i].Clarity) / 2
+	}
+	return nil
+}
+
+func clampScore(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > 10 {
+		return 10
+	}
+	return v
+}
+
+// extractJSON 去掉代码块包裹，并截取首个 open 到最后一个 close 之间的内容。
+func extractJSON(raw string, open, close byte) string {
+	text := stripCodeFence(raw)
+	if i := strings.IndexByte(text, open); i > 0 {
+		text = text[i:]
+	}
+	if i := strings.LastIndexByte(text, close); i >= 0 && i < len(text)-1 {
+		text = text[:i+1]
+	}
+	return text
+}
+
+var h1Re = regexp.MustCompile(`(?m)^#\s+.+$`)
+
+// replaceTitle 替换稿件标题及正文中的一级标题；没有一级标题时在开头补上。
+func replaceTitle(draft Draft, title string) Draft {
+	draft.Title = title
+	if loc := h1Re.FindStringIndex(draft.Markdown); loc != nil {
+		draft.Markdown = draft.Markdown[:loc[0]] + "# " + title + draft.Markdown[loc[1]:]
+	} else {
+		draft.Markdown = "# " + title + "\n\n" + draft.Markdown
+	}
+	draft.WordCount = CountWords(draft.Markdown)
+	return draft
+}
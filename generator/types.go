@@ -9,6 +9,27 @@ type Spec struct {
 	Words       int
 	Constraints []string
 	Style       string
+	// Audience 为目标读者，Tone 为语气，Taboo 为全文不得出现的内容/说法。
+	Audience string
+	Tone     string
+	Taboo    []string
+	// Sampling 为 session 级采样参数覆盖（可空）。
+	Sampling *SamplingParams
+	// Images 为用户上传的正文配图，生成/修订时由模型插入合适位置。
+	Images []ImageRef
+	// References 为参考链接的摘要，作为写作素材注入提示词。
+	References []Reference
+	// Research 为写作前联网检索到的资料片段。
+	Research []SearchResult
+	// Series 为所属系列在创建 session 时的快照（可空），用于保持前后篇连贯。
+	Series *Series
+}
+
+// ImageRef 描述一张可插入正文的图片。
+type ImageRef struct {
+	Path    string
+	Alt     string
+	Caption string
 }
 
 // Draft is the模型产出的稿件（Markdown 形式）。
@@ -19,12 +40,42 @@ type Draft struct {
 	// 预留扩展字段（暂不处理图片）。
 	CoverHint        string
 	InlineImageHints []string
+	// Provider 记录产出该稿件的模型服务商（回退链中的名称）。
+	Provider string
+	// WordCount 为正文字数（见 CountWords）。
+	WordCount int
+	// Sensitive 为正文中的敏感词命中（见 SensitiveFilter），无命中时为空。
+	Sensitive []SensitiveHit `json:",omitempty"`
 }
 
+// TurnKind 区分一轮稿件变更的类型，便于历史中区分首稿/修订/润色等。
+type TurnKind string
+
+const (
+	TurnInitial   TurnKind = "首稿"
+	TurnRevise    TurnKind = "修订"
+	TurnPolish    TurnKind = "润色"
+	TurnImages    TurnKind = "插图"
+	TurnSection   TurnKind = "小节"
+	TurnTitle     TurnKind = "标题"
+	TurnRewrite   TurnKind = "改写"
+	TurnTranslate TurnKind = "翻译"
+	TurnSensitive TurnKind = "敏感词"
+	TurnDigest    TurnKind = "摘要"
+	TurnRollback  TurnKind = "回滚"
+)
+
 // Turn 记录一次评论驱动的修订。
 type Turn struct {
 	Comment   string
 	Draft     Draft
 	Summary   string
+	Kind      TurnKind
 	CreatedAt time.Time
+	// Provider 为本轮实际使用的模型服务商。
+	Provider string
+	// Source 为改写/翻译轮次所依据的原文（可空）。
+	Source *SourceDoc `json:",omitempty"`
+	// Reasoning 为推理模型本轮的思考过程，仅在开启 record_reasoning 时记录，供调试。
+	Reasoning string `json:",omitempty"`
 }
@@ -0,0 +1,135 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// maxRepairAttempts 为稿件校验失败后自动请求修复的最多次数。
+const maxRepairAttempts = 2
+
+var (
+	// metaCommentRe 匹配模型常见的开场白与结束语，如“以下是……”“希望这篇文章对你有帮助”。
+	metaCommentRe = regexp.MustCompile(`^(以下是|下面是|好的[，,。！!]|当然[，,。！!]|没问题[，,。！!]|希望(这篇|本文|以上)|如需|如果(你|您)(还)?(需要|想))`)
+	// rawHTMLRe 匹配原始 HTML 标签与注释；<https://...> 这类自动链接不会命中。
+	rawHTMLRe    = regexp.MustCompile(`<!--|</?[A-Za-z][A-Za-z0-9-]*(\s[^<>]*)?/?>`)
+	inlineCodeRe = regexp.MustCompile("`[^`\n]*`")
+)
+
+// ValidationError 表示稿件未通过结构校验，Problems 为逐条问题描述。
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "draft validation failed: " + strings.Join(e.Problems, "；")
+}
+
+// ValidateMarkdown 检查稿件结构：恰好一个一级标题、没有“以下是……”之类的说明文字、
+// 代码块均已闭合、除非 allowHTML 否则不含原始 HTML。返回发现的问题，全部通过时为空。
+func ValidateMarkdown(md string, allowHTML bool) []string {
+	var (
+		problems  []string
+		h1        int
+		fence     string
+		prose     []string
+		htmlLines int
+	)
+	for _, line := range strings.Split(md, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if marker := fenceMarker(trimmed); marker != "" {
+			switch {
+			case fence == "":
+				fence = marker
+			case marker[0] == fence[0] && len(marker) >= len(fence) && strings.Trim(trimmed, marker[:1]) == "":
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+		if strings.HasPrefix(line, "# ") || line == "#" {
+			h1++
+		}
+		if trimmed != "" {
+			prose = append(prose, trimmed)
+		}
+		if !allowHTML && rawHTMLRe.MatchString(inlineCodeRe.ReplaceAllString(line, "")) {
+			htmlLines++
+		}
+	}
+
+	switch {
+	case h1 == 0:
+		problems = append(problems, "缺少一级标题（需以“# 标题”开头）")
+	case h1 > 1:
+		problems = append(problems, fmt.Sprintf("出现 %d 个一级标题，只能保留一个，其余改为二级标题", h1))
+	}
+	if n := len(prose); n > 0 {
+		for _, p := range uniqueLines(prose[0], prose[n-1]) {
+			if metaCommentRe.MatchString(p) {
+				problems = append(problems, fmt.Sprintf("包含面向提问者的说明文字「%s」，需删除", truncateRunes(p, 30)))
+			}
+		}
+	}
+	if fence != "" {
+		problems = append(problems, "存在未闭合的代码块（"+fence+"）")
+	}
+	if htmlLines > 0 {
+		problems = append(problems, fmt.Sprintf("%d 处使用了原始 HTML 标签，需改为 Markdown 语法", htmlLines))
+	}
+	return problems
+}
+
+// fenceMarker 返回代码块围栏标记（``` 或 ~~~ 及更长），不是围栏行时返回空串。
+func fenceMarker(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+func uniqueLines(first, last string) []string {
+	if first == last {
+		return []string{first}
+	}
+	return []string{first, last}
+}
+
+// SetAllowHTML 设置稿件中是否允许原始 HTML（默认不允许）。
+func (a *Agent) SetAllowHTML(allow bool) {
+	a.allowHTML = allow
+}
+
+// validate 校验稿件结构，不通过时把问题交给模型修复，最多 maxRepairAttempts 次，
+// 仍不通过则返回 *ValidationError。
+func (a *Agent) validate(ctx context.Context, spec Spec, draft Draft) (Draft, error) {
+	for attempt := 0; ; attempt++ {
+		problems := ValidateMarkdown(draft.Markdown, a.allowHTML)
+		if len(problems) == 0 {
+			return draft, nil
+		}
+		if attempt == maxRepairAttempts {
+			return Draft{}, &ValidationError{Problems: problems}
+		}
+		log.Printf("[Validate] attempt=%d problems=%q", attempt+1, problems)
+		raw, provider, err := a.complete(ctx, BuildRepairPrompt(draft, problems, a.allowHTML))
+		if err != nil {
+			return Draft{}, err
+		}
+		next, err := postProcessFrom(raw, spec, provider)
+		if err != nil {
+			return Draft{}, err
+		}
+		// 修复只改正文，沿用原稿的摘要与配图建议。
+		next.Digest, next.CoverHint, next.InlineImageHints = draft.Digest, draft.CoverHint, draft.InlineImageHints
+		draft = next
+	}
+}
@@ -0,0 +1,26 @@
+package generator
+
+import "fmt"
+
+// Version 返回第 n 个版本（即第 n 轮修订后的稿件，从 1 开始）。
+func (s *Session) Version(n int) (Draft, error) {
+	if n < 1 || n > len(s.History) {
+		return Draft{}, fmt.Errorf("version %d out of range (have %d)", n, len(s.History))
+	}
+	return s.History[n-1].Draft, nil
+}
+
+// Rollback 把稿件恢复为第 n 个版本，并记录为新的一轮，之后的版本仍保留在历史中。
+func (s *Session) Rollback(n int) (Draft, error) {
+	draft, err := s.Version(n)
+	if err != nil {
+		return Draft{}, err
+	}
+	if err := s.checkHistory(); err != nil {
+		return Draft{}, err
+	}
+	draft.Sensitive = s.agent.ScanSensitive(draft.Markdown)
+	s.Draft = draft
+	s.appendTurn(fmt.Sprintf("回滚到版本 %d", n), draft, TurnRollback)
+	return draft, nil
+}
@@ -0,0 +1,64 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrVisionUnsupported 表示当前模型客户端不支持图片理解。
+var ErrVisionUnsupported = errors.New("llm client does not support image input")
+
+// ImageCaption 视觉模型为图片生成的替代文本与图注。
+type ImageCaption struct {
+	Alt     string `json:"alt"`
+	Caption string `json:"caption"`
+}
+
+// ImageDescriber 由支持视觉输入的模型客户端实现。
+type ImageDescriber interface {
+	DescribeImage(ctx context.Context, data []byte, mimeType string) (ImageCaption, error)
+}
+
+const describeImagePrompt = `请用中文描述这张图片，供公众号文章配图使用。
+只输出 JSON，不要额外解释，格式：{"alt": "不超过 20 字的替代文本", "caption": "不超过 40 字的图注"}`
+
+// parseImageCaption 解析模型输出的 JSON；无法解析时把原文作为图注。
+func parseImageCaption(raw string) (ImageCaption, error) {
+	text := stripCodeFence(raw)
+	if text == "" {
+		return ImageCaption{}, errors.New("model returned empty caption")
+	}
+	var c ImageCaption
+	if err := json.Unmarshal([]byte(text), &c); err != nil || (c.Alt == "" && c.Caption == "") {
+		return ImageCaption{Alt: truncateRunes(text, 20), Caption: text}, nil
+	}
+	c.Alt = strings.TrimSpace(c.Alt)
+	c.Caption = strings.TrimSpace(c.Caption)
+	if c.Alt == "" {
+		c.Alt = truncateRunes(c.Caption, 20)
+	}
+	return c, nil
+}
+
+// stripCodeFence 去掉模型常见的 ```json ... ``` 包裹。
+func stripCodeFence(raw string) string {
+	text := strings.TrimSpace(raw)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```")
+		if i := strings.Index(text, "\n"); i >= 0 {
+			text = text[i+1:]
+		}
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+	return strings.TrimSpace(text)
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
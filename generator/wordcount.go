@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"context"
+	"log"
+	"math"
+	"regexp"
+	"unicode"
+)
+
+const (
+	// wordTolerance 为字数允许偏差，与提示词中的 ±15% 一致。
+	wordTolerance = 0.15
+	// maxWordFixAttempts 为字数不达标时最多追加的扩写/精简次数。
+	maxWordFixAttempts = 2
+)
+
+var (
+	mdImageRe = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLinkRe  = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	htmlTagRe = regexp.MustCompile(`<[^>]+>`)
+)
+
+// CountWords 统计正文字数：每个汉字计 1，连续的英文字母/数字计 1，
+// 不计标点、空白与 Markdown 语法（标题符号、链接地址、图片地址、HTML 标签等）。
+func CountWords(md string) int {
+	text := mdImageRe.ReplaceAllString(md, "$1")
+	text = mdLinkRe.ReplaceAllString(text, "$1")
+	text = htmlTagRe.ReplaceAllString(text, "")
+
+	count := 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			count++
+			inWord = false
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if !inWord {
+				count++
+				inWord = true
+			}
+		default:
+			inWord = false
+		}
+	}
+	return count
+}
+
+// wordsOff 判断字数是否超出容差，返回偏离方向：1 过长，-1 过短，0 达标。
+func wordsOff(count, target int) int {
+	if target <= 0 {
+		return 0
+	}
+	diff := float64(count-target) / float64(target)
+	switch {
+	case diff > wordTolerance:
+		return 1
+	case diff < -wordTolerance:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// enforceWordCount 在字数偏离目标过多时追加扩写/精简，最多 maxWordFixAttempts 次；
+// 追加调用失败时保留当前稿件。
+func (a *Agent) enforceWordCount(ctx context.Context, spec Spec, draft Draft) Draft {
+	for attempt := 0; attempt < maxWordFixAttempts; attempt++ {
+		dir := wordsOff(draft.WordCount, spec.Words)
+		if dir == 0 {
+			break
+		}
+		log.Printf("[WordCount] attempt=%d count=%d target=%d", attempt+1, draft.WordCount, spec.Words)
+		raw, provider, err := a.complete(ctx, BuildLengthPrompt(spec, draft, dir > 0))
+		if err != nil {
+			log.Printf("[WordCount] adjust failed: %v", err)
+			break
+		}
+		next, err := postProcessFrom(raw, spec, provider)
+		if err != nil {
+			log.Printf("[WordCount] adjust failed: %v", err)
+			break
+		}
+		// 调整后离目标更远或破坏了稿件结构则放弃本次结果。
+		if len(ValidateMarkdown(next.Markdown, a.allowHTML)) > 0 || math.Abs(float64(next.WordCount-spec.Words)) >= math.Abs(float64(draft.WordCount-spec.Words)) {
+			break
+		}
+		// 字数调整只改正文，沿用结构化输出中的摘要与配图建议。
+		next.Digest, next.CoverHint, next.InlineImageHints = draft.Digest, draft.CoverHint, draft.InlineImageHints
+		draft = next
+	}
+	return draft
+}
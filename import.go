@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// runImport 处理 `import hugo`、`import obsidian`、`import feishu`、`import docx`、`import html` 与 `import ipynb`
+// 子命令：读取 Hugo 站点的文章、Obsidian 仓库的笔记、飞书云文档或 Word/HTML/notebook 文件，按 publish batch 的流程发布到草稿箱。
+func runImport(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "obsidian":
+			return runImportObsidian(args[1:])
+		case "feishu":
+			return runImportFeishu(args[1:])
+		case "docx", "html", "ipynb":
+			return runImportFiles(args[0], args[1:])
+		}
+	}
+	if len(args) == 0 || args[0] != "hugo" {
+		return fmt.Errorf("usage: %s import hugo|obsidian|feishu|docx|html|ipynb <source> [flags]", os.Args[0])
+	}
+	fs := newFlagSet("import hugo", "<content-dir> [flags]",
+		"Publish the posts of a Hugo site to the draft box. Title, date, draft, author,\n"+
+			"description and the cover (cover.image, cover, image, featured_image, images, or a\n"+
+			"cover/feature image in the page bundle) are read from the front matter; common\n"+
+			"shortcodes are converted and images starting with / are read from --static.\n"+
+			"Draft and future posts are skipped unless --drafts or --future is given.")
+	staticDir := fs.String("static", "", "static directory for images starting with / (default <content-dir>/../static)")
+	defaultCover := fs.String("cover", "", "cover image for posts without a cover")
+	defaultAuthor := fs.String("author", "", "author for posts without an author")
+	drafts := fs.Bool("drafts", false, "include posts with draft: true")
+	future := fs.Bool("future", false, "include posts dated in the future")
+	since := fs.String("since", "", "only import posts dated on or after this date (YYYY-MM-DD)")
+	opts := addBatchFlags(fs, 1, "import-result.json")
+	dirs, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(dirs) != 1 {
+		fs.Usage()
+		return invalidf("exactly one content directory is required")
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	var sinceTime time.Time
+	if *since != "" {
+		if sinceTime, err = time.ParseInLocation("2006-01-02", *since, time.Local); err != nil {
+			return invalidf("invalid --since %q: use YYYY-MM-DD", *since)
+		}
+	}
+	dir := dirs[0]
+	if *staticDir == "" {
+		*staticDir = filepath.Join(filepath.Dir(filepath.Clean(dir)), "static")
+	}
+
+	paths, err := publisher.ListHugoContent(dir)
+	if err != nil {
+		return err
+	}
+	var entries []*batchEntry
+	dates := map[*batchEntry]time.Time{}
+	skipped := 0
+	for _, path := range paths {
+		post, err := publisher.ReadHugoPost(path, *staticDir)
+		if err == nil {
+			switch {
+			case post.Draft && !*drafts,
+				post.Date.After(time.Now()) && !*future,
+				!sinceTime.IsZero() && post.Date.Before(sinceTime):
+				skipped++
+				continue
+			}
+		}
+		e := hugoEntry(post, err, *defaultCover, *defaultAuthor)
+		dates[e] = post.Date
+		entries = append(entries, e)
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "skipped %d draft, future or older posts\n", skipped)
+	}
+	if len(entries) == 0 {
+		return invalidf("no posts to import under %s", dir)
+	}
+	// 按日期从旧到新发布，与博客中的先后一致。
+	sort.SliceStable(entries, func(i, j int) bool { return dates[entries[i]].Before(dates[entries[j]]) })
+	return publishBatch(dir, entries, opts)
+}
+
+// hugoEntry 把读取的文章转为 publish batch 的条目。
+func hugoEntry(post publisher.HugoPost, err error, defaultCover, defaultAuthor string) *batchEntry {
+	e := &batchEntry{path: post.Path, title: post.Title, cover: post.Cover, markdown: post.Markdown, err: err}
+	e.fm = publisher.FrontMatter{Title: post.Title, Cover: post.Cover, Author: post.Author, Digest: post.Digest}
+	return checkImportEntry(e, defaultCover, defaultAuthor, "set cover.image or images in front matter, add a cover image to the page bundle, or pass --cover")
+}
+
+// checkImportEntry 补上默认作者与封面，并检查标题、封面与正文；coverHint 为缺少封面时的提示。
+func checkImportEntry(e *batchEntry, defaultCover, defaultAuthor, coverHint string) *batchEntry {
+	if e.err != nil {
+		return e
+	}
+	if e.title == "" {
+		for _, line := range strings.Split(e.markdown, "\n") {
+			if t, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+				e.title = strings.TrimSpace(t)
+				break
+			}
+		}
+	}
+	if e.fm.Author == "" {
+		e.fm.Author = defaultAuthor
+	}
+	if e.cover == "" {
+		e.cover = defaultCover
+	}
+	switch {
+	case e.title == "":
+		e.err = errors.New("missing title: set title in front matter")
+	case e.cover == "":
+		e.err = errors.New("missing cover: " + coverHint)
+	case strings.TrimSpace(e.markdown) == "":
+		e.err = errors.New("empty post")
+	default:
+		if _, err := os.Stat(e.cover); err != nil {
+			e.err = fmt.Errorf("cover: %w", err)
+		}
+	}
+	return e
+}
+
+// runImportObsidian 发布 Obsidian 仓库中选定的笔记：命令行列出的笔记（路径或笔记名）与带 --tag 标签的笔记。
+func runImportObsidian(args []string) error {
+	fs := newFlagSet("import obsidian", "<vault> [note ...] [flags]",
+		"Publish selected notes of an Obsidian vault to the draft box: the notes given by path or\n"+
+			"name, plus every note tagged --tag. ![[image]] embeds and relative images are resolved in\n"+
+			"the vault; [[wikilinks]] become plain text, or numbered footnotes with --footnotes.\n"+
+			"Title, cover (cover, banner or image), author, description, group and order are read\n"+
+			"from the front matter; notes with draft: true or publish: false are skipped unless --drafts.")
+	tag := fs.String("tag", "", "also publish every note with this tag (front matter tags or inline #tag)")
+	footnotes := fs.Bool("footnotes", false, "turn [[wikilinks]] into numbered footnotes listing the linked notes")
+	defaultCover := fs.String("cover", "", "cover image for notes without a cover")
+	defaultAuthor := fs.String("author", "", "author for notes without an author")
+	drafts := fs.Bool("drafts", false, "include notes with draft: true or publish: false")
+	opts := addBatchFlags(fs, 1, "import-result.json")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		fs.Usage()
+		return invalidf("the vault directory is required")
+	}
+	if len(rest) == 1 && *tag == "" {
+		return invalidf("select notes by path or name, or with --tag")
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	vault, err := publisher.OpenObsidianVault(rest[0])
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	seen := map[string]bool{}
+	for _, arg := range rest[1:] {
+		path := filepath.Clean(arg)
+		if info, err := os.Stat(arg); err != nil || info.IsDir() {
+			var ok bool
+			if path, ok = vault.Resolve(arg, filepath.Join(vault.Dir, "_")); !ok {
+				return invalidf("note %q not found in %s", arg, vault.Dir)
+			}
+		}
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	tagged := 0
+	var entries []*batchEntry
+	skipped := 0
+	add := func(note publisher.ObsidianNote, err error) {
+		if err == nil && note.Draft && !*drafts {
+			skipped++
+			return
+		}
+		e := &batchEntry{path: note.Path, title: note.Title, cover: note.Cover, markdown: note.Markdown, err: err}
+		e.fm = publisher.FrontMatter{Title: note.Title, Cover: note.Cover, Author: note.Author, Digest: note.Digest, Group: note.Group, Order: note.Order}
+		entries = append(entries, checkImportEntry(e, *defaultCover, *defaultAuthor, "set cover in front matter or pass --cover"))
+	}
+	for _, path := range paths {
+		add(vault.ReadNote(path, *footnotes))
+	}
+	if *tag != "" {
+		want := strings.TrimPrefix(*tag, "#")
+		for _, path := range vault.Notes() {
+			if seen[path] {
+				continue
+			}
+			note, err := vault.ReadNote(path, *footnotes)
+			if !slices.Contains(note.Tags, want) {
+				continue
+			}
+			tagged++
+			add(note, err)
+		}
+		fmt.Fprintf(os.Stderr, "%d notes tagged #%s\n", tagged, want)
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "skipped %d draft notes\n", skipped)
+	}
+	if len(entries) == 0 {
+		return invalidf("no notes to import from %s", vault.Dir)
+	}
+	return publishBatch(vault.Dir, entries, opts)
+}
+
+// runImportFeishu 通过飞书开放接口下载文档（含图片）并转换为 Markdown 后发布，每篇文档一条草稿。
+func runImportFeishu(args []string) error {
+	fs := newFlagSet("import feishu", "<doc-url|doc-id> ... [flags]",
+		"Download Feishu (Lark) documents through the open API with the app credentials in the\n"+
+			"feishu section of the config, convert them to markdown and publish them to the draft box.\n"+
+			"Documents are given by their /docx/ or /wiki/ link or document ID; the document title is\n"+
+			"used as the article title and the first image as the cover unless --cover is given.")
+	defaultCover := fs.String("cover", "", "cover image for every document (default: the first image of each document)")
+	author := fs.String("author", "", "author name")
+	outDir := fs.String("out", "", "keep the converted markdown (<doc-id>.md) and images in this directory")
+	opts := addBatchFlags(fs, 1, "import-result.json")
+	refs, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(refs) == 0 {
+		fs.Usage()
+		return invalidf("at least one document link or ID is required")
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	cfg, err := publisher.LoadConfig(publisher.ResolveConfigPath(*opts.configPath))
+	if err != nil {
+		return err
+	}
+	if cfg.Feishu == nil {
+		return &publisher.ConfigError{Err: errors.New("feishu.app_id and feishu.app_secret are required to import feishu documents")}
+	}
+	fc, err := publisher.NewFeishuClient(*cfg.Feishu, nil)
+	if err != nil {
+		return &publisher.ConfigError{Err: err}
+	}
+	dir, source := *outDir, *outDir
+	if dir == "" {
+		if dir, err = os.MkdirTemp("", "feishu-import-"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		source = "feishu"
+	} else if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var entries []*batchEntry
+	for _, ref := range refs {
+		doc, err := fc.FetchDocument(ctx, ref, dir)
+		if err != nil {
+			entries = append(entries, &batchEntry{path: ref, err: err})
+			fmt.Fprintf(os.Stderr, "%s failed: %v\n", ref, err)
+			continue
+		}
+		cover, coverRef := *defaultCover, ""
+		if cover == "" && len(doc.Images) > 0 {
+			cover, coverRef = doc.Images[0], "images/"+filepath.Base(doc.Images[0])
+		}
+		path := filepath.Join(dir, doc.ID+".md")
+		if err := writeImported(path, doc.Title, coverRef, doc.Markdown); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s: %q, %d images\n", ref, doc.Title, len(doc.Images))
+		e := &batchEntry{path: path, title: doc.Title, cover: cover, markdown: doc.Markdown}
+		e.fm = publisher.FrontMatter{Title: doc.Title, Author: *author}
+		entries = append(entries, checkImportEntry(e, "", "", "the document has no images, pass --cover"))
+	}
+	return publishBatch(source, entries, opts)
+}
+
+// writeImported 把转换结果保存为带 front matter 的 Markdown，--out 时可修改后再用 publish batch 发布。
+func writeImported(path, title, coverRef, markdown string) error {
+	head := fmt.Sprintf("---\ntitle: %q\n", title)
+	if coverRef != "" {
+		head += fmt.Sprintf("cover: %s\n", coverRef)
+	}
+	return os.WriteFile(path, []byte(head+"---\n\n"+markdown), 0o644)
+}
+
+// fileImporters 为 import docx、html 与 ipynb 的转换函数及命令说明。
+var fileImporters = map[string]struct {
+	convert func(file, dir string) (publisher.ConvertedDocument, error)
+	help    string
+}{
+	"docx": {publisher.ConvertDocx, "Convert Word (.docx) files to markdown and publish them to the draft box, one draft per\n" +
+		"file. Headings, lists, tables, links and emphasis are kept; images are extracted to files and\n" +
+		"uploaded with the article."},
+	"html": {publisher.ConvertHTML, "Convert HTML files to markdown and publish them to the draft box, one draft per file.\n" +
+		"Headings, lists, tables, code, links and emphasis are kept; data URI and relative images are\n" +
+		"extracted to files and uploaded with the article."},
+	"ipynb": {publisher.ConvertNotebook, "Convert Jupyter notebooks (.ipynb) to markdown and publish them to the draft box, one\n" +
+		"draft per notebook. Markdown cells are kept, code cells become code blocks, image outputs\n" +
+		"(e.g. matplotlib plots) are extracted and uploaded as inline images, HTML outputs such as\n" +
+		"DataFrames become tables and text outputs become code blocks. Cells tagged remove-cell,\n" +
+		"remove-input or remove-output are trimmed accordingly."},
+}
+
+// runImportFiles 把 Word (.docx)、HTML 或 Jupyter notebook 文件转换为 Markdown（图片提取为文件）后发布，每个文件一条草稿。
+func runImportFiles(kind string, args []string) error {
+	importer := fileImporters[kind]
+	convert := importer.convert
+	fs := newFlagSet("import "+kind, "<file> ... [flags]", importer.help+"\n"+
+		"The document title (or the first heading) is used as the article title and the first\n"+
+		"image as the cover unless --cover is given.")
+	defaultCover := fs.String("cover", "", "cover image for every file (default: the first image of each file)")
+	author := fs.String("author", "", "author name")
+	outDir := fs.String("out", "", "keep the converted markdown (<name>.md) and images in this directory")
+	opts := addBatchFlags(fs, 1, "import-result.json")
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fs.Usage()
+		return invalidf("at least one %s file is required", kind)
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	dir, source := *outDir, *outDir
+	if dir == "" {
+		if dir, err = os.MkdirTemp("", kind+"-import-"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		source = kind
+	} else if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	var entries []*batchEntry
+	used := map[string]bool{}
+	for _, file := range files {
+		doc, err := convert(file, dir)
+		if err != nil {
+			entries = append(entries, &batchEntry{path: file, err: err})
+			fmt.Fprintf(os.Stderr, "%s failed: %v\n", file, err)
+			continue
+		}
+		cover, coverRef := *defaultCover, ""
+		if cover == "" && len(doc.Images) > 0 {
+			cover, coverRef = doc.Images[0], "images/"+filepath.Base(doc.Images[0])
+		}
+		base := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		path := filepath.Join(dir, base+".md")
+		for i := 2; used[path]; i++ {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d.md", base, i))
+		}
+		used[path] = true
+		e := &batchEntry{path: path, title: doc.Title, cover: cover, markdown: doc.Markdown}
+		e.fm = publisher.FrontMatter{Title: doc.Title, Author: *author}
+		// 先补上取自第一个标题的文章标题，再写入 front matter。
+		entries = append(entries, checkImportEntry(e, "", "", "the file has no images, pass --cover"))
+		if err := writeImported(path, e.title, coverRef, doc.Markdown); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s: %q, %d images\n", file, e.title, len(doc.Images))
+	}
+	return publishBatch(source, entries, opts)
+}
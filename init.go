@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"auto_wechat_article_publisher/generator"
+	"auto_wechat_article_publisher/publisher"
+)
+
+// initProviders 为 init 可选的模型服务商及默认模型；默认模型为空时需要用户填写。
+var initProviders = []struct{ name, model string }{
+	{"openai", "gpt-4.1-mini"},
+	{"deepseek", generator.DeepSeekDefaultModel},
+	{"qwen", generator.QwenDefaultModel},
+	{"gemini", generator.GeminiDefaultModel},
+	{"azure", ""},
+	{"mock", ""},
+}
+
+// runInit 处理 `init` 子命令：依次询问公众号凭据、模型、默认作者与写作风格，
+// 校验 access_token 与模型连通后写入配置文件。
+func runInit(args []string) error {
+	fs := newFlagSet("init", "[flags]", "Create a config file by answering a few questions. The WeChat credentials and\nthe LLM are checked before the file is written.")
+	configPath := fs.String("config", "config/config.json", "config file to write (.json, .yaml or .yml)")
+	force := fs.Bool("force", false, "overwrite an existing config file without asking")
+	skipCheck := fs.Bool("skip-check", false, "write the file without checking the WeChat credentials and the LLM")
+	fs.Parse(args)
+
+	path := *configPath
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".json" && ext != ".yaml" && ext != ".yml" {
+		return invalidf("init writes .json or .yaml config files, got %s", path)
+	}
+	in := bufio.NewReader(os.Stdin)
+	if _, err := os.Stat(path); err == nil && !*force {
+		ok, err := confirm(in, fmt.Sprintf("%s 已存在，覆盖？[y/N] ", path))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("aborted")
+		}
+	}
+
+	cfg, err := askConfig(in)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if ext != ".json" {
+		var doc any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		if data, err = yaml.Marshal(doc); err != nil {
+			return err
+		}
+	} else {
+		data = append(data, '\n')
+	}
+
+	// 先写入同目录的临时文件，按正式流程加载（环境变量、密钥引用与解密），校验通过后再改名。
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".init-*"+ext)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	loaded, err := publisher.LoadConfig(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("generated config is invalid: %w", err)
+	}
+
+	checks := map[string]string{}
+	if !*skipCheck {
+		failed := false
+		check := func(name, label string, fn func() error) {
+			fmt.Fprintf(os.Stderr, "检查%s… ", label)
+			if err := fn(); err != nil {
+				failed = true
+				checks[name] = err.Error()
+				fmt.Fprintf(os.Stderr, "失败：%v\n", err)
+				return
+			}
+			checks[name] = "ok"
+			fmt.Fprintln(os.Stderr, "成功")
+		}
+		check("wechat", "公众号凭据", func() error { return publisher.CheckAccessToken(nil, loaded) })
+		check("llm", "模型连通", func() error { return pingLLM(loaded) })
+		if failed {
+			ok, err := confirm(in, "检查未通过，仍然写入配置？[y/N] ")
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return errors.New("aborted: config not written")
+			}
+		}
+	}
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	output(map[string]any{"path": path, "checks": checks}, func() {
+		fmt.Fprintf(os.Stderr, "已写入 %s\n", path)
+		fmt.Fprintf(os.Stderr, "启动服务：%s serve --config %s\n", os.Args[0], path)
+		fmt.Fprintf(os.Stderr, "加密配置中的密钥：%s secrets encrypt --config %s\n", os.Args[0], path)
+	})
+	return nil
+}
+
+// askConfig 依次询问 init 需要的配置项。
+func askConfig(in *bufio.Reader) (publisher.Config, error) {
+	// 先读取当前目录下的自定义风格，便于直接选用。
+	_ = generator.LoadStyles(generator.DefaultStylesDir)
+	var cfg publisher.Config
+	var err error
+	if cfg.AppID, err = ask(in, "公众号 AppID", "", notEmpty); err != nil {
+		return cfg, err
+	}
+	if cfg.AppSecret, err = ask(in, "公众号 AppSecret（也可填 vault:// 等密钥引用）", "", notEmpty); err != nil {
+		return cfg, err
+	}
+
+	names := make([]string, len(initProviders))
+	for i, p := range initProviders {
+		names[i] = p.name
+	}
+	llm := &publisher.LLMConfig{}
+	llm.Provider, err = ask(in, "模型服务商（"+strings.Join(names, "/")+"）", "openai", func(v string) error {
+		for _, p := range initProviders {
+			if p.name == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("请从 %s 中选择", strings.Join(names, "、"))
+	})
+	if err != nil {
+		return cfg, err
+	}
+	if llm.Provider != "mock" {
+		defaultModel := ""
+		for _, p := range initProviders {
+			if p.name == llm.Provider {
+				defaultModel = p.model
+			}
+		}
+		label := "模型"
+		if llm.Provider == "azure" {
+			label = "模型部署名"
+		}
+		if llm.Model, err = ask(in, label, defaultModel, notEmpty); err != nil {
+			return cfg, err
+		}
+		keyEnv := publisher.EnvPrefix + "LLM_API_KEY"
+		if llm.APIKey, err = ask(in, "API Key（留空则运行时读取环境变量 "+keyEnv+"）", "", nil); err != nil {
+			return cfg, err
+		}
+		if llm.Provider == "azure" {
+			llm.BaseURL, err = ask(in, "Azure OpenAI 资源地址，如 https://xxx.openai.azure.com", "", notEmpty)
+		} else {
+			llm.BaseURL, err = ask(in, "接口地址（留空使用官方地址）", "", nil)
+		}
+		if err != nil {
+			return cfg, err
+		}
+	}
+	cfg.LLM = llm
+
+	if cfg.Author, err = ask(in, "默认作者（可留空）", "", nil); err != nil {
+		return cfg, err
+	}
+	var keys []string
+	for _, st := range generator.Styles() {
+		keys = append(keys, st.Key)
+	}
+	cfg.Style, err = ask(in, "默认写作风格（"+strings.Join(keys, "/")+"）", "life-rational", func(v string) error {
+		if _, ok := generator.LookupStyle(v); !ok {
+			return fmt.Errorf("未知风格 %s", v)
+		}
+		return nil
+	})
+	return cfg, err
+}
+
+// ask 输出提示并读取一行，空行使用默认值；check 非空时校验输入，不通过则重新询问。
+func ask(in *bufio.Reader, label, def string, check func(string) error) (string, error) {
+	for {
+		if def != "" {
+			fmt.Fprintf(os.Stderr, "%s [%s]：", label, def)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s：", label)
+		}
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			if err == io.EOF {
+				return "", errors.New("aborted: input closed")
+			}
+			return "", err
+		}
+		v := strings.TrimSpace(line)
+		if v == "" {
+			v = def
+		}
+		if check != nil {
+			if err := check(v); err != nil {
+				fmt.Fprintf(os.Stderr, "  %v\n", err)
+				continue
+			}
+		}
+		return v, nil
+	}
+}
+
+func notEmpty(v string) error {
+	if v == "" {
+		return errors.New("不能为空")
+	}
+	return nil
+}
+
+// confirm 询问是否继续，只有输入 y 或 yes 时返回 true。
+func confirm(in *bufio.Reader, label string) (bool, error) {
+	fmt.Fprint(os.Stderr, label)
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" && err != io.EOF {
+		return false, err
+	}
+	a := strings.ToLower(strings.TrimSpace(line))
+	return a == "y" || a == "yes", nil
+}
+
+// pingLLM 向配置的主模型发送一个极短请求。
+func pingLLM(cfg publisher.Config) error {
+	client, err := buildLLM(cfg)
+	if err != nil {
+		return err
+	}
+	agent, err := generator.NewAgent(client)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err = agent.Ping(ctx)
+	return err
+}
@@ -0,0 +1,29 @@
+package sigv4
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// 测试向量取自 AWS Signature Version 4 测试套件（get-vanilla-query-order-key-case）：查询参数须排序后签名。
+func TestSignQueryOrder(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/?Param2=value2&Param1=value1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred := Credentials{AccessKey: "AKIDEXAMPLE", SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	Sign(req, nil, cred, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Fatalf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://h/?b=2&a=x%2Fy&a=1&c=a+b", nil)
+	if got, want := CanonicalQuery(req.URL.Query()), "a=1&a=x%2Fy&b=2&c=a%20b"; got != want {
+		t.Fatalf("CanonicalQuery = %q, want %q", got, want)
+	}
+}
@@ -1,13 +1,32 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
+	"golang.org/x/crypto/acme/autocert"
+
+	"auto_wechat_article_publisher/client"
+	"auto_wechat_article_publisher/cover"
 	"auto_wechat_article_publisher/generator"
 	"auto_wechat_article_publisher/publisher"
 	"auto_wechat_article_publisher/server"
@@ -15,115 +34,1615 @@ import (
 
 var verbose bool
 
+// command 为一个子命令，run 接收子命令名之后的参数。
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"init", "create a config file interactively", runInit},
+	{"serve", "start the web server", runServe},
+	{"publish", "publish a markdown article, or a directory with publish batch, to the draft box", runPublish},
+	{"import", "publish posts of a Hugo site, Obsidian notes, Feishu documents or Word/HTML/notebook files to the draft box", runImport},
+	{"generate", "generate an article from a topic with the configured LLM", runGenerate},
+	{"rewrite", "rewrite an existing article in another style", func(args []string) error { return runRewrite(args, false) }},
+	{"translate", "translate an existing article into Chinese", func(args []string) error { return runRewrite(args, true) }},
+	{"draft", "list, update or delete drafts in the draft box", runDraft},
+	{"material", "list permanent materials", runMaterial},
+	{"comment", "list, elect or reply to comments of published articles, or open and close them", runComment},
+	{"masssend", "mass send a draft to followers, preview it, or check sent messages", runMassSend},
+	{"tag", "list, create or delete user tags, or tag followers for targeted mass sends", runTag},
+	{"quota", "show the WeChat API call quota, or reset it after hitting the limit", runQuota},
+	{"doctor", "diagnose the connection to the WeChat API, credentials and the IP whitelist", runDoctor},
+	{"export", "export published articles and drafts to a markdown archive", runExport},
+	{"preview", "render an article locally, or send a draft to a phone for preview (preview send)", runPreview},
+	{"history", "show publish history", runHistory},
+	{"stats", "show or collect read and share statistics of published articles", runStats},
+	{"schedule", "schedule publishes", runSchedule},
+	{"cover", "generate cover images", runCover},
+	{"user", "manage web users", runUser},
+	{"secrets", "encrypt or decrypt secrets in the config", runSecrets},
+}
+
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	configPath := flag.String("config", "config/config.json", "path to config.json")
-	mdPath := flag.String("md", "", "path to markdown file")
-	title := flag.String("title", "", "article title")
-	cover := flag.String("cover", "", "path to cover image")
-	author := flag.String("author", "", "author name")
-	digest := flag.String("digest", "", "article digest")
-	serve := flag.Bool("serve", false, "start web server")
-	addr := flag.String("addr", "", "http listen address when --serve (overrides config.server_addr)")
-	flag.BoolVar(&verbose, "v", false, "enable info logs")
-	flag.Parse()
-
-	// Web server mode
-	if *serve {
+	args := os.Args[1:]
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+	// --json 可以写在子命令之前，也可以作为子命令的参数。
+	for len(args) > 0 && (args[0] == "--json" || args[0] == "-json") {
+		jsonOutput, args = true, args[1:]
+	}
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+	name := args[0]
+	switch {
+	case name == "help" || name == "-h" || name == "--help":
+		if len(args) > 1 {
+			if cmd, ok := findCommand(args[1]); ok {
+				runCommand(cmd, []string{"-h"})
+				return
+			}
+		}
+		printUsage(os.Stdout)
+		return
+	case strings.HasPrefix(name, "-"):
+		// 兼容旧的平铺参数：带 --serve 时启动服务，否则发布草稿。
+		name = "publish"
+		if rest, ok := removeBoolFlag(args, "serve"); ok {
+			name, args = "serve", rest
+		}
+	default:
+		args = args[1:]
+	}
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+	runCommand(cmd, args)
+}
+
+func runCommand(cmd command, args []string) {
+	start := time.Now()
+	err := cmd.run(args)
+	var cliErr *cliError
+	if err != nil {
+		cliErr = newCLIError(err)
+	}
+	if jsonOutput {
+		res := cliResult{Command: cmd.name, OK: err == nil, DurationMS: time.Since(start).Milliseconds(), Result: commandResult, Error: cliErr}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(res)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if cliErr.ErrCode == publisher.ErrCodeQuotaExceeded {
+			fmt.Fprintf(os.Stderr, "hint: the API call quota is used up; check it with '%s quota show' and reset it with '%s quota clear' (10 times a month)\n", os.Args[0], os.Args[0])
+		}
+	}
+	if err != nil {
+		os.Exit(cliErr.ExitCode)
+	}
+}
+
+// 退出码按失败原因区分，便于脚本分支处理；--json 时 error.code 为对应的名称。
+// flag 解析失败与未知命令同样以 exitUsage 退出。
+const (
+	exitError      = 1 // error：其他错误
+	exitUsage      = 2 // usage：命令用法错误
+	exitValidation = 3 // validation：参数或输入内容不合法，如缺少必填参数、正文为空、文件不存在
+	exitConfig     = 4 // config：配置文件无法读取、解析或使用
+	exitWeChat     = 5 // wechat：微信接口返回错误，附 errcode 与 errmsg
+	exitLLM        = 6 // llm：模型服务返回错误或预算用尽
+	exitNetwork    = 7 // network：网络连接失败或超时
+	exitServer     = 8 // http（或服务返回的错误码）：--server 模式下服务返回错误
+	exitLocked     = 9 // locked：同一公众号的另一个发布正在运行
+)
+
+// validationError 表示参数或输入内容不合法。
+type validationError struct {
+	msg string
+}
+
+func (e *validationError) Error() string {
+	return e.msg
+}
+
+func invalidf(format string, args ...any) error {
+	return &validationError{msg: fmt.Sprintf(format, args...)}
+}
+
+// jsonOutput 为全局 --json：命令不再输出文本，结束时在 stdout 输出一个 cliResult。
+var jsonOutput bool
+
+// commandResult 为 --json 模式下命令的结果，由 output 设置。
+var commandResult any
+
+// cliResult 为 --json 模式的输出，失败时 Result 可能仍包含部分结果（如批量发布）。
+type cliResult struct {
+	Command    string    `json:"command"`
+	OK         bool      `json:"ok"`
+	DurationMS int64     `json:"duration_ms"`
+	Result     any       `json:"result,omitempty"`
+	Error      *cliError `json:"error,omitempty"`
+}
+
+// cliError 为失败原因：Code 与 ExitCode 见 exitError 等常量；
+// ErrCode、ErrMsg 为微信接口的错误码与说明，Status 为 --server 模式下服务返回的 HTTP 状态码。
+type cliError struct {
+	Message  string `json:"message"`
+	Code     string `json:"code"`
+	ExitCode int    `json:"exit_code"`
+	ErrCode  int    `json:"errcode,omitempty"`
+	ErrMsg   string `json:"errmsg,omitempty"`
+	Status   int    `json:"status,omitempty"`
+}
+
+// newCLIError 按失败原因分类；微信接口错误优先于网络错误，网络错误优先于模型错误。
+func newCLIError(err error) *cliError {
+	e := &cliError{Message: err.Error(), Code: "error", ExitCode: exitError}
+	var (
+		cfgErr    *publisher.ConfigError
+		lockedErr *publisher.LockedError
+		valErr    *validationError
+		draftErr  *generator.ValidationError
+		jobErr    *client.JobError
+		apiErr    *client.APIError
+		netErr    net.Error
+	)
+	switch {
+	case strings.HasPrefix(e.Message, "usage: "):
+		e.Code, e.ExitCode = "usage", exitUsage
+	case errors.As(err, &cfgErr):
+		e.Code, e.ExitCode = "config", exitConfig
+	case errors.As(err, &lockedErr):
+		e.Code, e.ExitCode = "locked", exitLocked
+	case errors.As(err, &valErr), errors.As(err, &draftErr), errors.Is(err, os.ErrNotExist):
+		e.Code, e.ExitCode = "validation", exitValidation
+	case publisher.WeChatErrorCode(err) != 0:
+		e.Code, e.ExitCode = "wechat", exitWeChat
+		e.ErrCode, e.ErrMsg = publisher.WeChatErrorCode(err), publisher.WeChatErrorMessage(err)
+	case errors.As(err, &jobErr) && jobErr.ErrCode != 0:
+		e.Code, e.ExitCode, e.ErrCode = "wechat", exitWeChat, jobErr.ErrCode
+	case errors.As(err, &netErr):
+		e.Code, e.ExitCode = "network", exitNetwork
+	case generator.IsLLMError(err):
+		e.Code, e.ExitCode = "llm", exitLLM
+	case errors.As(err, &apiErr):
+		e.Code, e.ExitCode, e.Status = "http", exitServer, apiErr.StatusCode
+		if apiErr.Code != "" {
+			e.Code = apiErr.Code
+		}
+	}
+	return e
+}
+
+// output 在 --json 模式下记录命令结果，否则调用 text 输出文本。
+func output(result any, text func()) {
+	if jsonOutput {
+		commandResult = result
+		return
+	}
+	text()
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "usage: %s <command> [flags]\n\ncommands:\n", os.Args[0])
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nRun '%s help <command>' for the flags of a command.\n", os.Args[0])
+}
+
+// newFlagSet 创建子命令的参数集（含全局的 --json），-h 时输出用法、说明与参数列表。
+func newFlagSet(name, usage, summary string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.BoolVar(&jsonOutput, "json", jsonOutput, "print the result as a single JSON object (same as --json before the command)")
+	fs.Usage = func() {
+		if describing {
+			panic(cmdSpec{path: name, usage: usage, summary: summary, fs: fs})
+		}
+		out := fs.Output()
+		fmt.Fprintf(out, "usage: %s %s %s\n\n%s\n", os.Args[0], name, usage, summary)
+		var hasFlags bool
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(out, "\nflags:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// parseInterspersed 解析参数，允许位置参数与 flag 交错出现，返回全部位置参数。
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// removeBoolFlag 从参数中去掉布尔 flag（-name、--name 或 --name=true），返回是否出现过。
+func removeBoolFlag(args []string, name string) ([]string, bool) {
+	var rest []string
+	var found bool
+	for _, arg := range args {
+		flagName, value, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && flagName == name {
+			found = value == "" || value == "true" || value == "1"
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, found
+}
+
+// runServe 启动 Web 服务。
+func runServe(args []string) error {
+	fs := newFlagSet("serve", "[flags]", "Start the web server.")
+	configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+	addr := fs.String("addr", "", "http listen address (overrides config.server_addr)")
+	staticDir := fs.String("static", "", "serve web UI from this directory instead of the embedded build (overrides config.static_dir)")
+	basePath := fs.String("base-path", "", "mount the app under this URL prefix, e.g. /wechat/ (overrides config.base_path)")
+	sessionTTL := fs.Int("session-ttl-minutes", 0, "minutes an idle session stays in memory (overrides config.sessions.ttl_minutes)")
+	sessionJanitor := fs.Int("session-janitor-seconds", 0, "interval for purging expired sessions (overrides config.sessions.janitor_seconds)")
+	maxSessions := fs.Int("max-sessions", 0, "max sessions kept in memory (overrides config.sessions.max_sessions)")
+	maxHistory := fs.Int("max-history", 0, "max revisions per session (overrides config.sessions.max_history)")
+	watchConfig := fs.Bool("watch-config", false, "reload the config automatically when it changes (SIGHUP and POST /api/admin/reload always reload)")
+	fs.BoolVar(&verbose, "v", false, "enable info logs")
+	fs.Parse(args)
+	*configPath = publisher.ResolveConfigPath(*configPath)
+
+	// loadConfig 读取配置并应用命令行参数的覆盖，重新加载配置时同样使用。
+	loadConfig := func() (publisher.Config, error) {
 		cfg, err := publisher.LoadConfig(*configPath)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+			return cfg, err
+		}
+		if *staticDir != "" {
+			cfg.StaticDir = *staticDir
+		}
+		if *basePath != "" {
+			cfg.BasePath = *basePath
+		}
+		if *sessionTTL != 0 || *sessionJanitor != 0 || *maxSessions != 0 || *maxHistory != 0 {
+			sc := publisher.SessionConfig{}
+			if cfg.Sessions != nil {
+				sc = *cfg.Sessions
+			}
+			if *sessionTTL != 0 {
+				sc.TTLMinutes = *sessionTTL
+			}
+			if *sessionJanitor != 0 {
+				sc.JanitorSeconds = *sessionJanitor
+			}
+			if *maxSessions != 0 {
+				sc.MaxSessions = *maxSessions
+			}
+			if *maxHistory != 0 {
+				sc.MaxHistory = *maxHistory
+			}
+			if err := publisher.ValidateSessions(&sc); err != nil {
+				return cfg, err
+			}
+			cfg.Sessions = &sc
+		}
+		return cfg, nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	agent, apply, err := buildAgent(cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	watchDir := stylesDir(cfg)
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	go generator.WatchStyles(watchCtx, watchDir, 5*time.Second)
+	srv, err := server.New(agent, cfg)
+	if err != nil {
+		stopWatch()
+		return err
+	}
+	srv.SetReloader(&server.ConfigReloader{
+		Path: *configPath,
+		Load: loadConfig,
+		Build: func(cfg publisher.Config, current *generator.Agent) (*generator.Agent, error) {
+			next, apply, err := buildAgent(cfg)
+			if err != nil {
+				return nil, err
+			}
+			apply()
+			next.Budget().CarryUsage(current.Budget())
+			if dir := stylesDir(cfg); dir != watchDir {
+				stopWatch()
+				watchDir = dir
+				watchCtx, stopWatch = context.WithCancel(context.Background())
+				go generator.WatchStyles(watchCtx, watchDir, 5*time.Second)
+			}
+			return next, nil
+		},
+	})
+	if *watchConfig {
+		go srv.WatchConfig(2 * time.Second)
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if _, err := srv.Reload("SIGHUP"); err != nil {
+				log.Printf("[config] reload %s failed, keeping the previous config: %v", *configPath, err)
+			}
 		}
-		llm, err := buildLLM(cfg)
+	}()
+	if cfg.Image != nil {
+		imageGen, err := buildImageGenerator(cfg)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+			stopWatch()
+			return err
 		}
-		agent, err := generator.NewAgent(llm)
+		srv.SetImageGenerator(imageGen)
+	}
+	listen := cfg.ServerAddr
+	if *addr != "" {
+		listen = *addr
+	}
+	if listen == "" {
+		listen = ":8080"
+		if cfg.TLS != nil {
+			listen = ":443"
+		}
+	}
+	if code := runServer(srv, listen, cfg); code != 0 {
+		os.Exit(code)
+	}
+	return nil
+}
+
+// runPublish 把 Markdown 发布到草稿箱，并记录发布历史。
+func runPublish(args []string) error {
+	if len(args) > 0 && args[0] == "batch" {
+		return runPublishBatch(args[1:])
+	}
+	fs := newFlagSet("publish", "--md article.md --title <title> --cover cover.jpg [flags] | --resume <manifest>",
+		"Publish a markdown article to the draft box and print its media_id.\nWith --update-media-id, the article replaces one in an existing draft instead.\nUploaded images and the cover are recorded in a manifest; when the publish fails,\n--resume retries from it without uploading them again.\nRun 'publish batch -h' to publish a directory of articles.")
+	configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+	mdPath := fs.String("md", "", "path to markdown file, - to read from stdin (relative image paths are resolved from the working directory)")
+	title := fs.String("title", "", "article title")
+	cover := fs.String("cover", "", "path to cover image")
+	author := fs.String("author", "", "author name")
+	digest := fs.String("digest", "", "article digest")
+	resume := fs.String("resume", "", "retry a failed publish from its manifest; --title, --cover, --author and --digest override the recorded values")
+	updateMediaID := fs.String("update-media-id", "", "update this existing draft instead of creating a new one; its media_id is kept")
+	updateIndex := fs.Int("update-index", 0, "index of the article to replace in the --update-media-id draft (0 for the first)")
+	lock := addLockFlags(fs)
+	manifestPath := fs.String("manifest", "", "manifest recording uploaded media (default <md>.publish.json next to the markdown, stdin.publish.json for stdin)")
+	crossPost := fs.String("cross-post", "", "after publishing, also write <md>.<platform>.html for these comma-separated platforms: zhihu, juejin, zsxq, or none (default cross_post in config)")
+	fs.BoolVar(&verbose, "v", false, "enable info logs")
+	remote := addRemoteFlags(fs)
+	fs.Parse(args)
+	*configPath = publisher.ResolveConfigPath(*configPath)
+	if *updateIndex < 0 || (*updateIndex > 0 && *updateMediaID == "") {
+		return invalidf("--update-index requires --update-media-id and must not be negative")
+	}
+	profiles, err := parseCrossPost(*crossPost)
+	if err != nil {
+		return err
+	}
+	if *crossPost != "" && remote.client() != nil {
+		return invalidf("--cross-post is not supported with --server")
+	}
+
+	var params publisher.PublishParams
+	if *resume != "" {
+		if *mdPath != "" {
+			return invalidf("--resume cannot be used with --md")
+		}
+		if remote.client() != nil {
+			return invalidf("--resume is not supported with --server")
+		}
+		manifest, err := publisher.LoadPublishManifest(*resume)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+			return err
+		}
+		if *title != "" {
+			manifest.Title = *title
+		}
+		if *cover != "" {
+			if manifest.CoverPath, err = filepath.Abs(*cover); err != nil {
+				return err
+			}
+		}
+		if *author != "" {
+			manifest.Author = *author
 		}
-		srv, err := server.New(agent, cfg)
+		if *digest != "" {
+			manifest.Digest = *digest
+		}
+		if *updateMediaID != "" {
+			manifest.UpdateMediaID, manifest.UpdateIndex = *updateMediaID, *updateIndex
+		}
+		params = manifest.Params()
+	} else {
+		if *mdPath == "" || *title == "" || *cover == "" {
+			fs.Usage()
+			return invalidf("--md, --title, and --cover are required")
+		}
+		params = publisher.PublishParams{
+			MarkdownPath:  *mdPath,
+			Title:         *title,
+			CoverPath:     *cover,
+			Author:        *author,
+			Digest:        *digest,
+			UpdateMediaID: *updateMediaID,
+			UpdateIndex:   *updateIndex,
+		}
+	}
+	if *mdPath == "-" {
+		data, err := io.ReadAll(os.Stdin)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+			return fmt.Errorf("read markdown from stdin: %w", err)
 		}
-		listen := cfg.ServerAddr
-		if *addr != "" {
-			listen = *addr
+		if strings.TrimSpace(string(data)) == "" {
+			return invalidf("markdown from stdin is empty")
 		}
-		if listen == "" {
-			listen = ":8080"
+		params.Markdown = string(data)
+	}
+	if c := remote.client(); c != nil {
+		res, err := publishRemote(c, params)
+		output(res, func() {
+			if err == nil {
+				fmt.Println(res.MediaID)
+			}
+		})
+		return err
+	}
+
+	cfg, err := publisher.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *crossPost == "" {
+		profiles = cfg.CrossPost
+	}
+	if params.UpdateMediaID != "" && cfg.AccountType == publisher.AccountWeCom {
+		return invalidf("--update-media-id is not supported for account_type wecom; sent messages cannot be updated")
+	}
+	p, err := publisher.New(cfg, nil, verbose, log.Default())
+	if err != nil {
+		return err
+	}
+	release, err := lock.acquire(cfg)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if params.Manifest == nil {
+		path := *manifestPath
+		if path == "" {
+			path = strings.TrimSuffix(*mdPath, filepath.Ext(*mdPath)) + ".publish.json"
+			if *mdPath == "-" {
+				path = "stdin.publish.json"
+			}
+		}
+		if params.Manifest, err = publisher.NewPublishManifest(path, params); err != nil {
+			return err
+		}
+	}
+	if err := params.Manifest.Save(); err != nil {
+		return fmt.Errorf("save publish manifest: %w", err)
+	}
+	res := publishResult{Title: params.Title, Images: []uploadedImage{}, StagesMS: map[string]int64{}, Updated: params.UpdateMediaID != ""}
+	last := time.Now()
+	params.Progress = func(stage string) {
+		res.StagesMS[stage] = time.Since(last).Milliseconds()
+		last = time.Now()
+	}
+	params.ImageUploaded = func(path, url string) {
+		res.Images = append(res.Images, uploadedImage{Path: path, URL: url})
+	}
+	res.MediaID, err = publishAndRecord(context.Background(), cfg, p, params)
+	if err != nil {
+		res.Manifest = params.Manifest.Path()
+	} else if rerr := os.Remove(params.Manifest.Path()); rerr != nil {
+		log.Printf("[cli] remove publish manifest failed: %v", rerr)
+	}
+	if err == nil && len(profiles) > 0 {
+		// 草稿已创建，跨平台文件写入失败只提示，不影响退出状态。
+		var cerr error
+		if res.CrossPost, cerr = writeCrossPost(params, profiles, cfg.AllowHTML, res.Images); cerr != nil {
+			log.Printf("[cli] cross-post failed: %v", cerr)
+		}
+	}
+	output(res, func() {
+		if err == nil {
+			fmt.Println(res.MediaID)
+		} else {
+			fmt.Fprintf(os.Stderr, "uploaded media are recorded in %s; retry with: %s publish --resume %s\n", res.Manifest, os.Args[0], res.Manifest)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[cli] publish done media_id=%s", res.MediaID)
+	return nil
+}
+
+// publishResult 为 publish 的 --json 结果；StagesMS 为各发布阶段（token、images、html、cover、draft、done）
+// 距上一阶段的耗时，--server 模式下 Images 的 URL 为服务上的地址。
+type publishResult struct {
+	MediaID   string           `json:"media_id,omitempty"`
+	Title     string           `json:"title"`
+	SessionID string           `json:"session_id,omitempty"`
+	Images    []uploadedImage  `json:"images"`
+	StagesMS  map[string]int64 `json:"stages_ms,omitempty"`
+	// Manifest 为发布失败时可用于 --resume 的清单文件。
+	Manifest string `json:"manifest,omitempty"`
+	// Updated 为 true 表示更新了已有草稿。
+	Updated bool `json:"updated,omitempty"`
+	// CrossPost 为发布后生成的跨平台 HTML 文件。
+	CrossPost []string `json:"cross_post,omitempty"`
+}
+
+type uploadedImage struct {
+	Path string `json:"path"`
+	URL  string `json:"url"`
+}
+
+// parseCrossPost 解析 --cross-post：为空时返回 nil（使用配置），none 表示不生成。
+func parseCrossPost(value string) ([]string, error) {
+	if value == "" || value == "none" {
+		return nil, nil
+	}
+	var profiles []string
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			profiles = append(profiles, p)
+		}
+	}
+	for _, p := range profiles {
+		if !slices.Contains(publisher.CrossPostProfiles, p) {
+			return nil, invalidf("--cross-post: unknown platform %q (supported: %s)", p, strings.Join(publisher.CrossPostProfiles, ", "))
+		}
+	}
+	return profiles, nil
+}
+
+// writeCrossPost 在 Markdown 旁写入跨平台 HTML，正文图片引用发布时上传到微信的地址；标准输入时写到当前目录的 stdin.<格式>.html。
+func writeCrossPost(params publisher.PublishParams, profiles []string, allowHTML bool, images []uploadedImage) ([]string, error) {
+	md, path := params.Markdown, params.MarkdownPath
+	if md == "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
 		}
+		md = string(data)
+	}
+	opts := publisher.CrossPostOptions{AllowHTML: allowHTML, Images: map[string]string{}}
+	if path == "-" || path == "" {
+		path, opts.BaseDir = "stdin.md", "."
+	}
+	for _, img := range images {
+		opts.Images[img.Path] = img.URL
+	}
+	return publisher.WriteCrossPost(path, params.Title, md, profiles, opts)
+}
+
+// runServer 启动 HTTP(S) 服务，收到 SIGINT/SIGTERM 后停止接收新请求，等待进行中的请求与发布任务完成后退出；
+// 超过 shutdown_timeout 秒（默认 30）时中止剩余任务。再次收到信号时立即退出。返回进程退出码。
+func runServer(srv *server.Server, listen string, cfg publisher.Config) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	httpSrv := &http.Server{Addr: listen, Handler: srv.Routes()}
+	servers := []*http.Server{httpSrv}
+	errCh := make(chan error, 2)
+	if cfg.TLS == nil {
 		log.Printf("Starting web server on %s", listen)
-		if err := http.ListenAndServe(listen, srv.Routes()); err != nil {
+		go func() { errCh <- httpSrv.ListenAndServe() }()
+	} else {
+		redirect, err := configureTLS(httpSrv, cfg.TLS)
+		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+			return 1
 		}
+		log.Printf("Starting web server on %s (https)", listen)
+		go func() { errCh <- httpSrv.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile) }()
+		if redirect != nil {
+			log.Printf("Redirecting http on %s to https", redirect.Addr)
+			servers = append(servers, redirect)
+			go func() { errCh <- redirect.ListenAndServe() }()
+		}
+	}
+
+	select {
+	case err := <-errCh:
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	case <-ctx.Done():
+	}
+	stop()
+
+	timeout := time.Duration(cfg.ShutdownTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	log.Printf("Shutting down; waiting up to %s for in-flight requests and publish jobs", timeout)
+	sctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// SSE 等长连接会一直占用 HTTP 关闭，发布队列与之并行排空。
+	httpErrs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, hs := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if httpErrs[i] = hs.Shutdown(sctx); httpErrs[i] != nil {
+				hs.Close()
+			}
+		}()
+	}
+	jobErr := srv.Shutdown(sctx)
+	wg.Wait()
+	closeErr := srv.Close()
+
+	code := 0
+	for _, err := range append(httpErrs, jobErr, closeErr) {
+		if err != nil {
+			log.Printf("Shutdown: %v", err)
+			code = 1
+		}
+	}
+	if code == 0 {
+		log.Printf("Server stopped")
+	}
+	return code
+}
+
+// configureTLS 按 tls 配置设置 HTTPS：使用证书文件，或通过 autocert 自动申请证书；
+// 配置 http_addr 时返回一个把 HTTP 重定向到 HTTPS（并响应 ACME 验证）的服务。
+func configureTLS(httpSrv *http.Server, cfg *publisher.TLSConfig) (*http.Server, error) {
+	httpSrv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	var redirect http.Handler = http.HandlerFunc(redirectHTTPS)
+	if len(cfg.AutocertDomains) > 0 {
+		cacheDir := cfg.AutocertCacheDir
+		if cacheDir == "" {
+			cacheDir = "certs"
+		}
+		if err := os.MkdirAll(cacheDir, 0o700); err != nil {
+			return nil, fmt.Errorf("tls: create autocert cache dir: %w", err)
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.AutocertDomains...),
+			Cache:      autocert.DirCache(cacheDir),
+			Email:      cfg.AutocertEmail,
+		}
+		httpSrv.TLSConfig = m.TLSConfig()
+		httpSrv.TLSConfig.MinVersion = tls.VersionTLS12
+		redirect = m.HTTPHandler(nil)
+		log.Printf("[tls] autocert enabled for %s (cache %s)", strings.Join(cfg.AutocertDomains, ", "), cacheDir)
+	}
+	if cfg.HTTPAddr == "" {
+		return nil, nil
+	}
+	return &http.Server{Addr: cfg.HTTPAddr, Handler: redirect, ReadHeaderTimeout: 10 * time.Second}, nil
+}
+
+// redirectHTTPS 把 HTTP 请求永久重定向到同一地址的 HTTPS（使用默认 443 端口）。
+func redirectHTTPS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "use https", http.StatusBadRequest)
 		return
 	}
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+}
 
-	if *mdPath == "" || *title == "" || *cover == "" {
-		fmt.Fprintln(os.Stderr, "--md, --title, and --cover are required")
-		os.Exit(1)
+// buildAgent 读取提示词模板与写作风格，并按配置创建带回退链、预算与检索的 Agent。
+// 模板与风格全局生效，全部步骤成功后才由返回的 apply 替换，配置无效时不影响当前使用的模板与风格。
+func buildAgent(cfg publisher.Config) (*generator.Agent, func(), error) {
+	prompts, err := generator.ParsePromptTemplates(cfg.PromptsDir)
+	if err != nil {
+		return nil, nil, err
 	}
+	styles, err := generator.ReadStyles(stylesDir(cfg))
+	if err != nil {
+		return nil, nil, err
+	}
+	chain, err := buildLLMChain(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	agent, err := generator.NewAgentChain(chain)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.Style != "" {
+		if _, ok := styles.Lookup(cfg.Style); !ok {
+			return nil, nil, &publisher.ConfigError{Err: fmt.Errorf("unknown style %q in config", cfg.Style)}
+		}
+		agent.SetDefaultStyle(cfg.Style)
+	}
+	if b := cfg.Budget; b != nil {
+		agent.SetBudget(generator.NewBudget(generator.BudgetConfig{
+			SessionMaxTokens:     b.SessionMaxTokens,
+			SessionMaxCost:       b.SessionMaxCost,
+			DailyMaxTokens:       b.DailyMaxTokens,
+			DailyMaxCost:         b.DailyMaxCost,
+			UserDailyMaxTokens:   b.UserDailyMaxTokens,
+			UserDailyMaxCost:     b.UserDailyMaxCost,
+			PricePer1KPrompt:     b.PricePer1KPrompt,
+			PricePer1KCompletion: b.PricePer1KCompletion,
+		}))
+	}
+	if sc := cfg.Search; sc != nil {
+		search, err := generator.NewSearchProvider(&generator.SearchSettings{
+			Provider: sc.Provider,
+			APIKey:   sc.APIKey,
+			BaseURL:  sc.BaseURL,
+			Limit:    sc.Limit,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		agent.SetSearch(search, sc.Limit)
+	}
+	agent.SetAllowHTML(cfg.AllowHTML)
+	sc := cfg.Sensitive
+	if sc == nil {
+		sc = &publisher.SensitiveConfig{}
+	}
+	filter, err := generator.LoadSensitiveFilter(sc.Path, !sc.DisableBuiltin)
+	if err != nil {
+		return nil, nil, err
+	}
+	agent.SetSensitive(filter, sc.AutoRephrase)
+	agent.SetKeepReasoning(cfg.RecordReasoning)
+	if h := cfg.History; h != nil {
+		agent.SetHistory(generator.HistorySettings{MaxTurns: h.MaxTurns, KeepRecent: h.KeepRecent, MaxRunes: h.MaxChars})
+	}
+	if s := cfg.Sessions; s != nil {
+		agent.SetMaxHistory(s.MaxHistory)
+	}
+	return agent, func() {
+		prompts.Use()
+		styles.Use()
+	}, nil
+}
 
-	cfg, err := publisher.LoadConfig(*configPath)
+func stylesDir(cfg publisher.Config) string {
+	if cfg.StylesDir == "" {
+		return generator.DefaultStylesDir
+	}
+	return cfg.StylesDir
+}
+
+func buildLLM(cfg publisher.Config) (generator.LLMClient, error) {
+	if cfg.LLM == nil || cfg.LLM.Provider == "" {
+		return nil, &publisher.ConfigError{Err: errors.New("llm config missing; please set llm.provider/model/api_key in config")}
+	}
+	client, err := generator.NewLLM(llmSettings(cfg.LLM))
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return nil, &publisher.ConfigError{Err: err}
 	}
-	p, err := publisher.New(cfg, nil, verbose, log.Default())
+	return client, nil
+}
+
+// buildLLMChain 构建主模型及 llm.fallbacks 组成的回退链。
+func buildLLMChain(cfg publisher.Config) ([]generator.NamedLLM, error) {
+	primary, err := buildLLM(cfg)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return nil, err
+	}
+	chain := []generator.NamedLLM{{Name: llmName(cfg.LLM), Client: primary, Timeout: time.Duration(cfg.LLM.Timeout) * time.Second}}
+	for i := range cfg.LLM.Fallbacks {
+		fb := &cfg.LLM.Fallbacks[i]
+		client, err := generator.NewLLM(llmSettings(fb))
+		if err != nil {
+			return nil, fmt.Errorf("llm.fallbacks[%d]: %w", i, err)
+		}
+		chain = append(chain, generator.NamedLLM{Name: llmName(fb), Client: client, Timeout: time.Duration(fb.Timeout) * time.Second})
+	}
+	return chain, nil
+}
+
+func llmName(c *publisher.LLMConfig) string {
+	if c.Model == "" {
+		return c.Provider
+	}
+	return c.Provider + "/" + c.Model
+}
+
+// llmSettings 把配置文件中的 llm 段转换为生成模块的设置。
+func llmSettings(c *publisher.LLMConfig) generator.LLMSettings {
+	return generator.LLMSettings{
+		Provider:    c.Provider,
+		Model:       c.Model,
+		APIKey:      c.APIKey,
+		BaseURL:     c.BaseURL,
+		VisionModel: c.VisionModel,
+		Deployment:  c.Deployment,
+		APIVersion:  c.APIVersion,
+		AuthMode:    c.AuthMode,
+		Fixtures:    c.Fixtures,
+		JSONMode:    c.JSONMode,
+		Sampling: generator.SamplingParams{
+			Temperature:      c.Temperature,
+			TopP:             c.TopP,
+			MaxTokens:        c.MaxTokens,
+			PresencePenalty:  c.PresencePenalty,
+			FrequencyPenalty: c.FrequencyPenalty,
+		},
+	}
+}
+
+func buildImageGenerator(cfg publisher.Config) (generator.ImageGenerator, error) {
+	settings := &generator.ImageSettings{
+		Provider: cfg.Image.Provider,
+		Model:    cfg.Image.Model,
+		APIKey:   cfg.Image.APIKey,
+		BaseURL:  cfg.Image.BaseURL,
+		Size:     cfg.Image.Size,
+	}
+	if settings.Provider == "" {
+		settings.Provider = "openai"
+	}
+	// 与 llm 同一服务商时复用其密钥与网关。
+	if cfg.LLM != nil && cfg.LLM.Provider == settings.Provider {
+		if settings.APIKey == "" {
+			settings.APIKey = cfg.LLM.APIKey
+		}
+		if settings.BaseURL == "" {
+			settings.BaseURL = cfg.LLM.BaseURL
+		}
+	}
+	switch settings.Provider {
+	case "openai":
+		return generator.NewOpenAIImageGeneratorFromConfig(settings)
+	case "mock":
+		return generator.MockImageGenerator{}, nil
+	default:
+		return nil, &publisher.ConfigError{Err: fmt.Errorf("image provider %s not supported", settings.Provider)}
+	}
+}
+
+// runCover 处理 `cover gen` 子命令：按标题生成封面图片。
+func runCover(args []string) error {
+	if len(args) == 0 || args[0] != "gen" {
+		return fmt.Errorf("usage: %s cover gen --title <title> [--out cover.jpg] [flags]", os.Args[0])
+	}
+	fs := newFlagSet("cover gen", "--title <title> [flags]", "Render a cover image with the title on a background image or a color template.")
+	configPath := fs.String("config", "", "optional config.json providing cover defaults")
+	title := fs.String("title", "", "cover title text")
+	out := fs.String("out", "cover.jpg", "output image path (JPEG)")
+	bg := fs.String("bg", "", "background image path (omit to use color template)")
+	bgColor := fs.String("bg-color", "", "background color, e.g. #1f2937")
+	gradientTo := fs.String("gradient-to", "", "gradient end color")
+	fontPath := fs.String("font", "", "TTF/OTF font path (required for CJK titles)")
+	fontSize := fs.Float64("font-size", 0, "font size in px")
+	textColor := fs.String("color", "", "title color, e.g. #ffffff")
+	width := fs.Int("width", cover.DefaultWidth, "output width")
+	height := fs.Int("height", cover.DefaultHeight, "output height")
+	_ = fs.Parse(args[1:])
+
+	opts := cover.Options{Width: *width, Height: *height}
+	if *configPath != "" {
+		cfg, err := publisher.LoadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		if c := cfg.Cover; c != nil {
+			opts.FontPath = c.FontPath
+			opts.FontSize = c.FontSize
+			opts.Color = c.Color
+			opts.BgColor = c.BgColor
+			opts.GradientTo = c.GradientTo
+		}
+	}
+	opts.Title = *title
+	opts.Background = *bg
+	if *bgColor != "" {
+		opts.BgColor = *bgColor
 	}
-	params := publisher.PublishParams{
-		MarkdownPath: *mdPath,
-		Title:        *title,
-		CoverPath:    *cover,
-		Author:       *author,
-		Digest:       *digest,
+	if *gradientTo != "" {
+		opts.GradientTo = *gradientTo
 	}
+	if *fontPath != "" {
+		opts.FontPath = *fontPath
+	}
+	if *fontSize > 0 {
+		opts.FontSize = *fontSize
+	}
+	if *textColor != "" {
+		opts.Color = *textColor
+	}
+	if err := cover.Generate(opts, *out); err != nil {
+		return err
+	}
+	output(map[string]any{"path": *out}, func() { fmt.Println(*out) })
+	return nil
+}
+
+// runRewrite 处理 `rewrite` 子命令：按风格改写已有文章并输出相似度报告；
+// translate 为 true 时处理 `translate` 子命令，把外文文章翻译并本地化为中文稿件。
+// lockFlags 为本地发布命令的锁参数：默认持有公众号的发布锁（见 publisher.PublishLockPath），
+// 避免定时任务等同时运行的发布触发微信接口的频率限制。
+type lockFlags struct {
+	noLock *bool
+	wait   *time.Duration
+}
 
-	ctx := context.Background()
+func addLockFlags(fs *flag.FlagSet) lockFlags {
+	return lockFlags{
+		noLock: fs.Bool("no-lock", false, "do not take the publish lock of the account (lock_path in config)"),
+		wait:   fs.Duration("lock-wait", 0, "wait up to this long for another publish to finish, e.g. 10m (0 fails at once)"),
+	}
+}
+
+// acquire 获取发布锁并返回释放函数；--no-lock 时不加锁。
+func (f lockFlags) acquire(cfg publisher.Config) (func(), error) {
+	if *f.noLock {
+		return func() {}, nil
+	}
+	return acquirePublishLock(cfg, *f.wait)
+}
+
+func acquirePublishLock(cfg publisher.Config, wait time.Duration) (func(), error) {
+	lock, err := publisher.LockFile(publisher.PublishLockPath(cfg), wait)
+	var lockedErr *publisher.LockedError
+	if errors.As(err, &lockedErr) {
+		return nil, fmt.Errorf("another publish is running (%w); retry later, wait with --lock-wait or skip the lock with --no-lock", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := lock.Unlock(); err != nil {
+			log.Printf("[cli] release publish lock failed: %v", err)
+		}
+	}, nil
+}
+
+// publishAndRecord 发布草稿，写入发布记录并发送群机器人与模板消息通知。
+func publishAndRecord(ctx context.Context, cfg publisher.Config, p *publisher.Publisher, params publisher.PublishParams) (string, error) {
 	log.Printf("[cli] publishing title=%q md=%s cover=%s", params.Title, params.MarkdownPath, params.CoverPath)
 	mediaID, err := p.PublishDraft(ctx, params)
+	rec := publisher.PublishRecord{Title: params.Title, MediaID: mediaID, CoverPath: params.CoverPath, Account: cfg.AccountID(), Status: publisher.PublishSucceeded, Updated: params.UpdateMediaID != ""}
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		rec.Status, rec.Error, rec.ErrCode = publisher.PublishFailed, err.Error(), publisher.WeChatErrorCode(err)
+	}
+	if _, herr := publisher.NewPublishHistory(cfg.PublishHistoryPath).Append(rec); herr != nil {
+		log.Printf("[cli] record history failed: %v", herr)
 	}
+	notice := publisher.PublishNotice{Title: params.Title, Digest: params.Digest, MediaID: mediaID, Account: cfg.AccountID(), Error: rec.Error, Time: time.Now()}
+	notifyPublish(ctx, cfg, p, notice)
+	if err != nil {
+		return "", err
+	}
+	return mediaID, nil
+}
 
-	log.Printf("[cli] publish done media_id=%s", mediaID)
-	fmt.Println(mediaID)
+// notifyPublish 把发布结果推送到群机器人，发布成功时另发模板消息；失败只打日志。
+func notifyPublish(ctx context.Context, cfg publisher.Config, p *publisher.Publisher, notice publisher.PublishNotice) {
+	if err := publisher.Notify(ctx, nil, cfg.Notify, notice); err != nil {
+		log.Printf("[cli] notify failed: %v", err)
+	}
+	if err := p.SendTemplateNotice(ctx, notice); err != nil {
+		log.Printf("[cli] template notify failed: %v", err)
+	}
 }
 
-func buildLLM(cfg publisher.Config) (generator.LLMClient, error) {
-	if cfg.LLM == nil || cfg.LLM.Provider == "" {
-		return nil, fmt.Errorf("llm config missing; please set llm.provider/model/api_key_env in config")
+// runGenerate 按主题生成文章，输出 Markdown；标题与摘要打印到 stderr，便于接着 publish。
+func runGenerate(args []string) error {
+	fs := newFlagSet("generate", "--topic <topic> [flags] | -i [flags]", "Generate an article from a topic with the configured LLM and print the markdown.\nWith -i, keep revising the draft in the terminal until it is published.")
+	configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+	topic := fs.String("topic", "", "article topic")
+	outline := fs.String("outline", "", "semicolon-separated outline points")
+	words := fs.Int("words", 0, "target word count")
+	style := fs.String("style", "", "style preset key")
+	audience := fs.String("audience", "", "target audience, e.g. 刚入职的程序员")
+	tone := fs.String("tone", "", "tone of voice, e.g. 轻松幽默")
+	taboo := fs.String("taboo", "", "comma-separated words or topics to avoid")
+	research := fs.Bool("research", false, "search the web for material before writing (requires config.search)")
+	out := fs.String("out", "", "output markdown path (default stdout)")
+	interactive := fs.Bool("i", false, "interactive mode: revise, retitle, polish and publish the draft in the terminal")
+	remote := addRemoteFlags(fs)
+	fs.Parse(args)
+	if strings.TrimSpace(*topic) == "" && !*interactive {
+		fs.Usage()
+		return invalidf("--topic is required")
 	}
-	switch cfg.LLM.Provider {
-	case "openai":
-		return generator.NewOpenAILLMFromConfig(&generator.LLMSettings{
-			Provider: cfg.LLM.Provider,
-			Model:    cfg.LLM.Model,
-			APIKey:   cfg.LLM.APIKey,
-			BaseURL:  cfg.LLM.BaseURL,
+	*configPath = publisher.ResolveConfigPath(*configPath)
+	splitList := func(s, sep string) []string {
+		var items []string
+		for _, item := range strings.Split(s, sep) {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	if c := remote.client(); c != nil {
+		if *interactive {
+			return invalidf("-i is not supported with --server; use the web UI of the server instead")
+		}
+		req := client.CreateSessionRequest{
+			Topic:    *topic,
+			Outline:  splitList(*outline, ";"),
+			Words:    *words,
+			Style:    *style,
+			Audience: *audience,
+			Tone:     *tone,
+			Taboo:    splitList(*taboo, ","),
+			Research: *research,
+		}
+		return generateRemote(c, req, *out)
+	}
+
+	cfg, err := publisher.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	agent, apply, err := buildAgent(cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	spec := generator.Spec{
+		Topic:    *topic,
+		Outline:  splitList(*outline, ";"),
+		Words:    *words,
+		Style:    *style,
+		Audience: *audience,
+		Tone:     *tone,
+		Taboo:    splitList(*taboo, ","),
+	}
+	if *interactive {
+		if jsonOutput {
+			return invalidf("-i does not support --json")
+		}
+		return runGenerateREPL(cfg, agent, spec, *research, *out)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	sess := generator.NewSession("cli", spec, agent)
+	if *research {
+		if _, err := sess.Research(ctx, ""); err != nil {
+			return err
+		}
+	}
+	draft, err := sess.Propose(ctx)
+	if err != nil {
+		return err
+	}
+	return writeGenerated(newGenerateResult(draft), *out)
+}
+
+// generateResult 为 generate、rewrite 与 translate 的输出。
+type generateResult struct {
+	SessionID   string                       `json:"session_id,omitempty"`
+	Title       string                       `json:"title"`
+	Digest      string                       `json:"digest"`
+	Markdown    string                       `json:"markdown"`
+	WordCount   int                          `json:"word_count"`
+	Path        string                       `json:"path,omitempty"`
+	Sensitive   []generator.SensitiveHit     `json:"sensitive,omitempty"`
+	Originality *generator.OriginalityReport `json:"originality,omitempty"`
+}
+
+func newGenerateResult(d generator.Draft) generateResult {
+	return generateResult{Title: d.Title, Digest: d.Digest, Markdown: d.Markdown, WordCount: d.WordCount, Sensitive: d.Sensitive}
+}
+
+// writeGenerated 把稿件写入 out（为空时输出到 stdout），标题等信息输出到 stderr。
+func writeGenerated(res generateResult, out string) error {
+	if out != "" {
+		if err := os.WriteFile(out, []byte(res.Markdown+"\n"), 0o644); err != nil {
+			return err
+		}
+		res.Path = out
+	}
+	output(res, func() {
+		if out == "" {
+			fmt.Println(res.Markdown)
+		}
+		fmt.Fprintf(os.Stderr, "标题：%s\n摘要：%s\n字数：%d\n", res.Title, res.Digest, res.WordCount)
+		if res.SessionID != "" {
+			fmt.Fprintf(os.Stderr, "session：%s\n", res.SessionID)
+		}
+		for _, hit := range res.Sensitive {
+			fmt.Fprintf(os.Stderr, "  敏感词：%s（第 %d 行）\n", hit.Word, hit.Line)
+		}
+		if report := res.Originality; report != nil {
+			verdict := "原创度达标"
+			if !report.Original {
+				verdict = "与原文重合较多，建议继续修改"
+			}
+			fmt.Fprintf(os.Stderr, "相似度 %.1f%%，最长连续相同 %d 字，%s\n", report.Similarity*100, report.LongestCopy, verdict)
+			for _, c := range report.Copied {
+				fmt.Fprintf(os.Stderr, "  - %s\n", c)
+			}
+		}
+	})
+	return nil
+}
+
+func runRewrite(args []string, translate bool) error {
+	name := "rewrite"
+	if translate {
+		name = "translate"
+	}
+	summary := "Rewrite an existing article in another style."
+	if translate {
+		summary = "Translate an existing article into Chinese."
+	}
+	fs := newFlagSet(name, "(--in article.md | --url https://...) [flags]", summary)
+	configPath := fs.String("config", "config/config.json", "path to config.json")
+	in := fs.String("in", "", "source markdown/text file")
+	srcURL := fs.String("url", "", "source article url (used when --in is empty)")
+	style := fs.String("style", "", "style preset key")
+	words := fs.Int("words", 0, "target word count (0 keeps original length)")
+	topic := fs.String("topic", "", "optional rewrite angle")
+	lang := fs.String("lang", "", "source language for translate, e.g. en (default auto-detect)")
+	audience := fs.String("audience", "", "target audience, e.g. 刚入职的程序员")
+	tone := fs.String("tone", "", "tone of voice, e.g. 轻松幽默")
+	taboo := fs.String("taboo", "", "comma-separated words or topics to avoid")
+	out := fs.String("out", "", "output markdown path (default stdout)")
+	_ = fs.Parse(args)
+	if *in == "" && *srcURL == "" {
+		return fmt.Errorf("usage: %s %s (--in article.md | --url https://...) [--style key] [--out out.md]", os.Args[0], name)
+	}
+
+	cfg, err := publisher.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	agent, apply, err := buildAgent(cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	var src generator.SourceDoc
+	if *in != "" {
+		data, err := os.ReadFile(*in)
+		if err != nil {
+			return err
+		}
+		src = generator.SourceDoc{Title: strings.TrimSuffix(filepath.Base(*in), filepath.Ext(*in)), Text: string(data)}
+	} else if src, err = generator.FetchSource(ctx, *srcURL); err != nil {
+		return err
+	}
+
+	src.Lang = *lang
+
+	spec := generator.Spec{Topic: *topic, Words: *words, Style: *style, Audience: *audience, Tone: *tone}
+	for _, t := range strings.Split(*taboo, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			spec.Taboo = append(spec.Taboo, t)
+		}
+	}
+	sess := generator.NewSession("cli", spec, agent)
+	var draft generator.Draft
+	if translate {
+		draft, err = sess.Translate(ctx, src)
+	} else {
+		draft, err = sess.Rewrite(ctx, src)
+	}
+	if err != nil {
+		return err
+	}
+	res := newGenerateResult(draft)
+	res.Originality = sess.Originality
+	return writeGenerated(res, *out)
+}
+
+// runHistory 处理 `history` 子命令：按时间倒序列出发布记录。
+func runHistory(args []string) error {
+	fs := newFlagSet("history", "[flags]", "Show publish records, newest first.")
+	configPath := fs.String("config", "config/config.json", "path to config.json")
+	session := fs.String("session", "", "only show publishes of this session id")
+	user := fs.String("user", "", "only show publishes by this user")
+	status := fs.String("status", "", "filter by status: success or failed")
+	query := fs.String("q", "", "search title")
+	since := fs.String("since", "", "only show publishes on or after this date (YYYY-MM-DD)")
+	limit := fs.Int("limit", 20, "max records to show (0 for all)")
+	remote := addRemoteFlags(fs)
+	_ = fs.Parse(args)
+
+	filter := publisher.PublishFilter{SessionID: *session, User: *user, Status: *status, Query: *query, Limit: *limit}
+	if *since != "" {
+		var err error
+		if filter.Since, err = time.ParseInLocation("2006-01-02", *since, time.Local); err != nil {
+			return invalidf("invalid --since %q: use YYYY-MM-DD", *since)
+		}
+	}
+	var records []publisher.PublishRecord
+	if c := remote.client(); c != nil {
+		var err error
+		if records, err = historyRemote(c, filter); err != nil {
+			return err
+		}
+	} else {
+		cfg, err := publisher.LoadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		if records, err = publisher.NewPublishHistory(cfg.PublishHistoryPath).List(filter); err != nil {
+			return err
+		}
+	}
+	if records == nil {
+		records = []publisher.PublishRecord{}
+	}
+	output(map[string]any{"publishes": records}, func() {
+		if len(records) == 0 {
+			fmt.Fprintln(os.Stderr, "no publish records")
+			return
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "TIME\tSTATUS\tTITLE\tMEDIA_ID\tSESSION")
+		for _, rec := range records {
+			media := rec.MediaID
+			if rec.Status == publisher.PublishFailed {
+				media = truncate(rec.Error, 60)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", rec.CreatedAt.Local().Format("2006-01-02 15:04"), rec.Status, rec.Title, media, rec.SessionID)
+		}
+		tw.Flush()
+	})
+	return nil
+}
+
+// runSchedule 处理 `schedule` 子命令：添加、列出与取消定时发布。
+// 定时发布由 serve 启动的服务执行，命令行只读写定时发布文件。
+func runSchedule(args []string) error {
+	usage := fmt.Errorf("usage: %s schedule add --md article.md --title <title> --cover cover.jpg --at \"2006-01-02 15:04\" | list [--status s] | cancel <id>", os.Args[0])
+	if len(args) == 0 {
+		return usage
+	}
+	fs := newFlagSet("schedule "+args[0], "[flags]", "Add, list or cancel scheduled publishes run by the server.")
+	configPath := fs.String("config", "config/config.json", "path to config.json")
+	switch args[0] {
+	case "add":
+		mdPath := fs.String("md", "", "path to markdown file")
+		title := fs.String("title", "", "article title")
+		cover := fs.String("cover", "", "path to cover image")
+		author := fs.String("author", "", "author name")
+		digest := fs.String("digest", "", "article digest")
+		at := fs.String("at", "", "publish time: RFC3339 or local YYYY-MM-DD HH:MM")
+		_ = fs.Parse(args[1:])
+		if *mdPath == "" || *cover == "" || *at == "" {
+			return usage
+		}
+		cfg, err := publisher.LoadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		when, err := publisher.ParseScheduleTime(*at)
+		if err != nil {
+			return err
+		}
+		// 服务可能在其他工作目录运行，保存绝对路径。
+		md, err := filepath.Abs(*mdPath)
+		if err != nil {
+			return err
+		}
+		coverPath, err := filepath.Abs(*cover)
+		if err != nil {
+			return err
+		}
+		for _, p := range []string{md, coverPath} {
+			if _, err := os.Stat(p); err != nil {
+				return err
+			}
+		}
+		item, err := publisher.NewScheduleStore(cfg.SchedulePath).Add(publisher.ScheduledPublish{
+			Title:        *title,
+			Author:       *author,
+			Digest:       *digest,
+			CoverPath:    coverPath,
+			MarkdownPath: md,
+			ScheduleAt:   when,
 		})
-	case "deepseek":
-		// DeepSeek 提供 OpenAI 兼容接口，需填写 base_url（例如官方/网关地址）。
-		if cfg.LLM.BaseURL == "" {
-			return nil, fmt.Errorf("llm provider deepseek requires base_url (OpenAI-compatible endpoint)")
-		}
-		return generator.NewOpenAILLMFromConfig(&generator.LLMSettings{
-			Provider: cfg.LLM.Provider,
-			Model:    cfg.LLM.Model,
-			APIKey:   cfg.LLM.APIKey,
-			BaseURL:  cfg.LLM.BaseURL,
+		if err != nil {
+			return err
+		}
+		output(item, func() {
+			fmt.Printf("scheduled %s at %s\n", item.ID, item.ScheduleAt.Local().Format("2006-01-02 15:04"))
+		})
+		return nil
+	case "list":
+		status := fs.String("status", "", "filter by status: scheduled, running, done, failed or canceled")
+		_ = fs.Parse(args[1:])
+		cfg, err := publisher.LoadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		items, err := publisher.NewScheduleStore(cfg.SchedulePath).List(*status)
+		if err != nil {
+			return err
+		}
+		if items == nil {
+			items = []publisher.ScheduledPublish{}
+		}
+		output(map[string]any{"schedules": items}, func() {
+			if len(items) == 0 {
+				fmt.Fprintln(os.Stderr, "no scheduled publishes")
+				return
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tAT\tSTATUS\tTITLE\tRESULT")
+			for _, it := range items {
+				result := it.MediaID
+				if it.Error != "" {
+					result = truncate(it.Error, 60)
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", it.ID, it.ScheduleAt.Local().Format("2006-01-02 15:04"), it.Status, it.Title, result)
+			}
+			tw.Flush()
 		})
+		return nil
+	case "cancel":
+		_ = fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return usage
+		}
+		cfg, err := publisher.LoadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		item, err := publisher.NewScheduleStore(cfg.SchedulePath).Cancel(fs.Arg(0), nil)
+		if err != nil {
+			return err
+		}
+		output(item, func() { fmt.Printf("canceled %s (%s)\n", item.ID, item.Title) })
+		return nil
 	default:
-		return nil, fmt.Errorf("llm provider %s not supported", cfg.LLM.Provider)
+		return usage
+	}
+}
+
+// runUser 处理 `user` 子命令：管理登录用户（需在配置中启用 auth）。
+// 未传 --password 时从标准输入读取一行作为密码。
+func runUser(args []string) error {
+	usage := fmt.Errorf("usage: %s user add <name> [--admin] [--roles writer,reviewer,publisher] | passwd <name> | admin <name> true|false | roles <name> writer,reviewer,publisher | delete <name> | list", os.Args[0])
+	if len(args) == 0 {
+		return usage
+	}
+	fs := newFlagSet("user "+args[0], "[flags]", "Manage login users of the server (requires auth in config).")
+	configPath := fs.String("config", "config/config.json", "path to config.json")
+	admin := fs.Bool("admin", false, "grant admin (add only)")
+	roles := fs.String("roles", "", "comma-separated roles: writer, reviewer, publisher (add only, default writer)")
+	password := fs.String("password", "", "password (read from stdin when empty)")
+	// 允许参数写在用户名前后。
+	var names []string
+	rest := args[1:]
+	for {
+		_ = fs.Parse(rest)
+		if fs.NArg() == 0 {
+			break
+		}
+		names = append(names, fs.Arg(0))
+		rest = fs.Args()[1:]
+	}
+
+	cfg, err := publisher.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.Auth == nil {
+		fmt.Fprintln(os.Stderr, "warning: auth is not enabled in config; users take effect after adding \"auth\"")
+	}
+	usersPath := ""
+	if cfg.Auth != nil {
+		usersPath = cfg.Auth.UsersPath
+	}
+	store := server.NewUserStore(usersPath)
+	readPassword := func() (string, error) {
+		if *password != "" {
+			return *password, nil
+		}
+		fmt.Fprint(os.Stderr, "password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("read password: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	// 账号变更写入审计日志。
+	audited := func(detail string, err error) error {
+		if err != nil {
+			return err
+		}
+		recordCLIAudit(cfg, publisher.AuditEntry{Action: publisher.AuditConfigChanged, Target: "user:" + names[0], Detail: detail})
+		output(map[string]any{"user": names[0], "action": args[0]}, func() {})
+		return nil
+	}
+
+	switch args[0] {
+	case "list":
+		users, err := store.List()
+		if err != nil {
+			return err
+		}
+		// JSON 输出不包含密码哈希。
+		list := make([]map[string]any, 0, len(users))
+		for _, u := range users {
+			list = append(list, map[string]any{"name": u.Name, "admin": u.Admin, "roles": u.Roles, "created_at": u.CreatedAt})
+		}
+		output(map[string]any{"users": list}, func() {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tADMIN\tROLES\tCREATED")
+			for _, u := range users {
+				fmt.Fprintf(tw, "%s\t%t\t%s\t%s\n", u.Name, u.Admin, strings.Join(u.Roles, ","), u.CreatedAt.Local().Format("2006-01-02 15:04"))
+			}
+			tw.Flush()
+		})
+		return nil
+	case "add", "passwd":
+		if len(names) != 1 {
+			return usage
+		}
+		pw, err := readPassword()
+		if err != nil {
+			return err
+		}
+		if args[0] == "passwd" {
+			return audited("user passwd", store.SetPassword(names[0], pw))
+		}
+		_, err = store.Add(names[0], pw, *admin, strings.Split(*roles, ","))
+		return audited(fmt.Sprintf("user add admin=%t roles=%s", *admin, *roles), err)
+	case "delete":
+		if len(names) != 1 {
+			return usage
+		}
+		return audited("user delete", store.Delete(names[0]))
+	case "admin":
+		if len(names) != 2 {
+			return usage
+		}
+		on, err := strconv.ParseBool(names[1])
+		if err != nil {
+			return usage
+		}
+		return audited(fmt.Sprintf("user admin %t", on), store.SetAdmin(names[0], on))
+	case "roles":
+		if len(names) != 2 {
+			return usage
+		}
+		return audited("user roles "+names[1], store.SetRoles(names[0], strings.Split(names[1], ",")))
+	default:
+		return usage
+	}
+}
+
+// cliUser 返回命令行操作的操作者：cli:系统用户名。
+func cliUser() string {
+	who := os.Getenv("USER")
+	if who == "" {
+		who = os.Getenv("USERNAME")
+	}
+	return "cli:" + who
+}
+
+// recordCLIAudit 把命令行操作写入审计日志，操作者记为执行命令的系统用户；写入失败只打日志。
+func recordCLIAudit(cfg publisher.Config, entry publisher.AuditEntry) {
+	entry.User = cliUser()
+	if _, err := publisher.NewAuditLog(cfg.AuditLogPath).Append(entry); err != nil {
+		log.Printf("[cli] record audit failed: %v", err)
+	}
+}
+
+// runSecrets 管理配置中加密保存的密钥：keygen 生成密钥，encrypt 加密单个值（--value 或标准输入）
+// 或就地加密配置文件中的 app_secret、api_key 等，decrypt 解密单个值用于核对。密钥读取 WECHAT_SECRET_KEY。
+func runSecrets(args []string) error {
+	usage := fmt.Errorf("usage: %s secrets keygen | encrypt [--value v] [--config path] | decrypt [--value v]", os.Args[0])
+	if len(args) == 0 {
+		return usage
+	}
+	fs := newFlagSet("secrets "+args[0], "[flags]", "Generate the secret key, or encrypt and decrypt secrets in config.")
+	configPath := fs.String("config", "", "encrypt sensitive values in this config file in place (encrypt only)")
+	value := fs.String("value", "", "value to encrypt or decrypt (read from stdin when empty)")
+	_ = fs.Parse(args[1:])
+
+	if args[0] == "keygen" {
+		key, err := publisher.GenerateSecretKey()
+		if err != nil {
+			return err
+		}
+		output(map[string]any{"key": key}, func() { fmt.Println(key) })
+		return nil
+	}
+	if args[0] != "encrypt" && args[0] != "decrypt" {
+		return usage
+	}
+	key, err := publisher.LoadSecretKey()
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return &publisher.ConfigError{Err: fmt.Errorf("set %s (or %s) first; generate a key with `%s secrets keygen`", publisher.SecretKeyEnv, publisher.SecretKeyFileEnv, os.Args[0])}
+	}
+	if args[0] == "encrypt" && *configPath != "" {
+		fields, err := publisher.EncryptConfigFile(*configPath, key)
+		if err != nil {
+			return err
+		}
+		path := publisher.ResolveConfigPath(*configPath)
+		if fields == nil {
+			fields = []string{}
+		}
+		output(map[string]any{"path": path, "encrypted": fields}, func() {
+			if len(fields) == 0 {
+				fmt.Fprintln(os.Stderr, "no plaintext secrets found")
+				return
+			}
+			fmt.Fprintf(os.Stderr, "encrypted %s in %s\n", strings.Join(fields, ", "), path)
+		})
+		return nil
+	}
+	in := *value
+	if in == "" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("read value: %w", err)
+		}
+		in = strings.TrimRight(line, "\r\n")
+	}
+	out, err := publisher.EncryptSecret(key, in)
+	if args[0] == "decrypt" {
+		out, err = publisher.DecryptSecret(key, in)
+	}
+	if err != nil {
+		return err
+	}
+	output(map[string]any{"value": out}, func() { fmt.Println(out) })
+	return nil
+}
+
+// truncate 按字符截断过长的文本，用于表格输出。
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
 	}
+	return string(r[:n]) + "…"
 }
@@ -0,0 +1,565 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	articleSummaryURL = "https://api.weixin.qq.com/datacube/getarticlesummary"
+	articleTotalURL   = "https://api.weixin.qq.com/datacube/getarticletotal"
+	userReadURL       = "https://api.weixin.qq.com/datacube/getuserread"
+	userSummaryURL    = "https://api.weixin.qq.com/datacube/getusersummary"
+	userCumulateURL   = "https://api.weixin.qq.com/datacube/getusercumulate"
+)
+
+const (
+	// DefaultAnalyticsPath 为未配置 analytics_path 时的图文数据文件。
+	DefaultAnalyticsPath = "analytics.json"
+	// DefaultAnalyticsCron 为未配置 analytics.cron 时的采集时间：微信在每天上午提供前一天的数据。
+	DefaultAnalyticsCron = "0 9 * * *"
+	// DefaultAnalyticsDays 为每次采集检查的天数：补齐其中缺少的日期，并更新这些天发表的文章的累计数据。
+	DefaultAnalyticsDays = 7
+	// analyticsDateLayout 为数据统计接口与数据文件中的日期格式。
+	analyticsDateLayout = "2006-01-02"
+	// userSourceAll 为 getuserread 中表示全部来源的 user_source。
+	userSourceAll = 99999999
+)
+
+// AnalyticsConfig 配置图文数据的定时采集（需要公众号已认证并开通数据统计接口权限）。
+type AnalyticsConfig struct {
+	// Cron 为采集时间，默认每天 9 点；每次采集前一天及之前 days 天内缺少的数据。
+	Cron string `json:"cron,omitempty"`
+	Days int    `json:"days,omitempty"`
+}
+
+// ReadStats 为阅读、分享与收藏的人数和次数。数据统计接口不提供点赞与“在看”数。
+type ReadStats struct {
+	ReadUser      int `json:"read_user"`
+	ReadCount     int `json:"read_count"`
+	ShareUser     int `json:"share_user"`
+	ShareCount    int `json:"share_count"`
+	FavoriteUser  int `json:"favorite_user"`
+	FavoriteCount int `json:"favorite_count"`
+}
+
+func (s *ReadStats) add(v ReadStats) {
+	s.ReadUser += v.ReadUser
+	s.ReadCount += v.ReadCount
+	s.ShareUser += v.ShareUser
+	s.ShareCount += v.ShareCount
+	s.FavoriteUser += v.FavoriteUser
+	s.FavoriteCount += v.FavoriteCount
+}
+
+// ArticleDayStats 为一篇文章某一天的数据（getarticlesummary）；MsgID 为“消息 ID_文章序号”。
+type ArticleDayStats struct {
+	Date  string `json:"date"`
+	MsgID string `json:"msgid"`
+	Title string `json:"title"`
+	ReadStats
+}
+
+// ArticleTotalStats 为一篇文章发表后的累计数据（getarticletotal），微信只统计发表后 7 天内的数据。
+// TargetUser 为送达人数，StatDate 为累计到的日期。
+type ArticleTotalStats struct {
+	MsgID       string `json:"msgid"`
+	Title       string `json:"title"`
+	PublishDate string `json:"publish_date"`
+	StatDate    string `json:"stat_date"`
+	TargetUser  int    `json:"target_user"`
+	ReadStats
+}
+
+// AccountDayStats 为公众号某一天全部图文的数据（getuserread）。
+type AccountDayStats struct {
+	Date string `json:"date"`
+	ReadStats
+}
+
+// FollowerDayStats 为某一天的粉丝变化（getusersummary、getusercumulate）：新关注、取消关注、净增与当天的总粉丝数。
+type FollowerDayStats struct {
+	Date       string `json:"date"`
+	NewUser    int    `json:"new_user"`
+	CancelUser int    `json:"cancel_user"`
+	NetGrowth  int    `json:"net_growth"`
+	Cumulate   int    `json:"cumulate_user"`
+}
+
+// datacubeStats 为数据统计接口中的阅读、分享与收藏字段；只统计图文页（int_page）的阅读。
+type datacubeStats struct {
+	ReadUser   int `json:"int_page_read_user"`
+	ReadCount  int `json:"int_page_read_count"`
+	ShareUser  int `json:"share_user"`
+	ShareCount int `json:"share_count"`
+	FavUser    int `json:"add_to_fav_user"`
+	FavCount   int `json:"add_to_fav_count"`
+}
+
+func (d datacubeStats) stats() ReadStats {
+	return ReadStats{ReadUser: d.ReadUser, ReadCount: d.ReadCount, ShareUser: d.ShareUser, ShareCount: d.ShareCount, FavoriteUser: d.FavUser, FavoriteCount: d.FavCount}
+}
+
+// datacube 调用数据统计接口查询 day 当天的数据。
+func (p *Publisher) datacube(ctx context.Context, endpoint string, day time.Time, out any) error {
+	date := day.Format(analyticsDateLayout)
+	return p.callAPI(ctx, endpoint, map[string]string{"begin_date": date, "end_date": date}, out)
+}
+
+// ArticleSummary 返回 day 当天各篇文章的阅读、分享与收藏数据。
+func (p *Publisher) ArticleSummary(ctx context.Context, day time.Time) ([]ArticleDayStats, error) {
+	var resp struct {
+		List []struct {
+			RefDate string `json:"ref_date"`
+			MsgID   string `json:"msgid"`
+			Title   string `json:"title"`
+			datacubeStats
+		} `json:"list"`
+	}
+	if err := p.datacube(ctx, articleSummaryURL, day, &resp); err != nil {
+		return nil, fmt.Errorf("getarticlesummary %s: %w", day.Format(analyticsDateLayout), err)
+	}
+	items := make([]ArticleDayStats, 0, len(resp.List))
+	for _, it := range resp.List {
+		items = append(items, ArticleDayStats{Date: it.RefDate, MsgID: it.MsgID, Title: it.Title, ReadStats: it.stats()})
+	}
+	return items, nil
+}
+
+// ArticleTotals 返回 day 当天发表的文章截至目前的累计数据。
+func (p *Publisher) ArticleTotals(ctx context.Context, day time.Time) ([]ArticleTotalStats, error) {
+	var resp struct {
+		List []struct {
+			RefDate string `json:"ref_date"`
+			MsgID   string `json:"msgid"`
+			Title   string `json:"title"`
+			Details []struct {
+				StatDate   string `json:"stat_date"`
+				TargetUser int    `json:"target_user"`
+				datacubeStats
+			} `json:"details"`
+		} `json:"list"`
+	}
+	if err := p.datacube(ctx, articleTotalURL, day, &resp); err != nil {
+		return nil, fmt.Errorf("getarticletotal %s: %w", day.Format(analyticsDateLayout), err)
+	}
+	items := make([]ArticleTotalStats, 0, len(resp.List))
+	for _, it := range resp.List {
+		if len(it.Details) == 0 {
+			continue
+		}
+		// details 按日期排列，每项为截至该日的累计数据，取最后一项。
+		last := it.Details[len(it.Details)-1]
+		items = append(items, ArticleTotalStats{
+			MsgID: it.MsgID, Title: it.Title, PublishDate: it.RefDate, StatDate: last.StatDate,
+			TargetUser: last.TargetUser, ReadStats: last.stats(),
+		})
+	}
+	return items, nil
+}
+
+// UserRead 返回 day 当天公众号全部图文的数据。
+func (p *Publisher) UserRead(ctx context.Context, day time.Time) (AccountDayStats, error) {
+	var resp struct {
+		List []struct {
+			UserSource int `json:"user_source"`
+			datacubeStats
+		} `json:"list"`
+	}
+	out := AccountDayStats{Date: day.Format(analyticsDateLayout)}
+	if err := p.datacube(ctx, userReadURL, day, &resp); err != nil {
+		return out, fmt.Errorf("getuserread %s: %w", out.Date, err)
+	}
+	// 有全部来源的汇总项时直接使用，否则把各来源相加。
+	var sum ReadStats
+	for _, it := range resp.List {
+		if it.UserSource == userSourceAll {
+			out.ReadStats = it.stats()
+			return out, nil
+		}
+		sum.add(it.stats())
+	}
+	out.ReadStats = sum
+	return out, nil
+}
+
+// Followers 返回 day 当天的粉丝变化，新关注与取消关注为各关注来源之和。
+func (p *Publisher) Followers(ctx context.Context, day time.Time) (FollowerDayStats, error) {
+	out := FollowerDayStats{Date: day.Format(analyticsDateLayout)}
+	var summary struct {
+		List []struct {
+			NewUser    int `json:"new_user"`
+			CancelUser int `json:"cancel_user"`
+		} `json:"list"`
+	}
+	if err := p.datacube(ctx, userSummaryURL, day, &summary); err != nil {
+		return out, fmt.Errorf("getusersummary %s: %w", out.Date, err)
+	}
+	for _, it := range summary.List {
+		out.NewUser += it.NewUser
+		out.CancelUser += it.CancelUser
+	}
+	out.NetGrowth = out.NewUser - out.CancelUser
+	var cumulate struct {
+		List []struct {
+			CumulateUser int `json:"cumulate_user"`
+		} `json:"list"`
+	}
+	if err := p.datacube(ctx, userCumulateURL, day, &cumulate); err != nil {
+		return out, fmt.Errorf("getusercumulate %s: %w", out.Date, err)
+	}
+	if len(cumulate.List) > 0 {
+		out.Cumulate = cumulate.List[0].CumulateUser
+	}
+	return out, nil
+}
+
+// AnalyticsData 为图文数据文件的内容。Collected 为已采集每日数据的日期。
+type AnalyticsData struct {
+	Articles  []ArticleDayStats   `json:"articles"`
+	Totals    []ArticleTotalStats `json:"totals"`
+	Days      []AccountDayStats   `json:"days"`
+	Followers []FollowerDayStats  `json:"followers"`
+	Collected []string            `json:"collected"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// AnalyticsFilter 为查询图文数据的条件，零值字段不参与过滤；Since、Until 为 YYYY-MM-DD，包含当天。
+type AnalyticsFilter struct {
+	Since string
+	Until string
+	// Query 按标题搜索（忽略大小写）。
+	Query string
+	MsgID string
+	// Titles 非空时只返回这些标题的文章，且不返回公众号的每日数据（写作者只能查看自己的文章）。
+	Titles map[string]bool
+}
+
+func (f AnalyticsFilter) matchDate(date string) bool {
+	return (f.Since == "" || date >= f.Since) && (f.Until == "" || date <= f.Until)
+}
+
+func (f AnalyticsFilter) matchArticle(msgID, title string) bool {
+	switch {
+	case f.MsgID != "" && msgID != f.MsgID,
+		f.Query != "" && !strings.Contains(strings.ToLower(title), strings.ToLower(f.Query)),
+		f.Titles != nil && !f.Titles[title]:
+		return false
+	}
+	return true
+}
+
+// ArticleAnalytics 为一篇文章在查询区间内的数据：Sum 为每日数据之和，Total 为微信统计的累计数据
+// （发表日期不在区间内或未采集到时为空，PublishDate 此时为区间内有数据的第一天）。
+type ArticleAnalytics struct {
+	MsgID       string             `json:"msgid"`
+	Title       string             `json:"title"`
+	PublishDate string             `json:"publish_date,omitempty"`
+	Sum         ReadStats          `json:"sum"`
+	Total       *ArticleTotalStats `json:"total,omitempty"`
+	Days        []ArticleDayStats  `json:"days"`
+}
+
+// AnalyticsReport 为查询结果：文章按发表日期倒序，公众号每日数据按日期倒序。
+type AnalyticsReport struct {
+	Articles  []ArticleAnalytics `json:"articles"`
+	Days      []AccountDayStats  `json:"days"`
+	UpdatedAt time.Time          `json:"updated_at,omitempty"`
+}
+
+// Report 按条件汇总每篇文章的数据。
+func (d AnalyticsData) Report(filter AnalyticsFilter) AnalyticsReport {
+	report := AnalyticsReport{Articles: []ArticleAnalytics{}, Days: []AccountDayStats{}, UpdatedAt: d.UpdatedAt}
+	index := map[string]int{}
+	article := func(msgID, title string) *ArticleAnalytics {
+		i, ok := index[msgID]
+		if !ok {
+			i = len(report.Articles)
+			index[msgID] = i
+			report.Articles = append(report.Articles, ArticleAnalytics{MsgID: msgID, Title: title, Days: []ArticleDayStats{}})
+		}
+		return &report.Articles[i]
+	}
+	for _, t := range d.Totals {
+		if filter.matchDate(t.PublishDate) && filter.matchArticle(t.MsgID, t.Title) {
+			a := article(t.MsgID, t.Title)
+			total := t
+			a.PublishDate, a.Total = t.PublishDate, &total
+		}
+	}
+	for _, day := range d.Articles {
+		if filter.matchDate(day.Date) && filter.matchArticle(day.MsgID, day.Title) {
+			a := article(day.MsgID, day.Title)
+			a.Sum.add(day.ReadStats)
+			a.Days = append(a.Days, day)
+		}
+	}
+	for i := range report.Articles {
+		a := &report.Articles[i]
+		sort.Slice(a.Days, func(x, y int) bool { return a.Days[x].Date < a.Days[y].Date })
+		if a.PublishDate == "" && len(a.Days) > 0 {
+			a.PublishDate = a.Days[0].Date
+		}
+	}
+	sort.SliceStable(report.Articles, func(i, j int) bool {
+		return report.Articles[i].PublishDate > report.Articles[j].PublishDate
+	})
+	if filter.Titles == nil {
+		for _, day := range d.Days {
+			if filter.matchDate(day.Date) {
+				report.Days = append(report.Days, day)
+			}
+		}
+	}
+	return report
+}
+
+// FollowerReportDay 为粉丝报表中的一天：粉丝变化、公众号当天的图文阅读次数与当天发表的文章。
+type FollowerReportDay struct {
+	FollowerDayStats
+	ReadCount int          `json:"read_count"`
+	Articles  []ArticleRef `json:"articles"`
+}
+
+// ArticleRef 为报表中引用的一篇文章。
+type ArticleRef struct {
+	MsgID string `json:"msgid"`
+	Title string `json:"title"`
+}
+
+// FollowerReport 为区间内的粉丝变化，按日期倒序；NetGrowth 为区间内净增之和，
+// Start 为区间开始前的总粉丝数，End 为区间最后一天的总粉丝数。
+type FollowerReport struct {
+	Days       []FollowerReportDay `json:"days"`
+	NewUser    int                 `json:"new_user"`
+	CancelUser int                 `json:"cancel_user"`
+	NetGrowth  int                 `json:"net_growth"`
+	Start      int                 `json:"start_cumulate_user"`
+	End        int                 `json:"end_cumulate_user"`
+	UpdatedAt  time.Time           `json:"updated_at,omitempty"`
+}
+
+// FollowerReport 按日期区间汇总粉丝变化，并列出每天的阅读次数与发表的文章，便于对照发文与涨粉。
+func (d AnalyticsData) FollowerReport(filter AnalyticsFilter) FollowerReport {
+	report := FollowerReport{Days: []FollowerReportDay{}, UpdatedAt: d.UpdatedAt}
+	reads := map[string]int{}
+	for _, day := range d.Days {
+		reads[day.Date] = day.ReadCount
+	}
+	published := map[string][]ArticleRef{}
+	for _, t := range d.Totals {
+		published[t.PublishDate] = append(published[t.PublishDate], ArticleRef{MsgID: t.MsgID, Title: t.Title})
+	}
+	for _, f := range d.Followers {
+		if !filter.matchDate(f.Date) {
+			continue
+		}
+		articles := published[f.Date]
+		if articles == nil {
+			articles = []ArticleRef{}
+		}
+		report.Days = append(report.Days, FollowerReportDay{FollowerDayStats: f, ReadCount: reads[f.Date], Articles: articles})
+		report.NewUser += f.NewUser
+		report.CancelUser += f.CancelUser
+		report.NetGrowth += f.NetGrowth
+	}
+	sort.SliceStable(report.Days, func(i, j int) bool { return report.Days[i].Date > report.Days[j].Date })
+	if n := len(report.Days); n > 0 {
+		first := report.Days[n-1]
+		report.End, report.Start = report.Days[0].Cumulate, first.Cumulate-first.NetGrowth
+	}
+	return report
+}
+
+// AnalyticsStore 把图文数据保存为单个 JSON 文件，服务端与命令行共用。
+type AnalyticsStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewAnalyticsStore 创建图文数据存储，文件不存在时视为空。
+func NewAnalyticsStore(path string) *AnalyticsStore {
+	if path == "" {
+		path = DefaultAnalyticsPath
+	}
+	return &AnalyticsStore{path: path}
+}
+
+// Load 读取全部数据。
+func (s *AnalyticsStore) Load() (AnalyticsData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readLocked()
+}
+
+func (s *AnalyticsStore) readLocked() (AnalyticsData, error) {
+	var d AnalyticsData
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return d, nil
+	}
+	if err != nil {
+		return d, err
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return d, fmt.Errorf("analytics %s: %w", s.path, err)
+	}
+	return d, nil
+}
+
+// update 读取数据、由 fn 修改后写回。
+func (s *AnalyticsStore) update(fn func(d *AnalyticsData)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, err := s.readLocked()
+	if err != nil {
+		return err
+	}
+	fn(&d)
+	d.UpdatedAt = time.Now()
+	sort.SliceStable(d.Articles, func(i, j int) bool { return d.Articles[i].Date > d.Articles[j].Date })
+	sort.SliceStable(d.Totals, func(i, j int) bool { return d.Totals[i].PublishDate > d.Totals[j].PublishDate })
+	sort.SliceStable(d.Days, func(i, j int) bool { return d.Days[i].Date > d.Days[j].Date })
+	sort.SliceStable(d.Followers, func(i, j int) bool { return d.Followers[i].Date > d.Followers[j].Date })
+	sort.Sort(sort.Reverse(sort.StringSlice(d.Collected)))
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create analytics dir: %w", err)
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// AnalyticsCollectResult 为一次采集的结果：Collected 为本次补齐每日数据的日期，Articles 为写入的文章每日数据条数，
+// Followers 为写入粉丝数据的天数。
+type AnalyticsCollectResult struct {
+	Collected []string `json:"collected"`
+	Articles  int      `json:"articles"`
+	Totals    int      `json:"totals"`
+	Followers int      `json:"followers"`
+}
+
+// CollectAnalytics 采集 now 前一天起 days 天内的数据：补齐尚未采集的每日数据（force 时全部重新采集），
+// 并更新这些天发表的文章的累计数据。遇到错误时停止，已采集的数据仍然保存。
+func CollectAnalytics(ctx context.Context, p *Publisher, store *AnalyticsStore, now time.Time, days int, force bool) (AnalyticsCollectResult, error) {
+	res := AnalyticsCollectResult{Collected: []string{}}
+	if days <= 0 {
+		days = DefaultAnalyticsDays
+	}
+	existing, err := store.Load()
+	if err != nil {
+		return res, err
+	}
+	collected, hasFollowers := map[string]bool{}, map[string]bool{}
+	for _, d := range existing.Collected {
+		collected[d] = true
+	}
+	for _, f := range existing.Followers {
+		hasFollowers[f.Date] = true
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for i := 1; i <= days; i++ {
+		day := today.AddDate(0, 0, -i)
+		date := day.Format(analyticsDateLayout)
+		totals, err := p.ArticleTotals(ctx, day)
+		if err != nil {
+			return res, err
+		}
+		var articles []ArticleDayStats
+		var account AccountDayStats
+		var followers FollowerDayStats
+		daily := force || !collected[date]
+		if daily {
+			if articles, err = p.ArticleSummary(ctx, day); err != nil {
+				return res, err
+			}
+			if account, err = p.UserRead(ctx, day); err != nil {
+				return res, err
+			}
+		}
+		// 早于粉丝数据采集的日期已标记为采集过，单独补齐粉丝数据。
+		fetchFollowers := daily || !hasFollowers[date]
+		if fetchFollowers {
+			if followers, err = p.Followers(ctx, day); err != nil {
+				return res, err
+			}
+		}
+		err = store.update(func(d *AnalyticsData) {
+			for _, t := range totals {
+				d.Totals = upsert(d.Totals, t, func(x ArticleTotalStats) bool { return x.MsgID == t.MsgID })
+			}
+			if fetchFollowers {
+				d.Followers = upsert(d.Followers, followers, func(x FollowerDayStats) bool { return x.Date == date })
+			}
+			if !daily {
+				return
+			}
+			for _, a := range articles {
+				d.Articles = upsert(d.Articles, a, func(x ArticleDayStats) bool { return x.Date == a.Date && x.MsgID == a.MsgID })
+			}
+			d.Days = upsert(d.Days, account, func(x AccountDayStats) bool { return x.Date == date })
+			if !collected[date] {
+				d.Collected = append(d.Collected, date)
+			}
+		})
+		if err != nil {
+			return res, err
+		}
+		res.Totals += len(totals)
+		if fetchFollowers {
+			res.Followers++
+		}
+		if daily {
+			res.Collected = append(res.Collected, date)
+			res.Articles += len(articles)
+		}
+	}
+	return res, nil
+}
+
+// upsert 替换 items 中第一个满足 match 的元素，没有时追加。
+func upsert[T any](items []T, v T, match func(T) bool) []T {
+	for i := range items {
+		if match(items[i]) {
+			items[i] = v
+			return items
+		}
+	}
+	return append(items, v)
+}
+
+// ValidateAnalytics 检查图文数据采集配置。
+func ValidateAnalytics(cfg *AnalyticsConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Cron != "" {
+		if _, err := ParseCron(cfg.Cron); err != nil {
+			return fmt.Errorf("analytics.cron: %w", err)
+		}
+	}
+	if cfg.Days < 0 || cfg.Days > 30 {
+		return errors.New("analytics.days must be between 1 and 30")
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package publisher
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAuditLogPath 为未配置 audit_log_path 时的审计日志文件。
+const DefaultAuditLogPath = "audit.jsonl"
+
+// 审计操作。
+const (
+	AuditSessionCreated    = "session.created"
+	AuditDraftRevised      = "draft.revised"
+	AuditSessionDeleted    = "session.deleted"
+	AuditPublishRequested  = "publish.requested"
+	AuditPublishCanceled   = "publish.canceled"
+	AuditMassSendRequested = "publish.mass_requested"
+	AuditMassSendApproved  = "publish.mass_approved"
+	AuditConfigChanged     = "config.changed"
+	AuditCommentModerated  = "comment.moderated"
+	AuditTagChanged        = "tag.changed"
+	AuditQuotaCleared      = "quota.cleared"
+	AuditLogin             = "auth.login"
+	AuditLoginFailed       = "auth.login_failed"
+)
+
+// AuditEntry 为一条审计记录：谁（User，未启用登录时为空，命令行操作为 cli:系统用户名）
+// 在何时（Time）对什么（Target，如 session ID、style:key、user:name）做了什么（Action 与 Detail）。
+type AuditEntry struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	User       string    `json:"user,omitempty"`
+	Action     string    `json:"action"`
+	Target     string    `json:"target,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+	RemoteAddr string    `json:"remote_addr,omitempty"`
+}
+
+// AuditFilter 为查询审计记录的条件，零值字段不参与过滤。
+type AuditFilter struct {
+	User   string
+	Action string
+	Target string
+	Since  time.Time
+	Until  time.Time
+	// Limit 为最多返回的条数（最新的在前），0 表示不限。
+	Limit int
+}
+
+func (f AuditFilter) match(e AuditEntry) bool {
+	switch {
+	case f.User != "" && e.User != f.User,
+		f.Action != "" && e.Action != f.Action && !strings.HasPrefix(e.Action, f.Action+"."),
+		f.Target != "" && e.Target != f.Target,
+		!f.Since.IsZero() && e.Time.Before(f.Since),
+		!f.Until.IsZero() && !e.Time.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// AuditLog 以 JSON Lines 只追加地保存审计记录，不提供修改与删除。
+type AuditLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewAuditLog 创建审计日志，文件不存在时视为没有记录。
+func NewAuditLog(path string) *AuditLog {
+	if path == "" {
+		path = DefaultAuditLogPath
+	}
+	return &AuditLog{path: path}
+}
+
+// Path 返回审计日志文件路径。
+func (a *AuditLog) Path() string {
+	return a.path
+}
+
+// Append 追加一条记录，未设置的 ID 与时间自动补全。
+func (a *AuditLog) Append(e AuditEntry) (AuditEntry, error) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.ID == "" {
+		e.ID = strconv.FormatInt(e.Time.UnixNano(), 36)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if dir := filepath.Dir(a.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return e, fmt.Errorf("create audit log dir: %w", err)
+		}
+	}
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return e, err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return e, err
+}
+
+// List 按时间倒序返回符合条件的记录。
+func (a *AuditLog) List(filter AuditFilter) ([]AuditEntry, error) {
+	var all []AuditEntry
+	err := a.scan(filter, func(e AuditEntry, _ []byte) error {
+		all = append(all, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]AuditEntry, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		out = append(out, all[i])
+		if filter.Limit > 0 && len(out) == filter.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// Export 按写入顺序把符合条件的记录原样写出为 JSON Lines（忽略 Limit）。
+func (a *AuditLog) Export(w io.Writer, filter AuditFilter) error {
+	return a.scan(filter, func(_ AuditEntry, line []byte) error {
+		_, err := w.Write(append(line, '\n'))
+		return err
+	})
+}
+
+func (a *AuditLog) scan(filter AuditFilter, fn func(e AuditEntry, line []byte) error) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, err := os.Open(a.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var e AuditEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return fmt.Errorf("audit log %s line %d: %w", a.path, line, err)
+		}
+		if !filter.match(e) {
+			continue
+		}
+		if err := fn(e, sc.Bytes()); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,57 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// MaxDraftArticles 为一条草稿最多包含的图文数。
+const MaxDraftArticles = 8
+
+// PrepareArticle 上传正文图片与封面并转换正文，返回可用于 AddDraft 的图文；可并发调用。
+func (p *Publisher) PrepareArticle(ctx context.Context, params PublishParams) (DraftArticle, error) {
+	if params.MarkdownPath == "" || params.Title == "" || params.CoverPath == "" {
+		return DraftArticle{}, errors.New("markdown path, title, and cover path are required")
+	}
+	if params.Author == "" {
+		params.Author = p.cfg.Author
+	}
+	if p.token() == "" {
+		if err := p.refreshAccessToken(ctx); err != nil {
+			return DraftArticle{}, fmt.Errorf("failed to init access_token: %w", err)
+		}
+	}
+	params.report("token")
+	content, err := p.markdownContent(ctx, params)
+	if err != nil {
+		return DraftArticle{}, err
+	}
+	thumbMediaID, err := p.uploadCover(ctx, params)
+	if err != nil {
+		return DraftArticle{}, err
+	}
+	params.report("cover")
+	// 与 PublishDraft 一致，摘要发送空字符串，避免长度限制错误。
+	return DraftArticle{
+		Title:        params.Title,
+		Author:       params.Author,
+		Content:      content,
+		ThumbMediaID: thumbMediaID,
+	}, nil
+}
+
+// AddDraft 用准备好的图文（1 到 MaxDraftArticles 篇，第一篇为头条）创建一条草稿，返回 media_id。
+func (p *Publisher) AddDraft(ctx context.Context, articles []DraftArticle) (string, error) {
+	if len(articles) == 0 || len(articles) > MaxDraftArticles {
+		return "", fmt.Errorf("a draft holds 1 to %d articles, got %d", MaxDraftArticles, len(articles))
+	}
+	var resp struct {
+		MediaID string `json:"media_id"`
+	}
+	if err := p.callAPI(ctx, addDraftURL, map[string]any{"articles": articles}, &resp); err != nil {
+		return "", err
+	}
+	p.logger.Printf("[publish] addDraft articles=%d title=%q", len(articles), articles[0].Title)
+	return resp.MediaID, nil
+}
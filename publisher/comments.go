@@ -0,0 +1,159 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	openCommentURL  = "https://api.weixin.qq.com/cgi-bin/comment/open"
+	closeCommentURL = "https://api.weixin.qq.com/cgi-bin/comment/close"
+	listCommentURL  = "https://api.weixin.qq.com/cgi-bin/comment/list"
+	markElectURL    = "https://api.weixin.qq.com/cgi-bin/comment/markelect"
+	unmarkElectURL  = "https://api.weixin.qq.com/cgi-bin/comment/unmarkelect"
+	replyCommentURL = "https://api.weixin.qq.com/cgi-bin/comment/reply/add"
+)
+
+// MaxCommentsCount 为每次列出留言的数量上限（微信接口限制）。
+const MaxCommentsCount = 50
+
+// 留言类型，用于 ListComments 过滤。
+const (
+	CommentsAll     = 0
+	CommentsNormal  = 1
+	CommentsElected = 2
+)
+
+// CommentTarget 为已群发的一篇图文：MsgDataID 为群发返回的 msg_data_id，Index 为其在群发中的序号（从 0 开始）。
+type CommentTarget struct {
+	MsgDataID int64 `json:"msg_data_id"`
+	Index     int   `json:"index"`
+}
+
+// String 返回数据统计接口中的 msgid 写法（msg_data_id_n，n 从 1 开始）。
+func (t CommentTarget) String() string {
+	return fmt.Sprintf("%d_%d", t.MsgDataID, t.Index+1)
+}
+
+// ParseCommentTarget 解析文章标识：单独的 msg_data_id 表示第一篇，
+// 数据统计中的 msgid（msg_data_id_n，见 stats）表示第 n 篇。
+func ParseCommentTarget(ref string) (CommentTarget, error) {
+	id, n, hasIndex := strings.Cut(strings.TrimSpace(ref), "_")
+	msgID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || msgID <= 0 {
+		return CommentTarget{}, fmt.Errorf("invalid article %q: use msg_data_id or msgid like 2247483650_1", ref)
+	}
+	t := CommentTarget{MsgDataID: msgID}
+	if hasIndex {
+		i, err := strconv.Atoi(n)
+		if err != nil || i < 1 {
+			return CommentTarget{}, fmt.Errorf("invalid article %q: the number after _ starts from 1", ref)
+		}
+		t.Index = i - 1
+	}
+	return t, nil
+}
+
+// ParseCommentsKind 解析留言类型：all（或空）、normal、elected。
+func ParseCommentsKind(s string) (int, error) {
+	switch s {
+	case "", "all":
+		return CommentsAll, nil
+	case "normal":
+		return CommentsNormal, nil
+	case "elected":
+		return CommentsElected, nil
+	}
+	return 0, fmt.Errorf("comment type must be all, normal or elected, got %q", s)
+}
+
+// ArticleComment 为图文下的一条留言；Elected 表示已精选，Reply 为公众号的回复。
+type ArticleComment struct {
+	ID         int64         `json:"user_comment_id"`
+	OpenID     string        `json:"openid"`
+	Content    string        `json:"content"`
+	CreateTime time.Time     `json:"create_time"`
+	Elected    bool          `json:"elected"`
+	Reply      *CommentReply `json:"reply,omitempty"`
+}
+
+// CommentReply 为公众号对留言的回复。
+type CommentReply struct {
+	Content    string    `json:"content"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+// ListComments 按时间顺序列出图文的留言，kind 为 CommentsAll 等；返回本页留言与留言总数，count 最大 50。
+func (p *Publisher) ListComments(ctx context.Context, t CommentTarget, kind, offset, count int) ([]ArticleComment, int, error) {
+	var resp struct {
+		Total   int `json:"total"`
+		Comment []struct {
+			UserCommentID int64  `json:"user_comment_id"`
+			OpenID        string `json:"openid"`
+			CreateTime    int64  `json:"create_time"`
+			Content       string `json:"content"`
+			CommentType   int    `json:"comment_type"`
+			Reply         struct {
+				Content    string `json:"content"`
+				CreateTime int64  `json:"create_time"`
+			} `json:"reply"`
+		} `json:"comment"`
+	}
+	payload := map[string]any{"msg_data_id": t.MsgDataID, "index": t.Index, "begin": offset, "count": count, "type": kind}
+	if err := p.callAPI(ctx, listCommentURL, payload, &resp); err != nil {
+		return nil, 0, err
+	}
+	comments := make([]ArticleComment, 0, len(resp.Comment))
+	for _, c := range resp.Comment {
+		item := ArticleComment{ID: c.UserCommentID, OpenID: c.OpenID, Content: c.Content, CreateTime: time.Unix(c.CreateTime, 0), Elected: c.CommentType == 1}
+		if c.Reply.Content != "" {
+			item.Reply = &CommentReply{Content: c.Reply.Content, CreateTime: time.Unix(c.Reply.CreateTime, 0)}
+		}
+		comments = append(comments, item)
+	}
+	return comments, resp.Total, nil
+}
+
+// SetCommentsOpen 打开或关闭图文的留言。
+func (p *Publisher) SetCommentsOpen(ctx context.Context, t CommentTarget, open bool) error {
+	endpoint := closeCommentURL
+	if open {
+		endpoint = openCommentURL
+	}
+	if err := p.callAPI(ctx, endpoint, t, nil); err != nil {
+		return err
+	}
+	p.logger.Printf("[comment] %s open=%v", t, open)
+	return nil
+}
+
+// ElectComment 把留言设为精选，elect 为 false 时取消精选。
+func (p *Publisher) ElectComment(ctx context.Context, t CommentTarget, commentID int64, elect bool) error {
+	endpoint := unmarkElectURL
+	if elect {
+		endpoint = markElectURL
+	}
+	payload := map[string]any{"msg_data_id": t.MsgDataID, "index": t.Index, "user_comment_id": commentID}
+	if err := p.callAPI(ctx, endpoint, payload, nil); err != nil {
+		return err
+	}
+	p.logger.Printf("[comment] %s comment=%d elect=%v", t, commentID, elect)
+	return nil
+}
+
+// ReplyComment 以公众号身份回复留言。
+func (p *Publisher) ReplyComment(ctx context.Context, t CommentTarget, commentID int64, content string) error {
+	if strings.TrimSpace(content) == "" {
+		return errors.New("reply content is empty")
+	}
+	payload := map[string]any{"msg_data_id": t.MsgDataID, "index": t.Index, "user_comment_id": commentID, "content": content}
+	if err := p.callAPI(ctx, replyCommentURL, payload, nil); err != nil {
+		return err
+	}
+	p.logger.Printf("[comment] %s replied comment=%d", t, commentID)
+	return nil
+}
@@ -0,0 +1,157 @@
+package publisher
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix 为覆盖配置项的环境变量前缀：配置路径的各级键名转为大写、以下划线连接，
+// 如 app_id → WECHAT_APP_ID，llm.api_key → WECHAT_LLM_API_KEY。
+const EnvPrefix = "WECHAT_"
+
+// ResolveConfigPath 在 path 为 .json 且不存在时，依次尝试同名的 .yaml、.yml 与 .toml 文件。
+func ResolveConfigPath(path string) string {
+	if !strings.EqualFold(filepath.Ext(path), ".json") {
+		return path
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, ext := range []string{".yaml", ".yml", ".toml"} {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+	return path
+}
+
+// decodeConfig 按扩展名解析 JSON、YAML 或 TOML 配置；三种格式的键名相同。
+func decodeConfig(path string, data []byte, cfg *Config) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		if doc == nil {
+			doc = map[string]any{}
+		}
+		return decodeConfigDoc(path, doc, cfg)
+	case ".toml":
+		doc, err := parseTOML(string(data))
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		return decodeConfigDoc(path, doc, cfg)
+	default:
+		return json.Unmarshal(data, cfg)
+	}
+}
+
+// decodeConfigDoc 把 YAML/TOML 文档转成 JSON 后解码，沿用 Config 的 json 标签。
+func decodeConfigDoc(path string, doc any, cfg *Config) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv 用 WECHAT_ 开头的环境变量覆盖配置，空值视为未设置。字符串、数字与布尔值直接填写，
+// 数组、对象（如 WECHAT_NOTIFY、WECHAT_LLM_FALLBACKS）填写 JSON。
+func applyEnv(cfg *Config) error {
+	_, err := applyEnvFields(reflect.ValueOf(cfg).Elem(), strings.TrimSuffix(EnvPrefix, "_"))
+	return err
+}
+
+// applyEnvFields 按 prefix_字段名 逐个覆盖结构体字段；返回是否有环境变量生效。
+func applyEnvFields(v reflect.Value, prefix string) (bool, error) {
+	set := false
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || tag == "" || tag == "-" {
+			continue
+		}
+		ok, err := applyEnvValue(v.Field(i), prefix+"_"+strings.ToUpper(tag))
+		if err != nil {
+			return false, err
+		}
+		set = set || ok
+	}
+	return set, nil
+}
+
+// applyEnvValue 用环境变量 name 覆盖 v，未设置时对结构体逐字段递归。
+func applyEnvValue(v reflect.Value, name string) (bool, error) {
+	if raw := os.Getenv(name); raw != "" {
+		return true, setEnvValue(v, name, raw)
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return applyEnvFields(v, name)
+	case reflect.Pointer:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			return false, nil
+		}
+		// 未配置的可选段落只在有对应环境变量时创建。
+		target := reflect.New(v.Type().Elem())
+		if !v.IsNil() {
+			target.Elem().Set(v.Elem())
+		}
+		ok, err := applyEnvValue(target.Elem(), name)
+		if ok && err == nil {
+			v.Set(target)
+		}
+		return ok, err
+	}
+	return false, nil
+}
+
+func setEnvValue(v reflect.Value, name, raw string) error {
+	if v.Kind() == reflect.Pointer && v.Type().Elem().Kind() != reflect.Struct {
+		target := reflect.New(v.Type().Elem())
+		if err := setEnvValue(target.Elem(), name, raw); err != nil {
+			return err
+		}
+		v.Set(target)
+		return nil
+	}
+	var err error
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(raw); err == nil {
+			v.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if n, err = strconv.ParseInt(raw, 10, 64); err == nil {
+			v.SetInt(n)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(raw, 64); err == nil {
+			v.SetFloat(f)
+		}
+	default:
+		err = json.Unmarshal([]byte(raw), v.Addr().Interface())
+	}
+	if err != nil {
+		return fmt.Errorf("env %s: %w", name, err)
+	}
+	return nil
+}
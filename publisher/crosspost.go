@@ -0,0 +1,203 @@
+package publisher
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// CrossPostProfiles 为支持的跨平台 HTML 格式：知乎、掘金与知识星球。
+var CrossPostProfiles = []string{"zhihu", "juejin", "zsxq"}
+
+// CrossPostOptions 为跨平台 HTML 的渲染参数。
+type CrossPostOptions struct {
+	AllowHTML bool
+	// BaseDir 为解析相对图片路径的目录，一般为 Markdown 文件所在目录。
+	BaseDir string
+	// Images 为本地图片路径到已上传地址的映射（如发布时上传到微信的图片地址），命中时直接引用该地址。
+	Images map[string]string
+}
+
+var (
+	crossHeading   = regexp.MustCompile(`(?s)<h([1-6])[^>]*>(.*?)</h[1-6]>`)
+	crossCodeBlock = regexp.MustCompile(`(?s)<pre><code(?: class="language-([^"]+)")?>(.*?)</code></pre>`)
+	crossImage     = regexp.MustCompile(`<img src="([^"]*)"([^>]*)>`)
+	crossInlineTag = regexp.MustCompile(`</?(?:code|blockquote)>`)
+)
+
+// ValidateCrossPost 检查 cross_post 中的格式名。
+func ValidateCrossPost(profiles []string) error {
+	for _, p := range profiles {
+		if !slices.Contains(CrossPostProfiles, p) {
+			return fmt.Errorf("cross_post: unknown profile %q (supported: %s)", p, strings.Join(CrossPostProfiles, ", "))
+		}
+	}
+	return nil
+}
+
+// RenderCrossPost 把 Markdown 渲染为适合粘贴到指定平台编辑器的 HTML 片段：
+//   - zhihu：标题只保留二、三级，代码块使用知乎的 <pre lang="..."> 写法；
+//   - juejin：保留全部标题，代码块带 hljs 类名以使用掘金的高亮样式；
+//   - zsxq：知识星球只支持段落、加粗与链接，标题转为加粗段落，列表展开为段落，代码块逐行输出，
+//     图片替换为“[图片 N]”占位并在 images 中按顺序返回，需在发帖时单独上传。
+//
+// zhihu 与 juejin 中已上传的图片引用其地址（两者在粘贴时会转存外链图片），其余本地图片内嵌为 data URI。
+func RenderCrossPost(profile, md string, opts CrossPostOptions) (content string, images []string, err error) {
+	if err := ValidateCrossPost([]string{profile}); err != nil {
+		return "", nil, err
+	}
+	_, body := ParseFrontMatter([]byte(md))
+	content, err = mdToHTML(string(body), opts.AllowHTML)
+	if err != nil {
+		return "", nil, err
+	}
+	switch profile {
+	case "zhihu":
+		content = crossHeading.ReplaceAllStringFunc(content, func(block string) string {
+			m := crossHeading.FindStringSubmatch(block)
+			level := "3"
+			if m[1] <= "2" {
+				level = "2"
+			}
+			return fmt.Sprintf("<h%s>%s</h%s>", level, m[2], level)
+		})
+		content = crossCodeBlock.ReplaceAllStringFunc(content, func(block string) string {
+			m := crossCodeBlock.FindStringSubmatch(block)
+			if m[1] == "" {
+				return block
+			}
+			return fmt.Sprintf(`<pre lang="%s"><code>%s</code></pre>`, m[1], m[2])
+		})
+	case "juejin":
+		content = crossCodeBlock.ReplaceAllStringFunc(content, func(block string) string {
+			m := crossCodeBlock.FindStringSubmatch(block)
+			class := "hljs"
+			if m[1] != "" {
+				class += " language-" + m[1]
+			}
+			return fmt.Sprintf(`<pre><code class="%s">%s</code></pre>`, class, m[2])
+		})
+	case "zsxq":
+		content = crossHeading.ReplaceAllString(content, "<p><strong>$2</strong></p>")
+		content = flattenListsForWeChat(content)
+		content = crossCodeBlock.ReplaceAllStringFunc(content, func(block string) string {
+			code := strings.TrimRight(crossCodeBlock.FindStringSubmatch(block)[2], "\n")
+			lines := strings.Split(code, "\n")
+			for i, l := range lines {
+				trimmed := strings.TrimLeft(l, " \t")
+				indent := strings.ReplaceAll(l[:len(l)-len(trimmed)], "\t", "    ")
+				lines[i] = strings.Repeat("&nbsp;", len(indent)) + trimmed
+			}
+			return "<p>" + strings.Join(lines, "<br>") + "</p>"
+		})
+		content = crossInlineTag.ReplaceAllString(content, "")
+		content = strings.ReplaceAll(content, "<hr>", "<p>——————</p>")
+		content = crossImage.ReplaceAllStringFunc(content, func(tag string) string {
+			// 需要手动上传，列出本地文件而不是上传地址。
+			src := crossImageSource(crossImage.FindStringSubmatch(tag)[1], CrossPostOptions{BaseDir: opts.BaseDir})
+			images = append(images, src)
+			return fmt.Sprintf("[图片 %d]", len(images))
+		})
+		return content, images, nil
+	}
+
+	var imgErr error
+	content = crossImage.ReplaceAllStringFunc(content, func(tag string) string {
+		m := crossImage.FindStringSubmatch(tag)
+		src := crossImageSource(m[1], opts)
+		if isLocalImageRef(src) {
+			data, err := os.ReadFile(src)
+			if err != nil {
+				if imgErr == nil {
+					imgErr = fmt.Errorf("image %s: %w", src, err)
+				}
+				return tag
+			}
+			src = "data:" + http.DetectContentType(data) + ";base64," + base64.StdEncoding.EncodeToString(data)
+		}
+		return `<img src="` + html.EscapeString(src) + `"` + m[2] + ">"
+	})
+	return content, nil, imgErr
+}
+
+// crossImageSource 返回图片的地址：已上传的本地图片返回上传地址，其余本地图片返回按 BaseDir 解析的路径。
+func crossImageSource(src string, opts CrossPostOptions) string {
+	src = html.UnescapeString(src)
+	if u, err := url.PathUnescape(src); err == nil {
+		src = u
+	}
+	if !isLocalImageRef(src) {
+		return src
+	}
+	local := src
+	if !filepath.IsAbs(local) {
+		if _, err := os.Stat(local); err != nil {
+			local = filepath.Join(opts.BaseDir, src)
+		}
+	}
+	if uploaded, ok := opts.Images[local]; ok {
+		return uploaded
+	}
+	return local
+}
+
+// crossPostPage 为跨平台 HTML 文件：在浏览器中打开，全选正文复制后粘贴到对应平台的编辑器。
+const crossPostPage = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>body{max-width:720px;margin:0 auto;padding:20px 16px;font-family:-apple-system,BlinkMacSystemFont,"PingFang SC","Microsoft YaHei",sans-serif;color:#333}img{max-width:100%%}aside{margin-top:2em;padding-top:1em;border-top:1px solid #ddd;color:#888;font-size:14px}</style>
+</head>
+<body>
+<article>
+%s
+</article>
+%s</body>
+</html>
+`
+
+// CrossPostPage 返回指定平台的完整 HTML 页面；知识星球格式在正文之后列出需要单独上传的图片。
+func CrossPostPage(profile, title, md string, opts CrossPostOptions) (string, error) {
+	content, images, err := RenderCrossPost(profile, md, opts)
+	if err != nil {
+		return "", err
+	}
+	var aside strings.Builder
+	if len(images) > 0 {
+		aside.WriteString("<aside>\n<p>需要单独上传的图片：</p>\n<ol>\n")
+		for _, img := range images {
+			fmt.Fprintf(&aside, "<li>%s</li>\n", html.EscapeString(img))
+		}
+		aside.WriteString("</ol>\n</aside>\n")
+	}
+	return fmt.Sprintf(crossPostPage, html.EscapeString(title), content, aside.String()), nil
+}
+
+// WriteCrossPost 按 profiles 渲染 mdPath 对应的文章，写入同目录的 <文件名>.<格式>.html，返回写入的文件。
+func WriteCrossPost(mdPath, title, md string, profiles []string, opts CrossPostOptions) ([]string, error) {
+	if opts.BaseDir == "" {
+		opts.BaseDir = filepath.Dir(mdPath)
+	}
+	base := strings.TrimSuffix(mdPath, filepath.Ext(mdPath))
+	var paths []string
+	for _, profile := range profiles {
+		page, err := CrossPostPage(profile, title, md, opts)
+		if err != nil {
+			return paths, fmt.Errorf("%s: %w", profile, err)
+		}
+		path := base + "." + profile + ".html"
+		if err := os.WriteFile(path, []byte(page), 0o644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,78 @@
+package publisher
+
+import (
+	"context"
+	"regexp"
+)
+
+const (
+	apiDomainIPURL  = "https://api.weixin.qq.com/cgi-bin/get_api_domain_ip"
+	callbackIPURL   = "https://api.weixin.qq.com/cgi-bin/getcallbackip"
+	selfMenuInfoURL = "https://api.weixin.qq.com/cgi-bin/get_current_selfmenu_info"
+)
+
+// ErrCodeIPNotWhitelisted 为调用方 IP 不在公众号 IP 白名单中时微信返回的错误码；
+// ErrCodeWeComIPNotAllowed 为企业微信中调用方 IP 不在应用的企业可信 IP 中时返回的错误码。
+const (
+	ErrCodeIPNotWhitelisted  = 40164
+	ErrCodeWeComIPNotAllowed = 60020
+)
+
+// whitelistIPPattern 匹配错误信息中的调用方 IP，如 "invalid ip 1.2.3.4 ipv6 ::ffff:1.2.3.4, not in whitelist"
+// 或企业微信的 "not allow to access from your ip, ... from ip: 1.2.3.4"。
+var whitelistIPPattern = regexp.MustCompile(`(?:invalid ip|from ip:) ([0-9a-fA-F:.]+)`)
+
+// WhitelistIP 返回 40164（或企业微信 60020）错误中看到的调用方出口 IP，其他错误返回空字符串。
+func WhitelistIP(err error) string {
+	if code := WeChatErrorCode(err); code != ErrCodeIPNotWhitelisted && code != ErrCodeWeComIPNotAllowed {
+		return ""
+	}
+	if m := whitelistIPPattern.FindStringSubmatch(WeChatErrorMessage(err)); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// APIDomainIPs 返回 api.weixin.qq.com 的服务器 IP 列表，用于核对 DNS 解析与出口代理。
+func (p *Publisher) APIDomainIPs(ctx context.Context) ([]string, error) {
+	var resp struct {
+		IPList []string `json:"ip_list"`
+	}
+	if err := p.callAPI(ctx, apiDomainIPURL, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.IPList, nil
+}
+
+// CallbackIPs 返回微信推送消息与事件时使用的服务器 IP 列表，用于配置防火墙。
+func (p *Publisher) CallbackIPs(ctx context.Context) ([]string, error) {
+	var resp struct {
+		IPList []string `json:"ip_list"`
+	}
+	if err := p.callAPI(ctx, callbackIPURL, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.IPList, nil
+}
+
+// SelfMenu 为公众号当前的自定义菜单：Open 表示菜单已开启，Buttons 为一级菜单数。
+type SelfMenu struct {
+	Open    bool `json:"is_menu_open"`
+	Buttons int  `json:"buttons"`
+}
+
+// CurrentSelfMenu 读取公众号当前的自定义菜单配置，可用来确认帐号的接口权限。
+func (p *Publisher) CurrentSelfMenu(ctx context.Context) (SelfMenu, error) {
+	var resp struct {
+		IsMenuOpen   int `json:"is_menu_open"`
+		SelfMenuInfo struct {
+			Button []struct {
+				Name string `json:"name"`
+			} `json:"button"`
+		} `json:"selfmenu_info"`
+	}
+	if err := p.callAPI(ctx, selfMenuInfoURL, nil, &resp); err != nil {
+		return SelfMenu{}, err
+	}
+	return SelfMenu{Open: resp.IsMenuOpen == 1, Buttons: len(resp.SelfMenuInfo.Button)}, nil
+}
@@ -0,0 +1,549 @@
+package publisher
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ConvertedDocument 为 Word 或 HTML 文件转换得到的 Markdown 文章。
+type ConvertedDocument struct {
+	Title    string
+	Markdown string
+	// Images 为提取到 dir/images 下的图片，Markdown 中以 images/<文件名> 引用。
+	Images []string
+	// Warnings 为未能下载、保持远程链接的图片等非致命问题。
+	Warnings []string
+}
+
+// xmlNode 为通用的 XML 元素树，按本地名访问，忽略命名空间前缀。
+type xmlNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Nodes   []*xmlNode `xml:",any"`
+	Text    string     `xml:",chardata"`
+}
+
+// attr 返回本地名为 local 的属性值，n 为 nil 或没有该属性时为空串。
+func (n *xmlNode) attr(local string) string {
+	if n == nil {
+		return ""
+	}
+	for _, a := range n.Attrs {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// child 返回第一个名为 local 的子元素，没有时为 nil。
+func (n *xmlNode) child(local string) *xmlNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.Nodes {
+		if c.XMLName.Local == local {
+			return c
+		}
+	}
+	return nil
+}
+
+// find 深度优先查找第一个名为 local 的后代元素。
+func (n *xmlNode) find(local string) *xmlNode {
+	for _, c := range n.Nodes {
+		if c.XMLName.Local == local {
+			return c
+		}
+		if f := c.find(local); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// docxStyle 为段落样式中转换用到的部分。
+type docxStyle struct {
+	name    string
+	basedOn string
+	outline int // 大纲级别 + 1，0 表示不是标题
+}
+
+// docxConverter 保存一次 .docx 转换的上下文：关系、样式、编号与已提取的图片。
+type docxConverter struct {
+	zip       *zip.Reader
+	dir       string
+	rels      map[string]string // 关系 ID -> 目标（图片为 zip 内路径，链接为 URL）
+	styles    map[string]docxStyle
+	numFormat map[string]map[string]string // numId -> ilvl -> numFmt
+	images    map[string]string            // zip 内路径 -> Markdown 引用
+	doc       ConvertedDocument
+	numbers   map[string]int // 有序列表 numId/ilvl -> 当前序号
+}
+
+// ConvertDocx 把 Word (.docx) 文档转为 Markdown：标题样式转为 #，项目符号与编号转为列表，
+// 表格转为 Markdown 表格，加粗、斜体、删除线与超链接保留，图片提取到 dir/images。
+// 标题取「标题」样式的段落，没有时取文档属性中的标题。
+func ConvertDocx(file, dir string) (ConvertedDocument, error) {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return ConvertedDocument{}, fmt.Errorf("open %s: %w", file, err)
+	}
+	defer zr.Close()
+	c := &docxConverter{
+		zip: &zr.Reader, dir: dir, rels: map[string]string{}, styles: map[string]docxStyle{},
+		numFormat: map[string]map[string]string{}, images: map[string]string{}, numbers: map[string]int{},
+	}
+	body, err := c.readXML("word/document.xml")
+	if err != nil {
+		return ConvertedDocument{}, fmt.Errorf("%s is not a Word document: %w", file, err)
+	}
+	body = body.child("body")
+	if body == nil {
+		return ConvertedDocument{}, fmt.Errorf("%s: word/document.xml has no body", file)
+	}
+	if err := c.loadParts(); err != nil {
+		return ConvertedDocument{}, fmt.Errorf("%s: %w", file, err)
+	}
+
+	var sb strings.Builder
+	if err := c.blocks(&sb, body); err != nil {
+		return c.doc, err
+	}
+	if c.doc.Title == "" {
+		if core, err := c.readXML("docProps/core.xml"); err == nil {
+			if t := core.child("title"); t != nil {
+				c.doc.Title = strings.TrimSpace(t.Text)
+			}
+		}
+	}
+	c.doc.Markdown = strings.TrimSpace(sb.String()) + "\n"
+	return c.doc, nil
+}
+
+// readXML 读取并解析 zip 内的 XML 文件，文件不存在时返回 os.ErrNotExist。
+func (c *docxConverter) readXML(name string) (*xmlNode, error) {
+	f, err := c.zip.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var n xmlNode
+	if err := xml.NewDecoder(f).Decode(&n); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", name, err)
+	}
+	return &n, nil
+}
+
+// loadParts 读取关系、样式与编号定义，后两者缺失时按无样式处理。
+func (c *docxConverter) loadParts() error {
+	if rels, err := c.readXML("word/_rels/document.xml.rels"); err == nil {
+		for _, r := range rels.Nodes {
+			target := r.attr("Target")
+			if r.attr("TargetMode") != "External" {
+				target = strings.TrimPrefix(path.Clean(path.Join("word", target)), "/")
+			}
+			c.rels[r.attr("Id")] = target
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if styles, err := c.readXML("word/styles.xml"); err == nil {
+		for _, s := range styles.Nodes {
+			if s.XMLName.Local != "style" {
+				continue
+			}
+			st := docxStyle{name: strings.ToLower(s.child("name").attr("val")), basedOn: s.child("basedOn").attr("val")}
+			if lvl := s.child("pPr").child("outlineLvl"); lvl != nil {
+				if n, err := strconv.Atoi(lvl.attr("val")); err == nil && n < 9 {
+					st.outline = n + 1
+				}
+			}
+			c.styles[s.attr("styleId")] = st
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if numbering, err := c.readXML("word/numbering.xml"); err == nil {
+		abstract := map[string]map[string]string{}
+		for _, a := range numbering.Nodes {
+			if a.XMLName.Local != "abstractNum" {
+				continue
+			}
+			levels := map[string]string{}
+			for _, l := range a.Nodes {
+				if l.XMLName.Local == "lvl" {
+					levels[l.attr("ilvl")] = l.child("numFmt").attr("val")
+				}
+			}
+			abstract[a.attr("abstractNumId")] = levels
+		}
+		for _, n := range numbering.Nodes {
+			if n.XMLName.Local == "num" {
+				c.numFormat[n.attr("numId")] = abstract[n.child("abstractNumId").attr("val")]
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// style 沿 basedOn 链查找段落样式的名称与标题级别。
+func (c *docxConverter) style(id string) docxStyle {
+	st := c.styles[id]
+	for i, base := 0, st.basedOn; st.outline == 0 && base != "" && i < 10; i++ {
+		parent := c.styles[base]
+		st.outline, base = parent.outline, parent.basedOn
+	}
+	if st.outline == 0 {
+		// 部分文档的标题样式没有大纲级别，按名称 heading N 识别。
+		if n, err := strconv.Atoi(strings.TrimPrefix(st.name, "heading ")); err == nil && strings.HasPrefix(st.name, "heading ") {
+			st.outline = n
+		}
+	}
+	return st
+}
+
+// blocks 转换 body、单元格等容器中的段落与表格；连续的代码样式段落合并为一个代码块。
+func (c *docxConverter) blocks(sb *strings.Builder, parent *xmlNode) error {
+	var code []string
+	inList, listID := false, ""
+	flush := func() {
+		if len(code) > 0 {
+			fmt.Fprintf(sb, "```\n%s\n```\n\n", strings.Join(code, "\n"))
+			code = nil
+		}
+	}
+	for _, n := range parent.Nodes {
+		switch n.XMLName.Local {
+		case "p":
+			pPr := n.child("pPr")
+			st := c.style(pPr.child("pStyle").attr("val"))
+			if strings.Contains(st.name, "code") || st.name == "html preformatted" {
+				text, err := c.runs(n, true)
+				if err != nil {
+					return err
+				}
+				code = append(code, text)
+				continue
+			}
+			flush()
+			text, err := c.runs(n, false)
+			if err != nil {
+				return err
+			}
+			text = strings.TrimSpace(text)
+			numPr := pPr.child("numPr")
+			isItem := numPr != nil && numPr.child("numId").attr("val") != "0" && st.outline == 0
+			if inList && !isItem && text != "" {
+				sb.WriteString("\n")
+				inList = false
+			}
+			switch {
+			case text == "":
+			case st.name == "title" && c.doc.Title == "":
+				c.doc.Title = plainText(text)
+			case st.name == "title" || st.outline > 0:
+				fmt.Fprintf(sb, "%s %s\n\n", strings.Repeat("#", min(max(st.outline, 1), 6)), text)
+			case isItem:
+				numID, ilvl := numPr.child("numId").attr("val"), numPr.child("ilvl").attr("val")
+				level, _ := strconv.Atoi(ilvl)
+				if inList && level == 0 && numID != listID {
+					// 不同编号定义的列表相邻时空一行分开，避免并成一个列表。
+					sb.WriteString("\n")
+				}
+				listID = numID
+				marker := "- "
+				if f := c.numFormat[numID][ilvl]; f != "" && f != "bullet" && f != "none" {
+					key := numID + "/" + ilvl
+					c.numbers[key]++
+					marker = fmt.Sprintf("%d. ", c.numbers[key])
+				}
+				// 回到上一级时重新开始下级的编号。
+				for k := range c.numbers {
+					if id, l, ok := strings.Cut(k, "/"); ok && id == numID {
+						if n, _ := strconv.Atoi(l); n > level {
+							delete(c.numbers, k)
+						}
+					}
+				}
+				fmt.Fprintf(sb, "%s%s%s\n", strings.Repeat("    ", level), marker, text)
+				inList = true
+			case strings.Contains(st.name, "quote"):
+				fmt.Fprintf(sb, "> %s\n\n", text)
+			default:
+				fmt.Fprintf(sb, "%s\n\n", text)
+			}
+		case "tbl":
+			flush()
+			if inList {
+				sb.WriteString("\n")
+				inList = false
+			}
+			if err := c.table(sb, n); err != nil {
+				return err
+			}
+		case "sdt":
+			// 内容控件：转换其中的段落。
+			if content := n.child("sdtContent"); content != nil {
+				flush()
+				if err := c.blocks(sb, content); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	flush()
+	if inList {
+		sb.WriteString("\n")
+	}
+	return nil
+}
+
+// table 把表格转为 Markdown 表格，第一行为表头，单元格内的多个段落以空格连接。
+func (c *docxConverter) table(sb *strings.Builder, tbl *xmlNode) error {
+	var rows [][]string
+	cols := 0
+	for _, tr := range tbl.Nodes {
+		if tr.XMLName.Local != "tr" {
+			continue
+		}
+		var row []string
+		for _, tc := range tr.Nodes {
+			if tc.XMLName.Local != "tc" {
+				continue
+			}
+			var inner strings.Builder
+			if err := c.blocks(&inner, tc); err != nil {
+				return err
+			}
+			row = append(row, strings.ReplaceAll(strings.Join(strings.Fields(inner.String()), " "), "|", "\\|"))
+		}
+		cols = max(cols, len(row))
+		rows = append(rows, row)
+	}
+	if cols == 0 {
+		return nil
+	}
+	for i, row := range rows {
+		for len(row) < cols {
+			row = append(row, "")
+		}
+		fmt.Fprintf(sb, "| %s |\n", strings.Join(row, " | "))
+		if i == 0 {
+			fmt.Fprintf(sb, "|%s\n", strings.Repeat(" --- |", cols))
+		}
+	}
+	sb.WriteString("\n")
+	return nil
+}
+
+// docxRun 为一段格式相同的文本。
+type docxRun struct {
+	text                         string
+	bold, italic, strike, isCode bool
+	link                         string
+	// raw 为已是 Markdown 的图片语法，原样输出。
+	raw bool
+}
+
+// runs 转换段落中的文本、超链接与图片；code 为 true 时输出纯文本。
+func (c *docxConverter) runs(p *xmlNode, code bool) (string, error) {
+	var out []docxRun
+	var walk func(n *xmlNode, link string) error
+	walk = func(n *xmlNode, link string) error {
+		for _, r := range n.Nodes {
+			switch r.XMLName.Local {
+			case "hyperlink":
+				if err := walk(r, c.rels[r.attr("id")]); err != nil {
+					return err
+				}
+			case "smartTag", "ins", "fldSimple":
+				if err := walk(r, link); err != nil {
+					return err
+				}
+			case "r":
+				rPr := r.child("rPr")
+				run := docxRun{
+					bold: docxOn(rPr.child("b")), italic: docxOn(rPr.child("i")), strike: docxOn(rPr.child("strike")),
+					isCode: strings.Contains(strings.ToLower(c.styles[rPr.child("rStyle").attr("val")].name), "code"), link: link,
+				}
+				for _, t := range r.Nodes {
+					switch t.XMLName.Local {
+					case "t":
+						run.text += t.Text
+					case "tab":
+						run.text += "\t"
+					case "br", "cr":
+						if t.attr("type") == "" || t.attr("type") == "textWrapping" {
+							if code {
+								run.text += "\n"
+							} else {
+								run.text += "  \n"
+							}
+						}
+					case "drawing", "pict", "object":
+						img, err := c.image(t)
+						if err != nil {
+							return err
+						}
+						if img != "" {
+							out = append(out, run)
+							out = append(out, docxRun{text: img, raw: true})
+							run.text = ""
+						}
+					}
+				}
+				out = append(out, run)
+			}
+		}
+		return nil
+	}
+	if err := walk(p, ""); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if code {
+		for _, r := range out {
+			sb.WriteString(r.text)
+		}
+		return sb.String(), nil
+	}
+	// 合并格式相同的相邻文本，避免输出 **a****b**。
+	var merged []docxRun
+	for _, r := range out {
+		if r.text == "" {
+			continue
+		}
+		if len(merged) > 0 {
+			last := &merged[len(merged)-1]
+			same := *last
+			same.text = r.text
+			if same == r && !r.raw {
+				last.text += r.text
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	for _, r := range merged {
+		if r.raw {
+			sb.WriteString(r.text)
+			continue
+		}
+		sb.WriteString(markdownRun(r.text, r.bold, r.italic, r.strike, r.isCode, r.link))
+	}
+	return sb.String(), nil
+}
+
+// markdownRun 给文本加上 Markdown 格式，首尾空白放在标记之外。
+func markdownRun(s string, bold, italic, strike, code bool, link string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	lead, trail := s[:strings.Index(s, trimmed)], s[strings.Index(s, trimmed)+len(trimmed):]
+	s = trimmed
+	switch {
+	case code:
+		s = "`" + s + "`"
+	default:
+		if bold {
+			s = "**" + s + "**"
+		}
+		if italic {
+			s = "*" + s + "*"
+		}
+		if strike {
+			s = "~~" + s + "~~"
+		}
+	}
+	if link != "" {
+		s = "[" + s + "](" + link + ")"
+	}
+	return lead + s + trail
+}
+
+// docxOn 判断 <w:b/> 这类开关属性是否打开（val 为 0/false/off 时关闭）。
+func docxOn(n *xmlNode) bool {
+	if n == nil {
+		return false
+	}
+	switch n.attr("val") {
+	case "0", "false", "off", "none":
+		return false
+	}
+	return true
+}
+
+// image 提取 drawing/pict 中引用的图片，返回 Markdown 图片语法；没有图片时返回空串。
+func (c *docxConverter) image(n *xmlNode) (string, error) {
+	id := ""
+	if blip := n.find("blip"); blip != nil {
+		id = blip.attr("embed")
+	} else if data := n.find("imagedata"); data != nil {
+		id = data.attr("id")
+	}
+	target, ok := c.rels[id]
+	if id == "" || !ok {
+		return "", nil
+	}
+	alt := ""
+	if pr := n.find("docPr"); pr != nil {
+		alt = strings.TrimSpace(pr.attr("descr"))
+	}
+	ref, ok := c.images[target]
+	if !ok {
+		f, err := c.zip.Open(target)
+		if err != nil {
+			return "", fmt.Errorf("image %s: %w", target, err)
+		}
+		defer f.Close()
+		dst, err := createImage(c.dir, path.Base(target))
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.Copy(dst, f); err != nil {
+			dst.Close()
+			return "", err
+		}
+		if err := dst.Close(); err != nil {
+			return "", err
+		}
+		c.doc.Images = append(c.doc.Images, dst.Name())
+		ref = "images/" + filepath.Base(dst.Name())
+		c.images[target] = ref
+	}
+	return "![" + alt + "](" + ref + ")", nil
+}
+
+// createImage 在 dir/images 下创建图片文件，重名时加上序号。
+func createImage(dir, name string) (*os.File, error) {
+	imageDir := filepath.Join(dir, "images")
+	if err := os.MkdirAll(imageDir, 0o755); err != nil {
+		return nil, err
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		f, err := os.OpenFile(filepath.Join(imageDir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if !errors.Is(err, os.ErrExist) || i > 1000 {
+			return f, err
+		}
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+}
+
+// plainText 去掉 Markdown 的加粗、斜体等标记，用于标题。
+func plainText(s string) string {
+	return strings.NewReplacer("**", "", "~~", "", "`", "", "*", "").Replace(s)
+}
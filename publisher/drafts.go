@@ -0,0 +1,276 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	batchGetDraftURL     = "https://api.weixin.qq.com/cgi-bin/draft/batchget"
+	getDraftURL          = "https://api.weixin.qq.com/cgi-bin/draft/get"
+	updateDraftURL       = "https://api.weixin.qq.com/cgi-bin/draft/update"
+	deleteDraftURL       = "https://api.weixin.qq.com/cgi-bin/draft/delete"
+	batchGetMaterialURL  = "https://api.weixin.qq.com/cgi-bin/material/batchget_material"
+	massPreviewURL       = "https://api.weixin.qq.com/cgi-bin/message/mass/preview"
+	batchGetPublishedURL = "https://api.weixin.qq.com/cgi-bin/freepublish/batchget"
+)
+
+// DraftArticle 为草稿箱中的一篇图文。
+type DraftArticle struct {
+	Title              string `json:"title"`
+	Author             string `json:"author"`
+	Digest             string `json:"digest"`
+	Content            string `json:"content,omitempty"`
+	ContentSourceURL   string `json:"content_source_url,omitempty"`
+	ThumbMediaID       string `json:"thumb_media_id"`
+	URL                string `json:"url,omitempty"`
+	ThumbURL           string `json:"thumb_url,omitempty"`
+	IsDeleted          bool   `json:"is_deleted,omitempty"`
+	NeedOpenComment    int    `json:"need_open_comment"`
+	OnlyFansCanComment int    `json:"only_fans_can_comment"`
+}
+
+// DraftItem 为草稿箱中的一条草稿（可含多篇图文）。
+type DraftItem struct {
+	MediaID    string         `json:"media_id"`
+	UpdateTime time.Time      `json:"update_time"`
+	Articles   []DraftArticle `json:"articles"`
+}
+
+// MaterialItem 为永久素材；图文素材的 Title 为第一篇的标题。
+type MaterialItem struct {
+	MediaID    string    `json:"media_id"`
+	Name       string    `json:"name,omitempty"`
+	Title      string    `json:"title,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	UpdateTime time.Time `json:"update_time"`
+}
+
+// DraftUpdate 描述对草稿中第 Index 篇图文的修改，空字段保持原值。
+type DraftUpdate struct {
+	Index        int
+	Title        string
+	Author       string
+	Digest       string
+	MarkdownPath string
+	CoverPath    string
+}
+
+// wechatStatus 为微信接口响应中的错误码。
+type wechatStatus struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// callAPI 以 access_token 调用微信 JSON 接口并解码响应；令牌过期时刷新一次后重试。
+// payload 为 nil 时以 GET 请求（如 tags/get）。
+func (p *Publisher) callAPI(ctx context.Context, endpoint string, payload, out any) error {
+	if p.token() == "" {
+		if err := p.refreshAccessToken(ctx); err != nil {
+			return fmt.Errorf("failed to init access_token: %w", err)
+		}
+	}
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+	_, err := p.withTokenRefreshString(ctx, func(token string) (string, error) {
+		method, reader := http.MethodGet, io.Reader(nil)
+		if body != nil {
+			method, reader = http.MethodPost, bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+		if err != nil {
+			return "", err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		q := req.URL.Query()
+		q.Set("access_token", token)
+		req.URL.RawQuery = q.Encode()
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		var raw json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+			return "", err
+		}
+		var status wechatStatus
+		if err := json.Unmarshal(raw, &status); err == nil && status.ErrCode != 0 {
+			return "", &wechatAPIError{Code: status.ErrCode, Msg: status.ErrMsg}
+		}
+		if out == nil {
+			return "", nil
+		}
+		return "", json.Unmarshal(raw, out)
+	})
+	return err
+}
+
+type draftNewsResp struct {
+	NewsItem []DraftArticle `json:"news_item"`
+}
+
+// ListDrafts 按更新时间倒序列出草稿箱（不含正文），返回本页草稿与草稿总数；count 最大 20。
+func (p *Publisher) ListDrafts(ctx context.Context, offset, count int) ([]DraftItem, int, error) {
+	return p.batchGetNews(ctx, batchGetDraftURL, offset, count, false)
+}
+
+// ListDraftContents 与 ListDrafts 相同，但包含正文。
+func (p *Publisher) ListDraftContents(ctx context.Context, offset, count int) ([]DraftItem, int, error) {
+	return p.batchGetNews(ctx, batchGetDraftURL, offset, count, true)
+}
+
+// ListPublished 按更新时间倒序列出发布成功的图文（含正文），MediaID 为 article_id；count 最大 20。
+func (p *Publisher) ListPublished(ctx context.Context, offset, count int) ([]DraftItem, int, error) {
+	return p.batchGetNews(ctx, batchGetPublishedURL, offset, count, true)
+}
+
+// batchGetNews 调用草稿箱或发布记录的 batchget 接口，两者仅条目 ID 字段名不同。
+func (p *Publisher) batchGetNews(ctx context.Context, endpoint string, offset, count int, withContent bool) ([]DraftItem, int, error) {
+	var resp struct {
+		TotalCount int `json:"total_count"`
+		Item       []struct {
+			MediaID    string        `json:"media_id"`
+			ArticleID  string        `json:"article_id"`
+			Content    draftNewsResp `json:"content"`
+			UpdateTime int64         `json:"update_time"`
+		} `json:"item"`
+	}
+	payload := map[string]int{"offset": offset, "count": count, "no_content": 1}
+	if withContent {
+		payload["no_content"] = 0
+	}
+	if err := p.callAPI(ctx, endpoint, payload, &resp); err != nil {
+		return nil, 0, err
+	}
+	items := make([]DraftItem, 0, len(resp.Item))
+	for _, it := range resp.Item {
+		id := it.MediaID
+		if id == "" {
+			id = it.ArticleID
+		}
+		items = append(items, DraftItem{MediaID: id, UpdateTime: time.Unix(it.UpdateTime, 0), Articles: it.Content.NewsItem})
+	}
+	return items, resp.TotalCount, nil
+}
+
+// GetDraft 返回草稿的全部图文（含正文）。
+func (p *Publisher) GetDraft(ctx context.Context, mediaID string) ([]DraftArticle, error) {
+	var resp draftNewsResp
+	if err := p.callAPI(ctx, getDraftURL, map[string]string{"media_id": mediaID}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.NewsItem, nil
+}
+
+// UpdateDraft 修改草稿中的一篇图文：MarkdownPath 非空时重新上传正文图片并替换正文，
+// CoverPath 非空时上传新封面，其余字段为空时保持原值。
+func (p *Publisher) UpdateDraft(ctx context.Context, mediaID string, upd DraftUpdate) error {
+	articles, err := p.GetDraft(ctx, mediaID)
+	if err != nil {
+		return err
+	}
+	if upd.Index < 0 || upd.Index >= len(articles) {
+		return fmt.Errorf("draft %s has %d article(s), index %d out of range", mediaID, len(articles), upd.Index)
+	}
+	art := articles[upd.Index]
+	art.URL = ""
+	if upd.Title != "" {
+		art.Title = upd.Title
+	}
+	if upd.Author != "" {
+		art.Author = upd.Author
+	}
+	if upd.Digest != "" {
+		art.Digest = upd.Digest
+	}
+	if upd.MarkdownPath != "" {
+		if art.Content, err = p.markdownContent(ctx, PublishParams{MarkdownPath: upd.MarkdownPath}); err != nil {
+			return err
+		}
+	}
+	if upd.CoverPath != "" {
+		art.ThumbMediaID, err = p.withTokenRefreshString(ctx, func(token string) (string, error) {
+			return uploadImage(ctx, p.client, uploadImageURL, token, upd.CoverPath)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	payload := map[string]any{"media_id": mediaID, "index": upd.Index, "articles": art}
+	if err := p.callAPI(ctx, updateDraftURL, payload, nil); err != nil {
+		return err
+	}
+	p.logger.Printf("[draft] updated title=%q index=%d", art.Title, upd.Index)
+	return nil
+}
+
+// DeleteDraft 删除草稿，删除后无法恢复。
+func (p *Publisher) DeleteDraft(ctx context.Context, mediaID string) error {
+	if err := p.callAPI(ctx, deleteDraftURL, map[string]string{"media_id": mediaID}, nil); err != nil {
+		return err
+	}
+	p.logger.Printf("[draft] deleted")
+	return nil
+}
+
+// ListMaterials 列出永久素材，kind 为 image、video、voice 或 news；返回本页素材与素材总数，count 最大 20。
+func (p *Publisher) ListMaterials(ctx context.Context, kind string, offset, count int) ([]MaterialItem, int, error) {
+	switch kind {
+	case "image", "video", "voice", "news":
+	default:
+		return nil, 0, fmt.Errorf("material type must be image, video, voice or news, got %q", kind)
+	}
+	var resp struct {
+		TotalCount int `json:"total_count"`
+		Item       []struct {
+			MediaID    string        `json:"media_id"`
+			Name       string        `json:"name"`
+			URL        string        `json:"url"`
+			Content    draftNewsResp `json:"content"`
+			UpdateTime int64         `json:"update_time"`
+		} `json:"item"`
+	}
+	payload := map[string]any{"type": kind, "offset": offset, "count": count}
+	if err := p.callAPI(ctx, batchGetMaterialURL, payload, &resp); err != nil {
+		return nil, 0, err
+	}
+	items := make([]MaterialItem, 0, len(resp.Item))
+	for _, it := range resp.Item {
+		item := MaterialItem{MediaID: it.MediaID, Name: it.Name, URL: it.URL, UpdateTime: time.Unix(it.UpdateTime, 0)}
+		if len(it.Content.NewsItem) > 0 {
+			item.Title = it.Content.NewsItem[0].Title
+		}
+		items = append(items, item)
+	}
+	return items, resp.TotalCount, nil
+}
+
+// SendPreview 把图文（草稿或图文素材的 media_id）发送到指定微信号（wxname）的手机上预览，
+// 接收者需已关注公众号；wxname 为空时使用 openid。
+func (p *Publisher) SendPreview(ctx context.Context, mediaID, wxname, openid string) error {
+	if wxname == "" && openid == "" {
+		return errors.New("wxname or openid is required")
+	}
+	payload := map[string]any{"mpnews": map[string]string{"media_id": mediaID}, "msgtype": "mpnews"}
+	if wxname != "" {
+		payload["towxname"] = wxname
+	} else {
+		payload["touser"] = openid
+	}
+	return p.callAPI(ctx, massPreviewURL, payload, nil)
+}
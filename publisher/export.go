@@ -0,0 +1,136 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxExportImageBytes 为导出时单张图片的下载上限。
+const maxExportImageBytes = 20 << 20
+
+// wechatHeadingSizes 为 convertHeadingsForWeChat 使用的字号与标题级别的对应关系。
+var wechatHeadingSizes = map[string]int{"24px": 1, "22px": 2, "20px": 3, "18px": 4, "16px": 5, "15px": 6}
+
+// wechatListItem 匹配列表项段落，子匹配为列表类型："-" 或 "."。
+var wechatListItem = regexp.MustCompile(`^(?:(-) |\d+(\.) )`)
+
+// ConvertWechatHTML 把公众号图文正文（RenderHTML 生成或在后台编辑的 HTML）转回 Markdown：
+// 带字号的加粗段落还原为标题，展开成段落的列表还原为列表。fetch 非空时图片下载到 dir/images，
+// 下载失败的图片保持链接并记入 Warnings。
+func ConvertWechatHTML(content, dir string, fetch func(url string) ([]byte, string, error)) (ConvertedDocument, error) {
+	c := &htmlConverter{dir: dir, images: map[string]string{}, fetch: fetch, wechat: true}
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(content), body)
+	if err != nil {
+		return ConvertedDocument{}, err
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+	var sb strings.Builder
+	if err := c.blocks(&sb, body); err != nil {
+		return c.doc, err
+	}
+	c.doc.Markdown = tightenWechatLists(strings.TrimSpace(sb.String())) + "\n"
+	return c.doc, nil
+}
+
+// wechatHeadingLevel 按 style 中的字号返回标题级别，不是加粗的标题段落时返回 0。
+func wechatHeadingLevel(style string) int {
+	style = strings.ReplaceAll(strings.ToLower(style), " ", "")
+	if !strings.Contains(style, "font-weight:700") && !strings.Contains(style, "font-weight:bold") {
+		return 0
+	}
+	for _, decl := range strings.Split(style, ";") {
+		if size, ok := strings.CutPrefix(decl, "font-size:"); ok {
+			return wechatHeadingSizes[size]
+		}
+	}
+	return 0
+}
+
+// tightenWechatLists 把 "• " 开头的段落改为无序列表项，并去掉相邻的同类列表项之间的空行。
+func tightenWechatLists(md string) string {
+	paras := strings.Split(md, "\n\n")
+	var sb strings.Builder
+	prevItem := ""
+	for i, p := range paras {
+		if rest, ok := strings.CutPrefix(p, "• "); ok {
+			p = "- " + rest
+		}
+		item := ""
+		if m := wechatListItem.FindStringSubmatch(p); m != nil && !strings.Contains(p, "\n") {
+			item = m[1] + m[2]
+		}
+		if i > 0 {
+			if item != "" && item == prevItem {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString("\n\n")
+			}
+		}
+		sb.WriteString(p)
+		prevItem = item
+	}
+	return sb.String()
+}
+
+// DownloadImage 下载图片，返回数据与扩展名：优先取公众号图片链接的 wx_fmt 参数，其次按 Content-Type；
+// client 为 nil 时使用 30 秒超时的默认客户端。
+func DownloadImage(ctx context.Context, client *http.Client, rawURL string) ([]byte, string, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxExportImageBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxExportImageBytes {
+		return nil, "", fmt.Errorf("image larger than %d MB", maxExportImageBytes>>20)
+	}
+	ext := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		switch f := strings.ToLower(u.Query().Get("wx_fmt")); f {
+		case "jpeg", "jpg":
+			ext = ".jpg"
+		case "png", "gif", "webp", "bmp":
+			ext = "." + f
+		}
+	}
+	if ext == "" {
+		ext = ".jpg"
+		if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+			switch mediaType {
+			case "image/png":
+				ext = ".png"
+			case "image/gif":
+				ext = ".gif"
+			case "image/webp":
+				ext = ".webp"
+			}
+		}
+	}
+	return data, ext, nil
+}
@@ -0,0 +1,384 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultFeedStatePath 为未配置 feed_state_path 时保存订阅源状态（已读条目与待汇总条目）的文件。
+const DefaultFeedStatePath = "feeds.json"
+
+const (
+	// maxFeedBytes 为单个订阅源读取的最大字节数。
+	maxFeedBytes = 5 << 20
+	// maxFeedSeen 为每个任务记住的已读条目数，超出时丢弃最早的。
+	maxFeedSeen = 2000
+	// maxFeedSummaryRunes 为条目摘要保留的最大字数。
+	maxFeedSummaryRunes = 300
+)
+
+// FeedConfig 为一个订阅源汇总任务：定期抓取 RSS/Atom 源，把新条目汇总成一篇文章（如每周的「业界动态」）。
+// 内嵌的周期任务字段含义相同：topic 模板另可用 {{.Count}}（本次汇总的条目数）；cron 为汇总时间，
+// 为空时每次抓取后只要新条目达到 min_items 就生成。
+type FeedConfig struct {
+	RecurringConfig
+	URLs []string `json:"urls"`
+	// PollMinutes 为抓取间隔（分钟），默认 60。
+	PollMinutes int `json:"poll_minutes,omitempty"`
+	// Keywords 非空时只汇总标题或摘要包含任一关键词（不区分大小写）的条目。
+	Keywords []string `json:"keywords,omitempty"`
+	// MinItems 为生成一篇汇总所需的最少新条目数，默认 1；MaxItems 为一篇最多汇总的条目数，默认 20，超出时取最新的，其余舍弃。
+	MinItems int `json:"min_items,omitempty"`
+	MaxItems int `json:"max_items,omitempty"`
+	// BackfillDays 为首次抓取时收入最近几天的条目，默认 0：首次抓取只记住已有条目，之后的新条目才会汇总。
+	BackfillDays int `json:"backfill_days,omitempty"`
+}
+
+// FeedItem 为订阅源中的一个条目。
+type FeedItem struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Link      string    `json:"link,omitempty"`
+	Summary   string    `json:"summary,omitempty"`
+	Published time.Time `json:"published,omitempty"`
+	// Feed 为订阅源标题（没有时为地址）。
+	Feed string `json:"feed,omitempty"`
+}
+
+// Feed 为抓取并解析后的订阅源。
+type Feed struct {
+	Title string
+	Items []FeedItem
+}
+
+// feedDoc 同时覆盖 RSS 2.0（rss/channel/item）、RSS 1.0（rdf:RDF/item）与 Atom（feed/entry）。
+type feedDoc struct {
+	XMLName xml.Name
+	Title   string         `xml:"title"`
+	Channel *feedEntryList `xml:"channel"`
+	Items   []feedEntry    `xml:"item"`
+	Entries []feedEntry    `xml:"entry"`
+}
+
+type feedEntryList struct {
+	Title string      `xml:"title"`
+	Items []feedEntry `xml:"item"`
+}
+
+type feedEntry struct {
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+		Text string `xml:",chardata"`
+	} `xml:"link"`
+	GUID        string `xml:"guid"`
+	ID          string `xml:"id"`
+	About       string `xml:"about,attr"`
+	Description string `xml:"description"`
+	Encoded     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Summary     string `xml:"summary"`
+	Content     string `xml:"content"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"date"`
+	Published   string `xml:"published"`
+	Updated     string `xml:"updated"`
+}
+
+// ParseFeed 解析 RSS 2.0、RSS 1.0 或 Atom 订阅源；条目的 ID 依次取 guid/id、链接与标题，摘要去掉 HTML 后截断。
+func ParseFeed(data []byte) (Feed, error) {
+	var doc feedDoc
+	dec := xml.NewDecoder(strings.NewReader(string(data)))
+	// 不少订阅源在正文中直接使用 &nbsp; 等 HTML 实体。
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	if err := dec.Decode(&doc); err != nil {
+		return Feed{}, fmt.Errorf("parse feed: %w", err)
+	}
+	feed := Feed{Title: strings.TrimSpace(doc.Title)}
+	entries := doc.Entries
+	switch doc.XMLName.Local {
+	case "rss":
+		if doc.Channel == nil {
+			return Feed{}, fmt.Errorf("parse feed: rss has no channel")
+		}
+		feed.Title, entries = strings.TrimSpace(doc.Channel.Title), doc.Channel.Items
+	case "RDF":
+		entries = doc.Items
+		if doc.Channel != nil {
+			feed.Title = strings.TrimSpace(doc.Channel.Title)
+		}
+	case "feed":
+	default:
+		return Feed{}, fmt.Errorf("parse feed: unknown root element <%s>, expected rss, rdf:RDF or feed", doc.XMLName.Local)
+	}
+	for _, e := range entries {
+		item := FeedItem{Title: strings.Join(strings.Fields(feedText(e.Title)), " "), Feed: feed.Title}
+		for _, l := range e.Links {
+			href := strings.TrimSpace(l.Href)
+			if href == "" {
+				href = strings.TrimSpace(l.Text)
+			}
+			if href != "" && (l.Rel == "" || l.Rel == "alternate") {
+				item.Link = href
+				break
+			}
+		}
+		item.ID = strings.TrimSpace(e.GUID)
+		for _, id := range []string{e.ID, e.About, item.Link, item.Title} {
+			if item.ID == "" {
+				item.ID = strings.TrimSpace(id)
+			}
+		}
+		for _, s := range []string{e.Summary, e.Description, e.Encoded, e.Content} {
+			if text := strings.Join(strings.Fields(feedText(s)), " "); text != "" {
+				item.Summary = truncateRunes(text, maxFeedSummaryRunes)
+				break
+			}
+		}
+		for _, d := range []string{e.Published, e.PubDate, e.Date, e.Updated} {
+			if t, ok := parseFeedTime(d); ok {
+				item.Published = t
+				break
+			}
+		}
+		if item.ID != "" {
+			feed.Items = append(feed.Items, item)
+		}
+	}
+	return feed, nil
+}
+
+// FetchFeed 抓取并解析订阅源；client 为 nil 时使用 30 秒超时的默认客户端。
+func FetchFeed(ctx context.Context, client *http.Client, url string) (Feed, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Feed{}, err
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+	req.Header.Set("User-Agent", "auto-wechat-article-publisher (feed reader)")
+	resp, err := client.Do(req)
+	if err != nil {
+		return Feed{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Feed{}, fmt.Errorf("fetch %s: HTTP %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedBytes))
+	if err != nil {
+		return Feed{}, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	feed, err := ParseFeed(data)
+	if err != nil {
+		return Feed{}, fmt.Errorf("%s: %w", url, err)
+	}
+	if feed.Title == "" {
+		for i := range feed.Items {
+			feed.Items[i].Feed = url
+		}
+	}
+	return feed, nil
+}
+
+// feedText 去掉 HTML 标签与实体，返回纯文本。
+func feedText(s string) string {
+	if !strings.ContainsAny(s, "<&") {
+		return s
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(s), nil)
+	if err != nil {
+		return s
+	}
+	var sb strings.Builder
+	for _, n := range nodes {
+		if n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style") {
+			continue
+		}
+		sb.WriteString(textContent(n) + " ")
+	}
+	return sb.String()
+}
+
+// parseFeedTime 解析 RSS（RFC 1123 等）与 Atom（RFC 3339）中的时间。
+func parseFeedTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339, time.RFC1123Z, time.RFC1123, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST", "2 Jan 2006 15:04:05 -0700", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
+
+// FeedState 为一个汇总任务的状态：抓取过的源、已读条目（用于去重）与尚未汇总的新条目。
+type FeedState struct {
+	Feeds    []string   `json:"feeds"`
+	Seen     []string   `json:"seen"`
+	Pending  []FeedItem `json:"pending"`
+	LastPoll time.Time  `json:"last_poll"`
+}
+
+// FeedStore 把各汇总任务的状态保存为单个 JSON 文件，重启后不会重复汇总已读条目。
+type FeedStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFeedStore 创建订阅源状态存储，文件不存在时视为空。
+func NewFeedStore(path string) *FeedStore {
+	if path == "" {
+		path = DefaultFeedStatePath
+	}
+	return &FeedStore{path: path}
+}
+
+// Add 记录从订阅源 feed 抓取的条目：未读且 keep 返回 true 的条目加入待汇总列表，返回新加入的条目数。
+// 任务首次抓取某个源时只把发布时间不早于 backfillSince 的条目加入待汇总（backfillSince 为零值时一条也不加入），其余只记为已读。
+func (s *FeedStore) Add(name, feed string, items []FeedItem, now, backfillSince time.Time, keep func(FeedItem) bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states, err := s.readLocked()
+	if err != nil {
+		return 0, err
+	}
+	st, ok := states[name]
+	if !ok {
+		st = &FeedState{}
+		states[name] = st
+	}
+	first := !slices.Contains(st.Feeds, feed)
+	if first {
+		st.Feeds = append(st.Feeds, feed)
+	}
+	seen := make(map[string]bool, len(st.Seen))
+	for _, id := range st.Seen {
+		seen[id] = true
+	}
+	added := 0
+	for _, item := range items {
+		if seen[item.ID] {
+			continue
+		}
+		seen[item.ID] = true
+		st.Seen = append(st.Seen, item.ID)
+		if first && (backfillSince.IsZero() || item.Published.IsZero() || item.Published.Before(backfillSince)) {
+			continue
+		}
+		if keep == nil || keep(item) {
+			st.Pending = append(st.Pending, item)
+			added++
+		}
+	}
+	if len(st.Seen) > maxFeedSeen {
+		st.Seen = st.Seen[len(st.Seen)-maxFeedSeen:]
+	}
+	st.LastPoll = now
+	return added, s.writeLocked(states)
+}
+
+// Pending 返回任务尚未汇总的条目，按发布时间从新到旧排列。
+func (s *FeedStore) Pending(name string) ([]FeedItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states, err := s.readLocked()
+	if err != nil {
+		return nil, err
+	}
+	st, ok := states[name]
+	if !ok {
+		return nil, nil
+	}
+	items := append([]FeedItem(nil), st.Pending...)
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Published.After(items[j].Published) })
+	return items, nil
+}
+
+// Remove 把已汇总（或被舍弃）的条目移出待汇总列表。
+func (s *FeedStore) Remove(name string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states, err := s.readLocked()
+	if err != nil {
+		return err
+	}
+	st, ok := states[name]
+	if !ok {
+		return nil
+	}
+	drop := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	kept := st.Pending[:0]
+	for _, item := range st.Pending {
+		if !drop[item.ID] {
+			kept = append(kept, item)
+		}
+	}
+	st.Pending = kept
+	return s.writeLocked(states)
+}
+
+func (s *FeedStore) readLocked() (map[string]*FeedState, error) {
+	states := map[string]*FeedState{}
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("feed state %s: %w", s.path, err)
+	}
+	return states, nil
+}
+
+func (s *FeedStore) writeLocked(states map[string]*FeedState) error {
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create feed state dir: %w", err)
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,527 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultFeishuBaseURL = "https://open.feishu.cn"
+
+// FeishuConfig 为导入飞书文档使用的自建应用凭证（需开通文档读取与云空间下载权限，并把文档共享给应用）；
+// 海外版 Lark 把 base_url 设为 https://open.larksuite.com。
+type FeishuConfig struct {
+	AppID     string `json:"app_id"`
+	AppSecret string `json:"app_secret"`
+	BaseURL   string `json:"base_url,omitempty"`
+}
+
+// FeishuError 为飞书开放接口返回的错误。
+type FeishuError struct {
+	Code int
+	Msg  string
+}
+
+func (e *FeishuError) Error() string {
+	return fmt.Sprintf("feishu error code=%d msg=%s", e.Code, e.Msg)
+}
+
+// FeishuClient 以 tenant_access_token 调用飞书开放接口读取云文档。
+type FeishuClient struct {
+	cfg    FeishuConfig
+	client *http.Client
+	token  string
+	expiry time.Time
+}
+
+// NewFeishuClient 创建客户端；client 为 nil 时使用 60 秒超时的默认客户端。
+func NewFeishuClient(cfg FeishuConfig, client *http.Client) (*FeishuClient, error) {
+	if cfg.AppID == "" || cfg.AppSecret == "" {
+		return nil, fmt.Errorf("feishu.app_id and feishu.app_secret are required")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultFeishuBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &FeishuClient{cfg: cfg, client: client}, nil
+}
+
+// FeishuDocument 为转换后的飞书文档。Markdown 中的图片路径相对下载目录，Images 为按出现顺序下载的图片文件。
+type FeishuDocument struct {
+	ID       string
+	Title    string
+	Markdown string
+	Images   []string
+}
+
+// ParseFeishuDocURL 从文档链接（.../docx/<id> 或 .../wiki/<token>）或直接给出的 ID 中取出 token，
+// wiki 为 true 表示是知识库节点，需先换成文档 ID。
+func ParseFeishuDocURL(ref string) (token string, wiki bool, err error) {
+	ref = strings.TrimSpace(ref)
+	if !strings.Contains(ref, "/") {
+		if ref == "" {
+			return "", false, fmt.Errorf("empty feishu document")
+		}
+		return ref, false, nil
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", false, err
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+1 < len(parts); i++ {
+		switch parts[i] {
+		case "docx":
+			return parts[i+1], false, nil
+		case "wiki":
+			return parts[i+1], true, nil
+		}
+	}
+	return "", false, fmt.Errorf("unsupported feishu document link %q: use a /docx/ or /wiki/ link", ref)
+}
+
+// FetchDocument 读取文档标题与全部块并转换为 Markdown，其中的图片下载到 dir/images。
+// ref 为文档链接或 ID，见 ParseFeishuDocURL。
+func (c *FeishuClient) FetchDocument(ctx context.Context, ref, dir string) (FeishuDocument, error) {
+	token, wiki, err := ParseFeishuDocURL(ref)
+	if err != nil {
+		return FeishuDocument{}, err
+	}
+	if wiki {
+		var node struct {
+			Node struct {
+				ObjToken string `json:"obj_token"`
+				ObjType  string `json:"obj_type"`
+			} `json:"node"`
+		}
+		if err := c.get(ctx, "/open-apis/wiki/v2/spaces/get_node?token="+url.QueryEscape(token), &node); err != nil {
+			return FeishuDocument{}, err
+		}
+		if node.Node.ObjType != "docx" {
+			return FeishuDocument{}, fmt.Errorf("wiki node %s is a %s, only docx documents can be imported", token, node.Node.ObjType)
+		}
+		token = node.Node.ObjToken
+	}
+
+	doc := FeishuDocument{ID: token}
+	var meta struct {
+		Document struct {
+			Title string `json:"title"`
+		} `json:"document"`
+	}
+	if err := c.get(ctx, "/open-apis/docx/v1/documents/"+url.PathEscape(token), &meta); err != nil {
+		return doc, err
+	}
+	doc.Title = meta.Document.Title
+
+	blocks := map[string]*feishuBlock{}
+	pageToken := ""
+	for {
+		var page struct {
+			Items     []*feishuBlock `json:"items"`
+			PageToken string         `json:"page_token"`
+			HasMore   bool           `json:"has_more"`
+		}
+		path := "/open-apis/docx/v1/documents/" + url.PathEscape(token) + "/blocks?page_size=500&document_revision_id=-1"
+		if pageToken != "" {
+			path += "&page_token=" + url.QueryEscape(pageToken)
+		}
+		if err := c.get(ctx, path, &page); err != nil {
+			return doc, err
+		}
+		for _, b := range page.Items {
+			blocks[b.BlockID] = b
+		}
+		if !page.HasMore || page.PageToken == "" {
+			break
+		}
+		pageToken = page.PageToken
+	}
+	root, ok := blocks[token]
+	if !ok {
+		return doc, fmt.Errorf("feishu document %s has no page block", token)
+	}
+
+	imageDir := filepath.Join(dir, "images")
+	r := &feishuRenderer{blocks: blocks, image: func(fileToken string) (string, error) {
+		if err := os.MkdirAll(imageDir, 0o755); err != nil {
+			return "", err
+		}
+		path, err := c.downloadMedia(ctx, fileToken, imageDir)
+		if err != nil {
+			return "", err
+		}
+		doc.Images = append(doc.Images, path)
+		return "images/" + filepath.Base(path), nil
+	}}
+	var sb strings.Builder
+	if err := r.children(&sb, root, ""); err != nil {
+		return doc, err
+	}
+	doc.Markdown = strings.TrimSpace(sb.String()) + "\n"
+	return doc, nil
+}
+
+// tenantToken 返回缓存的 tenant_access_token，过期前 5 分钟重新获取。
+func (c *FeishuClient) tenantToken(ctx context.Context) (string, error) {
+	if c.token != "" && time.Now().Before(c.expiry) {
+		return c.token, nil
+	}
+	body, _ := json.Marshal(map[string]string{"app_id": c.cfg.AppID, "app_secret": c.cfg.AppSecret})
+	req, err := http.NewRequestWithContext(ctx, "POST", c.cfg.BaseURL+"/open-apis/auth/v3/tenant_access_token/internal", strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Code   int    `json:"code"`
+		Msg    string `json:"msg"`
+		Token  string `json:"tenant_access_token"`
+		Expire int    `json:"expire"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("feishu tenant_access_token: HTTP %d: %w", resp.StatusCode, err)
+	}
+	if out.Code != 0 {
+		return "", &FeishuError{Code: out.Code, Msg: out.Msg}
+	}
+	c.token, c.expiry = out.Token, time.Now().Add(time.Duration(out.Expire)*time.Second-5*time.Minute)
+	return c.token, nil
+}
+
+func (c *FeishuClient) request(ctx context.Context, path string) (*http.Response, error) {
+	token, err := c.tenantToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.cfg.BaseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return c.client.Do(req)
+}
+
+// get 调用返回 {code, msg, data} 的接口，把 data 解码到 out。
+func (c *FeishuClient) get(ctx context.Context, path string, out any) error {
+	resp, err := c.request(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var env struct {
+		Code int             `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return fmt.Errorf("feishu %s: HTTP %d: %w", strings.SplitN(path, "?", 2)[0], resp.StatusCode, err)
+	}
+	if env.Code != 0 {
+		return &FeishuError{Code: env.Code, Msg: env.Msg}
+	}
+	return json.Unmarshal(env.Data, out)
+}
+
+// downloadMedia 下载图片素材到 dir，文件名为素材 token，扩展名按 Content-Type 确定。
+func (c *FeishuClient) downloadMedia(ctx context.Context, fileToken, dir string) (string, error) {
+	resp, err := c.request(ctx, "/open-apis/drive/v1/medias/"+url.PathEscape(fileToken)+"/download")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var env struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&env) == nil && env.Code != 0 {
+			return "", fmt.Errorf("download image %s: %w", fileToken, &FeishuError{Code: env.Code, Msg: env.Msg})
+		}
+		return "", fmt.Errorf("download image %s: HTTP %d", fileToken, resp.StatusCode)
+	}
+	ext := ".png"
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		switch mediaType {
+		case "image/jpeg":
+			ext = ".jpg"
+		case "image/gif":
+			ext = ".gif"
+		}
+	}
+	path := filepath.Join(dir, fileToken+ext)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return "", fmt.Errorf("download image %s: %w", fileToken, err)
+	}
+	return path, f.Close()
+}
+
+// feishuBlock 为 docx 文档的一个块，文本类块的内容在与类型同名的字段中（text、heading1、bullet 等）。
+type feishuBlock struct {
+	BlockID   string   `json:"block_id"`
+	BlockType int      `json:"block_type"`
+	Children  []string `json:"children"`
+
+	Text     *feishuText `json:"text"`
+	Heading1 *feishuText `json:"heading1"`
+	Heading2 *feishuText `json:"heading2"`
+	Heading3 *feishuText `json:"heading3"`
+	Heading4 *feishuText `json:"heading4"`
+	Heading5 *feishuText `json:"heading5"`
+	Heading6 *feishuText `json:"heading6"`
+	Heading7 *feishuText `json:"heading7"`
+	Heading8 *feishuText `json:"heading8"`
+	Heading9 *feishuText `json:"heading9"`
+	Bullet   *feishuText `json:"bullet"`
+	Ordered  *feishuText `json:"ordered"`
+	Code     *feishuText `json:"code"`
+	Quote    *feishuText `json:"quote"`
+	Todo     *feishuText `json:"todo"`
+	Image    *struct {
+		Token string `json:"token"`
+	} `json:"image"`
+	Table *struct {
+		Cells    []string `json:"cells"`
+		Property struct {
+			ColumnSize int `json:"column_size"`
+		} `json:"property"`
+	} `json:"table"`
+}
+
+type feishuText struct {
+	Elements []struct {
+		TextRun *struct {
+			Content string `json:"content"`
+			Style   struct {
+				Bold          bool `json:"bold"`
+				Italic        bool `json:"italic"`
+				Strikethrough bool `json:"strikethrough"`
+				InlineCode    bool `json:"inline_code"`
+				Link          *struct {
+					URL string `json:"url"`
+				} `json:"link"`
+			} `json:"text_element_style"`
+		} `json:"text_run"`
+		MentionDoc *struct {
+			Title string `json:"title"`
+			URL   string `json:"url"`
+		} `json:"mention_doc"`
+		Equation *struct {
+			Content string `json:"content"`
+		} `json:"equation"`
+	} `json:"elements"`
+	Style struct {
+		Language int  `json:"language"`
+		Done     bool `json:"done"`
+	} `json:"style"`
+}
+
+// 飞书 docx 的块类型。
+const (
+	feishuBlockText      = 2
+	feishuBlockHeading1  = 3
+	feishuBlockHeading9  = 11
+	feishuBlockBullet    = 12
+	feishuBlockOrdered   = 13
+	feishuBlockCode      = 14
+	feishuBlockQuote     = 15
+	feishuBlockTodo      = 17
+	feishuBlockCallout   = 19
+	feishuBlockDivider   = 22
+	feishuBlockImage     = 27
+	feishuBlockTable     = 31
+	feishuBlockTableCell = 32
+	feishuBlockQuoteBox  = 34
+)
+
+// feishuCodeLanguages 为代码块常用语言的编号。
+var feishuCodeLanguages = map[int]string{
+	7: "bash", 9: "cpp", 10: "c", 12: "css", 18: "dockerfile", 22: "go", 24: "html", 28: "json", 29: "java",
+	30: "javascript", 32: "kotlin", 39: "markdown", 43: "php", 49: "python", 52: "ruby", 53: "rust",
+	56: "sql", 60: "shell", 61: "swift", 63: "typescript", 66: "xml", 67: "yaml",
+}
+
+type feishuRenderer struct {
+	blocks map[string]*feishuBlock
+	image  func(token string) (string, error)
+}
+
+// children 依次输出 b 的子块，indent 为列表嵌套的缩进。
+func (r *feishuRenderer) children(sb *strings.Builder, b *feishuBlock, indent string) error {
+	for _, id := range b.Children {
+		child, ok := r.blocks[id]
+		if !ok {
+			continue
+		}
+		if err := r.block(sb, child, indent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *feishuRenderer) block(sb *strings.Builder, b *feishuBlock, indent string) error {
+	switch t := b.BlockType; {
+	case t == feishuBlockText && b.Text != nil:
+		fmt.Fprintf(sb, "%s%s\n\n", indent, b.Text.markdown())
+	case t >= feishuBlockHeading1 && t <= feishuBlockHeading9:
+		level := t - feishuBlockHeading1 + 1
+		text := []*feishuText{b.Heading1, b.Heading2, b.Heading3, b.Heading4, b.Heading5, b.Heading6, b.Heading7, b.Heading8, b.Heading9}[level-1]
+		if text != nil {
+			fmt.Fprintf(sb, "%s %s\n\n", strings.Repeat("#", min(level, 6)), text.markdown())
+		}
+	case t == feishuBlockBullet || t == feishuBlockOrdered || t == feishuBlockTodo:
+		marker, text := "- ", b.Bullet
+		switch t {
+		case feishuBlockOrdered:
+			marker, text = "1. ", b.Ordered
+		case feishuBlockTodo:
+			marker, text = "- [ ] ", b.Todo
+			if text != nil && text.Style.Done {
+				marker = "- [x] "
+			}
+		}
+		if text != nil {
+			fmt.Fprintf(sb, "%s%s%s\n", indent, marker, text.markdown())
+		}
+		if err := r.children(sb, b, indent+strings.Repeat(" ", len(marker))); err != nil {
+			return err
+		}
+		if indent == "" {
+			sb.WriteString("\n")
+		}
+		return nil
+	case t == feishuBlockCode && b.Code != nil:
+		var code strings.Builder
+		for _, e := range b.Code.Elements {
+			if e.TextRun != nil {
+				code.WriteString(e.TextRun.Content)
+			}
+		}
+		fmt.Fprintf(sb, "```%s\n%s\n```\n\n", feishuCodeLanguages[b.Code.Style.Language], strings.TrimRight(code.String(), "\n"))
+	case t == feishuBlockQuote && b.Quote != nil:
+		fmt.Fprintf(sb, "> %s\n\n", b.Quote.markdown())
+	case t == feishuBlockDivider:
+		sb.WriteString("---\n\n")
+	case t == feishuBlockImage && b.Image != nil:
+		path, err := r.image(b.Image.Token)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(sb, "%s![](%s)\n\n", indent, path)
+	case t == feishuBlockTable && b.Table != nil:
+		return r.table(sb, b)
+	default:
+		// 引用容器、高亮块等容器块：有子块时输出子块，引用容器与高亮块加上引用前缀。
+		if len(b.Children) == 0 {
+			return nil
+		}
+		var inner strings.Builder
+		if err := r.children(&inner, b, indent); err != nil {
+			return err
+		}
+		if t == feishuBlockCallout || t == feishuBlockQuoteBox {
+			lines := strings.Split(strings.TrimRight(inner.String(), "\n"), "\n")
+			for i, l := range lines {
+				lines[i] = strings.TrimRight("> "+l, " ")
+			}
+			sb.WriteString(strings.Join(lines, "\n") + "\n\n")
+			return nil
+		}
+		sb.WriteString(inner.String())
+	}
+	return nil
+}
+
+// table 把表格输出为 Markdown 表格，单元格内的多个块以空格连接。
+func (r *feishuRenderer) table(sb *strings.Builder, b *feishuBlock) error {
+	cols := b.Table.Property.ColumnSize
+	if cols <= 0 {
+		return nil
+	}
+	for i := 0; i < len(b.Table.Cells); i += cols {
+		row := b.Table.Cells[i:min(i+cols, len(b.Table.Cells))]
+		cells := make([]string, len(row))
+		for j, id := range row {
+			cell, ok := r.blocks[id]
+			if !ok {
+				continue
+			}
+			var inner strings.Builder
+			if err := r.children(&inner, cell, ""); err != nil {
+				return err
+			}
+			cells[j] = strings.ReplaceAll(strings.Join(strings.Fields(inner.String()), " "), "|", "\\|")
+		}
+		fmt.Fprintf(sb, "| %s |\n", strings.Join(cells, " | "))
+		if i == 0 {
+			fmt.Fprintf(sb, "|%s\n", strings.Repeat(" --- |", cols))
+		}
+	}
+	sb.WriteString("\n")
+	return nil
+}
+
+// markdown 把文本元素转为 Markdown：加粗、斜体、删除线、行内代码与链接，@文档转为链接，公式按行内代码输出。
+func (t *feishuText) markdown() string {
+	var sb strings.Builder
+	for _, e := range t.Elements {
+		switch {
+		case e.TextRun != nil:
+			s := e.TextRun.Content
+			st := e.TextRun.Style
+			if strings.TrimSpace(s) == "" {
+				sb.WriteString(s)
+				continue
+			}
+			switch {
+			case st.InlineCode:
+				s = "`" + s + "`"
+			default:
+				if st.Bold {
+					s = "**" + s + "**"
+				}
+				if st.Italic {
+					s = "*" + s + "*"
+				}
+				if st.Strikethrough {
+					s = "~~" + s + "~~"
+				}
+			}
+			if st.Link != nil && st.Link.URL != "" {
+				link, err := url.QueryUnescape(st.Link.URL)
+				if err != nil {
+					link = st.Link.URL
+				}
+				s = "[" + s + "](" + link + ")"
+			}
+			sb.WriteString(s)
+		case e.MentionDoc != nil:
+			fmt.Fprintf(&sb, "[%s](%s)", e.MentionDoc.Title, e.MentionDoc.URL)
+		case e.Equation != nil:
+			sb.WriteString("`" + strings.TrimSpace(e.Equation.Content) + "`")
+		}
+	}
+	return sb.String()
+}
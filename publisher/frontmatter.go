@@ -0,0 +1,60 @@
+package publisher
+
+import (
+	"bytes"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FrontMatter 为 Markdown 文件开头 "---" 包裹的 YAML 头中与发布相关的字段。
+// Cover 为相对 Markdown 文件的路径；Group 相同的文章在批量发布时放入同一条草稿，Order 决定先后。
+type FrontMatter struct {
+	Title  string `yaml:"title"`
+	Cover  string `yaml:"cover"`
+	Author string `yaml:"author"`
+	Digest string `yaml:"digest"`
+	Group  string `yaml:"group"`
+	Order  int    `yaml:"order"`
+}
+
+// ParseFrontMatter 拆分 YAML 头与正文；没有头或头不是合法 YAML 时全部视为正文。
+func ParseFrontMatter(data []byte) (FrontMatter, []byte) {
+	var fm FrontMatter
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !bytes.HasPrefix(data, []byte("---\n")) && !bytes.HasPrefix(data, []byte("---\r\n")) {
+		return fm, data
+	}
+	rest := data[3:]
+	end := bytes.Index(rest, []byte("\n---"))
+	if end < 0 {
+		return fm, data
+	}
+	if err := yaml.Unmarshal(rest[:end], &fm); err != nil {
+		return FrontMatter{}, data
+	}
+	body := rest[end+4:]
+	if i := bytes.IndexByte(body, '\n'); i >= 0 {
+		body = body[i+1:]
+	} else {
+		body = nil
+	}
+	return fm, body
+}
+
+// StripLeadingH1 removes the first top-level markdown heading (and a following blank line if present),
+// so that the content body doesn't repeat the title that will be provided separately to WeChat.
+func StripLeadingH1(md string) string {
+	lines := strings.Split(md, "\n")
+	if len(lines) == 0 {
+		return md
+	}
+	start := 0
+	if strings.HasPrefix(strings.TrimSpace(lines[0]), "# ") {
+		start = 1
+		if len(lines) > 1 && strings.TrimSpace(lines[1]) == "" {
+			start = 2
+		}
+	}
+	return strings.Join(lines[start:], "\n")
+}
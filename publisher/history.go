@@ -0,0 +1,157 @@
+package publisher
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPublishHistoryPath 为未配置 publish_history_path 时的发布记录文件。
+const DefaultPublishHistoryPath = "publishes.jsonl"
+
+// 发布记录状态。
+const (
+	PublishSucceeded = "success"
+	PublishFailed    = "failed"
+)
+
+// PublishRecord 为一次发布尝试的记录；User 为发布者，未启用登录时为空；
+// ErrCode 为失败时微信接口返回的 errcode，其他原因的失败为 0。
+type PublishRecord struct {
+	ID string `json:"id"`
+	// SessionID 为稿件所属 session，命令行发布时为空。
+	SessionID string    `json:"session_id,omitempty"`
+	User      string    `json:"user,omitempty"`
+	Title     string    `json:"title"`
+	MediaID   string    `json:"media_id,omitempty"`
+	CoverPath string    `json:"cover_path,omitempty"`
+	Account   string    `json:"account"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	ErrCode   int       `json:"errcode,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Updated 为 true 表示这次发布更新了已有草稿（MediaID）而非新建。
+	Updated bool `json:"updated,omitempty"`
+}
+
+// PublishFilter 为查询发布记录的条件，零值字段不参与过滤。
+type PublishFilter struct {
+	SessionID string
+	MediaID   string
+	User      string
+	Status    string
+	Account   string
+	// Query 按标题搜索（忽略大小写）。
+	Query string
+	Since time.Time
+	Until time.Time
+	// Limit 为最多返回的条数，0 表示不限。
+	Limit int
+}
+
+func (f PublishFilter) match(rec PublishRecord) bool {
+	switch {
+	case f.SessionID != "" && rec.SessionID != f.SessionID,
+		f.MediaID != "" && rec.MediaID != f.MediaID,
+		f.User != "" && rec.User != f.User,
+		f.Status != "" && rec.Status != f.Status,
+		f.Account != "" && rec.Account != f.Account,
+		f.Query != "" && !strings.Contains(strings.ToLower(rec.Title), strings.ToLower(f.Query)),
+		!f.Since.IsZero() && rec.CreatedAt.Before(f.Since),
+		!f.Until.IsZero() && !rec.CreatedAt.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// PublishHistory 以 JSON Lines 追加保存发布记录，每行一条。
+type PublishHistory struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewPublishHistory 创建发布记录存储，文件不存在时视为没有记录。
+func NewPublishHistory(path string) *PublishHistory {
+	if path == "" {
+		path = DefaultPublishHistoryPath
+	}
+	return &PublishHistory{path: path}
+}
+
+// Append 写入一条记录，未设置的 ID 与时间自动补全。
+func (h *PublishHistory) Append(rec PublishRecord) (PublishRecord, error) {
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now()
+	}
+	if rec.ID == "" {
+		rec.ID = strconv.FormatInt(rec.CreatedAt.UnixNano(), 36)
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return rec, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if dir := filepath.Dir(h.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return rec, fmt.Errorf("create publish history dir: %w", err)
+		}
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return rec, err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return rec, err
+}
+
+// List 按时间倒序返回符合条件的记录。
+func (h *PublishHistory) List(filter PublishFilter) ([]PublishRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var all []PublishRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var rec PublishRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("publish history %s line %d: %w", h.path, line, err)
+		}
+		if filter.match(rec) {
+			all = append(all, rec)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	// 文件按写入顺序追加，倒序即为最新在前。
+	out := make([]PublishRecord, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		out = append(out, all[i])
+		if filter.Limit > 0 && len(out) == filter.Limit {
+			break
+		}
+	}
+	return out, nil
+}
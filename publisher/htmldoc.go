@@ -0,0 +1,502 @@
+package publisher
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlConverter 保存一次 HTML 转换的上下文。
+type htmlConverter struct {
+	base   string // HTML 文件所在目录，用于查找相对路径的图片
+	dir    string
+	images map[string]string // src -> Markdown 引用
+	doc    ConvertedDocument
+	// fetch 非空时下载 http(s) 图片保存到 dir/images，返回图片数据与扩展名；为空时保持链接。
+	fetch func(url string) ([]byte, string, error)
+	// wechat 为 true 时把 RenderHTML 生成的带字号加粗段落还原为标题。
+	wechat bool
+}
+
+// ConvertHTML 把 HTML 文件转为 Markdown：标题、段落、列表、引用、代码块、表格、链接与强调保留，
+// 脚本、样式与导航等忽略。data URI 与相对路径的图片提取到 dir/images，http(s) 图片保持链接。
+// 标题取 <title>，没有时由调用方按第一个一级标题处理。
+func ConvertHTML(file, dir string) (ConvertedDocument, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return ConvertedDocument{}, err
+	}
+	defer f.Close()
+	root, err := html.Parse(f)
+	if err != nil {
+		return ConvertedDocument{}, fmt.Errorf("parse %s: %w", file, err)
+	}
+	c := &htmlConverter{base: filepath.Dir(file), dir: dir, images: map[string]string{}}
+	body := root
+	var find func(n *html.Node)
+	find = func(n *html.Node) {
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			if ch.Type != html.ElementNode {
+				continue
+			}
+			switch ch.DataAtom {
+			case atom.Title:
+				if c.doc.Title == "" {
+					c.doc.Title = strings.Join(strings.Fields(textContent(ch)), " ")
+				}
+			case atom.Body:
+				body = ch
+			default:
+				find(ch)
+			}
+		}
+	}
+	find(root)
+
+	var sb strings.Builder
+	if err := c.blocks(&sb, body); err != nil {
+		return c.doc, err
+	}
+	c.doc.Markdown = strings.TrimSpace(sb.String()) + "\n"
+	return c.doc, nil
+}
+
+// htmlSkip 为不输出内容的元素。
+var htmlSkip = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Nav: true, atom.Iframe: true, atom.Form: true, atom.Button: true, atom.Svg: true,
+}
+
+// htmlBlock 为按块处理的元素，其余元素按行内内容处理。
+var htmlBlock = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true, atom.Header: true,
+	atom.Footer: true, atom.Aside: true, atom.Figure: true, atom.Figcaption: true, atom.Blockquote: true,
+	atom.Pre: true, atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Table: true, atom.Hr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Dl: true, atom.Dt: true, atom.Dd: true, atom.Center: true, atom.Details: true, atom.Summary: true,
+}
+
+// blocks 转换 n 的子节点：连续的文本与行内元素合为一段，块级元素各自转换。
+func (c *htmlConverter) blocks(sb *strings.Builder, n *html.Node) error {
+	var para strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(para.String()); text != "" {
+			sb.WriteString(text + "\n\n")
+		}
+		para.Reset()
+	}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if ch.Type == html.ElementNode && htmlSkip[ch.DataAtom] {
+			continue
+		}
+		if ch.Type != html.ElementNode || !htmlBlock[ch.DataAtom] {
+			text, err := c.inline(ch)
+			if err != nil {
+				return err
+			}
+			para.WriteString(text)
+			continue
+		}
+		flush()
+		if err := c.block(sb, ch); err != nil {
+			return err
+		}
+	}
+	flush()
+	return nil
+}
+
+func (c *htmlConverter) block(sb *strings.Builder, n *html.Node) error {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text, err := c.inlineChildren(n)
+		if err != nil {
+			return err
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			fmt.Fprintf(sb, "%s %s\n\n", strings.Repeat("#", int(n.Data[1]-'0')), text)
+		}
+	case atom.Hr:
+		sb.WriteString("---\n\n")
+	case atom.Pre:
+		lang := ""
+		code := n
+		if ch := n.FirstChild; ch != nil && ch.DataAtom == atom.Code && ch.NextSibling == nil {
+			code = ch
+		}
+		for _, node := range []*html.Node{code, n} {
+			for _, class := range strings.Fields(htmlAttr(node, "class")) {
+				if l, ok := strings.CutPrefix(class, "language-"); ok && lang == "" {
+					lang = l
+				} else if l, ok := strings.CutPrefix(class, "lang-"); ok && lang == "" {
+					lang = l
+				}
+			}
+		}
+		fmt.Fprintf(sb, "```%s\n%s\n```\n\n", lang, strings.Trim(textContent(code), "\n"))
+	case atom.Blockquote:
+		var inner strings.Builder
+		if err := c.blocks(&inner, n); err != nil {
+			return err
+		}
+		lines := strings.Split(strings.TrimRight(inner.String(), "\n"), "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimRight("> "+l, " ")
+		}
+		sb.WriteString(strings.Join(lines, "\n") + "\n\n")
+	case atom.Ul, atom.Ol:
+		if err := c.list(sb, n, ""); err != nil {
+			return err
+		}
+		sb.WriteString("\n")
+	case atom.Table:
+		return c.table(sb, n)
+	case atom.P:
+		level := 0
+		if c.wechat {
+			level = wechatHeadingLevel(htmlAttr(n, "style"))
+		}
+		if level == 0 {
+			return c.blocks(sb, n)
+		}
+		text, err := c.inlineChildren(n)
+		if err != nil {
+			return err
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			fmt.Fprintf(sb, "%s %s\n\n", strings.Repeat("#", level), text)
+		}
+	default:
+		// div、section、li 外的 li 等容器：转换其中的内容。
+		return c.blocks(sb, n)
+	}
+	return nil
+}
+
+// list 输出列表，嵌套列表按 indent 缩进。
+func (c *htmlConverter) list(sb *strings.Builder, n *html.Node, indent string) error {
+	num := 1
+	if s, err := strconv.Atoi(htmlAttr(n, "start")); err == nil {
+		num = s
+	}
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", num)
+			num++
+		}
+		// 列表项中的嵌套列表单独处理，其余内容合为一段。
+		var text strings.Builder
+		var nested []*html.Node
+		for ch := li.FirstChild; ch != nil; ch = ch.NextSibling {
+			if ch.DataAtom == atom.Ul || ch.DataAtom == atom.Ol {
+				nested = append(nested, ch)
+				continue
+			}
+			if ch.Type == html.ElementNode && htmlSkip[ch.DataAtom] {
+				continue
+			}
+			var t string
+			var err error
+			if ch.Type == html.ElementNode && htmlBlock[ch.DataAtom] {
+				var inner strings.Builder
+				err = c.block(&inner, ch)
+				t = " " + strings.Join(strings.Fields(inner.String()), " ") + " "
+			} else {
+				t, err = c.inline(ch)
+			}
+			if err != nil {
+				return err
+			}
+			text.WriteString(t)
+		}
+		fmt.Fprintf(sb, "%s%s%s\n", indent, marker, strings.TrimSpace(text.String()))
+		for _, l := range nested {
+			if err := c.list(sb, l, indent+strings.Repeat(" ", len(marker))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// table 把表格输出为 Markdown 表格，第一行为表头。
+func (c *htmlConverter) table(sb *strings.Builder, n *html.Node) error {
+	var rows [][]string
+	cols := 0
+	var walk func(n *html.Node) error
+	walk = func(n *html.Node) error {
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			switch ch.DataAtom {
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				if err := walk(ch); err != nil {
+					return err
+				}
+			case atom.Tr:
+				var row []string
+				for cell := ch.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.DataAtom != atom.Td && cell.DataAtom != atom.Th {
+						continue
+					}
+					text, err := c.inlineChildren(cell)
+					if err != nil {
+						return err
+					}
+					row = append(row, strings.ReplaceAll(strings.Join(strings.Fields(text), " "), "|", "\\|"))
+				}
+				cols = max(cols, len(row))
+				rows = append(rows, row)
+			}
+		}
+		return nil
+	}
+	if err := walk(n); err != nil {
+		return err
+	}
+	if cols == 0 {
+		return nil
+	}
+	for i, row := range rows {
+		for len(row) < cols {
+			row = append(row, "")
+		}
+		fmt.Fprintf(sb, "| %s |\n", strings.Join(row, " | "))
+		if i == 0 {
+			fmt.Fprintf(sb, "|%s\n", strings.Repeat(" --- |", cols))
+		}
+	}
+	sb.WriteString("\n")
+	return nil
+}
+
+func (c *htmlConverter) inlineChildren(n *html.Node) (string, error) {
+	var sb strings.Builder
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		text, err := c.inline(ch)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(text)
+	}
+	return sb.String(), nil
+}
+
+// inline 转换行内内容，连续空白合并为一个空格。
+func (c *htmlConverter) inline(n *html.Node) (string, error) {
+	switch n.Type {
+	case html.TextNode:
+		text := strings.Join(strings.Fields(n.Data), " ")
+		if text == "" {
+			if n.Data != "" {
+				return " ", nil
+			}
+			return "", nil
+		}
+		if strings.TrimLeft(n.Data, " \t\r\n") != n.Data {
+			text = " " + text
+		}
+		if strings.TrimRight(n.Data, " \t\r\n") != n.Data {
+			text += " "
+		}
+		return text, nil
+	case html.ElementNode:
+	default:
+		return "", nil
+	}
+	if htmlSkip[n.DataAtom] {
+		return "", nil
+	}
+	switch n.DataAtom {
+	case atom.Br:
+		return "  \n", nil
+	case atom.Img:
+		return c.image(n)
+	case atom.Code, atom.Kbd, atom.Samp:
+		if text := textContent(n); strings.TrimSpace(text) != "" {
+			return "`" + text + "`", nil
+		}
+		return "", nil
+	}
+	text, err := c.inlineChildren(n)
+	if err != nil {
+		return "", err
+	}
+	switch n.DataAtom {
+	case atom.Strong, atom.B:
+		return markdownRun(text, true, false, false, false, ""), nil
+	case atom.Em, atom.I:
+		return markdownRun(text, false, true, false, false, ""), nil
+	case atom.Del, atom.S, atom.Strike:
+		return markdownRun(text, false, false, true, false, ""), nil
+	case atom.A:
+		if href := htmlAttr(n, "href"); href != "" && !strings.HasPrefix(href, "#") && !strings.HasPrefix(href, "javascript:") {
+			return markdownRun(text, false, false, false, false, href), nil
+		}
+	default:
+		if htmlBlock[n.DataAtom] {
+			// 出现在行内位置的块级元素（如 a 中的 div）按空格分隔。
+			return " " + strings.TrimSpace(text) + " ", nil
+		}
+	}
+	return text, nil
+}
+
+// image 返回图片的 Markdown 语法：data URI 解码保存，相对路径复制到 dir/images，
+// http(s) 链接在设置了 fetch 时下载保存，否则原样保留。
+func (c *htmlConverter) image(n *html.Node) (string, error) {
+	src := strings.TrimSpace(htmlAttr(n, "src"))
+	if src == "" {
+		src = strings.TrimSpace(htmlAttr(n, "data-src"))
+	}
+	if src == "" {
+		return "", nil
+	}
+	alt := strings.Join(strings.Fields(htmlAttr(n, "alt")), " ")
+	ref, ok := c.images[src]
+	switch {
+	case ok:
+	case strings.HasPrefix(src, "http://"), strings.HasPrefix(src, "https://"), strings.HasPrefix(src, "//"):
+		ref = src
+		if strings.HasPrefix(src, "//") {
+			ref = "https:" + src
+		}
+		if c.fetch == nil {
+			break
+		}
+		data, ext, err := c.fetch(ref)
+		if err != nil {
+			// 下载失败不影响其余内容，保持链接并记录。
+			c.doc.Warnings = append(c.doc.Warnings, fmt.Sprintf("image %s: %v", ref, err))
+			break
+		}
+		if ref, err = c.saveImage(fmt.Sprintf("image%d%s", len(c.doc.Images)+1, ext), data); err != nil {
+			return "", err
+		}
+	case strings.HasPrefix(src, "data:"):
+		meta, data, found := strings.Cut(src[len("data:"):], ",")
+		if !found || !strings.HasSuffix(meta, ";base64") {
+			return "", fmt.Errorf("image data URI must be base64 encoded")
+		}
+		raw, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(data), ""))
+		if err != nil {
+			return "", fmt.Errorf("image data URI: %w", err)
+		}
+		ext := ".png"
+		if exts, _ := mime.ExtensionsByType(strings.TrimSuffix(meta, ";base64")); len(exts) > 0 {
+			ext = exts[0]
+			if ext == ".jpe" || ext == ".jfif" {
+				ext = ".jpg"
+			}
+		}
+		if ref, err = c.saveImage(fmt.Sprintf("image%d%s", len(c.doc.Images)+1, ext), raw); err != nil {
+			return "", err
+		}
+	default:
+		var err error
+		if ref, err = c.copyImage(src); err != nil {
+			return "", err
+		}
+	}
+	c.images[src] = ref
+	return "![" + alt + "](" + ref + ")", nil
+}
+
+// copyImage 把相对 HTML 文件（或绝对路径）的本地图片复制到 dir/images，返回 Markdown 引用。
+func (c *htmlConverter) copyImage(src string) (string, error) {
+	u, err := url.Parse(src)
+	if err != nil || u.Scheme != "" {
+		return "", fmt.Errorf("image %s: unsupported image source", src)
+	}
+	local := filepath.FromSlash(u.Path)
+	if !filepath.IsAbs(local) {
+		local = filepath.Join(c.base, local)
+	}
+	in, err := os.Open(local)
+	if err != nil {
+		return "", fmt.Errorf("image %s: %w", src, err)
+	}
+	defer in.Close()
+	dst, err := createImage(c.dir, filepath.Base(local))
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(dst, in)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	return c.addImage(dst.Name()), nil
+}
+
+// saveImage 把图片数据保存到 dir/images，返回 Markdown 引用。
+func (c *htmlConverter) saveImage(name string, data []byte) (string, error) {
+	dst, err := createImage(c.dir, name)
+	if err != nil {
+		return "", err
+	}
+	_, err = dst.Write(data)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	return c.addImage(dst.Name()), nil
+}
+
+func (c *htmlConverter) addImage(path string) string {
+	c.doc.Images = append(c.doc.Images, path)
+	return "images/" + filepath.Base(path)
+}
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// textContent 返回节点内的全部文本，不做空白处理。
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		sb.WriteString(textContent(ch))
+	}
+	return sb.String()
+}
+
+// fragment 转换一段 HTML 片段（如 notebook 输出中的表格），图片按本次转换的规则提取。
+func (c *htmlConverter) fragment(s string) (string, error) {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(s), body)
+	if err != nil {
+		return "", err
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+	var sb strings.Builder
+	if err := c.blocks(&sb, body); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
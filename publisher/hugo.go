@@ -0,0 +1,391 @@
+package publisher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HugoPost 为 Hugo 站点 content 目录中的一篇文章，Markdown 已转换为可直接发布的正文。
+type HugoPost struct {
+	Path  string
+	Title string
+	// Date 取 publishDate，没有时取 date；都未设置时为零值。
+	Date  time.Time
+	Draft bool
+	// Cover 为封面图片的本地路径；未设置、是远程地址或文件不存在时为空。
+	Cover  string
+	Author string
+	Digest string
+	// Markdown 为去掉 front matter、转换短代码并改写图片路径后的正文。
+	Markdown string
+}
+
+// ListHugoContent 递归列出 contentDir 下的文章文件，按路径排序：跳过以 . 或 _ 开头的目录与
+// _index.md 等列表页；page bundle（含 index.md 的目录）只取 index.md，其余文件是它的资源。
+func ListHugoContent(contentDir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(contentDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != contentDir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			for _, index := range []string{"index.md", "index.markdown"} {
+				if info, err := os.Stat(filepath.Join(path, index)); err == nil && !info.IsDir() {
+					paths = append(paths, filepath.Join(path, index))
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if ext := strings.ToLower(filepath.Ext(name)); (ext == ".md" || ext == ".markdown") && !strings.HasPrefix(name, "_") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// ReadHugoPost 读取一篇 Hugo 文章。front matter 可以是 YAML（---）、TOML（+++）或 JSON；
+// 以 / 开头的图片路径按 staticDir 解析，其余相对文章所在目录（page bundle）。
+func ReadHugoPost(path, staticDir string) (HugoPost, error) {
+	post := HugoPost{Path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return post, err
+	}
+	meta, body, err := splitFrontMatterMap(data)
+	if err != nil {
+		return post, fmt.Errorf("front matter: %w", err)
+	}
+	post.Title = strings.TrimSpace(metaString(meta, "title"))
+	for _, key := range []string{"publishDate", "pubdate", "published", "date"} {
+		if s := metaString(meta, key); s != "" {
+			if post.Date, err = parseHugoDate(s); err != nil {
+				return post, fmt.Errorf("front matter %s: %w", key, err)
+			}
+			break
+		}
+	}
+	switch v := meta["draft"].(type) {
+	case bool:
+		post.Draft = v
+	case string:
+		post.Draft = v == "true"
+	}
+	post.Author = metaString(meta, "author")
+	if post.Author == "" {
+		if authors, ok := meta["authors"].([]any); ok && len(authors) > 0 {
+			post.Author, _ = authors[0].(string)
+		}
+	}
+	post.Digest = metaString(meta, "description")
+	if post.Digest == "" {
+		post.Digest = metaString(meta, "summary")
+	}
+
+	bundle := strings.HasPrefix(filepath.Base(path), "index.")
+	post.Cover = hugoCover(meta, filepath.Dir(path), staticDir, bundle)
+	post.Markdown = ConvertHugoShortcodes(string(body))
+	post.Markdown = rewriteHugoImages(post.Markdown, filepath.Dir(path), staticDir)
+	post.Markdown = strings.TrimSpace(strings.ReplaceAll(post.Markdown, "<!--more-->", "")) + "\n"
+	return post, nil
+}
+
+// splitFrontMatterMap 拆分 front matter 与正文，front matter 解析为 JSON 兼容的 map；没有 front matter 时 meta 为空。
+func splitFrontMatterMap(data []byte) (map[string]any, []byte, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	meta := map[string]any{}
+	var delim string
+	switch {
+	case bytes.HasPrefix(data, []byte("---\n")):
+		delim = "---"
+	case bytes.HasPrefix(data, []byte("+++\n")):
+		delim = "+++"
+	case bytes.HasPrefix(data, []byte("{")):
+		dec := json.NewDecoder(bytes.NewReader(data))
+		if err := dec.Decode(&meta); err != nil {
+			return nil, nil, err
+		}
+		return meta, data[dec.InputOffset():], nil
+	default:
+		return meta, data, nil
+	}
+	rest := data[len(delim)+1:]
+	head, body, ok := bytes.Cut(rest, []byte("\n"+delim+"\n"))
+	if !ok {
+		if !bytes.HasSuffix(rest, []byte("\n"+delim)) {
+			return nil, nil, fmt.Errorf("missing closing %s", delim)
+		}
+		head, body = rest[:len(rest)-len(delim)-1], nil
+	}
+	var doc any
+	if delim == "+++" {
+		m, err := parseTOML(string(head))
+		if err != nil {
+			return nil, nil, err
+		}
+		doc = m
+	} else if err := yaml.Unmarshal(head, &doc); err != nil {
+		return nil, nil, err
+	}
+	// 经 JSON 转换，YAML 的日期变为 RFC 3339 字符串，嵌套 map 的键统一为字符串。
+	js, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if string(js) != "null" {
+		if err := json.Unmarshal(js, &meta); err != nil {
+			return nil, nil, fmt.Errorf("front matter is not a mapping")
+		}
+	}
+	return meta, body, nil
+}
+
+func metaString(meta map[string]any, key string) string {
+	s, _ := meta[key].(string)
+	return s
+}
+
+func parseHugoDate(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05 -0700", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", s)
+}
+
+var hugoBundleCover = regexp.MustCompile(`(?i)(cover|feature|thumbnail)[^/]*\.(jpe?g|png|gif)$`)
+
+// hugoCover 依次查找常见主题使用的封面参数（cover.image、cover、image、featured_image、images），
+// 都没有时使用 page bundle 中文件名含 cover、feature 或 thumbnail 的图片。
+func hugoCover(meta map[string]any, dir, staticDir string, bundle bool) string {
+	var refs []string
+	if c, ok := meta["cover"].(map[string]any); ok {
+		s, _ := c["image"].(string)
+		refs = append(refs, s)
+	}
+	for _, key := range []string{"cover", "image", "featured_image", "featuredImage", "featureImage", "thumbnail"} {
+		refs = append(refs, metaString(meta, key))
+	}
+	if images, ok := meta["images"].([]any); ok && len(images) > 0 {
+		s, _ := images[0].(string)
+		refs = append(refs, s)
+	}
+	for _, ref := range refs {
+		if ref == "" || !isLocalImageRef(ref) {
+			continue
+		}
+		candidates := []string{filepath.Join(staticDir, filepath.FromSlash(ref))}
+		if !strings.HasPrefix(ref, "/") {
+			candidates = append([]string{filepath.Join(dir, filepath.FromSlash(ref))}, candidates...)
+		}
+		for _, c := range candidates {
+			if info, err := os.Stat(c); err == nil && !info.IsDir() {
+				return c
+			}
+		}
+	}
+	if !bundle {
+		return ""
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		if !e.IsDir() && hugoBundleCover.MatchString(e.Name()) {
+			return filepath.Join(dir, e.Name())
+		}
+	}
+	return ""
+}
+
+var (
+	hugoImage     = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+(?:"[^"]*"|'[^']*'))?\s*\)`)
+	hugoEmptyLink = regexp.MustCompile(`\[([^\]]*)\]\(\s*\)`)
+)
+
+// rewriteHugoImages 把本地图片改为文件路径（以 / 开头的在 staticDir 下，其余相对 dir），
+// 并去掉图片的标题（"title"），使发布时能找到并上传这些图片。
+func rewriteHugoImages(md, dir, staticDir string) string {
+	return hugoImage.ReplaceAllStringFunc(md, func(m string) string {
+		sub := hugoImage.FindStringSubmatch(m)
+		ref := sub[2]
+		switch {
+		case !isLocalImageRef(ref):
+		case strings.HasPrefix(ref, "/"):
+			ref = filepath.Join(staticDir, filepath.FromSlash(ref))
+		default:
+			ref = filepath.Join(dir, filepath.FromSlash(ref))
+		}
+		return "![" + sub[1] + "](" + ref + ")"
+	})
+}
+
+// ConvertHugoShortcodes 转换 Hugo 短代码：figure 转为图片，highlight 转为代码块，youtube、vimeo、
+// gist、tweet、instagram 转为链接，ref/relref 等站内引用去掉（链接只保留文字）；其他短代码去掉标签、
+// 保留其中内容。{{</* x */>}} 这样转义的短代码还原为原文。
+func ConvertHugoShortcodes(md string) string {
+	var out strings.Builder
+	for {
+		start := strings.Index(md, "{{")
+		if start < 0 || start+2 >= len(md) || (md[start+2] != '<' && md[start+2] != '%') {
+			if start < 0 {
+				out.WriteString(md)
+				break
+			}
+			out.WriteString(md[:start+2])
+			md = md[start+2:]
+			continue
+		}
+		out.WriteString(md[:start])
+		open := md[start+2]
+		closer := string(open) + "}}"
+		if open == '<' {
+			closer = ">}}"
+		}
+		inner := strings.TrimSpace(md[start+3:])
+		if strings.HasPrefix(inner, "/*") {
+			// 转义的短代码：原样输出去掉 /* */ 后的内容。
+			end := strings.Index(md[start:], "*/"+closer)
+			if end < 0 {
+				out.WriteString(md[start:])
+				break
+			}
+			body := strings.TrimSpace(md[start+3 : start+end])
+			body = strings.TrimSpace(strings.TrimPrefix(body, "/*"))
+			out.WriteString("{{" + string(open) + " " + body + " " + closer)
+			md = md[start+end+2+len(closer):]
+			continue
+		}
+		end := strings.Index(md[start:], closer)
+		if end < 0 {
+			out.WriteString(md[start:])
+			break
+		}
+		tag := strings.TrimSpace(md[start+3 : start+end])
+		md = md[start+end+len(closer):]
+		tag = strings.TrimSpace(strings.TrimSuffix(tag, "/"))
+		if name, ok := strings.CutPrefix(tag, "/"); ok {
+			if strings.TrimSpace(name) == "highlight" {
+				out.WriteString("```")
+			}
+			continue
+		}
+		out.WriteString(hugoShortcode(tag))
+	}
+	return hugoEmptyLink.ReplaceAllString(out.String(), "$1")
+}
+
+// hugoShortcode 返回一个短代码开始标签替换后的 Markdown。
+func hugoShortcode(tag string) string {
+	name, rest, _ := strings.Cut(tag, " ")
+	pos, named := shortcodeArgs(rest)
+	arg := func(key string, index int) string {
+		if v, ok := named[key]; ok {
+			return v
+		}
+		if index < len(pos) {
+			return pos[index]
+		}
+		return ""
+	}
+	switch name {
+	case "figure":
+		src := arg("src", 0)
+		if src == "" {
+			return ""
+		}
+		alt, caption := named["alt"], named["caption"]
+		if caption == "" {
+			caption = named["title"]
+		}
+		if alt == "" {
+			alt, caption = caption, ""
+		}
+		md := "![" + alt + "](" + src + ")"
+		if caption != "" {
+			md += "\n\n*" + caption + "*"
+		}
+		return md
+	case "highlight":
+		return "```" + arg("lang", 0)
+	case "youtube":
+		if id := arg("id", 0); id != "" {
+			return "https://www.youtube.com/watch?v=" + id
+		}
+	case "vimeo":
+		if id := arg("id", 0); id != "" {
+			return "https://vimeo.com/" + id
+		}
+	case "gist":
+		if user, id := arg("user", 0), arg("id", 1); user != "" && id != "" {
+			return "https://gist.github.com/" + user + "/" + id
+		}
+	case "tweet", "x", "twitter":
+		if user, id := arg("user", 0), arg("id", 1); user != "" && id != "" {
+			return "https://x.com/" + user + "/status/" + id
+		}
+	case "instagram":
+		if id := arg("id", 0); id != "" {
+			return "https://www.instagram.com/p/" + id + "/"
+		}
+	}
+	return ""
+}
+
+// shortcodeArgs 解析短代码参数：位置参数与 key=value（值可用双引号或反引号包裹）。
+func shortcodeArgs(s string) ([]string, map[string]string) {
+	var pos []string
+	named := map[string]string{}
+	for {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return pos, named
+		}
+		key := ""
+		if i := strings.IndexAny(s, "= \t\"`"); i > 0 && s[i] == '=' {
+			key, s = s[:i], s[i+1:]
+		}
+		var val string
+		if s != "" && (s[0] == '"' || s[0] == '`') {
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				val, s = s[1:], ""
+			} else {
+				val, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(s, " \t")
+			if end < 0 {
+				end = len(s)
+			}
+			val, s = s[:end], s[end:]
+		}
+		if key != "" {
+			named[key] = val
+		} else {
+			pos = append(pos, val)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package publisher
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mailTimeout 为一次收件箱检查或发送回信的总时长上限。
+const mailTimeout = 5 * time.Minute
+
+var (
+	// imapLiteral 匹配响应行末尾的字面量长度 {n}。
+	imapLiteral = regexp.MustCompile(`\{(\d+)\}$`)
+	// imapUIDValidity 匹配 SELECT 响应中的 UIDVALIDITY。
+	imapUIDValidity = regexp.MustCompile(`\[UIDVALIDITY (\d+)\]`)
+)
+
+// IMAPClient 为只实现投稿所需命令的 IMAP 客户端：登录、选择邮箱、搜索未读邮件、读取与标记已读。
+type IMAPClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	seq  int
+	// UIDValidity 为所选邮箱的 UIDVALIDITY，变化时之前记录的 UID 失效。
+	UIDValidity uint32
+}
+
+// imapResponse 为一条未标记的响应行及其中的字面量数据。
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+// DialIMAP 连接 cfg.IMAP、登录并选择邮箱；连接的读写总时长不超过 mailTimeout。
+func DialIMAP(ctx context.Context, cfg MailConfig) (*IMAPClient, error) {
+	u, err := url.Parse(cfg.IMAP)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialMail(ctx, u, "993", "143")
+	if err != nil {
+		return nil, fmt.Errorf("imap: %w", err)
+	}
+	c := &IMAPClient{conn: conn, r: bufio.NewReader(conn)}
+	if greeting, _, err := c.readLine(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("imap: %w", err)
+	} else if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("imap: unexpected greeting %q", greeting)
+	}
+	user, err := imapQuote(cfg.Username)
+	if err == nil {
+		var pass string
+		if pass, err = imapQuote(cfg.Password); err == nil {
+			_, err = c.command("LOGIN " + user + " " + pass)
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	mailbox := cfg.Mailbox
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+	box, err := imapQuote(mailbox)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	resps, err := c.command("SELECT " + box)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	for _, r := range resps {
+		if m := imapUIDValidity.FindStringSubmatch(r.line); m != nil {
+			v, _ := strconv.ParseUint(m[1], 10, 32)
+			c.UIDValidity = uint32(v)
+		}
+	}
+	return c, nil
+}
+
+// dialMail 按地址的协议建立连接：以 s 结尾的协议（imaps、smtps）使用 TLS，未指定端口时使用默认端口。
+func dialMail(ctx context.Context, u *url.URL, tlsPort, plainPort string) (net.Conn, error) {
+	secure := strings.HasSuffix(u.Scheme, "s")
+	host := u.Host
+	if u.Port() == "" {
+		port := plainPort
+		if secure {
+			port = tlsPort
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	var err error
+	if secure {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: u.Hostname()}}).DialContext(ctx, "tcp", host)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(mailTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	return conn, nil
+}
+
+// imapQuote 把字符串写成 IMAP 的带引号字符串，含换行或非 ASCII 字符时返回错误。
+func imapQuote(s string) (string, error) {
+	for _, r := range s {
+		if r == '\r' || r == '\n' || r > 0x7e {
+			return "", errors.New("imap: username, password and mailbox must be printable ASCII")
+		}
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`, nil
+}
+
+// readLine 读取一条响应，把其中的字面量 {n} 读出后接着读取该行的剩余部分。
+func (c *IMAPClient) readLine() (string, [][]byte, error) {
+	var sb strings.Builder
+	var literals [][]byte
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return "", nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		m := imapLiteral.FindStringSubmatch(line)
+		if m == nil {
+			sb.WriteString(line)
+			return sb.String(), literals, nil
+		}
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n > maxMailBytes {
+			return "", nil, fmt.Errorf("imap: message exceeds %d MB", maxMailBytes>>20)
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return "", nil, err
+		}
+		sb.WriteString(line[:len(line)-len(m[0])])
+		literals = append(literals, data)
+	}
+}
+
+// command 发送命令并读取响应，直到带本命令标签的结束行；结果不是 OK 时返回错误。
+func (c *IMAPClient) command(cmd string) ([]imapResponse, error) {
+	c.seq++
+	tag := "A" + strconv.Itoa(c.seq)
+	verb, _, _ := strings.Cut(cmd, " ")
+	if verb == "UID" {
+		verb = cmd[:strings.IndexByte(cmd[4:]+" ", ' ')+4]
+	}
+	if _, err := io.WriteString(c.conn, tag+" "+cmd+"\r\n"); err != nil {
+		return nil, fmt.Errorf("imap %s: %w", verb, err)
+	}
+	var resps []imapResponse
+	for {
+		line, literals, err := c.readLine()
+		if err != nil {
+			return nil, fmt.Errorf("imap %s: %w", verb, err)
+		}
+		if status, ok := strings.CutPrefix(line, tag+" "); ok {
+			if !strings.HasPrefix(status, "OK") {
+				return nil, fmt.Errorf("imap %s: %s", verb, status)
+			}
+			return resps, nil
+		}
+		resps = append(resps, imapResponse{line: line, literals: literals})
+	}
+}
+
+// Unseen 返回邮箱中未读邮件的 UID，按从旧到新排列。
+func (c *IMAPClient) Unseen() ([]uint32, error) {
+	resps, err := c.command("UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, r := range resps {
+		rest, ok := strings.CutPrefix(r.line, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, f := range strings.Fields(rest) {
+			if n, err := strconv.ParseUint(f, 10, 32); err == nil {
+				uids = append(uids, uint32(n))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// fetch 读取邮件的指定部分（不标记已读）。
+func (c *IMAPClient) fetch(uid uint32, section string) ([]byte, error) {
+	resps, err := c.command(fmt.Sprintf("UID FETCH %d (BODY.PEEK[%s])", uid, section))
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range resps {
+		if strings.Contains(r.line, "FETCH") && len(r.literals) > 0 {
+			return r.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("imap: message %d not found", uid)
+}
+
+// FetchHeader 读取邮件的 From 与 Subject 头（已解码）。
+func (c *IMAPClient) FetchHeader(uid uint32) (from, subject string, err error) {
+	data, err := c.fetch(uid, "HEADER.FIELDS (FROM SUBJECT)")
+	if err != nil {
+		return "", "", err
+	}
+	m, err := ParseMail(append(data, "\r\n"...))
+	return m.From, m.Subject, err
+}
+
+// Fetch 读取并解析整封邮件。
+func (c *IMAPClient) Fetch(uid uint32) (MailMessage, error) {
+	data, err := c.fetch(uid, "")
+	if err != nil {
+		return MailMessage{}, err
+	}
+	return ParseMail(data)
+}
+
+// MarkSeen 把邮件标记为已读。
+func (c *IMAPClient) MarkSeen(uid uint32) error {
+	_, err := c.command(fmt.Sprintf(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid))
+	return err
+}
+
+// Close 退出登录并关闭连接。
+func (c *IMAPClient) Close() error {
+	_, _ = c.command("LOGOUT")
+	return c.conn.Close()
+}
+
+// SendMailReply 通过 cfg.SMTP 回复投稿邮件；smtp:// 在服务器支持时升级为 STARTTLS。
+func SendMailReply(ctx context.Context, cfg MailConfig, orig MailMessage, body string) error {
+	u, err := url.Parse(cfg.SMTP)
+	if err != nil {
+		return err
+	}
+	conn, err := dialMail(ctx, u, "465", "587")
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	host := u.Hostname()
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp: %w", err)
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok && u.Scheme == "smtp" {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("smtp: %w", err)
+		}
+	}
+	// PlainAuth 只在 TLS 连接或本机地址上发送密码。
+	if ok, _ := c.Extension("AUTH"); ok {
+		if err := c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	from := cfg.From
+	if from == "" {
+		from = cfg.Username
+	}
+	if err := c.Mail(from); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	if err := c.Rcpt(orig.From); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	subject := orig.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\n", from, orig.From, mime.BEncoding.Encode("utf-8", subject), time.Now().Format(time.RFC1123Z))
+	if orig.MessageID != "" {
+		fmt.Fprintf(&sb, "In-Reply-To: %s\r\nReferences: %s\r\n", orig.MessageID, orig.MessageID)
+	}
+	sb.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: base64\r\n\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		sb.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	sb.WriteString(encoded + "\r\n")
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return c.Quit()
+}
@@ -0,0 +1,88 @@
+package publisher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileLock 为基于文件的建议锁（advisory lock）：同一文件同一时刻只有一个进程持有，进程退出时由系统释放。
+type FileLock struct {
+	f *os.File
+}
+
+// LockedError 表示锁已被其他进程持有，PID 为持有者写入的进程号（读取不到时为 0）。
+type LockedError struct {
+	Path string
+	PID  int
+}
+
+func (e *LockedError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("%s is locked by pid %d", e.Path, e.PID)
+	}
+	return fmt.Sprintf("%s is locked by another process", e.Path)
+}
+
+// LockFile 获取 path 上的锁，文件不存在时创建。锁被占用时每 200 毫秒重试一次，
+// 最多等待 wait（0 表示不等待），仍未获得则返回 *LockedError。
+func LockFile(path string, wait time.Duration) (*FileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		ok, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("lock %s: %w", path, err)
+		}
+		if ok {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			pid := lockPID(f)
+			f.Close()
+			return nil, &LockedError{Path: path, PID: pid}
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	// 写入进程号便于排查。释放后不删除文件：删除会让正在等待的进程锁住已被删除的文件。
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &FileLock{f: f}, nil
+}
+
+func lockPID(f *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := f.ReadAt(buf, 0)
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	return pid
+}
+
+// Unlock 清空进程号并释放锁。
+func (l *FileLock) Unlock() error {
+	_ = l.f.Truncate(0)
+	if err := unlockFile(l.f); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// PublishLockPath 返回命令行发布使用的锁文件：配置的 lock_path；未配置时为系统临时目录下
+// 按 app_id（企业微信为 corp_id 与 agent_id）区分的文件，同一台机器上对同一帐号的发布互斥。
+func PublishLockPath(cfg Config) string {
+	if cfg.LockPath != "" {
+		return cfg.LockPath
+	}
+	return filepath.Join(os.TempDir(), "auto-wechat-article-publisher-"+strings.ReplaceAll(cfg.AccountID(), "/", "-")+".lock")
+}
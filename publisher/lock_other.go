@@ -0,0 +1,17 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package publisher
+
+import (
+	"errors"
+	"os"
+	"runtime"
+)
+
+func tryLockFile(*os.File) (bool, error) {
+	return false, errors.New("file locking is not supported on " + runtime.GOOS)
+}
+
+func unlockFile(*os.File) error {
+	return nil
+}
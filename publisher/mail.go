@@ -0,0 +1,249 @@
+package publisher
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+const (
+	// DefaultMailPollMinutes 为未配置 poll_minutes 时检查收件箱的间隔。
+	DefaultMailPollMinutes = 5
+	// maxMailBytes 为处理的单封邮件大小上限。
+	maxMailBytes = 25 << 20
+)
+
+// MailConfig 配置邮件投稿：定期检查 IMAP 收件箱，把允许的发件人发来的 Markdown 邮件（正文或 .md 附件）
+// 发布到草稿箱或保存为待审核稿件，并回信告知结果。邮件主题作为标题，图片附件可在正文中按文件名引用。
+type MailConfig struct {
+	// IMAP 为收件服务器，如 imaps://imap.example.com:993；imap:// 为明文连接，只用于本机的邮件桥接程序。
+	IMAP     string `json:"imap"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Mailbox 为检查的邮箱，默认 INBOX。
+	Mailbox string `json:"mailbox,omitempty"`
+	// SMTP 为发送回信的服务器，如 smtps://smtp.example.com:465 或 smtp://smtp.example.com:587（STARTTLS），
+	// 使用同一账号登录；为空时不回信。From 为回信的发件地址，默认 username。
+	SMTP string `json:"smtp,omitempty"`
+	From string `json:"from,omitempty"`
+	// AllowedSenders 为允许投稿的发件地址，@example.com 表示整个域名。其他邮件保持未读、不做处理。
+	AllowedSenders []string `json:"allowed_senders"`
+	// SubjectPrefix 非空时只处理主题以此开头的邮件（如 [公众号]），标题中去掉该前缀。
+	SubjectPrefix string `json:"subject_prefix,omitempty"`
+	// PollMinutes 为检查收件箱的间隔（分钟），默认 5。
+	PollMinutes int `json:"poll_minutes,omitempty"`
+	// Mode 为 publish（默认，发布到草稿箱并回信 media_id）或 review（保存为 session 等待审核）。
+	Mode string `json:"mode,omitempty"`
+	// Owner 为生成的 session 所属用户（启用登录时），为空则只有管理员可见。
+	Owner string `json:"owner,omitempty"`
+	// 以下为默认值；front matter 中的 cover、author 与名为 cover 的图片附件优先。
+	CoverPath string `json:"cover_path,omitempty"`
+	AICover   bool   `json:"ai_cover,omitempty"`
+	Author    string `json:"author,omitempty"`
+}
+
+// Validate 检查邮件投稿配置。
+func (c MailConfig) Validate() error {
+	if c.IMAP == "" || c.Username == "" || c.Password == "" {
+		return errors.New("mail: imap, username and password required")
+	}
+	if !mailServerURL(c.IMAP, "imap") {
+		return fmt.Errorf("mail: invalid imap %q (use imaps://host:993)", c.IMAP)
+	}
+	if c.SMTP != "" && !mailServerURL(c.SMTP, "smtp") {
+		return fmt.Errorf("mail: invalid smtp %q (use smtps://host:465 or smtp://host:587)", c.SMTP)
+	}
+	if len(c.AllowedSenders) == 0 {
+		return errors.New("mail: allowed_senders required")
+	}
+	switch c.Mode {
+	case "", "publish", "review":
+	default:
+		return fmt.Errorf("mail: unknown mode %q (use publish or review)", c.Mode)
+	}
+	return nil
+}
+
+// mailServerURL 判断 addr 是否为 <proto>:// 或 <proto>s:// 形式的服务器地址。
+func mailServerURL(addr, proto string) bool {
+	u, err := url.Parse(addr)
+	return err == nil && u.Host != "" && (u.Scheme == proto || u.Scheme == proto+"s")
+}
+
+// Allowed 判断发件地址是否在 allowed_senders 中（不区分大小写）。
+func (c MailConfig) Allowed(from string) bool {
+	from = strings.ToLower(from)
+	for _, a := range c.AllowedSenders {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == from || (strings.HasPrefix(a, "@") && strings.HasSuffix(from, a)) {
+			return true
+		}
+	}
+	return false
+}
+
+// MailMessage 为解析后的一封投稿邮件。
+type MailMessage struct {
+	MessageID string
+	// From 为发件地址（不含显示名）。
+	From    string
+	Subject string
+	// Markdown 取自 .md 附件，没有时为纯文本正文；MarkdownName 为附件文件名。
+	Markdown     string
+	MarkdownName string
+	// Attachments 为图片等其他附件，按文件名保存。
+	Attachments map[string][]byte
+	// HTMLOnly 表示邮件只有 HTML 正文，没有纯文本或 Markdown。
+	HTMLOnly bool
+}
+
+// mailWordDecoder 解码 RFC 2047 编码的主题与附件名，支持 GBK 等非 UTF-8 字符集。
+var mailWordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %s", charset)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// ParseMail 解析原始邮件：multipart 中的第一个 .md 附件（或 text/markdown 部分）作为正文，
+// 没有时取第一个 text/plain 部分；其余带文件名的部分作为附件。
+func ParseMail(raw []byte) (MailMessage, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return MailMessage{}, fmt.Errorf("parse mail: %w", err)
+	}
+	m := MailMessage{MessageID: strings.TrimSpace(msg.Header.Get("Message-ID")), Attachments: map[string][]byte{}}
+	if from, err := mail.ParseAddress(decodeMailHeader(msg.Header.Get("From"))); err == nil {
+		m.From = from.Address
+	} else {
+		return m, fmt.Errorf("parse From: %w", err)
+	}
+	m.Subject = strings.TrimSpace(decodeMailHeader(msg.Header.Get("Subject")))
+
+	var plain string
+	hasPlain, hasHTML := false, false
+	var walk func(header map[string][]string, body io.Reader, depth int) error
+	walk = func(header map[string][]string, body io.Reader, depth int) error {
+		h := mailHeader(header)
+		mediaType, params, err := mime.ParseMediaType(h.get("Content-Type"))
+		if err != nil {
+			mediaType, params = "text/plain", map[string]string{}
+		}
+		if strings.HasPrefix(mediaType, "multipart/") {
+			if depth > 5 {
+				return errors.New("mail nested too deeply")
+			}
+			mr := multipart.NewReader(body, params["boundary"])
+			for {
+				p, err := mr.NextRawPart()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				if err := walk(p.Header, p, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		data, err := decodeTransfer(h.get("Content-Transfer-Encoding"), body)
+		if err != nil {
+			return err
+		}
+		disposition, dparams, _ := mime.ParseMediaType(h.get("Content-Disposition"))
+		name := decodeMailHeader(dparams["filename"])
+		if name == "" {
+			name = decodeMailHeader(params["name"])
+		}
+		name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+		ext := strings.ToLower(path.Ext(name))
+		switch {
+		case m.Markdown == "" && (ext == ".md" || ext == ".markdown" || mediaType == "text/markdown"):
+			text, err := decodeCharset(params["charset"], data)
+			if err != nil {
+				return err
+			}
+			m.Markdown, m.MarkdownName = text, name
+		case mediaType == "text/plain" && disposition != "attachment" && !hasPlain:
+			text, err := decodeCharset(params["charset"], data)
+			if err != nil {
+				return err
+			}
+			plain, hasPlain = text, true
+		case mediaType == "text/html" && disposition != "attachment":
+			hasHTML = true
+		case name != "" && name != "." && name != "/":
+			m.Attachments[name] = data
+		}
+		return nil
+	}
+	if err := walk(msg.Header, io.LimitReader(msg.Body, maxMailBytes), 0); err != nil {
+		return m, fmt.Errorf("parse mail body: %w", err)
+	}
+	if m.Markdown == "" {
+		m.Markdown = strings.ReplaceAll(plain, "\r\n", "\n")
+		m.HTMLOnly = !hasPlain && hasHTML
+	}
+	return m, nil
+}
+
+// mailHeader 按规范化的键读取 MIME 头。
+type mailHeader map[string][]string
+
+func (h mailHeader) get(key string) string {
+	if v := h[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// decodeMailHeader 解码 RFC 2047 编码的头部值，无法解码时原样返回。
+func decodeMailHeader(s string) string {
+	if out, err := mailWordDecoder.DecodeHeader(s); err == nil {
+		return out
+	}
+	return s
+}
+
+// decodeTransfer 按 Content-Transfer-Encoding 解码。
+func decodeTransfer(encoding string, r io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxMailBytes+1))
+	if err == nil && len(data) > maxMailBytes {
+		err = fmt.Errorf("attachment exceeds %d MB", maxMailBytes>>20)
+	}
+	return data, err
+}
+
+// decodeCharset 把文本按 charset 转为 UTF-8，未指定时视为 UTF-8。
+func decodeCharset(charset string, data []byte) (string, error) {
+	if charset == "" || strings.EqualFold(charset, "utf-8") || strings.EqualFold(charset, "us-ascii") {
+		return string(data), nil
+	}
+	r, err := charsetReader(charset, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	out, err := io.ReadAll(r)
+	return string(out), err
+}
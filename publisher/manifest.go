@@ -0,0 +1,230 @@
+package publisher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// PublishManifest 记录一次发布已上传的正文图片与封面，每次上传后立即写回文件；
+// 发布失败后用它续传，已上传且内容未变的文件直接复用，避免重复上传与占用素材配额。
+type PublishManifest struct {
+	// MarkdownPath 为绝对路径；正文来自标准输入时为工作目录下的 "-"，用于解析相对路径的图片。
+	MarkdownPath string `json:"markdown_path"`
+	// Markdown 为从标准输入读取的正文，续传时使用。
+	Markdown  string `json:"markdown,omitempty"`
+	Title     string `json:"title"`
+	CoverPath string `json:"cover_path"`
+	Author    string `json:"author,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	// UpdateMediaID 非空时续传仍更新该草稿。
+	UpdateMediaID string `json:"update_media_id,omitempty"`
+	UpdateIndex   int    `json:"update_index,omitempty"`
+	// Images 以图片绝对路径为键。
+	Images    map[string]ManifestUpload `json:"images"`
+	Cover     *ManifestUpload           `json:"cover,omitempty"`
+	UpdatedAt time.Time                 `json:"updated_at"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// ManifestUpload 为一个已上传的文件；SHA256 为上传时的文件内容摘要，内容变化后重新上传。
+type ManifestUpload struct {
+	Path    string `json:"path,omitempty"`
+	URL     string `json:"url,omitempty"`
+	MediaID string `json:"media_id,omitempty"`
+	SHA256  string `json:"sha256"`
+}
+
+// NewPublishManifest 按发布参数创建保存到 path 的清单，尚未写入文件。
+func NewPublishManifest(path string, params PublishParams) (*PublishManifest, error) {
+	mdPath, err := filepath.Abs(params.MarkdownPath)
+	if err != nil {
+		return nil, err
+	}
+	coverPath, err := filepath.Abs(params.CoverPath)
+	if err != nil {
+		return nil, err
+	}
+	return &PublishManifest{
+		MarkdownPath:  mdPath,
+		Markdown:      params.Markdown,
+		Title:         params.Title,
+		CoverPath:     coverPath,
+		Author:        params.Author,
+		Digest:        params.Digest,
+		UpdateMediaID: params.UpdateMediaID,
+		UpdateIndex:   params.UpdateIndex,
+		Images:        map[string]ManifestUpload{},
+		path:          path,
+	}, nil
+}
+
+// LoadPublishManifest 读取之前发布失败时留下的清单。
+func LoadPublishManifest(path string) (*PublishManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &PublishManifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parse manifest %s: %w", path, err)
+	}
+	if m.MarkdownPath == "" || m.Title == "" || m.CoverPath == "" {
+		return nil, fmt.Errorf("manifest %s is missing markdown_path, title or cover_path", path)
+	}
+	if m.Images == nil {
+		m.Images = map[string]ManifestUpload{}
+	}
+	m.path = path
+	return m, nil
+}
+
+// Path 返回清单文件路径。
+func (m *PublishManifest) Path() string {
+	return m.path
+}
+
+// Params 返回续传使用的发布参数，Manifest 指向清单本身。
+func (m *PublishManifest) Params() PublishParams {
+	return PublishParams{
+		MarkdownPath:  m.MarkdownPath,
+		Markdown:      m.Markdown,
+		Title:         m.Title,
+		CoverPath:     m.CoverPath,
+		Author:        m.Author,
+		Digest:        m.Digest,
+		UpdateMediaID: m.UpdateMediaID,
+		UpdateIndex:   m.UpdateIndex,
+		Manifest:      m,
+	}
+}
+
+// Save 写入清单文件（先写临时文件再改名）。
+func (m *PublishManifest) Save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.save()
+}
+
+func (m *PublishManifest) save() error {
+	m.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+// image 返回已上传且内容未变的正文图片地址；m 为 nil 时总是返回 false。
+func (m *PublishManifest) image(path string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	key, sum, err := fileKey(path)
+	if err != nil {
+		return "", false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	up, ok := m.Images[key]
+	if !ok || up.SHA256 != sum {
+		return "", false
+	}
+	return up.URL, true
+}
+
+// recordImage 记录上传成功的正文图片并写回文件。
+func (m *PublishManifest) recordImage(path, url string) error {
+	if m == nil {
+		return nil
+	}
+	key, sum, err := fileKey(path)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Images[key] = ManifestUpload{URL: url, SHA256: sum}
+	return m.save()
+}
+
+// cover 返回已上传且内容未变的封面 media_id。
+func (m *PublishManifest) cover(path string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	key, sum, err := fileKey(path)
+	if err != nil {
+		return "", false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Cover == nil || m.Cover.Path != key || m.Cover.SHA256 != sum {
+		return "", false
+	}
+	return m.Cover.MediaID, true
+}
+
+// recordCover 记录上传成功的封面并写回文件。
+func (m *PublishManifest) recordCover(path, mediaID string) error {
+	if m == nil {
+		return nil
+	}
+	key, sum, err := fileKey(path)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Cover = &ManifestUpload{Path: key, MediaID: mediaID, SHA256: sum}
+	return m.save()
+}
+
+// fileKey 返回文件的绝对路径与内容的 SHA-256。
+func fileKey(path string) (string, string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", err
+	}
+	f, err := os.Open(abs)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", "", err
+	}
+	return abs, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// uploadCover 上传封面为永久素材；params.Manifest 中已有内容相同的封面时直接复用。
+func (p *Publisher) uploadCover(ctx context.Context, params PublishParams) (string, error) {
+	if mediaID, ok := params.Manifest.cover(params.CoverPath); ok {
+		p.infof("Reusing uploaded cover %s from manifest", params.CoverPath)
+		return mediaID, nil
+	}
+	mediaID, err := p.withTokenRefreshString(ctx, func(token string) (string, error) {
+		return uploadImage(ctx, p.client, p.endpoint(uploadImageURL), token, params.CoverPath)
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := params.Manifest.recordCover(params.CoverPath, mediaID); err != nil {
+		return "", fmt.Errorf("save publish manifest: %w", err)
+	}
+	return mediaID, nil
+}
@@ -0,0 +1,265 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	massSendAllURL = "https://api.weixin.qq.com/cgi-bin/message/mass/sendall"
+	massGetURL     = "https://api.weixin.qq.com/cgi-bin/message/mass/get"
+)
+
+// DefaultMassSendPath 为未配置 mass_send_path 时的群发记录文件。
+const DefaultMassSendPath = "mass_sends.json"
+
+// 群发状态：申请后等待批准，批准时发送。
+const (
+	MassSendPending  = "pending"
+	MassSendSending  = "sending"
+	MassSendSent     = "sent"
+	MassSendFailed   = "failed"
+	MassSendCanceled = "canceled"
+)
+
+// ErrMassSendNotFound 表示不存在该群发。
+var ErrMassSendNotFound = errors.New("mass send not found")
+
+// MassSendParams 为一次群发：MediaID 为草稿的 media_id，TagID 为 0 时发给全部粉丝，否则只发给该标签的粉丝。
+// IgnoreReprint 为 true 时文章被判定为转载也继续群发。ClientMsgID 相同的群发微信在 24 小时内只发送一次。
+type MassSendParams struct {
+	MediaID       string
+	TagID         int
+	IgnoreReprint bool
+	ClientMsgID   string
+}
+
+// MassSendResult 为群发接口返回的消息 ID；MsgDataID 可用于留言管理与数据统计。
+type MassSendResult struct {
+	MsgID     int64 `json:"msg_id"`
+	MsgDataID int64 `json:"msg_data_id"`
+}
+
+// MassSend 群发图文。群发会推送到粉丝的消息列表且无法撤回推送，调用方须先确认。
+func (p *Publisher) MassSend(ctx context.Context, params MassSendParams) (MassSendResult, error) {
+	if params.MediaID == "" {
+		return MassSendResult{}, errors.New("media_id is required")
+	}
+	filter := map[string]any{"is_to_all": params.TagID == 0}
+	if params.TagID != 0 {
+		filter["tag_id"] = params.TagID
+	}
+	ignore := 0
+	if params.IgnoreReprint {
+		ignore = 1
+	}
+	payload := map[string]any{
+		"filter":              filter,
+		"mpnews":              map[string]string{"media_id": params.MediaID},
+		"msgtype":             "mpnews",
+		"send_ignore_reprint": ignore,
+	}
+	if params.ClientMsgID != "" {
+		payload["clientmsgid"] = params.ClientMsgID
+	}
+	var res MassSendResult
+	if err := p.callAPI(ctx, massSendAllURL, payload, &res); err != nil {
+		return MassSendResult{}, err
+	}
+	p.logger.Printf("[masssend] sent media_id=%s tag=%d msg_id=%d", params.MediaID, params.TagID, res.MsgID)
+	return res, nil
+}
+
+// MassStatus 查询群发的发送状态：SEND_SUCCESS、SENDING、SEND_FAIL 或 DELETE。
+func (p *Publisher) MassStatus(ctx context.Context, msgID int64) (string, error) {
+	var resp struct {
+		MsgStatus string `json:"msg_status"`
+	}
+	if err := p.callAPI(ctx, massGetURL, map[string]string{"msg_id": strconv.FormatInt(msgID, 10)}, &resp); err != nil {
+		return "", err
+	}
+	return resp.MsgStatus, nil
+}
+
+// MassSendRequest 为一条群发申请：RequestedBy 提交，ApprovedBy 批准后立即发送。
+// 命令行直接群发的条目没有申请阶段，创建时即为发送结果。SendStatus 为最近一次查询到的微信发送状态。
+type MassSendRequest struct {
+	ID            string    `json:"id"`
+	SessionID     string    `json:"session_id,omitempty"`
+	MediaID       string    `json:"media_id"`
+	Title         string    `json:"title,omitempty"`
+	TagID         int       `json:"tag_id,omitempty"`
+	TagName       string    `json:"tag_name,omitempty"`
+	IgnoreReprint bool      `json:"send_ignore_reprint,omitempty"`
+	Status        string    `json:"status"`
+	RequestedBy   string    `json:"requested_by,omitempty"`
+	ApprovedBy    string    `json:"approved_by,omitempty"`
+	MsgID         int64     `json:"msg_id,omitempty"`
+	MsgDataID     int64     `json:"msg_data_id,omitempty"`
+	SendStatus    string    `json:"send_status,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// MassSendStore 把群发申请与记录保存为单个 JSON 文件，服务端与命令行共用。
+type MassSendStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewMassSendStore 创建群发存储，文件不存在时视为空。
+func NewMassSendStore(path string) *MassSendStore {
+	if path == "" {
+		path = DefaultMassSendPath
+	}
+	return &MassSendStore{path: path}
+}
+
+// Add 保存一条群发，Status 为空时为待批准，返回带 ID 的条目。
+func (s *MassSendStore) Add(item MassSendRequest) (MassSendRequest, error) {
+	if strings.TrimSpace(item.MediaID) == "" {
+		return item, errors.New("media_id is required")
+	}
+	if item.TagID < 0 {
+		return item, errors.New("tag_id must not be negative")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, err := s.readLocked()
+	if err != nil {
+		return item, err
+	}
+	now := time.Now()
+	item.ID = strconv.FormatInt(now.UnixNano(), 36)
+	if item.Status == "" {
+		item.Status = MassSendPending
+	}
+	item.CreatedAt, item.UpdatedAt = now, now
+	if err := s.writeLocked(append(items, item)); err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+// List 按创建时间倒序返回条目；status 非空时只返回该状态。
+func (s *MassSendStore) List(status string) ([]MassSendRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, err := s.readLocked()
+	if err != nil {
+		return nil, err
+	}
+	kept := []MassSendRequest{}
+	for i := len(items) - 1; i >= 0; i-- {
+		if status == "" || items[i].Status == status {
+			kept = append(kept, items[i])
+		}
+	}
+	return kept, nil
+}
+
+// Get 返回单个条目。
+func (s *MassSendStore) Get(id string) (MassSendRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, err := s.readLocked()
+	if err != nil {
+		return MassSendRequest{}, err
+	}
+	for _, it := range items {
+		if it.ID == id {
+			return it, nil
+		}
+	}
+	return MassSendRequest{}, ErrMassSendNotFound
+}
+
+// FailInterrupted 把上次进程退出时仍在发送的条目标记为失败，避免重复群发。
+func (s *MassSendStore) FailInterrupted() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, err := s.readLocked()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i := range items {
+		if items[i].Status == MassSendSending {
+			items[i].Status, items[i].Error, items[i].UpdatedAt = MassSendFailed, "interrupted by restart; check the sent messages in the WeChat console before retrying", time.Now()
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, s.writeLocked(items)
+}
+
+// Update 在锁内修改单个条目并保存。
+func (s *MassSendStore) Update(id string, fn func(*MassSendRequest) error) (MassSendRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, err := s.readLocked()
+	if err != nil {
+		return MassSendRequest{}, err
+	}
+	for i := range items {
+		if items[i].ID != id {
+			continue
+		}
+		if err := fn(&items[i]); err != nil {
+			return MassSendRequest{}, err
+		}
+		items[i].UpdatedAt = time.Now()
+		updated := items[i]
+		return updated, s.writeLocked(items)
+	}
+	return MassSendRequest{}, ErrMassSendNotFound
+}
+
+func (s *MassSendStore) readLocked() ([]MassSendRequest, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var items []MassSendRequest
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("mass send %s: %w", s.path, err)
+	}
+	return items, nil
+}
+
+func (s *MassSendStore) writeLocked(items []MassSendRequest) error {
+	sort.SliceStable(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create mass send dir: %w", err)
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package publisher
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxNotebookOutputLines 为单个文本输出保留的最大行数，超出部分省略。
+const maxNotebookOutputLines = 50
+
+// notebookText 为 notebook 中写成字符串或字符串数组（按行拆分）的文本。
+type notebookText string
+
+func (t *notebookText) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = notebookText(s)
+		return nil
+	}
+	var lines []string
+	if err := json.Unmarshal(b, &lines); err != nil {
+		return err
+	}
+	*t = notebookText(strings.Join(lines, ""))
+	return nil
+}
+
+type notebookCell struct {
+	CellType string       `json:"cell_type"`
+	Source   notebookText `json:"source"`
+	Metadata struct {
+		Tags    []string `json:"tags"`
+		Jupyter struct {
+			SourceHidden  bool `json:"source_hidden"`
+			OutputsHidden bool `json:"outputs_hidden"`
+		} `json:"jupyter"`
+	} `json:"metadata"`
+	Outputs     []notebookOutput                   `json:"outputs"`
+	Attachments map[string]map[string]notebookText `json:"attachments"`
+}
+
+type notebookOutput struct {
+	OutputType string       `json:"output_type"`
+	Name       string       `json:"name"`
+	Text       notebookText `json:"text"`
+	// Data 按 MIME 类型给出富输出，文本类为字符串或字符串数组，application/json 等为对象。
+	Data   map[string]json.RawMessage `json:"data"`
+	EName  string                     `json:"ename"`
+	EValue string                     `json:"evalue"`
+}
+
+var (
+	// ansiEscape 匹配终端颜色等控制序列（异常与进度条输出中常见）。
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+	// objectRepr 匹配 [<matplotlib.lines.Line2D at 0x...>] 这类只有对象地址、没有内容的输出。
+	objectRepr = regexp.MustCompile(`^\[?<[^\n]* at 0x[0-9a-fA-F]+>\]?$`)
+)
+
+// ConvertNotebook 把 Jupyter notebook (.ipynb) 转为 Markdown：Markdown 单元格原样保留（附件与本地图片
+// 提取到 dir/images，相对路径按 notebook 所在目录查找，找不到时返回错误），代码单元格转为带语言的代码块，
+// 输出中的图片（如 matplotlib 的 PNG）提取到 dir/images，HTML 输出（如 DataFrame）转为表格，文本输出转为代码块。
+// 带 remove-cell、remove-input、remove-output（或 hide-*）标签的单元格按标签去掉相应部分。
+// 标题取 notebook 元数据中的 title，没有时由调用方按第一个一级标题处理。
+func ConvertNotebook(file, dir string) (ConvertedDocument, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ConvertedDocument{}, err
+	}
+	var nb struct {
+		NBFormat int            `json:"nbformat"`
+		Cells    []notebookCell `json:"cells"`
+		Metadata struct {
+			Title      string `json:"title"`
+			KernelSpec struct {
+				Language string `json:"language"`
+			} `json:"kernelspec"`
+			LanguageInfo struct {
+				Name string `json:"name"`
+			} `json:"language_info"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return ConvertedDocument{}, fmt.Errorf("parse %s: %w", file, err)
+	}
+	if nb.NBFormat < 4 {
+		return ConvertedDocument{}, fmt.Errorf("%s: nbformat %d is not supported, save it with Jupyter 4 or later", file, nb.NBFormat)
+	}
+	lang := nb.Metadata.LanguageInfo.Name
+	if lang == "" {
+		lang = nb.Metadata.KernelSpec.Language
+	}
+	c := &htmlConverter{base: filepath.Dir(file), dir: dir, images: map[string]string{}}
+	c.doc.Title = strings.TrimSpace(nb.Metadata.Title)
+
+	var sb strings.Builder
+	for i, cell := range nb.Cells {
+		tags := cell.Metadata.Tags
+		has := func(names ...string) bool {
+			return slices.ContainsFunc(names, func(n string) bool { return slices.Contains(tags, n) })
+		}
+		if has("remove-cell", "remove_cell", "hide-cell") {
+			continue
+		}
+		switch cell.CellType {
+		case "markdown":
+			md, err := c.notebookMarkdown(string(cell.Source), cell.Attachments, i+1)
+			if err != nil {
+				return c.doc, err
+			}
+			if md = strings.TrimSpace(md); md != "" {
+				sb.WriteString(md + "\n\n")
+			}
+		case "code":
+			if src := strings.TrimRight(string(cell.Source), "\n"); strings.TrimSpace(src) != "" &&
+				!cell.Metadata.Jupyter.SourceHidden && !has("remove-input", "remove_input", "hide-input") {
+				fmt.Fprintf(&sb, "```%s\n%s\n```\n\n", lang, src)
+			}
+			if cell.Metadata.Jupyter.OutputsHidden || has("remove-output", "remove_output", "hide-output") {
+				continue
+			}
+			if err := c.notebookOutputs(&sb, cell.Outputs, i+1); err != nil {
+				return c.doc, err
+			}
+		}
+	}
+	c.doc.Markdown = strings.TrimSpace(sb.String()) + "\n"
+	return c.doc, nil
+}
+
+// notebookMarkdown 提取 Markdown 单元格中 attachment: 引用的图片，并把本地图片（相对 notebook 所在目录）复制到 dir/images。
+func (c *htmlConverter) notebookMarkdown(md string, attachments map[string]map[string]notebookText, cell int) (string, error) {
+	var firstErr error
+	md = hugoImage.ReplaceAllStringFunc(md, func(m string) string {
+		sub := hugoImage.FindStringSubmatch(m)
+		ref := sub[2]
+		switch name, ok := strings.CutPrefix(ref, "attachment:"); {
+		case ok:
+			for mimeType, data := range attachments[name] {
+				if !strings.HasPrefix(mimeType, "image/") {
+					continue
+				}
+				saved, err := c.notebookImage(fmt.Sprintf("cell%d-%s", cell, name), mimeType, string(data))
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					return m
+				}
+				ref = saved
+				break
+			}
+		case isLocalImageRef(ref):
+			saved, ok := c.images[ref]
+			if !ok {
+				var err error
+				if saved, err = c.copyImage(ref); err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					return m
+				}
+				c.images[ref] = saved
+			}
+			ref = saved
+		}
+		return "![" + sub[1] + "](" + ref + ")"
+	})
+	return md, firstErr
+}
+
+// notebookOutputs 输出代码单元格的结果：连续的 stdout/stderr 合为一个代码块，富输出按图片、Markdown、
+// HTML、纯文本的顺序取第一种支持的格式。
+func (c *htmlConverter) notebookOutputs(sb *strings.Builder, outputs []notebookOutput, cell int) error {
+	var stream strings.Builder
+	flush := func() {
+		if text := strings.Trim(stream.String(), "\n"); strings.TrimSpace(text) != "" {
+			fmt.Fprintf(sb, "```text\n%s\n```\n\n", truncateLines(text, maxNotebookOutputLines))
+		}
+		stream.Reset()
+	}
+	for k, out := range outputs {
+		switch out.OutputType {
+		case "stream":
+			stream.WriteString(ansiEscape.ReplaceAllString(string(out.Text), ""))
+			continue
+		case "error":
+			flush()
+			fmt.Fprintf(sb, "```text\n%s: %s\n```\n\n", out.EName, ansiEscape.ReplaceAllString(out.EValue, ""))
+			continue
+		case "execute_result", "display_data":
+		default:
+			continue
+		}
+		flush()
+		text := func(mimeType string) (string, bool) {
+			raw, ok := out.Data[mimeType]
+			if !ok {
+				return "", false
+			}
+			var t notebookText
+			if err := json.Unmarshal(raw, &t); err != nil {
+				return "", false
+			}
+			return string(t), true
+		}
+		done := false
+		for _, mimeType := range []string{"image/png", "image/jpeg", "image/gif"} {
+			if data, ok := text(mimeType); ok {
+				ref, err := c.notebookImage(fmt.Sprintf("cell%d-%d", cell, k+1), mimeType, data)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(sb, "![](%s)\n\n", ref)
+				done = true
+				break
+			}
+		}
+		if done {
+			continue
+		}
+		if md, ok := text("text/markdown"); ok {
+			sb.WriteString(strings.TrimSpace(md) + "\n\n")
+			continue
+		}
+		if h, ok := text("text/html"); ok {
+			md, err := c.fragment(h)
+			if err != nil {
+				return err
+			}
+			if md != "" {
+				sb.WriteString(md + "\n\n")
+				continue
+			}
+		}
+		if plain, ok := text("text/plain"); ok {
+			plain = strings.Trim(ansiEscape.ReplaceAllString(plain, ""), "\n")
+			if strings.TrimSpace(plain) != "" && !objectRepr.MatchString(strings.TrimSpace(plain)) {
+				fmt.Fprintf(sb, "```text\n%s\n```\n\n", truncateLines(plain, maxNotebookOutputLines))
+			}
+		}
+	}
+	flush()
+	return nil
+}
+
+// notebookImage 解码 base64 的图片输出并保存，name 不含扩展名时按 MIME 类型补上。
+func (c *htmlConverter) notebookImage(name, mimeType, data string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(data), ""))
+	if err != nil {
+		return "", fmt.Errorf("%s output: %w", mimeType, err)
+	}
+	if filepath.Ext(name) == "" {
+		name += map[string]string{"image/png": ".png", "image/jpeg": ".jpg", "image/gif": ".gif"}[mimeType]
+	}
+	return c.saveImage(name, raw)
+}
+
+// truncateLines 保留前 n 行，其余以一行说明代替。
+func truncateLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) <= n {
+		return s
+	}
+	return strings.Join(lines[:n], "\n") + fmt.Sprintf("\n... (%d more lines)", len(lines)-n)
+}
@@ -0,0 +1,258 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 群机器人类型。
+const (
+	NotifyDingTalk = "dingtalk"
+	NotifyFeishu   = "feishu"
+	NotifyWeCom    = "wecom"
+)
+
+// defaultPreviewURL 为未配置 preview_url 时卡片的跳转地址：公众号后台（草稿箱）。
+const defaultPreviewURL = "https://mp.weixin.qq.com/"
+
+// NotifyConfig 配置一个接收发布结果的群机器人。Type 为 dingtalk、feishu 或 wecom，Webhook 为机器人地址；
+// Secret 为钉钉/飞书的加签密钥（可选）；OnlyFailed 为 true 时只通知失败；
+// PreviewURL 为卡片的跳转地址，可包含 {media_id}、{session_id} 占位符，默认打开公众号后台。
+type NotifyConfig struct {
+	Type       string `json:"type"`
+	Webhook    string `json:"webhook"`
+	Secret     string `json:"secret,omitempty"`
+	OnlyFailed bool   `json:"only_failed,omitempty"`
+	PreviewURL string `json:"preview_url,omitempty"`
+}
+
+// PublishNotice 为一次发布（或失败的发布任务）的结果，Error 非空表示失败。
+type PublishNotice struct {
+	Title     string
+	Digest    string
+	MediaID   string
+	SessionID string
+	User      string
+	Account   string
+	Error     string
+	Time      time.Time
+}
+
+// ValidateNotify 检查机器人配置。
+func ValidateNotify(cfgs []NotifyConfig) error {
+	for i, c := range cfgs {
+		switch c.Type {
+		case NotifyDingTalk, NotifyFeishu, NotifyWeCom:
+		default:
+			return fmt.Errorf("notify[%d]: type must be dingtalk, feishu or wecom", i)
+		}
+		if c.Webhook == "" {
+			return fmt.Errorf("notify[%d]: webhook required", i)
+		}
+	}
+	return nil
+}
+
+// Notify 把发布结果以卡片消息推送到所有配置的群机器人，返回各机器人的错误。
+func Notify(ctx context.Context, client *http.Client, cfgs []NotifyConfig, n PublishNotice) error {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	var errs []error
+	for _, c := range cfgs {
+		if c.OnlyFailed && n.Error == "" {
+			continue
+		}
+		if err := notifyOne(ctx, client, c, n); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Type, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func notifyOne(ctx context.Context, client *http.Client, c NotifyConfig, n PublishNotice) error {
+	link := c.PreviewURL
+	if link == "" {
+		link = defaultPreviewURL
+	}
+	link = strings.NewReplacer("{media_id}", url.QueryEscape(n.MediaID), "{session_id}", url.QueryEscape(n.SessionID)).Replace(link)
+
+	webhook := c.Webhook
+	var payload any
+	switch c.Type {
+	case NotifyDingTalk:
+		if c.Secret != "" {
+			var err error
+			if webhook, err = dingTalkSign(webhook, c.Secret, time.Now()); err != nil {
+				return err
+			}
+		}
+		payload = dingTalkCard(n, link)
+	case NotifyFeishu:
+		payload = feishuCard(n, link, c.Secret, time.Now())
+	case NotifyWeCom:
+		payload = weComCard(n, link)
+	default:
+		return fmt.Errorf("unknown notify type %q", c.Type)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	// 钉钉与企业微信返回 errcode，飞书返回 code；非 0 表示发送失败。
+	var data struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		Code    int    `json:"code"`
+		Msg     string `json:"msg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil
+	}
+	if data.ErrCode != 0 {
+		return fmt.Errorf("%d %s", data.ErrCode, data.ErrMsg)
+	}
+	if data.Code != 0 {
+		return fmt.Errorf("%d %s", data.Code, data.Msg)
+	}
+	return nil
+}
+
+// noticeHeadline 返回卡片标题。
+func noticeHeadline(n PublishNotice) string {
+	if n.Error != "" {
+		return "❌ 发布失败：" + n.Title
+	}
+	return "✅ 已发布到草稿箱：" + n.Title
+}
+
+// noticeLines 返回卡片正文各行（Markdown）。
+func noticeLines(n PublishNotice) []string {
+	var lines []string
+	if n.Digest != "" {
+		lines = append(lines, "**摘要**："+n.Digest)
+	}
+	if n.MediaID != "" {
+		lines = append(lines, "**media_id**："+n.MediaID)
+	}
+	if n.User != "" {
+		lines = append(lines, "**提交人**："+n.User)
+	}
+	if n.Account != "" {
+		lines = append(lines, "**公众号**："+n.Account)
+	}
+	if n.Error != "" {
+		lines = append(lines, "**错误**："+n.Error)
+	}
+	lines = append(lines, "**时间**："+n.Time.Local().Format("2006-01-02 15:04:05"))
+	return lines
+}
+
+func dingTalkCard(n PublishNotice, link string) map[string]any {
+	text := "### " + noticeHeadline(n) + "\n\n" + strings.Join(noticeLines(n), "\n\n")
+	return map[string]any{
+		"msgtype": "actionCard",
+		"actionCard": map[string]any{
+			"title":       noticeHeadline(n),
+			"text":        text,
+			"singleTitle": "查看",
+			"singleURL":   link,
+		},
+	}
+}
+
+// dingTalkSign 按钉钉加签规则在 webhook 上附加 timestamp 与 sign。
+func dingTalkSign(webhook, secret string, now time.Time) (string, error) {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return "", err
+	}
+	ts := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "\n" + secret))
+	q := u.Query()
+	q.Set("timestamp", ts)
+	q.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func feishuCard(n PublishNotice, link, secret string, now time.Time) map[string]any {
+	template := "green"
+	if n.Error != "" {
+		template = "red"
+	}
+	payload := map[string]any{
+		"msg_type": "interactive",
+		"card": map[string]any{
+			"header": map[string]any{
+				"title":    map[string]string{"tag": "plain_text", "content": noticeHeadline(n)},
+				"template": template,
+			},
+			"elements": []any{
+				map[string]any{"tag": "markdown", "content": strings.Join(noticeLines(n), "\n")},
+				map[string]any{"tag": "action", "actions": []any{
+					map[string]any{"tag": "button", "type": "primary", "url": link,
+						"text": map[string]string{"tag": "plain_text", "content": "查看"}},
+				}},
+			},
+		},
+	}
+	// 飞书加签：以 timestamp + "\n" + secret 为密钥对空消息做 HMAC-SHA256。
+	if secret != "" {
+		ts := strconv.FormatInt(now.Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(ts+"\n"+secret))
+		payload["timestamp"] = ts
+		payload["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	return payload
+}
+
+func weComCard(n PublishNotice, link string) map[string]any {
+	desc := n.Digest
+	if n.Error != "" {
+		desc = n.Error
+	}
+	var fields []map[string]string
+	if n.MediaID != "" {
+		fields = append(fields, map[string]string{"keyname": "media_id", "value": n.MediaID})
+	}
+	if n.User != "" {
+		fields = append(fields, map[string]string{"keyname": "提交人", "value": n.User})
+	}
+	fields = append(fields, map[string]string{"keyname": "时间", "value": n.Time.Local().Format("2006-01-02 15:04")})
+	return map[string]any{
+		"msgtype": "template_card",
+		"template_card": map[string]any{
+			"card_type":               "text_notice",
+			"main_title":              map[string]string{"title": noticeHeadline(n), "desc": n.Account},
+			"sub_title_text":          desc,
+			"horizontal_content_list": fields,
+			"card_action":             map[string]any{"type": 1, "url": link},
+		},
+	}
+}
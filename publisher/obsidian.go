@@ -0,0 +1,365 @@
+package publisher
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ObsidianVault 为一个 Obsidian 仓库，按文件名索引其中的笔记与附件，用于解析 [[链接]] 与 ![[嵌入]]。
+type ObsidianVault struct {
+	Dir string
+	// files 以小写文件名为键，值为相对 Dir 的路径（以 / 分隔）。
+	files map[string][]string
+}
+
+// ObsidianNote 为一篇笔记，Markdown 已转换为可直接发布的正文。
+type ObsidianNote struct {
+	Path   string
+	Title  string
+	Cover  string
+	Author string
+	Digest string
+	Tags   []string
+	Group  string
+	Order  int
+	// Draft 对应 front matter 的 draft: true 或 publish: false。
+	Draft    bool
+	Markdown string
+}
+
+// OpenObsidianVault 索引 dir 下的全部文件，跳过 .obsidian、.trash 等以 . 开头的目录。
+func OpenObsidianVault(dir string) (*ObsidianVault, error) {
+	v := &ObsidianVault{Dir: dir, files: map[string][]string{}}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		key := strings.ToLower(d.Name())
+		v.files[key] = append(v.files[key], filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Notes 返回仓库中的全部笔记（.md），按路径排序。
+func (v *ObsidianVault) Notes() []string {
+	var notes []string
+	for name, rels := range v.files {
+		if strings.HasSuffix(name, ".md") {
+			for _, rel := range rels {
+				notes = append(notes, filepath.Join(v.Dir, filepath.FromSlash(rel)))
+			}
+		}
+	}
+	sort.Strings(notes)
+	return notes
+}
+
+// Resolve 按 Obsidian 的规则解析链接目标：先按相对仓库根目录的路径查找，再按文件名查找，
+// 同名文件优先与 from（引用它的笔记）同目录的，其次路径最短的。找不到时再按笔记（加 .md）查找。
+func (v *ObsidianVault) Resolve(link, from string) (string, bool) {
+	link = strings.TrimSpace(strings.TrimPrefix(filepath.ToSlash(link), "/"))
+	if link == "" {
+		return "", false
+	}
+	names := []string{link}
+	if !strings.HasSuffix(strings.ToLower(link), ".md") {
+		names = append(names, link+".md")
+	}
+	fromDir := ""
+	if rel, err := filepath.Rel(v.Dir, filepath.Dir(from)); err == nil {
+		fromDir = filepath.ToSlash(rel)
+	}
+	for _, name := range names {
+		candidates := v.files[strings.ToLower(path.Base(name))]
+		var matched []string
+		for _, rel := range candidates {
+			if strings.EqualFold(rel, name) || strings.HasSuffix(strings.ToLower(rel), "/"+strings.ToLower(name)) {
+				matched = append(matched, rel)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		best := matched[0]
+		for _, rel := range matched[1:] {
+			switch {
+			case path.Dir(best) == fromDir:
+			case path.Dir(rel) == fromDir, len(rel) < len(best):
+				best = rel
+			}
+		}
+		return filepath.Join(v.Dir, filepath.FromSlash(best)), true
+	}
+	return "", false
+}
+
+var (
+	obsidianEmbed     = regexp.MustCompile(`!\[\[([^\]\n]+)\]\]`)
+	obsidianLink      = regexp.MustCompile(`\[\[([^\]\n]+)\]\]`)
+	obsidianImage     = regexp.MustCompile(`!\[([^\]\n]*)\]\(\s*<?([^)>\n]+?)>?(?:\s+"[^"\n]*")?\s*\)`)
+	obsidianComment   = regexp.MustCompile(`(?s)%%.*?%%`)
+	obsidianBlockID   = regexp.MustCompile(`(?m)[ \t]+\^[A-Za-z0-9-]+[ \t]*$`)
+	obsidianMark      = regexp.MustCompile(`==([^=\n]+)==`)
+	obsidianCallout   = regexp.MustCompile(`(?m)^(>\s*)\[!(\w+)\][+-]?[ \t]*(.*)$`)
+	obsidianInlineTag = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]+)`)
+	imageExt          = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".bmp": true, ".svg": true}
+)
+
+// ReadNote 读取并转换一篇笔记：![[图片]] 与相对路径的图片改为仓库中的文件路径（找不到时返回错误），
+// [[链接]] 转为链接文字，footnotes 为 true 时另在文末以脚注列出链接的笔记；去掉 %% 注释 %% 与块 ID，
+// ==高亮== 转为加粗，callout 转为带加粗标题的引用。围栏代码块中的内容保持不变。
+func (v *ObsidianVault) ReadNote(notePath string, footnotes bool) (ObsidianNote, error) {
+	note := ObsidianNote{Path: notePath}
+	data, err := os.ReadFile(notePath)
+	if err != nil {
+		return note, err
+	}
+	meta, body, err := splitFrontMatterMap(data)
+	if err != nil {
+		return note, fmt.Errorf("front matter: %w", err)
+	}
+	note.Title = strings.TrimSpace(metaString(meta, "title"))
+	note.Author = metaString(meta, "author")
+	note.Digest = metaString(meta, "description")
+	if note.Digest == "" {
+		note.Digest = metaString(meta, "digest")
+	}
+	note.Group = metaString(meta, "group")
+	if order, ok := meta["order"].(float64); ok {
+		note.Order = int(order)
+	}
+	if draft, ok := meta["draft"].(bool); ok && draft {
+		note.Draft = true
+	}
+	if publish, ok := meta["publish"].(bool); ok && !publish {
+		note.Draft = true
+	}
+	switch tags := meta["tags"].(type) {
+	case []any:
+		for _, t := range tags {
+			if s, ok := t.(string); ok {
+				note.Tags = append(note.Tags, strings.TrimPrefix(s, "#"))
+			}
+		}
+	case string:
+		for _, t := range strings.FieldsFunc(tags, func(r rune) bool { return r == ',' || r == ' ' }) {
+			note.Tags = append(note.Tags, strings.TrimPrefix(t, "#"))
+		}
+	}
+	for _, key := range []string{"cover", "banner", "image"} {
+		if ref := metaString(meta, key); ref != "" {
+			ref = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(ref, "!"), "[["), "]]")
+			if note.Cover, err = v.resolveImage(ref, notePath); err != nil {
+				return note, fmt.Errorf("front matter %s: %w", key, err)
+			}
+			break
+		}
+	}
+
+	var refs []string
+	var convErr error
+	convert := func(text string) string {
+		text = obsidianComment.ReplaceAllString(text, "")
+		for _, m := range obsidianInlineTag.FindAllStringSubmatch(text, -1) {
+			note.Tags = append(note.Tags, m[1])
+		}
+		text = obsidianImage.ReplaceAllStringFunc(text, func(m string) string {
+			sub := obsidianImage.FindStringSubmatch(m)
+			if !isLocalImageRef(sub[2]) {
+				return m
+			}
+			file, err := v.resolveImage(sub[2], notePath)
+			if err != nil && convErr == nil {
+				convErr = err
+			}
+			return "![" + sub[1] + "](" + file + ")"
+		})
+		text = obsidianEmbed.ReplaceAllStringFunc(text, func(m string) string {
+			target, label, _ := strings.Cut(obsidianEmbed.FindStringSubmatch(m)[1], "|")
+			if !imageExt[strings.ToLower(path.Ext(target))] {
+				// 嵌入其他笔记时按链接处理。
+				return v.linkText(m[1:], notePath, footnotes, &refs)
+			}
+			file, err := v.resolveImage(target, notePath)
+			if err != nil && convErr == nil {
+				convErr = err
+			}
+			// ![[图片|300]] 中的数字为显示宽度，不作为说明文字。
+			if strings.Trim(label, "0123456789x") == "" {
+				label = ""
+			}
+			return "![" + label + "](" + file + ")"
+		})
+		text = obsidianLink.ReplaceAllStringFunc(text, func(m string) string {
+			return v.linkText(m, notePath, footnotes, &refs)
+		})
+		text = obsidianBlockID.ReplaceAllString(text, "")
+		text = obsidianMark.ReplaceAllString(text, "**$1**")
+		return obsidianCallout.ReplaceAllStringFunc(text, func(m string) string {
+			sub := obsidianCallout.FindStringSubmatch(m)
+			title := strings.TrimSpace(sub[3])
+			if title == "" {
+				title = strings.ToUpper(sub[2][:1]) + strings.ToLower(sub[2][1:])
+			}
+			return sub[1] + "**" + title + "**"
+		})
+	}
+	md := convertOutsideCode(string(body), convert)
+	if convErr != nil {
+		return note, convErr
+	}
+	if footnotes && len(refs) > 0 {
+		md = strings.TrimRight(md, "\n") + "\n\n---\n\n"
+		for i, ref := range refs {
+			md += fmt.Sprintf("%d. %s\n", i+1, ref)
+		}
+	}
+	note.Markdown = md
+
+	if note.Title == "" {
+		for _, line := range strings.Split(md, "\n") {
+			if t, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+				note.Title = strings.TrimSpace(t)
+				break
+			}
+		}
+	}
+	if note.Title == "" {
+		note.Title = strings.TrimSuffix(filepath.Base(notePath), filepath.Ext(notePath))
+	}
+	return note, nil
+}
+
+// linkText 把 [[目标#标题|别名]] 转为显示的文字：有别名时为别名，否则为“目标 > 标题”。
+// footnotes 为 true 时在文字后加编号，refs 收集脚注内容（链接笔记的标题）；同一笔记内的链接不加脚注。
+func (v *ObsidianVault) linkText(m, from string, footnotes bool, refs *[]string) string {
+	target, alias, hasAlias := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(m, "[["), "]]"), "|")
+	target, heading, _ := strings.Cut(target, "#")
+	if strings.HasPrefix(heading, "^") {
+		heading = ""
+	}
+	name, title := "", ""
+	if target = strings.TrimSpace(target); target != "" {
+		name = path.Base(strings.TrimSuffix(filepath.ToSlash(target), ".md"))
+		title = name
+		if file, ok := v.Resolve(target, from); ok {
+			if t := noteTitle(file); t != "" {
+				title = t
+			}
+		}
+	}
+	display := joinHeading(name, heading)
+	if hasAlias {
+		display = strings.TrimSpace(alias)
+	}
+	if !footnotes || target == "" {
+		return display
+	}
+	ref := joinHeading(title, heading)
+	n := 0
+	for i, r := range *refs {
+		if r == ref {
+			n = i + 1
+		}
+	}
+	if n == 0 {
+		*refs = append(*refs, ref)
+		n = len(*refs)
+	}
+	return fmt.Sprintf("%s\\[%d\\]", display, n)
+}
+
+func joinHeading(name, heading string) string {
+	switch {
+	case heading == "":
+		return name
+	case name == "":
+		return heading
+	}
+	return name + " > " + heading
+}
+
+// noteTitle 返回笔记 front matter 中的 title，没有时为空。
+func noteTitle(file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	meta, _, err := splitFrontMatterMap(data)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(metaString(meta, "title"))
+}
+
+// resolveImage 返回图片的文件路径：依次相对笔记所在目录、仓库根目录，最后按文件名在仓库中查找。
+func (v *ObsidianVault) resolveImage(ref, from string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if !isLocalImageRef(ref) {
+		return ref, nil
+	}
+	if unescaped, err := url.PathUnescape(ref); err == nil {
+		ref = unescaped
+	}
+	if filepath.IsAbs(ref) {
+		if _, err := os.Stat(ref); err == nil {
+			return ref, nil
+		}
+	}
+	for _, dir := range []string{filepath.Dir(from), v.Dir} {
+		p := filepath.Join(dir, filepath.FromSlash(ref))
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p, nil
+		}
+	}
+	if p, ok := v.Resolve(ref, from); ok {
+		return p, nil
+	}
+	return "", fmt.Errorf("image %q not found in vault %s", ref, v.Dir)
+}
+
+// convertOutsideCode 对围栏代码块（``` 或 ~~~）以外的文本调用 convert，代码块原样保留。
+func convertOutsideCode(md string, convert func(string) string) string {
+	var out, text strings.Builder
+	fence := ""
+	for _, line := range strings.SplitAfter(md, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+			out.WriteString(convert(text.String()))
+			text.Reset()
+			fence = trimmed[:3]
+			out.WriteString(line)
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			out.WriteString(line)
+		default:
+			text.WriteString(line)
+		}
+	}
+	out.WriteString(convert(text.String()))
+	return out.String()
+}
@@ -10,13 +10,16 @@ import (
 	"log"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/yuin/goldmark"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
 )
 
 const (
@@ -28,10 +31,96 @@ const (
 
 // Config holds the WeChat app credentials.
 type Config struct {
-	AppID      string     `json:"app_id"`
-	AppSecret  string     `json:"app_secret"`
-	LLM        *LLMConfig `json:"llm,omitempty"`
-	ServerAddr string     `json:"server_addr,omitempty"`
+	AppID      string        `json:"app_id"`
+	AppSecret  string        `json:"app_secret"`
+	LLM        *LLMConfig    `json:"llm,omitempty"`
+	ServerAddr string        `json:"server_addr,omitempty"`
+	Cover      *CoverConfig  `json:"cover,omitempty"`
+	Image      *ImageConfig  `json:"image,omitempty"`
+	Budget     *BudgetConfig `json:"budget,omitempty"`
+	PromptsDir string        `json:"prompts_dir,omitempty"`
+	StylesDir  string        `json:"styles_dir,omitempty"`
+	SeriesDir  string        `json:"series_dir,omitempty"`
+	Search     *SearchConfig `json:"search,omitempty"`
+	// CalendarPath 为内容日历文件，默认 calendar.json。
+	CalendarPath string `json:"calendar_path,omitempty"`
+	// AllowHTML 为 true 时允许稿件包含原始 HTML，并在发布时原样保留（默认会被过滤）。
+	AllowHTML bool `json:"allow_html,omitempty"`
+	// CrossPost 为命令行发布成功后另外生成的跨平台 HTML 格式（zhihu、juejin、zsxq），可用 --cross-post 覆盖。
+	CrossPost []string `json:"cross_post,omitempty"`
+	// History 配置修订历史压缩阈值（可选）。
+	History *HistoryConfig `json:"history,omitempty"`
+	// Sensitive 配置敏感词检查（可选），未配置时使用内置词表且只标注不改写。
+	Sensitive *SensitiveConfig `json:"sensitive,omitempty"`
+	// RecordReasoning 为 true 时在修订历史中保存推理模型的思考过程（调试用）。
+	RecordReasoning bool `json:"record_reasoning,omitempty"`
+	// SessionDB 为 session 持久化文件（bbolt），为空时 session 只保存在内存中，重启即丢失。
+	SessionDB string `json:"session_db,omitempty"`
+	// PublishHistoryPath 为发布记录文件（JSON Lines），默认 publishes.jsonl。
+	PublishHistoryPath string `json:"publish_history_path,omitempty"`
+	// SchedulePath 为定时发布文件，默认 schedule.json。
+	SchedulePath string `json:"schedule_path,omitempty"`
+	// MassSendPath 为群发申请与记录文件，默认 mass_sends.json。
+	MassSendPath string `json:"mass_send_path,omitempty"`
+	// Recurring 为周期性自动写作任务（可选）。
+	Recurring []RecurringConfig `json:"recurring,omitempty"`
+	// Feeds 为订阅源汇总任务（可选）：抓取 RSS/Atom 新条目并汇总成文章。
+	Feeds []FeedConfig `json:"feeds,omitempty"`
+	// FeedStatePath 为订阅源状态文件，默认 feeds.json。
+	FeedStatePath string `json:"feed_state_path,omitempty"`
+	// Analytics 为图文阅读数据的定时采集（可选），AnalyticsPath 为保存的文件，默认 analytics.json。
+	Analytics     *AnalyticsConfig `json:"analytics,omitempty"`
+	AnalyticsPath string           `json:"analytics_path,omitempty"`
+	// Ingest 配置 POST /api/ingest：CI 推送 Markdown 或 Git 仓库的 push webhook 自动生成稿件（可选）。
+	Ingest *IngestConfig `json:"ingest,omitempty"`
+	// Bots 配置 Telegram / 企业微信机器人（可选），在聊天中完成生成、修订与发布。
+	Bots *BotConfig `json:"bots,omitempty"`
+	// Mail 配置邮件投稿（可选）：检查 IMAP 收件箱，把允许的发件人发来的 Markdown 转成草稿并回信。
+	Mail *MailConfig `json:"mail,omitempty"`
+	// Auth 启用多用户登录（可选），未配置时所有人共用全部 session。
+	Auth *AuthConfig `json:"auth,omitempty"`
+	// Notify 为发布后推送结果卡片的钉钉/飞书/企业微信群机器人（可选）。
+	Notify []NotifyConfig `json:"notify,omitempty"`
+	// TemplateNotify 为发布成功后以公众号模板消息或订阅通知提醒的管理员（可选）。
+	TemplateNotify *TemplateNotifyConfig `json:"template_notify,omitempty"`
+	// Health 配置 /readyz 的可选依赖检查。
+	Health *HealthConfig `json:"health,omitempty"`
+	// RateLimit 限制生成与发布接口的请求频率（可选），未配置时不限制。
+	RateLimit *RateLimitConfig `json:"rate_limit,omitempty"`
+	// ShutdownTimeout 为收到退出信号后等待进行中请求与发布任务完成的秒数，默认 30。
+	ShutdownTimeout int `json:"shutdown_timeout,omitempty"`
+	// CORS 配置允许跨域调用接口的来源（可选），未配置时只允许同源访问。
+	CORS *CORSConfig `json:"cors,omitempty"`
+	// ContentSecurityPolicy 覆盖 Web 界面的 Content-Security-Policy 响应头（可选）。
+	ContentSecurityPolicy string `json:"content_security_policy,omitempty"`
+	// TLS 让服务直接提供 HTTPS（可选），可使用证书文件或自动申请 Let's Encrypt 证书。
+	TLS *TLSConfig `json:"tls,omitempty"`
+	// StaticDir 为 Web 界面文件目录（可选），未配置时使用编译时嵌入的前端。
+	StaticDir string `json:"static_dir,omitempty"`
+	// BasePath 为应用挂载的 URL 前缀（可选，如 /wechat/），用于部署在反向代理的子路径下。
+	BasePath string `json:"base_path,omitempty"`
+	// Uploads 限制上传图片的大小与尺寸（可选）。
+	Uploads *UploadConfig `json:"uploads,omitempty"`
+	// ResumableDir 保存未完成的分片上传，默认 resumable；每个服务实例使用其中单独的子目录。
+	ResumableDir string `json:"resumable_dir,omitempty"`
+	// Storage 为上传文件的持久存储（可选），未配置时只保存在本地 uploads/ 目录。
+	Storage *StorageConfig `json:"storage,omitempty"`
+	// Sessions 控制内存中 session 的过期时间、数量上限与修订轮数上限（可选）。
+	Sessions *SessionConfig `json:"sessions,omitempty"`
+	// AuditLogPath 为审计日志文件（JSON Lines，只追加），默认 audit.jsonl。
+	AuditLogPath string `json:"audit_log_path,omitempty"`
+	// Author 为默认作者，发布时未指定作者则使用该值。
+	Author string `json:"author,omitempty"`
+	// Style 为默认写作风格预设，新建稿件未指定风格时使用；为空时使用 life-rational。
+	Style string `json:"style,omitempty"`
+	// LockPath 为命令行发布持有的锁文件，避免定时任务等并发发布触发频率限制；默认见 PublishLockPath。
+	LockPath string `json:"lock_path,omitempty"`
+	// Feishu 为 import feishu 读取飞书云文档使用的应用凭证（可选）。
+	Feishu *FeishuConfig `json:"feishu,omitempty"`
+	// AccountType 为发布目标：official（默认）发布到公众号草稿箱，wecom 以企业微信应用消息发送，此时不需要 app_id。
+	AccountType string `json:"account_type,omitempty"`
+	// WeCom 为 account_type 为 wecom 时使用的企业微信应用与接收范围。
+	WeCom *WeComConfig `json:"wecom,omitempty"`
 }
 
 // LLMConfig 预留给生成模块的模型配置（可选，不影响发布流程）。
@@ -40,6 +129,354 @@ type LLMConfig struct {
 	Model    string `json:"model,omitempty"`
 	APIKey   string `json:"api_key,omitempty"`
 	BaseURL  string `json:"base_url,omitempty"`
+	// VisionModel 可选，用于上传图片的 alt/图注生成。
+	VisionModel string `json:"vision_model,omitempty"`
+	// Azure OpenAI：deployment 为部署名（为空时用 model），auth 可选 key / aad。
+	Deployment string `json:"deployment,omitempty"`
+	APIVersion string `json:"api_version,omitempty"`
+	AuthMode   string `json:"auth,omitempty"`
+	// 采样参数（可选），未设置时使用模型默认值；可被 session 请求覆盖。
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	MaxTokens        *int     `json:"max_tokens,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	// JSONMode 表示服务商支持 JSON 模式（response_format: json_object 或 Gemini 的 responseMimeType），生成稿件时使用；
+	// 未设置时 openai（未配置 base_url）、azure、gemini 开启，其他 OpenAI 兼容服务商关闭。不支持时返回 400 会自动去掉后重试一次。
+	JSONMode *bool `json:"json_mode,omitempty"`
+	// Fallbacks 为按顺序尝试的备用模型；主模型超时/429/5xx 时自动切换。
+	Fallbacks []LLMConfig `json:"fallbacks,omitempty"`
+	// Timeout 为单次调用该模型的超时秒数（可选）；未设置时若还有备用模型，与其平分调用方剩余的时间。
+	Timeout int `json:"timeout,omitempty"`
+	// Fixtures 仅 provider 为 mock 时使用：预设响应文件（JSON/YAML），可模拟延迟与错误。
+	Fixtures string `json:"fixtures,omitempty"`
+}
+
+// CoverConfig 为自动生成封面提供默认样式（可选）。
+type CoverConfig struct {
+	FontPath   string  `json:"font_path,omitempty"`
+	FontSize   float64 `json:"font_size,omitempty"`
+	Color      string  `json:"color,omitempty"`
+	BgColor    string  `json:"bg_color,omitempty"`
+	GradientTo string  `json:"gradient_to,omitempty"`
+}
+
+// ImageConfig 配置 AI 封面使用的文生图模型；api_key/base_url 为空时沿用 llm 配置。
+type ImageConfig struct {
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	APIKey   string `json:"api_key,omitempty"`
+	BaseURL  string `json:"base_url,omitempty"`
+	Size     string `json:"size,omitempty"`
+}
+
+// SearchConfig 配置写作前的联网检索（bing / serpapi / tavily）。
+type SearchConfig struct {
+	Provider string `json:"provider,omitempty"`
+	APIKey   string `json:"api_key,omitempty"`
+	BaseURL  string `json:"base_url,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+	// Auto 为 true 时每个新 session 生成前都自动检索。
+	Auto bool `json:"auto,omitempty"`
+}
+
+// BudgetConfig 限制模型用量，0 表示不限制；user_daily_* 为启用登录后每个用户每天的限额；费用按每千 token 单价估算。
+type BudgetConfig struct {
+	SessionMaxTokens     int     `json:"session_max_tokens,omitempty"`
+	SessionMaxCost       float64 `json:"session_max_cost,omitempty"`
+	DailyMaxTokens       int     `json:"daily_max_tokens,omitempty"`
+	DailyMaxCost         float64 `json:"daily_max_cost,omitempty"`
+	UserDailyMaxTokens   int     `json:"user_daily_max_tokens,omitempty"`
+	UserDailyMaxCost     float64 `json:"user_daily_max_cost,omitempty"`
+	PricePer1KPrompt     float64 `json:"price_per_1k_prompt,omitempty"`
+	PricePer1KCompletion float64 `json:"price_per_1k_completion,omitempty"`
+}
+
+// HistoryConfig 控制修订历史压缩：未压缩的修改意见超过 max_turns 条或 max_chars 字时，
+// 把除最近 keep_recent 条以外的意见概括为“编辑历史摘要”；0 表示使用默认值（10 / 4 / 3000）。
+type HistoryConfig struct {
+	MaxTurns   int `json:"max_turns,omitempty"`
+	KeepRecent int `json:"keep_recent,omitempty"`
+	MaxChars   int `json:"max_chars,omitempty"`
+}
+
+// SensitiveConfig 配置生成后的敏感词检查：path 为额外词表（每行一个，# 开头为注释），
+// disable_builtin 关闭内置词表，auto_rephrase 为 true 时命中后自动请模型改写相关语句。
+type SensitiveConfig struct {
+	Path           string `json:"path,omitempty"`
+	DisableBuiltin bool   `json:"disable_builtin,omitempty"`
+	AutoRephrase   bool   `json:"auto_rephrase,omitempty"`
+}
+
+// AuthConfig 配置账号密码登录：users_path 为用户文件（默认 users.json，用 user 子命令管理），
+// secret 为登录令牌签名密钥（为空时每次启动随机生成，重启后需重新登录），session_hours 为登录有效期（默认 168）。
+type AuthConfig struct {
+	UsersPath    string `json:"users_path,omitempty"`
+	Secret       string `json:"secret,omitempty"`
+	SessionHours int    `json:"session_hours,omitempty"`
+}
+
+// HealthConfig 配置 /readyz 的可选检查：check_wechat 获取一次 access_token，check_llm 向主模型发送一个极短请求；
+// 两者结果缓存 cache_seconds 秒（默认 600），避免负载均衡探活频繁调用外部接口（access_token 每天有获取次数限制）。
+type HealthConfig struct {
+	CheckWeChat  bool `json:"check_wechat,omitempty"`
+	CheckLLM     bool `json:"check_llm,omitempty"`
+	CacheSeconds int  `json:"cache_seconds,omitempty"`
+}
+
+// RateLimitConfig 按令牌桶限制接口请求频率：sessions 作用于创建 session 与生成/修订等修改请求，publish 作用于发布；
+// trust_proxy 为 true 时按 X-Real-IP / X-Forwarded-For 识别客户端 IP（部署在 nginx 等反向代理之后时开启）。
+type RateLimitConfig struct {
+	Sessions   *RateRule `json:"sessions,omitempty"`
+	Publish    *RateRule `json:"publish,omitempty"`
+	TrustProxy bool      `json:"trust_proxy,omitempty"`
+}
+
+// RateRule 为一组限额：per_ip 为每个 IP、per_key 为每个调用方（登录用户，或绑定模式下的 API key）每分钟的请求数，
+// burst 为允许的突发请求数（默认同每分钟请求数）；0 表示不限制该维度。
+type RateRule struct {
+	PerIP  float64 `json:"per_ip,omitempty"`
+	PerKey float64 `json:"per_key,omitempty"`
+	Burst  int     `json:"burst,omitempty"`
+}
+
+// CORSConfig 配置跨域访问：allowed_origins 为允许的来源（如 https://admin.example.com，"*" 表示任意来源），
+// allow_credentials 为 true 时允许携带 cookie 与 Authorization 头（不能与 "*" 同时使用），max_age 为预检结果缓存秒数（默认 600）。
+type CORSConfig struct {
+	AllowedOrigins   []string `json:"allowed_origins"`
+	AllowCredentials bool     `json:"allow_credentials,omitempty"`
+	MaxAge           int      `json:"max_age,omitempty"`
+}
+
+// TLSConfig 配置 HTTPS：cert_file/key_file 为证书与私钥路径；或设置 autocert_domains 通过 ACME（Let's Encrypt）
+// 自动申请与续期证书，证书缓存在 autocert_cache_dir（默认 certs），autocert_email 用于接收到期提醒。
+// http_addr 为可选的 HTTP 监听地址（如 :80），把请求重定向到 HTTPS 并响应 ACME http-01 验证。
+type TLSConfig struct {
+	CertFile         string   `json:"cert_file,omitempty"`
+	KeyFile          string   `json:"key_file,omitempty"`
+	AutocertDomains  []string `json:"autocert_domains,omitempty"`
+	AutocertEmail    string   `json:"autocert_email,omitempty"`
+	AutocertCacheDir string   `json:"autocert_cache_dir,omitempty"`
+	HTTPAddr         string   `json:"http_addr,omitempty"`
+}
+
+// UploadConfig 限制上传的图片：max_size_mb 为单个文件大小上限（默认 10，与公众号图片素材上限一致），
+// max_width/max_height 为像素尺寸上限（默认 8000）。上传只接受 JPEG、PNG 与 GIF；
+// 分片上传另接受 MP4 视频，max_video_mb 为视频大小上限（默认 200）。
+type UploadConfig struct {
+	MaxSizeMB  int `json:"max_size_mb,omitempty"`
+	MaxWidth   int `json:"max_width,omitempty"`
+	MaxHeight  int `json:"max_height,omitempty"`
+	MaxVideoMB int `json:"max_video_mb,omitempty"`
+}
+
+// ValidateUploads 检查上传限制不为负数。
+func ValidateUploads(cfg *UploadConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.MaxSizeMB < 0 || cfg.MaxWidth < 0 || cfg.MaxHeight < 0 || cfg.MaxVideoMB < 0 {
+		return errors.New("uploads: max_size_mb, max_width, max_height and max_video_mb must not be negative")
+	}
+	return nil
+}
+
+// StorageConfig 为上传图片与封面的持久存储：type 为 local（dir 指向持久卷）、s3、oss 或 cos；
+// 远程存储需要 bucket、access_key 与 secret_key，region 用于推导默认 endpoint（S3 兼容服务与私有部署可直接配置 endpoint），
+// prefix 为对象键的前缀，path_style 让 S3 请求使用 endpoint/bucket/key 形式（MinIO 等需要）。
+type StorageConfig struct {
+	Type      string `json:"type"`
+	Dir       string `json:"dir,omitempty"`
+	Bucket    string `json:"bucket,omitempty"`
+	Region    string `json:"region,omitempty"`
+	Endpoint  string `json:"endpoint,omitempty"`
+	AccessKey string `json:"access_key,omitempty"`
+	SecretKey string `json:"secret_key,omitempty"`
+	Prefix    string `json:"prefix,omitempty"`
+	PathStyle bool   `json:"path_style,omitempty"`
+}
+
+// ValidateStorage 检查存储类型及其必填项。
+func ValidateStorage(cfg *StorageConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	switch cfg.Type {
+	case "local":
+		if cfg.Dir == "" {
+			return errors.New("storage: local requires dir")
+		}
+	case "s3", "oss", "cos":
+		if cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+			return fmt.Errorf("storage: %s requires bucket, access_key and secret_key", cfg.Type)
+		}
+		if cfg.Region == "" && cfg.Endpoint == "" {
+			return fmt.Errorf("storage: %s requires region or endpoint", cfg.Type)
+		}
+	default:
+		return fmt.Errorf("storage: unknown type %q (want local, s3, oss or cos)", cfg.Type)
+	}
+	return nil
+}
+
+// SessionConfig 控制内存中的 session：ttl_minutes 为无访问或心跳后过期的时间（默认 5），
+// janitor_seconds 为清理过期 session 的间隔（默认 60），max_sessions 为内存中同时保留的 session 上限（0 不限制），
+// eviction 为达到上限时的策略：lru（默认）移出最久未访问的 session，reject 拒绝新建并返回 429；
+// max_history 为每个 session 的修订轮数上限（0 不限制），达到后修订返回 429；
+// bind_owner 为 true 且未启用登录时，session 归属创建它的调用方（X-API-Key 头或浏览器 cookie），其他调用方无法访问。
+type SessionConfig struct {
+	TTLMinutes     int    `json:"ttl_minutes,omitempty"`
+	JanitorSeconds int    `json:"janitor_seconds,omitempty"`
+	MaxSessions    int    `json:"max_sessions,omitempty"`
+	Eviction       string `json:"eviction,omitempty"`
+	MaxHistory     int    `json:"max_history,omitempty"`
+	BindOwner      bool   `json:"bind_owner,omitempty"`
+}
+
+// ValidateSessions 检查 session 配置不为负数且淘汰策略有效。
+func ValidateSessions(cfg *SessionConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.TTLMinutes < 0 || cfg.JanitorSeconds < 0 || cfg.MaxSessions < 0 || cfg.MaxHistory < 0 {
+		return errors.New("sessions: ttl_minutes, janitor_seconds, max_sessions and max_history must not be negative")
+	}
+	switch cfg.Eviction {
+	case "", "lru", "reject":
+	default:
+		return fmt.Errorf("sessions: unknown eviction %q (want lru or reject)", cfg.Eviction)
+	}
+	return nil
+}
+
+// ValidateTLS 检查 HTTPS 配置：证书文件与 autocert 二选一，证书与私钥须同时配置。
+func ValidateTLS(cfg *TLSConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	files := cfg.CertFile != "" || cfg.KeyFile != ""
+	switch {
+	case files && len(cfg.AutocertDomains) > 0:
+		return errors.New("tls: use either cert_file/key_file or autocert_domains, not both")
+	case files && (cfg.CertFile == "" || cfg.KeyFile == ""):
+		return errors.New("tls: cert_file and key_file must be set together")
+	case !files && len(cfg.AutocertDomains) == 0:
+		return errors.New("tls: set cert_file/key_file or autocert_domains")
+	}
+	for i, d := range cfg.AutocertDomains {
+		if d == "" || strings.ContainsAny(d, "/:* ") {
+			return fmt.Errorf("tls: autocert_domains[%d] %q must be a plain host name", i, d)
+		}
+	}
+	return nil
+}
+
+// ValidateCORS 检查跨域配置：来源须为 scheme://host[:port]，且携带凭据时不能允许任意来源。
+func ValidateCORS(cfg *CORSConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	for i, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			if cfg.AllowCredentials {
+				return errors.New("cors: allowed_origins \"*\" cannot be combined with allow_credentials")
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return fmt.Errorf("cors: allowed_origins[%d] %q must look like https://example.com", i, origin)
+		}
+	}
+	return nil
+}
+
+// RecurringConfig 为一个周期性写作任务：按 cron 定时用 topic 模板生成文章，
+// mode 为 publish 时直接发布到草稿箱，为 review（默认）时只生成稿件等待人工审核；
+// 两种模式都会在设置了 notify_url 时推送通知。
+type RecurringConfig struct {
+	Name string `json:"name"`
+	Cron string `json:"cron"`
+	// Topic 为 Go 模板，可用 {{.Date}}、{{.Weekday}}、{{.Week}}、{{.Month}}、{{.Run}}（第几次执行）。
+	Topic    string   `json:"topic"`
+	Outline  []string `json:"outline,omitempty"`
+	Words    int      `json:"words,omitempty"`
+	Style    string   `json:"style,omitempty"`
+	Audience string   `json:"audience,omitempty"`
+	Tone     string   `json:"tone,omitempty"`
+	SeriesID string   `json:"series_id,omitempty"`
+	Research bool     `json:"research,omitempty"`
+	Mode     string   `json:"mode,omitempty"`
+	// 发布参数：cover_path 与 ai_cover 至少设置一个（mode 为 publish 时必填）。
+	CoverPath string `json:"cover_path,omitempty"`
+	AICover   bool   `json:"ai_cover,omitempty"`
+	Author    string `json:"author,omitempty"`
+	// NotifyURL 为通知地址，每次执行后 POST 一条 JSON。
+	NotifyURL string `json:"notify_url,omitempty"`
+	// Owner 为生成的 session 所属用户（启用登录时），为空则只有管理员可见。
+	Owner string `json:"owner,omitempty"`
+}
+
+// IngestConfig 配置 POST /api/ingest：接收推送的 Markdown（或 Git push webhook 中改动的 Markdown 文件），
+// mode 为 review（默认）时保存为 session 等待审核与修改，为 publish 时直接发布到草稿箱。
+type IngestConfig struct {
+	// Secret 为推送密钥：请求带 X-Ingest-Token 头，或 GitHub/Gitea 以其签名（X-Hub-Signature-256）、GitLab 以 X-Gitlab-Token 发送。
+	// 设置后不带密钥的请求须登录（启用登录时）或被拒绝；Git push webhook 必须设置。
+	Secret string `json:"secret,omitempty"`
+	// Owner 为用密钥推送时生成的 session 所属用户（启用登录时），为空则只有管理员可见。
+	Owner string `json:"owner,omitempty"`
+	// 以下为默认值，推送的请求可以覆盖；front matter 中的 cover、author 优先于这里的设置。
+	Mode      string `json:"mode,omitempty"`
+	CoverPath string `json:"cover_path,omitempty"`
+	AICover   bool   `json:"ai_cover,omitempty"`
+	Author    string `json:"author,omitempty"`
+	// Polish 为 true 时 review 模式的稿件在保存后自动润色一遍。
+	Polish bool `json:"polish,omitempty"`
+	// Paths 限定 Git push 中处理的文件（path.Match 模式，以 / 结尾表示目录下的全部文件），默认为全部 .md 文件。
+	Paths []string `json:"paths,omitempty"`
+	// Branch 非空时只处理推送到该分支的提交。
+	Branch string `json:"branch,omitempty"`
+	// RawURL 为读取仓库文件的地址模板，可用 {repo}、{sha}、{path}；默认按 GitHub、Gitea、GitLab 的 raw 地址生成。
+	RawURL string `json:"raw_url,omitempty"`
+	// Token 为读取私有仓库文件的访问令牌，以 Authorization: Bearer 发送。
+	Token string `json:"token,omitempty"`
+}
+
+// BotConfig 配置聊天机器人入口：在 Telegram 或企业微信中发送主题生成稿件、回复修改意见修订，最后 /publish 发布。
+type BotConfig struct {
+	Telegram *TelegramBotConfig `json:"telegram,omitempty"`
+	WeCom    *WeComBotConfig    `json:"wecom,omitempty"`
+	// Users 为允许使用机器人的聊天账号（telegram:<用户 ID> 或 wecom:<UserID>）到 Web 用户名的映射；
+	// 启用登录时按该用户的角色执行审核流程，未启用登录时值可以为空。未列出的账号收到自己的账号 ID。
+	Users map[string]string `json:"users"`
+	// 以下为新稿件的默认值。
+	Style     string `json:"style,omitempty"`
+	Words     int    `json:"words,omitempty"`
+	CoverPath string `json:"cover_path,omitempty"`
+	AICover   bool   `json:"ai_cover,omitempty"`
+	Author    string `json:"author,omitempty"`
+	// PreviewURL 为稿件的网页地址模板，可用 {session_id}，发送稿件时附上。
+	PreviewURL string `json:"preview_url,omitempty"`
+	// StatePath 保存各聊天账号当前的 session，默认 bots.json。
+	StatePath string `json:"state_path,omitempty"`
+}
+
+// TelegramBotConfig 为 Telegram 机器人，通过 getUpdates 长轮询接收消息，不需要公网地址。
+type TelegramBotConfig struct {
+	Token string `json:"token"`
+	// APIBase 为 Bot API 地址，默认 https://api.telegram.org，网络受限时可指向反向代理。
+	APIBase string `json:"api_base,omitempty"`
+}
+
+// WeComBotConfig 为企业微信自建应用，消息经回调地址 /api/bots/wecom 接收（需在应用中设置 Token 与 EncodingAESKey）。
+type WeComBotConfig struct {
+	CorpID         string `json:"corp_id"`
+	AgentID        int    `json:"agent_id"`
+	Secret         string `json:"secret"`
+	Token          string `json:"token"`
+	EncodingAESKey string `json:"encoding_aes_key"`
+	// APIBase 默认 https://qyapi.weixin.qq.com。
+	APIBase string `json:"api_base,omitempty"`
 }
 
 // PublishParams describes the content to be published.
@@ -49,6 +486,25 @@ type PublishParams struct {
 	CoverPath    string
 	Author       string
 	Digest       string
+	// Progress 可选，在各发布阶段回调（token/images/html/cover/draft/done）。
+	Progress func(stage string)
+	// ImageProgress 可选，每上传完一张正文本地图片回调一次（done 从 1 开始）。
+	ImageProgress func(done, total int)
+	// ImageUploaded 可选，每上传完一张正文本地图片回调其本地路径与微信图片地址。
+	ImageUploaded func(path, url string)
+	// Markdown 非空时作为正文，不再读取 MarkdownPath；相对路径的图片仍按 MarkdownPath 所在目录查找。
+	Markdown string
+	// Manifest 可选，记录已上传的正文图片与封面，续传时复用（见 PublishManifest）。
+	Manifest *PublishManifest
+	// UpdateMediaID 非空时更新该草稿的第 UpdateIndex 篇文章而不是新建草稿，media_id 保持不变。
+	UpdateMediaID string
+	UpdateIndex   int
+}
+
+func (params PublishParams) report(stage string) {
+	if params.Progress != nil {
+		params.Progress(stage)
+	}
 }
 
 type accessTokenResp struct {
@@ -89,6 +545,24 @@ func (e *wechatAPIError) tokenExpired() bool {
 	return isTokenExpiredCode(e.Code)
 }
 
+// WeChatErrorCode 返回 err 中微信接口的 errcode，不是微信接口错误时返回 0。
+func WeChatErrorCode(err error) int {
+	var apiErr *wechatAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return 0
+}
+
+// WeChatErrorMessage 返回 err 中微信接口的 errmsg，不是微信接口错误时返回空字符串。
+func WeChatErrorMessage(err error) string {
+	var apiErr *wechatAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Msg
+	}
+	return ""
+}
+
 // isTokenExpiredCode returns true for access_token related codes.
 func isTokenExpiredCode(code int) bool {
 	switch code {
@@ -119,6 +593,7 @@ type addDraftPayload struct {
 type Publisher struct {
 	cfg         Config
 	client      *http.Client
+	tokenMu     sync.Mutex
 	accessToken string
 	verbose     bool
 	logger      *log.Logger
@@ -126,8 +601,8 @@ type Publisher struct {
 
 // New creates a Publisher; access_token is fetched per publish for freshness.
 func New(cfg Config, client *http.Client, verbose bool, logger *log.Logger) (*Publisher, error) {
-	if cfg.AppID == "" || cfg.AppSecret == "" {
-		return nil, errors.New("config must include app_id and app_secret")
+	if err := ValidateAccount(cfg); err != nil {
+		return nil, err
 	}
 	if client == nil {
 		client = &http.Client{Timeout: 60 * time.Second}
@@ -155,7 +630,7 @@ func (p *Publisher) infof(format string, args ...interface{}) {
 // withTokenRefreshString executes a WeChat API call that returns a string result.
 // If it receives an access token related error, it refreshes the token once and retries.
 func (p *Publisher) withTokenRefreshString(ctx context.Context, fn func(token string) (string, error)) (string, error) {
-	res, err := fn(p.accessToken)
+	res, err := fn(p.token())
 	if err == nil {
 		return res, nil
 	}
@@ -163,23 +638,86 @@ func (p *Publisher) withTokenRefreshString(ctx context.Context, fn func(token st
 		if refreshErr := p.refreshAccessToken(ctx); refreshErr != nil {
 			return "", fmt.Errorf("token expired (%v) and refresh failed: %w", apiErr, refreshErr)
 		}
-		return fn(p.accessToken)
+		return fn(p.token())
 	}
 	return "", err
 }
 
-// LoadConfig reads JSON config from disk.
+// LoadConfig reads JSON, YAML or TOML config from disk (by extension), applies
+// WECHAT_* environment overrides, which take precedence over the file, resolves
+// secret references (vault://, aws-sm://, acm://) and decrypts enc:v1: values
+// with the key from WECHAT_SECRET_KEY. Errors are returned as *ConfigError.
 func LoadConfig(path string) (Config, error) {
+	path = ResolveConfigPath(path)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return Config{}, &ConfigError{Path: path, Err: err}
+	}
+	return cfg, nil
+}
+
+// ConfigError 表示配置文件读取、解析或校验失败，也用于配置项无法使用（如模型服务商不支持）；
+// 后者的 Path 为空。
+type ConfigError struct {
+	Path string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+func loadConfig(path string) (Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return Config{}, err
 	}
 	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	if err := decodeConfig(path, data, &cfg); err != nil {
 		return Config{}, err
 	}
-	if cfg.AppID == "" || cfg.AppSecret == "" {
-		return Config{}, errors.New("config must include app_id and app_secret")
+	if err := applyEnv(&cfg); err != nil {
+		return Config{}, err
+	}
+	if err := resolveSecretRefs(&cfg); err != nil {
+		return Config{}, err
+	}
+	if err := decryptConfig(&cfg); err != nil {
+		return Config{}, err
+	}
+	if err := ValidateAccount(cfg); err != nil {
+		return Config{}, err
+	}
+	if err := ValidateNotify(cfg.Notify); err != nil {
+		return Config{}, err
+	}
+	if err := ValidateTemplateNotify(cfg.TemplateNotify); err != nil {
+		return Config{}, err
+	}
+	if err := ValidateCORS(cfg.CORS); err != nil {
+		return Config{}, err
+	}
+	if err := ValidateTLS(cfg.TLS); err != nil {
+		return Config{}, err
+	}
+	if err := ValidateUploads(cfg.Uploads); err != nil {
+		return Config{}, err
+	}
+	if err := ValidateStorage(cfg.Storage); err != nil {
+		return Config{}, err
+	}
+	if err := ValidateSessions(cfg.Sessions); err != nil {
+		return Config{}, err
+	}
+	if err := ValidateCrossPost(cfg.CrossPost); err != nil {
+		return Config{}, err
+	}
+	if err := ValidateAnalytics(cfg.Analytics); err != nil {
+		return Config{}, err
 	}
 	return cfg, nil
 }
@@ -189,48 +727,35 @@ func (p *Publisher) PublishDraft(ctx context.Context, params PublishParams) (str
 	if params.MarkdownPath == "" || params.Title == "" || params.CoverPath == "" {
 		return "", errors.New("markdown path, title, and cover path are required")
 	}
+	if params.Author == "" {
+		params.Author = p.cfg.Author
+	}
+	if p.cfg.AccountType == AccountWeCom {
+		return p.publishWeCom(ctx, params)
+	}
 
-	token, err := getAccessToken(p.client, p.cfg)
-	if err != nil {
+	if err := p.refreshAccessToken(ctx); err != nil {
 		return "", fmt.Errorf("failed to init access_token: %w", err)
 	}
-	p.accessToken = token
 	p.infof("Fetched fresh access_token for publish")
+	params.report("token")
 
 	p.logger.Printf("[publish] start title=%q md=%s cover=%s", params.Title, params.MarkdownPath, params.CoverPath)
 
-	mdBytes, err := os.ReadFile(params.MarkdownPath)
-	if err != nil {
-		return "", err
-	}
-
 	// 摘要不再使用，直接发送空字符串，避免长度限制错误。
 	p.infof("Digest skipped; sending empty digest to WeChat")
 
-	mdWithImages, err := p.withTokenRefreshString(ctx, func(token string) (string, error) {
-		return replaceMarkdownImages(ctx, p.client, token, string(mdBytes), params.MarkdownPath)
-	})
-	if err != nil {
-		return "", err
-	}
-	p.infof("Processed markdown and uploaded inline images if any")
-
-	contentHTML, err := mdToHTML(mdWithImages)
+	contentHTML, err := p.markdownContent(ctx, params)
 	if err != nil {
 		return "", err
 	}
-	p.infof("Converted Markdown to HTML")
-
-	contentHTML = normalizeForWeChat(contentHTML)
-	p.infof("Normalized HTML for WeChat compatibility")
 
-	thumbMediaID, err := p.withTokenRefreshString(ctx, func(token string) (string, error) {
-		return uploadImage(ctx, p.client, token, params.CoverPath)
-	})
+	thumbMediaID, err := p.uploadCover(ctx, params)
 	if err != nil {
 		return "", err
 	}
 	p.infof("Uploaded cover image %s -> media_id=%s", params.CoverPath, thumbMediaID)
+	params.report("cover")
 
 	art := article{
 		Title:              params.Title,
@@ -242,8 +767,21 @@ func (p *Publisher) PublishDraft(ctx context.Context, params PublishParams) (str
 		OnlyFansCanComment: 0,
 	}
 
-	p.logger.Printf("[publish] addDraft title=%q cover_media=%s", art.Title, art.ThumbMediaID)
+	params.report("draft")
+	if params.UpdateMediaID != "" {
+		p.logger.Printf("[publish] updateDraft title=%q index=%d cover_media=%s", art.Title, params.UpdateIndex, art.ThumbMediaID)
+		payload := map[string]any{"media_id": params.UpdateMediaID, "index": params.UpdateIndex, "articles": art}
+		if err := p.callAPI(ctx, updateDraftURL, payload, nil); err != nil {
+			p.logger.Printf("[publish] updateDraft failed: %v", err)
+			return "", err
+		}
+		p.infof("Draft updated successfully")
+		params.report("done")
+		p.logger.Printf("[publish] success title=%q (updated)", params.Title)
+		return params.UpdateMediaID, nil
+	}
 
+	p.logger.Printf("[publish] addDraft title=%q cover_media=%s", art.Title, art.ThumbMediaID)
 	mediaID, err := p.withTokenRefreshString(ctx, func(token string) (string, error) {
 		return addDraft(ctx, p.client, token, art)
 	})
@@ -252,13 +790,64 @@ func (p *Publisher) PublishDraft(ctx context.Context, params PublishParams) (str
 		return "", err
 	}
 	p.infof("Draft created successfully: media_id=%s", mediaID)
+	params.report("done")
 	// 成功日志不再输出完整 media_id，避免暴露。
 	p.logger.Printf("[publish] success title=%q", params.Title)
 
 	return mediaID, nil
 }
 
+// markdownContent 读取 Markdown，上传其中的本地图片并转换为微信兼容的 HTML。
+func (p *Publisher) markdownContent(ctx context.Context, params PublishParams) (string, error) {
+	mdBytes := []byte(params.Markdown)
+	if params.Markdown == "" {
+		var err error
+		if mdBytes, err = os.ReadFile(params.MarkdownPath); err != nil {
+			return "", err
+		}
+	}
+
+	_, body := ParseFrontMatter(mdBytes)
+
+	onUpload := func(path, url string, done, total int) {
+		if params.ImageProgress != nil {
+			params.ImageProgress(done, total)
+		}
+		if params.ImageUploaded != nil {
+			params.ImageUploaded(path, url)
+		}
+	}
+	mdWithImages, err := p.withTokenRefreshString(ctx, func(token string) (string, error) {
+		return replaceMarkdownImages(ctx, p.client, p.endpoint(uploadImgURL), token, string(body), params.MarkdownPath, params.Manifest, onUpload)
+	})
+	if err != nil {
+		return "", err
+	}
+	p.infof("Processed markdown and uploaded inline images if any")
+	params.report("images")
+
+	contentHTML, err := RenderHTML(mdWithImages, p.cfg.AllowHTML)
+	if err != nil {
+		return "", err
+	}
+	p.infof("Converted Markdown to WeChat-compatible HTML")
+	params.report("html")
+	return contentHTML, nil
+}
+
+// RenderHTML 把 Markdown 转换为发布时使用的微信兼容 HTML（不上传图片），用于本地预览。
+func RenderHTML(md string, allowHTML bool) (string, error) {
+	contentHTML, err := mdToHTML(md, allowHTML)
+	if err != nil {
+		return "", err
+	}
+	return normalizeForWeChat(contentHTML), nil
+}
+
 func getAccessToken(client *http.Client, cfg Config) (string, error) {
+	if cfg.AccountType == AccountWeCom {
+		return getWeComToken(client, cfg.WeCom)
+	}
 	req, err := http.NewRequest("GET", accessTokenURL, nil)
 	if err != nil {
 		return "", err
@@ -271,6 +860,11 @@ func getAccessToken(client *http.Client, cfg Config) (string, error) {
 
 	resp, err := client.Do(req)
 	if err != nil {
+		// url.Error 会带上完整 URL（含 secret），错误信息会被记录和返回给前端，只保留底层原因。
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return "", fmt.Errorf("%s %s: %w", urlErr.Op, accessTokenURL, urlErr.Err)
+		}
 		return "", err
 	}
 	defer resp.Body.Close()
@@ -280,22 +874,40 @@ func getAccessToken(client *http.Client, cfg Config) (string, error) {
 		return "", err
 	}
 	if data.AccessToken == "" {
-		return "", fmt.Errorf("failed to get access_token: %d %s", data.ErrCode, data.ErrMsg)
+		return "", fmt.Errorf("failed to get access_token: %w", &wechatAPIError{Code: data.ErrCode, Msg: data.ErrMsg})
 	}
 	return data.AccessToken, nil
 }
 
+// CheckAccessToken 获取一次 access_token，校验 app_id/app_secret 与到微信接口的网络；不影响发布使用的令牌。
+func CheckAccessToken(client *http.Client, cfg Config) error {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	_, err := getAccessToken(client, cfg)
+	return err
+}
+
 // refreshAccessToken retrieves a new token and updates the publisher state.
 func (p *Publisher) refreshAccessToken(ctx context.Context) error {
 	newToken, err := getAccessToken(p.client, p.cfg)
 	if err != nil {
 		return err
 	}
+	p.tokenMu.Lock()
 	p.accessToken = newToken
+	p.tokenMu.Unlock()
 	return nil
 }
 
-func uploadImage(ctx context.Context, client *http.Client, accessToken, imagePath string) (string, error) {
+// token 返回当前的 access_token；批量发布时多个 goroutine 共用同一个 Publisher。
+func (p *Publisher) token() string {
+	p.tokenMu.Lock()
+	defer p.tokenMu.Unlock()
+	return p.accessToken
+}
+
+func uploadImage(ctx context.Context, client *http.Client, endpoint, accessToken, imagePath string) (string, error) {
 	file, err := os.Open(imagePath)
 	if err != nil {
 		return "", err
@@ -315,7 +927,7 @@ func uploadImage(ctx context.Context, client *http.Client, accessToken, imagePat
 		return "", err
 	}
 
-    req, err := http.NewRequestWithContext(ctx, "POST", uploadImageURL, &body)
+    req, err := http.NewRequestWithContext(ctx, "POST", endpoint, &body)
     if err != nil {
         return "", err
     }
@@ -341,7 +953,7 @@ func uploadImage(ctx context.Context, client *http.Client, accessToken, imagePat
 	return data.MediaID, nil
 }
 
-func uploadContentImage(ctx context.Context, client *http.Client, accessToken, imagePath string) (string, error) {
+func uploadContentImage(ctx context.Context, client *http.Client, endpoint, accessToken, imagePath string) (string, error) {
 	file, err := os.Open(imagePath)
 	if err != nil {
 		return "", err
@@ -361,7 +973,7 @@ func uploadContentImage(ctx context.Context, client *http.Client, accessToken, i
 		return "", err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", uploadImgURL, &body)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, &body)
 	if err != nil {
 		return "", err
 	}
@@ -386,9 +998,13 @@ func uploadContentImage(ctx context.Context, client *http.Client, accessToken, i
 	return data.URL, nil
 }
 
-func mdToHTML(md string) (string, error) {
+func mdToHTML(md string, allowHTML bool) (string, error) {
 	var buf bytes.Buffer
-	if err := goldmark.Convert([]byte(md), &buf); err != nil {
+	var opts []goldmark.Option
+	if allowHTML {
+		opts = append(opts, goldmark.WithRendererOptions(gmhtml.WithUnsafe()))
+	}
+	if err := goldmark.New(opts...).Convert([]byte(md), &buf); err != nil {
 		return "", err
 	}
 	return buf.String(), nil
@@ -465,12 +1081,18 @@ func normalizeForWeChat(html string) string {
 	return html
 }
 
-func replaceMarkdownImages(ctx context.Context, client *http.Client, accessToken, md string, mdPath string) (string, error) {
+func replaceMarkdownImages(ctx context.Context, client *http.Client, uploadURL, accessToken, md string, mdPath string, manifest *PublishManifest, onUpload func(path, url string, done, total int)) (string, error) {
 	imgPattern := regexp.MustCompile(`!\[[^\]]*\]\(([^)]+)\)`)
 	matches := imgPattern.FindAllStringSubmatchIndex(md, -1)
 	if len(matches) == 0 {
 		return md, nil
 	}
+	total, done := 0, 0
+	for _, match := range matches {
+		if len(match) >= 4 && isLocalImageRef(strings.TrimSpace(md[match[2]:match[3]])) {
+			total++
+		}
+	}
 
 	baseDir := filepath.Dir(mdPath)
 	var builder strings.Builder
@@ -483,12 +1105,7 @@ func replaceMarkdownImages(ctx context.Context, client *http.Client, accessToken
 		end := match[3]
 		builder.WriteString(md[last:start])
 		imgRef := strings.TrimSpace(md[start:end])
-		if strings.HasPrefix(imgRef, "http://") || strings.HasPrefix(imgRef, "https://") {
-			builder.WriteString(imgRef)
-			last = end
-			continue
-		}
-		if strings.HasPrefix(imgRef, "data:") {
+		if !isLocalImageRef(imgRef) {
 			builder.WriteString(imgRef)
 			last = end
 			continue
@@ -499,9 +1116,19 @@ func replaceMarkdownImages(ctx context.Context, client *http.Client, accessToken
 				localPath = filepath.Join(baseDir, imgRef)
 			}
 		}
-		uploadedURL, err := uploadContentImage(ctx, client, accessToken, localPath)
-		if err != nil {
-			return "", err
+		uploadedURL, ok := manifest.image(localPath)
+		if !ok {
+			var err error
+			if uploadedURL, err = uploadContentImage(ctx, client, uploadURL, accessToken, localPath); err != nil {
+				return "", err
+			}
+			if err := manifest.recordImage(localPath, uploadedURL); err != nil {
+				return "", fmt.Errorf("save publish manifest: %w", err)
+			}
+		}
+		done++
+		if onUpload != nil {
+			onUpload(localPath, uploadedURL, done, total)
 		}
 		builder.WriteString(uploadedURL)
 		last = end
@@ -510,6 +1137,11 @@ func replaceMarkdownImages(ctx context.Context, client *http.Client, accessToken
 	return builder.String(), nil
 }
 
+// isLocalImageRef 判断图片引用是否为需要上传的本地文件（非 http(s) 链接或 data URI）。
+func isLocalImageRef(ref string) bool {
+	return !strings.HasPrefix(ref, "http://") && !strings.HasPrefix(ref, "https://") && !strings.HasPrefix(ref, "data:")
+}
+
 
 func addDraft(ctx context.Context, client *http.Client, accessToken string, art article) (string, error) {
 	payload := addDraftPayload{Articles: []article{art}}
@@ -0,0 +1,78 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+const (
+	quotaGetURL   = "https://api.weixin.qq.com/cgi-bin/openapi/quota/get"
+	clearQuotaURL = "https://api.weixin.qq.com/cgi-bin/clear_quota"
+)
+
+// ErrCodeQuotaExceeded 为接口调用次数达到上限时微信返回的错误码（api minute-quota reach limit 等）。
+const ErrCodeQuotaExceeded = 45009
+
+// DefaultQuotaPaths 为未指定接口时查询额度的接口：发布、上传图片与群发使用的接口。
+var DefaultQuotaPaths = []string{
+	"/cgi-bin/draft/add",
+	"/cgi-bin/draft/update",
+	"/cgi-bin/media/uploadimg",
+	"/cgi-bin/material/add_material",
+	"/cgi-bin/message/mass/sendall",
+	"/cgi-bin/message/mass/preview",
+}
+
+// APIQuota 为一个接口当天的调用额度；Error 非空表示查询该接口失败（如接口不支持查询）。
+type APIQuota struct {
+	Path       string `json:"path"`
+	DailyLimit int    `json:"daily_limit"`
+	Used       int    `json:"used"`
+	Remain     int    `json:"remain"`
+	Error      string `json:"error,omitempty"`
+}
+
+// GetQuota 查询接口当天的调用额度，path 为接口路径，如 /cgi-bin/draft/add。
+func (p *Publisher) GetQuota(ctx context.Context, path string) (APIQuota, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "/") {
+		return APIQuota{}, errors.New("api path must start with /, e.g. /cgi-bin/draft/add")
+	}
+	var resp struct {
+		Quota struct {
+			DailyLimit int `json:"daily_limit"`
+			Used       int `json:"used"`
+			Remain     int `json:"remain"`
+		} `json:"quota"`
+	}
+	if err := p.callAPI(ctx, quotaGetURL, map[string]string{"cgi_path": path}, &resp); err != nil {
+		return APIQuota{}, err
+	}
+	return APIQuota{Path: path, DailyLimit: resp.Quota.DailyLimit, Used: resp.Quota.Used, Remain: resp.Quota.Remain}, nil
+}
+
+// QuotaReport 依次查询各接口的额度，paths 为空时使用 DefaultQuotaPaths；单个接口失败时记录在 Error 中。
+func (p *Publisher) QuotaReport(ctx context.Context, paths []string) []APIQuota {
+	if len(paths) == 0 {
+		paths = DefaultQuotaPaths
+	}
+	quotas := make([]APIQuota, 0, len(paths))
+	for _, path := range paths {
+		q, err := p.GetQuota(ctx, path)
+		if err != nil {
+			q = APIQuota{Path: path, Error: err.Error()}
+		}
+		quotas = append(quotas, q)
+	}
+	return quotas
+}
+
+// ClearQuota 把公众号全部接口的调用次数清零。微信限制每个帐号每月只能清零 10 次，调用方须先确认。
+func (p *Publisher) ClearQuota(ctx context.Context) error {
+	if err := p.callAPI(ctx, clearQuotaURL, map[string]string{"appid": p.cfg.AppID}, nil); err != nil {
+		return err
+	}
+	p.logger.Printf("[quota] cleared appid=%s", p.cfg.AppID)
+	return nil
+}
@@ -0,0 +1,241 @@
+package publisher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSchedulePath 为未配置 schedule_path 时的定时发布文件。
+const DefaultSchedulePath = "schedule.json"
+
+// 定时发布状态。
+const (
+	ScheduleWaiting  = "scheduled"
+	ScheduleRunning  = "running"
+	ScheduleDone     = "done"
+	ScheduleFailed   = "failed"
+	ScheduleCanceled = "canceled"
+)
+
+// ErrScheduleNotFound 表示不存在该定时发布。
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// ScheduledPublish 为一条定时发布。来自网页的条目带 SessionID 与提交时的稿件快照 Markdown；
+// 命令行添加的条目使用 MarkdownPath，发布时再读取文件。User 为稿件作者，By 为提交定时发布的用户（审核流程中的发布人）。
+type ScheduledPublish struct {
+	ID           string    `json:"id"`
+	SessionID    string    `json:"session_id,omitempty"`
+	User         string    `json:"user,omitempty"`
+	By           string    `json:"by,omitempty"`
+	Title        string    `json:"title"`
+	Author       string    `json:"author,omitempty"`
+	Digest       string    `json:"digest,omitempty"`
+	CoverPath    string    `json:"cover_path,omitempty"`
+	AICover      bool      `json:"ai_cover,omitempty"`
+	Markdown     string    `json:"markdown,omitempty"`
+	MarkdownPath string    `json:"markdown_path,omitempty"`
+	ScheduleAt   time.Time `json:"schedule_at"`
+	Status       string    `json:"status"`
+	// JobID 为到点后提交的发布任务（见 GET /api/jobs/{id}）。
+	JobID     string    `json:"job_id,omitempty"`
+	MediaID   string    `json:"media_id,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ParseScheduleTime 解析定时发布时间：RFC3339，或本地时间 "2006-01-02 15:04" / "2006-01-02T15:04"。
+func ParseScheduleTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid schedule time %q: use RFC3339 or YYYY-MM-DD HH:MM", s)
+}
+
+// ScheduleStore 把定时发布保存为单个 JSON 文件，服务端与命令行共用。
+type ScheduleStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewScheduleStore 创建定时发布存储，文件不存在时视为空。
+func NewScheduleStore(path string) *ScheduleStore {
+	if path == "" {
+		path = DefaultSchedulePath
+	}
+	return &ScheduleStore{path: path}
+}
+
+// Add 校验并保存一条定时发布，返回带 ID 的条目。
+func (s *ScheduleStore) Add(item ScheduledPublish) (ScheduledPublish, error) {
+	switch {
+	case strings.TrimSpace(item.Title) == "":
+		return item, errors.New("title is required")
+	case item.Markdown == "" && item.MarkdownPath == "":
+		return item, errors.New("markdown is required")
+	case item.CoverPath == "" && !item.AICover:
+		return item, errors.New("cover_path required")
+	case !item.ScheduleAt.After(time.Now()):
+		return item, errors.New("schedule_at must be in the future")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, err := s.readLocked()
+	if err != nil {
+		return item, err
+	}
+	now := time.Now()
+	item.ID = strconv.FormatInt(now.UnixNano(), 36)
+	item.Status = ScheduleWaiting
+	item.CreatedAt, item.UpdatedAt = now, now
+	if err := s.writeLocked(append(items, item)); err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+// List 按计划时间返回条目；status 非空时只返回该状态。
+func (s *ScheduleStore) List(status string) ([]ScheduledPublish, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, err := s.readLocked()
+	if err != nil || status == "" {
+		return items, err
+	}
+	kept := items[:0]
+	for _, it := range items {
+		if it.Status == status {
+			kept = append(kept, it)
+		}
+	}
+	return kept, nil
+}
+
+// Cancel 取消尚未执行的定时发布；allow 非空时只取消其允许的条目，其余视为不存在。
+func (s *ScheduleStore) Cancel(id string, allow func(ScheduledPublish) bool) (ScheduledPublish, error) {
+	return s.Update(id, func(it *ScheduledPublish) error {
+		if allow != nil && !allow(*it) {
+			return ErrScheduleNotFound
+		}
+		if it.Status != ScheduleWaiting {
+			return fmt.Errorf("schedule is %s; only scheduled items can be canceled", it.Status)
+		}
+		it.Status = ScheduleCanceled
+		return nil
+	})
+}
+
+// Claim 把已到点的条目标记为 running 并返回，调用方负责执行并回写结果。
+func (s *ScheduleStore) Claim(now time.Time) ([]ScheduledPublish, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, err := s.readLocked()
+	if err != nil {
+		return nil, err
+	}
+	var due []ScheduledPublish
+	for i := range items {
+		if items[i].Status == ScheduleWaiting && !items[i].ScheduleAt.After(now) {
+			items[i].Status, items[i].UpdatedAt = ScheduleRunning, now
+			due = append(due, items[i])
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	return due, s.writeLocked(items)
+}
+
+// FailInterrupted 把上次进程退出时仍在执行的条目标记为失败，避免重复发布。
+func (s *ScheduleStore) FailInterrupted() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, err := s.readLocked()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i := range items {
+		if items[i].Status == ScheduleRunning {
+			items[i].Status, items[i].Error, items[i].UpdatedAt = ScheduleFailed, "interrupted by restart; check the WeChat draft box before retrying", time.Now()
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, s.writeLocked(items)
+}
+
+// Update 在锁内修改单个条目并保存。
+func (s *ScheduleStore) Update(id string, fn func(*ScheduledPublish) error) (ScheduledPublish, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, err := s.readLocked()
+	if err != nil {
+		return ScheduledPublish{}, err
+	}
+	for i := range items {
+		if items[i].ID != id {
+			continue
+		}
+		if err := fn(&items[i]); err != nil {
+			return ScheduledPublish{}, err
+		}
+		items[i].UpdatedAt = time.Now()
+		updated := items[i]
+		return updated, s.writeLocked(items)
+	}
+	return ScheduledPublish{}, ErrScheduleNotFound
+}
+
+func (s *ScheduleStore) readLocked() ([]ScheduledPublish, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var items []ScheduledPublish
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("schedule %s: %w", s.path, err)
+	}
+	return items, nil
+}
+
+func (s *ScheduleStore) writeLocked(items []ScheduledPublish) error {
+	sort.SliceStable(items, func(i, j int) bool { return items[i].ScheduleAt.Before(items[j].ScheduleAt) })
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create schedule dir: %w", err)
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
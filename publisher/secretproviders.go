@@ -0,0 +1,252 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/internal/sigv4"
+)
+
+func init() {
+	RegisterSecretProvider("vault", vaultSecret)
+	RegisterSecretProvider("aws-sm", awsSecret)
+	RegisterSecretProvider("acm", aliyunSecret)
+	RegisterSecretProvider("aliyun-sm", aliyunSecret)
+}
+
+// doSecretRequest 发送请求并返回响应体，非 2xx 时返回包含状态码与响应内容的错误。
+func doSecretRequest(req *http.Request) ([]byte, error) {
+	resp, err := secretHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return body, &secretHTTPError{status: resp.StatusCode, body: strings.TrimSpace(string(body))}
+	}
+	return body, nil
+}
+
+type secretHTTPError struct {
+	status int
+	body   string
+}
+
+func (e *secretHTTPError) Error() string {
+	body := e.body
+	if len(body) > 300 {
+		body = body[:300]
+	}
+	return fmt.Sprintf("status %d: %s", e.status, body)
+}
+
+// vaultSecret 读取 HashiCorp Vault 的 KV 密钥：vault://<mount>/<path>#<field>，如 vault://secret/wechat#app_secret。
+// 地址与令牌读取 VAULT_ADDR、VAULT_TOKEN（可选 VAULT_NAMESPACE）；先按 KV v2 读取，不存在时按 KV v1；
+// KV v2 可用 ?version=N 指定版本。返回密钥数据的 JSON。
+func vaultSecret(ctx context.Context, ref SecretRef) (string, error) {
+	addr, token := strings.TrimRight(os.Getenv("VAULT_ADDR"), "/"), os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return "", errors.New("VAULT_ADDR and VAULT_TOKEN are required")
+	}
+	mount, path, ok := strings.Cut(strings.Trim(ref.Name, "/"), "/")
+	if !ok || path == "" {
+		return "", errors.New("use vault://<mount>/<path>#<field>")
+	}
+	get := func(u string) (map[string]any, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-Vault-Token", token)
+		if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+			req.Header.Set("X-Vault-Namespace", ns)
+		}
+		body, err := doSecretRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		var out struct {
+			Data map[string]any `json:"data"`
+		}
+		if err := json.Unmarshal(body, &out); err != nil {
+			return nil, fmt.Errorf("decode vault response: %w", err)
+		}
+		return out.Data, nil
+	}
+
+	v2 := addr + "/v1/" + mount + "/data/" + path
+	if version := ref.Query.Get("version"); version != "" {
+		v2 += "?version=" + url.QueryEscape(version)
+	}
+	data, err := get(v2)
+	var httpErr *secretHTTPError
+	if errors.As(err, &httpErr) && httpErr.status == http.StatusNotFound {
+		data, err = get(addr + "/v1/" + mount + "/" + path)
+	} else if err == nil {
+		inner, ok := data["data"].(map[string]any)
+		if !ok {
+			return "", errors.New("secret not found")
+		}
+		data = inner
+	}
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(data)
+	return string(out), err
+}
+
+// awsSecret 读取 AWS Secrets Manager：aws-sm://<secret-id 或 ARN>#<json 字段>，可用 ?region= 指定区域。
+// 凭证读取 AWS_ACCESS_KEY_ID、AWS_SECRET_ACCESS_KEY（可选 AWS_SESSION_TOKEN），区域默认取 ARN、
+// AWS_REGION 或 AWS_DEFAULT_REGION；AWS_ENDPOINT_URL_SECRETS_MANAGER 可覆盖接口地址。
+func awsSecret(ctx context.Context, ref SecretRef) (string, error) {
+	keyID, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if keyID == "" || secret == "" {
+		return "", errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required")
+	}
+	region := ref.Query.Get("region")
+	if parts := strings.Split(ref.Name, ":"); region == "" && len(parts) > 3 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	for _, env := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region == "" {
+			region = os.Getenv(env)
+		}
+	}
+	if region == "" {
+		return "", errors.New("region is required: set AWS_REGION or add ?region=")
+	}
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_SECRETS_MANAGER")
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com"
+	}
+	body, _ := json.Marshal(map[string]string{"SecretId": ref.Name})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	sigv4.Sign(req, body, sigv4.Credentials{AccessKey: keyID, SecretKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, region, "secretsmanager", time.Now())
+	resp, err := doSecretRequest(req)
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary []byte `json:"SecretBinary"`
+	}
+	if err := json.Unmarshal(resp, &out); err != nil {
+		return "", fmt.Errorf("decode secrets manager response: %w", err)
+	}
+	if out.SecretString == "" && out.SecretBinary != nil {
+		return string(out.SecretBinary), nil
+	}
+	return out.SecretString, nil
+}
+
+// aliyunSecret 读取阿里云 KMS 凭据管家：acm://<凭据名称>#<json 字段>，可用 ?region=、?stage= 指定区域与版本状态。
+// 凭证读取 ALIBABA_CLOUD_ACCESS_KEY_ID、ALIBABA_CLOUD_ACCESS_KEY_SECRET（可选 ALIBABA_CLOUD_SECURITY_TOKEN），
+// 区域默认取 ALIBABA_CLOUD_REGION_ID；ALIBABA_CLOUD_KMS_ENDPOINT 可覆盖接口地址（如 KMS 专属实例）。
+func aliyunSecret(ctx context.Context, ref SecretRef) (string, error) {
+	keyID, secret := os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_ID"), os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_SECRET")
+	if keyID == "" || secret == "" {
+		return "", errors.New("ALIBABA_CLOUD_ACCESS_KEY_ID and ALIBABA_CLOUD_ACCESS_KEY_SECRET are required")
+	}
+	region := ref.Query.Get("region")
+	if region == "" {
+		region = os.Getenv("ALIBABA_CLOUD_REGION_ID")
+	}
+	endpoint := os.Getenv("ALIBABA_CLOUD_KMS_ENDPOINT")
+	if endpoint == "" {
+		if region == "" {
+			return "", errors.New("region is required: set ALIBABA_CLOUD_REGION_ID or add ?region=")
+		}
+		endpoint = "https://kms." + region + ".aliyuncs.com"
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	params := url.Values{
+		"Action":           {"GetSecretValue"},
+		"SecretName":       {ref.Name},
+		"Format":           {"JSON"},
+		"Version":          {"2016-01-20"},
+		"AccessKeyId":      {keyID},
+		"SignatureMethod":  {"HMAC-SHA1"},
+		"SignatureVersion": {"1.0"},
+		"SignatureNonce":   {hex.EncodeToString(nonce)},
+		"Timestamp":        {time.Now().UTC().Format("2006-01-02T15:04:05Z")},
+	}
+	if token := os.Getenv("ALIBABA_CLOUD_SECURITY_TOKEN"); token != "" {
+		params.Set("SecurityToken", token)
+	}
+	if stage := ref.Query.Get("stage"); stage != "" {
+		params.Set("VersionStage", stage)
+	}
+	params.Set("Signature", signAliyunRPC(http.MethodGet, params, secret))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(endpoint, "/")+"/?"+aliyunQuery(params), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := doSecretRequest(req)
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		SecretData string `json:"SecretData"`
+	}
+	if err := json.Unmarshal(resp, &out); err != nil {
+		return "", fmt.Errorf("decode kms response: %w", err)
+	}
+	return out.SecretData, nil
+}
+
+// signAliyunRPC 计算阿里云 RPC 风格接口的签名（HMAC-SHA1）。
+func signAliyunRPC(method string, params url.Values, secret string) string {
+	toSign := method + "&" + aliyunEscape("/") + "&" + aliyunEscape(aliyunQuery(params))
+	h := hmac.New(sha1.New, []byte(secret+"&"))
+	h.Write([]byte(toSign))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// aliyunQuery 按参数名排序并编码为查询字符串。
+func aliyunQuery(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, aliyunEscape(k)+"="+aliyunEscape(params.Get(k)))
+	}
+	return strings.Join(parts, "&")
+}
+
+func aliyunEscape(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
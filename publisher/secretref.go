@@ -0,0 +1,136 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SecretRef 为配置中引用外部密钥的 URI，形如 scheme://name?query#field：
+// Name 为密钥名称或路径，Field 非空时从 JSON 格式的密钥中取该字段，Query 为服务商参数（如 region）。
+type SecretRef struct {
+	Scheme string
+	Name   string
+	Field  string
+	Query  url.Values
+}
+
+func (r SecretRef) String() string {
+	return r.Scheme + "://" + r.Name
+}
+
+// SecretProvider 按引用读取密钥的原始内容。
+type SecretProvider func(ctx context.Context, ref SecretRef) (string, error)
+
+var (
+	secretProvidersMu sync.RWMutex
+	secretProviders   = map[string]SecretProvider{}
+	secretHTTPClient  = &http.Client{Timeout: 15 * time.Second}
+)
+
+// secretResolveTimeout 为加载配置时解析全部密钥引用的总时长。
+const secretResolveTimeout = 30 * time.Second
+
+// RegisterSecretProvider 注册（或覆盖）一个密钥服务；scheme 不区分大小写。
+func RegisterSecretProvider(scheme string, provider SecretProvider) {
+	scheme = strings.ToLower(strings.TrimSpace(scheme))
+	if scheme == "" || provider == nil {
+		panic("publisher: RegisterSecretProvider requires scheme and provider")
+	}
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	secretProviders[scheme] = provider
+}
+
+// SecretProviders 返回已注册的密钥服务 scheme（已排序）。
+func SecretProviders() []string {
+	secretProvidersMu.RLock()
+	defer secretProvidersMu.RUnlock()
+	names := make([]string, 0, len(secretProviders))
+	for name := range secretProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseSecretRef 解析密钥引用；scheme 未注册（如 https://）时返回 false。
+func ParseSecretRef(value string) (SecretRef, SecretProvider, bool) {
+	scheme, rest, ok := strings.Cut(value, "://")
+	if !ok {
+		return SecretRef{}, nil, false
+	}
+	scheme = strings.ToLower(scheme)
+	secretProvidersMu.RLock()
+	provider, ok := secretProviders[scheme]
+	secretProvidersMu.RUnlock()
+	if !ok {
+		return SecretRef{}, nil, false
+	}
+	ref := SecretRef{Scheme: scheme}
+	rest, ref.Field, _ = strings.Cut(rest, "#")
+	rest, query, _ := strings.Cut(rest, "?")
+	ref.Name = rest
+	ref.Query, _ = url.ParseQuery(query)
+	return ref, provider, true
+}
+
+// ResolveSecret 读取密钥引用指向的值；value 不是密钥引用时原样返回。
+func ResolveSecret(ctx context.Context, value string) (string, error) {
+	ref, provider, ok := ParseSecretRef(value)
+	if !ok {
+		return value, nil
+	}
+	if ref.Name == "" {
+		return "", fmt.Errorf("%s: missing secret name", value)
+	}
+	raw, err := provider(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", ref, err)
+	}
+	if ref.Field == "" {
+		return raw, nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return "", fmt.Errorf("%s: secret is not a JSON object, cannot select #%s", ref, ref.Field)
+	}
+	switch v := fields[ref.Field].(type) {
+	case nil:
+		return "", fmt.Errorf("%s: field %q not found", ref, ref.Field)
+	case string:
+		return v, nil
+	default:
+		data, _ := json.Marshal(v)
+		return string(data), nil
+	}
+}
+
+// resolveSecretRefs 把配置中的密钥引用替换为实际的值，同一引用只读取一次。
+func resolveSecretRefs(cfg *Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), secretResolveTimeout)
+	defer cancel()
+	resolved := make(map[string]string)
+	return walkConfigStrings(reflect.ValueOf(cfg).Elem(), "", func(path string, v reflect.Value) error {
+		if _, _, ok := ParseSecretRef(v.String()); !ok {
+			return nil
+		}
+		value, ok := resolved[v.String()]
+		if !ok {
+			var err error
+			if value, err = ResolveSecret(ctx, v.String()); err != nil {
+				return fmt.Errorf("resolve %s: %w", path, err)
+			}
+			resolved[v.String()] = value
+		}
+		v.SetString(value)
+		return nil
+	})
+}
@@ -0,0 +1,292 @@
+package publisher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 配置中的密钥可以加密保存为 enc:v1:<base64(nonce+密文)>（AES-256-GCM），加载配置时自动解密。
+// 解密密钥（32 字节，base64）从环境变量 WECHAT_SECRET_KEY 读取，或从 WECHAT_SECRET_KEY_FILE
+// 指向的文件读取（便于由 KMS、systemd credentials 或 Kubernetes Secret 挂载）。
+const (
+	SecretKeyEnv     = "WECHAT_SECRET_KEY"
+	SecretKeyFileEnv = "WECHAT_SECRET_KEY_FILE"
+	encryptedPrefix  = "enc:v1:"
+)
+
+// SensitiveFields 为 secrets encrypt 默认加密的配置项（按 json 键名，任意层级）。
+var SensitiveFields = map[string]bool{
+	"app_secret": true, "api_key": true, "secret": true, "secret_key": true, "webhook": true,
+	"token": true, "encoding_aes_key": true, "password": true,
+}
+
+// IsEncrypted 表示配置值是否为加密后的密文。
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// GenerateSecretKey 生成新的随机密钥（base64）。
+func GenerateSecretKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// LoadSecretKey 读取解密密钥，未配置时返回 nil。
+func LoadSecretKey() ([]byte, error) {
+	encoded, source := os.Getenv(SecretKeyEnv), SecretKeyEnv
+	if encoded == "" {
+		path := os.Getenv(SecretKeyFileEnv)
+		if path == "" {
+			return nil, nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", SecretKeyFileEnv, err)
+		}
+		encoded, source = string(data), path
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%s must be a base64-encoded 32-byte key (generate one with `secrets keygen`)", source)
+	}
+	return key, nil
+}
+
+func secretCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptSecret 加密一个配置值；已加密的值原样返回。
+func EncryptSecret(key []byte, plain string) (string, error) {
+	if IsEncrypted(plain) {
+		return plain, nil
+	}
+	aead, err := secretCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret 解密 EncryptSecret 的结果；未加密的值原样返回。
+func DecryptSecret(key []byte, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", errors.New("malformed encrypted value")
+	}
+	aead, err := secretCipher(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("wrong key or corrupted value")
+	}
+	return string(plain), nil
+}
+
+// decryptConfig 解密配置中所有加密的字符串；存在密文但未配置密钥时返回错误。
+func decryptConfig(cfg *Config) error {
+	var key []byte
+	return walkConfigStrings(reflect.ValueOf(cfg).Elem(), "", func(path string, v reflect.Value) error {
+		if !IsEncrypted(v.String()) {
+			return nil
+		}
+		if key == nil {
+			k, err := LoadSecretKey()
+			if err != nil {
+				return err
+			}
+			if k == nil {
+				return fmt.Errorf("%s is encrypted but %s (or %s) is not set", path, SecretKeyEnv, SecretKeyFileEnv)
+			}
+			key = k
+		}
+		plain, err := DecryptSecret(key, v.String())
+		if err != nil {
+			return fmt.Errorf("decrypt %s: %w", path, err)
+		}
+		v.SetString(plain)
+		return nil
+	})
+}
+
+// ConfigSecrets 返回配置中属于 SensitiveFields 的非空值（按配置路径），供 secrets encrypt 使用。
+func ConfigSecrets(cfg Config) map[string]string {
+	out := make(map[string]string)
+	_ = walkConfigStrings(reflect.ValueOf(&cfg).Elem(), "", func(path string, v reflect.Value) error {
+		name := path[strings.LastIndexAny(path, ".]")+1:]
+		if SensitiveFields[name] && v.String() != "" {
+			out[path] = v.String()
+		}
+		return nil
+	})
+	return out
+}
+
+// EncryptConfigFile 就地加密配置文件（JSON、YAML 或 TOML）中属于 SensitiveFields 的明文值，
+// 只替换这些值本身，保留文件的格式与注释；返回加密的配置路径。写入前校验新文件能解密回原值。
+func EncryptConfigFile(path string, key []byte) ([]string, error) {
+	path = ResolveConfigPath(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := decodeConfig(path, data, &cfg); err != nil {
+		return nil, err
+	}
+	secrets := ConfigSecrets(cfg)
+	paths := make([]string, 0, len(secrets))
+	for p, v := range secrets {
+		// 已加密的值与外部密钥引用（vault:// 等）保持不变。
+		if _, _, ref := ParseSecretRef(v); !IsEncrypted(v) && !ref {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	text := string(data)
+	// done 记录已加密的明文，值为对应的密文。
+	done := make(map[string]string)
+	var missing []string
+	for _, p := range paths {
+		plain := secrets[p]
+		if _, ok := done[plain]; ok {
+			continue
+		}
+		enc, err := EncryptSecret(key, plain)
+		if err != nil {
+			return nil, err
+		}
+		replaced := false
+		for _, quoted := range []string{jsonQuote(plain), "'" + plain + "'"} {
+			if strings.Contains(text, quoted) {
+				text = strings.ReplaceAll(text, quoted, `"`+enc+`"`)
+				replaced = true
+			}
+		}
+		// YAML 中未加引号的值。
+		bare := regexp.MustCompile(`(?m)(:[ \t]+)` + regexp.QuoteMeta(plain) + `([ \t]*(#.*)?\r?)$`)
+		if bare.MatchString(text) {
+			text = bare.ReplaceAllString(text, `${1}"`+enc+`"${2}`)
+			replaced = true
+		}
+		if !replaced {
+			missing = append(missing, p)
+			continue
+		}
+		done[plain] = enc
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("cannot locate %s in %s; encrypt the value with `secrets encrypt` and paste it manually", strings.Join(missing, ", "), path)
+	}
+	if len(done) == 0 {
+		return nil, nil
+	}
+
+	var check Config
+	if err := decodeConfig(path, []byte(text), &check); err != nil {
+		return nil, fmt.Errorf("verify encrypted config: %w", err)
+	}
+	plains := make(map[string]string, len(done))
+	for plain, enc := range done {
+		plains[enc] = plain
+	}
+	_ = walkConfigStrings(reflect.ValueOf(&check).Elem(), "", func(_ string, v reflect.Value) error {
+		if plain, ok := plains[v.String()]; ok {
+			v.SetString(plain)
+		}
+		return nil
+	})
+	if !reflect.DeepEqual(check, cfg) {
+		return nil, fmt.Errorf("verify encrypted config: values do not round-trip, %s left unchanged", path)
+	}
+
+	mode := os.FileMode(0o600)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(text), mode); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return paths, nil
+}
+
+// jsonQuote 返回与配置文件中写法一致的带引号字符串（不转义 HTML 字符）。
+func jsonQuote(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// walkConfigStrings 遍历结构体中的字符串字段（含指针、切片元素），path 为 llm.fallbacks[0].api_key 形式。
+func walkConfigStrings(v reflect.Value, path string, fn func(path string, v reflect.Value) error) error {
+	switch v.Kind() {
+	case reflect.String:
+		return fn(path, v)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return walkConfigStrings(v.Elem(), path, fn)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkConfigStrings(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || tag == "" || tag == "-" {
+				continue
+			}
+			name := tag
+			if path != "" {
+				name = path + "." + tag
+			}
+			if err := walkConfigStrings(v.Field(i), name, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	tagsGetURL           = "https://api.weixin.qq.com/cgi-bin/tags/get"
+	tagsCreateURL        = "https://api.weixin.qq.com/cgi-bin/tags/create"
+	tagsUpdateURL        = "https://api.weixin.qq.com/cgi-bin/tags/update"
+	tagsDeleteURL        = "https://api.weixin.qq.com/cgi-bin/tags/delete"
+	tagsBatchTaggingURL  = "https://api.weixin.qq.com/cgi-bin/tags/members/batchtagging"
+	tagsBatchUntagURL    = "https://api.weixin.qq.com/cgi-bin/tags/members/batchuntagging"
+	maxTagNameRunes      = 30
+	maxTagOpenIDsPerCall = 50
+)
+
+// ErrInvalidTag 表示标签名、标签或 openid 参数无效，与微信接口错误区分。
+var ErrInvalidTag = errors.New("invalid tag")
+
+// UserTag 为公众号的用户标签，Count 为带该标签的粉丝数。
+type UserTag struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// ListTags 返回公众号的全部用户标签。
+func (p *Publisher) ListTags(ctx context.Context) ([]UserTag, error) {
+	var resp struct {
+		Tags []UserTag `json:"tags"`
+	}
+	if err := p.callAPI(ctx, tagsGetURL, nil, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Tags == nil {
+		resp.Tags = []UserTag{}
+	}
+	return resp.Tags, nil
+}
+
+// validTagName 检查标签名：不能为空，最多 30 个字符。
+func validTagName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%w: tag name is required", ErrInvalidTag)
+	}
+	if utf8.RuneCountInString(name) > maxTagNameRunes {
+		return fmt.Errorf("%w: tag name must be at most %d characters", ErrInvalidTag, maxTagNameRunes)
+	}
+	return nil
+}
+
+// CreateTag 创建用户标签，返回带 ID 的标签。
+func (p *Publisher) CreateTag(ctx context.Context, name string) (UserTag, error) {
+	if err := validTagName(name); err != nil {
+		return UserTag{}, err
+	}
+	var resp struct {
+		Tag UserTag `json:"tag"`
+	}
+	if err := p.callAPI(ctx, tagsCreateURL, map[string]any{"tag": map[string]string{"name": name}}, &resp); err != nil {
+		return UserTag{}, err
+	}
+	p.logger.Printf("[tag] created id=%d name=%q", resp.Tag.ID, resp.Tag.Name)
+	return resp.Tag, nil
+}
+
+// RenameTag 修改标签名。
+func (p *Publisher) RenameTag(ctx context.Context, id int, name string) error {
+	if err := validTagName(name); err != nil {
+		return err
+	}
+	if err := p.callAPI(ctx, tagsUpdateURL, map[string]any{"tag": map[string]any{"id": id, "name": name}}, nil); err != nil {
+		return err
+	}
+	p.logger.Printf("[tag] renamed id=%d name=%q", id, name)
+	return nil
+}
+
+// DeleteTag 删除标签，粉丝身上的该标签一并去掉。系统保留的标签（ID 0～2）不能删除。
+func (p *Publisher) DeleteTag(ctx context.Context, id int) error {
+	if err := p.callAPI(ctx, tagsDeleteURL, map[string]any{"tag": map[string]int{"id": id}}, nil); err != nil {
+		return err
+	}
+	p.logger.Printf("[tag] deleted id=%d", id)
+	return nil
+}
+
+// TagUsers 给粉丝打上标签，untag 为 true 时取消；openids 超过 50 个时分批调用。
+func (p *Publisher) TagUsers(ctx context.Context, id int, openids []string, untag bool) error {
+	if len(openids) == 0 {
+		return fmt.Errorf("%w: openid is required", ErrInvalidTag)
+	}
+	endpoint := tagsBatchTaggingURL
+	if untag {
+		endpoint = tagsBatchUntagURL
+	}
+	for start := 0; start < len(openids); start += maxTagOpenIDsPerCall {
+		batch := openids[start:min(start+maxTagOpenIDsPerCall, len(openids))]
+		if err := p.callAPI(ctx, endpoint, map[string]any{"openid_list": batch, "tagid": id}, nil); err != nil {
+			return fmt.Errorf("openids %d-%d: %w", start+1, start+len(batch), err)
+		}
+	}
+	p.logger.Printf("[tag] id=%d users=%d untag=%v", id, len(openids), untag)
+	return nil
+}
+
+// ResolveTag 按 ID 或名称查找标签，不存在时返回错误。
+func (p *Publisher) ResolveTag(ctx context.Context, ref string) (UserTag, error) {
+	ref = strings.TrimSpace(ref)
+	tags, err := p.ListTags(ctx)
+	if err != nil {
+		return UserTag{}, err
+	}
+	id, idErr := strconv.Atoi(ref)
+	for _, t := range tags {
+		if (idErr == nil && t.ID == id) || t.Name == ref {
+			return t, nil
+		}
+	}
+	return UserTag{}, fmt.Errorf("%w: tag %q not found", ErrInvalidTag, ref)
+}
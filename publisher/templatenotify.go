@@ -0,0 +1,92 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	templateSendURL  = "https://api.weixin.qq.com/cgi-bin/message/template/send"
+	subscribeSendURL = "https://api.weixin.qq.com/cgi-bin/message/subscribe/bizsend"
+	// maxSubscribeThingRunes 为订阅通知 thing 类字段的长度上限。
+	maxSubscribeThingRunes = 20
+)
+
+// defaultTemplateFields 为未配置 fields 时模板消息的字段，适用于“关键词1 标题、关键词2 时间”的模板。
+var defaultTemplateFields = map[string]string{"keyword1": "{title}", "keyword2": "{time}"}
+
+// TemplateNotifyConfig 配置发布成功后通过公众号模板消息（或订阅通知）提醒管理员。
+// OpenIDs 为接收者（需已关注公众号），TemplateID 为公众号后台添加的模板；Subscribe 为 true 时改用订阅通知，
+// 接收者需先订阅该模板。Fields 为模板字段名到取值的映射，取值可包含 {title}、{digest}、{media_id}、
+// {session_id}、{user}、{account}、{time} 占位符；URL 为点击消息打开的地址，可包含 {media_id}、{session_id}，默认打开公众号后台。
+type TemplateNotifyConfig struct {
+	TemplateID string            `json:"template_id"`
+	OpenIDs    []string          `json:"openids"`
+	Subscribe  bool              `json:"subscribe,omitempty"`
+	Fields     map[string]string `json:"fields,omitempty"`
+	URL        string            `json:"url,omitempty"`
+}
+
+// ValidateTemplateNotify 检查模板消息配置，未配置时返回 nil。
+func ValidateTemplateNotify(c *TemplateNotifyConfig) error {
+	switch {
+	case c == nil:
+		return nil
+	case c.TemplateID == "":
+		return errors.New("template_notify: template_id required")
+	case len(c.OpenIDs) == 0:
+		return errors.New("template_notify: openids required")
+	case c.Subscribe && len(c.Fields) == 0:
+		return errors.New("template_notify: fields required for subscribe (e.g. thing1, time2)")
+	}
+	return nil
+}
+
+// SendTemplateNotice 把发布成功的标题与链接以模板消息发送给配置的管理员；未配置或发布失败时不发送。
+// 各接收者分别发送，返回发送失败的接收者的错误。
+func (p *Publisher) SendTemplateNotice(ctx context.Context, n PublishNotice) error {
+	c := p.cfg.TemplateNotify
+	if c == nil || n.Error != "" {
+		return nil
+	}
+	fields := c.Fields
+	if len(fields) == 0 {
+		fields = defaultTemplateFields
+	}
+	values := strings.NewReplacer(
+		"{title}", n.Title, "{digest}", n.Digest, "{media_id}", n.MediaID, "{session_id}", n.SessionID,
+		"{user}", n.User, "{account}", n.Account, "{time}", n.Time.Local().Format("2006-01-02 15:04"),
+	)
+	data := make(map[string]map[string]string, len(fields))
+	for key, tmpl := range fields {
+		v := values.Replace(tmpl)
+		// 订阅通知的 thing 类字段超长时整条消息被拒绝，截断后发送。
+		if c.Subscribe && strings.HasPrefix(key, "thing") && utf8.RuneCountInString(v) > maxSubscribeThingRunes {
+			v = string([]rune(v)[:maxSubscribeThingRunes-1]) + "…"
+		}
+		data[key] = map[string]string{"value": v}
+	}
+	link := c.URL
+	if link == "" {
+		link = defaultPreviewURL
+	}
+	link = strings.NewReplacer("{media_id}", url.QueryEscape(n.MediaID), "{session_id}", url.QueryEscape(n.SessionID)).Replace(link)
+
+	endpoint, linkKey := templateSendURL, "url"
+	if c.Subscribe {
+		endpoint, linkKey = subscribeSendURL, "page"
+	}
+	var errs []error
+	for _, openid := range c.OpenIDs {
+		payload := map[string]any{"touser": openid, "template_id": c.TemplateID, linkKey: link, "data": data}
+		if err := p.callAPI(ctx, endpoint, payload, nil); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", openid, err))
+		}
+	}
+	p.logger.Printf("[notify] template message media_id=%s sent=%d failed=%d", n.MediaID, len(c.OpenIDs)-len(errs), len(errs))
+	return errors.Join(errs...)
+}
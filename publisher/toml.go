@@ -0,0 +1,74 @@
+package publisher
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// parseTOML 解析 TOML 配置或 front matter，返回可直接转成 JSON 的 map：本地日期与时间按原样保留为字符串，
+// 带时区的日期时间转为 RFC 3339 字符串；inf 与 nan 无法转成 JSON，返回错误。
+func parseTOML(data string) (map[string]any, error) {
+	doc := map[string]any{}
+	if _, err := toml.Decode(data, &doc); err != nil {
+		return nil, err
+	}
+	for k, v := range doc {
+		conv, err := tomlJSONValue(k, v)
+		if err != nil {
+			return nil, err
+		}
+		doc[k] = conv
+	}
+	return doc, nil
+}
+
+// tomlJSONValue 把 TOML 解码得到的值转换为 JSON 可表示的值，key 为出错时提示的配置路径。
+func tomlJSONValue(key string, v any) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			conv, err := tomlJSONValue(key+"."+k, item)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = conv
+		}
+	case []map[string]any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			conv, err := tomlJSONValue(fmt.Sprintf("%s[%d]", key, i), item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = conv
+		}
+		return out, nil
+	case []any:
+		for i, item := range v {
+			conv, err := tomlJSONValue(fmt.Sprintf("%s[%d]", key, i), item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = conv
+		}
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, fmt.Errorf("toml: %s: inf and nan are not supported", key)
+		}
+	case time.Time:
+		// 本地日期时间以时区名区分（见 BurntSushi/toml internal/tz.go）。
+		switch v.Location().String() {
+		case "date-local":
+			return v.Format(time.DateOnly), nil
+		case "time-local":
+			return v.Format("15:04:05.999999999"), nil
+		case "datetime-local":
+			return v.Format("2006-01-02T15:04:05.999999999"), nil
+		}
+		return v.Format(time.RFC3339Nano), nil
+	}
+	return v, nil
+}
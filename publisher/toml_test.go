@@ -0,0 +1,52 @@
+package publisher
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	doc, err := parseTOML(`
+app_id = "wx1"
+date = 2024-05-01
+at = 2024-05-01T08:00:00
+updated = 2024-05-01T08:00:00+08:00
+
+[llm]
+model = "m"
+temperature = 0.7
+
+[[notify]]
+type = "wecom"
+
+[[notify]]
+type = "feishu"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"app_id":"wx1","at":"2024-05-01T08:00:00","date":"2024-05-01","llm":{"model":"m","temperature":0.7},"notify":[{"type":"wecom"},{"type":"feishu"}],"updated":"2024-05-01T08:00:00+08:00"}`
+	if string(got) != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	for name, src := range map[string]string{
+		"duplicate table": "[llm]\nmodel = \"a\"\n[llm]\nprovider = \"b\"\n",
+		"duplicate key":   "a = 1\na = 2\n",
+		"inf":             "[budget]\ndaily_max_cost = inf\n",
+		"nan":             "[[llm.fallbacks]]\ntemperature = nan\n",
+	} {
+		if _, err := parseTOML(src); err == nil {
+			t.Errorf("%s: parseTOML succeeded; want an error", name)
+		} else if name == "nan" && !strings.Contains(err.Error(), "llm.fallbacks[0].temperature") {
+			t.Errorf("nan: error %q does not name the key", err)
+		}
+	}
+}
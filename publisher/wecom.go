@@ -0,0 +1,191 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 帐号类型：official 发布到公众号草稿箱，wecom 以企业微信应用消息发送给成员或群聊。
+const (
+	AccountOfficial = "official"
+	AccountWeCom    = "wecom"
+)
+
+// defaultWeComAPIBase 为企业微信接口地址。
+const defaultWeComAPIBase = "https://qyapi.weixin.qq.com"
+
+// wecomPaths 为企业微信中与公众号上传接口对应的接口路径：封面上传为临时素材（3 天内有效）。
+var wecomPaths = map[string]string{
+	uploadImageURL: "/cgi-bin/media/upload",
+	uploadImgURL:   "/cgi-bin/media/uploadimg",
+}
+
+// WeComConfig 为 account_type 为 wecom 时发布使用的企业微信自建应用。文章以图文消息（mpnews）发送：
+// ChatID 非空时发送到应用创建的群聊，否则发送给 ToUser（成员 UserID，"@all" 为全部成员）、ToParty（部门 ID）
+// 与 ToTag（标签 ID），接收者须在应用的可见范围内。Safe 为 true 时以保密消息发送（不可转发、带水印）。
+type WeComConfig struct {
+	CorpID  string   `json:"corp_id"`
+	AgentID int      `json:"agent_id"`
+	Secret  string   `json:"secret"`
+	ToUser  []string `json:"to_user,omitempty"`
+	ToParty []string `json:"to_party,omitempty"`
+	ToTag   []string `json:"to_tag,omitempty"`
+	ChatID  string   `json:"chat_id,omitempty"`
+	Safe    bool     `json:"safe,omitempty"`
+	// APIBase 默认 https://qyapi.weixin.qq.com。
+	APIBase string `json:"api_base,omitempty"`
+}
+
+func (c *WeComConfig) apiBase() string {
+	if c.APIBase == "" {
+		return defaultWeComAPIBase
+	}
+	return strings.TrimRight(c.APIBase, "/")
+}
+
+// ValidateAccount 按 account_type 检查发布帐号的凭据：公众号需要 app_id 与 app_secret，企业微信需要 wecom 段落。
+func ValidateAccount(cfg Config) error {
+	switch cfg.AccountType {
+	case "", AccountOfficial:
+		if cfg.AppID == "" || cfg.AppSecret == "" {
+			return errors.New("config must include app_id and app_secret")
+		}
+	case AccountWeCom:
+		c := cfg.WeCom
+		switch {
+		case c == nil:
+			return errors.New("account_type wecom requires the wecom section")
+		case c.CorpID == "" || c.Secret == "" || c.AgentID == 0:
+			return errors.New("wecom: corp_id, agent_id and secret required")
+		case c.ChatID == "" && len(c.ToUser)+len(c.ToParty)+len(c.ToTag) == 0:
+			return errors.New("wecom: chat_id or at least one of to_user, to_party, to_tag required")
+		}
+	default:
+		return fmt.Errorf("account_type must be %s or %s", AccountOfficial, AccountWeCom)
+	}
+	return nil
+}
+
+// AccountID 返回发布记录与通知中的帐号标识：公众号为 app_id，企业微信为 corp_id/agent_id。
+func (c Config) AccountID() string {
+	if c.AccountType == AccountWeCom && c.WeCom != nil {
+		return c.WeCom.CorpID + "/" + strconv.Itoa(c.WeCom.AgentID)
+	}
+	return c.AppID
+}
+
+// endpoint 返回当前帐号类型使用的上传接口：企业微信帐号换成对应的企业微信接口。
+func (p *Publisher) endpoint(officialURL string) string {
+	if p.cfg.AccountType != AccountWeCom {
+		return officialURL
+	}
+	return p.cfg.WeCom.apiBase() + wecomPaths[officialURL]
+}
+
+// getWeComToken 用企业微信应用的 corp_id 与 secret 获取 access_token。
+func getWeComToken(client *http.Client, c *WeComConfig) (string, error) {
+	endpoint := c.apiBase() + "/cgi-bin/gettoken"
+	resp, err := client.Get(endpoint + "?corpid=" + url.QueryEscape(c.CorpID) + "&corpsecret=" + url.QueryEscape(c.Secret))
+	if err != nil {
+		// 与公众号相同，去掉错误中带 secret 的完整 URL。
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return "", fmt.Errorf("%s %s: %w", urlErr.Op, endpoint, urlErr.Err)
+		}
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var data accessTokenResp
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return "", err
+	}
+	if data.AccessToken == "" {
+		return "", fmt.Errorf("failed to get wecom access_token: %w", &wechatAPIError{Code: data.ErrCode, Msg: data.ErrMsg})
+	}
+	return data.AccessToken, nil
+}
+
+// wecomArticle 为企业微信图文消息（mpnews）中的一篇文章。
+type wecomArticle struct {
+	Title        string `json:"title"`
+	ThumbMediaID string `json:"thumb_media_id"`
+	Author       string `json:"author,omitempty"`
+	Content      string `json:"content"`
+	Digest       string `json:"digest,omitempty"`
+}
+
+// publishWeCom 复用 Markdown 转换与图片上传，把文章以图文消息发送到企业微信，返回 wecom:<msgid>
+// （群聊消息没有 msgid，返回 wecom:chat:<chat_id>）。企业微信消息发出后不能修改，不支持 UpdateMediaID。
+func (p *Publisher) publishWeCom(ctx context.Context, params PublishParams) (string, error) {
+	if params.UpdateMediaID != "" {
+		return "", errors.New("wecom messages cannot be updated once sent; publish a new one instead")
+	}
+	c := p.cfg.WeCom
+	if err := p.refreshAccessToken(ctx); err != nil {
+		return "", fmt.Errorf("failed to init access_token: %w", err)
+	}
+	params.report("token")
+	p.logger.Printf("[publish] wecom start title=%q md=%s cover=%s", params.Title, params.MarkdownPath, params.CoverPath)
+
+	contentHTML, err := p.markdownContent(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	thumbMediaID, err := p.uploadCover(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	params.report("cover")
+
+	safe := 0
+	if c.Safe {
+		safe = 1
+	}
+	payload := map[string]any{
+		"msgtype": "mpnews",
+		"mpnews": map[string]any{"articles": []wecomArticle{{
+			Title: params.Title, ThumbMediaID: thumbMediaID, Author: params.Author, Content: contentHTML, Digest: params.Digest,
+		}}},
+		"safe": safe,
+	}
+	params.report("draft")
+	if c.ChatID != "" {
+		payload["chatid"] = c.ChatID
+		if err := p.callAPI(ctx, c.apiBase()+"/cgi-bin/appchat/send", payload, nil); err != nil {
+			p.logger.Printf("[publish] wecom appchat/send failed: %v", err)
+			return "", err
+		}
+		params.report("done")
+		p.logger.Printf("[publish] wecom success title=%q chat=%s", params.Title, c.ChatID)
+		return "wecom:chat:" + c.ChatID, nil
+	}
+
+	payload["agentid"] = c.AgentID
+	payload["touser"] = strings.Join(c.ToUser, "|")
+	payload["toparty"] = strings.Join(c.ToParty, "|")
+	payload["totag"] = strings.Join(c.ToTag, "|")
+	var resp struct {
+		MsgID        string `json:"msgid"`
+		InvalidUser  string `json:"invaliduser"`
+		InvalidParty string `json:"invalidparty"`
+		InvalidTag   string `json:"invalidtag"`
+	}
+	if err := p.callAPI(ctx, c.apiBase()+"/cgi-bin/message/send", payload, &resp); err != nil {
+		p.logger.Printf("[publish] wecom message/send failed: %v", err)
+		return "", err
+	}
+	// 部分接收者无效时企业微信仍发送给其余接收者，只记录日志。
+	if resp.InvalidUser != "" || resp.InvalidParty != "" || resp.InvalidTag != "" {
+		p.logger.Printf("[publish] wecom skipped invalid receivers user=%q party=%q tag=%q", resp.InvalidUser, resp.InvalidParty, resp.InvalidTag)
+	}
+	params.report("done")
+	p.logger.Printf("[publish] wecom success title=%q", params.Title)
+	return "wecom:" + resp.MsgID, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/client"
+	"auto_wechat_article_publisher/publisher"
+)
+
+// 远程模式的环境变量，与对应的 flag 等价，便于在 shell 配置中设置一次。
+const (
+	serverEnv = "AWP_SERVER"
+	tokenEnv  = "AWP_TOKEN"
+	apiKeyEnv = "AWP_API_KEY"
+)
+
+// remoteFlags 为 --server 模式的参数：设置后命令调用远程服务的接口，不读取本地配置与公众号凭据。
+type remoteFlags struct {
+	server *string
+	token  *string
+	apiKey *string
+}
+
+func addRemoteFlags(fs *flag.FlagSet) remoteFlags {
+	return remoteFlags{
+		server: fs.String("server", os.Getenv(serverEnv), "call the HTTP API of a running server, e.g. http://host:8080 (env "+serverEnv+")"),
+		token:  fs.String("token", os.Getenv(tokenEnv), "login token for --server, from POST /api/login (env "+tokenEnv+")"),
+		apiKey: fs.String("api-key", os.Getenv(apiKeyEnv), "X-API-Key for --server when the server binds sessions to callers (env "+apiKeyEnv+")"),
+	}
+}
+
+// client 返回远程服务的客户端；未设置 --server 时返回 nil。
+func (f remoteFlags) client() *client.Client {
+	if strings.TrimSpace(*f.server) == "" {
+		return nil
+	}
+	// 服务端生成首稿可能需要几分钟。
+	c := client.New(*f.server, &http.Client{Timeout: 10 * time.Minute})
+	c.Token, c.APIKey = *f.token, *f.apiKey
+	return c
+}
+
+// generateRemote 在远程服务上新建 session 并生成首稿。
+func generateRemote(c *client.Client, req client.CreateSessionRequest, out string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	sess, err := c.CreateSession(ctx, req)
+	if err != nil {
+		return err
+	}
+	res := newGenerateResult(sess.Draft)
+	res.SessionID = sess.SessionID
+	return writeGenerated(res, out)
+}
+
+// markdownImageRe 匹配 Markdown 图片，第 2 组为地址。
+var markdownImageRe = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(\s+"[^"]*")?\s*\)`)
+
+// publishRemote 把本地 Markdown 发布到远程服务：新建空 session，上传封面与正文中的本地图片，
+// 提交发布任务并等待结果。启用审核流程的服务只允许发布审核通过的稿件，此时会返回 409。
+func publishRemote(c *client.Client, params publisher.PublishParams) (publishResult, error) {
+	res := publishResult{Title: params.Title, Images: []uploadedImage{}}
+	data := []byte(params.Markdown)
+	if params.Markdown == "" {
+		var err error
+		if data, err = os.ReadFile(params.MarkdownPath); err != nil {
+			return res, err
+		}
+	}
+	_, body := publisher.ParseFrontMatter(data)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	sess, err := c.CreateSession(ctx, client.CreateSessionRequest{Topic: params.Title, Stream: true})
+	if err != nil {
+		return res, err
+	}
+	res.SessionID = sess.SessionID
+	cover, err := c.UploadFile(ctx, sess.SessionID, params.CoverPath, client.UsageCover)
+	if err != nil {
+		return res, fmt.Errorf("upload cover: %w", err)
+	}
+
+	// 正文中的本地图片上传到 session，地址替换为上传后的路径，发布时由服务上传到微信。
+	uploaded := map[string]string{}
+	var uploadErr error
+	md := markdownImageRe.ReplaceAllStringFunc(string(body), func(m string) string {
+		sub := markdownImageRe.FindStringSubmatch(m)
+		src := sub[2]
+		if uploadErr != nil || strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") || strings.HasPrefix(src, "data:") {
+			return m
+		}
+		if path, ok := uploaded[src]; ok {
+			return "![" + sub[1] + "](" + path + ")"
+		}
+		local := src
+		if !filepath.IsAbs(local) {
+			local = filepath.Join(filepath.Dir(params.MarkdownPath), local)
+		}
+		up, err := c.UploadFile(ctx, sess.SessionID, local, client.UsageInline)
+		if err != nil {
+			uploadErr = fmt.Errorf("upload %s: %w", src, err)
+			return m
+		}
+		uploaded[src] = up.Path
+		res.Images = append(res.Images, uploadedImage{Path: local, URL: up.URL})
+		return "![" + sub[1] + "](" + up.Path + ")"
+	})
+	if uploadErr != nil {
+		return res, uploadErr
+	}
+	if len(uploaded) > 0 {
+		fmt.Fprintf(os.Stderr, "uploaded cover and %d images\n", len(uploaded))
+	}
+
+	job, err := c.Publish(ctx, client.PublishRequest{
+		SessionID:     sess.SessionID,
+		CoverPath:     cover.Path,
+		Author:        params.Author,
+		Title:         params.Title,
+		Digest:        params.Digest,
+		Markdown:      md,
+		UpdateMediaID: params.UpdateMediaID,
+		UpdateIndex:   params.UpdateIndex,
+	})
+	if err != nil {
+		return res, err
+	}
+	job, err = c.WaitJob(ctx, job.ID, time.Second)
+	if err != nil {
+		return res, err
+	}
+	if job.Result == nil {
+		return res, errors.New("publish job finished without a result")
+	}
+	res.MediaID, res.Updated = job.Result.MediaID, job.Result.Updated
+	return res, nil
+}
+
+// historyRemote 从远程服务读取发布记录。
+func historyRemote(c *client.Client, filter publisher.PublishFilter) ([]publisher.PublishRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	opts := client.ListPublishesOptions{
+		SessionID: filter.SessionID,
+		User:      filter.User,
+		Status:    filter.Status,
+		Query:     filter.Query,
+		Since:     filter.Since,
+		Limit:     filter.Limit,
+	}
+	if opts.Limit == 0 {
+		opts.Limit = -1
+	}
+	return c.ListPublishes(ctx, opts)
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"auto_wechat_article_publisher/generator"
+	"auto_wechat_article_publisher/publisher"
+)
+
+const replHelp = `直接输入修改意见即可修订稿件，或使用命令：
+  /show               分页查看当前稿件
+  /title [标题]       给出标题时直接替换，否则生成备选标题供选择
+  /polish [重点]      润色稿件，可指定润色重点
+  /save [路径]        保存 Markdown（默认 --out 指定的路径）
+  /publish [封面]     发布到草稿箱
+  /help               显示本帮助
+  /quit               退出（也可按 Ctrl-D）
+生成过程中按 Ctrl-C 可中断本轮。`
+
+// repl 为 generate -i 的交互循环，直接在终端驱动 generator.Session。
+type repl struct {
+	cfg  publisher.Config
+	sess *generator.Session
+	in   *bufio.Reader
+	sigs chan os.Signal
+	// out 非空时每轮结束后自动保存到该路径。
+	out    string
+	cover  string
+	author string
+	pub    *publisher.Publisher
+}
+
+// runGenerateREPL 生成首稿后进入交互循环，直到发布后退出或用户输入 /quit。
+func runGenerateREPL(cfg publisher.Config, agent *generator.Agent, spec generator.Spec, research bool, out string) error {
+	r := &repl{cfg: cfg, in: bufio.NewReader(os.Stdin), sigs: make(chan os.Signal, 1), out: out}
+	signal.Notify(r.sigs, os.Interrupt)
+	defer signal.Stop(r.sigs)
+
+	for strings.TrimSpace(spec.Topic) == "" {
+		topic, ok := r.prompt("主题：")
+		if !ok {
+			return nil
+		}
+		spec.Topic = topic
+	}
+	r.sess = generator.NewSession("cli", spec, agent)
+	if research {
+		err := r.run(func(ctx context.Context) error {
+			results, err := r.sess.Research(ctx, "")
+			fmt.Fprintf(os.Stderr, "检索到 %d 条资料\n", len(results))
+			return err
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "联网检索失败，继续生成：%v\n", err)
+		}
+	}
+	for {
+		err := r.generate("生成首稿", func(ctx context.Context, onChunk func(string)) error {
+			_, err := r.sess.ProposeStream(ctx, onChunk)
+			return err
+		})
+		if err == nil {
+			break
+		}
+		fmt.Fprintf(os.Stderr, "生成失败：%v\n", err)
+		if answer, ok := r.prompt("重试？[Y/n] "); !ok || strings.EqualFold(answer, "n") {
+			return err
+		}
+	}
+	r.show()
+	fmt.Fprintln(os.Stderr, "输入修改意见修订稿件，/help 查看命令。")
+
+	for {
+		line, ok := r.prompt("> ")
+		if !ok {
+			fmt.Fprintln(os.Stderr)
+			return nil
+		}
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "/") {
+			err := r.generate("修订", func(ctx context.Context, onChunk func(string)) error {
+				_, err := r.sess.ReviseStream(ctx, line, onChunk)
+				return err
+			})
+			r.afterChange(err)
+			continue
+		}
+		cmd, arg, _ := strings.Cut(line[1:], " ")
+		arg = strings.TrimSpace(arg)
+		switch cmd {
+		case "help", "h", "?":
+			fmt.Fprintln(os.Stderr, replHelp)
+		case "show":
+			r.show()
+		case "title":
+			r.title(arg)
+		case "polish":
+			fmt.Fprintln(os.Stderr, "润色中…")
+			r.afterChange(r.run(func(ctx context.Context) error {
+				_, err := r.sess.Polish(ctx, arg)
+				return err
+			}))
+		case "save":
+			if arg == "" {
+				arg = r.out
+			}
+			if arg == "" {
+				fmt.Fprintln(os.Stderr, "用法：/save <路径>")
+				continue
+			}
+			if err := r.save(arg); err != nil {
+				fmt.Fprintf(os.Stderr, "保存失败：%v\n", err)
+			}
+		case "publish":
+			if r.publish(arg) {
+				return nil
+			}
+		case "quit", "exit", "q":
+			return nil
+		default:
+			fmt.Fprintf(os.Stderr, "未知命令 /%s，/help 查看命令\n", cmd)
+		}
+	}
+}
+
+// prompt 输出提示并读取一行；输入结束（Ctrl-D）时返回 false。
+func (r *repl) prompt(label string) (string, bool) {
+	fmt.Fprint(os.Stderr, label)
+	line, err := r.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", false
+	}
+	return strings.TrimSpace(line), true
+}
+
+// run 执行一次模型调用，期间 Ctrl-C 只取消本次调用。
+func (r *repl) run(fn func(ctx context.Context) error) error {
+	select {
+	case <-r.sigs:
+	default:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-r.sigs:
+			cancel()
+		case <-done:
+		}
+	}()
+	err := fn(ctx)
+	if errors.Is(err, context.Canceled) {
+		return errors.New("已中断")
+	}
+	return err
+}
+
+// generate 以流式方式执行生成或修订，在 stderr 显示已生成的字数。
+func (r *repl) generate(label string, fn func(ctx context.Context, onChunk func(string)) error) error {
+	n := 0
+	err := r.run(func(ctx context.Context) error {
+		return fn(ctx, func(chunk string) {
+			n += utf8.RuneCountInString(chunk)
+			fmt.Fprintf(os.Stderr, "\r%s… %d 字", label, n)
+		})
+	})
+	fmt.Fprintln(os.Stderr)
+	return err
+}
+
+// afterChange 在稿件变更后显示结果并按需自动保存。
+func (r *repl) afterChange(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "失败：%v\n", err)
+		return
+	}
+	r.show()
+	r.autosave()
+}
+
+func (r *repl) autosave() {
+	if r.out == "" {
+		return
+	}
+	if err := r.save(r.out); err != nil {
+		fmt.Fprintf(os.Stderr, "自动保存失败：%v\n", err)
+	}
+}
+
+// show 显示当前稿件；输出到终端时通过 $PAGER（默认 less -FRX）分页。
+func (r *repl) show() {
+	d := r.sess.Draft
+	var b strings.Builder
+	fmt.Fprintf(&b, "标题：%s\n摘要：%s\n字数：%d", d.Title, d.Digest, d.WordCount)
+	if d.Provider != "" {
+		fmt.Fprintf(&b, "  模型：%s", d.Provider)
+	}
+	b.WriteString("\n")
+	for _, hit := range d.Sensitive {
+		fmt.Fprintf(&b, "敏感词：%s（第 %d 行）\n", hit.Word, hit.Line)
+	}
+	b.WriteString(strings.Repeat("─", 40) + "\n\n")
+	b.WriteString(d.Markdown + "\n")
+	page(b.String())
+}
+
+func page(text string) {
+	if fi, err := os.Stdout.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		fmt.Print(text)
+		return
+	}
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less -FRX"
+	}
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Print(text)
+	}
+}
+
+// title 直接替换标题，或生成备选标题并让用户选择。
+func (r *repl) title(title string) {
+	if title == "" {
+		var candidates []generator.TitleCandidate
+		err := r.run(func(ctx context.Context) error {
+			var err error
+			candidates, err = r.sess.SuggestTitles(ctx, 5, true)
+			return err
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "生成标题失败：%v\n", err)
+			return
+		}
+		for i, c := range candidates {
+			fmt.Fprintf(os.Stderr, "  %d. %s（%.1f 分）\n", i+1, c.Title, c.Score)
+		}
+		answer, ok := r.prompt("选择编号，或直接输入标题（回车保持不变）：")
+		if !ok || answer == "" {
+			return
+		}
+		title = answer
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(candidates) {
+			title = candidates[i-1].Title
+		}
+	}
+	if _, err := r.sess.ApplyTitle(title); err != nil {
+		fmt.Fprintf(os.Stderr, "失败：%v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "标题改为：%s\n", title)
+	r.autosave()
+}
+
+func (r *repl) save(path string) error {
+	if err := os.WriteFile(path, []byte(r.sess.Draft.Markdown+"\n"), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "已保存到 %s\n", path)
+	return nil
+}
+
+// publish 把当前稿件发布到草稿箱，成功时返回 true。
+func (r *repl) publish(cover string) bool {
+	if cover == "" {
+		cover = r.cover
+	}
+	if cover == "" {
+		answer, ok := r.prompt("封面图片路径：")
+		if !ok || answer == "" {
+			return false
+		}
+		cover = answer
+		author, _ := r.prompt("作者（回车留空）：")
+		r.author = author
+	}
+	if _, err := os.Stat(cover); err != nil {
+		fmt.Fprintf(os.Stderr, "封面不可用：%v\n", err)
+		return false
+	}
+	r.cover = cover
+	if r.pub == nil {
+		logger := log.New(io.Discard, "", 0)
+		if verbose {
+			logger = log.Default()
+		}
+		pub, err := publisher.New(r.cfg, nil, verbose, logger)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "发布失败：%v\n", err)
+			return false
+		}
+		r.pub = pub
+	}
+
+	d := r.sess.Draft
+	tmp, err := os.CreateTemp("", "draft-*.md")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "发布失败：%v\n", err)
+		return false
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(publisher.StripLeadingH1(d.Markdown))
+	tmp.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "发布失败：%v\n", err)
+		return false
+	}
+
+	params := publisher.PublishParams{MarkdownPath: tmp.Name(), Title: d.Title, CoverPath: cover, Author: r.author, Digest: d.Digest}
+	params.Progress = func(stage string) { fmt.Fprintf(os.Stderr, "  %s\n", stage) }
+	// 交互模式不等待锁，被占用时提示稍后重试。
+	release, err := acquirePublishLock(r.cfg, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "发布失败：%v\n", err)
+		return false
+	}
+	defer release()
+	var mediaID string
+	err = r.run(func(ctx context.Context) error {
+		var err error
+		mediaID, err = publishAndRecord(ctx, r.cfg, r.pub, params)
+		return err
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "发布失败：%v\n", err)
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "已发布到草稿箱，media_id=%s\n", mediaID)
+	fmt.Println(mediaID)
+	return true
+}
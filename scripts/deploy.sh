@@ -34,6 +34,8 @@ SERVICE_NAME="${SERVICE_NAME:-auto-wechat.service}"
 SERVICE_UNIT_PATH="${SERVICE_UNIT_PATH:-/etc/systemd/system/$SERVICE_NAME}"
 BINARY="${BINARY:-$ROOT/bin/auto-wechat-article-publisher}"
 CONFIG_FILE="${CONFIG_FILE:-$ROOT/config/config.json}"
+# Optional WECHAT_* overrides (e.g. WECHAT_APP_SECRET) loaded by systemd; keeps secrets out of CONFIG_FILE.
+APP_ENV_FILE="${APP_ENV_FILE:-$ROOT/config/app.env}"
 BIND_ADDR="${BIND_ADDR:-}"
 STATIC_SRC="${STATIC_SRC:-$ROOT/server/web/dist}"
 STATIC_ROOT="${STATIC_ROOT:-/var/www/auto-wechat}"
@@ -93,7 +95,7 @@ try:
     raise SystemExit
 except Exception:
     pass
-m = re.search(r'"server_addr"\s*:\s*"([^"]+)"', text)
+m = re.search(r'^\s*"?server_addr"?\s*[:=]\s*["\']?([^"\'\s#]+)', text, re.M)
 if m:
     print(m.group(1))
 PY
@@ -165,6 +167,19 @@ server {
         proxy_connect_timeout ${PROXY_CONNECT_TIMEOUT};
     }
 
+    # WebSocket session events
+    location = /api/ws {
+        proxy_pass ${PROXY_PASS};
+        proxy_set_header Host \$host;
+        proxy_set_header X-Real-IP \$remote_addr;
+        proxy_set_header X-Forwarded-For \$proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto \$scheme;
+        proxy_http_version 1.1;
+        proxy_set_header Upgrade \$http_upgrade;
+        proxy_set_header Connection "upgrade";
+        proxy_read_timeout 3600s;
+    }
+
     # Serve uploaded images via backend so it can read ./uploads
     location ^~ /uploads/ {
         proxy_pass ${PROXY_PASS};
@@ -214,9 +229,12 @@ After=network.target
 User=${APP_USER}
 Group=${APP_USER}
 WorkingDirectory=${ROOT}
-ExecStart=${BINARY} --serve --config ${CONFIG_FILE} ${BIND_ADDR:+--addr ${BIND_ADDR}}
+EnvironmentFile=-${APP_ENV_FILE}
+ExecStart=${BINARY} serve --config ${CONFIG_FILE} ${BIND_ADDR:+--addr ${BIND_ADDR}}
 Restart=always
 RestartSec=5
+# 收到 SIGTERM 后等待发布任务完成（shutdown_timeout，默认 30 秒）
+TimeoutStopSec=60
 StandardOutput=append:${LOG_FILE_ABS}
 StandardError=append:${LOG_FILE_ABS}
 
@@ -275,7 +293,7 @@ case "${1:-deploy}" in
     cat >&2 <<EOF
 Usage: sudo ./scripts/deploy.sh [deploy|start|stop|restart|status|reload-nginx]
 Env vars (or config/deploy.env) to override defaults:
-  APP_USER, BINARY, CONFIG_FILE, BIND_ADDR, STATIC_SRC, STATIC_ROOT,
+  APP_USER, BINARY, CONFIG_FILE, APP_ENV_FILE, BIND_ADDR, STATIC_SRC, STATIC_ROOT,
   DOMAIN, DOMAIN_ALIASES, HTTPS_PORT, HTTP_PORT,
   SSL_CERT_PATH, SSL_KEY_PATH, SERVICE_NAME, NGINX_SITE_NAME
 EOF
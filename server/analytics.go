@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// analyticsCollector 为图文数据的定时采集及其运行状态；状态只保存在内存中。
+type analyticsCollector struct {
+	cron publisher.Cron
+	days int
+
+	mu         sync.Mutex
+	Cron       string                            `json:"cron"`
+	NextRun    time.Time                         `json:"next_run"`
+	LastRun    *time.Time                        `json:"last_run,omitempty"`
+	LastResult *publisher.AnalyticsCollectResult `json:"last_result,omitempty"`
+	LastError  string                            `json:"last_error,omitempty"`
+	Running    bool                              `json:"running"`
+}
+
+// newAnalyticsCollector 校验采集配置，未配置时返回 nil。
+func newAnalyticsCollector(cfg *publisher.AnalyticsConfig, now time.Time) (*analyticsCollector, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	expr := cfg.Cron
+	if expr == "" {
+		expr = publisher.DefaultAnalyticsCron
+	}
+	c, err := publisher.ParseCron(expr)
+	if err != nil {
+		return nil, fmt.Errorf("analytics: %w", err)
+	}
+	next := c.Next(now)
+	if next.IsZero() {
+		return nil, fmt.Errorf("analytics: cron %q never fires", expr)
+	}
+	return &analyticsCollector{cron: c, days: cfg.Days, Cron: expr, NextRun: next}, nil
+}
+
+// snapshot 返回采集状态的副本。
+func (a *analyticsCollector) snapshot() *analyticsCollector {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return &analyticsCollector{Cron: a.Cron, NextRun: a.NextRun, LastRun: a.LastRun, LastResult: a.LastResult, LastError: a.LastError, Running: a.Running}
+}
+
+// runAnalytics 定期检查是否到了采集时间。服务停止期间错过的采集在下一次补齐。
+func (s *Server) runAnalytics(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		select {
+		case now = <-ticker.C:
+		case <-s.stop:
+			return
+		}
+		a := s.collector
+		a.mu.Lock()
+		due := !a.Running && !now.Before(a.NextRun)
+		if due {
+			a.NextRun = a.cron.Next(now)
+			a.Running, a.LastRun = true, &now
+		}
+		a.mu.Unlock()
+		if due {
+			s.tasks.Add(1)
+			go func() {
+				defer s.tasks.Done()
+				s.collectAnalytics(now)
+			}()
+		}
+	}
+}
+
+// collectAnalytics 调用数据统计接口采集图文数据；服务关闭时中止。
+func (s *Server) collectAnalytics(now time.Time) {
+	a := s.collector
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	var res publisher.AnalyticsCollectResult
+	p, err := s.ensurePublisher()
+	if err == nil {
+		res, err = publisher.CollectAnalytics(ctx, p, s.analytics, now, a.days, false)
+	}
+	a.mu.Lock()
+	a.Running, a.LastResult, a.LastError = false, &res, ""
+	if err != nil {
+		a.LastError = err.Error()
+	}
+	a.mu.Unlock()
+	if err != nil {
+		log.Printf("[analytics] collect failed: %v", err)
+		return
+	}
+	log.Printf("[analytics] collected days=%v articles=%d totals=%d followers=%d", res.Collected, res.Articles, res.Totals, res.Followers)
+}
+
+// handleAnalytics 返回采集到的图文数据：每篇文章在区间内的每日数据与累计数据，以及公众号的每日数据。
+// 写作者只能查看自己发布的文章（按发布记录中的标题匹配），不返回公众号的每日数据。
+// Path: GET /api/analytics?since=YYYY-MM-DD&until=YYYY-MM-DD&q=&msgid=
+func (s *Server) handleAnalytics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	filter := publisher.AnalyticsFilter{Since: query.Get("since"), Until: query.Get("until"), Query: query.Get("q"), MsgID: query.Get("msgid")}
+	if !validAnalyticsDates(w, filter) {
+		return
+	}
+	if user := currentUser(r); user != "" && !s.hasRole(r, roleReviewer) && !s.hasRole(r, rolePublisher) {
+		records, err := s.publishes.List(publisher.PublishFilter{User: user, Status: publisher.PublishSucceeded})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		filter.Titles = map[string]bool{}
+		for _, rec := range records {
+			filter.Titles[rec.Title] = true
+		}
+	}
+	data, err := s.analytics.Load()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp := struct {
+		publisher.AnalyticsReport
+		Collector *analyticsCollector `json:"collector,omitempty"`
+	}{AnalyticsReport: data.Report(filter)}
+	if s.collector != nil {
+		resp.Collector = s.collector.snapshot()
+	}
+	writeJSON(w, resp)
+}
+
+// handleAnalyticsFollowers 返回区间内每天的粉丝变化，并附当天的图文阅读次数与发表的文章，便于对照发文与涨粉。
+// 粉丝数据属于整个公众号，启用登录时写作者不能查看。
+// Path: GET /api/analytics/followers?since=YYYY-MM-DD&until=YYYY-MM-DD
+func (s *Server) handleAnalyticsFollowers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if currentUser(r) != "" && !s.hasRole(r, roleReviewer) && !s.hasRole(r, rolePublisher) {
+		http.Error(w, "reviewer or publisher role required", http.StatusForbidden)
+		return
+	}
+	query := r.URL.Query()
+	filter := publisher.AnalyticsFilter{Since: query.Get("since"), Until: query.Get("until")}
+	if !validAnalyticsDates(w, filter) {
+		return
+	}
+	data, err := s.analytics.Load()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, data.FollowerReport(filter))
+}
+
+// validAnalyticsDates 检查 since、until 的格式，无效时返回 400。
+func validAnalyticsDates(w http.ResponseWriter, filter publisher.AnalyticsFilter) bool {
+	for name, v := range map[string]string{"since": filter.Since, "until": filter.Until} {
+		if _, err := time.Parse("2006-01-02", v); v != "" && err != nil {
+			http.Error(w, "invalid "+name+": use YYYY-MM-DD", http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
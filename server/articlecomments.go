@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+type commentReplyReq struct {
+	Content string `json:"content"`
+}
+
+// handleArticleComments 管理已群发图文的留言。{article} 为 msg_data_id 或数据统计中的 msgid（msg_data_id_n）。
+// 启用登录时只有审核人与发布人可以访问，修改操作写入审计日志。
+// Path: GET /api/articles/{article}/comments?type=all|normal|elected&offset=0&count=50,
+// POST /api/articles/{article}/comments/open|close,
+// POST /api/articles/{article}/comments/{user_comment_id}/elect|unelect|reply
+func (s *Server) handleArticleComments(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/articles/"), "/")
+	if len(parts) < 2 || parts[1] != "comments" || len(parts) > 4 {
+		http.NotFound(w, r)
+		return
+	}
+	if currentUser(r) != "" && !s.hasRole(r, roleReviewer) && !s.hasRole(r, rolePublisher) {
+		http.Error(w, "reviewer or publisher role required", http.StatusForbidden)
+		return
+	}
+	target, err := publisher.ParseCommentTarget(parts[0])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rest := parts[2:]
+	if len(rest) == 0 {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.listArticleComments(w, r, target)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	p, err := s.ensurePublisher()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	var action, detail string
+	switch {
+	case len(rest) == 1 && (rest[0] == "open" || rest[0] == "close"):
+		action, detail = rest[0], rest[0]+" comments"
+		err = p.SetCommentsOpen(ctx, target, action == "open")
+	case len(rest) == 2:
+		id, perr := strconv.ParseInt(rest[0], 10, 64)
+		if perr != nil || id <= 0 {
+			http.Error(w, "invalid user_comment_id", http.StatusBadRequest)
+			return
+		}
+		switch action = rest[1]; action {
+		case "elect", "unelect":
+			detail = fmt.Sprintf("%s comment %d", action, id)
+			err = p.ElectComment(ctx, target, id, action == "elect")
+		case "reply":
+			var req commentReplyReq
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if strings.TrimSpace(req.Content) == "" {
+				http.Error(w, "content required", http.StatusBadRequest)
+				return
+			}
+			detail = fmt.Sprintf("reply comment %d: %s", id, excerpt(req.Content))
+			err = p.ReplyComment(ctx, target, id, req.Content)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	s.audit(r, publisher.AuditCommentModerated, "article:"+target.String(), detail)
+	writeJSON(w, map[string]any{"article": target.String(), "action": action, "ok": true})
+}
+
+// listArticleComments 从微信接口读取一页留言。
+func (s *Server) listArticleComments(w http.ResponseWriter, r *http.Request, target publisher.CommentTarget) {
+	query := r.URL.Query()
+	kind, err := publisher.ParseCommentsKind(query.Get("type"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	offset, err := queryInt(query.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		http.Error(w, "invalid offset", http.StatusBadRequest)
+		return
+	}
+	count, err := queryInt(query.Get("count"), publisher.MaxCommentsCount)
+	if err != nil || count < 1 || count > publisher.MaxCommentsCount {
+		http.Error(w, fmt.Sprintf("count must be between 1 and %d", publisher.MaxCommentsCount), http.StatusBadRequest)
+		return
+	}
+	p, err := s.ensurePublisher()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	comments, total, err := p.ListComments(ctx, target, kind, offset, count)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, map[string]any{"article": target.String(), "comments": comments, "total": total})
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// 修改稿件的 session 子操作，成功后记为 draft.revised。
+var revisionActions = map[string]bool{
+	"": true, "stream": true, "expand": true, "sections": true, "polish": true, "images/place": true,
+	"factcheck/apply": true, "titles/apply": true, "quotes/apply": true, "rollback": true,
+}
+
+// audit 以当前用户（或绑定的调用方）与客户端 IP 追加一条审计记录。
+func (s *Server) audit(r *http.Request, action, target, detail string) {
+	s.recordAudit(publisher.AuditEntry{User: sessionOwner(r), Action: action, Target: target, Detail: detail, RemoteAddr: s.remoteIP(r)})
+}
+
+// recordAudit 追加一条审计记录；写入失败只打日志，不影响请求。
+func (s *Server) recordAudit(entry publisher.AuditEntry) {
+	if _, err := s.auditLog.Append(entry); err != nil {
+		log.Printf("[audit] record %s failed: %v", entry.Action, err)
+	}
+}
+
+// remoteIP 返回审计记录中的客户端 IP，配置 rate_limit.trust_proxy 时使用反向代理转发的地址。
+func (s *Server) remoteIP(r *http.Request) string {
+	if s.limits != nil {
+		return s.limits.clientIP(r)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// handleAdminAudit 查询审计记录；启用登录时只有管理员可以访问。
+// 查询参数：user、action（如 publish 匹配 publish.*）、target、since/until（YYYY-MM-DD，until 包含当天）、
+// limit（默认 100）；format=jsonl 时按时间顺序导出全部符合条件的记录。
+// Path: GET /api/admin/audit
+func (s *Server) handleAdminAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.auth != nil && !isAdmin(r) {
+		http.Error(w, "admin only", http.StatusForbidden)
+		return
+	}
+	query := r.URL.Query()
+	filter := publisher.AuditFilter{
+		User:   query.Get("user"),
+		Action: query.Get("action"),
+		Target: query.Get("target"),
+	}
+	var err error
+	if filter.Limit, err = queryInt(query.Get("limit"), 100); err != nil || filter.Limit < 0 {
+		http.Error(w, "invalid limit", http.StatusBadRequest)
+		return
+	}
+	if v := query.Get("since"); v != "" {
+		if filter.Since, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			http.Error(w, "invalid since: use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("until"); v != "" {
+		until, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			http.Error(w, "invalid until: use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		filter.Until = until.AddDate(0, 0, 1)
+	}
+
+	switch query.Get("format") {
+	case "", "json":
+	case "jsonl":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="audit-`+time.Now().Format("20060102")+`.jsonl"`)
+		if err := s.auditLog.Export(w, filter); err != nil {
+			log.Printf("[audit] export failed: %v", err)
+		}
+		return
+	default:
+		http.Error(w, "format must be json or jsonl", http.StatusBadRequest)
+		return
+	}
+	entries, err := s.auditLog.List(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]any{"entries": entries, "count": len(entries)})
+}
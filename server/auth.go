@@ -0,0 +1,337 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// authCookie 为保存登录令牌的 cookie。
+const authCookie = "awp_token"
+
+// authState 为启用登录时的用户存储与令牌签名配置；Server.auth 为 nil 表示未启用登录。
+type authState struct {
+	users  *UserStore
+	secret []byte
+	ttl    time.Duration
+}
+
+// authUser 为当前请求的登录用户。
+type authUser struct {
+	Name  string
+	Admin bool
+	Roles []string
+}
+
+type authUserKey struct{}
+
+func newAuthState(cfg *publisher.AuthConfig) (*authState, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	secret := []byte(cfg.Secret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		log.Printf("[auth] auth.secret not set; logins will not survive a restart")
+	}
+	hours := cfg.SessionHours
+	if hours <= 0 {
+		hours = 168
+	}
+	a := &authState{users: NewUserStore(cfg.UsersPath), secret: secret, ttl: time.Duration(hours) * time.Hour}
+	users, err := a.users.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		log.Printf("[auth] no users in %s; create one with `user add`", a.users.path)
+	}
+	return a, nil
+}
+
+// sign 生成令牌：base64(用户名).过期时间.HMAC-SHA256 签名。
+func (a *authState) sign(name string, exp time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(name)) + "." + strconv.FormatInt(exp.Unix(), 10)
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify 校验令牌签名与有效期，返回用户名。
+func (a *authState) verify(token string) (string, error) {
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return "", errors.New("malformed token")
+	}
+	payload, sig := token[:i], token[i+1:]
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(payload))
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, mac.Sum(nil)) {
+		return "", errors.New("invalid token")
+	}
+	nameText, expText, _ := strings.Cut(payload, ".")
+	exp, err := strconv.ParseInt(expText, 10, 64)
+	if err != nil || time.Now().Unix() >= exp {
+		return "", errors.New("token expired")
+	}
+	name, err := base64.RawURLEncoding.DecodeString(nameText)
+	if err != nil {
+		return "", errors.New("malformed token")
+	}
+	return string(name), nil
+}
+
+// authMiddleware 要求 /api/ 与 /uploads/ 请求携带有效的登录 cookie 或 Authorization: Bearer 令牌；
+// 页面静态资源、登录接口与接口文档不需要登录，配置了 ingest.secret 时 /api/ingest 由处理函数校验密钥或登录，
+// 企业微信回调 /api/bots/wecom 由处理函数校验签名。
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	if s.auth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		if p == "/api/login" || p == "/api/logout" || p == "/api/me" || p == "/api/openapi.json" || p == "/api/docs" || p == "/api/docs/init.js" ||
+			(!strings.HasPrefix(p, "/api/") && !strings.HasPrefix(p, "/uploads/")) || (p == "/api/ingest" && s.ingestSecret() != "") || p == "/api/bots/wecom" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		u, err := s.authenticate(r)
+		if err != nil {
+			http.Error(w, "login required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authUserKey{}, u)))
+	})
+}
+
+// authenticate 从 cookie 或 Bearer 令牌中取得当前用户；每次都重新读取用户，删除或降权立即生效。
+func (s *Server) authenticate(r *http.Request) (*authUser, error) {
+	token := ""
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		token = strings.TrimPrefix(h, "Bearer ")
+	} else if c, err := r.Cookie(authCookie); err == nil {
+		token = c.Value
+	}
+	if token == "" {
+		return nil, errors.New("no token")
+	}
+	name, err := s.auth.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.auth.users.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return &authUser{Name: u.Name, Admin: u.Admin, Roles: u.roles()}, nil
+}
+
+// currentUser 返回当前登录用户名，未启用登录时为空。
+func currentUser(r *http.Request) string {
+	if u, ok := r.Context().Value(authUserKey{}).(*authUser); ok {
+		return u.Name
+	}
+	return ""
+}
+
+// isAdmin 判断当前用户是否为管理员。
+func isAdmin(r *http.Request) bool {
+	u, ok := r.Context().Value(authUserKey{}).(*authUser)
+	return ok && u.Admin
+}
+
+// canAccess 判断当前用户能否访问属于 owner 的资源：未启用登录、管理员或本人。
+// 未启用登录但绑定了调用方时，没有归属的资源（如周期任务创建的 session）所有人可见，其余只有本人可见。
+func (s *Server) canAccess(r *http.Request, owner string) bool {
+	if s.auth == nil {
+		return !s.ownerBinding() || owner == "" || sessionOwner(r) == owner
+	}
+	return isAdmin(r) || (owner != "" && currentUser(r) == owner)
+}
+
+// sessionAllowed 判断当前用户能否访问 session；session 不存在时返回 true，由各处理函数返回 404。
+// 审核人与发布人可访问他人已提交审核的 session。
+func (s *Server) sessionAllowed(r *http.Request, id string) bool {
+	if s.auth == nil && !s.ownerBinding() {
+		return true
+	}
+	owner, status, ok := s.store.access(id)
+	return !ok || s.canAccess(r, owner) || s.canReview(r, status)
+}
+
+// ownsSession 判断当前用户是否为 session 的作者（或管理员）；session 不存在时返回 true。
+func (s *Server) ownsSession(r *http.Request, id string) bool {
+	owner, _, ok := s.store.access(id)
+	return !ok || s.canAccess(r, owner)
+}
+
+type loginReq struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type meResp struct {
+	// Auth 为 false 表示未启用登录。
+	Auth   bool     `json:"auth"`
+	User   string   `json:"user,omitempty"`
+	Admin  bool     `json:"admin,omitempty"`
+	Roles  []string `json:"roles,omitempty"`
+	Tokens int      `json:"tokens_today,omitempty"`
+	Cost   float64  `json:"cost_today,omitempty"`
+	// Token 仅登录时返回，可作为 Authorization: Bearer 令牌调用接口。
+	Token     string     `json:"token,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// handleLogin 校验用户名密码，设置登录 cookie 并返回令牌。
+// Path: POST /api/login
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.auth == nil {
+		http.Error(w, "login not enabled; set auth in config", http.StatusNotFound)
+		return
+	}
+	var req loginReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	u, err := s.auth.users.Verify(strings.TrimSpace(req.Username), req.Password)
+	if err != nil {
+		if errors.Is(err, ErrBadCredentials) {
+			log.Printf("[auth] login failed user=%q", req.Username)
+			s.recordAudit(publisher.AuditEntry{User: strings.TrimSpace(req.Username), Action: publisher.AuditLoginFailed, RemoteAddr: s.remoteIP(r)})
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	exp := time.Now().Add(s.auth.ttl)
+	token := s.auth.sign(u.Name, exp)
+	http.SetCookie(w, &http.Cookie{
+		Name:     authCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  exp,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	log.Printf("[auth] login user=%s", u.Name)
+	s.recordAudit(publisher.AuditEntry{User: u.Name, Action: publisher.AuditLogin, RemoteAddr: s.remoteIP(r)})
+	writeJSON(w, meResp{Auth: true, User: u.Name, Admin: u.Admin, Roles: u.roles(), Token: token, ExpiresAt: &exp})
+}
+
+// handleLogout 清除登录 cookie。令牌本身在过期前仍然有效。
+// Path: POST /api/logout
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: authCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMe 返回当前用户与其当天的模型用量；未登录时返回 401，未启用登录时 auth 为 false。
+// Path: GET /api/me
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.auth == nil {
+		writeJSON(w, meResp{})
+		return
+	}
+	u, err := s.authenticate(r)
+	if err != nil {
+		http.Error(w, "login required", http.StatusUnauthorized)
+		return
+	}
+	usage := s.agent().Budget().UserDaily(u.Name)
+	writeJSON(w, meResp{Auth: true, User: u.Name, Admin: u.Admin, Roles: u.Roles, Tokens: usage.Tokens(), Cost: usage.Cost})
+}
+
+// uploadDirFor 返回 owner 的上传目录：启用登录时每个用户一个子目录，便于按用户隔离访问。
+func (s *Server) uploadDirFor(owner string) (string, error) {
+	if owner == "" {
+		return s.uploadDir, nil
+	}
+	dir := filepath.Join(s.uploadDir, owner)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create upload dir: %w", err)
+	}
+	return dir, nil
+}
+
+// ownUploadPath 规范化客户端传入的上传文件路径，并要求它位于 owner 的上传目录下，
+// 避免发布他人上传的文件或服务器上的任意文件。
+func (s *Server) ownUploadPath(owner, p string) (string, error) {
+	dir, err := s.uploadDirFor(owner)
+	if err != nil {
+		return "", err
+	}
+	p = filepath.Clean(p)
+	rel, err := filepath.Rel(dir, p)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not in the upload dir %s", p, dir)
+	}
+	return p, nil
+}
+
+// uploadURL 返回上传文件的访问地址（含 base_path 前缀）。
+func (s *Server) uploadURL(path string) string {
+	rel, err := filepath.Rel(s.uploadDir, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	return s.basePath + "/uploads/" + filepath.ToSlash(rel)
+}
+
+// handleUploadFile 提供上传文件；启用登录时只能访问自己子目录下的文件，管理员不受限。
+// 本地缺失的文件从持久存储取回后再提供。
+// Path: GET /uploads/{user}/{file}
+func (s *Server) handleUploadFile() http.Handler {
+	files := http.StripPrefix("/uploads/", http.FileServer(http.Dir(s.uploadDir)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		owner, _, nested := strings.Cut(strings.TrimPrefix(r.URL.Path, "/uploads/"), "/")
+		if !nested {
+			owner = ""
+		}
+		if !s.canAccess(r, owner) {
+			http.NotFound(w, r)
+			return
+		}
+		if s.files != nil {
+			rel := path.Clean("/" + strings.TrimPrefix(r.URL.Path, "/uploads/"))
+			if err := s.fetchUpload(r.Context(), filepath.Join(s.uploadDir, filepath.FromSlash(rel))); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("[storage] fetch %s failed: %v", rel, err)
+			}
+		}
+		files.ServeHTTP(w, r)
+	})
+}
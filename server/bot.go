@@ -0,0 +1,496 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"auto_wechat_article_publisher/generator"
+	"auto_wechat_article_publisher/publisher"
+)
+
+// defaultBotStatePath 为未配置 bots.state_path 时保存聊天状态的文件。
+const defaultBotStatePath = "bots.json"
+
+// botHelp 为 /help 的回复。
+const botHelp = `发送文章主题即可生成稿件，之后直接回复修改意见修订。
+/new <主题>  开始一篇新稿件
+/show  查看当前稿件
+/polish [重点]  润色当前稿件
+/submit  提交审核（启用审核流程时）
+/approve、/reject [意见]  审核稿件（审核人）
+/publish  发布到公众号草稿箱，先发送一张图片可作为封面
+/open <session_id>  切换到网页中的稿件
+/reset  结束当前稿件`
+
+// botChat 为一个聊天账号的状态：当前稿件与发送的封面。
+type botChat struct {
+	SessionID string `json:"session_id,omitempty"`
+	CoverPath string `json:"cover_path,omitempty"`
+}
+
+// botMessage 为从 Telegram 或企业微信收到的一条消息；reply 把文本发回该聊天。
+type botMessage struct {
+	Platform string
+	UserID   string
+	Text     string
+	// Photo 非空时为图片消息，用于读取图片数据。
+	Photo func(ctx context.Context) ([]byte, error)
+	reply func(text string)
+}
+
+// botRunner 保存各聊天账号的状态（bots.state_path），同一账号的消息依次处理。
+type botRunner struct {
+	path     string
+	mu       sync.Mutex
+	chats    map[string]botChat
+	accounts map[string]*sync.Mutex
+	wecom    *wecomBot
+}
+
+func newBotRunner(cfg *publisher.BotConfig) (*botRunner, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.Telegram == nil && cfg.WeCom == nil {
+		return nil, errors.New("bots: configure telegram or wecom")
+	}
+	if cfg.Telegram != nil && cfg.Telegram.Token == "" {
+		return nil, errors.New("bots.telegram: token required")
+	}
+	b := &botRunner{path: cfg.StatePath, chats: map[string]botChat{}, accounts: map[string]*sync.Mutex{}}
+	if b.path == "" {
+		b.path = defaultBotStatePath
+	}
+	if cfg.WeCom != nil {
+		w, err := newWeComBot(*cfg.WeCom)
+		if err != nil {
+			return nil, fmt.Errorf("bots.wecom: %w", err)
+		}
+		b.wecom = w
+	}
+	data, err := os.ReadFile(b.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &b.chats); err != nil {
+			return nil, fmt.Errorf("bots %s: %w", b.path, err)
+		}
+	}
+	return b, nil
+}
+
+// chat 返回聊天账号的状态。
+func (b *botRunner) chat(account string) botChat {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.chats[account]
+}
+
+// setChat 修改聊天账号的状态并写入文件；写入失败只打日志。
+func (b *botRunner) setChat(account string, fn func(c *botChat)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.chats[account]
+	fn(&c)
+	if c == (botChat{}) {
+		delete(b.chats, account)
+	} else {
+		b.chats[account] = c
+	}
+	data, err := json.MarshalIndent(b.chats, "", "  ")
+	if err == nil {
+		tmp := b.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o644); err == nil {
+			err = os.Rename(tmp, b.path)
+		}
+	}
+	if err != nil {
+		log.Printf("[bot] save %s failed: %v", b.path, err)
+	}
+}
+
+// lock 锁定聊天账号，返回解锁函数。
+func (b *botRunner) lock(account string) func() {
+	b.mu.Lock()
+	m, ok := b.accounts[account]
+	if !ok {
+		m = &sync.Mutex{}
+		b.accounts[account] = m
+	}
+	b.mu.Unlock()
+	m.Lock()
+	return m.Unlock
+}
+
+// dispatchBot 在后台处理一条消息，关闭服务时等待处理完成。
+func (s *Server) dispatchBot(msg botMessage) {
+	s.tasks.Add(1)
+	go func() {
+		defer s.tasks.Done()
+		s.handleBotMessage(msg)
+	}()
+}
+
+// handleBotMessage 按 bots.users 把聊天账号映射为 Web 用户，以该用户的身份执行命令。
+func (s *Server) handleBotMessage(msg botMessage) {
+	cfg := s.config().Bots
+	if cfg == nil || s.bots == nil {
+		return
+	}
+	account := msg.Platform + ":" + msg.UserID
+	name, ok := cfg.Users[account]
+	if !ok {
+		log.Printf("[bot] message from unknown account %s", account)
+		msg.reply("该账号未获授权，请联系管理员把 " + account + " 加入配置 bots.users。")
+		return
+	}
+	r, err := s.botRequest(msg.Platform, name)
+	if err != nil {
+		log.Printf("[bot] %s -> %q: %v", account, name, err)
+		msg.reply("账号映射的用户 " + name + " 不可用：" + err.Error())
+		return
+	}
+	unlock := s.bots.lock(account)
+	defer unlock()
+
+	if msg.Photo != nil {
+		s.botCover(r, account, msg)
+		return
+	}
+	text := strings.TrimSpace(msg.Text)
+	if text == "" {
+		return
+	}
+	cmd, arg := "", text
+	if strings.HasPrefix(text, "/") {
+		cmd, arg, _ = strings.Cut(text[1:], " ")
+		// 群聊中的命令带有机器人用户名，如 /publish@my_bot。
+		cmd, _, _ = strings.Cut(strings.ToLower(cmd), "@")
+		arg = strings.TrimSpace(arg)
+	}
+	chat := s.bots.chat(account)
+	switch cmd {
+	case "":
+		if _, ok := s.botSession(r, chat.SessionID); ok {
+			s.botRevise(r, chat.SessionID, arg, msg)
+		} else {
+			s.botNew(r, account, arg, msg)
+		}
+	case "new":
+		if arg == "" {
+			msg.reply("用法：/new <主题>")
+			return
+		}
+		s.botNew(r, account, arg, msg)
+	case "show":
+		if sess, ok := s.botSession(r, chat.SessionID); ok {
+			s.sendDraft(msg, chat.SessionID, sess.Draft)
+		} else {
+			msg.reply("当前没有稿件，发送主题开始写作。")
+		}
+	case "polish":
+		s.botPolish(r, chat.SessionID, arg, msg)
+	case "submit", "approve", "reject":
+		if _, ok := s.botSession(r, chat.SessionID); !ok {
+			msg.reply("当前没有稿件。")
+			return
+		}
+		wf, err := s.applyWorkflow(r, chat.SessionID, cmd, arg)
+		if err != nil {
+			msg.reply("操作失败：" + err.Error())
+			return
+		}
+		msg.reply("审核状态：" + wf.status())
+	case "publish":
+		s.botPublish(r, account, chat, msg)
+	case "open":
+		sess, ok := s.botSession(r, arg)
+		if !ok {
+			msg.reply("找不到 session " + arg + "。")
+			return
+		}
+		s.bots.setChat(account, func(c *botChat) { c.SessionID, c.CoverPath = arg, "" })
+		s.sendDraft(msg, arg, sess.Draft)
+	case "reset":
+		s.bots.setChat(account, func(c *botChat) { *c = botChat{} })
+		msg.reply("已结束当前稿件，发送主题开始新的一篇。")
+	case "start", "help":
+		msg.reply(botHelp)
+	default:
+		msg.reply("未知命令 /" + cmd + "\n\n" + botHelp)
+	}
+}
+
+// botRequest 构造代表映射用户的请求，用于复用接口的权限、审核与审计逻辑。
+// 启用登录时用户必须存在；未启用登录时用户名作为 session 的归属。
+func (s *Server) botRequest(platform, name string) (*http.Request, error) {
+	r, err := http.NewRequest(http.MethodPost, "/api/bots/"+platform, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.RemoteAddr = platform + ":0"
+	ctx := r.Context()
+	if s.auth != nil {
+		u, err := s.auth.users.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		ctx = context.WithValue(ctx, authUserKey{}, &authUser{Name: u.Name, Admin: u.Admin, Roles: u.roles()})
+	} else if name != "" {
+		ctx = context.WithValue(ctx, clientOwnerKey{}, name)
+	}
+	return r.WithContext(ctx), nil
+}
+
+// botSession 返回当前用户可访问的 session。
+func (s *Server) botSession(r *http.Request, id string) (*generator.Session, bool) {
+	if id == "" || !s.sessionAllowed(r, id) {
+		return nil, false
+	}
+	return s.store.get(id)
+}
+
+// botNew 按主题生成新稿件，设为聊天账号的当前稿件。
+func (s *Server) botNew(r *http.Request, account, topic string, msg botMessage) {
+	cfg := s.config().Bots
+	if err := s.store.admit(); err != nil {
+		msg.reply("暂时无法新建稿件：" + err.Error())
+		return
+	}
+	id := newSessionID()
+	sess := generator.NewSession(id, generator.Spec{Topic: topic, Words: cfg.Words, Style: cfg.Style}, s.agent())
+	sess.Owner = sessionOwner(r)
+	msg.reply("正在撰写《" + topic + "》，请稍候…")
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	draft, err := sess.Propose(ctx)
+	cancel()
+	if err != nil {
+		log.Printf("[bot] %s generate failed: %v", account, err)
+		msg.reply("生成失败：" + err.Error())
+		return
+	}
+	s.store.set(id, sess)
+	s.audit(r, publisher.AuditSessionCreated, id, msg.Platform+" bot: "+topic)
+	s.bots.setChat(account, func(c *botChat) { c.SessionID, c.CoverPath = id, "" })
+	s.sendDraft(msg, id, draft)
+}
+
+// botRevise 按修改意见修订当前稿件。
+func (s *Server) botRevise(r *http.Request, id, comment string, msg botMessage) {
+	s.botEdit(r, id, "revise", msg, func(ctx context.Context, sess *generator.Session) (generator.Draft, error) {
+		s.events.publish(id, eventDraftStarted, map[string]string{"comment": comment})
+		return sess.Revise(ctx, comment)
+	})
+}
+
+// botPolish 润色当前稿件，focus 为润色重点（可空）。
+func (s *Server) botPolish(r *http.Request, id, focus string, msg botMessage) {
+	s.botEdit(r, id, "polish", msg, func(ctx context.Context, sess *generator.Session) (generator.Draft, error) {
+		return sess.Polish(ctx, focus)
+	})
+}
+
+// botEdit 执行一次修改并回复新稿件；session 上已有修改在执行（如网页中正在修订）时提示稍后再试。
+func (s *Server) botEdit(r *http.Request, id, action string, msg botMessage, edit func(ctx context.Context, sess *generator.Session) (generator.Draft, error)) {
+	sess, ok := s.botSession(r, id)
+	if !ok {
+		msg.reply("当前没有稿件，发送主题开始写作。")
+		return
+	}
+	done, op, ok := s.store.begin(id, action)
+	if !ok {
+		msg.reply(fmt.Sprintf("稿件正在执行 %s（%s 开始），请稍后再试。", op.action, op.since.Format("15:04:05")))
+		return
+	}
+	defer done()
+	msg.reply("正在修改，请稍候…")
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	draft, err := edit(ctx, sess)
+	cancel()
+	if err != nil {
+		s.events.publish(id, eventError, err.Error())
+		msg.reply("修改失败：" + err.Error())
+		return
+	}
+	s.store.persist(id)
+	s.events.publish(id, eventRevisionApplied, draft)
+	s.sendDraft(msg, id, draft)
+}
+
+// botCover 把图片保存为当前稿件的上传文件，作为下次 /publish 的封面。
+func (s *Server) botCover(r *http.Request, account string, msg botMessage) {
+	chat := s.bots.chat(account)
+	sess, ok := s.botSession(r, chat.SessionID)
+	if !ok {
+		msg.reply("请先发送主题生成稿件，再发送封面图片。")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	data, err := msg.Photo(ctx)
+	if err != nil {
+		msg.reply("读取图片失败：" + err.Error())
+		return
+	}
+	ext := ".jpg"
+	switch http.DetectContentType(data) {
+	case "image/png":
+		ext = ".png"
+	case "image/gif":
+		ext = ".gif"
+	}
+	tmp, err := os.MkdirTemp("", "bot-")
+	if err != nil {
+		msg.reply("保存图片失败：" + err.Error())
+		return
+	}
+	defer os.RemoveAll(tmp)
+	file := filepath.Join(tmp, "cover"+ext)
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		msg.reply("保存图片失败：" + err.Error())
+		return
+	}
+	dir, err := s.uploadDirFor(sess.Owner)
+	if err != nil {
+		msg.reply("保存图片失败：" + err.Error())
+		return
+	}
+	up, uploadErr, err := s.saveDocumentImage(r.WithContext(ctx), chat.SessionID, file, dir, s.uploadLimits())
+	if err == nil && uploadErr != nil {
+		err = errors.New(uploadErr.Error)
+	}
+	if err != nil {
+		msg.reply("保存图片失败：" + err.Error())
+		return
+	}
+	s.bots.setChat(account, func(c *botChat) { c.CoverPath = up.Path })
+	msg.reply("已设为封面，发送 /publish 发布。")
+}
+
+// botPublish 把当前稿件提交到发布队列，发布结束后回复结果。封面依次取发送的图片、bots.cover_path 与 AI 封面。
+func (s *Server) botPublish(r *http.Request, account string, chat botChat, msg botMessage) {
+	cfg := s.config().Bots
+	id := chat.SessionID
+	sess, ok := s.botSession(r, id)
+	if !ok {
+		msg.reply("当前没有稿件。")
+		return
+	}
+	markdown := sess.Draft.Markdown
+	if markdown == "" {
+		msg.reply("稿件为空，请先生成。")
+		return
+	}
+	if err := s.checkPublishable(r, id, markdown); err != nil {
+		msg.reply("不能发布：" + err.Error())
+		return
+	}
+	cover := chat.CoverPath
+	if cover == "" {
+		cover = cfg.CoverPath
+	}
+	if cover == "" && (!cfg.AICover || s.imageGen == nil) {
+		msg.reply("需要封面：先发送一张图片，或在配置中设置 bots.cover_path 或 bots.ai_cover。")
+		return
+	}
+	if cover != "" {
+		if err := s.fetchUpload(context.Background(), cover); err != nil {
+			msg.reply("封面不可用：" + err.Error())
+			return
+		}
+	}
+	title := sess.Draft.Title
+	if title == "" {
+		title = sess.Spec.Topic
+	}
+	req := publishReq{
+		SessionID: id,
+		CoverPath: cover,
+		Author:    cfg.Author,
+		Title:     title,
+		Digest:    sess.Draft.Digest,
+		Markdown:  markdown,
+		AICover:   cover == "",
+	}
+	job, err := s.jobs.enqueue(id, sess.Owner, currentUser(r), func(ctx context.Context, job *publishJob) (publishResp, error) {
+		resp, err := s.runPublish(ctx, job, req, sess)
+		if err != nil {
+			msg.reply("发布失败：" + err.Error())
+		} else {
+			msg.reply("已发布到草稿箱：《" + resp.Title + "》\nmedia_id: " + resp.MediaID)
+		}
+		return resp, err
+	})
+	if err != nil {
+		msg.reply("发布失败：" + err.Error())
+		return
+	}
+	s.audit(r, publisher.AuditPublishRequested, id, fmt.Sprintf("%s bot, job %s: %s", msg.Platform, job.ID, title))
+	log.Printf("[bot] %s publish session=%s job=%s", account, id, job.ID)
+	msg.reply("已提交发布任务 " + job.ID + "，完成后通知你。")
+}
+
+// sendDraft 回复稿件全文，配置了 bots.preview_url 时附上网页地址。
+func (s *Server) sendDraft(msg botMessage, id string, draft generator.Draft) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "《%s》（%d 字）\n", draft.Title, draft.WordCount)
+	if draft.Digest != "" {
+		sb.WriteString("摘要：" + draft.Digest + "\n")
+	}
+	sb.WriteString("\n" + strings.TrimSpace(draft.Markdown) + "\n\n")
+	if tmpl := s.config().Bots.PreviewURL; tmpl != "" {
+		sb.WriteString("网页编辑：" + strings.ReplaceAll(tmpl, "{session_id}", id) + "\n")
+	}
+	sb.WriteString("回复修改意见继续修订，发送 /publish 发布。")
+	msg.reply(sb.String())
+}
+
+// splitBotText 把长消息按行切分为不超过 limit 的多段，size 为长度的计算方式（字符数或字节数）；
+// 超长的单行按字符截断。
+func splitBotText(text string, limit int, size func(string) int) []string {
+	var parts []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			parts = append(parts, strings.TrimRight(cur.String(), "\n"))
+			cur.Reset()
+		}
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if size(cur.String()+line) <= limit {
+			cur.WriteString(line)
+			continue
+		}
+		flush()
+		for size(line) > limit {
+			n, total := 0, 0
+			for i, r := range line {
+				w := size(string(r))
+				if total+w > limit {
+					break
+				}
+				total += w
+				n = i + utf8.RuneLen(r)
+			}
+			if n == 0 {
+				_, n = utf8.DecodeRuneInString(line)
+			}
+			parts = append(parts, line[:n])
+			line = line[n:]
+		}
+		cur.WriteString(line)
+	}
+	flush()
+	return parts
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+const (
+	// telegramMaxText 为 Telegram 单条消息的字符上限。
+	telegramMaxText = 4096
+	// telegramPollTimeout 为 getUpdates 长轮询的等待时间（秒）。
+	telegramPollTimeout = 30
+)
+
+// telegramBot 通过 Bot API 收发消息。
+type telegramBot struct {
+	base   string
+	token  string
+	client *http.Client
+}
+
+func newTelegramBot(cfg publisher.TelegramBotConfig) *telegramBot {
+	base := strings.TrimRight(cfg.APIBase, "/")
+	if base == "" {
+		base = "https://api.telegram.org"
+	}
+	return &telegramBot{base: base, token: cfg.Token, client: &http.Client{Timeout: (telegramPollTimeout + 30) * time.Second}}
+}
+
+// telegramUpdate 为 getUpdates 返回的更新中用到的字段。
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		From *struct {
+			ID int64 `json:"id"`
+		} `json:"from"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Text  string `json:"text"`
+		Photo []struct {
+			FileID string `json:"file_id"`
+		} `json:"photo"`
+	} `json:"message"`
+}
+
+// call 调用 Bot API 方法，把 result 解析到 out（可空）。
+func (t *telegramBot) call(ctx context.Context, method string, params, out any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.base+"/bot"+t.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// 错误信息中的地址包含 token，不直接返回。
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	var res struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(&res); err != nil {
+		return fmt.Errorf("telegram %s: HTTP %d: %w", method, resp.StatusCode, err)
+	}
+	if !res.OK {
+		return fmt.Errorf("telegram %s: %s", method, res.Description)
+	}
+	if out != nil {
+		return json.Unmarshal(res.Result, out)
+	}
+	return nil
+}
+
+// send 发送纯文本消息，超长时分多条发送。
+func (t *telegramBot) send(chatID int64, text string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for _, part := range splitBotText(text, telegramMaxText, utf8.RuneCountInString) {
+		if err := t.call(ctx, "sendMessage", map[string]any{"chat_id": chatID, "text": part}, nil); err != nil {
+			log.Printf("[bot] %v", err)
+			return
+		}
+	}
+}
+
+// download 通过 getFile 下载用户发送的文件。
+func (t *telegramBot) download(ctx context.Context, fileID string) ([]byte, error) {
+	var file struct {
+		FilePath string `json:"file_path"`
+	}
+	if err := t.call(ctx, "getFile", map[string]any{"file_id": fileID}, &file); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.base+"/file/bot"+t.token+"/"+file.FilePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, errors.New("download failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 20<<20))
+}
+
+// runTelegram 以长轮询接收 Telegram 消息，直到服务关闭；出错时等待后重试。
+func (s *Server) runTelegram(t *telegramBot) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+	var offset int64
+	for ctx.Err() == nil {
+		var updates []telegramUpdate
+		err := t.call(ctx, "getUpdates", map[string]any{
+			"offset": offset, "timeout": telegramPollTimeout, "allowed_updates": []string{"message"},
+		}, &updates)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("[bot] %v", err)
+			select {
+			case <-time.After(5 * time.Second):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			m := u.Message
+			if m == nil || m.From == nil || ctx.Err() != nil {
+				continue
+			}
+			chatID := m.Chat.ID
+			msg := botMessage{
+				Platform: "telegram",
+				UserID:   strconv.FormatInt(m.From.ID, 10),
+				Text:     m.Text,
+				reply:    func(text string) { t.send(chatID, text) },
+			}
+			if len(m.Photo) > 0 {
+				// 同一图片的多个尺寸按从小到大排列，取最大的一个。
+				fileID := m.Photo[len(m.Photo)-1].FileID
+				msg.Photo = func(ctx context.Context) ([]byte, error) { return t.download(ctx, fileID) }
+			}
+			s.dispatchBot(msg)
+		}
+	}
+}
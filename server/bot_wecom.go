@@ -0,0 +1,295 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+const (
+	// wecomMaxText 为企业微信应用文本消息的字节上限。
+	wecomMaxText = 2048
+	// wecomSeenSize 为去重记录的消息数，企业微信在回调超时时会重试推送。
+	wecomSeenSize = 256
+)
+
+// wecomBot 为企业微信自建应用：回调消息经 EncodingAESKey 解密，回复通过 message/send 发送。
+type wecomBot struct {
+	cfg    publisher.WeComBotConfig
+	base   string
+	key    []byte
+	client *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+	seen      map[string]bool
+	seenOrder []string
+}
+
+func newWeComBot(cfg publisher.WeComBotConfig) (*wecomBot, error) {
+	if cfg.CorpID == "" || cfg.Secret == "" || cfg.AgentID == 0 || cfg.Token == "" {
+		return nil, errors.New("corp_id, agent_id, secret and token required")
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.EncodingAESKey + "=")
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("encoding_aes_key must be the 43-character key from the app settings")
+	}
+	base := strings.TrimRight(cfg.APIBase, "/")
+	if base == "" {
+		base = "https://qyapi.weixin.qq.com"
+	}
+	return &wecomBot{cfg: cfg, base: base, key: key, client: &http.Client{Timeout: 30 * time.Second}, seen: map[string]bool{}}, nil
+}
+
+// signature 返回回调签名：token、timestamp、nonce 与密文排序拼接后的 SHA-1。
+func (w *wecomBot) signature(timestamp, nonce, encrypted string) string {
+	parts := []string{w.cfg.Token, timestamp, nonce, encrypted}
+	sort.Strings(parts)
+	sum := sha1.Sum([]byte(strings.Join(parts, "")))
+	return hex.EncodeToString(sum[:])
+}
+
+// decrypt 解密回调密文：AES-256-CBC（IV 为密钥前 16 字节，PKCS#7 按 32 字节填充），
+// 明文为 16 字节随机数、4 字节网络序长度、消息与 CorpID。
+func (w *wecomBot) decrypt(encrypted string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("malformed ciphertext")
+	}
+	block, err := aes.NewCipher(w.key)
+	if err != nil {
+		return nil, err
+	}
+	cipher.NewCBCDecrypter(block, w.key[:aes.BlockSize]).CryptBlocks(data, data)
+	pad := int(data[len(data)-1])
+	if pad < 1 || pad > 32 || pad > len(data) {
+		return nil, errors.New("bad padding")
+	}
+	data = data[:len(data)-pad]
+	if len(data) < 20 {
+		return nil, errors.New("message too short")
+	}
+	n := int(binary.BigEndian.Uint32(data[16:20]))
+	if n > len(data)-20 {
+		return nil, errors.New("bad message length")
+	}
+	if string(data[20+n:]) != w.cfg.CorpID {
+		return nil, errors.New("corp_id mismatch")
+	}
+	return data[20 : 20+n], nil
+}
+
+// firstSeen 记录消息 ID，已处理过时返回 false。
+func (w *wecomBot) firstSeen(id string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.seen[id] {
+		return false
+	}
+	w.seen[id] = true
+	w.seenOrder = append(w.seenOrder, id)
+	if len(w.seenOrder) > wecomSeenSize {
+		delete(w.seen, w.seenOrder[0])
+		w.seenOrder = w.seenOrder[1:]
+	}
+	return true
+}
+
+// accessToken 返回缓存的 access_token，过期前 5 分钟重新获取。
+func (w *wecomBot) accessToken(ctx context.Context) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.token != "" && time.Now().Before(w.expiresAt) {
+		return w.token, nil
+	}
+	u := w.base + "/cgi-bin/gettoken?corpid=" + url.QueryEscape(w.cfg.CorpID) + "&corpsecret=" + url.QueryEscape(w.cfg.Secret)
+	var res struct {
+		ErrCode     int    `json:"errcode"`
+		ErrMsg      string `json:"errmsg"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := w.do(ctx, http.MethodGet, u, nil, &res); err != nil {
+		return "", err
+	}
+	if res.ErrCode != 0 {
+		return "", fmt.Errorf("wecom gettoken: errcode %d: %s", res.ErrCode, res.ErrMsg)
+	}
+	w.token, w.expiresAt = res.AccessToken, time.Now().Add(time.Duration(res.ExpiresIn)*time.Second-5*time.Minute)
+	return w.token, nil
+}
+
+// do 发送请求并解析 JSON 响应；错误信息中不包含带密钥的地址。
+func (w *wecomBot) do(ctx context.Context, method, u string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("wecom: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("wecom: HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+// send 以应用消息发送纯文本，超长时分多条发送；access_token 失效时重新获取一次。
+func (w *wecomBot) send(user, text string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for _, part := range splitBotText(text, wecomMaxText, func(s string) int { return len(s) }) {
+		body, _ := json.Marshal(map[string]any{
+			"touser": user, "msgtype": "text", "agentid": w.cfg.AgentID, "text": map[string]string{"content": part},
+		})
+		for retry := 0; ; retry++ {
+			token, err := w.accessToken(ctx)
+			if err != nil {
+				log.Printf("[bot] %v", err)
+				return
+			}
+			var res struct {
+				ErrCode int    `json:"errcode"`
+				ErrMsg  string `json:"errmsg"`
+			}
+			if err := w.do(ctx, http.MethodPost, w.base+"/cgi-bin/message/send?access_token="+url.QueryEscape(token), body, &res); err != nil {
+				log.Printf("[bot] %v", err)
+				return
+			}
+			// 40014、42001 为 access_token 无效或过期。
+			if (res.ErrCode == 40014 || res.ErrCode == 42001) && retry == 0 {
+				w.mu.Lock()
+				w.token = ""
+				w.mu.Unlock()
+				continue
+			}
+			if res.ErrCode != 0 {
+				log.Printf("[bot] wecom message/send: errcode %d: %s", res.ErrCode, res.ErrMsg)
+				return
+			}
+			break
+		}
+	}
+}
+
+// download 下载图片消息的 PicUrl。
+func (w *wecomBot) download(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 20<<20))
+}
+
+// wecomMessage 为解密后的回调消息中用到的字段。
+type wecomMessage struct {
+	FromUserName string `xml:"FromUserName"`
+	MsgType      string `xml:"MsgType"`
+	Content      string `xml:"Content"`
+	PicURL       string `xml:"PicUrl"`
+	MsgID        string `xml:"MsgId"`
+}
+
+// handleWeComBot 为企业微信应用的回调地址：GET 为设置回调时的地址验证，POST 为用户消息。
+// 消息校验签名并解密后立即返回，在后台处理，结果以应用消息回复。
+// Path: GET|POST /api/bots/wecom
+func (s *Server) handleWeComBot(w http.ResponseWriter, r *http.Request) {
+	if s.bots == nil || s.bots.wecom == nil {
+		http.NotFound(w, r)
+		return
+	}
+	bot := s.bots.wecom
+	q := r.URL.Query()
+	verify := func(encrypted string) ([]byte, bool) {
+		sig := bot.signature(q.Get("timestamp"), q.Get("nonce"), encrypted)
+		if subtle.ConstantTimeCompare([]byte(sig), []byte(q.Get("msg_signature"))) != 1 {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return nil, false
+		}
+		plain, err := bot.decrypt(encrypted)
+		if err != nil {
+			http.Error(w, "decrypt: "+err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		return plain, true
+	}
+	switch r.Method {
+	case http.MethodGet:
+		if plain, ok := verify(q.Get("echostr")); ok {
+			w.Write(plain)
+		}
+	case http.MethodPost:
+		var envelope struct {
+			Encrypt string `xml:"Encrypt"`
+		}
+		if err := xml.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&envelope); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		plain, ok := verify(envelope.Encrypt)
+		if !ok {
+			return
+		}
+		var m wecomMessage
+		if err := xml.Unmarshal(plain, &m); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if m.MsgID != "" && !bot.firstSeen(m.MsgID) {
+			return
+		}
+		user := m.FromUserName
+		msg := botMessage{Platform: "wecom", UserID: user, reply: func(text string) { bot.send(user, text) }}
+		switch m.MsgType {
+		case "text":
+			msg.Text = m.Content
+		case "image":
+			pic := m.PicURL
+			msg.Photo = func(ctx context.Context) ([]byte, error) { return bot.download(ctx, pic) }
+		default:
+			return
+		}
+		s.dispatchBot(msg)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,363 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+	"auto_wechat_article_publisher/publisher"
+)
+
+// 批注状态。
+const (
+	commentOpen     = "open"
+	commentResolved = "resolved"
+)
+
+const (
+	// maxCommentRunes 为单条批注的长度上限。
+	maxCommentRunes = 2000
+	// commentQuoteRunes 为批注保存的原文摘录长度。
+	commentQuoteRunes = 200
+)
+
+// draftComment 为审阅批注：锚定到小节标题（Heading）或段落范围（Start～End，从 1 开始，见 generator.Paragraphs），
+// 都为空时针对全文。Version 为批注时的稿件版本，Quote 为当时锚定位置的原文摘录，稿件修改后仍可对照。
+type draftComment struct {
+	ID        string    `json:"id"`
+	Heading   string    `json:"heading,omitempty"`
+	Start     int       `json:"start,omitempty"`
+	End       int       `json:"end,omitempty"`
+	Quote     string    `json:"quote,omitempty"`
+	Version   int       `json:"version,omitempty"`
+	Body      string    `json:"body"`
+	User      string    `json:"user,omitempty"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	// ResolvedVersion 为按批注修订后产生的版本，手动标记解决时为 0。
+	ResolvedBy      string     `json:"resolved_by,omitempty"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+	ResolvedVersion int        `json:"resolved_version,omitempty"`
+}
+
+type commentReq struct {
+	Body    string `json:"body"`
+	Heading string `json:"heading,omitempty"`
+	Start   int    `json:"start,omitempty"`
+	End     int    `json:"end,omitempty"`
+}
+
+// updateComments 在锁内修改 session 的批注，成功后持久化。
+func (s *sessionStore) updateComments(id string, fn func(sess *generator.Session, comments *[]draftComment) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.lookupLocked(id)
+	if !ok {
+		return &errWorkflow{http.StatusNotFound, "session not found"}
+	}
+	comments := append([]draftComment(nil), entry.comments...)
+	if err := fn(entry.sess, &comments); err != nil {
+		return err
+	}
+	entry.comments = comments
+	s.persistLocked(entry)
+	return nil
+}
+
+// commentsOf 返回 session 的批注副本。
+func (s *sessionStore) commentsOf(id string) ([]draftComment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.lookupLocked(id)
+	if !ok {
+		return nil, false
+	}
+	return append([]draftComment(nil), entry.comments...), true
+}
+
+// anchorComment 校验批注位置并记录原文摘录；Heading 按 generator.FindSection 匹配并改为完整标题。
+func anchorComment(md string, c *draftComment) error {
+	switch {
+	case c.Heading != "":
+		if c.Start != 0 || c.End != 0 {
+			return errors.New("use either heading or start/end, not both")
+		}
+		sec, err := generator.FindSection(md, c.Heading)
+		if err != nil {
+			return err
+		}
+		c.Heading = sec.Heading
+		c.Quote = excerpt(md[sec.Start:sec.End])
+	case c.Start != 0 || c.End != 0:
+		if c.End == 0 {
+			c.End = c.Start
+		}
+		paras := generator.Paragraphs(md)
+		if c.Start < 1 || c.End < c.Start || c.End > len(paras) {
+			return fmt.Errorf("paragraph range %d-%d out of range (have %d)", c.Start, c.End, len(paras))
+		}
+		c.Quote = excerpt(strings.Join(paras[c.Start-1:c.End], "\n\n"))
+	}
+	return nil
+}
+
+// excerpt 把文本压成一行并截断到 commentQuoteRunes。
+func excerpt(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > commentQuoteRunes {
+		return string(r[:commentQuoteRunes]) + "…"
+	}
+	return text
+}
+
+// commentsInstruction 把待处理的批注合并为一条修订意见，逐条注明位置。
+func commentsInstruction(comments []draftComment) string {
+	var sb strings.Builder
+	sb.WriteString("请逐条落实以下审阅批注，批注未涉及的内容保持不变：")
+	for i, c := range comments {
+		fmt.Fprintf(&sb, "\n%d. ", i+1)
+		switch {
+		case c.Heading != "":
+			fmt.Fprintf(&sb, "【小节「%s」】", c.Heading)
+		case c.Quote != "":
+			fmt.Fprintf(&sb, "【针对“%s”】", c.Quote)
+		default:
+			sb.WriteString("【全文】")
+		}
+		sb.WriteString(c.Body)
+	}
+	return sb.String()
+}
+
+// writeStoreError 把 errWorkflow 转为对应的状态码，其余错误返回 500。
+func writeStoreError(w http.ResponseWriter, err error) {
+	var wfErr *errWorkflow
+	if errors.As(err, &wfErr) {
+		http.Error(w, wfErr.msg, wfErr.status)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// handleComments 处理审阅批注。能查看稿件的用户（作者、审核人、发布人）都可以批注；
+// 作者、批注人与审核人可以标记解决或重新打开，批注人可以删除，只有作者能按批注修订。
+// Path: GET/POST /api/sessions/{id}/comments
+// Path: POST /api/sessions/{id}/comments/{cid}/resolve|reopen, DELETE /api/sessions/{id}/comments/{cid}
+// Path: POST /api/sessions/{id}/comments/revise
+func (s *Server) handleComments(w http.ResponseWriter, r *http.Request, id, rest string) {
+	switch {
+	case rest == "":
+		switch r.Method {
+		case http.MethodGet:
+			s.listComments(w, r, id)
+		case http.MethodPost:
+			s.addComment(w, r, id)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	case rest == "revise":
+		s.reviseFromComments(w, r, id)
+	default:
+		cid, action, _ := strings.Cut(rest, "/")
+		s.updateComment(w, r, id, cid, action)
+	}
+}
+
+func (s *Server) listComments(w http.ResponseWriter, r *http.Request, id string) {
+	comments, ok := s.store.commentsOf(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	out := make([]draftComment, 0, len(comments))
+	for _, c := range comments {
+		if status == "" || c.Status == status {
+			out = append(out, c)
+		}
+	}
+	writeJSON(w, map[string]any{"comments": out})
+}
+
+func (s *Server) addComment(w http.ResponseWriter, r *http.Request, id string) {
+	var req commentReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		http.Error(w, "body required", http.StatusBadRequest)
+		return
+	}
+	if len([]rune(body)) > maxCommentRunes {
+		http.Error(w, fmt.Sprintf("comment too long (max %d characters)", maxCommentRunes), http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	c := draftComment{
+		ID:        strconv.FormatInt(now.UnixNano(), 36),
+		Heading:   strings.TrimSpace(req.Heading),
+		Start:     req.Start,
+		End:       req.End,
+		Body:      body,
+		User:      currentUser(r),
+		Status:    commentOpen,
+		CreatedAt: now,
+	}
+	err := s.store.updateComments(id, func(sess *generator.Session, comments *[]draftComment) error {
+		if sess.Draft.Markdown == "" {
+			return &errWorkflow{http.StatusConflict, "draft is empty; generate first"}
+		}
+		if err := anchorComment(sess.Draft.Markdown, &c); err != nil {
+			return &errWorkflow{http.StatusBadRequest, err.Error()}
+		}
+		c.Version = currentVersion(sess)
+		*comments = append(*comments, c)
+		return nil
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	s.events.publish(id, eventCommentsChanged, map[string]string{"action": "add", "comment_id": c.ID, "user": c.User})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, c)
+}
+
+// updateComment 标记批注解决（resolve）、重新打开（reopen）或删除（DELETE）。
+func (s *Server) updateComment(w http.ResponseWriter, r *http.Request, id, cid, action string) {
+	switch {
+	case r.Method == http.MethodDelete && action == "":
+	case r.Method == http.MethodPost && (action == "resolve" || action == "reopen"):
+	case action == "" || action == "resolve" || action == "reopen":
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if action == "" {
+		action = "delete"
+	}
+	user := currentUser(r)
+	var updated draftComment
+	err := s.store.updateComments(id, func(sess *generator.Session, comments *[]draftComment) error {
+		for i := range *comments {
+			c := &(*comments)[i]
+			if c.ID != cid {
+				continue
+			}
+			switch action {
+			case "delete":
+				if !s.canAccess(r, c.User) {
+					return &errWorkflow{http.StatusForbidden, "only the commenter can delete this comment"}
+				}
+				*comments = append((*comments)[:i], (*comments)[i+1:]...)
+				return nil
+			case "resolve":
+				if !s.canAccess(r, sess.Owner) && !s.canAccess(r, c.User) && !s.hasRole(r, roleReviewer) {
+					return &errWorkflow{http.StatusForbidden, "not allowed to resolve this comment"}
+				}
+				if c.Status != commentResolved {
+					now := time.Now()
+					c.Status, c.ResolvedBy, c.ResolvedAt, c.ResolvedVersion = commentResolved, user, &now, 0
+				}
+			case "reopen":
+				if !s.canAccess(r, sess.Owner) && !s.canAccess(r, c.User) && !s.hasRole(r, roleReviewer) {
+					return &errWorkflow{http.StatusForbidden, "not allowed to reopen this comment"}
+				}
+				c.Status, c.ResolvedBy, c.ResolvedAt, c.ResolvedVersion = commentOpen, "", nil, 0
+			}
+			updated = *c
+			return nil
+		}
+		return &errWorkflow{http.StatusNotFound, "comment not found"}
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	s.events.publish(id, eventCommentsChanged, map[string]string{"action": action, "comment_id": cid, "user": user})
+	if action == "delete" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, updated)
+}
+
+// reviseFromComments 把全部待处理批注合并为一条修订意见交给模型修订，成功后把这些批注标记为已解决。
+func (s *Server) reviseFromComments(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.ownsSession(r, id) {
+		http.Error(w, "only the author can edit this draft", http.StatusForbidden)
+		return
+	}
+	release := s.beginEdit(w, id, "comments/revise")
+	if release == nil {
+		return
+	}
+	defer release()
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	comments, _ := s.store.commentsOf(id)
+	var pending []draftComment
+	for _, c := range comments {
+		if c.Status == commentOpen {
+			pending = append(pending, c)
+		}
+	}
+	if len(pending) == 0 {
+		http.Error(w, "no open comments", http.StatusBadRequest)
+		return
+	}
+
+	instruction := commentsInstruction(pending)
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+	s.events.publish(id, eventDraftStarted, map[string]string{"comment": instruction})
+	draft, err := sess.Revise(ctx, instruction)
+	if err != nil {
+		s.events.publish(id, eventError, err.Error())
+		writeGenerateError(w, err)
+		return
+	}
+	s.events.publish(id, eventRevisionApplied, draft)
+	s.audit(r, publisher.AuditDraftRevised, id, fmt.Sprintf("revise from %d comments", len(pending)))
+
+	// 只解决提交修订时的批注，修订期间新增的批注保持待处理。
+	done := make(map[string]bool, len(pending))
+	for _, c := range pending {
+		done[c.ID] = true
+	}
+	user := currentUser(r)
+	err = s.store.updateComments(id, func(sess *generator.Session, comments *[]draftComment) error {
+		now := time.Now()
+		for i := range *comments {
+			c := &(*comments)[i]
+			if done[c.ID] && c.Status == commentOpen {
+				c.Status, c.ResolvedBy, c.ResolvedAt, c.ResolvedVersion = commentResolved, user, &now, len(sess.History)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[comments] session=%s resolve after revision failed: %v", id, err)
+	}
+	s.events.publish(id, eventCommentsChanged, map[string]string{"action": "revise", "user": user})
+	writeJSON(w, sessionResp{SessionID: id, Draft: draft, History: sess.History, HistorySummary: sess.HistorySummary})
+}
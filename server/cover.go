@@ -0,0 +1,215 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/cover"
+	"auto_wechat_article_publisher/generator"
+)
+
+// coverGenReq 为生成封面的参数；background_path 为 session 作者上传目录中的文件，字体只能在配置中指定。
+type coverGenReq struct {
+	Title          string  `json:"title,omitempty"`
+	BackgroundPath string  `json:"background_path,omitempty"`
+	BgColor        string  `json:"bg_color,omitempty"`
+	GradientTo     string  `json:"gradient_to,omitempty"`
+	FontSize       float64 `json:"font_size,omitempty"`
+	Color          string  `json:"color,omitempty"`
+}
+
+// handleSessionCover 按稿件标题生成封面并登记到 session 的上传列表。
+// Path: POST /api/sessions/{id}/cover
+func (s *Server) handleSessionCover(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	var req coverGenReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = sess.Draft.Title
+	}
+	if title == "" {
+		title = sess.Spec.Topic
+	}
+	if title == "" {
+		http.Error(w, "title required; generate draft first", http.StatusBadRequest)
+		return
+	}
+	s.writeCover(w, r, sess, title, req)
+}
+
+// writeCover 以 title 为封面文字生成封面，登记到 session 的上传列表并返回上传信息。
+func (s *Server) writeCover(w http.ResponseWriter, r *http.Request, sess *generator.Session, title string, req coverGenReq) {
+	opts := s.coverDefaults()
+	opts.Title = title
+	if req.BackgroundPath != "" {
+		if !s.canAccess(r, sess.Owner) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		bg, err := s.resolveBackground(r.Context(), sess.Owner, req.BackgroundPath)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Background = bg
+	}
+	if req.BgColor != "" {
+		opts.BgColor = req.BgColor
+	}
+	if req.GradientTo != "" {
+		opts.GradientTo = req.GradientTo
+	}
+	if req.FontSize > 0 {
+		opts.FontSize = req.FontSize
+	}
+	if req.Color != "" {
+		opts.Color = req.Color
+	}
+
+	dir, err := s.uploadDirFor(sess.Owner)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	filename := fmt.Sprintf("cover_%d.jpg", time.Now().UnixNano())
+	path := filepath.Join(dir, filename)
+	if err := cover.Generate(opts, path); err != nil {
+		http.Error(w, "generate cover: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.saveUpload(r.Context(), path); err != nil {
+		_ = os.Remove(path)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	s.store.addUpload(sess.ID, path)
+
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+	writeJSON(w, uploadResp{
+		Path:     path,
+		URL:      s.uploadURL(path),
+		Filename: filename,
+		Size:     size,
+		Usage:    "cover",
+	})
+}
+
+// resolveBackground 把 background_path 解析为 owner 上传目录中的文件：可以是文件名、相对该目录的路径，
+// 或上传接口返回的 path；拒绝绝对路径与 ..。错误信息不包含底层原因，避免探测服务器上的文件。
+func (s *Server) resolveBackground(ctx context.Context, owner, ref string) (string, error) {
+	errInvalid := errors.New("background_path must be a file uploaded to this session's upload dir")
+	ref = filepath.FromSlash(strings.TrimSpace(ref))
+	if filepath.IsAbs(ref) || slices.Contains(strings.Split(ref, string(filepath.Separator)), "..") {
+		return "", errInvalid
+	}
+	dir, err := s.uploadDirFor(owner)
+	if err != nil {
+		return "", err
+	}
+	rel := filepath.Clean(ref)
+	if r, err := filepath.Rel(dir, rel); err == nil && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		rel = r
+	}
+	path := filepath.Join(dir, rel)
+	if r, err := filepath.Rel(dir, path); err != nil || r == "." || strings.HasPrefix(r, "..") {
+		return "", errInvalid
+	}
+	// 未启用登录时上传目录下有各用户的子目录，只接受不属于其他用户的文件。
+	if owner == "" && strings.ContainsRune(rel, filepath.Separator) {
+		return "", errInvalid
+	}
+	if err := s.fetchUpload(ctx, path); err != nil {
+		return "", errors.New("background_path not found")
+	}
+	return path, nil
+}
+
+// coverDefaults 读取配置中的封面样式。
+func (s *Server) coverDefaults() cover.Options {
+	var opts cover.Options
+	if c := s.config().Cover; c != nil {
+		opts.FontPath = c.FontPath
+		opts.FontSize = c.FontSize
+		opts.Color = c.Color
+		opts.BgColor = c.BgColor
+		opts.GradientTo = c.GradientTo
+	}
+	return opts
+}
+
+// SetImageGenerator 启用 AI 封面；未设置时 ai_cover 请求会返回错误。
+func (s *Server) SetImageGenerator(g generator.ImageGenerator) {
+	s.imageGen = g
+}
+
+// generateAICover 调用文生图模型生成封面，裁剪为 2.35:1 后保存到 uploads/ 并登记到 session。
+func (s *Server) generateAICover(ctx context.Context, id string, sess *generator.Session) (string, error) {
+	if s.imageGen == nil {
+		return "", errors.New("image generator not configured; set image in config")
+	}
+	title := sess.Draft.Title
+	if title == "" {
+		title = sess.Spec.Topic
+	}
+	data, err := s.imageGen.GenerateImage(ctx, generator.BuildCoverImagePrompt(title, sess.Draft.Digest))
+	if err != nil {
+		return "", err
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("decode generated image: %w", err)
+	}
+	// 文生图模型输出方图或 16:9 等比例，与上传的图片一样居中裁剪为公众号封面的 2.35:1。
+	out, err := cover.Edit(img, cover.EditOptions{Aspect: "2.35:1"})
+	if err != nil {
+		return "", err
+	}
+	ext := ".png"
+	if format == "jpeg" {
+		ext = ".jpg"
+	}
+	dir, err := s.uploadDirFor(sess.Owner)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("ai_cover_%d%s", time.Now().UnixNano(), ext))
+	if err := writeImage(path, out); err != nil {
+		return "", err
+	}
+	if err := s.saveUpload(ctx, path); err != nil {
+		_ = os.Remove(path)
+		return "", err
+	}
+	s.store.addUpload(id, path)
+	log.Printf("[cover] ai cover generated session=%s path=%s", id, path)
+	return path, nil
+}
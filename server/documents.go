@@ -0,0 +1,160 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+	"auto_wechat_article_publisher/publisher"
+)
+
+// documentExts 为 usage=document 接受的文件扩展名。
+var documentExts = []string{".docx", ".html", ".htm", ".ipynb"}
+
+// handleDocumentUpload 处理 usage=document：把 Word (.docx)、HTML 或 Jupyter notebook 文件转为 Markdown 写入 session 稿件，
+// 其中的图片按上传规则校验后保存为该 session 的上传文件，正文中的引用改为上传路径；不符合规则的图片从正文中去掉。
+func (s *Server) handleDocumentUpload(w http.ResponseWriter, r *http.Request, sess *generator.Session, file multipart.File, header *multipart.FileHeader, limits uploadLimits) {
+	if header.Size > limits.maxBytes {
+		writeUploadError(w, limits.tooLarge())
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	if !slices.Contains(documentExts, ext) {
+		writeUploadError(w, &uploadError{
+			status: http.StatusUnsupportedMediaType, Code: uploadBadExtension, Allowed: documentExts,
+			Error: fmt.Sprintf("file extension %q is not a Word (.docx), HTML or notebook (.ipynb) document", ext),
+		})
+		return
+	}
+	tmp, err := os.MkdirTemp("", "document-upload-")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(tmp)
+	src := filepath.Join(tmp, "document"+ext)
+	dst, err := os.Create(src)
+	if err != nil {
+		http.Error(w, "save file: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, err = io.Copy(dst, file)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		http.Error(w, "write file: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	convert := publisher.ConvertHTML
+	switch ext {
+	case ".docx":
+		convert = publisher.ConvertDocx
+	case ".ipynb":
+		convert = publisher.ConvertNotebook
+	}
+	// 上传的 HTML 与 notebook 只有单个文件，相对路径的图片无法找到，会作为转换错误返回。
+	doc, err := convert(src, filepath.Join(tmp, "out"))
+	if err != nil {
+		msg := strings.ReplaceAll(strings.ReplaceAll(err.Error(), src, header.Filename), tmp+string(filepath.Separator), "")
+		http.Error(w, "convert document: "+msg, http.StatusBadRequest)
+		return
+	}
+	dir, err := s.uploadDirFor(sess.Owner)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := uploadResp{Filename: header.Filename, Size: header.Size, Usage: "document"}
+	markdown := doc.Markdown
+	for _, img := range doc.Images {
+		ref := "images/" + filepath.Base(img)
+		saved, uploadErr, err := s.saveDocumentImage(r, sess.ID, img, dir, limits)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if uploadErr != nil {
+			log.Printf("[upload] dropped image %s of %q session=%s: %s", filepath.Base(img), header.Filename, sess.ID, uploadErr.Error)
+			resp.Skipped = append(resp.Skipped, filepath.Base(img)+": "+uploadErr.Error)
+			markdown = dropImageRef(markdown, ref)
+			continue
+		}
+		markdown = strings.ReplaceAll(markdown, "]("+ref+")", "]("+saved.Path+")")
+		resp.Images = append(resp.Images, saved)
+	}
+	if doc.Title == "" {
+		for _, line := range strings.Split(markdown, "\n") {
+			if t, ok := strings.CutPrefix(line, "# "); ok {
+				doc.Title = strings.TrimSpace(t)
+				break
+			}
+		}
+	}
+	sess.Draft = generator.Draft{Title: doc.Title, Markdown: markdown, WordCount: generator.CountWords(markdown)}
+	s.store.persist(sess.ID)
+	s.events.publish(sess.ID, eventRevisionApplied, sess.Draft)
+	resp.Title, resp.Markdown = doc.Title, markdown
+	writeJSON(w, resp)
+}
+
+// saveDocumentImage 校验文档中提取的图片并保存到上传目录；不符合上传规则时返回 uploadError。
+func (s *Server) saveDocumentImage(r *http.Request, sessID, img, dir string, limits uploadLimits) (uploadResp, *uploadError, error) {
+	f, err := os.Open(img)
+	if err != nil {
+		return uploadResp{}, nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return uploadResp{}, nil, err
+	}
+	ext, uploadErr := limits.check(f, info.Size(), img)
+	if uploadErr != nil {
+		return uploadResp{}, uploadErr, nil
+	}
+	base := strings.TrimSuffix(filepath.Base(img), filepath.Ext(img))
+	path := filepath.Join(dir, fmt.Sprintf("%s_%d%s", base, time.Now().UnixNano(), ext))
+	dst, err := os.Create(path)
+	if err != nil {
+		return uploadResp{}, nil, fmt.Errorf("save file: %w", err)
+	}
+	n, err := io.Copy(dst, f)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = s.saveUpload(r.Context(), path)
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return uploadResp{}, nil, err
+	}
+	s.store.addUpload(sessID, path)
+	return uploadResp{Path: path, URL: s.uploadURL(path), Filename: filepath.Base(img), Size: n, Usage: "inline"}, nil, nil
+}
+
+// dropImageRef 去掉正文中引用 ref 的图片语法。
+func dropImageRef(markdown, ref string) string {
+	for {
+		end := strings.Index(markdown, "]("+ref+")")
+		if end < 0 {
+			return markdown
+		}
+		start := strings.LastIndex(markdown[:end], "![")
+		if start < 0 {
+			return markdown
+		}
+		markdown = markdown[:start] + markdown[end+len("]("+ref+")"):]
+	}
+}
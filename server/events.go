@@ -0,0 +1,190 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Session 生命周期事件类型。
+const (
+	eventDraftStarted    = "draft_started"
+	eventToken           = "token"
+	eventRevisionApplied = "revision_applied"
+	eventPublishProgress = "publish_progress"
+	eventError           = "error"
+	eventHeartbeat       = "heartbeat"
+	eventWorkflowChanged = "workflow_changed"
+	eventCommentsChanged = "comments_changed"
+	eventUploadProgress  = "upload_progress"
+)
+
+type sessionEvent struct {
+	Type      string    `json:"type"`
+	SessionID string    `json:"session_id"`
+	Data      any       `json:"data,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// eventHub 按 session ID 分发事件；订阅者较慢时丢弃事件，避免阻塞生成流程。
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan sessionEvent]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[string]map[chan sessionEvent]struct{})}
+}
+
+func (h *eventHub) subscribe(id string, ch chan sessionEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	set, ok := h.subs[id]
+	if !ok {
+		set = make(map[chan sessionEvent]struct{})
+		h.subs[id] = set
+	}
+	set[ch] = struct{}{}
+}
+
+func (h *eventHub) unsubscribe(id string, ch chan sessionEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	set, ok := h.subs[id]
+	if !ok {
+		return
+	}
+	delete(set, ch)
+	if len(set) == 0 {
+		delete(h.subs, id)
+	}
+}
+
+func (h *eventHub) publish(id, typ string, data any) {
+	if id == "" {
+		return
+	}
+	ev := sessionEvent{Type: typ, SessionID: id, Data: data, Time: time.Now()}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[id] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// wsClientMsg 为客户端发来的控制消息：subscribe / unsubscribe / heartbeat。
+type wsClientMsg struct {
+	Type      string `json:"type"`
+	SessionID string `json:"session_id"`
+}
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	// 来源在 handleWS 中按 cors 配置检查。
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// handleWS 建立 WebSocket 连接，复用一个连接订阅多个 session 的事件。
+// Path: /api/ws[?session_id=...]
+// 客户端发送 {"type":"heartbeat","session_id":...} 可替代 /api/heartbeat 续期。
+func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	if !s.originAllowed(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("[ws] upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	events := make(chan sessionEvent, 64)
+	subscribed := make(map[string]struct{})
+	var subMu sync.Mutex
+	defer func() {
+		subMu.Lock()
+		defer subMu.Unlock()
+		for id := range subscribed {
+			s.events.unsubscribe(id, events)
+		}
+	}()
+	subscribe := func(id string) {
+		if !s.sessionAllowed(r, id) {
+			return
+		}
+		subMu.Lock()
+		defer subMu.Unlock()
+		if _, ok := subscribed[id]; ok {
+			return
+		}
+		subscribed[id] = struct{}{}
+		s.events.subscribe(id, events)
+	}
+	unsubscribe := func(id string) {
+		subMu.Lock()
+		defer subMu.Unlock()
+		delete(subscribed, id)
+		s.events.unsubscribe(id, events)
+	}
+	if id := r.URL.Query().Get("session_id"); id != "" {
+		subscribe(id)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg wsClientMsg
+			if err := json.Unmarshal(data, &msg); err != nil || msg.SessionID == "" {
+				continue
+			}
+			switch msg.Type {
+			case "subscribe":
+				subscribe(msg.SessionID)
+			case "unsubscribe":
+				unsubscribe(msg.SessionID)
+			case "heartbeat":
+				if !s.sessionAllowed(r, msg.SessionID) {
+					continue
+				}
+				ok := s.store.heartbeat(msg.SessionID)
+				s.events.publish(msg.SessionID, eventHeartbeat, map[string]bool{"alive": ok})
+			}
+		}
+	}()
+
+	ping := time.NewTicker(30 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case ev := <-events:
+			_ = conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteJSON(ev); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+				return
+			}
+		case <-done:
+			return
+		case <-s.stop:
+			// 服务关闭：通知客户端稍后重连。
+			_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(time.Second))
+			return
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"auto_wechat_article_publisher/generator"
+)
+
+// countingLLM 记录调用次数，用于确认只读请求不会调用模型。
+type countingLLM struct{ calls atomic.Int32 }
+
+func (l *countingLLM) Complete(ctx context.Context, p generator.Prompt) (string, error) {
+	l.calls.Add(1)
+	return `{"claims":[]}`, nil
+}
+
+func (l *countingLLM) Stream(ctx context.Context, p generator.Prompt, onChunk func(string)) (string, error) {
+	return l.Complete(ctx, p)
+}
+
+func TestFactCheckGetIsReadOnly(t *testing.T) {
+	llm := &countingLLM{}
+	srv, ts := newTestServer(t, llm)
+	sess := generator.NewSession("s1", generator.Spec{Topic: "x"}, srv.genAgent)
+	sess.Draft = generator.Draft{Title: "标题", Markdown: "# 标题\n\n正文"}
+	srv.store.set("s1", sess)
+	url := ts.URL + "/api/sessions/s1/factcheck"
+
+	get := func() int {
+		res, err := http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	if code := get(); code != http.StatusNotFound {
+		t.Fatalf("GET without report = %d, want 404", code)
+	}
+	sess.FactCheck = &generator.FactCheckReport{DraftHash: "stale"}
+	if code := get(); code != http.StatusConflict {
+		t.Fatalf("GET with outdated report = %d, want 409", code)
+	}
+	if n := llm.calls.Load(); n != 0 {
+		t.Fatalf("GET called the model %d times, want 0", n)
+	}
+}
@@ -0,0 +1,393 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+	"auto_wechat_article_publisher/publisher"
+)
+
+// feedTask 为一个订阅源汇总任务及其运行状态；待汇总条目保存在 feed_state_path 中，其余状态只在内存中。
+type feedTask struct {
+	cfg   publisher.FeedConfig
+	cron  *publisher.Cron
+	topic *template.Template
+	poll  time.Duration
+
+	Name        string     `json:"name"`
+	URLs        []string   `json:"urls"`
+	Cron        string     `json:"cron,omitempty"`
+	Mode        string     `json:"mode"`
+	NextPoll    time.Time  `json:"next_poll"`
+	NextRun     *time.Time `json:"next_run,omitempty"`
+	LastPoll    *time.Time `json:"last_poll,omitempty"`
+	PollErrors  []string   `json:"poll_errors,omitempty"`
+	Pending     int        `json:"pending"`
+	LastRun     *time.Time `json:"last_run,omitempty"`
+	LastSession string     `json:"last_session,omitempty"`
+	LastJob     string     `json:"last_job,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	Runs        int        `json:"runs"`
+	Running     bool       `json:"running"`
+}
+
+type feedRunner struct {
+	mu    sync.Mutex
+	tasks []*feedTask
+	store *publisher.FeedStore
+}
+
+// newFeedRunner 校验配置中的订阅源汇总任务，配置错误时拒绝启动。首次抓取在启动后的第一次检查时进行。
+func newFeedRunner(cfgs []publisher.FeedConfig, statePath string, now time.Time) (*feedRunner, error) {
+	r := &feedRunner{store: publisher.NewFeedStore(statePath)}
+	seen := map[string]bool{}
+	for _, cfg := range cfgs {
+		if cfg.Name == "" {
+			return nil, errors.New("feeds: name is required")
+		}
+		if seen[cfg.Name] {
+			return nil, fmt.Errorf("feed %s: duplicate name", cfg.Name)
+		}
+		seen[cfg.Name] = true
+		if len(cfg.URLs) == 0 {
+			return nil, fmt.Errorf("feed %s: urls is required", cfg.Name)
+		}
+		for _, u := range cfg.URLs {
+			if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+				return nil, fmt.Errorf("feed %s: url %q must start with http:// or https://", cfg.Name, u)
+			}
+		}
+		tmpl, err := checkAutoWrite("feed", &cfg.RecurringConfig)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.PollMinutes <= 0 {
+			cfg.PollMinutes = 60
+		}
+		if cfg.MinItems <= 0 {
+			cfg.MinItems = 1
+		}
+		if cfg.MaxItems <= 0 {
+			cfg.MaxItems = 20
+		}
+		if cfg.MinItems > cfg.MaxItems {
+			return nil, fmt.Errorf("feed %s: min_items %d is larger than max_items %d", cfg.Name, cfg.MinItems, cfg.MaxItems)
+		}
+		t := &feedTask{
+			cfg: cfg, topic: tmpl, poll: time.Duration(cfg.PollMinutes) * time.Minute,
+			Name: cfg.Name, URLs: cfg.URLs, Cron: cfg.Cron, Mode: cfg.Mode, NextPoll: now,
+		}
+		if cfg.Cron != "" {
+			c, err := publisher.ParseCron(cfg.Cron)
+			if err != nil {
+				return nil, fmt.Errorf("feed %s: %w", cfg.Name, err)
+			}
+			next := c.Next(now)
+			if next.IsZero() {
+				return nil, fmt.Errorf("feed %s: cron %q never fires", cfg.Name, cfg.Cron)
+			}
+			t.cron, t.NextRun = &c, &next
+		}
+		if pending, err := r.store.Pending(cfg.Name); err == nil {
+			t.Pending = len(pending)
+		} else {
+			return nil, err
+		}
+		r.tasks = append(r.tasks, t)
+	}
+	return r, nil
+}
+
+// snapshot 返回任务状态的副本。
+func (r *feedRunner) snapshot() []feedTask {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]feedTask, 0, len(r.tasks))
+	for _, t := range r.tasks {
+		out = append(out, *t)
+	}
+	return out
+}
+
+// start 把任务标记为运行中；任务仍在运行时返回 false。
+func (r *feedRunner) start(t *feedTask) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t.Running {
+		return false
+	}
+	t.Running = true
+	return true
+}
+
+func (r *feedRunner) finish(t *feedTask, fn func(t *feedTask)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(t)
+}
+
+func (r *feedRunner) find(name string) *feedTask {
+	for _, t := range r.tasks {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// runFeeds 定期检查到了抓取间隔或汇总时间的任务。服务停止期间错过的汇总不会补跑，新条目在下次汇总时收入。
+func (s *Server) runFeeds(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		select {
+		case now = <-ticker.C:
+		case <-s.stop:
+			return
+		}
+		type dueTask struct {
+			t             *feedTask
+			poll, compile bool
+		}
+		var due []dueTask
+		s.feeds.mu.Lock()
+		for _, t := range s.feeds.tasks {
+			d := dueTask{t: t, poll: !now.Before(t.NextPoll)}
+			if d.poll {
+				t.NextPoll = now.Add(t.poll)
+			}
+			if t.cron != nil && !now.Before(*t.NextRun) {
+				next := t.cron.Next(now)
+				t.NextRun, d.compile = &next, true
+			}
+			if d.poll || d.compile {
+				due = append(due, d)
+			}
+		}
+		s.feeds.mu.Unlock()
+		for _, d := range due {
+			s.triggerFeed(d.t, now, d.poll, d.compile, false)
+		}
+	}
+}
+
+// triggerFeed 在后台抓取订阅源并按需汇总；上一次尚未结束时跳过。
+// 未配置 cron 的任务在抓取后新条目达到 min_items 即汇总；manual 为 true 时只要有新条目就汇总。
+func (s *Server) triggerFeed(t *feedTask, now time.Time, poll, compile, manual bool) bool {
+	if !s.feeds.start(t) {
+		log.Printf("[feeds] %s still running; skip", t.Name)
+		return false
+	}
+	s.tasks.Add(1)
+	go func() {
+		defer s.tasks.Done()
+		if poll {
+			s.pollFeed(t, now)
+		}
+		if compile || manual || t.cron == nil {
+			s.compileFeed(t, now, manual)
+		}
+		s.feeds.finish(t, func(t *feedTask) { t.Running = false })
+	}()
+	return true
+}
+
+// pollFeed 抓取任务的全部订阅源，把新条目（按 keywords 过滤）加入待汇总列表；单个源失败不影响其他源。
+func (s *Server) pollFeed(t *feedTask, now time.Time) {
+	cfg := t.cfg
+	keep := func(item publisher.FeedItem) bool {
+		if len(cfg.Keywords) == 0 {
+			return true
+		}
+		text := strings.ToLower(item.Title + " " + item.Summary)
+		for _, k := range cfg.Keywords {
+			if strings.Contains(text, strings.ToLower(k)) {
+				return true
+			}
+		}
+		return false
+	}
+	var backfill time.Time
+	if cfg.BackfillDays > 0 {
+		backfill = now.AddDate(0, 0, -cfg.BackfillDays)
+	}
+	added := 0
+	var errs []string
+	for _, u := range cfg.URLs {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		feed, err := publisher.FetchFeed(ctx, nil, u)
+		cancel()
+		if err == nil {
+			var n int
+			n, err = s.feeds.store.Add(cfg.Name, u, feed.Items, now, backfill, keep)
+			added += n
+		}
+		if err != nil {
+			log.Printf("[feeds] %s: %v", cfg.Name, err)
+			errs = append(errs, err.Error())
+		}
+	}
+	pending, _ := s.feeds.store.Pending(cfg.Name)
+	if added > 0 {
+		log.Printf("[feeds] %s: %d new items, %d pending", cfg.Name, added, len(pending))
+	}
+	s.feeds.finish(t, func(t *feedTask) {
+		t.LastPoll, t.PollErrors, t.Pending = &now, errs, len(pending)
+	})
+}
+
+// compileFeed 把待汇总条目（最新的 max_items 条）作为参考资料生成一篇汇总文章；不足 min_items 时跳过。
+// 条目只在 session 创建成功后移出待汇总列表，生成失败时下次汇总仍会收入。
+func (s *Server) compileFeed(t *feedTask, now time.Time, manual bool) {
+	cfg := t.cfg
+	pending, err := s.feeds.store.Pending(cfg.Name)
+	if err != nil {
+		s.feeds.finish(t, func(t *feedTask) { t.LastError = err.Error() })
+		return
+	}
+	need := cfg.MinItems
+	if manual {
+		need = 1
+	}
+	if len(pending) < need {
+		if manual || t.cron != nil {
+			log.Printf("[feeds] %s: %d pending items, need %d; skip", cfg.Name, len(pending), need)
+		}
+		return
+	}
+	var run int
+	s.feeds.finish(t, func(t *feedTask) {
+		t.Runs++
+		run = t.Runs
+		t.LastRun = &now
+		t.LastSession, t.LastJob, t.LastError = "", "", ""
+	})
+	items := pending
+	if len(items) > cfg.MaxItems {
+		items = items[:cfg.MaxItems]
+	}
+	notice := recurringNotice{Task: cfg.Name, Mode: cfg.Mode, Time: now, Items: len(items)}
+	fail := func(err error) {
+		log.Printf("[feeds] %s run=%d failed: %v", cfg.Name, run, err)
+		s.feeds.finish(t, func(t *feedTask) { t.LastError = err.Error() })
+		notice.Status, notice.Error = "failed", err.Error()
+		s.notifyRecurring(cfg.NotifyURL, notice)
+	}
+
+	topic, err := renderTopic(t.topic, now, topicData{Run: run, Count: len(items)})
+	if err != nil {
+		fail(err)
+		return
+	}
+	notice.Topic = topic
+	spec, err := s.recurringSpec(cfg.RecurringConfig, topic)
+	if err != nil {
+		fail(err)
+		return
+	}
+	spec.References = feedReferences(items)
+	spec.Constraints = append(spec.Constraints, fmt.Sprintf("这是一篇资讯汇总：参考资料为订阅源中的 %d 条新动态，按主题归类整理，每条说明要点并注明来源，不要编造资料之外的事实", len(items)))
+
+	id, jobID, err := s.autoWrite("feed task "+cfg.Name, cfg.RecurringConfig, spec, &notice, func(err error) {
+		s.feeds.finish(t, func(t *feedTask) { t.LastError = err.Error() })
+	})
+	if id != "" {
+		ids := make([]string, 0, len(pending))
+		for _, item := range pending {
+			ids = append(ids, item.ID)
+		}
+		if err := s.feeds.store.Remove(cfg.Name, ids); err != nil {
+			log.Printf("[feeds] %s: save state: %v", cfg.Name, err)
+		}
+		s.feeds.finish(t, func(t *feedTask) { t.LastSession, t.Pending = id, 0 })
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+	log.Printf("[feeds] %s run=%d items=%d session=%s title=%q", cfg.Name, run, len(items), id, notice.Title)
+	s.feeds.finish(t, func(t *feedTask) { t.LastJob = jobID })
+}
+
+// feedReferences 把条目转为参考资料，摘要前注明来源与发布日期。
+func feedReferences(items []publisher.FeedItem) []generator.Reference {
+	refs := make([]generator.Reference, 0, len(items))
+	for _, item := range items {
+		summary := item.Summary
+		if summary == "" {
+			summary = item.Title
+		}
+		var meta []string
+		if item.Feed != "" {
+			meta = append(meta, "来源："+item.Feed)
+		}
+		if !item.Published.IsZero() {
+			meta = append(meta, "发布于 "+item.Published.Local().Format("2006-01-02"))
+		}
+		if len(meta) > 0 {
+			summary = "（" + strings.Join(meta, "，") + "）" + summary
+		}
+		refs = append(refs, generator.Reference{URL: item.Link, Title: item.Title, Summary: summary})
+	}
+	return refs
+}
+
+// handleFeeds 列出订阅源汇总任务及其待汇总条目数与最近一次结果；启用登录时只列出属于当前用户的任务。
+// Path: GET /api/feeds
+func (s *Server) handleFeeds(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tasks := []feedTask{}
+	for _, t := range s.feeds.snapshot() {
+		if s.canAccess(r, t.cfg.Owner) {
+			tasks = append(tasks, t)
+		}
+	}
+	writeJSON(w, map[string]any{"tasks": tasks})
+}
+
+// handleFeedByName 查看任务的待汇总条目，或立即抓取并汇总一次（有新条目即生成，不受 min_items 限制）。
+// Path: GET /api/feeds/{name}, POST /api/feeds/{name}/run
+func (s *Server) handleFeedByName(w http.ResponseWriter, r *http.Request) {
+	name, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/feeds/"), "/")
+	t := s.feeds.find(name)
+	if t == nil || !s.canAccess(r, t.cfg.Owner) {
+		http.Error(w, "feed task not found", http.StatusNotFound)
+		return
+	}
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		items, err := s.feeds.store.Pending(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if items == nil {
+			items = []publisher.FeedItem{}
+		}
+		var task feedTask
+		s.feeds.finish(t, func(t *feedTask) { task = *t })
+		writeJSON(w, map[string]any{"task": task, "items": items})
+	case action == "run" && r.Method == http.MethodPost:
+		if !s.triggerFeed(t, time.Now(), true, false, true) {
+			http.Error(w, "task is still running", http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	case action == "" || action == "run":
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
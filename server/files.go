@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+	"auto_wechat_article_publisher/storage"
+)
+
+// newFileStore 按 storage 配置创建上传文件的持久存储，未配置时返回 nil（只使用本地 uploads/）。
+func newFileStore(cfg *publisher.StorageConfig) (storage.Store, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	st, err := storage.New(storage.Config{
+		Type:      cfg.Type,
+		Dir:       cfg.Dir,
+		Bucket:    cfg.Bucket,
+		Region:    cfg.Region,
+		Endpoint:  cfg.Endpoint,
+		AccessKey: cfg.AccessKey,
+		SecretKey: cfg.SecretKey,
+		Prefix:    cfg.Prefix,
+		PathStyle: cfg.PathStyle,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[storage] uploads stored in %s", st.Name())
+	return st, nil
+}
+
+// uploadKey 返回上传目录下文件的存储键；不在上传目录中的路径（如本地的默认封面）返回 false。
+func (s *Server) uploadKey(path string) (string, bool) {
+	rel, err := filepath.Rel(s.uploadDir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// saveUpload 把刚写入本地的上传文件保存到持久存储；未配置存储时不做任何事。
+func (s *Server) saveUpload(ctx context.Context, path string) error {
+	if s.files == nil {
+		return nil
+	}
+	key, ok := s.uploadKey(path)
+	if !ok {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+	if err := s.files.Put(ctx, key, data, http.DetectContentType(data)); err != nil {
+		return fmt.Errorf("save to storage: %w", err)
+	}
+	return nil
+}
+
+// fetchUpload 确保上传文件在本地可用：本地缺失（如容器重建）时从持久存储取回。
+// 返回的错误在文件不存在时满足 errors.Is(err, os.ErrNotExist)。
+func (s *Server) fetchUpload(ctx context.Context, path string) error {
+	_, err := os.Stat(path)
+	if err == nil || !errors.Is(err, os.ErrNotExist) || s.files == nil {
+		return err
+	}
+	key, ok := s.uploadKey(path)
+	if !ok {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+	data, gerr := s.files.Get(ctx, key)
+	if errors.Is(gerr, storage.ErrNotFound) {
+		return err
+	}
+	if gerr != nil {
+		return fmt.Errorf("fetch from storage: %w", gerr)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", path, time.Now().UnixNano())
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	log.Printf("[storage] restored %s", path)
+	return nil
+}
+
+// removeUploads 在后台从持久存储删除 session 的上传文件，失败只记录日志。
+func (s *Server) removeUploads(paths []string) {
+	if s.files == nil || len(paths) == 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		for _, p := range paths {
+			key, ok := s.uploadKey(p)
+			if !ok {
+				continue
+			}
+			if err := s.files.Delete(ctx, key); err != nil {
+				log.Printf("[storage] delete %s failed: %v", key, err)
+			}
+		}
+	}()
+}
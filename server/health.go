@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+	"auto_wechat_article_publisher/storage"
+)
+
+// defaultHealthCache 为 health.cache_seconds 未配置时外部依赖检查结果的缓存时间。
+const defaultHealthCache = 10 * time.Minute
+
+// checkResult 为 /readyz 中一项检查的结果。
+type checkResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Detail 为附加说明，如 session 存储类型、主模型名称。
+	Detail    string    `json:"detail,omitempty"`
+	LatencyMS int64     `json:"latency_ms"`
+	Cached    bool      `json:"cached,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// healthCache 缓存外部依赖（微信、模型）的检查结果；检查时持锁，避免并发探活重复调用外部接口。
+type healthCache struct {
+	mu      sync.Mutex
+	results map[string]checkResult
+}
+
+// runCheck 执行一项检查并计时。
+func runCheck(fn func() (string, error)) checkResult {
+	start := time.Now()
+	detail, err := fn()
+	res := checkResult{Status: "ok", Detail: detail, LatencyMS: time.Since(start).Milliseconds(), CheckedAt: start}
+	if err != nil {
+		res.Status, res.Error = "fail", err.Error()
+	}
+	return res
+}
+
+// cached 返回 ttl 内的上次结果，过期时重新检查；失败的结果最多缓存 1 分钟，以便依赖恢复后尽快就绪。
+func (c *healthCache) cached(name string, ttl time.Duration, fn func() (string, error)) checkResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if res, ok := c.results[name]; ok {
+		keep := ttl
+		if res.Status != "ok" {
+			keep = min(ttl, time.Minute)
+		}
+		if time.Since(res.CheckedAt) < keep {
+			res.Cached = true
+			return res
+		}
+	}
+	res := runCheck(fn)
+	if c.results == nil {
+		c.results = make(map[string]checkResult)
+	}
+	c.results[name] = res
+	return res
+}
+
+// checkWritableDir 在目录中创建并删除一个临时文件，确认可写。
+func checkWritableDir(dir string) error {
+	f, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	_, werr := f.WriteString("ok")
+	cerr := f.Close()
+	os.Remove(name)
+	if werr != nil {
+		return werr
+	}
+	return cerr
+}
+
+// handleHealthz 只表示进程存活，不检查依赖。
+// Path: GET /healthz
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, map[string]any{"status": "ok", "uptime_seconds": int64(time.Since(s.startedAt).Seconds()), "time": time.Now()})
+}
+
+// handleReadyz 检查上传目录、session 存储、发布记录、定时发布与用户文件是否可用，
+// 并按 health 配置检查微信 access_token 与主模型（结果缓存）；任一项失败返回 503。
+// Path: GET /readyz
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	checks := map[string]checkResult{
+		"uploads": runCheck(func() (string, error) {
+			return s.uploadDir, checkWritableDir(s.uploadDir)
+		}),
+		"sessions": runCheck(func() (string, error) {
+			if s.store.backend == nil {
+				return "memory", nil
+			}
+			return "bbolt", s.store.backend.ping()
+		}),
+		"publish_history": runCheck(func() (string, error) {
+			path := s.config().PublishHistoryPath
+			if path == "" {
+				path = publisher.DefaultPublishHistoryPath
+			}
+			return path, checkWritableDir(filepath.Dir(path))
+		}),
+		"audit_log": runCheck(func() (string, error) {
+			path := s.auditLog.Path()
+			return path, checkWritableDir(filepath.Dir(path))
+		}),
+		"schedules": runCheck(func() (string, error) {
+			path := s.config().SchedulePath
+			if path == "" {
+				path = publisher.DefaultSchedulePath
+			}
+			if _, err := s.schedules.List(""); err != nil {
+				return path, err
+			}
+			return path, checkWritableDir(filepath.Dir(path))
+		}),
+	}
+	if s.files != nil {
+		checks["storage"] = s.health.cached("storage", time.Minute, func() (string, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			return s.files.Name(), storage.Ping(ctx, s.files)
+		})
+	}
+	if s.auth != nil {
+		checks["users"] = runCheck(func() (string, error) {
+			_, err := s.auth.users.List()
+			return s.auth.users.path, err
+		})
+	}
+	if cfg := s.config().Health; cfg != nil {
+		ttl := defaultHealthCache
+		if cfg.CacheSeconds > 0 {
+			ttl = time.Duration(cfg.CacheSeconds) * time.Second
+		}
+		if cfg.CheckWeChat {
+			checks["wechat"] = s.health.cached("wechat", ttl, func() (string, error) {
+				return s.config().AccountID(), publisher.CheckAccessToken(nil, s.config())
+			})
+		}
+		if cfg.CheckLLM {
+			checks["llm"] = s.health.cached("llm", ttl, func() (string, error) {
+				ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+				defer cancel()
+				return s.agent().Ping(ctx)
+			})
+		}
+	}
+
+	status, code := "ok", http.StatusOK
+	for _, c := range checks {
+		if c.Status != "ok" {
+			status, code = "fail", http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	writeJSON(w, map[string]any{"status": status, "checks": checks, "time": time.Now()})
+}
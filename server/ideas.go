@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+)
+
+type ideasReq struct {
+	Audience string `json:"audience"`
+	Domain   string `json:"domain"`
+	Count    int    `json:"count,omitempty"`
+	Style    string `json:"style,omitempty"`
+	// StartDate 为首篇计划发布日期（YYYY-MM-DD），默认明天；IntervalDays 默认 7。
+	StartDate    string `json:"start_date,omitempty"`
+	IntervalDays int    `json:"interval_days,omitempty"`
+	// Save 为 true 时把选题写入内容日历。
+	Save bool `json:"save,omitempty"`
+}
+
+// handleIdeas 按读者与领域生成打分的选题及建议大纲，可选写入内容日历。
+// Path: POST /api/ideas
+func (s *Server) handleIdeas(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req ideasReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 8
+	}
+	ideaReq := generator.IdeaRequest{
+		Audience:     strings.TrimSpace(req.Audience),
+		Domain:       strings.TrimSpace(req.Domain),
+		Count:        req.Count,
+		Style:        req.Style,
+		IntervalDays: req.IntervalDays,
+	}
+	if req.StartDate != "" {
+		start, err := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+		if err != nil {
+			http.Error(w, "invalid start_date: use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		ideaReq.Start = start
+	}
+	ideaReq.User = currentUser(r)
+	if ideaReq.Audience == "" && ideaReq.Domain == "" {
+		http.Error(w, "audience or domain is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+	ideas, err := s.agent().GenerateIdeas(ctx, ideaReq)
+	if err != nil {
+		writeGenerateError(w, err)
+		return
+	}
+	if req.Save {
+		if ideas, err = s.calendar.Add(ideas); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	writeJSON(w, map[string]any{"ideas": ideas})
+}
+
+// handleCalendar 列出内容日历。
+// Path: GET /api/calendar
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	items, err := s.calendar.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if items == nil {
+		items = []generator.Idea{}
+	}
+	writeJSON(w, items)
+}
+
+type calendarUpdateReq struct {
+	Title       *string   `json:"title,omitempty"`
+	Angle       *string   `json:"angle,omitempty"`
+	Outline     *[]string `json:"outline,omitempty"`
+	PublishDate *string   `json:"publish_date,omitempty"`
+}
+
+// handleCalendarByID 修改或删除日历条目。
+// Path: PUT/DELETE /api/calendar/{id}
+func (s *Server) handleCalendarByID(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/calendar/")
+	if id == "" {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		var req calendarUpdateReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		item, err := s.calendar.Update(id, func(it *generator.Idea) error {
+			if req.Title != nil {
+				if strings.TrimSpace(*req.Title) == "" {
+					return errors.New("title is empty")
+				}
+				it.Title = strings.TrimSpace(*req.Title)
+			}
+			if req.Angle != nil {
+				it.Angle = *req.Angle
+			}
+			if req.Outline != nil {
+				it.Outline = *req.Outline
+			}
+			if req.PublishDate != nil {
+				it.PublishDate = *req.PublishDate
+			}
+			return nil
+		})
+		if err != nil {
+			writeCalendarError(w, err)
+			return
+		}
+		writeJSON(w, item)
+	case http.MethodDelete:
+		if err := s.calendar.Delete(id); err != nil {
+			writeCalendarError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeCalendarError(w http.ResponseWriter, err error) {
+	if errors.Is(err, generator.ErrIdeaNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/cover"
+)
+
+// cropRect 为裁剪区域（像素，旋转后图片的坐标）。
+type cropRect struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// uploadEditReq 为图片编辑请求，按旋转、裁剪、缩放的顺序执行。
+type uploadEditReq struct {
+	SessionID string `json:"session_id"`
+	// Rotate 为顺时针旋转角度：90、180 或 270。
+	Rotate int       `json:"rotate,omitempty"`
+	Crop   *cropRect `json:"crop,omitempty"`
+	// Aspect 为裁剪比例预设 2.35:1 或 1:1，在 crop（或整张图）内居中取该比例的最大区域。
+	Aspect string `json:"aspect,omitempty"`
+	// Width/Height 为缩放后的尺寸，只设置一个时按比例计算另一个。
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	Usage  string `json:"usage,omitempty"`
+}
+
+// handleUploadByName 处理分片上传与已上传文件的操作。
+// Path: POST /api/uploads/{name}/edit
+func (s *Server) handleUploadByName(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/uploads/")
+	if rest == "resumable" || strings.HasPrefix(rest, "resumable/") {
+		s.handleResumable(w, r, strings.TrimPrefix(strings.TrimPrefix(rest, "resumable"), "/"))
+		return
+	}
+	name, action, _ := strings.Cut(rest, "/")
+	if name == "" || action != "edit" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.handleUploadEdit(w, r, name)
+}
+
+// handleUploadEdit 裁剪、旋转或缩放 session 上传目录中的图片，结果保存为新的上传文件，原文件保留。
+func (s *Server) handleUploadEdit(w http.ResponseWriter, r *http.Request, name string) {
+	var req uploadEditReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	req.SessionID = strings.TrimSpace(req.SessionID)
+	if req.SessionID == "" {
+		http.Error(w, "session_id required", http.StatusBadRequest)
+		return
+	}
+	sess, ok := s.store.get(req.SessionID)
+	if !ok || !s.sessionAllowed(r, req.SessionID) {
+		http.Error(w, "session not found or expired; regenerate draft", http.StatusNotFound)
+		return
+	}
+	if name != filepath.Base(name) || strings.Contains(name, "..") || strings.ContainsAny(name, `/\`) {
+		http.Error(w, "invalid upload name", http.StatusBadRequest)
+		return
+	}
+	if req.Rotate == 0 && req.Crop == nil && req.Aspect == "" && req.Width == 0 && req.Height == 0 {
+		http.Error(w, "nothing to do: set rotate, crop, aspect, width or height", http.StatusBadRequest)
+		return
+	}
+	limits := s.uploadLimits()
+	if req.Width > limits.maxWidth || req.Height > limits.maxHeight {
+		http.Error(w, fmt.Sprintf("width/height exceed %dx%d", limits.maxWidth, limits.maxHeight), http.StatusBadRequest)
+		return
+	}
+
+	dir, err := s.uploadDirFor(sess.Owner)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	src := filepath.Join(dir, name)
+	if err := s.fetchUpload(r.Context(), src); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			http.Error(w, "upload not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	img, format, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		http.Error(w, "decode image: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts := cover.EditOptions{Rotate: req.Rotate, Aspect: req.Aspect, Width: req.Width, Height: req.Height}
+	if c := req.Crop; c != nil {
+		if c.Width <= 0 || c.Height <= 0 {
+			http.Error(w, "crop width and height must be positive", http.StatusBadRequest)
+			return
+		}
+		opts.Crop = image.Rect(c.X, c.Y, c.X+c.Width, c.Y+c.Height)
+	}
+	out, err := cover.Edit(img, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// JPEG 保持 JPEG，其余（PNG、GIF 的第一帧）保存为 PNG。
+	ext := ".png"
+	if format == "jpeg" {
+		ext = ".jpg"
+	}
+	filename := fmt.Sprintf("%s_edit_%d%s", editBase(name), time.Now().UnixNano(), ext)
+	path := filepath.Join(dir, filename)
+	if err := writeImage(path, out); err != nil {
+		http.Error(w, "write file: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := s.saveUpload(r.Context(), path); err != nil {
+		_ = os.Remove(path)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	s.store.addUpload(req.SessionID, path)
+
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+	b := out.Bounds()
+	log.Printf("[upload] edited %s -> %s (%dx%d) session=%s", name, filename, b.Dx(), b.Dy(), req.SessionID)
+	usage := strings.TrimSpace(req.Usage)
+	if usage == "" {
+		usage = "cover"
+	}
+	writeJSON(w, uploadResp{
+		Path:     path,
+		URL:      s.uploadURL(path),
+		Filename: filename,
+		Size:     size,
+		Usage:    usage,
+		Width:    b.Dx(),
+		Height:   b.Dy(),
+	})
+}
+
+// editBase 返回编辑结果文件名的前缀：去掉扩展名及上传、编辑时追加的时间戳，避免多次编辑后文件名越来越长。
+func editBase(name string) string {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	if i := strings.LastIndexByte(base, '_'); i > 0 && strings.Trim(base[i+1:], "0123456789") == "" {
+		base = base[:i]
+	}
+	base = strings.TrimSuffix(base, "_edit")
+	if base == "" {
+		base = "upload"
+	}
+	return base
+}
+
+// writeImage 按扩展名把图片编码为 JPEG（.jpg）或 PNG 写入 path，失败时删除写了一半的文件。
+func writeImage(path string, img image.Image) error {
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(path) == ".jpg" {
+		err = jpeg.Encode(dst, img, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(dst, img)
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+	}
+	return err
+}
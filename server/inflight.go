@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// inflightOp 为 session 上正在执行的修改（生成、修订、润色等）。
+type inflightOp struct {
+	action string
+	since  time.Time
+}
+
+// begin 标记 session 开始一次修改，返回结束时调用的函数；同一 session 同时只允许一次修改，
+// 避免并发修订交错写入稿件与修订历史。已有修改在执行时返回该修改与 false。
+func (s *sessionStore) begin(id, action string) (func(), inflightOp, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if op, ok := s.inflight[id]; ok {
+		return nil, op, false
+	}
+	if s.inflight == nil {
+		s.inflight = make(map[string]inflightOp)
+	}
+	s.inflight[id] = inflightOp{action: action, since: time.Now()}
+	return func() {
+		s.mu.Lock()
+		delete(s.inflight, id)
+		// 执行期间重新加载了配置时，结束后再切换到新的 Agent。
+		if entry, ok := s.sessions[id]; ok && s.agent != nil {
+			entry.sess.SetAgent(s.agent)
+		}
+		s.mu.Unlock()
+	}, inflightOp{}, true
+}
+
+// generating 判断 session 是否有修改在执行。
+func (s *sessionStore) generating(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.inflight[id]
+	return ok
+}
+
+// beginEdit 开始一次修改；已有修改在执行时返回 409 与 JSON（code 为 generation_in_progress）并返回 nil。
+func (s *Server) beginEdit(w http.ResponseWriter, id, action string) func() {
+	done, busy, ok := s.store.begin(id, action)
+	if ok {
+		return done
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error":      fmt.Sprintf("session is busy with %q since %s; wait for it to finish", busy.action, busy.since.Format(time.RFC3339)),
+		"code":       "generation_in_progress",
+		"action":     busy.action,
+		"started_at": busy.since,
+	})
+	return nil
+}
@@ -0,0 +1,584 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+	"auto_wechat_article_publisher/publisher"
+)
+
+const (
+	// maxIngestBytes 为推送请求体与读取的单个仓库文件的大小上限。
+	maxIngestBytes = 10 << 20
+	// maxIngestFiles 为一次 Git push 最多处理的 Markdown 文件数。
+	maxIngestFiles = 20
+)
+
+// ingestImage 匹配 Markdown 图片语法，第 2 组为图片地址。
+var ingestImage = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+
+// ingestReq 为 JSON 形式的推送；以 text/markdown 等直接推送正文时，同名参数放在查询字符串中。
+// 未设置的字段使用配置中 ingest 的默认值。
+type ingestReq struct {
+	Markdown string `json:"markdown"`
+	// Path 为文档的文件名（可选），只用于标识结果，front matter 与正文都没有标题时作为标题。
+	Path      string `json:"path,omitempty"`
+	Mode      string `json:"mode,omitempty"`
+	CoverPath string `json:"cover_path,omitempty"`
+	AICover   bool   `json:"ai_cover,omitempty"`
+	Author    string `json:"author,omitempty"`
+	Polish    bool   `json:"polish,omitempty"`
+}
+
+// ingestResult 为一篇推送文档的处理结果。
+type ingestResult struct {
+	Path string `json:"path,omitempty"`
+	// Status 为 review（已保存为 session 等待审核）、queued（已提交到发布队列）或 failed。
+	Status    string `json:"status"`
+	SessionID string `json:"session_id,omitempty"`
+	Title     string `json:"title,omitempty"`
+	JobID     string `json:"job_id,omitempty"`
+	// Skipped 为未能读取或不符合上传规则、已从正文中去掉的图片。
+	Skipped []string `json:"skipped,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// ingestDoc 为一篇待处理的推送文档；fetch 非空时用于读取相对路径的图片与封面（Git push 中为仓库中的文件）。
+type ingestDoc struct {
+	path     string
+	markdown string
+	err      error
+	fetch    func(ctx context.Context, ref string) ([]byte, error)
+	// title 非空时作为标题，front matter 中的 title 优先（邮件投稿中为主题）。
+	title string
+	// cover 为 front matter 未设置 cover 时通过 fetch 读取的封面（邮件投稿中为名为 cover 的图片附件）。
+	cover string
+	// source 为审计日志中的来源，默认为 ingest 与文档路径。
+	source string
+}
+
+// handleIngest 接收 CI 推送的 Markdown 或 Git push webhook，解析 front matter 后保存为 session 等待审核，
+// 或直接发布到草稿箱。任一文档失败时返回 422，结果中列出每篇文档的处理情况。
+// Path: POST /api/ingest
+func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var cfg publisher.IngestConfig
+	if c := s.config().Ingest; c != nil {
+		cfg = *c
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIngestBytes))
+	if err != nil {
+		http.Error(w, "read body: "+err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	signed := cfg.Secret != "" && ingestSigned(r, body, cfg.Secret)
+	owner := sessionOwner(r)
+	switch {
+	case signed:
+		owner = cfg.Owner
+	case cfg.Secret == "":
+		// 未设置密钥时与其他接口一样，已由登录中间件检查。
+	case s.auth != nil:
+		u, err := s.authenticate(r)
+		if err != nil {
+			http.Error(w, "ingest token or login required", http.StatusUnauthorized)
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), authUserKey{}, u))
+		owner = u.Name
+	default:
+		http.Error(w, "invalid ingest token or signature", http.StatusUnauthorized)
+		return
+	}
+
+	opts := ingestReq{Mode: cfg.Mode, CoverPath: cfg.CoverPath, AICover: cfg.AICover, Author: cfg.Author, Polish: cfg.Polish}
+	// 请求中的 cover_path 只能是调用方上传目录中的文件；配置中的 cover_path 不受此限制。
+	overlay := func(req ingestReq) bool {
+		if req.CoverPath != "" {
+			p, err := s.ownUploadPath(owner, req.CoverPath)
+			if err != nil {
+				http.Error(w, "invalid cover_path: "+err.Error(), http.StatusBadRequest)
+				return false
+			}
+			req.CoverPath = p
+		}
+		opts.overlay(req)
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	var docs []ingestDoc
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if platform, event := gitEvent(r); platform != "" {
+		// 仓库地址来自请求体，必须校验签名，避免被用来让服务器请求任意地址。
+		if !signed {
+			http.Error(w, "git webhooks require ingest.secret in config and a signed request", http.StatusUnauthorized)
+			return
+		}
+		if event != "push" && event != "Push Hook" {
+			writeJSON(w, map[string]any{"ignored": event, "results": []ingestResult{}})
+			return
+		}
+		if docs, err = gitPushDocs(ctx, cfg, platform, body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if mediaType == "application/json" {
+		var req ingestReq
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !overlay(req) {
+			return
+		}
+		docs = []ingestDoc{{path: req.Path, markdown: req.Markdown}}
+	} else {
+		q := r.URL.Query()
+		req := ingestReq{Mode: q.Get("mode"), CoverPath: q.Get("cover_path"), Author: q.Get("author")}
+		req.AICover, _ = strconv.ParseBool(q.Get("ai_cover"))
+		req.Polish, _ = strconv.ParseBool(q.Get("polish"))
+		if !overlay(req) {
+			return
+		}
+		docs = []ingestDoc{{path: q.Get("path"), markdown: string(body)}}
+	}
+
+	switch opts.Mode {
+	case "":
+		opts.Mode = recurringReview
+	case recurringReview, recurringPublish:
+	default:
+		http.Error(w, fmt.Sprintf("unknown mode %q (use review or publish)", opts.Mode), http.StatusBadRequest)
+		return
+	}
+	// 启用审核流程时，登录用户推送的稿件同样须审核通过后才能发布；密钥推送视同配置中的周期任务。
+	if opts.Mode == recurringPublish && !signed && s.workflowEnforced() {
+		http.Error(w, "review workflow is enabled; ingest with mode review, or push with the ingest secret", http.StatusConflict)
+		return
+	}
+	if opts.CoverPath != "" {
+		if err := s.fetchUpload(r.Context(), opts.CoverPath); err != nil {
+			http.Error(w, "cover_path not found: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	results := make([]ingestResult, 0, len(docs))
+	status := http.StatusOK
+	for _, doc := range docs {
+		res := s.ingestDocument(ctx, r, doc, opts, owner)
+		if res.Status == "failed" {
+			status = http.StatusUnprocessableEntity
+			log.Printf("[ingest] %s failed: %s", doc.path, res.Error)
+		} else {
+			log.Printf("[ingest] %s -> session=%s status=%s title=%q", doc.path, res.SessionID, res.Status, res.Title)
+		}
+		results = append(results, res)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	writeJSON(w, map[string]any{"results": results})
+}
+
+// ingestSecret 返回配置的推送密钥，未配置时为空。
+func (s *Server) ingestSecret() string {
+	if c := s.config().Ingest; c != nil {
+		return c.Secret
+	}
+	return ""
+}
+
+// overlay 用请求中设置了的字段覆盖默认值。
+func (o *ingestReq) overlay(req ingestReq) {
+	if req.Mode != "" {
+		o.Mode = req.Mode
+	}
+	if req.CoverPath != "" {
+		o.CoverPath = req.CoverPath
+	}
+	if req.Author != "" {
+		o.Author = req.Author
+	}
+	o.AICover = o.AICover || req.AICover
+	o.Polish = o.Polish || req.Polish
+}
+
+// ingestDocument 把一篇文档保存为 session：相对路径的图片与 front matter 中的封面从仓库读取后按上传规则保存，
+// 读取失败或不符合规则的图片从正文中去掉；publish 模式再提交到发布队列。
+func (s *Server) ingestDocument(ctx context.Context, r *http.Request, doc ingestDoc, opts ingestReq, owner string) ingestResult {
+	res := ingestResult{Path: doc.path, Status: "failed"}
+	fail := func(err error) ingestResult {
+		res.Error = err.Error()
+		return res
+	}
+	if doc.err != nil {
+		return fail(doc.err)
+	}
+	fm, body := publisher.ParseFrontMatter([]byte(doc.markdown))
+	markdown := strings.TrimSpace(string(body))
+	if markdown == "" {
+		return fail(errors.New("document is empty"))
+	}
+	title := strings.TrimSpace(fm.Title)
+	if title == "" {
+		title = strings.TrimSpace(doc.title)
+	}
+	for _, line := range strings.Split(markdown, "\n") {
+		if t, ok := strings.CutPrefix(line, "# "); ok && title == "" {
+			title = strings.TrimSpace(t)
+			break
+		}
+	}
+	if title == "" {
+		title = strings.TrimSuffix(path.Base(doc.path), path.Ext(doc.path))
+	}
+	if title == "" || title == "." {
+		return fail(errors.New("title missing: set title in the front matter or start with a # heading"))
+	}
+	res.Title = title
+	publish := opts.Mode == recurringPublish
+	if publish && fm.Cover == "" && doc.cover == "" && opts.CoverPath == "" && (!opts.AICover || s.imageGen == nil) {
+		return fail(errors.New("cover required: set cover in the front matter, cover_path, or ai_cover with image configured"))
+	}
+
+	if err := s.store.admit(); err != nil {
+		return fail(err)
+	}
+	id := newSessionID()
+	sess := generator.NewSession(id, generator.Spec{Topic: title}, s.agent())
+	sess.Owner = owner
+	s.store.set(id, sess)
+	res.SessionID = id
+	dir, err := s.uploadDirFor(owner)
+	if err != nil {
+		return fail(err)
+	}
+	tmp, err := os.MkdirTemp("", "ingest-")
+	if err != nil {
+		return fail(err)
+	}
+	defer os.RemoveAll(tmp)
+	limits := s.uploadLimits()
+	saved := 0
+	// save 读取仓库中的图片并保存为 session 的上传文件。
+	save := func(ref string) (string, error) {
+		if strings.Contains(ref, "://") {
+			return "", errors.New("remote images are not downloaded; use a path in the repository")
+		}
+		if doc.fetch == nil {
+			return "", errors.New("relative paths cannot be resolved in a pushed document; use an absolute URL")
+		}
+		data, err := doc.fetch(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+		// 不同目录下的图片可能同名，各放一个子目录，保存时仍使用原文件名。
+		saved++
+		file := filepath.Join(tmp, strconv.Itoa(saved), path.Base(ref))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			return "", err
+		}
+		up, uploadErr, err := s.saveDocumentImage(r, id, file, dir, limits)
+		if err != nil {
+			return "", err
+		}
+		if uploadErr != nil {
+			return "", errors.New(uploadErr.Error)
+		}
+		return up.Path, nil
+	}
+	markdown = ingestImage.ReplaceAllStringFunc(markdown, func(m string) string {
+		sub := ingestImage.FindStringSubmatch(m)
+		ref := sub[2]
+		if strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "data:") {
+			return m
+		}
+		p, err := save(ref)
+		if err != nil {
+			res.Skipped = append(res.Skipped, ref+": "+err.Error())
+			return ""
+		}
+		return "![" + sub[1] + "](" + p + ")"
+	})
+	markdown = strings.TrimSpace(markdown) + "\n"
+	cover := opts.CoverPath
+	coverRef := fm.Cover
+	if coverRef == "" {
+		coverRef = doc.cover
+	}
+	if coverRef != "" {
+		if p, err := save(coverRef); err == nil {
+			cover = p
+		} else {
+			res.Skipped = append(res.Skipped, "cover "+coverRef+": "+err.Error())
+		}
+	}
+	source := doc.source
+	if source == "" {
+		source = "ingest " + doc.path
+	}
+	sess.Draft = generator.Draft{Title: title, Digest: fm.Digest, Markdown: markdown, WordCount: generator.CountWords(markdown)}
+	s.store.persist(id)
+	s.recordAudit(publisher.AuditEntry{User: owner, Action: publisher.AuditSessionCreated, Target: id, Detail: source + ": " + title, RemoteAddr: s.remoteIP(r)})
+
+	if !publish {
+		if opts.Polish {
+			s.polishIngested(id, sess)
+		}
+		res.Status = "review"
+		return res
+	}
+	if cover == "" && (!opts.AICover || s.imageGen == nil) {
+		return fail(errors.New("cover could not be loaded; the draft is kept as a session for review"))
+	}
+	author := fm.Author
+	if author == "" {
+		author = opts.Author
+	}
+	req := publishReq{
+		SessionID: id,
+		CoverPath: cover,
+		Author:    author,
+		Title:     title,
+		Digest:    fm.Digest,
+		Markdown:  markdown,
+		AICover:   cover == "",
+	}
+	job, err := s.jobs.enqueue(id, owner, owner, func(ctx context.Context, job *publishJob) (publishResp, error) {
+		return s.runPublish(ctx, job, req, sess)
+	})
+	if err != nil {
+		return fail(err)
+	}
+	s.recordAudit(publisher.AuditEntry{User: owner, Action: publisher.AuditPublishRequested, Target: id, Detail: fmt.Sprintf("%s, job %s: %s", source, job.ID, title), RemoteAddr: s.remoteIP(r)})
+	res.Status, res.JobID = "queued", job.ID
+	return res
+}
+
+// polishIngested 在后台润色推送的稿件，完成后推送给 session 的订阅者；失败只打日志，稿件保持原样。
+func (s *Server) polishIngested(id string, sess *generator.Session) {
+	done, _, ok := s.store.begin(id, "polish")
+	if !ok {
+		return
+	}
+	s.tasks.Add(1)
+	go func() {
+		defer s.tasks.Done()
+		defer done()
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+		draft, err := sess.Polish(ctx, "")
+		cancel()
+		if err != nil {
+			log.Printf("[ingest] session=%s polish failed: %v", id, err)
+			return
+		}
+		s.store.persist(id)
+		s.events.publish(id, eventRevisionApplied, draft)
+	}()
+}
+
+// ingestSigned 校验推送密钥：X-Ingest-Token、GitLab 的 X-Gitlab-Token，或 GitHub/Gitea 对请求体的 HMAC-SHA256 签名。
+func ingestSigned(r *http.Request, body []byte, secret string) bool {
+	equal := func(a, b string) bool { return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1 }
+	if t := r.Header.Get("X-Ingest-Token"); t != "" {
+		return equal(t, secret)
+	}
+	if t := r.Header.Get("X-Gitlab-Token"); t != "" {
+		return equal(t, secret)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	sum := hex.EncodeToString(mac.Sum(nil))
+	if sig := r.Header.Get("X-Hub-Signature-256"); sig != "" {
+		return equal(sig, "sha256="+sum)
+	}
+	if sig := r.Header.Get("X-Gitea-Signature"); sig != "" {
+		return equal(sig, sum)
+	}
+	return false
+}
+
+// gitEvent 按请求头识别 Git 平台（github、gitea、gitlab）与事件名，不是 Git webhook 时返回空。
+// Gitea 同时发送 X-GitHub-Event，需先判断。
+func gitEvent(r *http.Request) (platform, event string) {
+	for _, h := range []struct{ platform, header string }{
+		{"gitea", "X-Gitea-Event"}, {"github", "X-GitHub-Event"}, {"gitlab", "X-Gitlab-Event"},
+	} {
+		if e := r.Header.Get(h.header); e != "" {
+			return h.platform, e
+		}
+	}
+	return "", ""
+}
+
+// gitPush 为 GitHub、Gitea 与 GitLab push 事件中用到的字段。
+type gitPush struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Repository struct {
+		FullName string `json:"full_name"`
+		HTMLURL  string `json:"html_url"`
+	} `json:"repository"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		WebURL            string `json:"web_url"`
+	} `json:"project"`
+	Commits []struct {
+		Added    []string `json:"added"`
+		Modified []string `json:"modified"`
+		Removed  []string `json:"removed"`
+	} `json:"commits"`
+}
+
+// gitPushDocs 读取 push 中新增或修改、且符合 ingest.paths 的 Markdown 文件（推送后的版本）。
+// 分支不符或分支被删除时返回空。
+func gitPushDocs(ctx context.Context, cfg publisher.IngestConfig, platform string, body []byte) ([]ingestDoc, error) {
+	var push gitPush
+	if err := json.Unmarshal(body, &push); err != nil {
+		return nil, fmt.Errorf("parse push event: %w", err)
+	}
+	if cfg.Branch != "" && push.Ref != "refs/heads/"+cfg.Branch {
+		return nil, nil
+	}
+	if strings.Trim(push.After, "0") == "" {
+		return nil, nil
+	}
+	repo, base := push.Repository.FullName, push.Repository.HTMLURL
+	if platform == "gitlab" {
+		repo, base = push.Project.PathWithNamespace, push.Project.WebURL
+	}
+	raw := cfg.RawURL
+	if raw == "" {
+		if base == "" {
+			return nil, errors.New("push event has no repository URL; set ingest.raw_url")
+		}
+		switch platform {
+		case "gitlab":
+			raw = base + "/-/raw/{sha}/{path}"
+		case "gitea":
+			raw = base + "/raw/commit/{sha}/{path}"
+		default:
+			raw = base + "/raw/{sha}/{path}"
+		}
+	}
+
+	changed := map[string]bool{}
+	var order []string
+	for _, c := range push.Commits {
+		for _, f := range append(c.Added, c.Modified...) {
+			if _, ok := changed[f]; !ok {
+				order = append(order, f)
+			}
+			changed[f] = true
+		}
+		for _, f := range c.Removed {
+			changed[f] = false
+		}
+	}
+	var files []string
+	for _, f := range order {
+		if changed[f] && ingestMatch(cfg.Paths, f) {
+			files = append(files, f)
+		}
+	}
+	if len(files) > maxIngestFiles {
+		return nil, fmt.Errorf("push changes %d markdown files; at most %d are ingested per push", len(files), maxIngestFiles)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	fetch := func(ctx context.Context, file string) ([]byte, error) {
+		segs := strings.Split(file, "/")
+		for i, seg := range segs {
+			segs[i] = url.PathEscape(seg)
+		}
+		u := strings.NewReplacer("{repo}", repo, "{sha}", push.After, "{path}", strings.Join(segs, "/")).Replace(raw)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+cfg.Token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("fetch %s: %w", file, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetch %s: HTTP %d", file, resp.StatusCode)
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxIngestBytes+1))
+		if err == nil && len(data) > maxIngestBytes {
+			err = fmt.Errorf("file exceeds %d MB", maxIngestBytes>>20)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fetch %s: %w", file, err)
+		}
+		return data, nil
+	}
+
+	docs := make([]ingestDoc, 0, len(files))
+	for _, f := range files {
+		data, err := fetch(ctx, f)
+		dir := path.Dir(f)
+		docs = append(docs, ingestDoc{path: f, markdown: string(data), err: err, fetch: func(ctx context.Context, ref string) ([]byte, error) {
+			if unescaped, err := url.PathUnescape(ref); err == nil {
+				ref = unescaped
+			}
+			p := path.Join(dir, ref)
+			if strings.HasPrefix(ref, "/") {
+				p = path.Clean(ref[1:])
+			}
+			if p == ".." || strings.HasPrefix(p, "../") {
+				return nil, errors.New("path is outside the repository")
+			}
+			return fetch(ctx, p)
+		}})
+	}
+	return docs, nil
+}
+
+// ingestMatch 判断文件是否为 Markdown 且符合 paths（path.Match 模式，以 / 结尾表示目录前缀）；paths 为空时全部符合。
+func ingestMatch(paths []string, file string) bool {
+	if ext := strings.ToLower(path.Ext(file)); ext != ".md" && ext != ".markdown" {
+		return false
+	}
+	if len(paths) == 0 {
+		return true
+	}
+	for _, p := range paths {
+		if strings.HasSuffix(p, "/") && strings.HasPrefix(file, p) {
+			return true
+		}
+		if ok, _ := path.Match(p, file); ok {
+			return true
+		}
+	}
+	return false
+}
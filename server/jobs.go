@@ -0,0 +1,241 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// 发布任务状态。
+const (
+	jobQueued  = "queued"
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+const (
+	// publishQueueSize 为排队中的发布任务上限，超出时拒绝新任务。
+	publishQueueSize = 32
+	// jobRetention 为已结束任务的保留时间。
+	jobRetention = time.Hour
+)
+
+// publishStageText 为发布各阶段的说明，对应 publisher.PublishParams.Progress 的 stage。
+var publishStageText = map[string]string{
+	"token":  "获取 access_token",
+	"images": "正文图片上传完成",
+	"html":   "转换排版",
+	"cover":  "上传封面",
+	"draft":  "创建草稿",
+	"done":   "发布完成",
+}
+
+// publishJob 为一个异步发布任务及其进度。
+type publishJob struct {
+	ID        string `json:"job_id"`
+	SessionID string `json:"session_id"`
+	Status    string `json:"status"`
+	// Stage 为当前阶段（ai_cover/token/images/html/cover/draft/done），Progress 为可读说明，如“上传图片 3/7”。
+	Stage       string       `json:"stage,omitempty"`
+	Progress    string       `json:"progress,omitempty"`
+	ImagesDone  int          `json:"images_done,omitempty"`
+	ImagesTotal int          `json:"images_total,omitempty"`
+	Result      *publishResp `json:"result,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	// ErrCode 为失败原因中微信接口的 errcode。
+	ErrCode   int       `json:"errcode,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// owner 为稿件作者，发布记录按此隔离；by 为提交任务的用户（审核流程中的发布人），二者均可查询任务。
+	owner string
+	by    string
+	// title 与 digest 为发布的标题与摘要，用于结束时的通知。
+	title  string
+	digest string
+	run    func(ctx context.Context, job *publishJob) (publishResp, error)
+}
+
+// jobQueue 按提交顺序由单个 worker 依次执行发布任务；Publisher 共享 access_token，不宜并发发布。
+// finished 在每个任务结束（成功或失败）后调用，可空。
+type jobQueue struct {
+	mu       sync.Mutex
+	jobs     map[string]*publishJob
+	queue    chan *publishJob
+	finished func(job publishJob)
+	// closed 为 true 表示正在关闭，不再接受新任务；ctx 在关闭超时后取消，中止进行中的发布。
+	closed bool
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newJobQueue(finished func(job publishJob)) *jobQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &jobQueue{
+		jobs:     make(map[string]*publishJob),
+		queue:    make(chan *publishJob, publishQueueSize),
+		finished: finished,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go q.work()
+	return q
+}
+
+// enqueue 提交任务并返回其快照；队列已满时返回错误。
+func (q *jobQueue) enqueue(sessionID, owner, by string, run func(ctx context.Context, job *publishJob) (publishResp, error)) (publishJob, error) {
+	now := time.Now()
+	job := &publishJob{
+		ID:        strconv.FormatInt(now.UnixNano(), 36),
+		SessionID: sessionID,
+		owner:     owner,
+		by:        by,
+		Status:    jobQueued,
+		Progress:  "排队中",
+		CreatedAt: now,
+		UpdatedAt: now,
+		run:       run,
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return publishJob{}, errors.New("server is shutting down; try again later")
+	}
+	q.purgeLocked()
+	select {
+	case q.queue <- job:
+	default:
+		return publishJob{}, errors.New("publish queue is full; try again later")
+	}
+	q.jobs[job.ID] = job
+	return *job, nil
+}
+
+func (q *jobQueue) get(id string) (publishJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return publishJob{}, false
+	}
+	return *job, true
+}
+
+func (q *jobQueue) update(job *publishJob, stage, progress string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job.Stage, job.UpdatedAt = stage, time.Now()
+	if progress != "" {
+		job.Progress = progress
+	}
+}
+
+// describe 记录任务发布的标题与摘要。
+func (q *jobQueue) describe(job *publishJob, title, digest string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job.title, job.digest = title, digest
+}
+
+func (q *jobQueue) updateImages(job *publishJob, done, total int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job.Stage, job.ImagesDone, job.ImagesTotal = "images", done, total
+	job.Progress = fmt.Sprintf("上传图片 %d/%d", done, total)
+	job.UpdatedAt = time.Now()
+}
+
+func (q *jobQueue) work() {
+	defer close(q.done)
+	for job := range q.queue {
+		// 关闭超时后不再开始排队中的任务；定时发布条目在下次启动时标记为中断。
+		if q.ctx.Err() != nil {
+			q.mu.Lock()
+			job.Status, job.Error, job.Progress, job.UpdatedAt = jobFailed, "server shut down before the job started", "发布失败", time.Now()
+			q.mu.Unlock()
+			log.Printf("[publish] job=%s session=%s dropped on shutdown", job.ID, job.SessionID)
+			continue
+		}
+		q.mu.Lock()
+		job.Status, job.Progress, job.UpdatedAt = jobRunning, "开始发布", time.Now()
+		q.mu.Unlock()
+
+		resp, err := job.run(q.ctx, job)
+
+		q.mu.Lock()
+		job.UpdatedAt = time.Now()
+		if err != nil {
+			job.Status, job.Error, job.Progress = jobFailed, err.Error(), "发布失败"
+			job.ErrCode = publisher.WeChatErrorCode(err)
+			log.Printf("[publish] job=%s session=%s failed: %v", job.ID, job.SessionID, err)
+		} else {
+			job.Status, job.Stage, job.Progress, job.Result = jobDone, "done", publishStageText["done"], &resp
+		}
+		snapshot := *job
+		q.mu.Unlock()
+		if q.finished != nil {
+			go q.finished(snapshot)
+		}
+	}
+}
+
+// drain 停止接受新任务并等待已提交的任务执行完；ctx 结束时取消进行中的发布并放弃其余任务。
+func (q *jobQueue) drain(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.queue)
+	}
+	pending := len(q.queue)
+	q.mu.Unlock()
+	if pending > 0 {
+		log.Printf("[publish] waiting for %d queued jobs", pending)
+	}
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+	}
+	q.cancel()
+	select {
+	case <-q.done:
+	case <-time.After(5 * time.Second):
+	}
+	return ctx.Err()
+}
+
+// purgeLocked 清理结束超过 jobRetention 的任务。
+func (q *jobQueue) purgeLocked() {
+	threshold := time.Now().Add(-jobRetention)
+	for id, job := range q.jobs {
+		if (job.Status == jobDone || job.Status == jobFailed) && job.UpdatedAt.Before(threshold) {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+// handleJob 返回发布任务的状态、进度与结果。
+// Path: GET /api/jobs/{id}
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	job, ok := s.jobs.get(strings.TrimPrefix(r.URL.Path, "/api/jobs/"))
+	if !ok || !(s.canAccess(r, job.owner) || s.canAccess(r, job.by)) {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, job)
+}
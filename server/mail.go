@@ -0,0 +1,268 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+const (
+	// mailBatch 为每次检查最多处理的邮件数，其余留到下一次。
+	mailBatch = 10
+	// mailJobWait 为发布模式下等待发布任务结束、以便回信 media_id 的最长时间。
+	mailJobWait = 15 * time.Minute
+)
+
+// mailRunner 记录收件箱检查的状态：不符合条件而保持未读的邮件不再重复读取。
+type mailRunner struct {
+	interval time.Duration
+
+	mu          sync.Mutex
+	running     bool
+	uidValidity uint32
+	ignored     map[uint32]bool
+}
+
+func newMailRunner(cfg *publisher.MailConfig) (*mailRunner, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	minutes := cfg.PollMinutes
+	if minutes <= 0 {
+		minutes = publisher.DefaultMailPollMinutes
+	}
+	return &mailRunner{interval: time.Duration(minutes) * time.Minute, ignored: map[uint32]bool{}}, nil
+}
+
+// runMail 启动时与之后每隔 poll_minutes 检查一次收件箱，直到服务关闭。
+func (s *Server) runMail() {
+	s.triggerMail()
+	ticker := time.NewTicker(s.mail.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.triggerMail()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// triggerMail 在后台检查收件箱；上一次尚未结束时跳过。
+func (s *Server) triggerMail() {
+	s.mail.mu.Lock()
+	if s.mail.running {
+		s.mail.mu.Unlock()
+		return
+	}
+	s.mail.running = true
+	s.mail.mu.Unlock()
+	s.tasks.Add(1)
+	go func() {
+		defer s.tasks.Done()
+		defer func() {
+			s.mail.mu.Lock()
+			s.mail.running = false
+			s.mail.mu.Unlock()
+		}()
+		if err := s.checkMail(); err != nil {
+			log.Printf("[mail] %v", err)
+		}
+	}()
+}
+
+// mailItem 为一封待处理的邮件，err 为读取或解析时的错误。
+type mailItem struct {
+	msg publisher.MailMessage
+	err error
+}
+
+// checkMail 读取收件箱中的投稿，断开连接后逐封处理并回信。
+func (s *Server) checkMail() error {
+	cfg := s.config().Mail
+	if cfg == nil {
+		return nil
+	}
+	msgs, err := s.fetchMail(*cfg)
+	for _, item := range msgs {
+		select {
+		case <-s.stop:
+			return err
+		default:
+		}
+		msg, reply := item.msg, "邮件无法解析："+fmt.Sprint(item.err)
+		if item.err == nil {
+			reply = s.handleMail(*cfg, msg)
+		}
+		if cfg.SMTP == "" {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		if err := publisher.SendMailReply(ctx, *cfg, msg, reply); err != nil {
+			log.Printf("[mail] reply to %s: %v", msg.From, err)
+		}
+		cancel()
+	}
+	return err
+}
+
+// fetchMail 读取允许的发件人发来的未读邮件并标记为已读；出错时仍返回此前已标记的邮件。
+// 邮件在处理前标记已读，处理失败时不会在下一次检查中重复发布。
+func (s *Server) fetchMail(cfg publisher.MailConfig) ([]mailItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	c, err := publisher.DialIMAP(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	uids, err := c.Unseen()
+	if err != nil {
+		return nil, err
+	}
+	s.mail.mu.Lock()
+	if c.UIDValidity != s.mail.uidValidity {
+		s.mail.uidValidity, s.mail.ignored = c.UIDValidity, map[uint32]bool{}
+	}
+	s.mail.mu.Unlock()
+
+	var msgs []mailItem
+	for _, uid := range uids {
+		if len(msgs) >= mailBatch {
+			break
+		}
+		s.mail.mu.Lock()
+		ignored := s.mail.ignored[uid]
+		s.mail.mu.Unlock()
+		if ignored {
+			continue
+		}
+		from, subject, err := c.FetchHeader(uid)
+		if err != nil || !cfg.Allowed(from) || !strings.HasPrefix(subject, cfg.SubjectPrefix) {
+			if err != nil {
+				log.Printf("[mail] message %d: %v", uid, err)
+			}
+			s.mail.mu.Lock()
+			s.mail.ignored[uid] = true
+			s.mail.mu.Unlock()
+			continue
+		}
+		msg, err := c.Fetch(uid)
+		if markErr := c.MarkSeen(uid); markErr != nil {
+			return msgs, markErr
+		}
+		if err != nil {
+			// 无法解析的邮件同样标记为已读，回信告知原因。
+			log.Printf("[mail] message %d from %s: %v", uid, from, err)
+			msg = publisher.MailMessage{MessageID: msg.MessageID, From: from, Subject: subject}
+		}
+		msgs = append(msgs, mailItem{msg: msg, err: err})
+	}
+	return msgs, nil
+}
+
+// handleMail 把一封投稿邮件保存为 session，发布模式下提交发布并等待结果，返回回信正文。
+func (s *Server) handleMail(cfg publisher.MailConfig, msg publisher.MailMessage) string {
+	title := strings.TrimSpace(strings.TrimPrefix(msg.Subject, cfg.SubjectPrefix))
+	log.Printf("[mail] from %s: %q", msg.From, title)
+	switch {
+	case msg.HTMLOnly:
+		return "邮件只有 HTML 正文，无法转换。请以纯文本格式发送 Markdown，或把文章作为 .md 附件发送。"
+	case strings.TrimSpace(msg.Markdown) == "":
+		return "没有找到文章内容：请在正文中写 Markdown，或把文章作为 .md 附件发送。"
+	}
+	doc := ingestDoc{
+		path:     msg.MarkdownName,
+		markdown: msg.Markdown,
+		title:    title,
+		source:   "mail from " + msg.From,
+		// 正文中的图片按文件名引用邮件附件，如 ![](photo.jpg)。
+		fetch: func(ctx context.Context, ref string) ([]byte, error) {
+			name, err := url.PathUnescape(ref)
+			if err != nil {
+				name = ref
+			}
+			if data, ok := msg.Attachments[path.Base(name)]; ok {
+				return data, nil
+			}
+			return nil, errors.New("no attachment with this file name")
+		},
+	}
+	for name := range msg.Attachments {
+		if strings.EqualFold(strings.TrimSuffix(name, path.Ext(name)), "cover") {
+			doc.cover = name
+			break
+		}
+	}
+	mode := cfg.Mode
+	if mode == "" {
+		mode = recurringPublish
+	}
+	opts := ingestReq{Mode: mode, CoverPath: cfg.CoverPath, AICover: cfg.AICover, Author: cfg.Author}
+	r, err := http.NewRequest(http.MethodPost, "/api/mail", nil)
+	if err != nil {
+		return "投稿处理失败：" + err.Error()
+	}
+	r.RemoteAddr = "mail:0"
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	res := s.ingestDocument(ctx, r, doc, opts, cfg.Owner)
+
+	var sb strings.Builder
+	switch res.Status {
+	case "failed":
+		log.Printf("[mail] from %s failed: %s", msg.From, res.Error)
+		fmt.Fprintf(&sb, "投稿处理失败：%s\n", res.Error)
+		if res.SessionID != "" {
+			fmt.Fprintf(&sb, "稿件已保存为 session %s，可在网页中修改后发布。\n", res.SessionID)
+		}
+	case "review":
+		fmt.Fprintf(&sb, "稿件《%s》已保存，等待审核。\nsession: %s\n", res.Title, res.SessionID)
+	default:
+		job, ok := s.waitJob(res.JobID)
+		switch {
+		case ok && job.Status == jobDone && job.Result != nil:
+			fmt.Fprintf(&sb, "《%s》已发布到公众号草稿箱。\nmedia_id: %s\nsession: %s\n", res.Title, job.Result.MediaID, res.SessionID)
+		case ok && job.Status == jobFailed:
+			fmt.Fprintf(&sb, "《%s》发布失败：%s\n稿件已保存为 session %s，可在网页中修改后重新发布。\n", res.Title, job.Error, res.SessionID)
+		default:
+			fmt.Fprintf(&sb, "《%s》已提交发布，结果请在网页中查看。\nsession: %s\njob: %s\n", res.Title, res.SessionID, res.JobID)
+		}
+	}
+	if len(res.Skipped) > 0 {
+		sb.WriteString("\n以下图片未能使用，已从正文中去掉（正文中按附件文件名引用图片）：\n")
+		for _, item := range res.Skipped {
+			sb.WriteString("- " + item + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// waitJob 等待发布任务结束；服务关闭或超过 mailJobWait 时返回当前状态。
+func (s *Server) waitJob(id string) (publishJob, bool) {
+	deadline := time.Now().Add(mailJobWait)
+	for {
+		job, ok := s.jobs.get(id)
+		if !ok || job.Status == jobDone || job.Status == jobFailed || time.Now().After(deadline) {
+			return job, ok
+		}
+		select {
+		case <-time.After(time.Second):
+		case <-s.stop:
+			return job, ok
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// massSendReq 为群发申请：media_id 为草稿的 media_id；只给 session_id 时使用该 session 最近一次发布的草稿。
+// tag 为标签名或 ID，与 tag_id 都为空时发给全部粉丝。
+type massSendReq struct {
+	SessionID     string `json:"session_id,omitempty"`
+	MediaID       string `json:"media_id,omitempty"`
+	TagID         int    `json:"tag_id,omitempty"`
+	Tag           string `json:"tag,omitempty"`
+	IgnoreReprint bool   `json:"send_ignore_reprint,omitempty"`
+}
+
+// massPreviewReq 为群发前发送到手机的预览。
+type massPreviewReq struct {
+	MediaID string `json:"media_id"`
+	To      string `json:"to,omitempty"`
+	OpenID  string `json:"openid,omitempty"`
+}
+
+// handleMassSends 列出群发，或提交群发申请。申请需要启用登录与发布人角色，由另一人批准后才会发送。
+// Path: GET /api/masssend?status=pending|sending|sent|failed|canceled, POST /api/masssend
+func (s *Server) handleMassSends(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if currentUser(r) != "" && !s.hasRole(r, roleReviewer) && !s.hasRole(r, rolePublisher) {
+			http.Error(w, "reviewer or publisher role required", http.StatusForbidden)
+			return
+		}
+		items, err := s.massSends.List(r.URL.Query().Get("status"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]any{"mass_sends": items})
+	case http.MethodPost:
+		s.requestMassSend(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) requestMassSend(w http.ResponseWriter, r *http.Request) {
+	if !s.requireMassSendAuth(w) {
+		return
+	}
+	if !s.hasRole(r, rolePublisher) {
+		http.Error(w, "publisher role required", http.StatusForbidden)
+		return
+	}
+	var req massSendReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	item := publisher.MassSendRequest{SessionID: req.SessionID, MediaID: req.MediaID, TagID: req.TagID, IgnoreReprint: req.IgnoreReprint, RequestedBy: currentUser(r)}
+	if item.MediaID == "" && item.SessionID != "" {
+		records, err := s.publishes.List(publisher.PublishFilter{SessionID: item.SessionID, Status: publisher.PublishSucceeded, Limit: 1})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(records) == 0 {
+			http.Error(w, "session has not been published to the draft box", http.StatusConflict)
+			return
+		}
+		item.MediaID, item.Title = records[0].MediaID, records[0].Title
+	}
+	if item.MediaID == "" {
+		http.Error(w, "media_id or session_id required", http.StatusBadRequest)
+		return
+	}
+	// 读取草稿以确认 media_id 有效，并记录标题供批准人核对。
+	p, err := s.ensurePublisher()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	articles, err := p.GetDraft(ctx, item.MediaID)
+	if err != nil {
+		http.Error(w, "media_id must be a draft in the draft box: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if len(articles) > 0 {
+		titles := make([]string, 0, len(articles))
+		for _, a := range articles {
+			titles = append(titles, a.Title)
+		}
+		item.Title = strings.Join(titles, " / ")
+	}
+	if ref := req.Tag; ref != "" || req.TagID != 0 {
+		if ref == "" {
+			ref = strconv.Itoa(req.TagID)
+		}
+		tag, err := p.ResolveTag(ctx, ref)
+		if err != nil {
+			writeTagError(w, err)
+			return
+		}
+		item.TagID, item.TagName = tag.ID, tag.Name
+	}
+	item, err = s.massSends.Add(item)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("[masssend] id=%s requested media_id=%s by=%s", item.ID, item.MediaID, item.RequestedBy)
+	s.audit(r, publisher.AuditMassSendRequested, item.SessionID, fmt.Sprintf("mass send %s of %s: %s", item.ID, item.MediaID, item.Title))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, item)
+}
+
+// handleMassSendByID 查看、批准或取消群发；发送预览。批准后立即调用群发接口。
+// 申请与批准需要启用登录；批准需要发布人角色，且批准人不能是申请人。
+// Path: GET /api/masssend/{id}, POST /api/masssend/{id}/approve|cancel, POST /api/masssend/preview
+func (s *Server) handleMassSendByID(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/masssend/"), "/")
+	if id == "preview" && action == "" {
+		s.previewMassSend(w, r)
+		return
+	}
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		if currentUser(r) != "" && !s.hasRole(r, roleReviewer) && !s.hasRole(r, rolePublisher) {
+			http.Error(w, "reviewer or publisher role required", http.StatusForbidden)
+			return
+		}
+		item, err := s.massSends.Get(id)
+		if err != nil {
+			writeMassSendError(w, err)
+			return
+		}
+		writeJSON(w, s.refreshMassStatus(r.Context(), item))
+	case action == "approve" && r.Method == http.MethodPost:
+		s.approveMassSend(w, r, id)
+	case action == "cancel" && r.Method == http.MethodPost:
+		if !s.hasRole(r, rolePublisher) {
+			http.Error(w, "publisher role required", http.StatusForbidden)
+			return
+		}
+		item, err := s.massSends.Update(id, func(it *publisher.MassSendRequest) error {
+			if it.Status != publisher.MassSendPending {
+				return fmt.Errorf("mass send is %s; only pending requests can be canceled", it.Status)
+			}
+			it.Status = publisher.MassSendCanceled
+			return nil
+		})
+		if err != nil {
+			writeMassSendError(w, err)
+			return
+		}
+		s.audit(r, publisher.AuditPublishCanceled, item.SessionID, "mass send "+item.ID)
+		writeJSON(w, item)
+	case action == "" || action == "approve" || action == "cancel":
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) approveMassSend(w http.ResponseWriter, r *http.Request, id string) {
+	if !s.requireMassSendAuth(w) {
+		return
+	}
+	if !s.hasRole(r, rolePublisher) {
+		http.Error(w, "publisher role required", http.StatusForbidden)
+		return
+	}
+	p, err := s.ensurePublisher()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	user := currentUser(r)
+	// 先把状态改为 sending，同一申请只会发送一次。
+	item, err := s.massSends.Update(id, func(it *publisher.MassSendRequest) error {
+		if it.Status != publisher.MassSendPending {
+			return fmt.Errorf("mass send is %s; only pending requests can be approved", it.Status)
+		}
+		if user == "" || user == it.RequestedBy {
+			return errMassSendSelfApproval
+		}
+		it.Status, it.ApprovedBy = publisher.MassSendSending, user
+		return nil
+	})
+	if err != nil {
+		writeMassSendError(w, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	res, sendErr := p.MassSend(ctx, publisher.MassSendParams{MediaID: item.MediaID, TagID: item.TagID, IgnoreReprint: item.IgnoreReprint, ClientMsgID: "masssend-" + item.ID})
+	item, err = s.massSends.Update(id, func(it *publisher.MassSendRequest) error {
+		if sendErr != nil {
+			it.Status, it.Error = publisher.MassSendFailed, sendErr.Error()
+			return nil
+		}
+		it.Status, it.MsgID, it.MsgDataID = publisher.MassSendSent, res.MsgID, res.MsgDataID
+		return nil
+	})
+	if err != nil {
+		log.Printf("[masssend] id=%s save result failed: %v", id, err)
+	}
+	s.audit(r, publisher.AuditMassSendApproved, item.SessionID, fmt.Sprintf("mass send %s of %s: %s", id, item.MediaID, item.Status))
+	if sendErr != nil {
+		log.Printf("[masssend] id=%s failed: %v", id, sendErr)
+		http.Error(w, sendErr.Error(), http.StatusBadGateway)
+		return
+	}
+	log.Printf("[masssend] id=%s sent msg_id=%d approved_by=%s", id, res.MsgID, user)
+	writeJSON(w, item)
+}
+
+// refreshMassStatus 查询已发送群发的微信发送状态并保存；查询失败时返回原条目。
+func (s *Server) refreshMassStatus(ctx context.Context, item publisher.MassSendRequest) publisher.MassSendRequest {
+	if item.Status != publisher.MassSendSent || item.MsgID == 0 || item.SendStatus == "SEND_SUCCESS" {
+		return item
+	}
+	p, err := s.ensurePublisher()
+	if err != nil {
+		return item
+	}
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	status, err := p.MassStatus(ctx, item.MsgID)
+	if err != nil {
+		log.Printf("[masssend] id=%s query status failed: %v", item.ID, err)
+		return item
+	}
+	updated, err := s.massSends.Update(item.ID, func(it *publisher.MassSendRequest) error {
+		it.SendStatus = status
+		return nil
+	})
+	if err != nil {
+		return item
+	}
+	return updated
+}
+
+// previewMassSend 把草稿发送到指定微信号预览，群发前核对排版。
+func (s *Server) previewMassSend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if currentUser(r) != "" && !s.hasRole(r, roleReviewer) && !s.hasRole(r, rolePublisher) {
+		http.Error(w, "reviewer or publisher role required", http.StatusForbidden)
+		return
+	}
+	var req massPreviewReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.MediaID == "" || (req.To == "" && req.OpenID == "") {
+		http.Error(w, "media_id and to or openid required", http.StatusBadRequest)
+		return
+	}
+	p, err := s.ensurePublisher()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	if err := p.SendPreview(ctx, req.MediaID, req.To, req.OpenID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, map[string]any{"media_id": req.MediaID, "sent": true})
+}
+
+// requireMassSendAuth 在未启用登录时拒绝群发申请与批准：无法识别调用方时两人审批不起作用，
+// 且未启用登录时默认允许任意来源跨域调用。
+func (s *Server) requireMassSendAuth(w http.ResponseWriter) bool {
+	if s.auth == nil {
+		http.Error(w, "mass send requires auth to be enabled so that another publisher can approve it", http.StatusNotImplemented)
+		return false
+	}
+	return true
+}
+
+// errMassSendSelfApproval 表示申请人试图批准自己的群发。
+var errMassSendSelfApproval = errors.New("mass send must be approved by another publisher")
+
+func writeMassSendError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, publisher.ErrMassSendNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, errMassSendSelfApproval):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusConflict)
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// notifyJob 在发布任务结束后把结果卡片推送到配置的群机器人，发布成功时另发模板消息；失败只打日志。
+func (s *Server) notifyJob(job publishJob) {
+	cfg := s.config()
+	if len(cfg.Notify) == 0 && cfg.TemplateNotify == nil {
+		return
+	}
+	n := publisher.PublishNotice{
+		Title:     job.title,
+		Digest:    job.digest,
+		SessionID: job.SessionID,
+		User:      job.by,
+		Account:   cfg.AccountID(),
+		Error:     job.Error,
+		Time:      job.UpdatedAt,
+	}
+	if n.User == "" {
+		n.User = job.owner
+	}
+	if job.Result != nil {
+		n.MediaID = job.Result.MediaID
+		if n.Title == "" {
+			n.Title = job.Result.Title
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := publisher.Notify(ctx, nil, cfg.Notify, n); err != nil {
+		log.Printf("[notify] job=%s failed: %v", job.ID, err)
+	}
+	if cfg.TemplateNotify == nil || n.Error != "" {
+		return
+	}
+	p, err := s.ensurePublisher()
+	if err != nil {
+		log.Printf("[notify] job=%s template message: %v", job.ID, err)
+		return
+	}
+	if err := p.SendTemplateNotice(ctx, n); err != nil {
+		log.Printf("[notify] job=%s template message failed: %v", job.ID, err)
+	}
+}
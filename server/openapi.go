@@ -0,0 +1,476 @@
+package server
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+	"auto_wechat_article_publisher/publisher"
+)
+
+// swaggerUIVersion 为 /api/docs 加载的 swagger-ui-dist 版本。
+const swaggerUIVersion = "5.17.14"
+
+// apiOp 描述一个接口，用于生成 OpenAPI 文档。body/resp 为请求与响应示例值（按其类型生成 schema），
+// 或直接给出的 schema（map[string]any）；resp 为 nil 表示无响应体。
+type apiOp struct {
+	method, path, tag, summary string
+	query                      []apiParam
+	body                       any
+	form                       map[string]any
+	status                     int
+	resp                       any
+	// public 表示启用登录时也不需要令牌。
+	public bool
+	// contentType 覆盖响应类型，如 text/event-stream。
+	contentType string
+}
+
+type apiParam struct {
+	name, typ, desc string
+}
+
+func obj(props map[string]any) map[string]any {
+	return map[string]any{"type": "object", "properties": props}
+}
+
+func arr(item any) map[string]any {
+	return map[string]any{"type": "array", "items": item}
+}
+
+var (
+	schemaString  = map[string]any{"type": "string"}
+	schemaInt     = map[string]any{"type": "integer"}
+	schemaBool    = map[string]any{"type": "boolean"}
+	schemaBinary  = map[string]any{"type": "string", "format": "binary"}
+	schemaAnyJSON = map[string]any{}
+)
+
+// commentActionResp 为留言管理操作的响应。
+var commentActionResp = obj(map[string]any{"article": schemaString, "action": schemaString, "ok": schemaBool})
+
+// tagActionResp 为用户标签操作的响应。
+var tagActionResp = obj(map[string]any{"id": schemaInt, "action": schemaString, "ok": schemaBool})
+
+// apiOperations 为全部接口。新增或修改接口时需同步这里，请求与响应 schema 由对应 Go 类型生成。
+func apiOperations() []apiOp {
+	sess := sessionResp{}
+	wf := obj(map[string]any{
+		"session_id": schemaString, "status": schemaString, "stale": schemaBool,
+		"events": arr(workflowEvent{}),
+	})
+	listQuery := []apiParam{
+		{"q", "string", "按主题或标题搜索"},
+		{"status", "string", "按审核状态过滤：draft/submitted/approved/published"},
+		{"page", "integer", "页码，从 1 开始"},
+		{"page_size", "integer", "每页条数，默认 20，最大 100"},
+		{"archived", "boolean", "为 true 时包含已归档的 session"},
+	}
+	ops := []apiOp{
+		{method: "GET", path: "/healthz", tag: "health", summary: "进程存活检查", public: true, resp: obj(map[string]any{"status": schemaString, "uptime_seconds": schemaInt})},
+		{method: "GET", path: "/readyz", tag: "health", summary: "依赖就绪检查，失败时返回 503", public: true, resp: obj(map[string]any{"status": schemaString, "checks": map[string]any{"type": "object", "additionalProperties": checkResult{}}})},
+
+		{method: "POST", path: "/api/login", tag: "auth", summary: "登录，返回令牌并设置 cookie", public: true, body: loginReq{}, resp: meResp{}},
+		{method: "POST", path: "/api/logout", tag: "auth", summary: "清除登录 cookie", public: true, status: http.StatusNoContent},
+		{method: "GET", path: "/api/me", tag: "auth", summary: "当前用户与当天模型用量", public: true, resp: meResp{}},
+
+		{method: "GET", path: "/api/sessions", tag: "sessions", summary: "分页列出 session", query: listQuery, resp: obj(map[string]any{
+			"sessions": arr(sessionSummary{}), "total": schemaInt, "page": schemaInt, "page_size": schemaInt,
+		})},
+		{method: "POST", path: "/api/sessions", tag: "sessions", summary: "新建 session 并生成首稿（stream=true 时只创建）", body: sessionCreateReq{}, resp: sess},
+		{method: "GET", path: "/api/sessions/{id}", tag: "sessions", summary: "获取 session 的当前稿件与历史", resp: sess},
+		{method: "POST", path: "/api/sessions/{id}", tag: "sessions", summary: "按修改意见修订稿件", body: reviseReq{}, resp: sess},
+		{method: "DELETE", path: "/api/sessions/{id}", tag: "sessions", summary: "删除 session", status: http.StatusNoContent},
+		{method: "POST", path: "/api/sessions/{id}/stream", tag: "sessions", summary: "在后台流式生成首稿或修订稿，通过 GET 同一路径读取输出", body: streamReq{}, status: http.StatusAccepted, resp: obj(map[string]any{"session_id": schemaString, "status": schemaString})},
+		{method: "GET", path: "/api/sessions/{id}/stream", tag: "sessions", summary: "以 SSE 读取进行中或刚结束的流式生成（事件 delta/done/error），不会触发生成", contentType: "text/event-stream", resp: schemaString},
+		{method: "POST", path: "/api/sessions/{id}/expand", tag: "sessions", summary: "按（编辑后的）大纲展开全文", body: expandReq{}, resp: sess},
+		{method: "GET", path: "/api/sessions/{id}/sections", tag: "sessions", summary: "列出稿件小节", resp: arr(generator.Section{})},
+		{method: "POST", path: "/api/sessions/{id}/sections", tag: "sessions", summary: "重写单个小节", body: sectionReq{}, resp: sess},
+		{method: "GET", path: "/api/sessions/{id}/variants", tag: "sessions", summary: "列出候选首稿", resp: arr(generator.Draft{})},
+		{method: "POST", path: "/api/sessions/{id}/variants", tag: "sessions", summary: "选定一份候选首稿", body: pickVariantReq{}, resp: sess},
+		{method: "POST", path: "/api/sessions/{id}/titles", tag: "sessions", summary: "生成备选标题", body: titlesReq{}, resp: obj(map[string]any{"titles": arr(generator.TitleCandidate{})})},
+		{method: "POST", path: "/api/sessions/{id}/titles/apply", tag: "sessions", summary: "采用标题", body: applyTitleReq{}, resp: sess},
+		{method: "POST", path: "/api/sessions/{id}/polish", tag: "sessions", summary: "润色稿件", body: polishReq{}, resp: sess},
+		{method: "GET", path: "/api/sessions/{id}/factcheck", tag: "sessions", summary: "获取上次事实核查结果（无报告时 404，稿件已变化时 409，不会触发核查）", resp: generator.FactCheckReport{}},
+		{method: "POST", path: "/api/sessions/{id}/factcheck", tag: "sessions", summary: "执行事实核查", resp: generator.FactCheckReport{}},
+		{method: "POST", path: "/api/sessions/{id}/factcheck/apply", tag: "sessions", summary: "把核查引用写入稿件", resp: sess},
+		{method: "GET", path: "/api/sessions/{id}/research", tag: "sessions", summary: "获取写作前检索的资料", resp: obj(map[string]any{"results": arr(generator.SearchResult{})})},
+		{method: "POST", path: "/api/sessions/{id}/research", tag: "sessions", summary: "联网检索资料", body: researchReq{}, resp: obj(map[string]any{"results": arr(generator.SearchResult{})})},
+		{method: "GET", path: "/api/sessions/{id}/originality", tag: "sessions", summary: "改写稿与原文的相似度报告", resp: generator.OriginalityReport{}},
+		{method: "GET", path: "/api/sessions/{id}/sensitive", tag: "sessions", summary: "敏感词检查", resp: obj(map[string]any{"hits": arr(generator.SensitiveHit{})})},
+		{method: "POST", path: "/api/sessions/{id}/sensitive", tag: "sessions", summary: "替换敏感词", resp: sess},
+		{method: "GET", path: "/api/sessions/{id}/quotes", tag: "sessions", summary: "获取已提取的金句", resp: obj(map[string]any{"quotes": arr(generator.GoldenQuote{})})},
+		{method: "POST", path: "/api/sessions/{id}/quotes", tag: "sessions", summary: "提取金句", body: quotesReq{}, resp: obj(map[string]any{"quotes": arr(generator.GoldenQuote{})})},
+		{method: "POST", path: "/api/sessions/{id}/quotes/apply", tag: "sessions", summary: "把金句写入摘要（返回 session）或生成封面（返回上传文件）", body: applyQuoteReq{}, resp: sess},
+		{method: "GET", path: "/api/sessions/{id}/versions", tag: "sessions", summary: "版本列表（每轮修订一个版本）", resp: versionsResp{}},
+		{method: "GET", path: "/api/sessions/{id}/diff", tag: "sessions", summary: "比较两个版本", query: []apiParam{
+			{"from", "integer", "起始版本，默认为 to 的上一个版本"},
+			{"to", "integer", "目标版本，省略或 0 表示当前稿件"},
+			{"format", "string", "unified（默认）或 html"},
+			{"context", "integer", "unified 格式的上下文行数，默认 3"},
+		}, resp: diffResp{}},
+		{method: "POST", path: "/api/sessions/{id}/rollback", tag: "sessions", summary: "回滚到指定版本", query: []apiParam{{"to", "integer", "要恢复的版本"}}, resp: sess},
+		{method: "POST", path: "/api/sessions/{id}/cover", tag: "sessions", summary: "生成封面", body: coverGenReq{}, resp: uploadResp{}},
+		{method: "POST", path: "/api/sessions/{id}/images/place", tag: "sessions", summary: "把上传的配图插入正文", resp: sess},
+		{method: "POST", path: "/api/heartbeat/{id}", tag: "sessions", summary: "续期 session", status: http.StatusNoContent},
+
+		{method: "GET", path: "/api/sessions/{id}/workflow", tag: "workflow", summary: "审核状态与记录", resp: wf},
+		{method: "POST", path: "/api/sessions/{id}/submit", tag: "workflow", summary: "提交审核", body: workflowReq{}, resp: wf},
+		{method: "POST", path: "/api/sessions/{id}/approve", tag: "workflow", summary: "审核通过", body: workflowReq{}, resp: wf},
+		{method: "POST", path: "/api/sessions/{id}/reject", tag: "workflow", summary: "驳回", body: workflowReq{}, resp: wf},
+
+		{method: "GET", path: "/api/sessions/{id}/comments", tag: "comments", summary: "批注列表", query: []apiParam{{"status", "string", "open 或 resolved，省略时返回全部"}}, resp: obj(map[string]any{"comments": arr(draftComment{})})},
+		{method: "POST", path: "/api/sessions/{id}/comments", tag: "comments", summary: "添加批注（锚定小节标题或段落范围）", body: commentReq{}, status: http.StatusCreated, resp: draftComment{}},
+		{method: "POST", path: "/api/sessions/{id}/comments/{cid}/resolve", tag: "comments", summary: "标记批注已解决", resp: draftComment{}},
+		{method: "POST", path: "/api/sessions/{id}/comments/{cid}/reopen", tag: "comments", summary: "重新打开批注", resp: draftComment{}},
+		{method: "DELETE", path: "/api/sessions/{id}/comments/{cid}", tag: "comments", summary: "删除批注", status: http.StatusNoContent},
+		{method: "POST", path: "/api/sessions/{id}/comments/revise", tag: "comments", summary: "按全部待处理批注修订稿件", resp: sess},
+
+		{method: "POST", path: "/api/uploads", tag: "uploads", summary: "上传封面或配图，或上传 Word/HTML 文档转为稿件", form: map[string]any{
+			"session_id": schemaString, "file": schemaBinary, "usage": map[string]any{"type": "string", "description": "cover、inline 或 document（.docx/.html/.ipynb 转为 Markdown 写入 session 稿件，图片保存为配图）"},
+		}, resp: uploadResp{}},
+		{method: "POST", path: "/api/uploads/resumable", tag: "uploads", summary: "创建分片上传（大图或 MP4 视频）", body: resumableCreateReq{}, status: http.StatusCreated, resp: resumableStatus{}},
+		{method: "GET", path: "/api/uploads/resumable/{id}", tag: "uploads", summary: "查询分片上传进度（断线后据 offset 续传）", resp: resumableStatus{}},
+		{method: "PUT", path: "/api/uploads/resumable/{id}", tag: "uploads", summary: "上传一个分片（请求体为原始字节，offset 不符时返回 409 与当前状态）", query: []apiParam{{"offset", "integer", "分片起始位置，须等于已接收的字节数"}}, resp: resumableStatus{}},
+		{method: "DELETE", path: "/api/uploads/resumable/{id}", tag: "uploads", summary: "取消分片上传", status: http.StatusNoContent},
+		{method: "POST", path: "/api/uploads/resumable/{id}/complete", tag: "uploads", summary: "校验并完成分片上传", resp: uploadResp{}},
+		{method: "POST", path: "/api/uploads/{name}/edit", tag: "uploads", summary: "裁剪、旋转或缩放已上传的图片，结果保存为新的上传文件", body: uploadEditReq{}, resp: uploadResp{}},
+
+		{method: "POST", path: "/api/publish", tag: "publish", summary: "提交发布任务（schedule_at 非空时创建定时发布并返回 201）", body: publishReq{}, status: http.StatusAccepted, resp: publishJob{}},
+		{method: "GET", path: "/api/jobs/{id}", tag: "publish", summary: "查询发布任务进度", resp: publishJob{}},
+		{method: "GET", path: "/api/publishes", tag: "publish", summary: "发布记录", query: []apiParam{
+			{"session_id", "string", ""}, {"media_id", "string", ""}, {"status", "string", "success 或 failed"}, {"account", "string", ""}, {"q", "string", "按标题搜索"},
+			{"since", "string", "YYYY-MM-DD"}, {"until", "string", "YYYY-MM-DD"}, {"limit", "integer", "默认 50，0 表示不限"},
+		}, resp: obj(map[string]any{"publishes": arr(publisher.PublishRecord{}), "count": schemaInt})},
+		{method: "POST", path: "/api/publishes/{media_id}/preview", tag: "publish", summary: "把草稿发送到微信号预览（写作者只能预览自己发布的草稿）", body: publishPreviewReq{}, resp: obj(map[string]any{"media_id": schemaString, "to": schemaString, "openid": schemaString, "sent": schemaBool})},
+		{method: "GET", path: "/api/analytics", tag: "publish", summary: "图文阅读、分享与收藏数据（写作者只能查看自己发布的文章）", query: []apiParam{
+			{"since", "string", "YYYY-MM-DD"}, {"until", "string", "YYYY-MM-DD，包含当天"}, {"q", "string", "按标题搜索"}, {"msgid", "string", ""},
+		}, resp: obj(map[string]any{"articles": arr(publisher.ArticleAnalytics{}), "days": arr(publisher.AccountDayStats{}), "updated_at": schemaString, "collector": analyticsCollector{}})},
+		{method: "GET", path: "/api/analytics/followers", tag: "publish", summary: "每日粉丝变化，附当天的图文阅读次数与发表的文章（启用登录时需审核人或发布人）", query: []apiParam{
+			{"since", "string", "YYYY-MM-DD"}, {"until", "string", "YYYY-MM-DD，包含当天"},
+		}, resp: publisher.FollowerReport{}},
+		{method: "GET", path: "/api/articles/{article}/comments", tag: "publish", summary: "已群发图文的留言，{article} 为 msg_data_id 或 msgid（msg_data_id_n）（启用登录时需审核人或发布人）", query: []apiParam{
+			{"type", "string", "all/normal/elected"}, {"offset", "integer", ""}, {"count", "integer", "1-50，默认 50"},
+		}, resp: obj(map[string]any{"article": schemaString, "comments": arr(publisher.ArticleComment{}), "total": schemaInt})},
+		{method: "POST", path: "/api/articles/{article}/comments/open", tag: "publish", summary: "打开图文的留言", resp: commentActionResp},
+		{method: "POST", path: "/api/articles/{article}/comments/close", tag: "publish", summary: "关闭图文的留言", resp: commentActionResp},
+		{method: "POST", path: "/api/articles/{article}/comments/{user_comment_id}/elect", tag: "publish", summary: "把留言设为精选", resp: commentActionResp},
+		{method: "POST", path: "/api/articles/{article}/comments/{user_comment_id}/unelect", tag: "publish", summary: "取消精选留言", resp: commentActionResp},
+		{method: "POST", path: "/api/articles/{article}/comments/{user_comment_id}/reply", tag: "publish", summary: "以公众号身份回复留言", body: commentReplyReq{}, resp: commentActionResp},
+		{method: "GET", path: "/api/schedules", tag: "publish", summary: "定时发布列表", query: []apiParam{{"status", "string", "scheduled/running/done/failed/canceled"}}, resp: obj(map[string]any{"schedules": arr(publisher.ScheduledPublish{})})},
+		{method: "DELETE", path: "/api/schedules/{id}", tag: "publish", summary: "取消定时发布", resp: publisher.ScheduledPublish{}},
+		{method: "GET", path: "/api/masssend", tag: "publish", summary: "群发申请与记录（启用登录时需审核人或发布人）", query: []apiParam{{"status", "string", "pending/sending/sent/failed/canceled"}}, resp: obj(map[string]any{"mass_sends": arr(publisher.MassSendRequest{})})},
+		{method: "POST", path: "/api/masssend", tag: "publish", summary: "提交群发申请（需启用登录与发布人），批准后才发送；未启用登录时返回 501", body: massSendReq{}, resp: publisher.MassSendRequest{}, status: http.StatusCreated},
+		{method: "GET", path: "/api/masssend/{id}", tag: "publish", summary: "群发详情，已发送时查询微信的发送状态", resp: publisher.MassSendRequest{}},
+		{method: "POST", path: "/api/masssend/{id}/approve", tag: "publish", summary: "批准并立即群发（需启用登录与发布人，且不能是申请人）", resp: publisher.MassSendRequest{}},
+		{method: "POST", path: "/api/masssend/{id}/cancel", tag: "publish", summary: "取消待批准的群发", resp: publisher.MassSendRequest{}},
+		{method: "POST", path: "/api/masssend/preview", tag: "publish", summary: "把草稿发送到微信号预览", body: massPreviewReq{}, resp: obj(map[string]any{"media_id": schemaString, "sent": schemaBool})},
+		{method: "GET", path: "/api/tags", tag: "publish", summary: "公众号的用户标签，群发时可按标签发送（启用登录时需审核人或发布人）", resp: obj(map[string]any{"tags": arr(publisher.UserTag{})})},
+		{method: "POST", path: "/api/tags", tag: "publish", summary: "创建用户标签（需发布人）", body: tagReq{}, resp: publisher.UserTag{}, status: http.StatusCreated},
+		{method: "PUT", path: "/api/tags/{id}", tag: "publish", summary: "修改标签名（需发布人）", body: tagReq{}, resp: tagActionResp},
+		{method: "DELETE", path: "/api/tags/{id}", tag: "publish", summary: "删除标签（需发布人）", status: http.StatusNoContent},
+		{method: "POST", path: "/api/tags/{id}/tag", tag: "publish", summary: "给粉丝打上标签（需发布人）", body: tagUsersReq{}, resp: tagActionResp},
+		{method: "POST", path: "/api/tags/{id}/untag", tag: "publish", summary: "取消粉丝的标签（需发布人）", body: tagUsersReq{}, resp: tagActionResp},
+		{method: "GET", path: "/api/recurring", tag: "publish", summary: "周期任务列表", resp: obj(map[string]any{"tasks": arr(recurringTask{})})},
+		{method: "POST", path: "/api/recurring/{name}/run", tag: "publish", summary: "立即执行一次周期任务", status: http.StatusAccepted},
+		{method: "GET", path: "/api/feeds", tag: "publish", summary: "订阅源汇总任务列表", resp: obj(map[string]any{"tasks": arr(feedTask{})})},
+		{method: "GET", path: "/api/feeds/{name}", tag: "publish", summary: "订阅源汇总任务的待汇总条目", resp: obj(map[string]any{"task": feedTask{}, "items": arr(publisher.FeedItem{})})},
+		{method: "POST", path: "/api/feeds/{name}/run", tag: "publish", summary: "立即抓取订阅源并汇总一次", status: http.StatusAccepted},
+		{method: "POST", path: "/api/bots/wecom", tag: "publish", summary: "企业微信应用回调：接收加密的用户消息（GET 为回调地址验证），按 msg_signature 校验", public: true, query: []apiParam{{"msg_signature", "string", "消息签名"}, {"timestamp", "string", "时间戳"}, {"nonce", "string", "随机数"}}},
+		{method: "POST", path: "/api/ingest", tag: "publish", summary: "推送 Markdown 或 Git push webhook，保存为待审核稿件或直接发布", body: ingestReq{}, resp: obj(map[string]any{"results": arr(ingestResult{})})},
+		{method: "GET", path: "/api/admin/stats", tag: "admin", summary: "使用统计（启用登录时仅管理员）", query: []apiParam{
+			{"since", "string", "YYYY-MM-DD"}, {"until", "string", "YYYY-MM-DD，包含当天"}, {"days", "integer", "未指定 since 时统计最近的天数，默认 30"},
+		}, resp: adminStats{}},
+		{method: "GET", path: "/api/admin/audit", tag: "admin", summary: "审计日志（启用登录时仅管理员），format=jsonl 时导出 JSON Lines", query: []apiParam{
+			{"user", "string", ""}, {"action", "string", "如 publish 匹配 publish.*"}, {"target", "string", "session ID、style:key 或 user:name"},
+			{"since", "string", "YYYY-MM-DD"}, {"until", "string", "YYYY-MM-DD"}, {"limit", "integer", "默认 100"}, {"format", "string", "json 或 jsonl"},
+		}, resp: obj(map[string]any{"entries": arr(publisher.AuditEntry{}), "count": schemaInt})},
+		{method: "POST", path: "/api/admin/reload", tag: "admin", summary: "重新加载配置文件（启用登录时仅管理员），配置无效时保留原配置并返回 422", resp: reloadResult{}},
+		{method: "GET", path: "/api/admin/quota", tag: "admin", summary: "接口当天的调用额度（启用登录时仅管理员），单个接口查询失败时附 error", query: []apiParam{
+			{"path", "string", "接口路径，如 /cgi-bin/draft/add，可重复；省略时查询发布、上传与群发使用的接口"},
+		}, resp: obj(map[string]any{"quotas": arr(publisher.APIQuota{})})},
+		{method: "POST", path: "/api/admin/quota/clear", tag: "admin", summary: "把接口调用次数清零（启用登录时仅管理员，每月最多 10 次）", resp: obj(map[string]any{"cleared": schemaBool})},
+
+		{method: "GET", path: "/api/styles", tag: "content", summary: "写作风格列表", resp: arr(generator.StylePreset{})},
+		{method: "POST", path: "/api/styles", tag: "content", summary: "新建写作风格", body: generator.StylePreset{}, resp: generator.StylePreset{}},
+		{method: "GET", path: "/api/styles/{key}", tag: "content", summary: "获取写作风格", resp: generator.StylePreset{}},
+		{method: "PUT", path: "/api/styles/{key}", tag: "content", summary: "修改写作风格", body: generator.StylePreset{}, resp: generator.StylePreset{}},
+		{method: "DELETE", path: "/api/styles/{key}", tag: "content", summary: "删除写作风格", status: http.StatusNoContent},
+		{method: "GET", path: "/api/series", tag: "content", summary: "系列列表", resp: arr(generator.Series{})},
+		{method: "POST", path: "/api/series", tag: "content", summary: "新建系列", body: generator.Series{}, resp: generator.Series{}},
+		{method: "GET", path: "/api/series/{id}", tag: "content", summary: "获取系列", resp: generator.Series{}},
+		{method: "PUT", path: "/api/series/{id}", tag: "content", summary: "修改系列", body: generator.Series{}, resp: generator.Series{}},
+		{method: "DELETE", path: "/api/series/{id}", tag: "content", summary: "删除系列", status: http.StatusNoContent},
+		{method: "POST", path: "/api/series/{id}/parts", tag: "content", summary: "登记系列中的一篇", body: seriesPartReq{}, resp: generator.Series{}},
+		{method: "POST", path: "/api/ideas", tag: "content", summary: "生成选题", body: ideasReq{}, resp: obj(map[string]any{"ideas": arr(generator.Idea{})})},
+		{method: "GET", path: "/api/calendar", tag: "content", summary: "内容日历", resp: arr(generator.Idea{})},
+		{method: "PUT", path: "/api/calendar/{id}", tag: "content", summary: "修改日历条目", body: calendarUpdateReq{}, resp: generator.Idea{}},
+		{method: "DELETE", path: "/api/calendar/{id}", tag: "content", summary: "删除日历条目", status: http.StatusNoContent},
+
+		{method: "GET", path: "/api/ws", tag: "events", summary: "WebSocket 事件通道（需 Upgrade），可用 session_id 订阅", query: []apiParam{{"session_id", "string", ""}}, status: http.StatusSwitchingProtocols},
+		{method: "GET", path: "/api/openapi.json", tag: "docs", summary: "本文档", public: true, resp: schemaAnyJSON},
+		{method: "GET", path: "/api/docs", tag: "docs", summary: "Swagger UI", public: true, contentType: "text/html", resp: schemaString},
+	}
+	return ops
+}
+
+// schemaGen 按 Go 类型生成 JSON Schema，具名结构体放入 components.schemas 并以 $ref 引用。
+type schemaGen struct {
+	defs map[string]any
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// of 返回 v 的 schema；v 为 map[string]any 时视为已写好的 schema，其中的值递归处理。
+func (g *schemaGen) of(v any) map[string]any {
+	if m, ok := v.(map[string]any); ok {
+		out := make(map[string]any, len(m))
+		for k, val := range m {
+			switch val.(type) {
+			case map[string]any:
+				out[k] = g.of(val)
+			case string, bool, int, float64, []string:
+				out[k] = val
+			default:
+				out[k] = g.of(val)
+			}
+		}
+		return out
+	}
+	return g.schema(reflect.TypeOf(v))
+}
+
+func (g *schemaGen) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return arr(g.schema(t.Elem()))
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+		if _, ok := g.defs[name]; !ok {
+			g.defs[name] = map[string]any{} // 占位，避免递归类型无限展开
+			g.defs[name] = g.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]any{}
+}
+
+// structSchema 按 encoding/json 的规则列出字段：跳过未导出与 json:"-" 字段，展开匿名嵌入的结构体。
+func (g *schemaGen) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				walk(ft)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = g.schema(f.Type)
+		}
+	}
+	walk(t)
+	return obj(props)
+}
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// openAPISpec 生成 OpenAPI 3 文档；servers 使用 base_path。
+func (s *Server) openAPISpec() map[string]any {
+	g := &schemaGen{defs: map[string]any{}}
+	errResp := map[string]any{
+		"description": "错误信息（纯文本；限流时为 JSON 并带 Retry-After）",
+		"content":     map[string]any{"text/plain": map[string]any{"schema": schemaString}},
+	}
+	paths := map[string]any{}
+	for _, op := range apiOperations() {
+		o := map[string]any{
+			"tags":        []string{op.tag},
+			"summary":     op.summary,
+			"operationId": operationID(op),
+		}
+		var params []any
+		for _, m := range pathParam.FindAllStringSubmatch(op.path, -1) {
+			params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "schema": schemaString})
+		}
+		for _, q := range op.query {
+			p := map[string]any{"name": q.name, "in": "query", "schema": map[string]any{"type": q.typ}}
+			if q.desc != "" {
+				p["description"] = q.desc
+			}
+			params = append(params, p)
+		}
+		if len(params) > 0 {
+			o["parameters"] = params
+		}
+		switch {
+		case op.form != nil:
+			o["requestBody"] = map[string]any{"required": true, "content": map[string]any{
+				"multipart/form-data": map[string]any{"schema": map[string]any{"type": "object", "properties": op.form, "required": []string{"session_id", "file"}}},
+			}}
+		case op.body != nil:
+			o["requestBody"] = map[string]any{"required": true, "content": map[string]any{
+				"application/json": map[string]any{"schema": g.of(op.body)},
+			}}
+		}
+		status := op.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		resp := map[string]any{"description": http.StatusText(status)}
+		if op.resp != nil {
+			ct := op.contentType
+			if ct == "" {
+				ct = "application/json"
+			}
+			resp["content"] = map[string]any{ct: map[string]any{"schema": g.of(op.resp)}}
+		}
+		responses := map[string]any{strconv.Itoa(status): resp, "default": errResp}
+		if op.path == "/api/publish" {
+			responses["201"] = map[string]any{"description": "已创建定时发布", "content": map[string]any{
+				"application/json": map[string]any{"schema": g.of(publisher.ScheduledPublish{})},
+			}}
+		}
+		o["responses"] = responses
+		if op.public {
+			o["security"] = []any{}
+		}
+		item, _ := paths[op.path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[op.path] = item
+		}
+		item[strings.ToLower(op.method)] = o
+	}
+	server := s.basePath
+	if server == "" {
+		server = "/"
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Auto WeChat Article Publisher API",
+			"version":     "1.0",
+			"description": "写稿、审核与发布到公众号草稿箱的接口。启用登录时需携带 Authorization: Bearer 令牌（POST /api/login 获取）或登录 cookie。",
+		},
+		"servers": []any{map[string]any{"url": server}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": g.defs,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+				"cookieAuth": map[string]any{"type": "apiKey", "in": "cookie", "name": authCookie},
+			},
+		},
+		"security": []any{map[string]any{"bearerAuth": []string{}}, map[string]any{"cookieAuth": []string{}}},
+	}
+}
+
+// operationID 由方法与路径生成，如 GET /api/sessions/{id}/workflow → getSessionsIdWorkflow。
+func operationID(op apiOp) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(op.method))
+	for _, part := range strings.FieldsFunc(strings.TrimPrefix(op.path, "/api"), func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '.' || r == '_'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// handleOpenAPI 返回 OpenAPI 3 文档。
+// Path: GET /api/openapi.json
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, s.openAPISpec())
+}
+
+// handleAPIDocs 提供 Swagger UI 页面（GET /api/docs）及其初始化脚本（GET /api/docs/init.js）；
+// Swagger UI 的脚本与样式从 unpkg 加载，需要浏览器能访问外网。
+// Path: GET /api/docs
+func (s *Server) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, "/init.js") {
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		io.WriteString(w, swaggerInit)
+		return
+	}
+	cdn := "https://unpkg.com/swagger-ui-dist@" + swaggerUIVersion
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'self' https://unpkg.com; style-src 'self' 'unsafe-inline' https://unpkg.com; img-src 'self' data: https:; connect-src 'self'; frame-ancestors 'none'")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, swaggerPage, cdn, html.EscapeString(s.basePath+"/api/openapi.json"), cdn, html.EscapeString(s.basePath+"/api/docs/init.js"))
+}
+
+// swaggerPage 不使用内联脚本，以满足 script-src 'self' 的 CSP；文档地址通过 data-spec 传给 init.js。
+const swaggerPage = `<!doctype html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>API 文档</title>
+<link rel="stylesheet" href="%s/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui" data-spec="%s"></div>
+<script src="%s/swagger-ui-bundle.js"></script>
+<script src="%s"></script>
+</body>
+</html>
+`
+
+const swaggerInit = `window.ui = SwaggerUIBundle({
+  url: document.getElementById('swagger-ui').dataset.spec,
+  dom_id: '#swagger-ui',
+  persistAuthorization: true,
+});
+`
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 未启用登录时按调用方绑定 session（sessions.bind_owner）：接口调用方通过 X-API-Key 头标识自己，
+// 浏览器在首次打开页面时获得随机的 awp_client cookie。
+const (
+	apiKeyHeader  = "X-API-Key"
+	clientCookie  = "awp_client"
+	minAPIKeyLen  = 16
+	clientKeyLife = 365 * 24 * time.Hour
+)
+
+type clientOwnerKey struct{}
+
+// ownerBinding 表示未启用登录且配置了 sessions.bind_owner；启用登录时 session 已归属登录用户。
+func (s *Server) ownerBinding() bool {
+	sc := s.config().Sessions
+	return s.auth == nil && sc != nil && sc.BindOwner
+}
+
+// clientOwner 由 API key 派生 session 的归属标识，不保存 key 本身。
+func clientOwner(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "key-" + hex.EncodeToString(sum[:16])
+}
+
+// sessionOwner 返回当前调用方作为 session 归属的标识：登录用户名，或绑定模式下由 API key 派生的标识。
+func sessionOwner(r *http.Request) string {
+	if owner, ok := r.Context().Value(clientOwnerKey{}).(string); ok {
+		return owner
+	}
+	return currentUser(r)
+}
+
+// ownerMiddleware 在绑定模式下识别调用方：优先使用 X-API-Key 头，其次为 awp_client cookie；
+// 打开页面时没有 cookie 则签发一个。接口请求两者都没有时不带归属，不能新建或访问已绑定的 session。
+func (s *Server) ownerMiddleware(next http.Handler) http.Handler {
+	if !s.ownerBinding() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get(apiKeyHeader))
+		if key != "" && len(key) < minAPIKeyLen {
+			http.Error(w, "X-API-Key must be at least 16 characters", http.StatusUnauthorized)
+			return
+		}
+		if key == "" {
+			if c, err := r.Cookie(clientCookie); err == nil && len(c.Value) >= minAPIKeyLen {
+				key = c.Value
+			}
+		}
+		if key == "" && !strings.HasPrefix(r.URL.Path, "/api/") && !strings.HasPrefix(r.URL.Path, "/uploads/") {
+			b := make([]byte, 24)
+			if _, err := rand.Read(b); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			key = base64.RawURLEncoding.EncodeToString(b)
+			http.SetCookie(w, &http.Cookie{
+				Name:     clientCookie,
+				Value:    key,
+				Path:     "/",
+				Expires:  time.Now().Add(clientKeyLife),
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+		if key != "" {
+			r = r.WithContext(context.WithValue(r.Context(), clientOwnerKey{}, clientOwner(key)))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
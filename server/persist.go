@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"auto_wechat_article_publisher/generator"
+)
+
+// sessionRecord 为持久化保存的一条 session：状态快照、已上传文件、审核状态、批注与最后更新时间。
+type sessionRecord struct {
+	State     generator.SessionState `json:"state"`
+	Uploads   []string               `json:"uploads,omitempty"`
+	Workflow  workflow               `json:"workflow"`
+	Comments  []draftComment         `json:"comments,omitempty"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// sessionBackend 为 session 的持久化存储。未配置时 sessionStore 只保存在内存中，
+// 过期即删除；配置后重启时从这里恢复，过期只从内存移出。
+type sessionBackend interface {
+	save(rec sessionRecord) error
+	// load 读取单个 session，不存在时 ok 为 false。
+	load(id string) (rec sessionRecord, ok bool, err error)
+	list() ([]sessionRecord, error)
+	delete(id string) error
+	// ping 检查存储是否可用，供 /readyz 使用。
+	ping() error
+	close() error
+}
+
+var sessionsBucket = []byte("sessions")
+
+// boltBackend 把每个 session 以 JSON 保存在 bbolt 文件的 sessions 桶中。
+type boltBackend struct {
+	db *bolt.DB
+}
+
+func openBoltBackend(path string) (*boltBackend, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create session db dir: %w", err)
+		}
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open session db %s: %w", path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(sessionsBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &boltBackend{db: db}, nil
+}
+
+func (b *boltBackend) save(rec sessionRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Put([]byte(rec.State.ID), data)
+	})
+}
+
+func (b *boltBackend) load(id string) (sessionRecord, bool, error) {
+	var rec sessionRecord
+	var ok bool
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(sessionsBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(data, &rec)
+	})
+	return rec, ok, err
+}
+
+func (b *boltBackend) list() ([]sessionRecord, error) {
+	var out []sessionRecord
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).ForEach(func(k, v []byte) error {
+			var rec sessionRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return fmt.Errorf("session %s: %w", k, err)
+			}
+			out = append(out, rec)
+			return nil
+		})
+	})
+	return out, err
+}
+
+func (b *boltBackend) delete(id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Delete([]byte(id))
+	})
+}
+
+func (b *boltBackend) ping() error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(sessionsBucket) == nil {
+			return errors.New("sessions bucket missing")
+		}
+		return nil
+	})
+}
+
+func (b *boltBackend) close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"auto_wechat_article_publisher/generator"
+)
+
+func TestPublishRejectsForeignCoverPath(t *testing.T) {
+	srv, _ := newTestServer(t, &countingLLM{})
+	sess := generator.NewSession("s1", generator.Spec{Topic: "x"}, srv.genAgent)
+	sess.Owner = "alice"
+	sess.Draft = generator.Draft{Title: "标题", Markdown: "# 标题\n\n正文"}
+	srv.store.set("s1", sess)
+	other := filepath.Join(srv.uploadDir, "bob", "cover.jpg")
+	outside := filepath.Join(t.TempDir(), "secret.jpg")
+	for _, p := range []string{other, outside} {
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, p := range []string{other, outside, filepath.Join(srv.uploadDir, "alice", "..", "bob", "cover.jpg")} {
+		body := `{"session_id":"s1","cover_path":` + strconv.Quote(p) + `}`
+		rec := httptest.NewRecorder()
+		srv.handlePublish(rec, httptest.NewRequest(http.MethodPost, "/api/publish", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid cover_path") {
+			t.Errorf("cover_path %s: %d %s, want 400 invalid cover_path", p, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestPublishMarkdownOverrideWaitsForEdit(t *testing.T) {
+	srv, _ := newTestServer(t, &countingLLM{})
+	sess := generator.NewSession("s1", generator.Spec{Topic: "x"}, srv.genAgent)
+	sess.Draft = generator.Draft{Title: "标题", Markdown: "# 标题\n\n正文"}
+	srv.store.set("s1", sess)
+	done, _, ok := srv.store.begin("s1", "stream")
+	if !ok {
+		t.Fatal("begin edit failed")
+	}
+	defer done()
+
+	body := `{"session_id":"s1","markdown":"# 覆盖\n\n新正文","ai_cover":true}`
+	rec := httptest.NewRecorder()
+	srv.handlePublish(rec, httptest.NewRequest(http.MethodPost, "/api/publish", strings.NewReader(body)))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("publish during edit = %d %s, want 409", rec.Code, rec.Body.String())
+	}
+	if got := sess.Draft.Markdown; got != "# 标题\n\n正文" {
+		t.Fatalf("draft = %q, want unchanged while another edit runs", got)
+	}
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// recordPublish 记录一次发布结果；记录失败只打日志，不影响发布响应。
+func (s *Server) recordPublish(rec publisher.PublishRecord, err error) {
+	rec.Account = s.config().AccountID()
+	rec.Status = publisher.PublishSucceeded
+	if err != nil {
+		rec.Status = publisher.PublishFailed
+		rec.Error = err.Error()
+		rec.ErrCode = publisher.WeChatErrorCode(err)
+	}
+	if _, err := s.publishes.Append(rec); err != nil {
+		log.Printf("[publish] record history failed: %v", err)
+	}
+}
+
+// handlePublishes 按时间倒序列出发布记录。
+// Path: GET /api/publishes?session_id=&media_id=&status=success|failed&account=&q=&since=YYYY-MM-DD&until=YYYY-MM-DD&limit=50
+func (s *Server) handlePublishes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	filter := publisher.PublishFilter{
+		SessionID: query.Get("session_id"),
+		MediaID:   query.Get("media_id"),
+		User:      query.Get("user"),
+		Status:    query.Get("status"),
+		Account:   query.Get("account"),
+		Query:     query.Get("q"),
+	}
+	var err error
+	if filter.Limit, err = queryInt(query.Get("limit"), 50); err != nil || filter.Limit < 0 {
+		http.Error(w, "invalid limit", http.StatusBadRequest)
+		return
+	}
+	if v := query.Get("since"); v != "" {
+		if filter.Since, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			http.Error(w, "invalid since: use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("until"); v != "" {
+		until, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			http.Error(w, "invalid until: use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		// until 包含当天。
+		filter.Until = until.AddDate(0, 0, 1)
+	}
+	// 写作者只能查看自己的发布记录，管理员、审核人与发布人可查看全部。
+	if user := currentUser(r); user != "" && !s.hasRole(r, roleReviewer) && !s.hasRole(r, rolePublisher) {
+		filter.User = user
+	}
+	records, err := s.publishes.List(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if records == nil {
+		records = []publisher.PublishRecord{}
+	}
+	writeJSON(w, map[string]any{"publishes": records, "count": len(records)})
+}
+
+// publishPreviewReq 为预览的接收者：to 为微信号，为空时使用 openid；接收者需已关注公众号。
+type publishPreviewReq struct {
+	To     string `json:"to,omitempty"`
+	OpenID string `json:"openid,omitempty"`
+}
+
+// handlePublishPreview 把已发布到草稿箱的图文发送到手机预览，在群发或正式发布前核对排版。
+// 审核人与发布人可预览任意草稿，写作者只能预览自己发布的草稿。
+// Path: POST /api/publishes/{media_id}/preview
+func (s *Server) handlePublishPreview(w http.ResponseWriter, r *http.Request) {
+	mediaID, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/publishes/"), "/")
+	if mediaID == "" || action != "preview" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if user := currentUser(r); user != "" && !s.hasRole(r, roleReviewer) && !s.hasRole(r, rolePublisher) {
+		records, err := s.publishes.List(publisher.PublishFilter{MediaID: mediaID, User: user, Status: publisher.PublishSucceeded, Limit: 1})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(records) == 0 {
+			http.Error(w, "only drafts you published can be previewed", http.StatusForbidden)
+			return
+		}
+	}
+	var req publishPreviewReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.To == "" && req.OpenID == "" {
+		http.Error(w, "to or openid required", http.StatusBadRequest)
+		return
+	}
+	p, err := s.ensurePublisher()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	if err := p.SendPreview(ctx, mediaID, req.To, req.OpenID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	log.Printf("[preview] media_id=%s to=%s openid=%s by=%s", mediaID, req.To, req.OpenID, currentUser(r))
+	writeJSON(w, map[string]any{"media_id": mediaID, "to": req.To, "openid": req.OpenID, "sent": true})
+}
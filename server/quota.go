@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// handleAdminQuota 查询接口调用额度，或把调用次数清零（每月最多 10 次）。启用登录时仅管理员。
+// Path: GET /api/admin/quota?path=/cgi-bin/draft/add&path=..., POST /api/admin/quota/clear
+func (s *Server) handleAdminQuota(w http.ResponseWriter, r *http.Request) {
+	reset := r.URL.Path == "/api/admin/quota/clear"
+	switch {
+	case !reset && r.URL.Path != "/api/admin/quota":
+		http.NotFound(w, r)
+		return
+	case reset && r.Method != http.MethodPost, !reset && r.Method != http.MethodGet:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.auth != nil && !isAdmin(r) {
+		http.Error(w, "admin only", http.StatusForbidden)
+		return
+	}
+	p, err := s.ensurePublisher()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	if !reset {
+		writeJSON(w, map[string]any{"quotas": p.QuotaReport(ctx, r.URL.Query()["path"])})
+		return
+	}
+	if err := p.ClearQuota(ctx); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	s.audit(r, publisher.AuditQuotaCleared, "account:"+s.config().AppID, "clear api quota")
+	writeJSON(w, map[string]any{"cleared": true})
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// rateLimiterIdle 为空闲令牌桶的保留时间，超过后清理，避免按 IP 无限增长。
+const rateLimiterIdle = 10 * time.Minute
+
+// tokenBucket 为一个令牌桶：每秒补充 rate 个令牌，最多 burst 个。
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter 按 key 维护令牌桶。
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+func newRateLimiter(perMinute float64, burst int) *rateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	b := float64(burst)
+	if b <= 0 {
+		b = math.Max(1, perMinute)
+	}
+	return &rateLimiter{rate: perMinute / 60, burst: b, buckets: make(map[string]*tokenBucket)}
+}
+
+// allow 取一个令牌；不足时返回需要等待的时间。
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.swept) > rateLimiterIdle {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > rateLimiterIdle {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// rateRule 为一组接口的按 IP 与按用户限额，任一为 nil 表示不限制该维度。
+type rateRule struct {
+	name  string
+	perIP *rateLimiter
+	byKey *rateLimiter
+}
+
+func newRateRule(name string, cfg *publisher.RateRule) *rateRule {
+	if cfg == nil {
+		return nil
+	}
+	r := &rateRule{name: name, perIP: newRateLimiter(cfg.PerIP, cfg.Burst), byKey: newRateLimiter(cfg.PerKey, cfg.Burst)}
+	if r.perIP == nil && r.byKey == nil {
+		return nil
+	}
+	return r
+}
+
+// rateLimits 为 rate_limit 配置对应的限流器；Server.limits 为 nil 表示未启用。
+type rateLimits struct {
+	sessions   *rateRule
+	publish    *rateRule
+	trustProxy bool
+}
+
+func newRateLimits(cfg *publisher.RateLimitConfig) *rateLimits {
+	if cfg == nil {
+		return nil
+	}
+	l := &rateLimits{
+		sessions:   newRateRule("sessions", cfg.Sessions),
+		publish:    newRateRule("publish", cfg.Publish),
+		trustProxy: cfg.TrustProxy,
+	}
+	if l.sessions == nil && l.publish == nil {
+		return nil
+	}
+	return l
+}
+
+// ruleFor 返回请求适用的限额：发布与推送请求，以及创建 session、生成/修订等会调用模型的修改请求；查询类请求不限制。
+func (l *rateLimits) ruleFor(r *http.Request) *rateRule {
+	p := r.URL.Path
+	switch {
+	case p == "/api/publish" || p == "/api/ingest":
+		return l.publish
+	case p == "/api/sessions" || strings.HasPrefix(p, "/api/sessions/"):
+		if r.Method != http.MethodGet {
+			return l.sessions
+		}
+	}
+	return nil
+}
+
+// clientIP 返回客户端 IP；trustProxy 为 true 时优先使用反向代理设置的 X-Real-IP / X-Forwarded-For。
+func (l *rateLimits) clientIP(r *http.Request) string {
+	if l.trustProxy {
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			first, _, _ := strings.Cut(xff, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimitMiddleware 按 IP 与调用方（登录用户，或绑定模式下的 API key / awp_client cookie）限制请求频率，
+// 超出时返回 429 与 Retry-After。需放在 authMiddleware 与 ownerMiddleware 之内，才能取得调用方。
+func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
+	if s.limits == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rule := s.limits.ruleFor(r)
+		if rule == nil || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		now := time.Now()
+		ip := s.limits.clientIP(r)
+		if rule.perIP != nil {
+			if ok, wait := rule.perIP.allow(ip, now); !ok {
+				s.writeRateLimited(w, rule.name, "ip", ip, wait)
+				return
+			}
+		}
+		if owner := sessionOwner(r); owner != "" && rule.byKey != nil {
+			if ok, wait := rule.byKey.allow(owner, now); !ok {
+				s.writeRateLimited(w, rule.name, "key", owner, wait)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) writeRateLimited(w http.ResponseWriter, rule, scope, key string, wait time.Duration) {
+	retry := int(math.Ceil(wait.Seconds()))
+	if retry < 1 {
+		retry = 1
+	}
+	log.Printf("[ratelimit] %s limited %s=%s retry_after=%ds", rule, scope, key, retry)
+	w.Header().Set("Retry-After", strconv.Itoa(retry))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error":       "rate limit exceeded; retry later",
+		"scope":       scope,
+		"limit":       rule,
+		"retry_after": retry,
+	})
+}
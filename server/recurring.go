@@ -0,0 +1,415 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+	"auto_wechat_article_publisher/publisher"
+)
+
+// 周期任务模式。
+const (
+	recurringReview  = "review"
+	recurringPublish = "publish"
+)
+
+// recurringInterval 为检查周期任务是否到点的间隔。
+const recurringInterval = 30 * time.Second
+
+var weekdayNames = [...]string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}
+
+// topicData 为周期任务 topic 模板的可用字段。
+type topicData struct {
+	Date    string
+	Weekday string
+	Week    int
+	Month   int
+	Run     int
+	// Count 为订阅源汇总本次收入的条目数（周期任务中为 0）。
+	Count int
+}
+
+// recurringTask 为一个周期任务及其运行状态；状态只保存在内存中，重启后从当前时间重新计算下次执行。
+type recurringTask struct {
+	cfg   publisher.RecurringConfig
+	cron  publisher.Cron
+	topic *template.Template
+
+	Name        string     `json:"name"`
+	Cron        string     `json:"cron"`
+	Mode        string     `json:"mode"`
+	NextRun     time.Time  `json:"next_run"`
+	LastRun     *time.Time `json:"last_run,omitempty"`
+	LastSession string     `json:"last_session,omitempty"`
+	LastJob     string     `json:"last_job,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	Runs        int        `json:"runs"`
+	Running     bool       `json:"running"`
+}
+
+type recurringRunner struct {
+	mu    sync.Mutex
+	tasks []*recurringTask
+}
+
+// newRecurringRunner 校验配置中的周期任务，配置错误时拒绝启动。
+func newRecurringRunner(cfgs []publisher.RecurringConfig, now time.Time) (*recurringRunner, error) {
+	r := &recurringRunner{}
+	seen := map[string]bool{}
+	for _, cfg := range cfgs {
+		if cfg.Name == "" {
+			return nil, errors.New("recurring: name is required")
+		}
+		if seen[cfg.Name] {
+			return nil, fmt.Errorf("recurring %s: duplicate name", cfg.Name)
+		}
+		seen[cfg.Name] = true
+		c, err := publisher.ParseCron(cfg.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("recurring %s: %w", cfg.Name, err)
+		}
+		tmpl, err := checkAutoWrite("recurring", &cfg)
+		if err != nil {
+			return nil, err
+		}
+		next := c.Next(now)
+		if next.IsZero() {
+			return nil, fmt.Errorf("recurring %s: cron %q never fires", cfg.Name, cfg.Cron)
+		}
+		r.tasks = append(r.tasks, &recurringTask{
+			cfg: cfg, cron: c, topic: tmpl,
+			Name: cfg.Name, Cron: cfg.Cron, Mode: cfg.Mode, NextRun: next,
+		})
+	}
+	return r, nil
+}
+
+// checkAutoWrite 校验周期任务与订阅源汇总共用的 topic 模板与模式，mode 为空时设为 review。
+func checkAutoWrite(kind string, cfg *publisher.RecurringConfig) (*template.Template, error) {
+	if strings.TrimSpace(cfg.Topic) == "" {
+		return nil, fmt.Errorf("%s %s: topic is required", kind, cfg.Name)
+	}
+	tmpl, err := template.New(cfg.Name).Option("missingkey=error").Parse(cfg.Topic)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: topic: %w", kind, cfg.Name, err)
+	}
+	switch cfg.Mode {
+	case "":
+		cfg.Mode = recurringReview
+	case recurringReview:
+	case recurringPublish:
+		if cfg.CoverPath == "" && !cfg.AICover {
+			return nil, fmt.Errorf("%s %s: publish mode needs cover_path or ai_cover", kind, cfg.Name)
+		}
+	default:
+		return nil, fmt.Errorf("%s %s: unknown mode %q (use review or publish)", kind, cfg.Name, cfg.Mode)
+	}
+	return tmpl, nil
+}
+
+// snapshot 返回任务状态的副本。
+func (r *recurringRunner) snapshot() []recurringTask {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]recurringTask, 0, len(r.tasks))
+	for _, t := range r.tasks {
+		out = append(out, *t)
+	}
+	return out
+}
+
+// start 把任务标记为运行中并返回本次序号；任务仍在运行时返回 false。
+func (r *recurringRunner) start(t *recurringTask, now time.Time) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t.Running {
+		return 0, false
+	}
+	t.Running = true
+	t.Runs++
+	t.LastRun = &now
+	t.LastSession, t.LastJob, t.LastError = "", "", ""
+	return t.Runs, true
+}
+
+func (r *recurringRunner) finish(t *recurringTask, fn func(t *recurringTask)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(t)
+}
+
+func (r *recurringRunner) find(name string) *recurringTask {
+	for _, t := range r.tasks {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// runRecurring 定期检查到点的周期任务。服务停止期间错过的执行不会补跑。
+func (s *Server) runRecurring(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		select {
+		case now = <-ticker.C:
+		case <-s.stop:
+			return
+		}
+		var due []*recurringTask
+		s.recurring.mu.Lock()
+		for _, t := range s.recurring.tasks {
+			if !now.Before(t.NextRun) {
+				t.NextRun = t.cron.Next(now)
+				due = append(due, t)
+			}
+		}
+		s.recurring.mu.Unlock()
+		for _, t := range due {
+			s.triggerRecurring(t, now)
+		}
+	}
+}
+
+// triggerRecurring 在后台执行一次周期任务；上一次尚未结束时跳过。
+func (s *Server) triggerRecurring(t *recurringTask, now time.Time) bool {
+	run, ok := s.recurring.start(t, now)
+	if !ok {
+		log.Printf("[recurring] %s still running; skip", t.Name)
+		return false
+	}
+	s.tasks.Add(1)
+	go func() {
+		defer s.tasks.Done()
+		s.runRecurringTask(t, now, run)
+	}()
+	return true
+}
+
+// recurringNotice 为周期任务执行后推送到 notify_url 的内容。
+type recurringNotice struct {
+	Task string `json:"task"`
+	Mode string `json:"mode"`
+	// Status 为 review（待审核）、published（已发布到草稿箱）或 failed。
+	Status    string    `json:"status"`
+	Topic     string    `json:"topic"`
+	SessionID string    `json:"session_id,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	Digest    string    `json:"digest,omitempty"`
+	JobID     string    `json:"job_id,omitempty"`
+	MediaID   string    `json:"media_id,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+	// Items 为订阅源汇总收入的条目数。
+	Items int `json:"items,omitempty"`
+}
+
+// runRecurringTask 生成一篇文章：review 模式保存为 session 等待审核，publish 模式提交到发布队列。
+func (s *Server) runRecurringTask(t *recurringTask, now time.Time, run int) {
+	cfg := t.cfg
+	notice := recurringNotice{Task: cfg.Name, Mode: cfg.Mode, Time: now}
+	fail := func(err error) {
+		log.Printf("[recurring] %s run=%d failed: %v", cfg.Name, run, err)
+		s.recurring.finish(t, func(t *recurringTask) {
+			t.Running, t.LastError = false, err.Error()
+		})
+		notice.Status, notice.Error = "failed", err.Error()
+		s.notifyRecurring(cfg.NotifyURL, notice)
+	}
+
+	topic, err := renderTopic(t.topic, now, topicData{Run: run})
+	if err != nil {
+		fail(err)
+		return
+	}
+	notice.Topic = topic
+	spec, err := s.recurringSpec(cfg, topic)
+	if err != nil {
+		fail(err)
+		return
+	}
+	id, jobID, err := s.autoWrite("recurring task "+cfg.Name, cfg, spec, &notice, func(err error) {
+		s.recurring.finish(t, func(t *recurringTask) { t.LastError = err.Error() })
+	})
+	if err != nil {
+		s.recurring.finish(t, func(t *recurringTask) { t.LastSession = id })
+		fail(err)
+		return
+	}
+	log.Printf("[recurring] %s run=%d session=%s title=%q", cfg.Name, run, id, notice.Title)
+	s.recurring.finish(t, func(t *recurringTask) {
+		t.Running, t.LastSession, t.LastJob = false, id, jobID
+	})
+}
+
+// renderTopic 用执行时间补全 data 中的日期字段并渲染 topic 模板。
+func renderTopic(tmpl *template.Template, now time.Time, data topicData) (string, error) {
+	_, data.Week = now.ISOWeek()
+	data.Date, data.Weekday, data.Month = now.Format("2006-01-02"), weekdayNames[now.Weekday()], int(now.Month())
+	var topic bytes.Buffer
+	if err := tmpl.Execute(&topic, data); err != nil {
+		return "", fmt.Errorf("render topic: %w", err)
+	}
+	return topic.String(), nil
+}
+
+// recurringSpec 按任务配置生成写作要求。
+func (s *Server) recurringSpec(cfg publisher.RecurringConfig, topic string) (generator.Spec, error) {
+	spec := generator.Spec{
+		Topic:    topic,
+		Outline:  cfg.Outline,
+		Words:    cfg.Words,
+		Style:    cfg.Style,
+		Audience: cfg.Audience,
+		Tone:     cfg.Tone,
+	}
+	if cfg.SeriesID != "" {
+		series, err := s.series.Get(cfg.SeriesID)
+		if err != nil {
+			return spec, err
+		}
+		spec.Series = &series
+	}
+	return spec, nil
+}
+
+// autoWrite 按 spec 生成一篇文章并保存为 session（source 写入审计日志）：review 模式推送待审核通知，
+// publish 模式提交到发布队列，发布结束后推送结果，失败时调用 publishFailed。notice 中补上 session 与稿件信息。
+// 返回 session ID（未能创建时为空）与发布任务 ID。
+func (s *Server) autoWrite(source string, cfg publisher.RecurringConfig, spec generator.Spec, notice *recurringNotice, publishFailed func(error)) (string, string, error) {
+	if err := s.store.admit(); err != nil {
+		return "", "", err
+	}
+	id := newSessionID()
+	sess := generator.NewSession(id, spec, s.agent())
+	sess.Owner = cfg.Owner
+	if cfg.Research && s.agent().HasSearch() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if _, err := sess.Research(ctx, ""); err != nil {
+			log.Printf("[recurring] %s research failed: %v", source, err)
+		}
+		cancel()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	draft, err := sess.Propose(ctx)
+	cancel()
+	if err != nil {
+		return "", "", err
+	}
+	s.store.set(id, sess)
+	s.recordAudit(publisher.AuditEntry{User: cfg.Owner, Action: publisher.AuditSessionCreated, Target: id, Detail: source + ": " + spec.Topic})
+	notice.SessionID, notice.Title, notice.Digest = id, draft.Title, draft.Digest
+
+	if cfg.Mode == recurringReview {
+		notice.Status = "review"
+		s.notifyRecurring(cfg.NotifyURL, *notice)
+		return id, "", nil
+	}
+
+	title := draft.Title
+	if title == "" {
+		title = spec.Topic
+	}
+	req := publishReq{
+		SessionID: id,
+		CoverPath: cfg.CoverPath,
+		Author:    cfg.Author,
+		Title:     title,
+		Digest:    draft.Digest,
+		Markdown:  draft.Markdown,
+		AICover:   cfg.CoverPath == "" && cfg.AICover,
+	}
+	queued := *notice
+	job, err := s.jobs.enqueue(id, cfg.Owner, cfg.Owner, func(ctx context.Context, job *publishJob) (publishResp, error) {
+		resp, err := s.runPublish(ctx, job, req, sess)
+		done := queued
+		done.JobID = job.ID
+		if err != nil {
+			done.Status, done.Error = "failed", err.Error()
+			publishFailed(err)
+		} else {
+			done.Status, done.MediaID = "published", resp.MediaID
+		}
+		s.notifyRecurring(cfg.NotifyURL, done)
+		return resp, err
+	})
+	if err != nil {
+		return id, "", err
+	}
+	s.recordAudit(publisher.AuditEntry{User: cfg.Owner, Action: publisher.AuditPublishRequested, Target: id, Detail: fmt.Sprintf("%s, job %s: %s", source, job.ID, title)})
+	return id, job.ID, nil
+}
+
+// notifyRecurring 把执行结果 POST 到 notify_url；失败只打日志。
+func (s *Server) notifyRecurring(url string, notice recurringNotice) {
+	if url == "" {
+		return
+	}
+	body, err := json.Marshal(notice)
+	if err != nil {
+		log.Printf("[recurring] encode notice failed: %v", err)
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("[recurring] notify %s failed: %v", notice.Task, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("[recurring] notify %s failed: status %d", notice.Task, resp.StatusCode)
+	}
+}
+
+// handleRecurring 列出周期任务及其下次执行时间与最近一次结果；启用登录时只列出属于当前用户的任务。
+// Path: GET /api/recurring
+func (s *Server) handleRecurring(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tasks := []recurringTask{}
+	for _, t := range s.recurring.snapshot() {
+		if s.canAccess(r, t.cfg.Owner) {
+			tasks = append(tasks, t)
+		}
+	}
+	writeJSON(w, map[string]any{"tasks": tasks})
+}
+
+// handleRecurringRun 立即执行一次周期任务，不影响下次定时执行。
+// Path: POST /api/recurring/{name}/run
+func (s *Server) handleRecurringRun(w http.ResponseWriter, r *http.Request) {
+	name, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/recurring/"), "/")
+	if action != "run" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	t := s.recurring.find(name)
+	if t == nil || !s.canAccess(r, t.cfg.Owner) {
+		http.Error(w, "recurring task not found", http.StatusNotFound)
+		return
+	}
+	if !s.triggerRecurring(t, time.Now()) {
+		http.Error(w, "task is still running", http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+	"auto_wechat_article_publisher/publisher"
+)
+
+// ConfigReloader 由 main 提供：Path 为配置文件，Load 重新读取配置（含命令行参数的覆盖），
+// Build 按配置读取提示词与写作风格并创建 Agent，current 为正在使用的 Agent；Build 须在全部步骤成功后
+// 才替换全局使用的提示词与风格，返回错误时不能改变任何正在使用的配置。
+type ConfigReloader struct {
+	Path  string
+	Load  func() (publisher.Config, error)
+	Build func(cfg publisher.Config, current *generator.Agent) (*generator.Agent, error)
+}
+
+// restartOnly 为启动时使用、重新加载不生效的配置项，修改后需要重启服务；重新加载时沿用原值。
+var restartOnly = map[string]bool{
+	"server_addr": true, "tls": true, "static_dir": true, "base_path": true, "auth": true, "cors": true,
+	"content_security_policy": true, "rate_limit": true, "shutdown_timeout": true, "session_db": true,
+	"sessions": true, "storage": true, "resumable_dir": true, "image": true, "series_dir": true, "calendar_path": true,
+	"publish_history_path": true, "schedule_path": true, "mass_send_path": true, "recurring": true, "feeds": true, "feed_state_path": true, "audit_log_path": true,
+	"bots": true, "mail": true, "analytics": true, "analytics_path": true,
+}
+
+// reloadResult 为一次重新加载的结果：Changed 为已生效的配置项，RestartRequired 为修改后需要重启的配置项。
+type reloadResult struct {
+	ReloadedAt      time.Time `json:"reloaded_at"`
+	Trigger         string    `json:"trigger"`
+	Changed         []string  `json:"changed"`
+	RestartRequired []string  `json:"restart_required"`
+}
+
+// agent 返回当前使用的 Agent。
+func (s *Server) agent() *generator.Agent {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.genAgent
+}
+
+// config 返回当前生效的配置。
+func (s *Server) config() publisher.Config {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.pubCfg
+}
+
+// SetReloader 启用重新加载配置（POST /api/admin/reload、SIGHUP 与 WatchConfig）。
+func (s *Server) SetReloader(r *ConfigReloader) {
+	s.reloader = r
+}
+
+// Reload 重新读取配置文件并替换 Agent 与配置，不中断服务、不丢失内存中的 session：
+// 模型、写作风格、公众号账号、群机器人通知、预算等立即生效（预算沿用当天已累计的用量），
+// 正在生成的 session 在本次生成结束后切换到新配置。配置无效时保留原配置并返回错误。
+func (s *Server) Reload(trigger string) (reloadResult, error) {
+	if s.reloader == nil {
+		return reloadResult{}, errors.New("config reload not enabled")
+	}
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	old := s.config()
+	cfg, err := s.reloader.Load()
+	if err != nil {
+		return reloadResult{}, err
+	}
+	res := reloadResult{ReloadedAt: time.Now(), Trigger: trigger, Changed: []string{}, RestartRequired: []string{}}
+	oldVal, newVal := reflect.ValueOf(old), reflect.ValueOf(&cfg).Elem()
+	for i := 0; i < newVal.NumField(); i++ {
+		name, _, _ := strings.Cut(newVal.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || reflect.DeepEqual(oldVal.Field(i).Interface(), newVal.Field(i).Interface()) {
+			continue
+		}
+		if restartOnly[name] {
+			res.RestartRequired = append(res.RestartRequired, name)
+			newVal.Field(i).Set(oldVal.Field(i))
+			continue
+		}
+		res.Changed = append(res.Changed, name)
+	}
+	agent, err := s.reloader.Build(cfg, s.agent())
+	if err != nil {
+		return reloadResult{}, err
+	}
+
+	s.cfgMu.Lock()
+	s.pubCfg, s.genAgent = cfg, agent
+	s.cfgMu.Unlock()
+	s.store.swapAgent(agent)
+	// 公众号账号可能已变更，下次发布时按新配置重新创建（进行中的发布继续使用原来的实例）。
+	s.pubMu.Lock()
+	s.pub = nil
+	s.pubMu.Unlock()
+
+	log.Printf("[config] reloaded (%s): changed=%v restart_required=%v", trigger, res.Changed, res.RestartRequired)
+	s.recordAudit(publisher.AuditEntry{User: trigger, Action: publisher.AuditConfigChanged, Target: "config",
+		Detail: fmt.Sprintf("reload: changed=%s restart_required=%s", strings.Join(res.Changed, ","), strings.Join(res.RestartRequired, ","))})
+	return res, nil
+}
+
+// swapAgent 让内存中的 session 与之后恢复的 session 使用新的 Agent；正在生成的 session 在结束时切换。
+func (s *sessionStore) swapAgent(agent *generator.Agent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agent = agent
+	for id, entry := range s.sessions {
+		if _, busy := s.inflight[id]; !busy {
+			entry.sess.SetAgent(agent)
+		}
+	}
+}
+
+// WatchConfig 每隔 interval 检查配置文件的修改时间，变化后自动重新加载，服务关闭时退出。
+func (s *Server) WatchConfig(interval time.Duration) {
+	if s.reloader == nil || s.reloader.Path == "" {
+		return
+	}
+	stat := func() time.Time {
+		fi, err := os.Stat(s.reloader.Path)
+		if err != nil {
+			return time.Time{}
+		}
+		return fi.ModTime()
+	}
+	last := stat()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		mod := stat()
+		if mod.IsZero() || mod.Equal(last) {
+			continue
+		}
+		last = mod
+		if _, err := s.Reload("watch"); err != nil {
+			log.Printf("[config] reload %s failed, keeping the previous config: %v", s.reloader.Path, err)
+		}
+	}
+}
+
+// handleAdminReload 立即重新加载配置文件；启用登录时只有管理员可以调用。
+// Path: POST /api/admin/reload
+func (s *Server) handleAdminReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.auth != nil && !isAdmin(r) {
+		http.Error(w, "admin only", http.StatusForbidden)
+		return
+	}
+	if s.reloader == nil {
+		http.Error(w, "config reload not enabled", http.StatusNotFound)
+		return
+	}
+	trigger := "api"
+	if user := sessionOwner(r); user != "" {
+		trigger = "api:" + user
+	}
+	res, err := s.Reload(trigger)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error(), "code": "invalid_config"})
+		return
+	}
+	writeJSON(w, res)
+}
@@ -0,0 +1,439 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+)
+
+// 分片上传：建议的分片大小、单个分片的上限与未完成上传的保留时间。
+const (
+	resumableChunkSize = 4 << 20
+	resumableMaxChunk  = 16 << 20
+	resumableTTL       = 24 * time.Hour
+)
+
+// defaultResumableDir 为默认的分片上传目录，不放在 uploads/ 下以免经 /uploads/ 对外提供。
+const defaultResumableDir = "resumable"
+
+// resumableStatus 为分片上传的状态；Offset 为已接收的字节数，客户端断线后据此续传。
+type resumableStatus struct {
+	ID        string    `json:"upload_id"`
+	SessionID string    `json:"session_id"`
+	Filename  string    `json:"filename"`
+	Usage     string    `json:"usage,omitempty"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	ChunkSize int64     `json:"chunk_size"`
+	Progress  float64   `json:"progress"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// resumableUpload 为一次进行中的分片上传。
+type resumableUpload struct {
+	resumableStatus
+	owner string
+	video bool
+	path  string
+	// mu 串行化同一上传的分片写入。
+	mu sync.Mutex
+}
+
+// resumableStore 保存进行中的分片上传（仅在内存中，服务重启后需重新上传）。
+// 每个服务实例的分片文件放在 root 下各自的 instance-* 目录中，多个实例共用 root 时互不影响。
+type resumableStore struct {
+	mu      sync.Mutex
+	dir     string
+	uploads map[string]*resumableUpload
+}
+
+func newResumableStore(root string) (*resumableStore, error) {
+	if root == "" {
+		root = defaultResumableDir
+	}
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return nil, fmt.Errorf("create resumable dir: %w", err)
+	}
+	cleanupResumableDirs(root, resumableTTL)
+	dir, err := os.MkdirTemp(root, "instance-")
+	if err != nil {
+		return nil, fmt.Errorf("create resumable dir: %w", err)
+	}
+	return &resumableStore{dir: dir, uploads: make(map[string]*resumableUpload)}, nil
+}
+
+// cleanupResumableDirs 删除已退出的实例遗留的分片文件：超过 maxAge 未写入的分片，以及随后变空的实例目录。
+// 其他仍在运行的实例的分片在有效期内会持续写入，不会被删除。
+func cleanupResumableDirs(root string, maxAge time.Duration) {
+	dirs, err := filepath.Glob(filepath.Join(root, "instance-*"))
+	if err != nil {
+		return
+	}
+	threshold := time.Now().Add(-maxAge)
+	for _, dir := range dirs {
+		// 删除分片会更新目录的修改时间，须先取得。
+		dirInfo, err := os.Stat(dir)
+		if err != nil {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		left := 0
+		for _, e := range entries {
+			info, err := e.Info()
+			if err == nil && info.ModTime().Before(threshold) && os.Remove(filepath.Join(dir, e.Name())) == nil {
+				continue
+			}
+			left++
+		}
+		if left == 0 && dirInfo.ModTime().Before(threshold) {
+			if os.Remove(dir) == nil {
+				log.Printf("[cleanup] removed stale resumable dir %s", dir)
+			}
+		}
+	}
+}
+
+// close 删除本实例的分片目录；服务退出后未完成的上传不能再续传。
+func (s *resumableStore) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uploads = make(map[string]*resumableUpload)
+	return os.RemoveAll(s.dir)
+}
+
+func (s *resumableStore) add(u *resumableUpload) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, old := range s.uploads {
+		if old.ExpiresAt.Before(now) {
+			_ = os.Remove(old.path)
+			delete(s.uploads, id)
+		}
+	}
+	s.uploads[u.ID] = u
+}
+
+func (s *resumableStore) get(id string) (*resumableUpload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[id]
+	if ok && u.ExpiresAt.Before(time.Now()) {
+		_ = os.Remove(u.path)
+		delete(s.uploads, id)
+		return nil, false
+	}
+	return u, ok
+}
+
+func (s *resumableStore) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.uploads[id]; ok {
+		_ = os.Remove(u.path)
+		delete(s.uploads, id)
+	}
+}
+
+// status 返回上传状态的快照（调用方持有 u.mu）。
+func (u *resumableUpload) status() resumableStatus {
+	st := u.resumableStatus
+	if u.Size > 0 {
+		st.Progress = float64(u.Offset*1000/u.Size) / 10
+	}
+	return st
+}
+
+type resumableCreateReq struct {
+	SessionID string `json:"session_id"`
+	Filename  string `json:"filename"`
+	Size      int64  `json:"size"`
+	Usage     string `json:"usage,omitempty"`
+}
+
+// handleResumable 处理分片上传。
+// Path: POST /api/uploads/resumable
+// Path: GET|PUT|DELETE /api/uploads/resumable/{id}
+// Path: POST /api/uploads/resumable/{id}/complete
+func (s *Server) handleResumable(w http.ResponseWriter, r *http.Request, rest string) {
+	if rest == "" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.createResumable(w, r)
+		return
+	}
+	id, action, _ := strings.Cut(rest, "/")
+	u, ok := s.resumables.get(id)
+	if !ok || !s.sessionAllowed(r, u.SessionID) || !s.canAccess(r, u.owner) {
+		http.Error(w, "upload not found or expired", http.StatusNotFound)
+		return
+	}
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		u.mu.Lock()
+		st := u.status()
+		u.mu.Unlock()
+		writeJSON(w, st)
+	case action == "" && r.Method == http.MethodPut:
+		s.writeChunk(w, r, u)
+	case action == "" && r.Method == http.MethodDelete:
+		s.resumables.remove(id)
+		w.WriteHeader(http.StatusNoContent)
+	case action == "complete" && r.Method == http.MethodPost:
+		s.completeResumable(w, r, u)
+	case action == "" || action == "complete":
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// createResumable 创建分片上传：按扩展名与总大小预先校验，返回 upload_id 与建议的分片大小。
+func (s *Server) createResumable(w http.ResponseWriter, r *http.Request) {
+	var req resumableCreateReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	req.SessionID = strings.TrimSpace(req.SessionID)
+	if req.SessionID == "" {
+		http.Error(w, "session_id required; generate draft first", http.StatusBadRequest)
+		return
+	}
+	sess, ok := s.store.get(req.SessionID)
+	if !ok || !s.sessionAllowed(r, req.SessionID) {
+		http.Error(w, "session not found or expired; regenerate draft", http.StatusNotFound)
+		return
+	}
+	if req.Size <= 0 {
+		http.Error(w, "size must be positive", http.StatusBadRequest)
+		return
+	}
+
+	limits := s.uploadLimits()
+	ext := strings.ToLower(filepath.Ext(req.Filename))
+	allowed := []string{".gif", ".jpeg", ".jpg", ".mp4", ".png"}
+	if !slices.Contains(allowed, ext) {
+		writeUploadError(w, &uploadError{
+			status: http.StatusUnsupportedMediaType, Code: uploadBadExtension, Allowed: allowed,
+			Error: fmt.Sprintf("file extension %q is not allowed", ext),
+		})
+		return
+	}
+	video := ext == ".mp4"
+	maxBytes := limits.maxBytes
+	if video {
+		maxBytes = limits.maxVideoBytes
+	}
+	if req.Size > maxBytes {
+		writeUploadError(w, &uploadError{
+			status: http.StatusRequestEntityTooLarge, Code: uploadTooLarge, MaxBytes: maxBytes,
+			Error: fmt.Sprintf("file exceeds %d MB", maxBytes>>20),
+		})
+		return
+	}
+
+	// 本实例的目录空闲过久时可能已被其他实例清理，按需重建。
+	if err := os.MkdirAll(s.resumables.dir, 0o700); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	id := newSessionID()
+	path := filepath.Join(s.resumables.dir, id+".part")
+	f, err := os.Create(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f.Close()
+	u := &resumableUpload{
+		resumableStatus: resumableStatus{
+			ID: id, SessionID: req.SessionID, Filename: req.Filename, Usage: strings.TrimSpace(req.Usage),
+			Size: req.Size, ChunkSize: resumableChunkSize, ExpiresAt: time.Now().Add(resumableTTL),
+		},
+		owner: sess.Owner, video: video, path: path,
+	}
+	s.resumables.add(u)
+	log.Printf("[upload] resumable %s created %q size=%d session=%s", id, req.Filename, req.Size, req.SessionID)
+
+	u.mu.Lock()
+	st := u.status()
+	u.mu.Unlock()
+	w.Header().Set("Location", s.basePath+"/api/uploads/resumable/"+id)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, st)
+}
+
+// writeChunk 在 offset 处写入一个分片；offset 必须等于已接收的字节数，否则返回 409 与当前状态。
+// 连接中断时保留已写入的部分，客户端查询状态后从新的 offset 继续。
+func (s *Server) writeChunk(w http.ResponseWriter, r *http.Request, u *resumableUpload) {
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil {
+		http.Error(w, "offset query parameter required", http.StatusBadRequest)
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if offset != u.Offset {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		writeJSON(w, u.status())
+		return
+	}
+	if r.ContentLength > resumableMaxChunk {
+		http.Error(w, fmt.Sprintf("chunk exceeds %d MB", resumableMaxChunk>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if r.ContentLength > u.Size-u.Offset {
+		http.Error(w, "chunk exceeds declared size", http.StatusBadRequest)
+		return
+	}
+
+	f, err := os.OpenFile(u.path, os.O_WRONLY, 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Seek(u.Offset, io.SeekStart); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	limit := min(u.Size-u.Offset, resumableMaxChunk)
+	n, err := io.Copy(f, io.LimitReader(r.Body, limit+1))
+	if n > limit {
+		// 超出的部分不计入，截断回已声明的范围。
+		_ = f.Truncate(u.Offset + limit)
+		http.Error(w, "chunk exceeds declared size or chunk limit", http.StatusBadRequest)
+		return
+	}
+	u.Offset += n
+	u.ExpiresAt = time.Now().Add(resumableTTL)
+	st := u.status()
+	s.events.publish(u.SessionID, eventUploadProgress, st)
+	if err != nil {
+		log.Printf("[upload] resumable %s interrupted at %d: %v", u.ID, u.Offset, err)
+		http.Error(w, "chunk interrupted: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, st)
+}
+
+// completeResumable 在全部分片到达后校验文件内容，移动到 session 的上传目录并返回与普通上传相同的结果。
+func (s *Server) completeResumable(w http.ResponseWriter, r *http.Request, u *resumableUpload) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.Offset != u.Size {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		writeJSON(w, u.status())
+		return
+	}
+	sess, ok := s.store.get(u.SessionID)
+	if !ok {
+		http.Error(w, "session not found or expired; regenerate draft", http.StatusNotFound)
+		return
+	}
+
+	f, err := os.Open(u.path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	limits := s.uploadLimits()
+	ext := ".mp4"
+	var uploadErr *uploadError
+	if u.video {
+		uploadErr = limits.checkVideo(f, u.Size)
+	} else {
+		ext, uploadErr = limits.check(f, u.Size, u.Filename)
+	}
+	f.Close()
+	if uploadErr != nil {
+		log.Printf("[upload] resumable %s rejected %q: %s", u.ID, u.Filename, uploadErr.Error)
+		s.resumables.remove(u.ID)
+		writeUploadError(w, uploadErr)
+		return
+	}
+
+	base := strings.TrimSuffix(sanitizeFilename(u.Filename), filepath.Ext(u.Filename))
+	if base == "" {
+		base = "upload"
+	}
+	dir, err := s.uploadDirFor(sess.Owner)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s_%d%s", base, time.Now().UnixNano(), ext))
+	if err := moveFile(u.path, path); err != nil {
+		http.Error(w, "save file: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.resumables.remove(u.ID)
+	if err := s.saveUpload(r.Context(), path); err != nil {
+		_ = os.Remove(path)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	s.store.addUpload(u.SessionID, path)
+	log.Printf("[upload] resumable %s completed -> %s", u.ID, path)
+
+	resp := uploadResp{
+		Path:     path,
+		URL:      s.uploadURL(path),
+		Filename: u.Filename,
+		Size:     u.Size,
+		Usage:    u.Usage,
+	}
+	if !u.video && u.Usage != "cover" {
+		caption := s.describeUpload(r.Context(), path)
+		resp.Alt = caption.Alt
+		resp.Caption = caption.Caption
+		sess.AddImage(generator.ImageRef{Path: path, Alt: caption.Alt, Caption: caption.Caption})
+	}
+	writeJSON(w, resp)
+}
+
+// moveFile 移动文件；跨文件系统（临时目录与上传目录不在同一分区）时改为复制后删除。
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		_ = os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
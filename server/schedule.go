@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// scheduleInterval 为检查到点定时发布的间隔。
+const scheduleInterval = 15 * time.Second
+
+// schedulePublish 保存已校验的发布请求，到点后由 runScheduler 提交到发布队列。
+func (s *Server) schedulePublish(w http.ResponseWriter, r *http.Request, req publishReq, owner string) {
+	at, err := publisher.ParseScheduleTime(req.ScheduleAt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	item, err := s.schedules.Add(publisher.ScheduledPublish{
+		SessionID:  req.SessionID,
+		User:       owner,
+		By:         currentUser(r),
+		Title:      req.Title,
+		Author:     req.Author,
+		Digest:     req.Digest,
+		CoverPath:  req.CoverPath,
+		AICover:    req.AICover,
+		Markdown:   req.Markdown,
+		ScheduleAt: at,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("[schedule] session=%s scheduled at %s id=%s", req.SessionID, at.Format(time.RFC3339), item.ID)
+	s.audit(r, publisher.AuditPublishRequested, req.SessionID, fmt.Sprintf("schedule %s at %s: %s", item.ID, at.Format(time.RFC3339), req.Title))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, item)
+}
+
+// runScheduler 定期把到点的定时发布提交到发布队列。启动时先把上次中断的条目标记为失败。
+func (s *Server) runScheduler(interval time.Duration) {
+	if n, err := s.schedules.FailInterrupted(); err != nil {
+		log.Printf("[schedule] recover failed: %v", err)
+	} else if n > 0 {
+		log.Printf("[schedule] marked %d interrupted publishes as failed", n)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.dispatchSchedules(time.Now())
+		select {
+		case <-ticker.C:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Server) dispatchSchedules(now time.Time) {
+	due, err := s.schedules.Claim(now)
+	if err != nil {
+		log.Printf("[schedule] claim failed: %v", err)
+		return
+	}
+	for _, item := range due {
+		item := item
+		job, err := s.jobs.enqueue(item.SessionID, item.User, item.By, func(ctx context.Context, job *publishJob) (publishResp, error) {
+			resp, err := s.runScheduled(ctx, job, item)
+			s.finishSchedule(item.ID, resp, err)
+			return resp, err
+		})
+		if err != nil {
+			s.finishSchedule(item.ID, publishResp{}, err)
+			continue
+		}
+		log.Printf("[schedule] id=%s dispatched as job=%s", item.ID, job.ID)
+		if _, err := s.schedules.Update(item.ID, func(it *publisher.ScheduledPublish) error {
+			it.JobID = job.ID
+			return nil
+		}); err != nil {
+			log.Printf("[schedule] id=%s save job id failed: %v", item.ID, err)
+		}
+	}
+}
+
+// runScheduled 执行一条定时发布：网页提交的按稿件快照发布，命令行添加的直接发布 Markdown 文件。
+func (s *Server) runScheduled(ctx context.Context, job *publishJob, item publisher.ScheduledPublish) (publishResp, error) {
+	if item.MarkdownPath != "" {
+		s.jobs.describe(job, item.Title, item.Digest)
+		return s.publishFile(ctx, job, item.SessionID, publisher.PublishParams{
+			MarkdownPath: item.MarkdownPath,
+			Title:        item.Title,
+			CoverPath:    item.CoverPath,
+			Author:       item.Author,
+			Digest:       item.Digest,
+		})
+	}
+	sess, _ := s.store.get(item.SessionID)
+	return s.runPublish(ctx, job, publishReq{
+		SessionID: item.SessionID,
+		CoverPath: item.CoverPath,
+		Author:    item.Author,
+		Title:     item.Title,
+		Digest:    item.Digest,
+		Markdown:  item.Markdown,
+		AICover:   item.AICover,
+	}, sess)
+}
+
+func (s *Server) finishSchedule(id string, resp publishResp, runErr error) {
+	_, err := s.schedules.Update(id, func(it *publisher.ScheduledPublish) error {
+		if runErr != nil {
+			it.Status, it.Error = publisher.ScheduleFailed, runErr.Error()
+			return nil
+		}
+		it.Status, it.MediaID = publisher.ScheduleDone, resp.MediaID
+		return nil
+	})
+	if err != nil {
+		log.Printf("[schedule] id=%s save result failed: %v", id, err)
+	}
+}
+
+// handleSchedules 列出定时发布，可按 status 过滤。
+// Path: GET /api/schedules?status=scheduled|running|done|failed|canceled
+func (s *Server) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	items, err := s.schedules.List(r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	kept := []publisher.ScheduledPublish{}
+	for _, it := range items {
+		if s.canAccess(r, it.User) || s.canAccess(r, it.By) {
+			kept = append(kept, it)
+		}
+	}
+	items = kept
+	writeJSON(w, map[string]any{"schedules": items})
+}
+
+// handleScheduleByID 取消尚未执行的定时发布。
+// Path: DELETE /api/schedules/{id}
+func (s *Server) handleScheduleByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/schedules/")
+	item, err := s.schedules.Cancel(id, func(it publisher.ScheduledPublish) bool { return s.canAccess(r, it.User) || s.canAccess(r, it.By) })
+	if err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, publisher.ErrScheduleNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	s.audit(r, publisher.AuditPublishCanceled, item.SessionID, "schedule "+item.ID)
+	writeJSON(w, item)
+}
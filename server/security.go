@@ -0,0 +1,132 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// defaultCSP 为 Web 界面的默认 Content-Security-Policy：脚本只允许同源；
+// 样式允许内联（React style 属性与预览 HTML）与 Google Fonts；预览中的图片允许外链。
+const defaultCSP = "default-src 'self'; script-src 'self'; " +
+	"style-src 'self' 'unsafe-inline' https://fonts.googleapis.com; font-src 'self' data: https://fonts.gstatic.com; " +
+	"img-src 'self' data: blob: https:; connect-src 'self'; " +
+	"object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+
+// uploadCSP 用于上传文件：禁止其中的脚本执行，避免上传的 SVG/HTML 被当作页面打开。
+const uploadCSP = "default-src 'none'; img-src 'self'; style-src 'unsafe-inline'; sandbox"
+
+// corsPolicy 为跨域访问规则；origins 为空且 any 为 false 时只允许同源。
+type corsPolicy struct {
+	any         bool
+	origins     map[string]bool
+	credentials bool
+	maxAge      int
+}
+
+// newCORSPolicy 按 cors 配置创建跨域规则，未配置时只允许同源；"*" 与 allow_credentials 同时设置时返回错误。
+func newCORSPolicy(cfg *publisher.CORSConfig) (*corsPolicy, error) {
+	if cfg == nil {
+		return &corsPolicy{}, nil
+	}
+	if err := publisher.ValidateCORS(cfg); err != nil {
+		return nil, err
+	}
+	p := &corsPolicy{origins: make(map[string]bool), credentials: cfg.AllowCredentials, maxAge: cfg.MaxAge}
+	if p.maxAge <= 0 {
+		p.maxAge = 600
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			p.any = true
+			continue
+		}
+		p.origins[normalizeOrigin(origin)] = true
+	}
+	return p, nil
+}
+
+// normalizeOrigin 去掉末尾的 / 并转为小写，便于与请求的 Origin 头比较。
+func normalizeOrigin(origin string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+}
+
+func (p *corsPolicy) allowed(origin string) bool {
+	return p.any || p.origins[normalizeOrigin(origin)]
+}
+
+// sameOrigin 判断 Origin 是否与请求的 Host 相同（部署在反向代理之后时需要转发 Host 头）。
+func sameOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// originAllowed 判断 WebSocket 握手的来源：非浏览器客户端（无 Origin）、同源或在 cors 白名单中。
+func (s *Server) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || sameOrigin(r, origin) || s.cors.allowed(origin)
+}
+
+// corsMiddleware 按 cors 配置回应跨域请求：只对白名单中的来源返回 Access-Control-Allow-Origin，
+// 允许携带凭据时回显具体来源；预检请求直接返回 204，不经过登录校验。
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		origin := r.Header.Get("Origin")
+		if origin != "" && s.cors.allowed(origin) {
+			if s.cors.any && !s.cors.credentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+				h.Add("Vary", "Origin")
+			}
+			if s.cors.credentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			h.Set("Access-Control-Expose-Headers", "Retry-After")
+			if r.Method == http.MethodOptions {
+				h.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+				h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+				if s.cors.maxAge > 0 {
+					h.Set("Access-Control-Max-Age", strconv.Itoa(s.cors.maxAge))
+				}
+			}
+		} else if origin != "" && len(s.cors.origins) > 0 {
+			h.Add("Vary", "Origin")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// securityHeaders 为所有响应添加 X-Content-Type-Options 与 Referrer-Policy（直接提供 HTTPS 时另加 HSTS），
+// 为 Web 界面添加 Content-Security-Policy 并禁止被嵌入 iframe，上传文件使用更严格的策略。
+func (s *Server) securityHeaders(next http.Handler) http.Handler {
+	csp := s.config().ContentSecurityPolicy
+	if csp == "" {
+		csp = defaultCSP
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		if r.TLS != nil {
+			h.Set("Strict-Transport-Security", "max-age=31536000")
+		}
+		switch p := r.URL.Path; {
+		case strings.HasPrefix(p, "/uploads/"):
+			h.Set("Content-Security-Policy", uploadCSP)
+		case !strings.HasPrefix(p, "/api/") && p != "/healthz" && p != "/readyz":
+			h.Set("Content-Security-Policy", csp)
+			h.Set("X-Frame-Options", "DENY")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
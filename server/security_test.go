@@ -0,0 +1,35 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+func TestCORSDefaultsToSameOrigin(t *testing.T) {
+	cors, err := newCORSPolicy(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{cors: cors}
+	h := s.corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("Access-Control-Allow-Origin = %q without cors config, want none", got)
+	}
+}
+
+func TestCORSRejectsWildcardWithCredentials(t *testing.T) {
+	_, err := newCORSPolicy(&publisher.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	if err == nil {
+		t.Fatal("want error for \"*\" with allow_credentials")
+	}
+	if _, err := newCORSPolicy(&publisher.CORSConfig{AllowedOrigins: []string{"*"}}); err != nil {
+		t.Fatalf("\"*\" without credentials: %v", err)
+	}
+}
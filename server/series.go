@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+)
+
+// handleSeries 列出或新建系列。
+// Path: GET/POST /api/series
+func (s *Server) handleSeries(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.series.List()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, list)
+	case http.MethodPost:
+		var req generator.Series
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		series := generator.Series{Title: req.Title, Description: req.Description}
+		series.MergeTerms(req.Terms)
+		saved, err := s.series.Save(series)
+		if err != nil {
+			writeSeriesError(w, err)
+			return
+		}
+		writeJSON(w, saved)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSeriesByID 查看、修改、删除系列，或追加已完成的篇目。
+// Path: GET/PUT/DELETE /api/series/{id}，POST /api/series/{id}/parts
+func (s *Server) handleSeriesByID(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/series/"), "/")
+	if id == "" {
+		http.NotFound(w, r)
+		return
+	}
+	switch action {
+	case "":
+	case "parts":
+		s.handleSeriesParts(w, r, id)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		series, err := s.series.Get(id)
+		if err != nil {
+			writeSeriesError(w, err)
+			return
+		}
+		writeJSON(w, series)
+	case http.MethodPut:
+		var req generator.Series
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// 篇目只能通过 parts 追加，这里只更新标题、定位与术语表。
+		saved, err := s.series.Update(id, func(series *generator.Series) error {
+			series.Title = req.Title
+			series.Description = req.Description
+			series.Terms = nil
+			series.MergeTerms(req.Terms)
+			return nil
+		})
+		if err != nil {
+			writeSeriesError(w, err)
+			return
+		}
+		writeJSON(w, saved)
+	case http.MethodDelete:
+		if err := s.series.Delete(id); err != nil {
+			writeSeriesError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+type seriesPartReq struct {
+	// SessionID 非空时由模型概括该 session 的当前稿件；否则直接使用 Title/Summary。
+	SessionID string                 `json:"session_id,omitempty"`
+	Title     string                 `json:"title,omitempty"`
+	Summary   string                 `json:"summary,omitempty"`
+	Terms     []generator.SeriesTerm `json:"terms,omitempty"`
+}
+
+// handleSeriesParts 把一篇已完成的文章追加到系列，供后续篇目引用。
+func (s *Server) handleSeriesParts(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req seriesPartReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := s.series.Get(id); err != nil {
+		writeSeriesError(w, err)
+		return
+	}
+
+	part := generator.SeriesPart{Title: strings.TrimSpace(req.Title), Summary: strings.TrimSpace(req.Summary)}
+	terms := req.Terms
+	if req.SessionID != "" {
+		sess, ok := s.store.get(req.SessionID)
+		if !ok || !s.sessionAllowed(r, req.SessionID) {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		defer cancel()
+		summarized, newTerms, err := sess.SummarizeForSeries(ctx)
+		if err != nil {
+			writeGenerateError(w, err)
+			return
+		}
+		switch {
+		case part.Title != "":
+			summarized.Title = part.Title
+		case summarized.Title == "":
+			summarized.Title = sess.Spec.Topic
+		}
+		part = summarized
+		terms = append(terms, newTerms...)
+	}
+	if part.Title == "" || part.Summary == "" {
+		http.Error(w, "title and summary are required without session_id", http.StatusBadRequest)
+		return
+	}
+
+	saved, err := s.series.Update(id, func(series *generator.Series) error {
+		series.AddPart(part, terms)
+		return nil
+	})
+	if err != nil {
+		writeSeriesError(w, err)
+		return
+	}
+	writeJSON(w, saved)
+}
+
+func writeSeriesError(w http.ResponseWriter, err error) {
+	if errors.Is(err, generator.ErrSeriesNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
@@ -1,7 +1,9 @@
 package server
 
 import (
+	"bufio"
 	"context"
+	"crypto/rand"
 	"embed"
 	"encoding/json"
 	"errors"
@@ -9,6 +11,7 @@ import (
 	"io"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -18,6 +21,7 @@ import (
 
 	"auto_wechat_article_publisher/generator"
 	"auto_wechat_article_publisher/publisher"
+	"auto_wechat_article_publisher/storage"
 )
 
 //go:embed web/dist web/dist/* web/dist/assets/*
@@ -29,8 +33,55 @@ type Server struct {
 	pub       *publisher.Publisher
 	pubMu     sync.Mutex
 	store     *sessionStore
-	staticFS  http.Handler
+	static    fs.FS
 	uploadDir string
+	imageGen  generator.ImageGenerator
+	events    *eventHub
+	streams   *streamRuns
+	series    *generator.SeriesStore
+	calendar  *generator.CalendarStore
+	publishes *publisher.PublishHistory
+	jobs      *jobQueue
+	schedules *publisher.ScheduleStore
+	massSends *publisher.MassSendStore
+	recurring *recurringRunner
+	feeds     *feedRunner
+	analytics *publisher.AnalyticsStore
+	// collector 为图文数据的定时采集，nil 表示未配置。
+	collector *analyticsCollector
+	// bots 为聊天机器人的状态，nil 表示未配置。
+	bots *botRunner
+	// mail 为邮件投稿的状态，nil 表示未配置。
+	mail *mailRunner
+	// auth 为登录配置，nil 表示未启用登录。
+	auth *authState
+	// startedAt 为启动时间，health 缓存 /readyz 的外部依赖检查结果。
+	startedAt time.Time
+	health    healthCache
+	// limits 为接口限流，nil 表示未启用。
+	limits *rateLimits
+	// cors 为跨域访问规则。
+	cors *corsPolicy
+	// basePath 为应用挂载的 URL 前缀（如 /wechat），空表示根路径。
+	basePath string
+	// files 为上传文件的持久存储，nil 表示只保存在本地 uploads/。
+	files storage.Store
+	// resumables 为进行中的分片上传。
+	resumables *resumableStore
+	// auditLog 为只追加的审计日志。
+	auditLog *publisher.AuditLog
+	// cfgMu 保护重新加载配置时替换的 genAgent 与 pubCfg，读取请使用 agent() 与 config()；
+	// reloader 为重新加载配置的方法（可空），reloadMu 保证同一时间只执行一次重新加载。
+	cfgMu    sync.RWMutex
+	reloader *ConfigReloader
+	reloadMu sync.Mutex
+	// stop 在关闭时关闭，通知定时与周期任务退出；tasks 跟踪执行中的周期任务与后台生成。
+	stop     chan struct{}
+	stopOnce sync.Once
+	tasks    sync.WaitGroup
+	// ctx 为服务生命周期的 context，关闭时取消，请求发起的后台生成以它为父 context。
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 type sessionStore struct {
@@ -39,18 +90,39 @@ type sessionStore struct {
 	ttl      time.Duration
 	ticker   *time.Ticker
 	done     chan struct{}
+	// backend 为持久化存储（可空）；agent 用于从快照恢复 session。
+	backend sessionBackend
+	agent   *generator.Agent
+	// onCleanup 在删除 session 的上传文件时调用（持锁），用于同步删除持久存储中的副本。
+	onCleanup func(paths []string)
+	// maxSessions 为内存中 session 的上限（0 不限制），evictLRU 为 false 时达到上限拒绝新建；
+	// gone 记录因过期或被淘汰而丢失的 session。
+	maxSessions int
+	evictLRU    bool
+	gone        map[string]goneSession
+	// inflight 记录正在执行修改的 session，执行期间不会因过期或淘汰移出内存。
+	inflight map[string]inflightOp
 }
 
 type sessionEntry struct {
 	sess      *generator.Session
 	expiresAt time.Time
 	uploads   []string
+	workflow  workflow
+	comments  []draftComment
+	updatedAt time.Time
+}
+
+// record 返回 entry 的持久化记录。
+func (e *sessionEntry) record() sessionRecord {
+	return sessionRecord{State: e.sess.State(), Uploads: e.uploads, Workflow: e.workflow, Comments: e.comments, UpdatedAt: e.updatedAt}
 }
 
 func newStore() *sessionStore {
 	return &sessionStore{
 		sessions: make(map[string]*sessionEntry),
-		ttl:      5 * time.Minute,
+		ttl:      defaultSessionTTL,
+		evictLRU: true,
 		done:     make(chan struct{}),
 	}
 }
@@ -81,14 +153,22 @@ func (s *sessionStore) stopJanitor() {
 func (s *sessionStore) set(id string, sess *generator.Session) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.sessions[id] = &sessionEntry{sess: sess, expiresAt: time.Now().Add(s.ttl)}
+	entry := &sessionEntry{sess: sess, expiresAt: time.Now().Add(s.ttl)}
+	if old, ok := s.sessions[id]; ok {
+		entry.uploads = old.uploads
+		entry.workflow = old.workflow
+		entry.comments = old.comments
+	}
+	s.sessions[id] = entry
+	s.persistLocked(entry)
+	s.evictLocked(id)
 }
 
 func (s *sessionStore) get(id string) (*generator.Session, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.purgeLocked()
-	entry, ok := s.sessions[id]
+	entry, ok := s.lookupLocked(id)
 	if !ok {
 		return nil, false
 	}
@@ -96,10 +176,92 @@ func (s *sessionStore) get(id string) (*generator.Session, bool) {
 	return entry.sess, true
 }
 
+// persist 记录 session 的更新时间并写入持久化存储；session 不在内存中时忽略。
+func (s *sessionStore) persist(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.sessions[id]; ok {
+		s.persistLocked(entry)
+	}
+}
+
+func (s *sessionStore) persistLocked(entry *sessionEntry) {
+	entry.updatedAt = time.Now()
+	if s.backend == nil {
+		return
+	}
+	rec := entry.record()
+	if err := s.backend.save(rec); err != nil {
+		log.Printf("[session] persist %s failed: %v", rec.State.ID, err)
+	}
+}
+
+// lookupLocked 先查内存，未命中时从持久化存储恢复。
+func (s *sessionStore) lookupLocked(id string) (*sessionEntry, bool) {
+	if entry, ok := s.sessions[id]; ok {
+		return entry, true
+	}
+	return s.loadLocked(id)
+}
+
+// loadLocked 从持久化存储恢复已移出内存的 session。
+func (s *sessionStore) loadLocked(id string) (*sessionEntry, bool) {
+	if s.backend == nil {
+		return nil, false
+	}
+	rec, ok, err := s.backend.load(id)
+	if err != nil {
+		log.Printf("[session] load %s failed: %v", id, err)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	entry := s.restoreLocked(rec)
+	s.evictLocked(id)
+	return entry, true
+}
+
+func (s *sessionStore) restoreLocked(rec sessionRecord) *sessionEntry {
+	entry := &sessionEntry{
+		sess:      generator.RestoreSession(rec.State, s.agent),
+		expiresAt: time.Now().Add(s.ttl),
+		uploads:   rec.Uploads,
+		workflow:  rec.Workflow,
+		comments:  rec.Comments,
+		updatedAt: rec.UpdatedAt,
+	}
+	s.sessions[rec.State.ID] = entry
+	return entry
+}
+
+// restoreAll 在启动时把持久化的 session 载入内存，返回仍被引用的上传文件。
+func (s *sessionStore) restoreAll() (map[string]bool, error) {
+	keep := map[string]bool{}
+	if s.backend == nil {
+		return keep, nil
+	}
+	recs, err := s.backend.list()
+	if err != nil {
+		return keep, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rec := range recs {
+		s.restoreLocked(rec)
+		for _, p := range rec.Uploads {
+			keep[filepath.Clean(p)] = true
+		}
+	}
+	s.evictLocked("")
+	log.Printf("[session] restored %d sessions", len(recs))
+	return keep, nil
+}
+
 func (s *sessionStore) getUploads(id string) []string {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	entry, ok := s.sessions[id]
+	entry, ok := s.lookupLocked(id)
 	if !ok {
 		return nil
 	}
@@ -110,7 +272,7 @@ func (s *sessionStore) heartbeat(id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.purgeLocked()
-	entry, ok := s.sessions[id]
+	entry, ok := s.lookupLocked(id)
 	if !ok {
 		return false
 	}
@@ -124,11 +286,12 @@ func (s *sessionStore) addUpload(id, path string) {
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	entry, ok := s.sessions[id]
+	entry, ok := s.lookupLocked(id)
 	if !ok {
 		return
 	}
 	entry.uploads = append(entry.uploads, path)
+	s.persistLocked(entry)
 }
 
 func (s *sessionStore) delete(id string) {
@@ -146,26 +309,38 @@ func (s *sessionStore) purgeExpired() {
 func (s *sessionStore) purgeLocked() {
 	now := time.Now()
 	for id, entry := range s.sessions {
-		if entry.expiresAt.Before(now) {
-			s.cleanupUploads(entry.uploads)
+		if _, busy := s.inflight[id]; entry.expiresAt.Before(now) && !busy {
+			// 已持久化的 session 只移出内存，再次访问时从存储恢复。
+			if s.backend == nil {
+				s.cleanupUploads(entry.uploads)
+				s.markGoneLocked(id, entry, goneExpired)
+			}
 			delete(s.sessions, id)
 		}
 	}
 }
 
 func (s *sessionStore) deleteLocked(id string) {
-	entry, ok := s.sessions[id]
+	entry, ok := s.lookupLocked(id)
 	if !ok {
 		return
 	}
 	s.cleanupUploads(entry.uploads)
 	delete(s.sessions, id)
+	if s.backend != nil {
+		if err := s.backend.delete(id); err != nil {
+			log.Printf("[session] delete %s failed: %v", id, err)
+		}
+	}
 }
 
 func (s *sessionStore) cleanupUploads(paths []string) {
 	for _, p := range paths {
 		_ = os.Remove(p)
 	}
+	if s.onCleanup != nil {
+		s.onCleanup(paths)
+	}
 }
 
 func New(genAgent *generator.Agent, pubCfg publisher.Config) (*Server, error) {
@@ -173,167 +348,1125 @@ func New(genAgent *generator.Agent, pubCfg publisher.Config) (*Server, error) {
 		return nil, errors.New("generator agent required")
 	}
 
+	recurring, err := newRecurringRunner(pubCfg.Recurring, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	feeds, err := newFeedRunner(pubCfg.Feeds, pubCfg.FeedStatePath, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	bots, err := newBotRunner(pubCfg.Bots)
+	if err != nil {
+		return nil, err
+	}
+	collector, err := newAnalyticsCollector(pubCfg.Analytics, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	mail, err := newMailRunner(pubCfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := newAuthState(pubCfg.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	cors, err := newCORSPolicy(pubCfg.CORS)
+	if err != nil {
+		return nil, err
+	}
+	files, err := newFileStore(pubCfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+
 	store := newStore()
-	store.startJanitor(1 * time.Minute)
+	store.agent = genAgent
+	if pubCfg.SessionDB != "" {
+		backend, err := openBoltBackend(pubCfg.SessionDB)
+		if err != nil {
+			return nil, err
+		}
+		store.backend = backend
+	}
+	janitor := store.applyConfig(pubCfg.Sessions)
+	keep, err := store.restoreAll()
+	if err != nil {
+		return nil, fmt.Errorf("restore sessions: %w", err)
+	}
+	store.startJanitor(janitor)
 
 	uploadDir := "uploads"
 	if err := os.MkdirAll(uploadDir, 0o755); err != nil {
 		return nil, fmt.Errorf("create upload dir: %w", err)
 	}
-	cleanupUploadsAll(uploadDir)
+	cleanupUploadsAll(uploadDir, keep)
 	cleanupTempDrafts(24 * time.Hour)
 
-	sub, err := fs.Sub(embeddedStatic, "web/dist")
+	static, err := staticRoot(pubCfg.StaticDir)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Server{
+	srv := &Server{
 		genAgent:  genAgent,
 		pubCfg:    pubCfg,
 		pub:       nil,
 		store:     store,
-		staticFS:  http.FileServer(http.FS(sub)),
+		static:    static,
 		uploadDir: uploadDir,
-	}, nil
+		events:    newEventHub(),
+		streams:   newStreamRuns(),
+		series:    generator.NewSeriesStore(pubCfg.SeriesDir),
+		calendar:  generator.NewCalendarStore(pubCfg.CalendarPath),
+		publishes: publisher.NewPublishHistory(pubCfg.PublishHistoryPath),
+		schedules: publisher.NewScheduleStore(pubCfg.SchedulePath),
+		massSends: publisher.NewMassSendStore(pubCfg.MassSendPath),
+		recurring: recurring,
+		feeds:     feeds,
+		analytics: publisher.NewAnalyticsStore(pubCfg.AnalyticsPath),
+		collector: collector,
+		bots:      bots,
+		mail:      mail,
+		auth:      auth,
+		startedAt: time.Now(),
+		limits:    newRateLimits(pubCfg.RateLimit),
+		cors:      cors,
+		basePath:  normalizeBasePath(pubCfg.BasePath),
+		files:     files,
+		stop:      make(chan struct{}),
+	}
+	srv.ctx, srv.cancel = context.WithCancel(context.Background())
+	store.mu.Lock()
+	store.onCleanup = srv.removeUploads
+	store.mu.Unlock()
+	srv.jobs = newJobQueue(srv.notifyJob)
+	if srv.resumables, err = newResumableStore(pubCfg.ResumableDir); err != nil {
+		return nil, err
+	}
+	srv.auditLog = publisher.NewAuditLog(pubCfg.AuditLogPath)
+	go srv.runScheduler(scheduleInterval)
+	if n, err := srv.massSends.FailInterrupted(); err != nil {
+		log.Printf("[masssend] recover failed: %v", err)
+	} else if n > 0 {
+		log.Printf("[masssend] marked %d interrupted mass sends as failed", n)
+	}
+	if len(recurring.tasks) > 0 {
+		go srv.runRecurring(recurringInterval)
+	}
+	if len(feeds.tasks) > 0 {
+		go srv.runFeeds(recurringInterval)
+	}
+	if pubCfg.Bots != nil && pubCfg.Bots.Telegram != nil {
+		go srv.runTelegram(newTelegramBot(*pubCfg.Bots.Telegram))
+	}
+	if collector != nil {
+		go srv.runAnalytics(recurringInterval)
+	}
+	if mail != nil {
+		go srv.runMail()
+	}
+	return srv, nil
 }
 
 func (s *Server) Routes() http.Handler {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/api/sessions", s.handleSessionCreate)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+	mux.HandleFunc("/api/login", s.handleLogin)
+	mux.HandleFunc("/api/logout", s.handleLogout)
+	mux.HandleFunc("/api/me", s.handleMe)
+	mux.HandleFunc("/api/sessions", s.handleSessions)
 	mux.HandleFunc("/api/sessions/", s.handleSessionByID)
 	mux.HandleFunc("/api/heartbeat/", s.handleHeartbeat)
 	mux.HandleFunc("/api/publish", s.handlePublish)
+	mux.HandleFunc("/api/publishes", s.handlePublishes)
+	mux.HandleFunc("/api/publishes/", s.handlePublishPreview)
+	mux.HandleFunc("/api/analytics", s.handleAnalytics)
+	mux.HandleFunc("/api/analytics/followers", s.handleAnalyticsFollowers)
+	mux.HandleFunc("/api/articles/", s.handleArticleComments)
+	mux.HandleFunc("/api/jobs/", s.handleJob)
+	mux.HandleFunc("/api/schedules", s.handleSchedules)
+	mux.HandleFunc("/api/masssend", s.handleMassSends)
+	mux.HandleFunc("/api/masssend/", s.handleMassSendByID)
+	mux.HandleFunc("/api/tags", s.handleTags)
+	mux.HandleFunc("/api/tags/", s.handleTagByID)
+	mux.HandleFunc("/api/schedules/", s.handleScheduleByID)
+	mux.HandleFunc("/api/recurring", s.handleRecurring)
+	mux.HandleFunc("/api/recurring/", s.handleRecurringRun)
+	mux.HandleFunc("/api/feeds", s.handleFeeds)
+	mux.HandleFunc("/api/feeds/", s.handleFeedByName)
+	mux.HandleFunc("/api/ingest", s.handleIngest)
+	mux.HandleFunc("/api/bots/wecom", s.handleWeComBot)
 	mux.HandleFunc("/api/uploads", s.handleUpload)
-	mux.Handle("/uploads/", http.StripPrefix("/uploads/", http.FileServer(http.Dir(s.uploadDir))))
+	mux.HandleFunc("/api/uploads/", s.handleUploadByName)
+	mux.HandleFunc("/api/ws", s.handleWS)
+	mux.HandleFunc("/api/styles", s.handleStyles)
+	mux.HandleFunc("/api/styles/", s.handleStyleByKey)
+	mux.HandleFunc("/api/series", s.handleSeries)
+	mux.HandleFunc("/api/series/", s.handleSeriesByID)
+	mux.HandleFunc("/api/ideas", s.handleIdeas)
+	mux.HandleFunc("/api/calendar", s.handleCalendar)
+	mux.HandleFunc("/api/calendar/", s.handleCalendarByID)
+	mux.HandleFunc("/api/admin/stats", s.handleAdminStats)
+	mux.HandleFunc("/api/admin/audit", s.handleAdminAudit)
+	mux.HandleFunc("/api/admin/reload", s.handleAdminReload)
+	mux.HandleFunc("/api/admin/quota", s.handleAdminQuota)
+	mux.HandleFunc("/api/admin/quota/clear", s.handleAdminQuota)
+	mux.HandleFunc("/api/openapi.json", s.handleOpenAPI)
+	mux.HandleFunc("/api/docs", s.handleAPIDocs)
+	mux.HandleFunc("/api/docs/init.js", s.handleAPIDocs)
+	mux.Handle("/uploads/", s.handleUploadFile())
 	mux.Handle("/", s.staticHandler())
-	return corsMiddleware(logMiddleware(mux))
+	return s.mountBasePath(s.securityHeaders(s.corsMiddleware(logMiddleware(s.authMiddleware(s.ownerMiddleware(s.rateLimitMiddleware(mux)))))))
 }
 
-func (s *Server) staticHandler() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// fall back to index.html for SPA-ish behavior
-		upath := r.URL.Path
-		if upath == "/" || !strings.HasPrefix(upath, "/api/") {
-			p := upath
-			if p == "/" {
-				p = "/index.html"
+// --- Handlers ---
+
+type sessionCreateReq struct {
+	Topic       string   `json:"topic"`
+	Outline     []string `json:"outline"`
+	Words       int      `json:"words"`
+	Constraints []string `json:"constraints"`
+	Style       string   `json:"style"`
+	// Audience/Tone/Taboo 分别为目标读者、语气与禁忌，作为独立小节写入系统提示词。
+	Audience string   `json:"audience,omitempty"`
+	Tone     string   `json:"tone,omitempty"`
+	Taboo    []string `json:"taboo,omitempty"`
+	// Sampling 覆盖本 session 的采样参数（temperature/top_p/max_tokens/penalties）。
+	Sampling *generator.SamplingParams `json:"sampling,omitempty"`
+	// Stream 为 true 时仅创建 session，稿件通过 POST /api/sessions/{id}/stream 开始流式生成，GET 同一路径读取输出。
+	Stream bool `json:"stream,omitempty"`
+	// Phase 为 "outline" 时只生成大纲，确认后调用 POST /api/sessions/{id}/expand 展开全文。
+	Phase string `json:"phase,omitempty"`
+	// Variants 大于 1 时生成多份不同切入角度的候选首稿，通过 POST /api/sessions/{id}/variants 选定。
+	Variants int `json:"variants,omitempty"`
+	// ReferenceURLs 为参考网页，抓取并摘要后作为写作素材。
+	ReferenceURLs []string `json:"reference_urls,omitempty"`
+	// Research 为 true 时先联网检索主题资料（需配置 search）；ResearchQuery 可覆盖检索词。
+	Research      bool   `json:"research,omitempty"`
+	ResearchQuery string `json:"research_query,omitempty"`
+	// Rewrite 为 true 时按所选风格改写原文（Source 为粘贴的 Markdown/纯文本，或用 SourceURL 抓取网页）。
+	Rewrite     bool   `json:"rewrite,omitempty"`
+	Source      string `json:"source,omitempty"`
+	SourceTitle string `json:"source_title,omitempty"`
+	SourceURL   string `json:"source_url,omitempty"`
+	// Translate 为 true 时把外文原文（同样使用 Source/SourceURL）翻译并本地化为中文稿件；SourceLang 可选，如 en。
+	Translate  bool   `json:"translate,omitempty"`
+	SourceLang string `json:"source_lang,omitempty"`
+	// SeriesID 指定所属系列，系列的术语与前文摘要会注入提示词。
+	SeriesID string `json:"series_id,omitempty"`
+	// IdeaID 为内容日历条目，Topic/Outline 为空时使用该选题的标题与建议大纲。
+	IdeaID string `json:"idea_id,omitempty"`
+}
+
+type sessionResp struct {
+	SessionID string             `json:"session_id"`
+	Draft     generator.Draft    `json:"draft"`
+	History   []generator.Turn   `json:"history"`
+	Outline   *generator.Outline `json:"outline,omitempty"`
+	Variants  []generator.Draft  `json:"variants,omitempty"`
+	// References 为参考链接的抓取/摘要结果，仅创建 session 时返回。
+	References []generator.Reference `json:"references,omitempty"`
+	// Research 为写作前联网检索到的资料，便于核对模型依据。
+	Research []generator.SearchResult `json:"research,omitempty"`
+	// Originality 为改写稿与原文的相似度报告，仅改写模式返回。
+	Originality *generator.OriginalityReport `json:"originality,omitempty"`
+	// HistorySummary 为早期修订意见压缩后的编辑历史摘要。
+	HistorySummary string `json:"history_summary,omitempty"`
+	// Generating 表示 session 上有生成或修订正在执行，此时新的修改请求返回 409。
+	Generating bool `json:"is_generating"`
+}
+
+type reviseReq struct {
+	Comment string `json:"comment"`
+}
+
+type publishReq struct {
+	SessionID string `json:"session_id"`
+	// CoverPath 为上传接口返回的路径，须位于 session 作者的上传目录下。
+	CoverPath string `json:"cover_path,omitempty"`
+	Author    string `json:"author,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	// Markdown 非空时覆盖并保存 session 的正文后发布；session 正在生成或修订时返回 409。
+	Markdown string `json:"markdown,omitempty"`
+	AICover  bool   `json:"ai_cover,omitempty"`
+	// ScheduleAt 非空时不立即发布，到点后再执行（RFC3339 或本地时间 YYYY-MM-DD HH:MM）。
+	ScheduleAt string `json:"schedule_at,omitempty"`
+	// UpdateMediaID 非空时更新该草稿的第 UpdateIndex 篇文章，不新建草稿，media_id 保持不变。
+	UpdateMediaID string `json:"update_media_id,omitempty"`
+	UpdateIndex   int    `json:"update_index,omitempty"`
+}
+
+type publishResp struct {
+	MediaID   string `json:"media_id"`
+	Title     string `json:"title"`
+	CoverPath string `json:"cover_path"`
+	// Updated 为 true 表示更新了已有草稿。
+	Updated bool `json:"updated,omitempty"`
+}
+
+func (s *Server) handleSessionCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req sessionCreateReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.ownerBinding() && sessionOwner(r) == "" {
+		http.Error(w, "X-API-Key header required", http.StatusUnauthorized)
+		return
+	}
+	var limitErr *sessionLimitError
+	if err := s.store.admit(); errors.As(err, &limitErr) {
+		writeSessionLimit(w, limitErr)
+		return
+	}
+	spec := generator.Spec{
+		Topic:       req.Topic,
+		Outline:     req.Outline,
+		Words:       req.Words,
+		Constraints: req.Constraints,
+		Style:       req.Style,
+		Audience:    strings.TrimSpace(req.Audience),
+		Tone:        strings.TrimSpace(req.Tone),
+		Taboo:       req.Taboo,
+		Sampling:    req.Sampling,
+	}
+	if req.SeriesID != "" {
+		series, err := s.series.Get(req.SeriesID)
+		if err != nil {
+			writeSeriesError(w, err)
+			return
+		}
+		spec.Series = &series
+	}
+	id := newSessionID()
+	defer func() {
+		if _, _, ok := s.store.access(id); ok {
+			s.audit(r, publisher.AuditSessionCreated, id, spec.Topic)
+		}
+	}()
+	if req.IdeaID != "" {
+		idea, err := s.calendar.Update(req.IdeaID, func(it *generator.Idea) error {
+			it.Status = generator.IdeaDrafting
+			it.SessionID = id
+			return nil
+		})
+		if err != nil {
+			writeCalendarError(w, err)
+			return
+		}
+		if spec.Topic == "" {
+			spec.Topic = idea.Title
+			if idea.Angle != "" {
+				spec.Topic += "（切入角度：" + idea.Angle + "）"
 			}
-			r.URL.Path = p
-			s.staticFS.ServeHTTP(w, r)
+		}
+		if len(spec.Outline) == 0 {
+			spec.Outline = idea.Outline
+		}
+	}
+	sess := generator.NewSession(id, spec, s.agent())
+	sess.Owner = sessionOwner(r)
+	var refs []generator.Reference
+	if len(req.ReferenceURLs) > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		loaded, err := sess.LoadReferences(ctx, req.ReferenceURLs)
+		cancel()
+		if err != nil {
+			var budgetErr *generator.BudgetExceededError
+			if errors.As(err, &budgetErr) {
+				writeGenerateError(w, err)
+			} else {
+				http.Error(w, "reference urls: "+err.Error(), http.StatusBadRequest)
+			}
+			return
+		}
+		refs = loaded
+	}
+	if req.Research || (s.config().Search != nil && s.config().Search.Auto) {
+		if !s.agent().HasSearch() {
+			http.Error(w, "search not configured; set search in config", http.StatusBadRequest)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		_, err := sess.Research(ctx, req.ResearchQuery)
+		cancel()
+		if err != nil {
+			// 检索失败不阻塞写作。
+			log.Printf("[research] session=%s failed: %v", id, err)
+		}
+	}
+	if req.Rewrite || req.Translate {
+		s.handleSourceCreate(w, r, sess, req, refs)
+		return
+	}
+	switch req.Phase {
+	case "", "draft":
+	case "outline":
+		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		defer cancel()
+		if _, err := sess.ProposeOutline(ctx); err != nil {
+			writeGenerateError(w, err)
+			return
+		}
+		s.store.set(id, sess)
+		writeJSON(w, sessionResp{SessionID: id, Draft: sess.Draft, History: sess.History, Outline: sess.Outline, References: refs, Research: sess.Spec.Research})
+		return
+	default:
+		http.Error(w, "unknown phase: "+req.Phase, http.StatusBadRequest)
+		return
+	}
+	if req.Variants > 1 {
+		if req.Variants > maxVariants {
+			http.Error(w, fmt.Sprintf("variants must be <= %d", maxVariants), http.StatusBadRequest)
+			return
+		}
+		// 第一份候选单独生成后其余才并行生成，需要约两次生成的时间。
+		ctx, cancel := context.WithTimeout(r.Context(), 180*time.Second)
+		defer cancel()
+		variants, err := sess.ProposeVariants(ctx, req.Variants)
+		if err != nil {
+			writeGenerateError(w, err)
+			return
+		}
+		s.store.set(id, sess)
+		writeJSON(w, sessionResp{SessionID: id, Draft: sess.Draft, History: sess.History, Variants: variants, References: refs, Research: sess.Spec.Research})
+		return
+	}
+	if req.Stream {
+		s.store.set(id, sess)
+		writeJSON(w, sessionResp{SessionID: id, Draft: sess.Draft, History: sess.History, References: refs, Research: sess.Spec.Research})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+	draft, err := sess.Propose(ctx)
+	if err != nil {
+		writeGenerateError(w, err)
+		return
+	}
+	s.store.set(id, sess)
+	s.events.publish(id, eventRevisionApplied, draft)
+	writeJSON(w, sessionResp{SessionID: id, Draft: draft, History: sess.History, References: refs, Research: sess.Spec.Research})
+}
+
+func (s *Server) handleSessionByID(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/sessions/")
+	id, action, _ := strings.Cut(id, "/")
+	if id == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if s.sessionGone(w, r, id) {
+		return
+	}
+	if !s.sessionAllowed(r, id) {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	switch action {
+	case "workflow", "submit", "approve", "reject":
+		s.handleWorkflow(w, r, id, action)
+		return
+	}
+	if action == "comments" || strings.HasPrefix(action, "comments/") {
+		s.handleComments(w, r, id, strings.TrimPrefix(strings.TrimPrefix(action, "comments"), "/"))
+		return
+	}
+	// 审核人与发布人只能查看他人的稿件，修改仍限于作者本人。
+	if r.Method != http.MethodGet && !s.ownsSession(r, id) {
+		http.Error(w, "only the author can edit this draft", http.StatusForbidden)
+		return
+	}
+	// 流式生成在后台进行，由 handleSessionStream 自行占用 session 并在结束后保存。
+	if action == "stream" {
+		s.handleSessionStream(w, r, id)
+		return
+	}
+	// 修改类请求同一时间只允许一个，结束后保存 session 的最新状态。
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		name := action
+		if name == "" {
+			name = "revise"
+		}
+		release := s.beginEdit(w, id, name)
+		if release == nil {
+			return
+		}
+		defer release()
+		if r.Method == http.MethodPost && revisionActions[action] {
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			w = rec
+			defer func() {
+				if rec.status < http.StatusBadRequest {
+					s.audit(r, publisher.AuditDraftRevised, id, name)
+				}
+			}()
+		}
+	}
+	if r.Method != http.MethodGet {
+		defer s.store.persist(id)
+	}
+
+	switch action {
+	case "":
+	case "cover":
+		s.handleSessionCover(w, r, id)
+		return
+	case "images/place":
+		s.handlePlaceImages(w, r, id)
+		return
+	case "expand":
+		s.handleSessionExpand(w, r, id)
+		return
+	case "sections":
+		s.handleSessionSections(w, r, id)
+		return
+	case "variants":
+		s.handleSessionVariants(w, r, id)
+		return
+	case "titles":
+		s.handleSessionTitles(w, r, id)
+		return
+	case "polish":
+		s.handleSessionPolish(w, r, id)
+		return
+	case "factcheck":
+		s.handleFactCheck(w, r, id)
+		return
+	case "research":
+		s.handleSessionResearch(w, r, id)
+		return
+	case "originality":
+		s.handleSessionOriginality(w, r, id)
+		return
+	case "sensitive":
+		s.handleSessionSensitive(w, r, id)
+		return
+	case "factcheck/apply":
+		s.handleApplyCitations(w, r, id)
+		return
+	case "titles/apply":
+		s.handleApplyTitle(w, r, id)
+		return
+	case "quotes":
+		s.handleSessionQuotes(w, r, id)
+		return
+	case "quotes/apply":
+		s.handleApplyQuote(w, r, id)
+		return
+	case "versions":
+		s.handleSessionVersions(w, r, id)
+		return
+	case "diff":
+		s.handleSessionDiff(w, r, id)
+		return
+	case "rollback":
+		s.handleSessionRollback(w, r, id)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		sess, ok := s.store.get(id)
+		if !ok {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, sessionResp{SessionID: id, Draft: sess.Draft, History: sess.History, Outline: sess.Outline, HistorySummary: sess.HistorySummary, Generating: s.store.generating(id)})
+	case http.MethodPost:
+		sess, ok := s.store.get(id)
+		if !ok {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		var req reviseReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		defer cancel()
+		s.events.publish(id, eventDraftStarted, map[string]string{"comment": req.Comment})
+		draft, err := sess.Revise(ctx, req.Comment)
+		if err != nil {
+			s.events.publish(id, eventError, err.Error())
+			writeGenerateError(w, err)
+			return
+		}
+		s.events.publish(id, eventRevisionApplied, draft)
+		writeJSON(w, sessionResp{SessionID: id, Draft: draft, History: sess.History, HistorySummary: sess.HistorySummary})
+	case http.MethodDelete:
+		s.store.delete(id)
+		s.audit(r, publisher.AuditSessionDeleted, id, "")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSourceCreate 以改写或翻译模式生成首稿：原文来自请求中的文本或抓取的网页。
+func (s *Server) handleSourceCreate(w http.ResponseWriter, r *http.Request, sess *generator.Session, req sessionCreateReq, refs []generator.Reference) {
+	if req.Rewrite && req.Translate {
+		http.Error(w, "rewrite and translate are mutually exclusive", http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 120*time.Second)
+	defer cancel()
+	src := generator.SourceDoc{Title: req.SourceTitle, URL: req.SourceURL, Text: req.Source, Lang: req.SourceLang}
+	if strings.TrimSpace(src.Text) == "" {
+		if req.SourceURL == "" {
+			http.Error(w, "rewrite/translate requires source or source_url", http.StatusBadRequest)
+			return
+		}
+		fetched, err := generator.FetchSource(ctx, req.SourceURL)
+		if err != nil {
+			http.Error(w, "source url: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if src.Title == "" {
+			src.Title = fetched.Title
+		}
+		src.Text = fetched.Text
+	}
+	if err := src.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var draft generator.Draft
+	var err error
+	if req.Translate {
+		draft, err = sess.Translate(ctx, src)
+	} else {
+		draft, err = sess.Rewrite(ctx, src)
+	}
+	if err != nil {
+		writeGenerateError(w, err)
+		return
+	}
+	s.store.set(sess.ID, sess)
+	s.events.publish(sess.ID, eventRevisionApplied, draft)
+	writeJSON(w, sessionResp{SessionID: sess.ID, Draft: draft, History: sess.History, References: refs, Research: sess.Spec.Research, Originality: sess.Originality})
+}
+
+// handleSessionOriginality 返回当前稿件与改写原文的相似度报告（稿件变化后重新计算）。
+// Path: GET /api/sessions/{id}/originality
+func (s *Server) handleSessionOriginality(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	report := sess.CheckOriginality()
+	if report == nil {
+		http.Error(w, "session is not in rewrite mode", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, report)
+}
+
+// handleSessionSensitive 查看当前稿件的敏感词命中（GET），或请模型改写命中的语句（POST）。
+// Path: GET/POST /api/sessions/{id}/sensitive
+func (s *Server) handleSessionSensitive(w http.ResponseWriter, r *http.Request, id string) {
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		hits := s.agent().ScanSensitive(sess.Draft.Markdown)
+		if hits == nil {
+			hits = []generator.SensitiveHit{}
+		}
+		writeJSON(w, map[string]any{"hits": hits})
+	case http.MethodPost:
+		if len(s.agent().ScanSensitive(sess.Draft.Markdown)) == 0 {
+			http.Error(w, "no sensitive words found", http.StatusBadRequest)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		defer cancel()
+		s.events.publish(id, eventDraftStarted, map[string]string{"comment": "敏感词改写"})
+		draft, err := sess.RephraseSensitive(ctx)
+		if err != nil {
+			s.events.publish(id, eventError, err.Error())
+			writeGenerateError(w, err)
+			return
+		}
+		s.events.publish(id, eventRevisionApplied, draft)
+		writeJSON(w, sessionResp{SessionID: id, Draft: draft, History: sess.History})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+type researchReq struct {
+	Query string `json:"query,omitempty"`
+}
+
+// handleSessionResearch 查看（GET）或重新执行（POST）写作前的联网检索，结果用于后续生成/修订。
+// Path: GET/POST /api/sessions/{id}/research
+func (s *Server) handleSessionResearch(w http.ResponseWriter, r *http.Request, id string) {
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, map[string]any{"results": sess.Spec.Research})
+	case http.MethodPost:
+		if !s.agent().HasSearch() {
+			http.Error(w, "search not configured; set search in config", http.StatusBadRequest)
+			return
+		}
+		var req researchReq
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+		results, err := sess.Research(ctx, req.Query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, map[string]any{"results": results})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleFactCheck 处理事实核查：GET 只返回保存的报告（缺失时 404，稿件已变化时 409），POST 重新核查。
+// Path: GET/POST /api/sessions/{id}/factcheck
+func (s *Server) handleFactCheck(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodGet {
+		switch {
+		case sess.FactCheck == nil:
+			http.Error(w, "no fact check report; POST to run one", http.StatusNotFound)
+		case !sess.FactCheckCurrent():
+			http.Error(w, "fact check report is outdated; POST to run it again", http.StatusConflict)
+		default:
+			writeJSON(w, sess.FactCheck)
+		}
+		return
+	}
+	if sess.Draft.Markdown == "" {
+		http.Error(w, "draft is empty; generate first", http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 90*time.Second)
+	defer cancel()
+	report, err := sess.RunFactCheck(ctx)
+	if err != nil {
+		writeGenerateError(w, err)
+		return
+	}
+	writeJSON(w, report)
+}
+
+// handleApplyCitations 把核查报告中的参考资料追加到稿件末尾。
+// Path: POST /api/sessions/{id}/factcheck/apply
+func (s *Server) handleApplyCitations(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	draft, err := sess.AppendCitations()
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+	s.events.publish(id, eventRevisionApplied, draft)
+	writeJSON(w, sessionResp{SessionID: id, Draft: draft, History: sess.History})
+}
+
+type polishReq struct {
+	Focus string `json:"focus,omitempty"`
+}
+
+// handleSessionPolish 润色当前稿件（不改结构与事实），在历史中记为“润色”。
+// Path: POST /api/sessions/{id}/polish
+func (s *Server) handleSessionPolish(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	var req polishReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+	s.events.publish(id, eventDraftStarted, map[string]string{"comment": "润色"})
+	draft, err := sess.Polish(ctx, strings.TrimSpace(req.Focus))
+	if err != nil {
+		s.events.publish(id, eventError, err.Error())
+		writeGenerateError(w, err)
+		return
+	}
+	s.events.publish(id, eventRevisionApplied, draft)
+	writeJSON(w, sessionResp{SessionID: id, Draft: draft, History: sess.History})
+}
+
+type titlesReq struct {
+	Count int  `json:"count,omitempty"`
+	Score bool `json:"score,omitempty"`
+}
+
+type applyTitleReq struct {
+	Title string `json:"title"`
+}
+
+// handleSessionTitles 生成 5～10 个备选标题；score=true 时按点击意愿/清晰度/长度评分排序。
+// Path: POST /api/sessions/{id}/titles
+func (s *Server) handleSessionTitles(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	var req titlesReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	switch {
+	case req.Count == 0:
+		req.Count = 5
+	case req.Count < 5 || req.Count > 10:
+		http.Error(w, "count must be between 5 and 10", http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+	titles, err := sess.SuggestTitles(ctx, req.Count, req.Score)
+	if err != nil {
+		writeGenerateError(w, err)
+		return
+	}
+	writeJSON(w, map[string]any{"titles": titles})
+}
+
+// handleApplyTitle 把选中的标题写入当前稿件。
+// Path: POST /api/sessions/{id}/titles/apply
+func (s *Server) handleApplyTitle(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	var req applyTitleReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	draft, err := sess.ApplyTitle(req.Title)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+	s.events.publish(id, eventRevisionApplied, draft)
+	writeJSON(w, sessionResp{SessionID: id, Draft: draft, History: sess.History})
+}
+
+type quotesReq struct {
+	Count int `json:"count,omitempty"`
+}
+
+type applyQuoteReq struct {
+	Index int `json:"index"`
+	// Target 为 digest（写入摘要）或 cover（作为封面文字生成封面）。
+	Target string `json:"target"`
+	// 以下封面样式字段仅在 target=cover 时使用。
+	coverGenReq
+}
+
+// handleSessionQuotes 返回最近一次提取的金句（GET），或从当前稿件提取 2～3 句金句（POST）。
+// Path: GET/POST /api/sessions/{id}/quotes
+func (s *Server) handleSessionQuotes(w http.ResponseWriter, r *http.Request, id string) {
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		quotes := sess.Quotes
+		if quotes == nil {
+			quotes = []generator.GoldenQuote{}
+		}
+		writeJSON(w, map[string]any{"quotes": quotes})
+	case http.MethodPost:
+		var req quotesReq
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		switch {
+		case req.Count == 0:
+			req.Count = 3
+		case req.Count < 2 || req.Count > 3:
+			http.Error(w, "count must be 2 or 3", http.StatusBadRequest)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		defer cancel()
+		quotes, err := sess.ExtractQuotes(ctx, req.Count)
+		if err != nil {
+			writeGenerateError(w, err)
+			return
+		}
+		writeJSON(w, map[string]any{"quotes": quotes})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleApplyQuote 把选中的金句写入摘要，或作为封面文字生成封面。
+// Path: POST /api/sessions/{id}/quotes/apply
+func (s *Server) handleApplyQuote(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	var req applyQuoteReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	quote, err := sess.Quote(req.Index)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch req.Target {
+	case generator.QuoteForDigest:
+		draft, err := sess.ApplyDigest(quote.Text)
+		if err != nil {
+			writeRevisionError(w, err)
 			return
 		}
-		http.NotFound(w, r)
-	})
-}
-
-// --- Handlers ---
-
-type sessionCreateReq struct {
-	Topic       string   `json:"topic"`
-	Outline     []string `json:"outline"`
-	Words       int      `json:"words"`
-	Constraints []string `json:"constraints"`
-	Style       string   `json:"style"`
+		s.events.publish(id, eventRevisionApplied, draft)
+		writeJSON(w, sessionResp{SessionID: id, Draft: draft, History: sess.History})
+	case generator.QuoteForCover:
+		s.writeCover(w, r, sess, quote.Text, req.coverGenReq)
+	default:
+		http.Error(w, `target must be "digest" or "cover"`, http.StatusBadRequest)
+	}
 }
 
-type sessionResp struct {
-	SessionID string           `json:"session_id"`
-	Draft     generator.Draft  `json:"draft"`
-	History   []generator.Turn `json:"history"`
-}
+// maxVariants 限制一次并行生成的候选数量。
+const maxVariants = 5
 
-type reviseReq struct {
-	Comment string `json:"comment"`
+type pickVariantReq struct {
+	Index int `json:"index"`
 }
 
-type publishReq struct {
-	SessionID string `json:"session_id"`
-	CoverPath string `json:"cover_path,omitempty"`
-	Author    string `json:"author,omitempty"`
-	Title     string `json:"title,omitempty"`
-	Digest    string `json:"digest,omitempty"`
-	Markdown  string `json:"markdown,omitempty"`
+// handleSessionVariants 返回候选首稿（GET），或选定其中一份作为当前稿件（POST，index 从 0 开始）。
+// Path: GET/POST /api/sessions/{id}/variants
+func (s *Server) handleSessionVariants(w http.ResponseWriter, r *http.Request, id string) {
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, sess.Variants)
+	case http.MethodPost:
+		var req pickVariantReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		draft, err := sess.PickVariant(req.Index)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.events.publish(id, eventRevisionApplied, draft)
+		writeJSON(w, sessionResp{SessionID: id, Draft: draft, History: sess.History, Variants: sess.Variants})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
 }
 
-type publishResp struct {
-	MediaID   string `json:"media_id"`
-	Title     string `json:"title"`
-	CoverPath string `json:"cover_path"`
+type expandReq struct {
+	// Outline 为用户编辑后的大纲；省略时使用 session 中已生成的大纲。
+	Outline *generator.Outline `json:"outline,omitempty"`
 }
 
-func (s *Server) handleSessionCreate(w http.ResponseWriter, r *http.Request) {
+// handleSessionExpand 按已确认的大纲生成全文。
+// Path: POST /api/sessions/{id}/expand
+func (s *Server) handleSessionExpand(w http.ResponseWriter, r *http.Request, id string) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	var req sessionCreateReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
 		return
 	}
-	spec := generator.Spec{
-		Topic:       req.Topic,
-		Outline:     req.Outline,
-		Words:       req.Words,
-		Constraints: req.Constraints,
-		Style:       req.Style,
+	var req expandReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
-	id := newSessionID()
-	sess := generator.NewSession(id, spec, s.genAgent)
-	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	if req.Outline == nil && sess.Outline == nil {
+		http.Error(w, "outline required; create session with phase=outline first", http.StatusBadRequest)
+		return
+	}
+	if req.Outline != nil {
+		if err := req.Outline.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 120*time.Second)
 	defer cancel()
-	draft, err := sess.Propose(ctx)
+	s.events.publish(id, eventDraftStarted, map[string]string{"comment": "按大纲展开"})
+	draft, err := sess.Expand(ctx, req.Outline)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadGateway)
+		s.events.publish(id, eventError, err.Error())
+		writeGenerateError(w, err)
 		return
 	}
-	s.store.set(id, sess)
-	writeJSON(w, sessionResp{SessionID: id, Draft: draft, History: sess.History})
+	s.events.publish(id, eventRevisionApplied, draft)
+	writeJSON(w, sessionResp{SessionID: id, Draft: draft, History: sess.History, Outline: sess.Outline})
 }
 
-func (s *Server) handleSessionByID(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/api/sessions/")
-	if id == "" {
-		http.NotFound(w, r)
+type sectionReq struct {
+	Heading string `json:"heading"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// handleSessionSections 列出稿件小节（GET），或只重写指定小节（POST），其余内容保持不变。
+// Path: GET/POST /api/sessions/{id}/sections
+func (s *Server) handleSessionSections(w http.ResponseWriter, r *http.Request, id string) {
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
 		return
 	}
-
 	switch r.Method {
 	case http.MethodGet:
-		sess, ok := s.store.get(id)
-		if !ok {
-			http.Error(w, "session not found", http.StatusNotFound)
-			return
-		}
-		writeJSON(w, sessionResp{SessionID: id, Draft: sess.Draft, History: sess.History})
+		writeJSON(w, generator.Sections(sess.Draft.Markdown))
 	case http.MethodPost:
-		sess, ok := s.store.get(id)
-		if !ok {
-			http.Error(w, "session not found", http.StatusNotFound)
+		var req sectionReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		var req reviseReq
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if _, err := generator.FindSection(sess.Draft.Markdown, req.Heading); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
 		defer cancel()
-		draft, err := sess.Revise(ctx, req.Comment)
+		s.events.publish(id, eventDraftStarted, map[string]string{"comment": "重写小节：" + req.Heading})
+		draft, err := sess.RegenerateSection(ctx, req.Heading, strings.TrimSpace(req.Comment))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadGateway)
+			s.events.publish(id, eventError, err.Error())
+			writeGenerateError(w, err)
 			return
 		}
-		writeJSON(w, sessionResp{SessionID: id, Draft: draft, History: sess.History})
-	case http.MethodDelete:
-		s.store.delete(id)
-		w.WriteHeader(http.StatusNoContent)
+		s.events.publish(id, eventRevisionApplied, draft)
+		writeJSON(w, sessionResp{SessionID: id, Draft: draft, History: sess.History, Outline: sess.Outline})
 	default:
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
+// handlePlaceImages 把 session 中已上传的正文配图交给模型插入稿件。
+// Path: POST /api/sessions/{id}/images/place
+func (s *Server) handlePlaceImages(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	if len(sess.Images()) == 0 {
+		http.Error(w, "no uploaded images to place", http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+	s.events.publish(id, eventDraftStarted, map[string]string{"comment": "插图"})
+	draft, err := sess.PlaceImages(ctx)
+	if err != nil {
+		s.events.publish(id, eventError, err.Error())
+		writeGenerateError(w, err)
+		return
+	}
+	s.events.publish(id, eventRevisionApplied, draft)
+	writeJSON(w, sessionResp{SessionID: id, Draft: draft, History: sess.History})
+}
+
 // handleHeartbeat extends a session's TTL; if not found returns 404.
 // Path: /api/heartbeat/{id}
 func (s *Server) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
@@ -346,6 +1479,13 @@ func (s *Server) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	if s.sessionGone(w, r, id) {
+		return
+	}
+	if !s.sessionAllowed(r, id) {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
 	if ok := s.store.heartbeat(id); !ok {
 		http.Error(w, "session not found", http.StatusNotFound)
 		return
@@ -353,6 +1493,8 @@ func (s *Server) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// handlePublish 校验发布请求后加入发布队列，立即返回 job_id；进度与结果见 GET /api/jobs/{id}。
+// Path: POST /api/publish
 func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -367,50 +1509,142 @@ func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "session_id required", http.StatusBadRequest)
 		return
 	}
+	req.UpdateMediaID = strings.TrimSpace(req.UpdateMediaID)
+	if req.UpdateMediaID != "" && req.ScheduleAt != "" {
+		http.Error(w, "update_media_id cannot be combined with schedule_at", http.StatusBadRequest)
+		return
+	}
+	if req.UpdateIndex < 0 || (req.UpdateIndex > 0 && req.UpdateMediaID == "") {
+		http.Error(w, "update_index requires update_media_id and must not be negative", http.StatusBadRequest)
+		return
+	}
+	if s.sessionGone(w, r, req.SessionID) {
+		return
+	}
 	sess, ok := s.store.get(req.SessionID)
-	if !ok {
+	if !ok || !s.sessionAllowed(r, req.SessionID) {
 		http.Error(w, "session not found", http.StatusNotFound)
 		return
 	}
-	if sess.Draft.Markdown == "" {
+	// 请求带 markdown 时发布该正文，命令行 --server 模式借此发布本地文件而不必先生成稿件。
+	markdown := sess.Draft.Markdown
+	if strings.TrimSpace(req.Markdown) != "" {
+		markdown = req.Markdown
+	}
+	if markdown == "" {
 		http.Error(w, "draft is empty; generate first", http.StatusBadRequest)
 		return
 	}
+	// 启用登录时只能发布审核通过且之后未改动的稿件。
+	if err := s.checkPublishable(r, req.SessionID, markdown); err != nil {
+		var wfErr *errWorkflow
+		if errors.As(err, &wfErr) {
+			http.Error(w, wfErr.msg, wfErr.status)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
+	// 覆盖正文与修订、流式生成一样须独占 session，并立即保存。
 	if strings.TrimSpace(req.Markdown) != "" {
+		release := s.beginEdit(w, req.SessionID, "publish")
+		if release == nil {
+			return
+		}
 		sess.Draft.Markdown = req.Markdown
+		s.store.persist(req.SessionID)
+		release()
 	}
-	uploads := s.store.getUploads(req.SessionID)
+	// 任务按提交时的稿件发布，排队期间的修订不影响本次发布。
+	req.Markdown = sess.Draft.Markdown
 
-	// Resolve cover path (required by WeChat). Use provided path or fallback to samples/cover.jpg if exists.
-	coverPath := strings.TrimSpace(req.CoverPath)
-	if coverPath == "" {
+	// Resolve cover path (required by WeChat). ai_cover=true generates one inside the job.
+	req.CoverPath = strings.TrimSpace(req.CoverPath)
+	if req.CoverPath == "" && !req.AICover {
 		http.Error(w, "cover_path required", http.StatusBadRequest)
 		return
 	}
-	if _, err := os.Stat(coverPath); err != nil {
-		http.Error(w, "cover_path not found: "+err.Error(), http.StatusBadRequest)
+	if req.CoverPath != "" {
+		p, err := s.ownUploadPath(sess.Owner, req.CoverPath)
+		if err != nil {
+			http.Error(w, "invalid cover_path: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.CoverPath = p
+		if err := s.fetchUpload(r.Context(), req.CoverPath); err != nil {
+			http.Error(w, "cover_path not found: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.CoverPath == "" && s.imageGen == nil {
+		http.Error(w, "ai_cover not available: no image generator configured; set image in config or pass cover_path", http.StatusNotImplemented)
 		return
 	}
 
-	title := strings.TrimSpace(req.Title)
-	if title == "" {
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
 		if sess.Draft.Title != "" {
-			title = sess.Draft.Title
+			req.Title = sess.Draft.Title
 		} else {
-			title = sess.Spec.Topic
+			req.Title = sess.Spec.Topic
+		}
+	}
+	req.Digest = strings.TrimSpace(req.Digest)
+	if req.Digest == "" {
+		req.Digest = sess.Draft.Digest
+	}
+
+	if req.ScheduleAt != "" {
+		s.schedulePublish(w, r, req, sess.Owner)
+		return
+	}
+
+	job, err := s.jobs.enqueue(req.SessionID, sess.Owner, currentUser(r), func(ctx context.Context, job *publishJob) (publishResp, error) {
+		return s.runPublish(ctx, job, req, sess)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	s.audit(r, publisher.AuditPublishRequested, req.SessionID, fmt.Sprintf("job %s: %s", job.ID, req.Title))
+	w.Header().Set("Location", s.basePath+"/api/jobs/"+job.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(w, job)
+}
+
+// runPublish 在发布队列中执行一次发布：按需生成 AI 封面、上传图片并创建草稿，记录发布结果。
+// sess 仅用于生成 AI 封面，定时发布时 session 可能已不存在（nil）。
+func (s *Server) runPublish(ctx context.Context, job *publishJob, req publishReq, sess *generator.Session) (publishResp, error) {
+	s.jobs.describe(job, req.Title, req.Digest)
+	coverPath := req.CoverPath
+	if coverPath == "" {
+		if sess == nil {
+			return publishResp{}, errors.New("ai cover failed: session not found")
 		}
+		s.jobs.update(job, "ai_cover", "生成 AI 封面")
+		coverCtx, cancel := context.WithTimeout(ctx, 120*time.Second)
+		path, err := s.generateAICover(coverCtx, req.SessionID, sess)
+		cancel()
+		if err != nil {
+			return publishResp{}, fmt.Errorf("ai cover failed: %w", err)
+		}
+		coverPath = path
 	}
-	digest := strings.TrimSpace(req.Digest)
-	if digest == "" {
-		digest = sess.Draft.Digest
+	// 容器重建后本地副本可能已不存在，先从持久存储取回封面与正文图片。
+	if err := s.fetchUpload(ctx, coverPath); err != nil {
+		return publishResp{}, fmt.Errorf("cover_path not found: %w", err)
 	}
 
-	mdText := stripLeadingH1(sess.Draft.Markdown)
-	for _, up := range uploads {
+	mdText := publisher.StripLeadingH1(req.Markdown)
+	for _, up := range s.store.getUploads(req.SessionID) {
 		if up == "" {
 			continue
 		}
+		if err := s.fetchUpload(ctx, up); err != nil {
+			log.Printf("[publish] upload %s unavailable: %v", up, err)
+		}
 		abs, err := filepath.Abs(up)
 		if err != nil {
 			continue
@@ -420,61 +1654,113 @@ func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
 
 	tmp, err := os.CreateTemp("", "draft-*.md")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return publishResp{}, err
 	}
 	defer os.Remove(tmp.Name())
 	if _, err := tmp.WriteString(mdText); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return publishResp{}, err
 	}
 	_ = tmp.Close()
 
+	return s.publishFile(ctx, job, req.SessionID, publisher.PublishParams{
+		MarkdownPath:  tmp.Name(),
+		Title:         req.Title,
+		CoverPath:     coverPath,
+		Author:        req.Author,
+		Digest:        req.Digest,
+		UpdateMediaID: req.UpdateMediaID,
+		UpdateIndex:   req.UpdateIndex,
+	})
+}
+
+// publishFile 发布 Markdown 文件，进度写入任务并推送给 session 的订阅者，结果写入发布记录。
+func (s *Server) publishFile(ctx context.Context, job *publishJob, sessionID string, params publisher.PublishParams) (publishResp, error) {
 	pub, err := s.ensurePublisher()
 	if err != nil {
-		http.Error(w, "publisher init failed: "+err.Error(), http.StatusBadGateway)
-		return
+		return publishResp{}, fmt.Errorf("publisher init failed: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
-	mediaID, err := pub.PublishDraft(ctx, publisher.PublishParams{
-		MarkdownPath: tmp.Name(),
-		Title:        title,
-		CoverPath:    coverPath,
-		Author:       req.Author,
-		Digest:       digest,
-	})
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadGateway)
-		return
+	params.Progress = func(stage string) {
+		s.jobs.update(job, stage, publishStageText[stage])
+		s.events.publish(sessionID, eventPublishProgress, map[string]string{"stage": stage})
 	}
-
-	writeJSON(w, publishResp{MediaID: mediaID, Title: title, CoverPath: coverPath})
-}
-
-// stripLeadingH1 removes the first top-level markdown heading (and a following blank line if present),
-// so that the content body doesn't repeat the title that will be provided separately to WeChat.
-func stripLeadingH1(md string) string {
-	lines := strings.Split(md, "\n")
-	if len(lines) == 0 {
-		return md
+	params.ImageProgress = func(done, total int) {
+		s.jobs.updateImages(job, done, total)
 	}
-	start := 0
-	if strings.HasPrefix(strings.TrimSpace(lines[0]), "# ") {
-		start = 1
-		if len(lines) > 1 && strings.TrimSpace(lines[1]) == "" {
-			start = 2
-		}
+	mediaID, err := pub.PublishDraft(ctx, params)
+	updated := params.UpdateMediaID != ""
+	s.recordPublish(publisher.PublishRecord{SessionID: sessionID, User: job.owner, Title: params.Title, MediaID: mediaID, CoverPath: params.CoverPath, Updated: updated}, err)
+	if err != nil {
+		s.events.publish(sessionID, eventError, err.Error())
+		return publishResp{}, err
+	}
+	if sessionID != "" {
+		s.markPublished(sessionID, job.by, mediaID)
 	}
-	return strings.Join(lines[start:], "\n")
+	return publishResp{MediaID: mediaID, Title: params.Title, CoverPath: params.CoverPath, Updated: updated}, nil
 }
 
 // --- Helpers ---
 
+// crockford 为 ULID 使用的 Crockford Base32 字母表。
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newSessionID 生成 ULID：48 位毫秒时间戳加 80 位 crypto/rand 随机数，按字典序即按创建时间排序，无法猜测。
 func newSessionID() string {
-	return strings.ReplaceAll(time.Now().Format("20060102T150405.000000000"), ".", "")
+	var b [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+	if _, err := rand.Read(b[6:]); err != nil {
+		panic(fmt.Sprintf("crypto/rand: %v", err))
+	}
+	// 128 位按 5 位一组编码为 26 个字符，首字符只占 3 位。
+	hi := uint64(b[0])<<56 | uint64(b[1])<<48 | uint64(b[2])<<40 | uint64(b[3])<<32 | uint64(b[4])<<24 | uint64(b[5])<<16 | uint64(b[6])<<8 | uint64(b[7])
+	lo := uint64(b[8])<<56 | uint64(b[9])<<48 | uint64(b[10])<<40 | uint64(b[11])<<32 | uint64(b[12])<<24 | uint64(b[13])<<16 | uint64(b[14])<<8 | uint64(b[15])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
+
+// writeGenerateError 把生成错误映射为 HTTP 响应：预算用尽或修订轮数达到上限返回 429，其余为 502。
+func writeGenerateError(w http.ResponseWriter, err error) {
+	var historyErr *generator.HistoryLimitError
+	if errors.As(err, &historyErr) {
+		writeHistoryLimit(w, historyErr)
+		return
+	}
+	var budgetErr *generator.BudgetExceededError
+	if errors.As(err, &budgetErr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"error":            budgetErr.Error(),
+			"scope":            budgetErr.Scope,
+			"remaining_tokens": budgetErr.RemainingTokens,
+			"remaining_cost":   budgetErr.RemainingCost,
+		})
+		return
+	}
+	var validationErr *generator.ValidationError
+	if errors.As(err, &validationErr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"error":    validationErr.Error(),
+			"problems": validationErr.Problems,
+		})
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadGateway)
 }
 
 func writeJSON(w http.ResponseWriter, v any) {
@@ -496,6 +1782,10 @@ func logMiddleware(next http.Handler) http.Handler {
 			// 正常心跳不打印，避免刷日志
 			return
 		}
+		if (path == "/healthz" || path == "/readyz") && rec.status == http.StatusOK {
+			// 探活请求成功时不打印
+			return
+		}
 		log.Printf("[HTTP] %s %s -> %d (%dB) in %v", r.Method, path, rec.status, rec.bytes, time.Since(start))
 	})
 }
@@ -506,21 +1796,6 @@ type statusRecorder struct {
 	bytes  int
 }
 
-// corsMiddleware enables simple CORS for API routes so browsers can preflight POST safely.
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,OPTIONS")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
-}
-
 func (r *statusRecorder) WriteHeader(statusCode int) {
 	r.status = statusCode
 	r.ResponseWriter.WriteHeader(statusCode)
@@ -532,13 +1807,30 @@ func (r *statusRecorder) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Hijack lets the WebSocket upgrader take over the connection.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijack not supported")
+	}
+	r.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+// Flush lets streaming handlers (SSE) push data through the recorder.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func (s *Server) ensurePublisher() (*publisher.Publisher, error) {
 	s.pubMu.Lock()
 	defer s.pubMu.Unlock()
 	if s.pub != nil {
 		return s.pub, nil
 	}
-	p, err := publisher.New(s.pubCfg, nil, false, log.Default())
+	p, err := publisher.New(s.config(), nil, false, log.Default())
 	if err != nil {
 		return nil, err
 	}
@@ -552,6 +1844,16 @@ type uploadResp struct {
 	Filename string `json:"filename"`
 	Size     int64  `json:"size"`
 	Usage    string `json:"usage,omitempty"`
+	Alt      string `json:"alt,omitempty"`
+	Caption  string `json:"caption,omitempty"`
+	// Width/Height 为图片尺寸，仅编辑结果返回。
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// 以下仅 usage=document 返回：转换得到并已写入 session 的稿件、保存的配图与因不符合上传规则而去掉的图片。
+	Title    string       `json:"title,omitempty"`
+	Markdown string       `json:"markdown,omitempty"`
+	Images   []uploadResp `json:"images,omitempty"`
+	Skipped  []string     `json:"skipped,omitempty"`
 }
 
 func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
@@ -559,7 +1861,15 @@ func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	limits := s.uploadLimits()
+	// 表单中除文件外只有少量字段，多留 1 MB 余量。
+	r.Body = http.MaxBytesReader(w, r.Body, limits.maxBytes+1<<20)
 	if err := r.ParseMultipartForm(25 << 20); err != nil { // 25 MB
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeUploadError(w, limits.tooLarge())
+			return
+		}
 		http.Error(w, "parse form: "+err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -570,7 +1880,11 @@ func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "session_id required; generate draft first", http.StatusBadRequest)
 		return
 	}
-	if _, ok := s.store.get(sessID); !ok {
+	if s.sessionGone(w, r, sessID) {
+		return
+	}
+	sess, ok := s.store.get(sessID)
+	if !ok || !s.sessionAllowed(r, sessID) {
 		http.Error(w, "session not found or expired; regenerate draft", http.StatusNotFound)
 		return
 	}
@@ -583,17 +1897,33 @@ func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 	defer file.Close()
 
 	usage := strings.TrimSpace(r.FormValue("usage"))
+	if usage == "document" {
+		s.handleDocumentUpload(w, r, sess, file, header, limits)
+		return
+	}
+	// 只接受 JPEG/PNG/GIF 图片，避免上传目录被用来存放经 /uploads/ 对外提供的任意文件。
+	ext, uploadErr := limits.check(file, header.Size, header.Filename)
+	if uploadErr != nil {
+		log.Printf("[upload] rejected %q session=%s: %s", header.Filename, sessID, uploadErr.Error)
+		writeUploadError(w, uploadErr)
+		return
+	}
+
 	orig := sanitizeFilename(header.Filename)
 	if orig == "" {
 		orig = "upload"
 	}
-	ext := filepath.Ext(orig)
-	base := strings.TrimSuffix(orig, ext)
+	base := strings.TrimSuffix(orig, filepath.Ext(orig))
 	if base == "" {
 		base = "upload"
 	}
+	dir, err := s.uploadDirFor(sess.Owner)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	filename := fmt.Sprintf("%s_%d%s", base, time.Now().UnixNano(), ext)
-	path := filepath.Join(s.uploadDir, filename)
+	path := filepath.Join(dir, filename)
 
 	dst, err := os.Create(path)
 	if err != nil {
@@ -603,22 +1933,55 @@ func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 	defer dst.Close()
 
 	n, err := io.Copy(dst, file)
+	if err == nil {
+		err = dst.Close()
+	}
 	if err != nil {
 		http.Error(w, "write file: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if err := s.saveUpload(r.Context(), path); err != nil {
+		_ = os.Remove(path)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if sessID != "" {
+		s.store.addUpload(sessID, path)
+	}
 
-	writeJSON(w, uploadResp{
+	resp := uploadResp{
 		Path:     path,
-		URL:      "/uploads/" + filename,
+		URL:      s.uploadURL(path),
 		Filename: header.Filename,
 		Size:     n,
 		Usage:    usage,
-	})
+	}
+	if usage != "cover" {
+		caption := s.describeUpload(r.Context(), path)
+		resp.Alt = caption.Alt
+		resp.Caption = caption.Caption
+		sess.AddImage(generator.ImageRef{Path: path, Alt: caption.Alt, Caption: caption.Caption})
+	}
+	writeJSON(w, resp)
+}
 
-	if sessID != "" {
-		s.store.addUpload(sessID, path)
+// describeUpload 调用视觉模型生成 alt/图注；失败时仅记录日志，不影响上传。
+func (s *Server) describeUpload(ctx context.Context, path string) generator.ImageCaption {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return generator.ImageCaption{}
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	caption, err := s.agent().DescribeImage(ctx, data, http.DetectContentType(data))
+	if err != nil {
+		if !errors.Is(err, generator.ErrVisionUnsupported) {
+			log.Printf("[upload] describe image %s failed: %v", path, err)
+		}
+		return generator.ImageCaption{}
 	}
+	return caption
 }
 
 func sanitizeFilename(name string) string {
@@ -629,7 +1992,8 @@ func sanitizeFilename(name string) string {
 }
 
 // cleanupUploadsOlderThan removes files in dir older than maxAge; best-effort.
-func cleanupUploadsAll(dir string) {
+// cleanupUploadsAll 删除上传目录（含各用户子目录）下的文件，keep 中仍被持久化 session 引用的文件除外。
+func cleanupUploadsAll(dir string, keep map[string]bool) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		log.Printf("[cleanup] read uploads dir failed: %v", err)
@@ -637,9 +2001,13 @@ func cleanupUploadsAll(dir string) {
 	}
 	for _, e := range entries {
 		if e.IsDir() {
+			cleanupUploadsAll(filepath.Join(dir, e.Name()), keep)
 			continue
 		}
 		fp := filepath.Join(dir, e.Name())
+		if keep[fp] {
+			continue
+		}
 		if err := os.Remove(fp); err == nil {
 			log.Printf("[cleanup] removed upload %s", fp)
 		}
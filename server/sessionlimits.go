@@ -0,0 +1,190 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+	"auto_wechat_article_publisher/publisher"
+)
+
+// session 数量与生命周期的默认值（见 publisher.SessionConfig）。
+const (
+	defaultSessionTTL      = 5 * time.Minute
+	defaultJanitorInterval = time.Minute
+	// goneTTL 为过期或被淘汰的 session 记录的保留时间，maxGoneSessions 为记录数上限。
+	goneTTL         = 24 * time.Hour
+	maxGoneSessions = 10000
+)
+
+// 未持久化的 session 丢失的原因。
+const (
+	goneExpired = "expired"
+	goneEvicted = "evicted"
+)
+
+// goneSession 记录因过期或被淘汰而丢失的 session，再次访问时返回 410 而不是 404。
+type goneSession struct {
+	reason string
+	owner  string
+	at     time.Time
+}
+
+// sessionLimitError 表示内存中的 session 已达 max_sessions 且策略为拒绝新建。
+type sessionLimitError struct {
+	max        int
+	retryAfter time.Duration
+}
+
+func (e *sessionLimitError) Error() string {
+	return fmt.Sprintf("too many active sessions (max %d); try again later", e.max)
+}
+
+// applyConfig 按 sessions 配置设置过期时间与数量上限，返回清理间隔。
+func (s *sessionStore) applyConfig(cfg *publisher.SessionConfig) time.Duration {
+	s.ttl = defaultSessionTTL
+	interval := defaultJanitorInterval
+	if cfg == nil {
+		return interval
+	}
+	if cfg.TTLMinutes > 0 {
+		s.ttl = time.Duration(cfg.TTLMinutes) * time.Minute
+	}
+	if cfg.JanitorSeconds > 0 {
+		interval = time.Duration(cfg.JanitorSeconds) * time.Second
+	}
+	s.maxSessions = cfg.MaxSessions
+	s.evictLRU = cfg.Eviction != "reject"
+	return interval
+}
+
+// admit 检查能否新建 session：未持久化、达到 max_sessions 且策略为 reject 时返回 *sessionLimitError，
+// 其余情况由 set 按最久未访问淘汰。
+func (s *sessionStore) admit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxSessions <= 0 || s.evictLRU || s.backend != nil {
+		return nil
+	}
+	s.purgeLocked()
+	if len(s.sessions) < s.maxSessions {
+		return nil
+	}
+	retry := s.ttl
+	for _, e := range s.sessions {
+		retry = min(retry, time.Until(e.expiresAt))
+	}
+	return &sessionLimitError{max: s.maxSessions, retryAfter: retry}
+}
+
+// evictLocked 在内存中的 session 超过 max_sessions 时移出最久未访问的 session（keep 除外）：
+// 已持久化的只移出内存，否则连同上传文件删除并记录为已淘汰。
+func (s *sessionStore) evictLocked(keep string) {
+	if s.maxSessions <= 0 || (!s.evictLRU && s.backend == nil) {
+		return
+	}
+	for len(s.sessions) > s.maxSessions {
+		var oldestID string
+		var oldest *sessionEntry
+		for id, e := range s.sessions {
+			if _, busy := s.inflight[id]; id != keep && !busy && (oldest == nil || e.expiresAt.Before(oldest.expiresAt)) {
+				oldestID, oldest = id, e
+			}
+		}
+		if oldest == nil {
+			return
+		}
+		if s.backend == nil {
+			s.cleanupUploads(oldest.uploads)
+			s.markGoneLocked(oldestID, oldest, goneEvicted)
+		}
+		delete(s.sessions, oldestID)
+		log.Printf("[session] evicted %s (max_sessions=%d)", oldestID, s.maxSessions)
+	}
+}
+
+// markGoneLocked 记录丢失的 session，并清理过旧的记录。
+func (s *sessionStore) markGoneLocked(id string, entry *sessionEntry, reason string) {
+	if s.gone == nil {
+		s.gone = make(map[string]goneSession)
+	}
+	now := time.Now()
+	if len(s.gone) >= maxGoneSessions {
+		for gid, g := range s.gone {
+			if now.Sub(g.at) > goneTTL || len(s.gone) >= maxGoneSessions {
+				delete(s.gone, gid)
+			}
+		}
+	}
+	s.gone[id] = goneSession{reason: reason, owner: entry.sess.Owner, at: now}
+}
+
+// goneReason 返回 session 丢失的原因；不存在记录或记录已过期时返回 false。
+func (s *sessionStore) goneReason(id string) (goneSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.gone[id]
+	if !ok || time.Since(g.at) > goneTTL {
+		return goneSession{}, false
+	}
+	return g, true
+}
+
+// sessionGone 在 session 已过期或被淘汰时返回 410 与原因并返回 true；启用登录时只对有权访问的用户说明原因。
+func (s *Server) sessionGone(w http.ResponseWriter, r *http.Request, id string) bool {
+	g, ok := s.store.goneReason(id)
+	if !ok || !s.canAccess(r, g.owner) {
+		return false
+	}
+	msg := fmt.Sprintf("session expired after %s without activity; drafts are kept only while the page sends heartbeats (configure session_db to keep them)", s.store.ttl)
+	if g.reason == goneEvicted {
+		msg = fmt.Sprintf("session was evicted to stay within max_sessions (%d); configure session_db to keep evicted drafts", s.store.maxSessions)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusGone)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error":      msg,
+		"code":       "session_" + g.reason,
+		"session_id": id,
+		"gone_at":    g.at,
+	})
+	return true
+}
+
+// writeSessionLimit 返回 429 与 Retry-After（最早一个 session 过期前的秒数）。
+func writeSessionLimit(w http.ResponseWriter, err *sessionLimitError) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(err.retryAfter.Seconds()+0.5))))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error":        err.Error(),
+		"code":         "too_many_sessions",
+		"max_sessions": err.max,
+	})
+}
+
+// writeHistoryLimit 在修订轮数达到 max_history 时返回 429。
+func writeHistoryLimit(w http.ResponseWriter, err *generator.HistoryLimitError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error":       err.Error(),
+		"code":        "history_limit",
+		"max_history": err.Max,
+	})
+}
+
+// writeRevisionError 处理不调用模型的修改（改标题、回滚等）的错误：修订轮数达到上限返回 429，其余为 400。
+func writeRevisionError(w http.ResponseWriter, err error) {
+	var historyErr *generator.HistoryLimitError
+	if errors.As(err, &historyErr) {
+		writeHistoryLimit(w, historyErr)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
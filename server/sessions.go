@@ -0,0 +1,133 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSessionPageSize = 20
+	maxSessionPageSize     = 100
+)
+
+// sessionSummary 为 session 列表中的一项。Archived 表示已移出内存、仅保存在持久化存储中。
+type sessionSummary struct {
+	ID        string    `json:"id"`
+	Topic     string    `json:"topic"`
+	Title     string    `json:"title,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Turns     int       `json:"turns"`
+	Archived  bool      `json:"archived,omitempty"`
+	Owner     string    `json:"owner,omitempty"`
+	Status    string    `json:"status"`
+}
+
+// list 返回内存中的 session；archived 为 true 时一并返回仅在持久化存储中的 session。
+func (s *sessionStore) list(archived bool) ([]sessionSummary, error) {
+	s.mu.Lock()
+	s.purgeLocked()
+	out := make([]sessionSummary, 0, len(s.sessions))
+	live := make(map[string]bool, len(s.sessions))
+	for id, entry := range s.sessions {
+		live[id] = true
+		out = append(out, sessionSummary{
+			ID:        id,
+			Topic:     entry.sess.Spec.Topic,
+			Title:     entry.sess.Draft.Title,
+			UpdatedAt: entry.updatedAt,
+			Turns:     len(entry.sess.History),
+			Owner:     entry.sess.Owner,
+			Status:    entry.workflow.status(),
+		})
+	}
+	s.mu.Unlock()
+
+	if archived && s.backend != nil {
+		recs, err := s.backend.list()
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range recs {
+			if live[rec.State.ID] {
+				continue
+			}
+			out = append(out, sessionSummary{
+				ID:        rec.State.ID,
+				Topic:     rec.State.Spec.Topic,
+				Title:     rec.State.Draft.Title,
+				UpdatedAt: rec.UpdatedAt,
+				Turns:     len(rec.State.History),
+				Archived:  true,
+				Owner:     rec.State.Owner,
+				Status:    rec.Workflow.status(),
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	return out, nil
+}
+
+// handleSessions 列出 session（GET）或新建 session（POST）。
+// Path: GET/POST /api/sessions
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		s.handleSessionList(w, r)
+		return
+	}
+	s.handleSessionCreate(w, r)
+}
+
+// handleSessionList 按更新时间倒序分页列出 session。
+// 启用登录时只列出当前用户的 session（管理员可见全部，审核人与发布人另可见已提交审核的 session）。
+// 查询参数：q 按主题或标题搜索，status 按审核状态过滤，page 从 1 开始，page_size 默认 20（最大 100），archived=true 包含已归档的 session。
+func (s *Server) handleSessionList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := queryInt(query.Get("page"), 1)
+	if err != nil || page < 1 {
+		http.Error(w, "invalid page", http.StatusBadRequest)
+		return
+	}
+	size, err := queryInt(query.Get("page_size"), defaultSessionPageSize)
+	if err != nil || size < 1 || size > maxSessionPageSize {
+		http.Error(w, "page_size must be between 1 and "+strconv.Itoa(maxSessionPageSize), http.StatusBadRequest)
+		return
+	}
+	archived, _ := strconv.ParseBool(query.Get("archived"))
+
+	all, err := s.store.list(archived)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	q := strings.ToLower(strings.TrimSpace(query.Get("q")))
+	status := query.Get("status")
+	kept := all[:0]
+	for _, it := range all {
+		if !s.canAccess(r, it.Owner) && !s.canReview(r, it.Status) {
+			continue
+		}
+		if status != "" && it.Status != status {
+			continue
+		}
+		if q == "" || strings.Contains(strings.ToLower(it.Topic), q) || strings.Contains(strings.ToLower(it.Title), q) {
+			kept = append(kept, it)
+		}
+	}
+	all = kept
+
+	items := []sessionSummary{}
+	if start := (page - 1) * size; start < len(all) {
+		items = all[start:min(start+size, len(all))]
+	}
+	writeJSON(w, map[string]any{"sessions": items, "total": len(all), "page": page, "page_size": size})
+}
+
+func queryInt(v string, def int) (int, error) {
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
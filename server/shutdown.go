@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// Shutdown 停止定时与周期任务的调度并取消进行中的流式生成，等待执行中的任务与发布队列完成；
+// ctx 结束时取消进行中的发布并返回 ctx.Err()。调用前应先停止 HTTP 服务接收新请求。
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		s.cancel()
+	})
+
+	tasksDone := make(chan struct{})
+	go func() {
+		s.tasks.Wait()
+		close(tasksDone)
+	}()
+	var errs []error
+	select {
+	case <-tasksDone:
+	case <-ctx.Done():
+		log.Printf("[shutdown] recurring tasks still running: %v", ctx.Err())
+		errs = append(errs, ctx.Err())
+	}
+	if err := s.jobs.drain(ctx); err != nil {
+		log.Printf("[shutdown] publish queue not drained: %v", err)
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Close 删除本实例的分片上传目录，停止 session 清理，把内存中的 session 写入持久化存储并关闭存储。应在 Shutdown 之后调用。
+func (s *Server) Close() error {
+	if err := s.resumables.close(); err != nil {
+		log.Printf("[shutdown] remove resumable dir failed: %v", err)
+	}
+	return s.store.close()
+}
+
+func (s *sessionStore) close() error {
+	s.stopJanitor()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.backend == nil {
+		return nil
+	}
+	// 保留原更新时间，避免关闭时改变 session 列表的排序。
+	for _, entry := range s.sessions {
+		rec := entry.record()
+		if err := s.backend.save(rec); err != nil {
+			log.Printf("[session] persist %s failed: %v", rec.State.ID, err)
+		}
+	}
+	err := s.backend.close()
+	s.backend = nil
+	return err
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// staticRoot 返回 Web 界面的文件：配置 static_dir 时从磁盘读取（便于前端开发时直接使用 npm run build 的输出），
+// 否则使用编译时嵌入的 web/dist。
+func staticRoot(dir string) (fs.FS, error) {
+	if dir == "" {
+		return fs.Sub(embeddedStatic, "web/dist")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "index.html")); err != nil {
+		return nil, fmt.Errorf("static_dir: %w", err)
+	}
+	log.Printf("[static] serving web UI from %s", dir)
+	return os.DirFS(dir), nil
+}
+
+// normalizeBasePath 把 base_path 规范为以 / 开头、不以 / 结尾的形式（如 /wechat），根路径返回空字符串。
+func normalizeBasePath(p string) string {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// mountBasePath 把整个应用挂载到 base_path 下：去掉前缀后交给 next，/wechat 重定向到 /wechat/；
+// /healthz 与 /readyz 同时保留在根路径，便于本机探活。
+func (s *Server) mountBasePath(next http.Handler) http.Handler {
+	if s.basePath == "" {
+		return next
+	}
+	stripped := http.StripPrefix(s.basePath, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		switch {
+		case p == s.basePath:
+			http.Redirect(w, r, s.basePath+"/", http.StatusMovedPermanently)
+		case strings.HasPrefix(p, s.basePath+"/"):
+			stripped.ServeHTTP(w, r)
+		case p == "/healthz" || p == "/readyz":
+			next.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func (s *Server) staticHandler() http.Handler {
+	files := http.FileServer(http.FS(s.static))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upath := r.URL.Path
+		if strings.HasPrefix(upath, "/api/") {
+			http.NotFound(w, r)
+			return
+		}
+		if upath == "/" || upath == "/index.html" {
+			s.serveIndex(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// serveIndex 返回 index.html；设置 base_path 时在 <head> 中注入 <base> 与 base-path meta，
+// 前端据此为接口与 WebSocket 地址加上前缀。
+func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request) {
+	data, err := fs.ReadFile(s.static, "index.html")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if s.basePath != "" {
+		p := html.EscapeString(s.basePath)
+		inject := []byte(`<head><base href="` + p + `/"><meta name="base-path" content="` + p + `">`)
+		data = bytes.Replace(data, []byte("<head>"), inject, 1)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, "index.html", time.Time{}, bytes.NewReader(data))
+}
@@ -0,0 +1,265 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+	"auto_wechat_article_publisher/publisher"
+)
+
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 366
+)
+
+// adminStats 为 GET /api/admin/stats 的响应：统计区间内的 session、生成与修订、发布、模型用量与微信接口错误。
+// Persistent 为 false 表示未配置 session_db，已过期的 session 不在统计之内。
+type adminStats struct {
+	Since             time.Time         `json:"since"`
+	Until             time.Time         `json:"until"`
+	Persistent        bool              `json:"persistent"`
+	Sessions          int               `json:"sessions_created"`
+	Drafts            int               `json:"drafts_generated"`
+	Revisions         int               `json:"revisions"`
+	RevisionsPerDraft float64           `json:"revisions_per_draft"`
+	Publishes         int               `json:"publishes"`
+	PublishFailures   int               `json:"publish_failures"`
+	Usage             statsUsage        `json:"usage"`
+	Days              []dayStats        `json:"days"`
+	Users             []userStats       `json:"users"`
+	WeChatErrors      []wechatErrorStat `json:"wechat_errors"`
+}
+
+// statsUsage 为模型用量；按 session 创建日期计入，Cost 需要配置 budget 的单价。
+type statsUsage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+func (u *statsUsage) add(v generator.Usage) {
+	u.PromptTokens += v.PromptTokens
+	u.CompletionTokens += v.CompletionTokens
+	u.TotalTokens += v.Tokens()
+	u.Cost += v.Cost
+}
+
+// dayStats 为某一天（本地时间）的统计。
+type dayStats struct {
+	Date            string `json:"date"`
+	Sessions        int    `json:"sessions_created"`
+	Drafts          int    `json:"drafts_generated"`
+	Revisions       int    `json:"revisions"`
+	Publishes       int    `json:"publishes"`
+	PublishFailures int    `json:"publish_failures"`
+	Tokens          int    `json:"tokens"`
+}
+
+// userStats 为某个用户的统计；未启用登录时用户为空。
+type userStats struct {
+	User      string     `json:"user"`
+	Sessions  int        `json:"sessions_created"`
+	Drafts    int        `json:"drafts_generated"`
+	Revisions int        `json:"revisions"`
+	Publishes int        `json:"publishes"`
+	Usage     statsUsage `json:"usage"`
+}
+
+// wechatErrorStat 按 errcode 汇总失败的发布；errcode 为 0 表示网络、封面等非微信接口原因。
+type wechatErrorStat struct {
+	ErrCode   int       `json:"errcode"`
+	Count     int       `json:"count"`
+	LastError string    `json:"last_error"`
+	LastAt    time.Time `json:"last_at"`
+}
+
+// records 返回全部 session 的快照：内存中的 session 加上仅在持久化存储中的 session。
+func (s *sessionStore) records() ([]sessionRecord, error) {
+	s.mu.Lock()
+	out := make([]sessionRecord, 0, len(s.sessions))
+	live := make(map[string]bool, len(s.sessions))
+	for id, entry := range s.sessions {
+		live[id] = true
+		out = append(out, entry.record())
+	}
+	s.mu.Unlock()
+	if s.backend == nil {
+		return out, nil
+	}
+	recs, err := s.backend.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range recs {
+		if !live[rec.State.ID] {
+			out = append(out, rec)
+		}
+	}
+	return out, nil
+}
+
+// handleAdminStats 汇总使用情况，供团队负责人查看；启用登录时只有管理员可以访问。
+// 查询参数：since/until（YYYY-MM-DD，until 包含当天），或 days（默认最近 30 天，最多 366）。
+// Path: GET /api/admin/stats
+func (s *Server) handleAdminStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.auth != nil && !isAdmin(r) {
+		http.Error(w, "admin only", http.StatusForbidden)
+		return
+	}
+	query := r.URL.Query()
+	days, err := queryInt(query.Get("days"), defaultStatsDays)
+	if err != nil || days < 1 || days > maxStatsDays {
+		http.Error(w, "days must be between 1 and "+strconv.Itoa(maxStatsDays), http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	until := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, 1)
+	if v := query.Get("until"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			http.Error(w, "invalid until: use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		until = t.AddDate(0, 0, 1)
+	}
+	since := until.AddDate(0, 0, -days)
+	if v := query.Get("since"); v != "" {
+		if since, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			http.Error(w, "invalid since: use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if !since.Before(until) || until.Sub(since) > maxStatsDays*24*time.Hour {
+		http.Error(w, "since must be before until and the range at most "+strconv.Itoa(maxStatsDays)+" days", http.StatusBadRequest)
+		return
+	}
+
+	recs, err := s.store.records()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	publishes, err := s.publishes.List(publisher.PublishFilter{Since: since, Until: until})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, buildStats(since, until, recs, publishes, s.store.backend != nil))
+}
+
+// buildStats 按天与用户汇总 session 与发布记录。
+func buildStats(since, until time.Time, recs []sessionRecord, publishes []publisher.PublishRecord, persistent bool) adminStats {
+	st := adminStats{Since: since, Until: until, Persistent: persistent}
+	dayIndex := make(map[string]int)
+	for d := since; d.Before(until); d = d.AddDate(0, 0, 1) {
+		dayIndex[d.Format("2006-01-02")] = len(st.Days)
+		st.Days = append(st.Days, dayStats{Date: d.Format("2006-01-02")})
+	}
+	inRange := func(t time.Time) (*dayStats, bool) {
+		if t.Before(since) || !t.Before(until) {
+			return nil, false
+		}
+		return &st.Days[dayIndex[t.In(time.Local).Format("2006-01-02")]], true
+	}
+	users := make(map[string]*userStats)
+	userOf := func(name string) *userStats {
+		u, ok := users[name]
+		if !ok {
+			u = &userStats{User: name}
+			users[name] = u
+		}
+		return u
+	}
+
+	for _, rec := range recs {
+		state := rec.State
+		created := rec.UpdatedAt
+		if len(state.History) > 0 {
+			created = state.History[0].CreatedAt
+		}
+		if day, ok := inRange(created); ok {
+			u := userOf(state.Owner)
+			st.Sessions++
+			day.Sessions++
+			u.Sessions++
+			st.Usage.add(state.Usage)
+			u.Usage.add(state.Usage)
+			day.Tokens += state.Usage.Tokens()
+		}
+		for _, turn := range state.History {
+			day, ok := inRange(turn.CreatedAt)
+			if !ok {
+				continue
+			}
+			u := userOf(state.Owner)
+			switch turn.Kind {
+			case generator.TurnInitial, generator.TurnRewrite, generator.TurnTranslate:
+				st.Drafts++
+				day.Drafts++
+				u.Drafts++
+			default:
+				st.Revisions++
+				day.Revisions++
+				u.Revisions++
+			}
+		}
+	}
+	if st.Drafts > 0 {
+		st.RevisionsPerDraft = float64(st.Revisions) / float64(st.Drafts)
+	}
+
+	codes := make(map[int]*wechatErrorStat)
+	for _, rec := range publishes {
+		day, ok := inRange(rec.CreatedAt)
+		if !ok {
+			continue
+		}
+		st.Publishes++
+		day.Publishes++
+		userOf(rec.User).Publishes++
+		if rec.Status != publisher.PublishFailed {
+			continue
+		}
+		st.PublishFailures++
+		day.PublishFailures++
+		e, ok := codes[rec.ErrCode]
+		if !ok {
+			e = &wechatErrorStat{ErrCode: rec.ErrCode}
+			codes[rec.ErrCode] = e
+		}
+		e.Count++
+		if rec.CreatedAt.After(e.LastAt) {
+			e.LastAt, e.LastError = rec.CreatedAt, rec.Error
+		}
+	}
+
+	st.Users = make([]userStats, 0, len(users))
+	for _, u := range users {
+		st.Users = append(st.Users, *u)
+	}
+	sort.Slice(st.Users, func(i, j int) bool {
+		if st.Users[i].Sessions != st.Users[j].Sessions {
+			return st.Users[i].Sessions > st.Users[j].Sessions
+		}
+		return st.Users[i].User < st.Users[j].User
+	})
+	st.WeChatErrors = make([]wechatErrorStat, 0, len(codes))
+	for _, e := range codes {
+		st.WeChatErrors = append(st.WeChatErrors, *e)
+	}
+	sort.Slice(st.WeChatErrors, func(i, j int) bool {
+		if st.WeChatErrors[i].Count != st.WeChatErrors[j].Count {
+			return st.WeChatErrors[i].Count > st.WeChatErrors[j].Count
+		}
+		return st.WeChatErrors[i].ErrCode < st.WeChatErrors[j].ErrCode
+	})
+	return st
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// streamRunKeep 为流式生成结束后保留输出的时间，供稍晚连接的 GET /stream 读取结果。
+const streamRunKeep = 10 * time.Minute
+
+// streamRun 为一次由 POST /stream 发起的流式生成；GET /stream 只读取它的输出，不会再次触发生成。
+type streamRun struct {
+	mu     sync.Mutex
+	text   strings.Builder
+	done   bool
+	result *sessionResp
+	err    string
+	// changed 在每次有新输出或结束时关闭并替换，用于唤醒读取方。
+	changed chan struct{}
+}
+
+func (run *streamRun) update(fn func()) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	fn()
+	close(run.changed)
+	run.changed = make(chan struct{})
+}
+
+// streamRuns 保存每个 session 最近一次流式生成。
+type streamRuns struct {
+	mu   sync.Mutex
+	runs map[string]*streamRun
+}
+
+func newStreamRuns() *streamRuns {
+	return &streamRuns{runs: make(map[string]*streamRun)}
+}
+
+// start 为 session 登记一次新的流式生成，替换之前的结果。
+func (r *streamRuns) start(id string) *streamRun {
+	run := &streamRun{changed: make(chan struct{})}
+	r.mu.Lock()
+	r.runs[id] = run
+	r.mu.Unlock()
+	return run
+}
+
+func (r *streamRuns) get(id string) *streamRun {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.runs[id]
+}
+
+// finish 结束 run 并在 streamRunKeep 后清理（期间没有新的生成替换它时）。
+func (r *streamRuns) finish(id string, run *streamRun, result *sessionResp, err error) {
+	run.update(func() {
+		run.done = true
+		run.result = result
+		if err != nil {
+			run.err = err.Error()
+		}
+	})
+	time.AfterFunc(streamRunKeep, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.runs[id] == run {
+			delete(r.runs, id)
+		}
+	})
+}
+
+// streamReq 为开始流式生成的参数；已有稿件时 comment 为修订意见，必填。
+type streamReq struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+// handleSessionStream 处理流式生成。
+// Path: POST /api/sessions/{id}/stream 开始生成首稿或修订稿，立即返回 202。
+// Path: GET /api/sessions/{id}/stream 以 Server-Sent Events 读取进行中（或刚结束）的生成：事件依次为 delta / done（或 error）。
+func (s *Server) handleSessionStream(w http.ResponseWriter, r *http.Request, id string) {
+	switch r.Method {
+	case http.MethodPost:
+		s.startSessionStream(w, r, id)
+	case http.MethodGet:
+		s.readSessionStream(w, r, id)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// startSessionStream 在后台生成首稿（尚无稿件时）或按 comment 修订，生成期间 session 不接受其他修改，
+// 结束后保存 session 并记录审计。服务关闭时取消生成，并在关闭存储前等待它结束。
+func (s *Server) startSessionStream(w http.ResponseWriter, r *http.Request, id string) {
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	var req streamReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if sess.Draft.Markdown != "" && comment == "" {
+		http.Error(w, "comment required for revision", http.StatusBadRequest)
+		return
+	}
+	release := s.beginEdit(w, id, "stream")
+	if release == nil {
+		return
+	}
+	run := s.streams.start(id)
+	user, remote := sessionOwner(r), s.remoteIP(r)
+	s.events.publish(id, eventDraftStarted, map[string]string{"comment": comment})
+
+	s.tasks.Add(1)
+	go func() {
+		defer s.tasks.Done()
+		defer release()
+		defer s.store.persist(id)
+		onChunk := func(chunk string) {
+			run.update(func() { run.text.WriteString(chunk) })
+			s.events.publish(id, eventToken, chunk)
+		}
+		ctx, cancel := context.WithTimeout(s.ctx, 120*time.Second)
+		defer cancel()
+		var err error
+		if sess.Draft.Markdown == "" {
+			_, err = sess.ProposeStream(ctx, onChunk)
+		} else {
+			_, err = sess.ReviseStream(ctx, comment, onChunk)
+		}
+		if err != nil {
+			s.events.publish(id, eventError, err.Error())
+			s.streams.finish(id, run, nil, err)
+			return
+		}
+		s.recordAudit(publisher.AuditEntry{User: user, Action: publisher.AuditDraftRevised, Target: id, Detail: "stream", RemoteAddr: remote})
+		s.events.publish(id, eventRevisionApplied, sess.Draft)
+		s.streams.finish(id, run, &sessionResp{SessionID: id, Draft: sess.Draft, History: sess.History, HistorySummary: sess.HistorySummary}, nil)
+	}()
+
+	w.Header().Set("Location", s.basePath+"/api/sessions/"+id+"/stream")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(w, map[string]string{"session_id": id, "status": "started"})
+}
+
+// readSessionStream 只读取生成的输出：先补发已生成的文本，再推送新的输出，结束时推送 done 或 error。
+func (s *Server) readSessionStream(w http.ResponseWriter, r *http.Request, id string) {
+	run := s.streams.get(id)
+	if run == nil {
+		http.Error(w, "no streaming generation for this session; POST to /stream to start one", http.StatusNotFound)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // 关闭 nginx 缓冲
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	sent := 0
+	for {
+		run.mu.Lock()
+		text := run.text.String()[sent:]
+		done, result, errMsg, changed := run.done, run.result, run.err, run.changed
+		run.mu.Unlock()
+		if text != "" {
+			sent += len(text)
+			writeSSE(w, "delta", map[string]string{"text": text})
+			flusher.Flush()
+		}
+		if done {
+			if errMsg != "" {
+				writeSSE(w, "error", map[string]string{"error": errMsg})
+			} else {
+				writeSSE(w, "done", result)
+			}
+			flusher.Flush()
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, event string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
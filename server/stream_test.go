@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+	"auto_wechat_article_publisher/publisher"
+)
+
+// gatedLLM 先输出标题，等 gate 关闭后再输出正文。
+type gatedLLM struct{ gate chan struct{} }
+
+func (l gatedLLM) Complete(ctx context.Context, p generator.Prompt) (string, error) {
+	return "# 标题\n\n正文", nil
+}
+
+func (l gatedLLM) Stream(ctx context.Context, p generator.Prompt, onChunk func(string)) (string, error) {
+	onChunk("# 标题\n\n")
+	select {
+	case <-l.gate:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	onChunk("正文")
+	return "# 标题\n\n正文", nil
+}
+
+// newTestServer 在临时目录中运行服务，审计日志与上传目录都不会写入包目录。
+func newTestServer(t *testing.T, llm generator.LLMClient) (*Server, *httptest.Server) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	agent, err := generator.NewAgent(llm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := New(agent, publisher.Config{
+		AuditLogPath: filepath.Join(dir, "audit.jsonl"),
+		ResumableDir: filepath.Join(dir, "resumable"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.Routes())
+	t.Cleanup(func() {
+		ts.Close()
+		srv.Shutdown(context.Background())
+		srv.Close()
+	})
+	return srv, ts
+}
+
+func TestSessionStreamReadOnlyGet(t *testing.T) {
+	gate := make(chan struct{})
+	srv, ts := newTestServer(t, gatedLLM{gate: gate})
+	srv.store.set("s1", generator.NewSession("s1", generator.Spec{Topic: "x"}, srv.genAgent))
+	url := ts.URL + "/api/sessions/s1/stream"
+
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("GET before start = %d, want 404", res.StatusCode)
+	}
+
+	res, err = http.Post(url, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusAccepted {
+		t.Fatalf("POST = %d, want 202", res.StatusCode)
+	}
+	res, err = http.Post(url, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("second POST = %d, want 409", res.StatusCode)
+	}
+	close(gate)
+
+	read := func() string {
+		res, err := http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		return string(b)
+	}
+	first := read()
+	if !strings.Contains(first, "event: done") || !strings.Contains(first, `正文`) {
+		t.Fatalf("stream = %q, want done with full draft", first)
+	}
+	if again := read(); again != first {
+		t.Fatalf("second GET = %q, want replay of %q", again, first)
+	}
+	sess, _ := srv.store.get("s1")
+	if n := len(sess.History); n != 1 {
+		t.Fatalf("history = %d entries, want 1", n)
+	}
+}
+
+func TestSessionStreamShutdown(t *testing.T) {
+	srv, ts := newTestServer(t, gatedLLM{gate: make(chan struct{})})
+	srv.store.set("s1", generator.NewSession("s1", generator.Spec{Topic: "x"}, srv.genAgent))
+
+	res, err := http.Post(ts.URL+"/api/sessions/s1/stream", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusAccepted {
+		t.Fatalf("POST = %d, want 202", res.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown = %v, want generation cancelled and awaited", err)
+	}
+	run := srv.streams.get("s1")
+	run.mu.Lock()
+	done, errMsg := run.done, run.err
+	run.mu.Unlock()
+	if !done || errMsg == "" {
+		t.Fatalf("run done=%v err=%q, want finished with cancellation error", done, errMsg)
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"auto_wechat_article_publisher/generator"
+	"auto_wechat_article_publisher/publisher"
+)
+
+// handleStyles 列出或新建写作风格。
+// Path: GET/POST /api/styles
+func (s *Server) handleStyles(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, generator.Styles())
+	case http.MethodPost:
+		var req generator.StylePreset
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if st, ok := generator.LookupStyle(strings.TrimSpace(req.Key)); ok && !st.Builtin {
+			http.Error(w, "style already exists", http.StatusConflict)
+			return
+		}
+		s.saveStyle(w, r, req)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleStyleByKey 更新或删除单个风格；修改内置风格会生成同 key 的自定义覆盖。
+// Path: GET/PUT/DELETE /api/styles/{key}
+func (s *Server) handleStyleByKey(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/api/styles/")
+	if key == "" {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		st, ok := generator.LookupStyle(key)
+		if !ok {
+			http.Error(w, "style not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, st)
+	case http.MethodPut:
+		var req generator.StylePreset
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Key = key
+		s.saveStyle(w, r, req)
+	case http.MethodDelete:
+		if err := generator.DeleteStyle(s.stylesDir(), key); err != nil {
+			switch {
+			case errors.Is(err, generator.ErrStyleNotFound):
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case errors.Is(err, generator.ErrBuiltinStyle):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		s.audit(r, publisher.AuditConfigChanged, "style:"+key, "delete style")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) saveStyle(w http.ResponseWriter, r *http.Request, st generator.StylePreset) {
+	saved, err := generator.SaveStyle(s.stylesDir(), st)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.audit(r, publisher.AuditConfigChanged, "style:"+saved.Key, "save style")
+	writeJSON(w, saved)
+}
+
+func (s *Server) stylesDir() string {
+	if s.config().StylesDir != "" {
+		return s.config().StylesDir
+	}
+	return generator.DefaultStylesDir
+}
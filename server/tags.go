@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+type tagReq struct {
+	Name string `json:"name"`
+}
+
+type tagUsersReq struct {
+	OpenIDs []string `json:"openids"`
+}
+
+// handleTags 列出或创建用户标签。列出需要审核人或发布人，创建需要发布人角色。
+// Path: GET /api/tags, POST /api/tags
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if currentUser(r) != "" && !s.hasRole(r, roleReviewer) && !s.hasRole(r, rolePublisher) {
+			http.Error(w, "reviewer or publisher role required", http.StatusForbidden)
+			return
+		}
+		p, err := s.ensurePublisher()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+		tags, err := p.ListTags(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, map[string]any{"tags": tags})
+	case http.MethodPost:
+		if !s.hasRole(r, rolePublisher) {
+			http.Error(w, "publisher role required", http.StatusForbidden)
+			return
+		}
+		var req tagReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p, err := s.ensurePublisher()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+		tag, err := p.CreateTag(ctx, req.Name)
+		if err != nil {
+			writeTagError(w, err)
+			return
+		}
+		s.audit(r, publisher.AuditTagChanged, "tag:"+strconv.Itoa(tag.ID), "create "+tag.Name)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, tag)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTagByID 修改、删除标签，或给粉丝打上、取消标签，均需要发布人角色并写入审计日志。
+// Path: PUT /api/tags/{id}, DELETE /api/tags/{id}, POST /api/tags/{id}/tag|untag
+func (s *Server) handleTagByID(w http.ResponseWriter, r *http.Request) {
+	ref, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/tags/"), "/")
+	id, err := strconv.Atoi(ref)
+	if err != nil || id < 0 {
+		http.Error(w, "invalid tag id", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case action == "" && (r.Method == http.MethodPut || r.Method == http.MethodDelete):
+	case (action == "tag" || action == "untag") && r.Method == http.MethodPost:
+	case action == "" || action == "tag" || action == "untag":
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if !s.hasRole(r, rolePublisher) {
+		http.Error(w, "publisher role required", http.StatusForbidden)
+		return
+	}
+	p, err := s.ensurePublisher()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	var detail string
+	switch {
+	case r.Method == http.MethodPut:
+		var req tagReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		detail, err = "rename to "+req.Name, p.RenameTag(ctx, id, req.Name)
+	case r.Method == http.MethodDelete:
+		detail, err = "delete", p.DeleteTag(ctx, id)
+	default:
+		var req tagUsersReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		detail, err = fmt.Sprintf("%s %d users", action, len(req.OpenIDs)), p.TagUsers(ctx, id, req.OpenIDs, action == "untag")
+	}
+	if err != nil {
+		writeTagError(w, err)
+		return
+	}
+	s.audit(r, publisher.AuditTagChanged, "tag:"+strconv.Itoa(id), detail)
+	if r.Method == http.MethodDelete {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, map[string]any{"id": id, "action": strings.Fields(detail)[0], "ok": true})
+}
+
+// writeTagError 把参数错误映射为 400，其余为微信接口错误 502。
+func writeTagError(w http.ResponseWriter, err error) {
+	if errors.Is(err, publisher.ErrInvalidTag) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadGateway)
+}
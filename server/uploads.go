@@ -0,0 +1,198 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// 上传限制的默认值（见 publisher.UploadConfig）。
+const (
+	defaultUploadMaxMB        = 10
+	defaultUploadMaxDimension = 8000
+	defaultUploadMaxVideoMB   = 200
+)
+
+// uploadExts 为允许上传的图片类型（按内容嗅探）及对应的扩展名，第一个为保存时的默认扩展名。
+var uploadExts = map[string][]string{
+	"image/jpeg": {".jpg", ".jpeg"},
+	"image/png":  {".png"},
+	"image/gif":  {".gif"},
+}
+
+// markupSniff 为文件开头不应出现的 HTML/SVG 片段，用于拒绝在图片文件头后拼接页面或脚本的文件。
+var markupSniff = [][]byte{
+	[]byte("<script"), []byte("<html"), []byte("<svg"), []byte("<!doctype"),
+	[]byte("<iframe"), []byte("<body"), []byte("<head"), []byte("<object"), []byte("<embed"),
+}
+
+// 上传校验失败的错误码。
+const (
+	uploadTooLarge       = "too_large"
+	uploadUnsupported    = "unsupported_type"
+	uploadBadExtension   = "bad_extension"
+	uploadMarkup         = "markup_content"
+	uploadInvalidImage   = "invalid_image"
+	uploadDimensionLimit = "dimensions_too_large"
+)
+
+// uploadError 为上传校验失败时的 JSON 响应，code 便于客户端区分原因，其余字段为相关的限制。
+type uploadError struct {
+	status    int
+	Error     string   `json:"error"`
+	Code      string   `json:"code"`
+	MaxBytes  int64    `json:"max_bytes,omitempty"`
+	MaxWidth  int      `json:"max_width,omitempty"`
+	MaxHeight int      `json:"max_height,omitempty"`
+	Allowed   []string `json:"allowed,omitempty"`
+}
+
+func writeUploadError(w http.ResponseWriter, e *uploadError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.status)
+	_ = json.NewEncoder(w).Encode(e)
+}
+
+// uploadLimits 为生效的上传限制。
+type uploadLimits struct {
+	maxBytes            int64
+	maxWidth, maxHeight int
+	// maxVideoBytes 为分片上传的 MP4 视频大小上限。
+	maxVideoBytes int64
+}
+
+func (s *Server) uploadLimits() uploadLimits {
+	l := uploadLimits{
+		maxBytes: defaultUploadMaxMB << 20, maxWidth: defaultUploadMaxDimension, maxHeight: defaultUploadMaxDimension,
+		maxVideoBytes: defaultUploadMaxVideoMB << 20,
+	}
+	if cfg := s.config().Uploads; cfg != nil {
+		if cfg.MaxVideoMB > 0 {
+			l.maxVideoBytes = int64(cfg.MaxVideoMB) << 20
+		}
+		if cfg.MaxSizeMB > 0 {
+			l.maxBytes = int64(cfg.MaxSizeMB) << 20
+		}
+		if cfg.MaxWidth > 0 {
+			l.maxWidth = cfg.MaxWidth
+		}
+		if cfg.MaxHeight > 0 {
+			l.maxHeight = cfg.MaxHeight
+		}
+	}
+	return l
+}
+
+func (l uploadLimits) tooLarge() *uploadError {
+	return &uploadError{
+		status: http.StatusRequestEntityTooLarge, Code: uploadTooLarge, MaxBytes: l.maxBytes,
+		Error: fmt.Sprintf("file exceeds %d MB", l.maxBytes>>20),
+	}
+}
+
+// check 校验上传的文件：大小、嗅探出的类型、扩展名、文件头中的 HTML/SVG 标记与像素尺寸，
+// 通过时返回保存使用的扩展名（与实际类型不符时改为该类型的扩展名）。读取后 file 回到开头。
+func (l uploadLimits) check(file io.ReadSeeker, size int64, filename string) (string, *uploadError) {
+	if size > l.maxBytes {
+		return "", l.tooLarge()
+	}
+	head := make([]byte, 1024)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", &uploadError{status: http.StatusBadRequest, Code: uploadInvalidImage, Error: "read file: " + err.Error()}
+	}
+	head = head[:n]
+
+	allowed := make([]string, 0, len(uploadExts))
+	for ct := range uploadExts {
+		allowed = append(allowed, ct)
+	}
+	slices.Sort(allowed)
+	ct := http.DetectContentType(head)
+	exts, ok := uploadExts[ct]
+	if !ok {
+		return "", &uploadError{
+			status: http.StatusUnsupportedMediaType, Code: uploadUnsupported, Allowed: allowed,
+			Error: fmt.Sprintf("unsupported file type %s; only JPEG, PNG and GIF images are accepted", ct),
+		}
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	var allowedExts []string
+	for _, t := range allowed {
+		allowedExts = append(allowedExts, uploadExts[t]...)
+	}
+	if ext != "" && !slices.Contains(allowedExts, ext) {
+		return "", &uploadError{
+			status: http.StatusUnsupportedMediaType, Code: uploadBadExtension, Allowed: allowedExts,
+			Error: fmt.Sprintf("file extension %s is not allowed", ext),
+		}
+	}
+	if !slices.Contains(exts, ext) {
+		ext = exts[0]
+	}
+	lower := bytes.ToLower(head)
+	for _, m := range markupSniff {
+		if bytes.Contains(lower, m) {
+			return "", &uploadError{status: http.StatusUnsupportedMediaType, Code: uploadMarkup, Error: "file contains HTML or SVG markup"}
+		}
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", &uploadError{status: http.StatusBadRequest, Code: uploadInvalidImage, Error: "read file: " + err.Error()}
+	}
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return "", &uploadError{status: http.StatusBadRequest, Code: uploadInvalidImage, Error: "not a valid image: " + err.Error()}
+	}
+	if cfg.Width > l.maxWidth || cfg.Height > l.maxHeight {
+		return "", &uploadError{
+			status: http.StatusBadRequest, Code: uploadDimensionLimit, MaxWidth: l.maxWidth, MaxHeight: l.maxHeight,
+			Error: fmt.Sprintf("image is %dx%d; max is %dx%d", cfg.Width, cfg.Height, l.maxWidth, l.maxHeight),
+		}
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", &uploadError{status: http.StatusBadRequest, Code: uploadInvalidImage, Error: "read file: " + err.Error()}
+	}
+	return ext, nil
+}
+
+// checkVideo 校验分片上传的视频：大小、嗅探出的类型（只接受 MP4）与文件头中的 HTML/SVG 标记。读取后 file 回到开头。
+func (l uploadLimits) checkVideo(file io.ReadSeeker, size int64) *uploadError {
+	if size > l.maxVideoBytes {
+		return &uploadError{
+			status: http.StatusRequestEntityTooLarge, Code: uploadTooLarge, MaxBytes: l.maxVideoBytes,
+			Error: fmt.Sprintf("video exceeds %d MB", l.maxVideoBytes>>20),
+		}
+	}
+	head := make([]byte, 1024)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return &uploadError{status: http.StatusBadRequest, Code: uploadInvalidImage, Error: "read file: " + err.Error()}
+	}
+	head = head[:n]
+	if ct := http.DetectContentType(head); ct != "video/mp4" {
+		return &uploadError{
+			status: http.StatusUnsupportedMediaType, Code: uploadUnsupported, Allowed: []string{"video/mp4"},
+			Error: fmt.Sprintf("unsupported file type %s; only MP4 videos are accepted", ct),
+		}
+	}
+	lower := bytes.ToLower(head)
+	for _, m := range markupSniff {
+		if bytes.Contains(lower, m) {
+			return &uploadError{status: http.StatusUnsupportedMediaType, Code: uploadMarkup, Error: "file contains HTML or SVG markup"}
+		}
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return &uploadError{status: http.StatusBadRequest, Code: uploadInvalidImage, Error: "read file: " + err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package server
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultUsersPath 为未配置 auth.users_path 时的用户文件。
+const DefaultUsersPath = "users.json"
+
+const (
+	passwordIterations = 210000
+	minPasswordLen     = 8
+)
+
+var (
+	// ErrUserNotFound 表示用户不存在。
+	ErrUserNotFound = errors.New("user not found")
+	// ErrBadCredentials 表示用户名或密码错误。
+	ErrBadCredentials = errors.New("invalid username or password")
+
+	// 用户名会作为上传子目录名，只允许字母、数字、下划线与短横线。
+	userNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+)
+
+// User 为一个登录账号。Admin 可查看与管理所有人的 session、发布记录与定时发布，并拥有全部角色；
+// Roles 为审核流程中的角色（writer/reviewer/publisher），为空时视为 writer。
+type User struct {
+	Name         string    `json:"name"`
+	PasswordHash string    `json:"password_hash"`
+	Admin        bool      `json:"admin,omitempty"`
+	Roles        []string  `json:"roles,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (u User) roles() []string {
+	if len(u.Roles) == 0 {
+		return []string{roleWriter}
+	}
+	return u.Roles
+}
+
+// UserStore 把用户保存为单个 JSON 文件，服务端与 user 子命令共用。
+type UserStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewUserStore 创建用户存储，文件不存在时视为没有用户。
+func NewUserStore(path string) *UserStore {
+	if path == "" {
+		path = DefaultUsersPath
+	}
+	return &UserStore{path: path}
+}
+
+// Add 新建用户；roles 为空时默认为 writer。
+func (s *UserStore) Add(name, password string, admin bool, roles []string) (User, error) {
+	if !userNameRe.MatchString(name) {
+		return User{}, errors.New("username must be 1-32 letters, digits, '_' or '-'")
+	}
+	roles, err := normalizeRoles(roles)
+	if err != nil {
+		return User{}, err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return User{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users, err := s.readLocked()
+	if err != nil {
+		return User{}, err
+	}
+	for _, u := range users {
+		if u.Name == name {
+			return User{}, fmt.Errorf("user %s already exists", name)
+		}
+	}
+	u := User{Name: name, PasswordHash: hash, Admin: admin, Roles: roles, CreatedAt: time.Now()}
+	return u, s.writeLocked(append(users, u))
+}
+
+// SetPassword 修改用户密码。
+func (s *UserStore) SetPassword(name, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	return s.update(name, func(u *User) { u.PasswordHash = hash })
+}
+
+// SetAdmin 修改用户的管理员权限。
+func (s *UserStore) SetAdmin(name string, admin bool) error {
+	return s.update(name, func(u *User) { u.Admin = admin })
+}
+
+// SetRoles 修改用户的审核流程角色。
+func (s *UserStore) SetRoles(name string, roles []string) error {
+	roles, err := normalizeRoles(roles)
+	if err != nil {
+		return err
+	}
+	return s.update(name, func(u *User) { u.Roles = roles })
+}
+
+// Delete 删除用户；其 session 与发布记录保留，只有管理员可见。
+func (s *UserStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users, err := s.readLocked()
+	if err != nil {
+		return err
+	}
+	for i, u := range users {
+		if u.Name == name {
+			return s.writeLocked(append(users[:i], users[i+1:]...))
+		}
+	}
+	return ErrUserNotFound
+}
+
+// Get 返回用户。
+func (s *UserStore) Get(name string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users, err := s.readLocked()
+	if err != nil {
+		return User{}, err
+	}
+	for _, u := range users {
+		if u.Name == name {
+			return u, nil
+		}
+	}
+	return User{}, ErrUserNotFound
+}
+
+// List 按用户名返回所有用户。
+func (s *UserStore) List() ([]User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readLocked()
+}
+
+// Verify 校验用户名与密码，失败时统一返回 ErrBadCredentials。
+func (s *UserStore) Verify(name, password string) (User, error) {
+	u, err := s.Get(name)
+	if errors.Is(err, ErrUserNotFound) {
+		return User{}, ErrBadCredentials
+	}
+	if err != nil {
+		return User{}, err
+	}
+	if !checkPassword(u.PasswordHash, password) {
+		return User{}, ErrBadCredentials
+	}
+	return u, nil
+}
+
+func (s *UserStore) update(name string, fn func(*User)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users, err := s.readLocked()
+	if err != nil {
+		return err
+	}
+	for i := range users {
+		if users[i].Name == name {
+			fn(&users[i])
+			return s.writeLocked(users)
+		}
+	}
+	return ErrUserNotFound
+}
+
+func (s *UserStore) readLocked() ([]User, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var users []User
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, fmt.Errorf("users %s: %w", s.path, err)
+	}
+	return users, nil
+}
+
+func (s *UserStore) writeLocked(users []User) error {
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+	data, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create users dir: %w", err)
+		}
+	}
+	// 文件包含密码哈希，只允许当前用户读写。
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// normalizeRoles 校验角色并去重排序，为空时返回 writer。
+func normalizeRoles(roles []string) ([]string, error) {
+	seen := map[string]bool{}
+	var out []string
+	for _, r := range roles {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if r == "" || seen[r] {
+			continue
+		}
+		if !slices.Contains(knownRoles, r) {
+			return nil, fmt.Errorf("unknown role %q; want one of %s", r, strings.Join(knownRoles, ", "))
+		}
+		seen[r] = true
+		out = append(out, r)
+	}
+	if len(out) == 0 {
+		return []string{roleWriter}, nil
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// hashPassword 以 PBKDF2-SHA256 加随机盐计算密码哈希，格式为 pbkdf2-sha256$迭代次数$盐$哈希。
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLen {
+		return "", fmt.Errorf("password must be at least %d characters", minPasswordLen)
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, 32)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", passwordIterations, enc.EncodeToString(salt), enc.EncodeToString(key)), nil
+}
+
+func checkPassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iter, err := strconv.Atoi(parts[1])
+	if err != nil || iter <= 0 {
+		return false
+	}
+	enc := base64.RawStdEncoding
+	salt, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := enc.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, iter, len(want))
+	return err == nil && subtle.ConstantTimeCompare(got, want) == 1
+}
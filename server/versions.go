@@ -0,0 +1,198 @@
+package server
+
+import (
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+)
+
+// versionInfo 为版本列表中的一项；Version 从 1 开始，对应第 Version 轮修订后的稿件。
+type versionInfo struct {
+	Version   int                `json:"version"`
+	Kind      generator.TurnKind `json:"kind"`
+	Comment   string             `json:"comment"`
+	Title     string             `json:"title"`
+	WordCount int                `json:"word_count"`
+	Provider  string             `json:"provider,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type versionsResp struct {
+	Versions []versionInfo `json:"versions"`
+	// Current 为与当前稿件内容一致的最新版本；发布时直接修改过正文则为 0。
+	Current int `json:"current"`
+}
+
+type diffResp struct {
+	From int `json:"from"`
+	// To 为 0 表示当前稿件。
+	To      int    `json:"to"`
+	Format  string `json:"format"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+	Diff    string `json:"diff"`
+}
+
+// currentVersion 返回与当前稿件正文相同的最新版本号，没有时返回 0。
+func currentVersion(sess *generator.Session) int {
+	for i := len(sess.History) - 1; i >= 0; i-- {
+		if sess.History[i].Draft.Markdown == sess.Draft.Markdown {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// handleSessionVersions 列出 session 的全部版本（每轮修订一个）。
+// Path: GET /api/sessions/{id}/versions
+func (s *Server) handleSessionVersions(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	resp := versionsResp{Versions: make([]versionInfo, 0, len(sess.History)), Current: currentVersion(sess)}
+	for i, t := range sess.History {
+		resp.Versions = append(resp.Versions, versionInfo{
+			Version:   i + 1,
+			Kind:      t.Kind,
+			Comment:   t.Comment,
+			Title:     t.Draft.Title,
+			WordCount: t.Draft.WordCount,
+			Provider:  t.Provider,
+			CreatedAt: t.CreatedAt,
+		})
+	}
+	writeJSON(w, resp)
+}
+
+// handleSessionDiff 比较两个版本的正文：to 省略或为 0 时为当前稿件，from 默认为 to 的上一个版本；
+// format 为 unified（默认，context 为上下文行数，默认 3）或 html（全文，<ins>/<del> 标出增删行）。
+// Path: GET /api/sessions/{id}/diff?from=&to=&format=
+func (s *Server) handleSessionDiff(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	q := r.URL.Query()
+	to, err := queryInt(q.Get("to"), 0)
+	if err != nil {
+		http.Error(w, "invalid to", http.StatusBadRequest)
+		return
+	}
+	// 当前稿件与某个版本一致时，默认与该版本的上一个版本比较。
+	base := to
+	if base == 0 {
+		if base = currentVersion(sess); base == 0 {
+			base = len(sess.History) + 1
+		}
+	}
+	from, err := queryInt(q.Get("from"), base-1)
+	if err != nil {
+		http.Error(w, "invalid from", http.StatusBadRequest)
+		return
+	}
+	ctxLines, err := queryInt(q.Get("context"), 3)
+	if err != nil || ctxLines < 0 {
+		http.Error(w, "invalid context", http.StatusBadRequest)
+		return
+	}
+	format := q.Get("format")
+	if format == "" {
+		format = "unified"
+	}
+	if format != "unified" && format != "html" {
+		http.Error(w, "format must be unified or html", http.StatusBadRequest)
+		return
+	}
+
+	older, err := sess.Version(from)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	newer, toName := sess.Draft, "current"
+	if to != 0 {
+		if newer, err = sess.Version(to); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		toName = "v" + strconv.Itoa(to)
+	}
+
+	lines := generator.DiffLines(older.Markdown, newer.Markdown)
+	resp := diffResp{From: from, To: to, Format: format}
+	for _, l := range lines {
+		switch l.Op {
+		case '+':
+			resp.Added++
+		case '-':
+			resp.Removed++
+		}
+	}
+	if format == "html" {
+		resp.Diff = diffHTML(lines)
+	} else {
+		resp.Diff = generator.UnifiedDiff("v"+strconv.Itoa(from), toName, lines, ctxLines)
+	}
+	writeJSON(w, resp)
+}
+
+// diffHTML 把逐行差异渲染为 <pre class="diff">，新增行包在 <ins>、删除行包在 <del> 中。
+func diffHTML(lines []generator.DiffLine) string {
+	var sb strings.Builder
+	sb.WriteString(`<pre class="diff">`)
+	for _, l := range lines {
+		text := html.EscapeString(l.Text)
+		switch l.Op {
+		case '+':
+			sb.WriteString("<ins>" + text + "</ins>")
+		case '-':
+			sb.WriteString("<del>" + text + "</del>")
+		default:
+			sb.WriteString(text)
+		}
+		sb.WriteByte('\n')
+	}
+	sb.WriteString("</pre>")
+	return sb.String()
+}
+
+// handleSessionRollback 把稿件恢复为指定版本，记录为新的一轮“回滚”，不删除之后的版本。
+// Path: POST /api/sessions/{id}/rollback?to=
+func (s *Server) handleSessionRollback(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, ok := s.store.get(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, "to must be a version number", http.StatusBadRequest)
+		return
+	}
+	draft, err := sess.Rollback(to)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+	s.events.publish(id, eventRevisionApplied, draft)
+	writeJSON(w, sessionResp{SessionID: id, Draft: draft, History: sess.History})
+}
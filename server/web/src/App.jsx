@@ -1,15 +1,59 @@
 import React, { useEffect, useMemo, useRef, useState } from 'react';
 import { marked } from 'marked';
+import { withBase } from './basePath.js';
 import './style.css';
 
+const builtinStyles = [
+  { key: 'life-rational', name: '生活化·理性' },
+  { key: 'warm-healing', name: '温和·治愈' },
+  { key: 'novelistic', name: '小说式' },
+];
+
+// 大纲与可编辑文本互转：# 标题 / ## 小节 / - 要点
+const outlineToText = (outline) => {
+  if (!outline) return '';
+  const lines = [`# ${outline.title || ''}`];
+  (outline.sections || []).forEach((sec) => {
+    lines.push(`## ${sec.heading}`);
+    (sec.points || []).forEach((p) => lines.push(`- ${p}`));
+  });
+  return lines.join('\n');
+};
+
+const textToOutline = (text) => {
+  const outline = { title: '', sections: [] };
+  text.split('\n').map((l) => l.trim()).filter(Boolean).forEach((line) => {
+    if (line.startsWith('## ')) {
+      outline.sections.push({ heading: line.slice(3).trim(), points: [] });
+    } else if (line.startsWith('# ')) {
+      outline.title = line.slice(2).trim();
+    } else if (outline.sections.length) {
+      outline.sections[outline.sections.length - 1].points.push(line.replace(/^[-*]\s*/, ''));
+    }
+  });
+  return outline;
+};
+
 const defaultSpec = {
   topic: '',
   outline: '',
   tone: '',
   audience: '',
+  taboo: '',
   words: '500',
   constraints: '',
   style: 'life-rational',
+  references: '',
+  research: false,
+  seriesId: '',
+  ideaId: '',
+};
+
+const workflowLabels = {
+  draft: '草稿',
+  submitted: '待审核',
+  approved: '已通过',
+  published: '已发布',
 };
 
 function App() {
@@ -21,10 +65,33 @@ function App() {
   const [status, setStatus] = useState('等待生成...');
   const [loading, setLoading] = useState(false);
   const [publishing, setPublishing] = useState(false);
+  const [scheduleAt, setScheduleAt] = useState('');
+  const [me, setMe] = useState(null);
+  const [workflow, setWorkflow] = useState(null);
+  const [comments, setComments] = useState([]);
+  const [commentForm, setCommentForm] = useState({ body: '', heading: '' });
+  const [loginForm, setLoginForm] = useState({ username: '', password: '' });
   const [cover, setCover] = useState({ path: '', url: '', filename: '' });
   const [bodyImages, setBodyImages] = useState([]);
   const [uploading, setUploading] = useState(false);
   const [showConstraints, setShowConstraints] = useState(false);
+  const [styles, setStyles] = useState(builtinStyles);
+  const [styleEditor, setStyleEditor] = useState(null);
+  const [outlineText, setOutlineText] = useState('');
+  const [sectionHeading, setSectionHeading] = useState('');
+  const [variantCount, setVariantCount] = useState(1);
+  const [variants, setVariants] = useState([]);
+  const [titleOptions, setTitleOptions] = useState([]);
+  const [quotes, setQuotes] = useState([]);
+  const [sessionList, setSessionList] = useState(null);
+  const [sessionQuery, setSessionQuery] = useState('');
+  const [publishList, setPublishList] = useState(null);
+  const [factReport, setFactReport] = useState(null);
+  const [rewriteSource, setRewriteSource] = useState('');
+  const [originality, setOriginality] = useState(null);
+  const [seriesList, setSeriesList] = useState([]);
+  const [ideaForm, setIdeaForm] = useState({ audience: '', domain: '' });
+  const [calendar, setCalendar] = useState([]);
 
   const coverInputRef = useRef(null);
   const bodyInputRef = useRef(null);
@@ -35,9 +102,14 @@ function App() {
     outline: spec.outline.split('\n').filter(Boolean),
     tone: spec.tone.trim(),
     audience: spec.audience.trim(),
+    taboo: spec.taboo.split('\n').map((t) => t.trim()).filter(Boolean),
     words: parseInt(spec.words, 10) || 0,
     constraints: spec.constraints.split('\n').filter(Boolean),
     style: spec.style,
+    reference_urls: spec.references.split('\n').map((u) => u.trim()).filter(Boolean),
+    research: spec.research,
+    series_id: spec.seriesId || undefined,
+    idea_id: spec.ideaId || undefined,
   }), [spec]);
 
   const handleSubmit = async (forceNew = false) => {
@@ -52,18 +124,75 @@ function App() {
     setLoading(true);
     const isNew = forceNew || !sessionId;
     setStatus(isNew ? '生成中...' : '修订中...');
-    const res = await fetch(isNew ? '/api/sessions' : `/api/sessions/${sessionId}`, {
+    let id = sessionId;
+    if (isNew && variantCount > 1) {
+      const res = await fetch('/api/sessions', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ ...payload, variants: variantCount }),
+      });
+      if (!res.ok) return handleError(res);
+      const created = await res.json();
+      setSessionId(created.session_id);
+      setVariants(created.variants || []);
+      setStatus(`已生成 ${(created.variants || []).length} 份候选，请选择一份`);
+      setLoading(false);
+      return;
+    }
+    if (isNew) {
+      const res = await fetch('/api/sessions', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ ...payload, stream: true }),
+      });
+      if (!res.ok) return handleError(res);
+      const created = await res.json();
+      id = created.session_id;
+      setSessionId(id);
+    }
+    try {
+      const data = await streamGenerate(id, isNew ? '' : comment.trim());
+      applySession(data);
+      setStatus(isNew ? '首稿生成完成' : '修订完成');
+    } catch (err) {
+      setStatus(`错误: ${err.message}`);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  // POST 开始生成后通过 SSE 实时接收模型输出，结束时返回完整 session。
+  const streamGenerate = async (id, commentText) => {
+    const res = await fetch(`/api/sessions/${id}/stream`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
-      body: JSON.stringify(isNew ? payload : { comment: comment.trim() }),
+      body: JSON.stringify({ comment: commentText }),
     });
-    if (!res.ok) return handleError(res);
-    const data = await res.json();
-    applySession(data);
-    setStatus(isNew ? '首稿生成完成' : '修订完成');
-    setLoading(false);
+    if (!res.ok) throw new Error((await res.text()).trim() || `HTTP ${res.status}`);
+    return readStream(id);
   };
 
+  const readStream = (id) => new Promise((resolve, reject) => {
+    const es = new EventSource(withBase(`/api/sessions/${id}/stream`));
+    let text = '';
+    es.addEventListener('delta', (e) => {
+      text += JSON.parse(e.data).text || '';
+      setDraft((prev) => ({ ...prev, markdown: text }));
+    });
+    es.addEventListener('done', (e) => {
+      es.close();
+      resolve(JSON.parse(e.data));
+    });
+    es.addEventListener('error', (e) => {
+      es.close();
+      let msg = '流式生成中断';
+      try {
+        if (e.data) msg = JSON.parse(e.data).error || msg;
+      } catch (_) { /* 连接错误没有 data */ }
+      reject(new Error(msg));
+    });
+  });
+
   const handlePublish = async () => {
     if (!sessionId || !draft.markdown) {
       setStatus('请先生成稿件');
@@ -74,7 +203,7 @@ function App() {
       return;
     }
     setPublishing(true);
-    setStatus('发布中...');
+    setStatus(scheduleAt ? '提交定时发布...' : '发布中...');
     const res = await fetch('/api/publish', {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
@@ -84,15 +213,366 @@ function App() {
         markdown: draft.markdown,
         title: draft.title,
         digest: draft.digest,
+        schedule_at: scheduleAt || undefined,
       }),
     });
     if (!res.ok) return handleError(res, true);
-    const data = await res.json();
-    // 不在前端显示 media_id，防止误泄露；仅提示成功。
-    setStatus('发布成功');
+    if (scheduleAt) {
+      const item = await res.json();
+      setStatus(`已定时，将于 ${new Date(item.schedule_at).toLocaleString()} 发布`);
+      setPublishing(false);
+      return;
+    }
+    let job = await res.json();
+    // 轮询发布任务；不在前端显示 media_id，防止误泄露，仅提示成功。
+    while (job.status === 'queued' || job.status === 'running') {
+      setStatus(`发布中... ${job.progress || ''}`);
+      await new Promise((resolve) => setTimeout(resolve, 1000));
+      const poll = await fetch(`/api/jobs/${job.job_id}`);
+      if (!poll.ok) return handleError(poll, true);
+      job = await poll.json();
+    }
+    setStatus(job.status === 'done' ? '发布成功' : `发布失败: ${job.error || ''}`);
     setPublishing(false);
   };
 
+  // 大纲优先：先生成大纲，编辑确认后再展开全文。
+  const handleOutline = async () => {
+    if (sessionId) await deleteSession();
+    setLoading(true);
+    setStatus('大纲生成中...');
+    setHistory([]);
+    setDraft({ markdown: '' });
+    const res = await fetch('/api/sessions', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ ...payload, phase: 'outline' }),
+    });
+    if (!res.ok) return handleError(res);
+    const data = await res.json();
+    setSessionId(data.session_id);
+    setOutlineText(outlineToText(data.outline));
+    setStatus('大纲生成完成，确认后点击“按大纲展开”');
+    setLoading(false);
+  };
+
+  // 改写/翻译模式：原文为单个网址时由服务端抓取，否则按 Markdown/纯文本处理。
+  const handleRewrite = async (translate = false) => {
+    const text = rewriteSource.trim();
+    if (!text) {
+      setStatus('请先粘贴原文或上传 Markdown 文件');
+      return;
+    }
+    if (sessionId) await deleteSession();
+    setLoading(true);
+    setStatus(translate ? '翻译中...' : '改写中...');
+    setHistory([]);
+    setDraft({ markdown: '' });
+    const isURL = /^https?:\/\/\S+$/.test(text);
+    const res = await fetch('/api/sessions', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({
+        ...payload,
+        ...(translate ? { translate: true } : { rewrite: true }),
+        ...(isURL ? { source_url: text } : { source: text }),
+      }),
+    });
+    if (!res.ok) return handleError(res);
+    const data = await res.json();
+    setSessionId(data.session_id);
+    applySession(data);
+    setOriginality(data.originality || null);
+    setStatus(translate ? '翻译完成' : '改写完成');
+    setLoading(false);
+  };
+
+  const handleOriginality = async () => {
+    const res = await fetch(`/api/sessions/${sessionId}/originality`);
+    if (!res.ok) return handleError(res);
+    setOriginality(await res.json());
+  };
+
+  const handleRewriteFile = (e) => {
+    const file = e.target.files && e.target.files[0];
+    if (!file) return;
+    file.text().then(setRewriteSource);
+    e.target.value = '';
+  };
+
+  const handleExpand = async () => {
+    if (!sessionId || !outlineText.trim()) return;
+    setLoading(true);
+    setStatus('按大纲展开中...');
+    const res = await fetch(`/api/sessions/${sessionId}/expand`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ outline: textToOutline(outlineText) }),
+    });
+    if (!res.ok) return handleError(res);
+    applySession(await res.json());
+    setStatus('首稿生成完成');
+    setLoading(false);
+  };
+
+  // 稿件中的二级及以下标题（忽略代码块），用于单节重写。
+  const sectionHeadings = useMemo(() => {
+    const heads = [];
+    let inFence = false;
+    (draft.markdown || '').split('\n').forEach((line) => {
+      if (/^\s*(```|~~~)/.test(line)) inFence = !inFence;
+      const m = !inFence && line.match(/^#{2,6}\s+(.+?)\s*#*\s*$/);
+      if (m) heads.push(m[1]);
+    });
+    return heads;
+  }, [draft.markdown]);
+
+  const handleRegenerateSection = async () => {
+    const heading = sectionHeading || sectionHeadings[0];
+    if (!sessionId || !heading) return;
+    setLoading(true);
+    setStatus(`重写小节「${heading}」中...`);
+    const res = await fetch(`/api/sessions/${sessionId}/sections`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ heading, comment: comment.trim() }),
+    });
+    if (!res.ok) return handleError(res);
+    applySession(await res.json());
+    setStatus('小节重写完成');
+    setLoading(false);
+  };
+
+  const handlePolish = async () => {
+    if (!sessionId || !draft.markdown) return;
+    setLoading(true);
+    setStatus('润色中...');
+    const res = await fetch(`/api/sessions/${sessionId}/polish`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ focus: comment.trim() }),
+    });
+    if (!res.ok) return handleError(res);
+    applySession(await res.json());
+    setStatus('润色完成');
+    setLoading(false);
+  };
+
+  const handleRephraseSensitive = async () => {
+    if (!sessionId || !draft.markdown) return;
+    setLoading(true);
+    setStatus('改写敏感词中...');
+    const res = await fetch(`/api/sessions/${sessionId}/sensitive`, { method: 'POST' });
+    if (!res.ok) return handleError(res);
+    applySession(await res.json());
+    setStatus('敏感词改写完成');
+    setLoading(false);
+  };
+
+  const handleFactCheck = async () => {
+    if (!sessionId || !draft.markdown) return;
+    setLoading(true);
+    setStatus('事实核查中...');
+    const res = await fetch(`/api/sessions/${sessionId}/factcheck`, { method: 'POST' });
+    if (!res.ok) return handleError(res);
+    const report = await res.json();
+    setFactReport(report);
+    setStatus(report.flagged ? `核查完成：${report.flagged} 处存疑` : '核查完成：未发现问题');
+    setLoading(false);
+  };
+
+  const handleApplyCitations = async () => {
+    const res = await fetch(`/api/sessions/${sessionId}/factcheck/apply`, { method: 'POST' });
+    if (!res.ok) return handleError(res);
+    applySession(await res.json());
+    setFactReport(null);
+    setStatus('参考资料已追加');
+  };
+
+  const handleSuggestTitles = async () => {
+    if (!sessionId || !draft.markdown) return;
+    setLoading(true);
+    setStatus('备选标题生成中...');
+    const res = await fetch(`/api/sessions/${sessionId}/titles`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ count: 5, score: true }),
+    });
+    if (!res.ok) return handleError(res);
+    const data = await res.json();
+    setTitleOptions(data.titles || []);
+    setStatus('备选标题生成完成');
+    setLoading(false);
+  };
+
+  const handleApplyTitle = async (title) => {
+    const res = await fetch(`/api/sessions/${sessionId}/titles/apply`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ title }),
+    });
+    if (!res.ok) return handleError(res);
+    applySession(await res.json());
+    setTitleOptions([]);
+    setStatus('标题已更新');
+  };
+
+  const handleExtractQuotes = async () => {
+    if (!sessionId || !draft.markdown) return;
+    setLoading(true);
+    setStatus('金句提取中...');
+    const res = await fetch(`/api/sessions/${sessionId}/quotes`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ count: 3 }),
+    });
+    if (!res.ok) return handleError(res);
+    const data = await res.json();
+    setQuotes(data.quotes || []);
+    setStatus('金句提取完成');
+    setLoading(false);
+  };
+
+  const handleApplyQuote = async (index, target) => {
+    setLoading(true);
+    setStatus(target === 'cover' ? '封面生成中...' : '摘要更新中...');
+    const res = await fetch(`/api/sessions/${sessionId}/quotes/apply`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ index, target }),
+    });
+    if (!res.ok) return handleError(res);
+    const data = await res.json();
+    if (target === 'cover') {
+      setCover({ path: data.path, url: data.url, filename: data.filename });
+      setStatus('封面已生成');
+    } else {
+      applySession(data);
+      setStatus('摘要已更新');
+    }
+    setLoading(false);
+  };
+
+  const handlePickVariant = async (index) => {
+    const res = await fetch(`/api/sessions/${sessionId}/variants`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ index }),
+    });
+    if (!res.ok) return handleError(res);
+    applySession(await res.json());
+    setVariants([]);
+    setStatus(`已选用候选 ${index + 1}`);
+  };
+
+  const handlePlaceImages = async () => {
+    if (!sessionId || !bodyImages.length) return;
+    setLoading(true);
+    setStatus('智能插图中...');
+    const res = await fetch(`/api/sessions/${sessionId}/images/place`, { method: 'POST' });
+    if (!res.ok) return handleError(res);
+    const data = await res.json();
+    applySession(data);
+    setStatus('插图完成');
+    setLoading(false);
+  };
+
+  const loadSessionList = async () => {
+    const params = new URLSearchParams({ archived: 'true', page_size: '10' });
+    if (sessionQuery.trim()) params.set('q', sessionQuery.trim());
+    const res = await fetch(`/api/sessions?${params}`);
+    if (!res.ok) return handleError(res);
+    const data = await res.json();
+    setSessionList(data.sessions || []);
+  };
+
+  // 审核流程：启用登录时发布前需 reviewer 审核通过。
+  const loadWorkflow = async (id = sessionId) => {
+    if (!id || !me?.auth) return setWorkflow(null);
+    const res = await fetch(`/api/sessions/${id}/workflow`);
+    setWorkflow(res.ok ? await res.json() : null);
+  };
+
+  const handleWorkflowAction = async (action) => {
+    const labels = { submit: '提交审核', approve: '审核通过', reject: '驳回' };
+    let comment = '';
+    if (action === 'reject') {
+      comment = window.prompt('驳回意见');
+      if (comment === null) return;
+    }
+    setStatus(`${labels[action]}中...`);
+    const res = await fetch(`/api/sessions/${sessionId}/${action}`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ comment }),
+    });
+    if (!res.ok) return handleError(res);
+    setWorkflow(await res.json());
+    setStatus(`${labels[action]}完成`);
+  };
+
+  const hasRole = (role) => !!me?.admin || (me?.roles || []).includes(role);
+
+  // 审阅批注：可锚定到小节，作者可一次性按全部待处理批注修订。
+  const loadComments = async (id = sessionId) => {
+    if (!id) return setComments([]);
+    const res = await fetch(`/api/sessions/${id}/comments`);
+    if (res.ok) setComments((await res.json()).comments || []);
+  };
+
+  const handleAddComment = async () => {
+    const body = commentForm.body.trim();
+    if (!sessionId || !body) return;
+    const res = await fetch(`/api/sessions/${sessionId}/comments`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ body, heading: commentForm.heading }),
+    });
+    if (!res.ok) return handleError(res);
+    setCommentForm({ body: '', heading: commentForm.heading });
+    loadComments();
+  };
+
+  const handleCommentAction = async (id, action) => {
+    const res = await fetch(`/api/sessions/${sessionId}/comments/${id}${action === 'delete' ? '' : `/${action}`}`, {
+      method: action === 'delete' ? 'DELETE' : 'POST',
+    });
+    if (!res.ok) return handleError(res);
+    loadComments();
+  };
+
+  const handleReviseFromComments = async () => {
+    if (!sessionId) return;
+    setLoading(true);
+    setStatus('按批注修订中...');
+    const res = await fetch(`/api/sessions/${sessionId}/comments/revise`, { method: 'POST' });
+    if (!res.ok) return handleError(res);
+    applySession(await res.json());
+    loadComments();
+    setStatus('已按批注修订');
+    setLoading(false);
+  };
+
+  const loadPublishList = async () => {
+    const res = await fetch('/api/publishes?limit=20');
+    if (!res.ok) return handleError(res);
+    const data = await res.json();
+    setPublishList(data.publishes || []);
+  };
+
+  const handleResumeSession = async (id) => {
+    const res = await fetch(`/api/sessions/${id}`);
+    if (!res.ok) return handleError(res);
+    setVariants([]);
+    setTitleOptions([]);
+    setQuotes([]);
+    setFactReport(null);
+    setSessionList(null);
+    setPublishList(null);
+    applySession(await res.json());
+    setStatus('已恢复会话，可继续修订');
+  };
+
   const deleteSession = async () => {
     if (!sessionId) return;
     try {
@@ -102,16 +582,208 @@ function App() {
     }
   };
 
-  // Heartbeat: keep session alive while page is open
+  // Heartbeat: keep session alive while page is open.
+  // 优先走 WebSocket（同时接收发布进度等事件），连接失败时回退到 /api/heartbeat。
+  const loadStyles = () =>
+    fetch('/api/styles')
+      .then((res) => (res.ok ? res.json() : null))
+      .then((list) => {
+        if (Array.isArray(list) && list.length) setStyles(list);
+      })
+      .catch(() => {});
+
+  const loadSeries = () =>
+    fetch('/api/series')
+      .then((res) => (res.ok ? res.json() : null))
+      .then((list) => {
+        if (Array.isArray(list)) setSeriesList(list);
+      })
+      .catch(() => {});
+
+  const loadCalendar = () =>
+    fetch('/api/calendar')
+      .then((res) => (res.ok ? res.json() : null))
+      .then((list) => {
+        if (Array.isArray(list)) setCalendar(list);
+      })
+      .catch(() => {});
+
+  // 启用登录时 /api/me 返回 401，先显示登录页。
+  const loadMe = () =>
+    fetch('/api/me')
+      .then((res) => (res.status === 401 ? { auth: true } : res.json()))
+      .then(setMe)
+      .catch(() => setMe({ auth: false }));
+
+  useEffect(() => {
+    loadMe();
+  }, []);
+
+  const signedIn = !!me && (!me.auth || !!me.user);
+  useEffect(() => {
+    if (!signedIn) return;
+    loadStyles();
+    loadSeries();
+    loadCalendar();
+  }, [signedIn]);
+
+  const handleLogin = async (e) => {
+    e.preventDefault();
+    const res = await fetch('/api/login', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(loginForm),
+    });
+    if (!res.ok) {
+      setStatus(`登录失败: ${(await res.text()).trim()}`);
+      return;
+    }
+    setLoginForm({ username: '', password: '' });
+    setStatus('等待生成...');
+    loadMe();
+  };
+
+  const handleLogout = async () => {
+    await fetch('/api/logout', { method: 'POST' });
+    setSessionId(null);
+    setDraft({ markdown: '' });
+    setHistory([]);
+    setMe({ auth: true });
+  };
+
+  // 按读者与领域生成选题，并写入内容日历。
+  const handleIdeas = async () => {
+    if (!ideaForm.audience.trim() && !ideaForm.domain.trim()) {
+      setStatus('请填写目标读者或内容领域');
+      return;
+    }
+    setLoading(true);
+    setStatus('选题生成中...');
+    const res = await fetch('/api/ideas', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ ...ideaForm, style: spec.style, save: true }),
+    });
+    if (!res.ok) return handleError(res);
+    const data = await res.json();
+    await loadCalendar();
+    setStatus(`已加入 ${(data.ideas || []).length} 个选题到内容日历`);
+    setLoading(false);
+  };
+
+  // 选用日历中的选题：填入主题与背景要点，创建 session 时关联该条目。
+  const pickIdea = (idea) => {
+    setSpec((prev) => ({
+      ...prev,
+      topic: idea.title,
+      outline: (idea.outline || []).join('\n'),
+      ideaId: idea.id,
+    }));
+    setStatus(`已选用选题「${idea.title}」，点击“生成首稿”开始写作`);
+  };
+
+  const createSeries = async () => {
+    const title = window.prompt('系列名称');
+    if (!title || !title.trim()) return;
+    const description = window.prompt('系列定位 / 目标读者（可空）') || '';
+    const res = await fetch('/api/series', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ title: title.trim(), description }),
+    });
+    if (!res.ok) return handleError(res);
+    const saved = await res.json();
+    await loadSeries();
+    setSpec((prev) => ({ ...prev, seriesId: saved.id }));
+  };
+
+  // 把当前稿件概括后追加到所选系列，后续篇目会引用它。
+  const handleAddToSeries = async () => {
+    if (!sessionId || !spec.seriesId) return;
+    setLoading(true);
+    setStatus('加入系列中...');
+    const res = await fetch(`/api/series/${spec.seriesId}/parts`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ session_id: sessionId }),
+    });
+    if (!res.ok) return handleError(res);
+    const saved = await res.json();
+    await loadSeries();
+    setStatus(`已加入系列「${saved.title}」第 ${saved.parts.length} 篇完成`);
+    setLoading(false);
+  };
+
+  const openStyleEditor = (isNew) => {
+    if (isNew) {
+      setStyleEditor({ isNew: true, key: '', name: '', prompt: '' });
+      return;
+    }
+    const cur = styles.find((st) => st.key === spec.style) || { key: spec.style };
+    setStyleEditor({ isNew: false, key: cur.key, name: cur.name || '', prompt: cur.prompt || '', builtin: cur.builtin });
+  };
+
+  const saveStyle = async () => {
+    const { isNew, key, name, prompt } = styleEditor;
+    const res = await fetch(isNew ? '/api/styles' : `/api/styles/${encodeURIComponent(key)}`, {
+      method: isNew ? 'POST' : 'PUT',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ key: key.trim(), name, prompt }),
+    });
+    if (!res.ok) {
+      setStatus(`保存风格失败：${await res.text()}`);
+      return;
+    }
+    const saved = await res.json();
+    await loadStyles();
+    setSpec((prev) => ({ ...prev, style: saved.key }));
+    setStyleEditor(null);
+    setStatus(`风格「${saved.name}」已保存`);
+  };
+
+  const deleteStyle = async () => {
+    const { key } = styleEditor;
+    const res = await fetch(`/api/styles/${encodeURIComponent(key)}`, { method: 'DELETE' });
+    if (!res.ok) {
+      setStatus(`删除风格失败：${await res.text()}`);
+      return;
+    }
+    await loadStyles();
+    setSpec((prev) => ({ ...prev, style: builtinStyles.some((st) => st.key === key) ? key : defaultSpec.style }));
+    setStyleEditor(null);
+    setStatus('风格已删除');
+  };
+
   useEffect(() => {
     if (!sessionId) {
+      setWorkflow(null);
+      setComments([]);
       if (heartbeatRef.current) {
         clearInterval(heartbeatRef.current);
         heartbeatRef.current = null;
       }
       return;
     }
+    let ws = null;
+    try {
+      const proto = window.location.protocol === 'https:' ? 'wss' : 'ws';
+      ws = new WebSocket(`${proto}://${window.location.host}${withBase('/api/ws')}?session_id=${sessionId}`);
+      ws.onmessage = (e) => {
+        try {
+          const ev = JSON.parse(e.data);
+          if (ev.type === 'publish_progress') setStatus(`发布中... (${ev.data?.stage})`);
+          if (ev.type === 'workflow_changed') loadWorkflow(sessionId);
+          if (ev.type === 'comments_changed') loadComments(sessionId);
+        } catch (_) { /* ignore malformed */ }
+      };
+    } catch (err) {
+      ws = null;
+    }
     const sendBeat = async () => {
+      if (ws && ws.readyState === WebSocket.OPEN) {
+        ws.send(JSON.stringify({ type: 'heartbeat', session_id: sessionId }));
+        return;
+      }
       try {
         await fetch(`/api/heartbeat/${sessionId}`, { method: 'POST' });
       } catch (err) {
@@ -119,16 +791,62 @@ function App() {
       }
     };
     sendBeat();
+    loadWorkflow(sessionId);
+    loadComments(sessionId);
     heartbeatRef.current = setInterval(sendBeat, 60_000); // 60s
     return () => {
       if (heartbeatRef.current) {
         clearInterval(heartbeatRef.current);
         heartbeatRef.current = null;
       }
+      if (ws) ws.close();
     };
   }, [sessionId]);
 
+  // 大文件分片上传：每片失败时查询服务端已接收的位置后重试，慢速网络下断线可续传。
+  const uploadResumable = async (file, usage) => {
+    const readError = async (res, fallback) => {
+      const msg = await res.text();
+      return new Error(msg || fallback);
+    };
+    const res = await fetch('/api/uploads/resumable', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ session_id: sessionId, filename: file.name, size: file.size, usage }),
+    });
+    if (!res.ok) throw await readError(res, '上传失败');
+    let upload = await res.json();
+    const base = `/api/uploads/resumable/${upload.upload_id}`;
+    let failures = 0;
+    while (upload.offset < upload.size) {
+      const chunk = file.slice(upload.offset, upload.offset + upload.chunk_size);
+      try {
+        const put = await fetch(`${base}?offset=${upload.offset}`, { method: 'PUT', body: chunk });
+        if (put.ok || put.status === 409) {
+          upload = await put.json();
+          failures = 0;
+        } else {
+          throw await readError(put, '分片上传失败');
+        }
+      } catch (err) {
+        failures += 1;
+        if (failures > 3) throw err;
+        await new Promise((resolve) => setTimeout(resolve, 1000 * failures));
+        const st = await fetch(base);
+        if (!st.ok) throw await readError(st, '上传已失效');
+        upload = await st.json();
+      }
+      setStatus(`上传中 ${upload.progress}%`);
+    }
+    const done = await fetch(`${base}/complete`, { method: 'POST' });
+    if (!done.ok) throw await readError(done, '上传失败');
+    return done.json();
+  };
+
   const uploadFile = async (file, usage = 'content') => {
+    if (sessionId && file.size > 5 * 1024 * 1024) {
+      return uploadResumable(file, usage);
+    }
     const formData = new FormData();
     formData.append('file', file);
     formData.append('usage', usage);
@@ -163,6 +881,31 @@ function App() {
     }
   };
 
+  const handleCoverEdit = async (edit, label) => {
+    if (!sessionId || !cover.path) return;
+    const name = cover.path.split('/').pop();
+    setUploading(true);
+    setStatus(`封面${label}中...`);
+    try {
+      const res = await fetch(`/api/uploads/${encodeURIComponent(name)}/edit`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ session_id: sessionId, usage: 'cover', ...edit }),
+      });
+      if (!res.ok) {
+        const msg = await res.text();
+        throw new Error(msg || '编辑失败');
+      }
+      const data = await res.json();
+      setCover({ path: data.path, url: data.url, filename: data.filename });
+      setStatus(`封面已${label}（${data.width}×${data.height}）`);
+    } catch (err) {
+      setStatus(`错误: ${err.message}`);
+    } finally {
+      setUploading(false);
+    }
+  };
+
   const handleBodySelect = async (e) => {
     if (!sessionId) {
       setStatus('请先生成草稿再上传图片');
@@ -178,7 +921,7 @@ function App() {
       for (const f of files) {
         // 逐个上传，避免过多并发
         const data = await uploadFile(f, 'content');
-        results.push({ path: data.path, url: data.url, filename: data.filename });
+        results.push({ path: data.path, url: data.url, filename: data.filename, alt: data.alt, caption: data.caption });
       }
       setBodyImages((prev) => [...prev, ...results]);
       setStatus(`已上传 ${files.length} 张正文图片`);
@@ -194,7 +937,8 @@ function App() {
 
   const insertImageIntoMarkdown = (img) => {
     if (!img?.path) return;
-    insertSnippet(`![正文图片](${img.path})`);
+    const snippet = `![${img.alt || '正文图片'}](${img.path})`;
+    insertSnippet(img.caption ? `${snippet}\n*${img.caption}*` : snippet);
   };
 
   const insertSnippet = (snippet) => {
@@ -225,6 +969,8 @@ function App() {
       markdown: rawDraft.markdown || rawDraft.Markdown || '',
       title: rawDraft.title || rawDraft.Title || '',
       digest: rawDraft.digest || rawDraft.Digest || '',
+      wordCount: rawDraft.word_count || rawDraft.WordCount || 0,
+      sensitive: rawDraft.Sensitive || [],
     };
     const normalizedHistory = (data.history || []).map((h) => {
       const baseSummary = h.summary || h.Summary || '';
@@ -236,6 +982,7 @@ function App() {
       return {
         comment: isInitial ? friendly : (baseComment || friendly || baseSummary),
         summary: friendly || baseSummary || baseComment,
+        kind: h.kind || h.Kind || friendly,
         created_at: h.created_at || h.CreatedAt || '',
       };
     });
@@ -283,6 +1030,7 @@ function App() {
     return (
       <div key={idx} className="history-item">
         <span className="badge">{ts}</span>
+        {t.kind && <span className="badge">{t.kind}</span>}
         <span className="history-text">{t.comment || t.summary || ''}</span>
       </div>
     );
@@ -291,6 +1039,26 @@ function App() {
   const canUploadCover = !!sessionId && !uploading;
   const coverHint = sessionId ? '支持 JPG / PNG，点击选择' : '需先生成草稿再上传';
 
+  if (me && !signedIn) {
+    return (
+      <div className="page">
+        <div className="aurora aurora-1" />
+        <div className="aurora aurora-2" />
+        <div className="container">
+          <form className="card card-solid login-card" onSubmit={handleLogin}>
+            <div className="logo">写作工坊·光谱</div>
+            <label>用户名</label>
+            <input value={loginForm.username} onChange={e => setLoginForm({ ...loginForm, username: e.target.value })} autoComplete="username" />
+            <label>密码</label>
+            <input type="password" value={loginForm.password} onChange={e => setLoginForm({ ...loginForm, password: e.target.value })} autoComplete="current-password" />
+            {status.startsWith('登录失败') && <p className="login-error">{status}</p>}
+            <button className="btn btn-primary" type="submit" disabled={!loginForm.username || !loginForm.password}>登录</button>
+          </form>
+        </div>
+      </div>
+    );
+  }
+
   return (
     <div className="page">
       <div className="aurora aurora-1" />
@@ -298,6 +1066,12 @@ function App() {
       <div className="container">
         <header className="hero card card-accent">
           <div className="logo">写作工坊·光谱</div>
+          {me?.user && (
+            <div className="user-bar">
+              <span>{me.user}{me.admin ? '（管理员）' : ''}{!me.admin && me.roles?.length ? ` · ${me.roles.join('/')}` : ''}</span>
+              <button className="btn btn-ghost" onClick={handleLogout}>退出</button>
+            </div>
+          )}
           <p className="subtitle">把灵感交给次元助手，自动生成可发布的微信图文。</p>
           <div className="chips">
             <span>主题塑形</span>
@@ -313,11 +1087,72 @@ function App() {
               <div className="section-title status-row">
                 <div className={`status-pill status-${statusTone}`} title={status}>{clippedStatus}</div>
                 <div className="actions">
-                  <button className="btn btn-secondary" onClick={handlePublish} disabled={!draft.markdown || publishing || uploading}>一键发布</button>
+                  <input type="datetime-local" value={scheduleAt} onChange={e => setScheduleAt(e.target.value)} title="留空立即发布，选择时间则定时发布" />
+                  <button className="btn btn-secondary" onClick={handlePublish} disabled={!draft.markdown || publishing || uploading}>{scheduleAt ? '定时发布' : '一键发布'}</button>
                 </div>
               </div>
+              {workflow && (
+                <div className="workflow-row">
+                  <span className={`badge workflow-${workflow.status}`}>{workflowLabels[workflow.status] || workflow.status}{workflow.stale ? '（通过后已修改）' : ''}</span>
+                  {workflow.status !== 'submitted' && (
+                    <button className="btn btn-ghost compact-btn" onClick={() => handleWorkflowAction('submit')} disabled={!draft.markdown || loading}>提交审核</button>
+                  )}
+                  {workflow.status === 'submitted' && hasRole('reviewer') && (
+                    <>
+                      <button className="btn btn-ghost compact-btn" onClick={() => handleWorkflowAction('approve')} disabled={loading}>通过</button>
+                      <button className="btn btn-ghost compact-btn" onClick={() => handleWorkflowAction('reject')} disabled={loading}>驳回</button>
+                    </>
+                  )}
+                  {workflow.status === 'approved' && hasRole('reviewer') && (
+                    <button className="btn btn-ghost compact-btn" onClick={() => handleWorkflowAction('reject')} disabled={loading}>撤回通过</button>
+                  )}
+                  {workflow.events.length > 0 && (
+                    <div className="variant-snippet">
+                      {workflow.events.slice(-3).map((ev) => `${new Date(ev.at).toLocaleString()} ${ev.user || ''} ${workflowLabels[ev.to] || ev.to}${ev.comment ? `：${ev.comment}` : ''}`).join(' / ')}
+                    </div>
+                  )}
+                </div>
+              )}
             </section>
 
+            {sessionId && draft.markdown && (
+              <section className="card card-solid">
+                <div className="section-title">
+                  <span className="dot" />
+                  审阅批注
+                  {comments.some((c) => c.status === 'open') && <span className="badge">{`${comments.filter((c) => c.status === 'open').length} 条待处理`}</span>}
+                </div>
+                {comments.map((c) => (
+                  <div key={c.id} className={`comment-item comment-${c.status}`}>
+                    <div className="variant-snippet">
+                      {c.user ? `${c.user} · ` : ''}{c.heading ? `「${c.heading}」` : c.quote ? `“${c.quote.slice(0, 30)}”` : '全文'}
+                      {c.status === 'resolved' ? (c.resolved_version ? ` · 已在版本 ${c.resolved_version} 修订` : ' · 已解决') : ''}
+                    </div>
+                    <div className="history-text">{c.body}</div>
+                    <div className="actions">
+                      {c.status === 'open'
+                        ? <button className="btn btn-ghost compact-btn" onClick={() => handleCommentAction(c.id, 'resolve')}>标记解决</button>
+                        : <button className="btn btn-ghost compact-btn" onClick={() => handleCommentAction(c.id, 'reopen')}>重新打开</button>}
+                      {(!me?.auth || me?.admin || c.user === me?.user) && (
+                        <button className="btn btn-ghost compact-btn" onClick={() => handleCommentAction(c.id, 'delete')}>删除</button>
+                      )}
+                    </div>
+                  </div>
+                ))}
+                <select className="compact" value={commentForm.heading} onChange={(e) => setCommentForm({ ...commentForm, heading: e.target.value })}>
+                  <option value="">全文</option>
+                  {sectionHeadings.map((h) => (
+                    <option key={h} value={h}>{h}</option>
+                  ))}
+                </select>
+                <textarea value={commentForm.body} onChange={(e) => setCommentForm({ ...commentForm, body: e.target.value })} placeholder="批注意见，例如：这一节缺少数据支撑" />
+                <div className="actions">
+                  <button className="btn btn-ghost compact-btn" onClick={handleAddComment} disabled={!commentForm.body.trim()}>添加批注</button>
+                  <button className="btn btn-secondary compact-btn" onClick={handleReviseFromComments} disabled={loading || !comments.some((c) => c.status === 'open')}>按批注修订</button>
+                </div>
+              </section>
+            )}
+
             <section className="card card-solid">
               <div className="section-title">
                 <span className="dot" />
@@ -331,6 +1166,37 @@ function App() {
                 onChange={e => setSpec({ ...spec, outline: e.target.value })}
                 placeholder={`资料要点/链接/案例\n可多行，每行一条`}
               />
+              <label>目标读者</label>
+              <input value={spec.audience} onChange={e => setSpec({ ...spec, audience: e.target.value })} placeholder="例如：刚入职的程序员" />
+              <label>语气</label>
+              <input value={spec.tone} onChange={e => setSpec({ ...spec, tone: e.target.value })} placeholder="例如：轻松幽默，像朋友聊天" />
+              <label>禁忌</label>
+              <textarea
+                value={spec.taboo}
+                onChange={e => setSpec({ ...spec, taboo: e.target.value })}
+                placeholder={`全文不得出现的说法或话题\n每行一条`}
+              />
+              <label>参考链接</label>
+              <textarea
+                value={spec.references}
+                onChange={e => setSpec({ ...spec, references: e.target.value })}
+                placeholder={`每行一个网址（最多 5 个），抓取摘要后作为写作素材`}
+              />
+              <label className="checkbox">
+                <input
+                  type="checkbox"
+                  checked={spec.research}
+                  onChange={e => setSpec({ ...spec, research: e.target.checked })}
+                />
+                写作前联网检索（需配置 search）
+              </label>
+              <label>原文（改写 / 翻译）</label>
+              <textarea
+                value={rewriteSource}
+                onChange={e => setRewriteSource(e.target.value)}
+                placeholder={`粘贴已有文章（Markdown/纯文本）或一个网址：“改写原文”按所选风格改写，“翻译原文”把外文文章编译为中文`}
+              />
+              <input type="file" accept=".md,.markdown,.txt" onChange={handleRewriteFile} />
               <div className="inline-field dual">
                 <label>字数</label>
                 <input
@@ -345,11 +1211,106 @@ function App() {
                   value={spec.style}
                   onChange={(e) => setSpec({ ...spec, style: e.target.value })}
                 >
-                  <option value="life-rational">生活化·理性</option>
-                  <option value="warm-healing">温和·治愈</option>
-                  <option value="novelistic">小说式</option>
+                  {styles.map((st) => (
+                    <option key={st.key} value={st.key}>{st.name || st.key}</option>
+                  ))}
                 </select>
               </div>
+              <div className="constraints-toggle">
+                <label>风格管理</label>
+                <span>
+                  <button type="button" className="btn btn-ghost compact-btn" onClick={() => openStyleEditor(false)}>
+                    编辑当前
+                  </button>
+                  <button type="button" className="btn btn-ghost compact-btn" onClick={() => openStyleEditor(true)}>
+                    新建
+                  </button>
+                </span>
+              </div>
+              <div className="constraints-toggle">
+                <label>系列</label>
+                <span>
+                  <select
+                    className="compact"
+                    value={spec.seriesId}
+                    onChange={(e) => setSpec({ ...spec, seriesId: e.target.value })}
+                  >
+                    <option value="">不属于系列</option>
+                    {seriesList.map((se) => (
+                      <option key={se.id} value={se.id}>{`${se.title}（已有 ${(se.parts || []).length} 篇）`}</option>
+                    ))}
+                  </select>
+                  <button type="button" className="btn btn-ghost compact-btn" onClick={createSeries}>
+                    新建
+                  </button>
+                </span>
+              </div>
+              <label>选题</label>
+              <div className="inline-field dual">
+                <input
+                  className="compact"
+                  value={ideaForm.audience}
+                  onChange={e => setIdeaForm({ ...ideaForm, audience: e.target.value })}
+                  placeholder="目标读者"
+                />
+                <input
+                  className="compact"
+                  value={ideaForm.domain}
+                  onChange={e => setIdeaForm({ ...ideaForm, domain: e.target.value })}
+                  placeholder="内容领域"
+                />
+                <button type="button" className="btn btn-ghost compact-btn" onClick={handleIdeas} disabled={loading}>
+                  生成选题
+                </button>
+              </div>
+              {calendar.length > 0 && (
+                <div className="variant-list">
+                  {calendar.map((idea) => (
+                    <div key={idea.id} className="variant-item">
+                      <div className="variant-title">{`${idea.publish_date || '未排期'} · ${idea.title}`}</div>
+                      <div className="variant-snippet">
+                        {`${idea.score} 分${idea.angle ? ` · ${idea.angle}` : ''}${idea.status === 'drafting' ? ' · 写作中' : ''}`}
+                      </div>
+                      <button type="button" className="btn btn-ghost compact-btn" onClick={() => pickIdea(idea)}>
+                        写这篇
+                      </button>
+                    </div>
+                  ))}
+                </div>
+              )}
+              {styleEditor && (
+                <div className="style-editor">
+                  <input
+                    value={styleEditor.key}
+                    disabled={!styleEditor.isNew}
+                    onChange={e => setStyleEditor({ ...styleEditor, key: e.target.value })}
+                    placeholder="key（字母、数字、-、_）"
+                  />
+                  <input
+                    value={styleEditor.name}
+                    onChange={e => setStyleEditor({ ...styleEditor, name: e.target.value })}
+                    placeholder="显示名称"
+                  />
+                  <textarea
+                    value={styleEditor.prompt}
+                    onChange={e => setStyleEditor({ ...styleEditor, prompt: e.target.value })}
+                    placeholder="风格提示词：语气、结构、禁忌..."
+                  />
+                  <div className="actions">
+                    <button type="button" className="btn btn-primary compact-btn" onClick={saveStyle}>
+                      保存
+                    </button>
+                    {!styleEditor.isNew && !styleEditor.builtin && (
+                      <button type="button" className="btn btn-ghost compact-btn" onClick={deleteStyle}>
+                        删除
+                      </button>
+                    )}
+                    <button type="button" className="btn btn-ghost compact-btn" onClick={() => setStyleEditor(null)}>
+                      取消
+                    </button>
+                  </div>
+                </div>
+              )}
               <div className="constraints-toggle">
                 <label>写作指南</label>
                 <button
@@ -371,12 +1332,38 @@ function App() {
                 <button className="btn btn-primary" onClick={() => handleSubmit(true)} disabled={loading}>
                   生成首稿
                 </button>
+                <button className="btn btn-secondary" onClick={handleOutline} disabled={loading}>
+                  先出大纲
+                </button>
+                <button className="btn btn-secondary" onClick={() => handleRewrite()} disabled={loading}>
+                  改写原文
+                </button>
+                <button className="btn btn-secondary" onClick={() => handleRewrite(true)} disabled={loading}>
+                  翻译原文
+                </button>
+                <select
+                  className="compact"
+                  value={variantCount}
+                  onChange={(e) => setVariantCount(parseInt(e.target.value, 10))}
+                  title="并行生成候选数"
+                >
+                  <option value={1}>1 份</option>
+                  <option value={2}>2 份候选</option>
+                  <option value={3}>3 份候选</option>
+                </select>
                 <button
                   className="btn btn-ghost"
                   onClick={() => {
                     deleteSession();
                     setSpec(defaultSpec);
                     setComment('');
+                    setOutlineText('');
+                    setVariants([]);
+                    setTitleOptions([]);
+                    setQuotes([]);
+                    setFactReport(null);
+                    setRewriteSource('');
+                    setOriginality(null);
                     setSessionId(null);
                     setDraft({ markdown: '' });
                     setHistory([]);
@@ -388,7 +1375,82 @@ function App() {
                 >
                   重置
                 </button>
+                <button className="btn btn-ghost" onClick={loadSessionList} disabled={loading}>
+                  继续上次
+                </button>
+                <button className="btn btn-ghost" onClick={loadPublishList} disabled={loading}>
+                  发布记录
+                </button>
               </div>
+              {publishList && (
+                <div className="variant-list">
+                  {publishList.length === 0 && <div className="variant-snippet">暂无发布记录</div>}
+                  {publishList.map((p) => (
+                    <div key={p.id} className="variant-item">
+                      <div className="variant-title">{p.title}</div>
+                      <div className="variant-snippet">
+                        {`${new Date(p.created_at).toLocaleString()} · ${p.status === 'success' ? `成功 ${p.media_id}` : `失败 ${p.error || ''}`}`}
+                      </div>
+                      {p.session_id && (
+                        <button className="btn btn-ghost compact-btn" onClick={() => handleResumeSession(p.session_id)}>
+                          打开稿件
+                        </button>
+                      )}
+                    </div>
+                  ))}
+                </div>
+              )}
+              {sessionList && (
+                <div className="variant-list">
+                  <div className="inline-field dual">
+                    <input
+                      value={sessionQuery}
+                      onChange={(e) => setSessionQuery(e.target.value)}
+                      onKeyDown={(e) => e.key === 'Enter' && loadSessionList()}
+                      placeholder="按主题或标题搜索"
+                    />
+                    <button className="btn btn-ghost compact-btn" onClick={loadSessionList}>
+                      搜索
+                    </button>
+                  </div>
+                  {sessionList.length === 0 && <div className="variant-snippet">没有可继续的会话</div>}
+                  {sessionList.map((it) => (
+                    <div key={it.id} className="variant-item">
+                      <div className="variant-title">{it.title || it.topic || it.id}</div>
+                      <div className="variant-snippet">
+                        {`${it.topic} · ${it.turns} 轮 · ${new Date(it.updated_at).toLocaleString()}${it.archived ? ' · 已归档' : ''}${me?.auth ? ` · ${workflowLabels[it.status] || it.status}${it.owner ? ` · ${it.owner}` : ''}` : ''}`}
+                      </div>
+                      <button className="btn btn-ghost compact-btn" onClick={() => handleResumeSession(it.id)}>
+                        继续
+                      </button>
+                    </div>
+                  ))}
+                </div>
+              )}
+              {variants.length > 0 && (
+                <div className="variant-list">
+                  {variants.map((v, i) => (
+                    <div key={i} className="variant-item">
+                      <div className="variant-title">{`候选 ${i + 1}：${v.Title || v.title || '无标题'}`}</div>
+                      <div className="variant-snippet">{(v.Markdown || v.markdown || '').slice(0, 120)}</div>
+                      <button className="btn btn-ghost compact-btn" onClick={() => handlePickVariant(i)}>
+                        选用
+                      </button>
+                    </div>
+                  ))}
+                </div>
+              )}
+              {outlineText && (
+                <>
+                  <label>大纲（# 标题 / ## 小节 / - 要点，可直接编辑）</label>
+                  <textarea value={outlineText} onChange={e => setOutlineText(e.target.value)} />
+                  <div className="actions spaced">
+                    <button className="btn btn-primary" onClick={handleExpand} disabled={loading || !sessionId}>
+                      按大纲展开
+                    </button>
+                  </div>
+                </>
+              )}
               <label>评论 / 追加要求</label>
               <textarea value={comment} onChange={e => setComment(e.target.value)} placeholder="例：加强案例部分，补充图片占位说明" />
               <div className="actions spaced">
@@ -399,7 +1461,121 @@ function App() {
                 >
                   提交评论修订
                 </button>
+                <button className="btn btn-ghost" onClick={handlePolish} disabled={loading || !draft.markdown}>
+                  润色
+                </button>
+              </div>
+              <div className="actions spaced">
+                <button className="btn btn-ghost" onClick={handleSuggestTitles} disabled={loading || !draft.markdown}>
+                  备选标题
+                </button>
+                <button className="btn btn-ghost" onClick={handleExtractQuotes} disabled={loading || !draft.markdown}>
+                  金句
+                </button>
+                <button className="btn btn-ghost" onClick={handleFactCheck} disabled={loading || !draft.markdown}>
+                  事实核查
+                </button>
+                {spec.seriesId && (
+                  <button className="btn btn-ghost" onClick={handleAddToSeries} disabled={loading || !draft.markdown}>
+                    加入系列
+                  </button>
+                )}
+                {originality && (
+                  <button className="btn btn-ghost" onClick={handleOriginality} disabled={loading}>
+                    重新比对原文
+                  </button>
+                )}
               </div>
+              {(draft.sensitive || []).length > 0 && (
+                <div className="variant-list">
+                  <div className="variant-item">
+                    <div className="variant-title">{`敏感词 ${draft.sensitive.length} 处`}</div>
+                    {draft.sensitive.map((h, i) => (
+                      <div key={i} className="variant-snippet">{`第 ${h.line} 行「${h.word}」：${h.context}`}</div>
+                    ))}
+                  </div>
+                  <button className="btn btn-ghost compact-btn" onClick={handleRephraseSensitive} disabled={loading}>
+                    改写敏感词
+                  </button>
+                </div>
+              )}
+              {originality && (
+                <div className="variant-list">
+                  <div className="variant-item">
+                    <div className="variant-title">
+                      {`与原文相似度 ${(originality.similarity * 100).toFixed(1)}% · 最长连续相同 ${originality.longest_copy} 字 · ${originality.original ? '原创度达标' : '重合较多，建议修改'}`}
+                    </div>
+                    {(originality.copied || []).map((c, i) => (
+                      <div key={i} className="variant-snippet">{c}</div>
+                    ))}
+                  </div>
+                </div>
+              )}
+              {factReport && (
+                <div className="variant-list">
+                  {(factReport.claims || []).filter((c) => c.verdict !== 'ok').map((c, i) => (
+                    <div key={i} className="variant-item">
+                      <div className="variant-title">{`${c.verdict === 'dubious' ? '存疑' : '无法确认'}：${c.claim}`}</div>
+                      <div className="variant-snippet">{[c.note, c.suggestion].filter(Boolean).join(' · ')}</div>
+                    </div>
+                  ))}
+                  {(factReport.citations || []).length > 0 && (
+                    <button className="btn btn-ghost compact-btn" onClick={handleApplyCitations}>
+                      {`追加 ${factReport.citations.length} 条参考资料`}
+                    </button>
+                  )}
+                </div>
+              )}
+              {titleOptions.length > 0 && (
+                <div className="variant-list">
+                  {titleOptions.map((t) => (
+                    <div key={t.title} className="variant-item">
+                      <div className="variant-title">{t.title}</div>
+                      <div className="variant-snippet">
+                        {`综合 ${t.score} · 点击 ${t.clickability} · 清晰 ${t.clarity}${t.length_ok ? '' : ' · 超过 64 字'}${t.reason ? ` · ${t.reason}` : ''}`}
+                      </div>
+                      <button className="btn btn-ghost compact-btn" onClick={() => handleApplyTitle(t.title)}>
+                        使用
+                      </button>
+                    </div>
+                  ))}
+                </div>
+              )}
+              {quotes.length > 0 && (
+                <div className="variant-list">
+                  {quotes.map((q, i) => (
+                    <div key={q.text} className="variant-item">
+                      <div className="variant-title">{q.text}</div>
+                      <div className="variant-snippet">
+                        {`${q.reason || ''}${q.digest_ok ? '' : ' · 超过摘要字数，将被截断'}`}
+                      </div>
+                      <button className="btn btn-ghost compact-btn" onClick={() => handleApplyQuote(i, 'digest')} disabled={loading}>
+                        用作摘要
+                      </button>
+                      <button className="btn btn-ghost compact-btn" onClick={() => handleApplyQuote(i, 'cover')} disabled={loading}>
+                        生成封面
+                      </button>
+                    </div>
+                  ))}
+                </div>
+              )}
+              {sectionHeadings.length > 0 && (
+                <div className="inline-field dual">
+                  <label className="shrink">小节</label>
+                  <select
+                    className="compact"
+                    value={sectionHeading}
+                    onChange={(e) => setSectionHeading(e.target.value)}
+                  >
+                    {sectionHeadings.map((h) => (
+                      <option key={h} value={h}>{h}</option>
+                    ))}
+                  </select>
+                  <button className="btn btn-ghost compact-btn" onClick={handleRegenerateSection} disabled={loading || !sessionId}>
+                    只重写该节
+                  </button>
+                </div>
+              )}
             </section>
           </div>
 
@@ -410,6 +1586,7 @@ function App() {
                 <div className="section-title">
                   <span className="dot" />
                   正文编辑（可定位插图）
+                  {draft.wordCount > 0 && <span className="badge">{`${draft.wordCount} 字`}</span>}
                 </div>
                 <textarea
                   ref={editorRef}
@@ -487,6 +1664,14 @@ function App() {
                       </div>
                     )}
                   </div>
+                  {cover.url && (
+                    <div className="actions spaced">
+                      <button className="btn btn-ghost compact-btn" onClick={() => handleCoverEdit({ aspect: '2.35:1' }, '裁剪')} disabled={uploading}>裁成 2.35:1</button>
+                      <button className="btn btn-ghost compact-btn" onClick={() => handleCoverEdit({ aspect: '1:1' }, '裁剪')} disabled={uploading}>裁成 1:1</button>
+                      <button className="btn btn-ghost compact-btn" onClick={() => handleCoverEdit({ rotate: 90 }, '旋转')} disabled={uploading}>旋转 90°</button>
+                      <button className="btn btn-ghost compact-btn" onClick={() => handleCoverEdit({ width: 900 }, '缩放')} disabled={uploading}>缩放到 900 宽</button>
+                    </div>
+                  )}
                   <input ref={coverInputRef} type="file" accept="image/*" onChange={handleCoverSelect} hidden disabled={!canUploadCover} />
                 </div>
 
@@ -497,6 +1682,7 @@ function App() {
                   </div>
                   <div className="actions spaced">
                     <button className="btn btn-primary" onClick={() => bodyInputRef.current?.click()} disabled={!sessionId || uploading}>上传正文图片</button>
+                    <button className="btn btn-ghost" onClick={handlePlaceImages} disabled={!bodyImages.length || loading || uploading}>智能插图</button>
                   </div>
                   <input ref={bodyInputRef} type="file" accept="image/*" multiple hidden onChange={handleBodySelect} />
                   {bodyImages.length ? (
@@ -0,0 +1,11 @@
+// 服务配置 base_path 时会在 index.html 中注入 <meta name="base-path">，接口地址需要加上该前缀。
+export const BASE_PATH = document.querySelector('meta[name="base-path"]')?.getAttribute('content') || '';
+
+export const withBase = (url) => (BASE_PATH && url.startsWith('/') && !url.startsWith(`${BASE_PATH}/`) ? BASE_PATH + url : url);
+
+// 让 fetch('/api/...') 自动带上前缀，组件中无需逐个修改。
+export function installBasePath() {
+  if (!BASE_PATH) return;
+  const rawFetch = window.fetch.bind(window);
+  window.fetch = (input, init) => rawFetch(typeof input === 'string' ? withBase(input) : input, init);
+}
@@ -1,6 +1,9 @@
 import React from 'react';
 import ReactDOM from 'react-dom/client';
 import App from './App.jsx';
+import { installBasePath } from './basePath.js';
 import './style.css';
 
+installBasePath();
+
 ReactDOM.createRoot(document.getElementById('root')).render(<App />);
@@ -3,6 +3,8 @@ import react from '@vitejs/plugin-react';
 
 export default defineConfig({
   plugins: [react()],
+  // 使用相对路径引用打包资源，服务挂载在 base_path 子路径下时也能加载。
+  base: './',
   build: {
     outDir: 'dist',
     emptyOutDir: true,
@@ -0,0 +1,295 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"auto_wechat_article_publisher/generator"
+)
+
+// 用户角色：writer 写稿并提交审核，reviewer 审核通过或驳回，publisher 发布已通过的稿件。管理员拥有全部角色。
+const (
+	roleWriter    = "writer"
+	roleReviewer  = "reviewer"
+	rolePublisher = "publisher"
+)
+
+var knownRoles = []string{roleWriter, roleReviewer, rolePublisher}
+
+// 审核流程状态：draft → submitted → approved → published；驳回回到 draft。
+const (
+	wfDraft     = "draft"
+	wfSubmitted = "submitted"
+	wfApproved  = "approved"
+	wfPublished = "published"
+)
+
+// workflowEvent 为审核记录中的一条：谁在何时把稿件从什么状态改到什么状态。
+type workflowEvent struct {
+	Action  string `json:"action"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	User    string `json:"user,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	// Title 与 DraftHash 记录操作时的稿件标题与正文摘要（SHA-256），用于核对审核通过的版本。
+	Title     string    `json:"title,omitempty"`
+	DraftHash string    `json:"draft_hash,omitempty"`
+	MediaID   string    `json:"media_id,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// workflow 为 session 的审核状态与审核记录，随 session 一起持久化。
+type workflow struct {
+	Status string `json:"status"`
+	// ApprovedHash 为审核通过时的正文摘要，发布时正文须与之一致。
+	ApprovedHash string          `json:"approved_hash,omitempty"`
+	Events       []workflowEvent `json:"events,omitempty"`
+}
+
+func (w workflow) status() string {
+	if w.Status == "" {
+		return wfDraft
+	}
+	return w.Status
+}
+
+// draftHash 返回正文的 SHA-256 摘要。
+func draftHash(md string) string {
+	sum := sha256.Sum256([]byte(md))
+	return hex.EncodeToString(sum[:])
+}
+
+// errWorkflow 表示当前状态或角色不允许该操作，对应 409/403。
+type errWorkflow struct {
+	status int
+	msg    string
+}
+
+func (e *errWorkflow) Error() string { return e.msg }
+
+// transition 在锁内检查并修改 session 的审核状态，成功后持久化。
+func (s *sessionStore) transition(id string, fn func(sess *generator.Session, wf *workflow) error) (workflow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.lookupLocked(id)
+	if !ok {
+		return workflow{}, &errWorkflow{http.StatusNotFound, "session not found"}
+	}
+	wf := entry.workflow
+	wf.Events = append([]workflowEvent(nil), wf.Events...)
+	if err := fn(entry.sess, &wf); err != nil {
+		return workflow{}, err
+	}
+	entry.workflow = wf
+	s.persistLocked(entry)
+	return wf, nil
+}
+
+// access 返回 session 的所有者与审核状态。
+func (s *sessionStore) access(id string) (owner, status string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.lookupLocked(id)
+	if !ok {
+		return "", "", false
+	}
+	return entry.sess.Owner, entry.workflow.status(), true
+}
+
+// workflowOf 返回 session 的审核状态副本。
+func (s *sessionStore) workflowOf(id string) (workflow, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.lookupLocked(id)
+	if !ok {
+		return workflow{}, false
+	}
+	return entry.workflow, true
+}
+
+// workflowEnforced 表示是否强制审核流程：启用登录后才有角色，未启用时发布不受限制。
+func (s *Server) workflowEnforced() bool {
+	return s.auth != nil
+}
+
+// hasRole 判断当前用户是否拥有角色；未启用登录时视为拥有全部角色。
+func (s *Server) hasRole(r *http.Request, role string) bool {
+	if s.auth == nil {
+		return true
+	}
+	u, ok := r.Context().Value(authUserKey{}).(*authUser)
+	if !ok {
+		return false
+	}
+	if u.Admin {
+		return true
+	}
+	for _, have := range u.Roles {
+		if have == role {
+			return true
+		}
+	}
+	return false
+}
+
+// canReview 判断审核人或发布人能否查看处于 status 的他人稿件：提交审核后才可见；未启用登录时没有审核人。
+func (s *Server) canReview(r *http.Request, status string) bool {
+	return s.auth != nil && status != wfDraft && (s.hasRole(r, roleReviewer) || s.hasRole(r, rolePublisher))
+}
+
+// checkPublishable 校验发布权限与审核状态：需要 publisher 角色，稿件已审核通过且正文未改动。
+func (s *Server) checkPublishable(r *http.Request, id, markdown string) error {
+	if !s.workflowEnforced() {
+		return nil
+	}
+	if !s.hasRole(r, rolePublisher) {
+		return &errWorkflow{http.StatusForbidden, "publisher role required"}
+	}
+	wf, _ := s.store.workflowOf(id)
+	if wf.status() != wfApproved {
+		return &errWorkflow{http.StatusConflict, fmt.Sprintf("draft is %s; it must be approved before publishing", wf.status())}
+	}
+	if draftHash(markdown) != wf.ApprovedHash {
+		return &errWorkflow{http.StatusConflict, "draft changed after approval; submit it for review again"}
+	}
+	return nil
+}
+
+// markPublished 在发布成功后把审核状态改为 published 并记录 media_id。
+func (s *Server) markPublished(id, user, mediaID string) {
+	if _, _, ok := s.store.access(id); !ok {
+		return
+	}
+	_, err := s.store.transition(id, func(sess *generator.Session, wf *workflow) error {
+		from := wf.status()
+		wf.Status = wfPublished
+		wf.Events = append(wf.Events, workflowEvent{
+			Action: "publish", From: from, To: wfPublished, User: user,
+			Title: sess.Draft.Title, DraftHash: wf.ApprovedHash, MediaID: mediaID, At: time.Now(),
+		})
+		return nil
+	})
+	if err != nil {
+		log.Printf("[workflow] session=%s mark published failed: %v", id, err)
+		return
+	}
+	s.events.publish(id, eventWorkflowChanged, map[string]string{"status": wfPublished, "action": "publish", "user": user})
+}
+
+type workflowReq struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+// handleWorkflow 查看审核状态与审核记录（GET），或执行 submit/approve/reject（POST）。
+// Path: GET /api/sessions/{id}/workflow
+// Path: POST /api/sessions/{id}/submit|approve|reject
+func (s *Server) handleWorkflow(w http.ResponseWriter, r *http.Request, id, action string) {
+	if action == "workflow" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		wf, ok := s.store.workflowOf(id)
+		if !ok {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		s.writeWorkflow(w, id, wf)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req workflowReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	wf, err := s.applyWorkflow(r, id, action, req.Comment)
+	if err != nil {
+		var wfErr *errWorkflow
+		if errors.As(err, &wfErr) {
+			http.Error(w, wfErr.msg, wfErr.status)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeWorkflow(w, id, wf)
+}
+
+// applyWorkflow 以当前用户执行 submit/approve/reject，成功后记录日志并推送状态变化；
+// 状态或角色不允许时返回 *errWorkflow。
+func (s *Server) applyWorkflow(r *http.Request, id, action, comment string) (workflow, error) {
+	user := currentUser(r)
+	wf, err := s.store.transition(id, func(sess *generator.Session, wf *workflow) error {
+		from := wf.status()
+		ev := workflowEvent{Action: action, From: from, User: user, Comment: strings.TrimSpace(comment), Title: sess.Draft.Title, At: time.Now()}
+		switch action {
+		case "submit":
+			if !s.canAccess(r, sess.Owner) {
+				return &errWorkflow{http.StatusForbidden, "only the author can submit"}
+			}
+			if from == wfSubmitted {
+				return &errWorkflow{http.StatusConflict, "draft is already submitted"}
+			}
+			if sess.Draft.Markdown == "" {
+				return &errWorkflow{http.StatusConflict, "draft is empty; generate first"}
+			}
+			ev.To = wfSubmitted
+		case "approve", "reject":
+			if !s.hasRole(r, roleReviewer) {
+				return &errWorkflow{http.StatusForbidden, "reviewer role required"}
+			}
+			// 审核人不能审核自己的稿件（管理员除外）。
+			if s.workflowEnforced() && sess.Owner == user && !isAdmin(r) {
+				return &errWorkflow{http.StatusForbidden, "reviewers cannot review their own drafts"}
+			}
+			if from != wfSubmitted && !(action == "reject" && from == wfApproved) {
+				return &errWorkflow{http.StatusConflict, fmt.Sprintf("draft is %s; only submitted drafts can be %sd", from, action)}
+			}
+			ev.To = wfDraft
+			if action == "approve" {
+				ev.To = wfApproved
+				wf.ApprovedHash = draftHash(sess.Draft.Markdown)
+			} else {
+				wf.ApprovedHash = ""
+			}
+		}
+		ev.DraftHash = draftHash(sess.Draft.Markdown)
+		wf.Status = ev.To
+		wf.Events = append(wf.Events, ev)
+		return nil
+	})
+	if err != nil {
+		return workflow{}, err
+	}
+	log.Printf("[workflow] session=%s %s by %q -> %s", id, action, user, wf.Status)
+	s.events.publish(id, eventWorkflowChanged, map[string]string{"status": wf.Status, "action": action, "user": user})
+	return wf, nil
+}
+
+// writeWorkflow 返回审核状态；stale 表示审核通过后正文又被修改，需要重新提交。
+func (s *Server) writeWorkflow(w http.ResponseWriter, id string, wf workflow) {
+	stale := false
+	if wf.status() == wfApproved {
+		if sess, ok := s.store.get(id); ok {
+			stale = draftHash(sess.Draft.Markdown) != wf.ApprovedHash
+		}
+	}
+	events := wf.Events
+	if events == nil {
+		events = []workflowEvent{}
+	}
+	writeJSON(w, map[string]any{"session_id": id, "status": wf.status(), "stale": stale, "events": events})
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// runStats 处理 `stats` 子命令：显示采集到的图文数据，或以 collect 立即从数据统计接口采集。
+func runStats(args []string) error {
+	if len(args) > 0 && args[0] == "collect" {
+		return runStatsCollect(args[1:])
+	}
+	fs := newFlagSet("stats", "[flags]",
+		"Show read, share and favorite counts of published articles, or daily follower growth with\n"+
+			"--followers, collected by the server (analytics in the config) or by 'stats collect'.\n"+
+			"Run 'stats collect -h' to fetch them now.")
+	configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+	since := fs.String("since", "", "only show data on or after this date (YYYY-MM-DD, default 30 days ago)")
+	until := fs.String("until", "", "only show data on or before this date (YYYY-MM-DD)")
+	query := fs.String("q", "", "search title")
+	daily := fs.Bool("daily", false, "also show the account's daily totals")
+	followers := fs.Bool("followers", false, "show daily follower growth with the articles published on each day instead")
+	_ = fs.Parse(args)
+
+	filter := publisher.AnalyticsFilter{Since: *since, Until: *until, Query: *query}
+	if filter.Since == "" {
+		filter.Since = time.Now().AddDate(0, 0, -30).Format("2006-01-02")
+	}
+	for name, v := range map[string]string{"--since": filter.Since, "--until": filter.Until} {
+		if _, err := time.Parse("2006-01-02", v); v != "" && err != nil {
+			return invalidf("invalid %s %q: use YYYY-MM-DD", name, v)
+		}
+	}
+	cfg, err := publisher.LoadConfig(publisher.ResolveConfigPath(*configPath))
+	if err != nil {
+		return err
+	}
+	data, err := publisher.NewAnalyticsStore(cfg.AnalyticsPath).Load()
+	if err != nil {
+		return err
+	}
+	if *followers {
+		showFollowers(data.FollowerReport(filter))
+		return nil
+	}
+	report := data.Report(filter)
+	output(report, func() {
+		if len(report.Articles) == 0 {
+			fmt.Fprintln(os.Stderr, "no article data; run 'stats collect' or enable analytics in the config")
+			return
+		}
+		// 有累计数据时显示累计数，否则显示区间内每日数据之和。
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "PUBLISHED\tREADS\tREADERS\tSHARES\tFAVORITES\tDELIVERED\tTITLE")
+		for _, a := range report.Articles {
+			stats, delivered := a.Sum, "-"
+			if a.Total != nil {
+				stats, delivered = a.Total.ReadStats, fmt.Sprint(a.Total.TargetUser)
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\t%s\n", a.PublishDate, stats.ReadCount, stats.ReadUser, stats.ShareCount, stats.FavoriteCount, delivered, truncate(a.Title, 40))
+		}
+		tw.Flush()
+		if *daily && len(report.Days) > 0 {
+			fmt.Println()
+			tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "DATE\tREADS\tREADERS\tSHARES\tFAVORITES")
+			for _, d := range report.Days {
+				fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", d.Date, d.ReadCount, d.ReadUser, d.ShareCount, d.FavoriteCount)
+			}
+			tw.Flush()
+		}
+		if !report.UpdatedAt.IsZero() {
+			fmt.Fprintf(os.Stderr, "updated %s\n", report.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		}
+	})
+	return nil
+}
+
+// showFollowers 输出每天的粉丝变化，与当天的阅读次数和发表的文章对照。
+func showFollowers(report publisher.FollowerReport) {
+	output(report, func() {
+		if len(report.Days) == 0 {
+			fmt.Fprintln(os.Stderr, "no follower data; run 'stats collect' or enable analytics in the config")
+			return
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "DATE\tNEW\tCANCEL\tNET\tFOLLOWERS\tREADS\tPUBLISHED")
+		for _, d := range report.Days {
+			titles := make([]string, 0, len(d.Articles))
+			for _, a := range d.Articles {
+				titles = append(titles, a.Title)
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%+d\t%d\t%d\t%s\n", d.Date, d.NewUser, d.CancelUser, d.NetGrowth, d.Cumulate, d.ReadCount, truncate(strings.Join(titles, " / "), 50))
+		}
+		tw.Flush()
+		fmt.Fprintf(os.Stderr, "net growth %+d (%d new, %d canceled), followers %d -> %d\n", report.NetGrowth, report.NewUser, report.CancelUser, report.Start, report.End)
+	})
+}
+
+// runStatsCollect 从数据统计接口采集前一天起若干天的数据，写入 analytics_path。
+func runStatsCollect(args []string) error {
+	fs := newFlagSet("stats collect", "[flags]",
+		"Fetch article statistics for the days before today from the WeChat datacube API and save\n"+
+			"them to analytics_path. Days already collected are skipped unless --force is set; the\n"+
+			"cumulative counts of articles published in these days are always refreshed.\n"+
+			"The official account must be verified to use the datacube API.")
+	configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+	days := fs.Int("days", publisher.DefaultAnalyticsDays, "number of days before today to collect (1-30)")
+	force := fs.Bool("force", false, "fetch daily data again for days already collected")
+	fs.BoolVar(&verbose, "v", false, "enable info logs")
+	_ = fs.Parse(args)
+	if *days < 1 || *days > 30 {
+		return invalidf("--days must be between 1 and 30")
+	}
+	cfg, err := publisher.LoadConfig(publisher.ResolveConfigPath(*configPath))
+	if err != nil {
+		return err
+	}
+	p, err := publisher.New(cfg, nil, verbose, log.Default())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	res, err := publisher.CollectAnalytics(ctx, p, publisher.NewAnalyticsStore(cfg.AnalyticsPath), time.Now(), *days, *force)
+	if err != nil {
+		return err
+	}
+	output(res, func() {
+		collected := "none"
+		if len(res.Collected) > 0 {
+			collected = strings.Join(res.Collected, ", ")
+		}
+		fmt.Printf("collected days: %s\narticle rows: %d, cumulative totals: %d, follower days: %d\n", collected, res.Articles, res.Totals, res.Followers)
+	})
+	return nil
+}
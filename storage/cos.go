@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// cosStore 使用腾讯云 COS 保存文件（bucket 为带 APPID 的完整名称，如 example-1250000000），
+// 请求按 COS 签名（q-sign-algorithm=sha1）认证。
+type cosStore struct {
+	client    *http.Client
+	endpoint  *url.URL
+	bucket    string
+	secretID  string
+	secretKey string
+	prefix    string
+}
+
+func (s *cosStore) do(ctx context.Context, method, key string, body []byte, contentType string) ([]byte, error) {
+	key = objectKey(s.prefix, key)
+	u := objectURL(s.endpoint, s.bucket+"."+s.endpoint.Host, "/"+key)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, time.Now())
+	return send(s.client, req)
+}
+
+func (s *cosStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	_, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	return err
+}
+
+func (s *cosStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.do(ctx, http.MethodGet, key, nil, "")
+}
+
+func (s *cosStore) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, http.MethodDelete, key, nil, "")
+	return err
+}
+
+func (s *cosStore) Name() string {
+	return "cos://" + path.Join(s.bucket, s.prefix)
+}
+
+// sign 按 COS 请求签名规则生成 Authorization 头，签名 host 头，有效期 1 小时。
+func (s *cosStore) sign(req *http.Request, now time.Time) {
+	keyTime := fmt.Sprintf("%d;%d", now.Unix()-60, now.Unix()+3600)
+	signKey := hex.EncodeToString(hmacSHA1([]byte(s.secretKey), keyTime))
+	httpString := fmt.Sprintf("%s\n%s\n\nhost=%s\n", strings.ToLower(req.Method), req.URL.Path, url.QueryEscape(req.URL.Host))
+	sum := sha1.Sum([]byte(httpString))
+	stringToSign := "sha1\n" + keyTime + "\n" + hex.EncodeToString(sum[:]) + "\n"
+	signature := hex.EncodeToString(hmacSHA1([]byte(signKey), stringToSign))
+	req.Header.Set("Authorization", "q-sign-algorithm=sha1&q-ak="+s.secretID+
+		"&q-sign-time="+keyTime+"&q-key-time="+keyTime+
+		"&q-header-list=host&q-url-param-list=&q-signature="+signature)
+}
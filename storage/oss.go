@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ossStore 使用阿里云 OSS 保存文件，请求按 OSS 签名（HMAC-SHA1）认证。
+type ossStore struct {
+	client    *http.Client
+	endpoint  *url.URL
+	bucket    string
+	accessKey string
+	secretKey string
+	prefix    string
+}
+
+func (s *ossStore) do(ctx context.Context, method, key string, body []byte, contentType string) ([]byte, error) {
+	key = objectKey(s.prefix, key)
+	u := objectURL(s.endpoint, s.bucket+"."+s.endpoint.Host, "/"+key)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, "/"+s.bucket+"/"+key, time.Now())
+	return send(s.client, req)
+}
+
+func (s *ossStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	_, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	return err
+}
+
+func (s *ossStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.do(ctx, http.MethodGet, key, nil, "")
+}
+
+func (s *ossStore) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, http.MethodDelete, key, nil, "")
+	return err
+}
+
+func (s *ossStore) Name() string {
+	return "oss://" + path.Join(s.bucket, s.prefix)
+}
+
+// sign 按 OSS 的 Authorization 头签名：resource 为 /bucket/object（不编码）。
+func (s *ossStore) sign(req *http.Request, resource string, now time.Time) {
+	date := now.UTC().Format(http.TimeFormat)
+	req.Header.Set("Date", date)
+	var ossHeaders []string
+	for k, v := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-oss-") {
+			ossHeaders = append(ossHeaders, lk+":"+strings.TrimSpace(strings.Join(v, ","))+"\n")
+		}
+	}
+	sort.Strings(ossHeaders)
+	stringToSign := req.Method + "\n" +
+		req.Header.Get("Content-MD5") + "\n" +
+		req.Header.Get("Content-Type") + "\n" +
+		date + "\n" +
+		strings.Join(ossHeaders, "") + resource
+	signature := base64.StdEncoding.EncodeToString(hmacSHA1([]byte(s.secretKey), stringToSign))
+	req.Header.Set("Authorization", "OSS "+s.accessKey+":"+signature)
+}
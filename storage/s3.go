@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"auto_wechat_article_publisher/internal/sigv4"
+)
+
+// s3Store 使用 AWS S3 或兼容服务（MinIO 等，配合 path_style）保存文件，请求按 Signature V4 签名。
+type s3Store struct {
+	client    *http.Client
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	prefix    string
+	pathStyle bool
+}
+
+func (s *s3Store) url(key string) *url.URL {
+	key = objectKey(s.prefix, key)
+	if s.pathStyle {
+		return objectURL(s.endpoint, s.endpoint.Host, "/"+s.bucket+"/"+key)
+	}
+	return objectURL(s.endpoint, s.bucket+"."+s.endpoint.Host, "/"+key)
+}
+
+func (s *s3Store) do(ctx context.Context, method, key string, body []byte, contentType string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.url(key).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, body, time.Now())
+	return send(s.client, req)
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	_, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	return err
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.do(ctx, http.MethodGet, key, nil, "")
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, http.MethodDelete, key, nil, "")
+	return err
+}
+
+func (s *s3Store) Name() string {
+	return "s3://" + path.Join(s.bucket, s.prefix)
+}
+
+// sign 按 AWS Signature V4 为请求签名，签名 host 与请求中已有的全部头。
+func (s *s3Store) sign(req *http.Request, payload []byte, now time.Time) {
+	sigv4.Sign(req, payload, sigv4.Credentials{AccessKey: s.accessKey, SecretKey: s.secretKey}, s.region, "s3", now)
+}
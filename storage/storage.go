@@ -0,0 +1,168 @@
+// Package storage 持久化保存上传的图片与封面，使服务运行在无持久磁盘的容器中时，重建后仍能取回文件。
+// 服务仍以本地 uploads/ 目录作为工作副本，写入时同步保存到这里，本地缺失时再从这里取回。
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound 表示对象不存在。
+var ErrNotFound = errors.New("storage: object not found")
+
+// Store 为上传文件的存储；key 为相对上传目录、以 / 分隔的路径，如 alice/cover_1.png。
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get 读取对象，不存在时返回 ErrNotFound。
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete 删除对象，不存在时不报错。
+	Delete(ctx context.Context, key string) error
+	// Name 返回存储的描述，如 s3://bucket/prefix，用于日志与健康检查。
+	Name() string
+}
+
+// Config 为存储配置，字段含义见 publisher.StorageConfig。
+type Config struct {
+	Type      string
+	Dir       string
+	Bucket    string
+	Region    string
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Prefix    string
+	PathStyle bool
+}
+
+// New 按配置创建存储：local、s3、oss 或 cos。
+func New(cfg Config) (Store, error) {
+	prefix := strings.Trim(cfg.Prefix, "/")
+	client := &http.Client{Timeout: 60 * time.Second}
+	switch cfg.Type {
+	case "local":
+		if cfg.Dir == "" {
+			return nil, errors.New("storage: local requires dir")
+		}
+		return &localStore{dir: cfg.Dir}, nil
+	case "s3":
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = "s3." + cfg.Region + ".amazonaws.com"
+		}
+		u, err := parseEndpoint(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return &s3Store{client: client, endpoint: u, bucket: cfg.Bucket, region: cfg.Region, accessKey: cfg.AccessKey, secretKey: cfg.SecretKey, prefix: prefix, pathStyle: cfg.PathStyle}, nil
+	case "oss":
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = "oss-" + cfg.Region + ".aliyuncs.com"
+		}
+		u, err := parseEndpoint(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return &ossStore{client: client, endpoint: u, bucket: cfg.Bucket, accessKey: cfg.AccessKey, secretKey: cfg.SecretKey, prefix: prefix}, nil
+	case "cos":
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = "cos." + cfg.Region + ".myqcloud.com"
+		}
+		u, err := parseEndpoint(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return &cosStore{client: client, endpoint: u, bucket: cfg.Bucket, secretID: cfg.AccessKey, secretKey: cfg.SecretKey, prefix: prefix}, nil
+	default:
+		return nil, fmt.Errorf("storage: unknown type %q (want local, s3, oss or cos)", cfg.Type)
+	}
+}
+
+// Ping 读取一个不存在的对象来检查存储是否可用（凭据、网络与 bucket）。
+func Ping(ctx context.Context, st Store) error {
+	_, err := st.Get(ctx, ".healthcheck")
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// parseEndpoint 解析服务地址，未写协议时使用 https。
+func parseEndpoint(endpoint string) (*url.URL, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("storage: invalid endpoint %q", endpoint)
+	}
+	return &url.URL{Scheme: u.Scheme, Host: u.Host}, nil
+}
+
+// objectKey 给 key 加上前缀。
+func objectKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return path.Join(prefix, key)
+}
+
+// escapePath 按 RFC 3986 编码路径，保留 /；S3 签名与请求都使用这一编码。
+func escapePath(p string) string {
+	var sb strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+			sb.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&sb, "%%%02X", c)
+	}
+	return sb.String()
+}
+
+// objectURL 返回 host 下对象的地址，路径按 escapePath 编码。
+func objectURL(base *url.URL, host, p string) *url.URL {
+	return &url.URL{Scheme: base.Scheme, Host: host, Path: p, RawPath: escapePath(p)}
+}
+
+// send 发送已签名的请求并读取响应；404 返回 ErrNotFound，其余非 2xx 返回包含响应内容的错误。
+func send(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > 300 {
+			msg = msg[:300]
+		}
+		return nil, fmt.Errorf("storage: %s %s: %s: %s", req.Method, req.URL.Redacted(), resp.Status, msg)
+	}
+	return body, nil
+}
+
+func hmacSHA1(key []byte, data string) []byte {
+	h := hmac.New(sha1.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,778 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"auto_wechat_article_publisher/publisher"
+)
+
+// newPublisher 读取配置并创建 Publisher，供 draft/material/preview 子命令使用。
+func newPublisher(configPath string) (*publisher.Publisher, error) {
+	cfg, err := publisher.LoadConfig(publisher.ResolveConfigPath(configPath))
+	if err != nil {
+		return nil, err
+	}
+	return publisher.New(cfg, nil, verbose, log.Default())
+}
+
+// runDraft 管理草稿箱：list / update / delete。
+func runDraft(args []string) error {
+	usage := fmt.Errorf("usage: %s draft list [--offset n] [--count n] | update <media_id> [--index n] [--md article.md] [--title t] [--cover cover.jpg] [--author a] [--digest d] | delete <media_id> [--yes]", os.Args[0])
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		return usage
+	}
+	action := args[0]
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	switch action {
+	case "list":
+		fs := newFlagSet("draft list", "[flags]", "List drafts in the draft box, most recently updated first.")
+		configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+		offset := fs.Int("offset", 0, "number of drafts to skip")
+		count := fs.Int("count", 20, "number of drafts to list (1-20)")
+		fs.BoolVar(&verbose, "v", false, "enable info logs")
+		fs.Parse(args[1:])
+		if *count < 1 || *count > 20 {
+			return invalidf("--count must be between 1 and 20")
+		}
+		p, err := newPublisher(*configPath)
+		if err != nil {
+			return err
+		}
+		items, total, err := p.ListDrafts(ctx, *offset, *count)
+		if err != nil {
+			return err
+		}
+		if items == nil {
+			items = []publisher.DraftItem{}
+		}
+		output(map[string]any{"drafts": items, "total": total}, func() {
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "MEDIA_ID\tUPDATED\tARTICLES\tTITLE")
+			for _, it := range items {
+				titles := make([]string, 0, len(it.Articles))
+				for _, a := range it.Articles {
+					titles = append(titles, a.Title)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", it.MediaID, it.UpdateTime.Format("2006-01-02 15:04"), len(it.Articles), truncate(strings.Join(titles, " / "), 40))
+			}
+			w.Flush()
+			fmt.Fprintf(os.Stderr, "%d-%d of %d drafts\n", min(*offset+1, total), *offset+len(items), total)
+		})
+		return nil
+
+	case "update":
+		fs := newFlagSet("draft update", "<media_id> [flags]", "Update one article of a draft; empty flags keep the current value.")
+		configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+		index := fs.Int("index", 0, "index of the article in the draft (0 for the first)")
+		mdPath := fs.String("md", "", "replace the content with this markdown file")
+		title := fs.String("title", "", "new title")
+		cover := fs.String("cover", "", "replace the cover with this image")
+		author := fs.String("author", "", "new author")
+		digest := fs.String("digest", "", "new digest")
+		fs.BoolVar(&verbose, "v", false, "enable info logs")
+		ids, err := parseInterspersed(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(ids) != 1 {
+			return usage
+		}
+		upd := publisher.DraftUpdate{Index: *index, Title: *title, Author: *author, Digest: *digest, MarkdownPath: *mdPath, CoverPath: *cover}
+		if upd == (publisher.DraftUpdate{Index: *index}) {
+			return invalidf("nothing to update: set at least one of --md, --title, --cover, --author, --digest")
+		}
+		p, err := newPublisher(*configPath)
+		if err != nil {
+			return err
+		}
+		if err := p.UpdateDraft(ctx, ids[0], upd); err != nil {
+			return err
+		}
+		output(map[string]any{"media_id": ids[0], "index": *index}, func() { fmt.Printf("updated draft %s\n", ids[0]) })
+		return nil
+
+	case "delete":
+		fs := newFlagSet("draft delete", "<media_id> [--yes]", "Delete a draft. Deleted drafts cannot be recovered.")
+		configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+		yes := fs.Bool("yes", false, "do not ask for confirmation")
+		fs.BoolVar(&verbose, "v", false, "enable info logs")
+		ids, err := parseInterspersed(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(ids) != 1 {
+			return usage
+		}
+		if !*yes {
+			fmt.Fprintf(os.Stderr, "delete draft %s? this cannot be undone [y/N]: ", ids[0])
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+				return errors.New("aborted")
+			}
+		}
+		p, err := newPublisher(*configPath)
+		if err != nil {
+			return err
+		}
+		if err := p.DeleteDraft(ctx, ids[0]); err != nil {
+			return err
+		}
+		output(map[string]any{"media_id": ids[0], "deleted": true}, func() { fmt.Printf("deleted draft %s\n", ids[0]) })
+		return nil
+	}
+	return usage
+}
+
+// runMaterial 列出永久素材。
+func runMaterial(args []string) error {
+	usage := fmt.Errorf("usage: %s material list [--type image|video|voice|news] [--offset n] [--count n]", os.Args[0])
+	if len(args) == 0 || args[0] != "list" {
+		return usage
+	}
+	fs := newFlagSet("material list", "[flags]", "List permanent materials of the official account.")
+	configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+	kind := fs.String("type", "image", "material type: image, video, voice or news")
+	offset := fs.Int("offset", 0, "number of materials to skip")
+	count := fs.Int("count", 20, "number of materials to list (1-20)")
+	fs.BoolVar(&verbose, "v", false, "enable info logs")
+	fs.Parse(args[1:])
+	if *count < 1 || *count > 20 {
+		return invalidf("--count must be between 1 and 20")
+	}
+	p, err := newPublisher(*configPath)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	items, total, err := p.ListMaterials(ctx, *kind, *offset, *count)
+	if err != nil {
+		return err
+	}
+	if items == nil {
+		items = []publisher.MaterialItem{}
+	}
+	output(map[string]any{"type": *kind, "materials": items, "total": total}, func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "MEDIA_ID\tUPDATED\tNAME\tURL")
+		for _, it := range items {
+			name := it.Name
+			if it.Title != "" {
+				name = it.Title
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", it.MediaID, it.UpdateTime.Format("2006-01-02 15:04"), truncate(name, 30), it.URL)
+		}
+		w.Flush()
+		fmt.Fprintf(os.Stderr, "%d-%d of %d %s materials\n", min(*offset+1, total), *offset+len(items), total, *kind)
+	})
+	return nil
+}
+
+// runComment 管理已群发图文的留言：list / open / close / elect / unelect / reply。
+func runComment(args []string) error {
+	usage := fmt.Errorf("usage: %s comment list <article> [--type all|normal|elected] [--offset n] [--count n] | open <article> | close <article> | elect <article> <comment_id> | unelect <article> <comment_id> | reply <article> <comment_id> <text>", os.Args[0])
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		return usage
+	}
+	action := args[0]
+	argsUsage, want := "<article>", 1
+	var desc string
+	switch action {
+	case "list":
+		argsUsage, desc = "<article> [flags]", "List comments of a published article, oldest first."
+	case "open":
+		desc = "Open comments of a published article."
+	case "close":
+		desc = "Close comments of a published article."
+	case "elect":
+		argsUsage, want, desc = "<article> <comment_id>", 2, "Mark a comment as elected so it is shown under the article."
+	case "unelect":
+		argsUsage, want, desc = "<article> <comment_id>", 2, "Remove a comment from the elected comments."
+	case "reply":
+		argsUsage, want, desc = "<article> <comment_id> <text>", 3, "Reply to a comment as the official account."
+	default:
+		return usage
+	}
+	fs := newFlagSet("comment "+action, argsUsage, desc+"\n<article> is the msg_data_id of the mass message, or the msgid shown by 'stats --json'\n(msg_data_id_n for the n-th article of the message).")
+	configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+	kind := fs.String("type", "all", "comment type: all, normal or elected (list only)")
+	offset := fs.Int("offset", 0, "number of comments to skip (list only)")
+	count := fs.Int("count", publisher.MaxCommentsCount, fmt.Sprintf("number of comments to list (1-%d, list only)", publisher.MaxCommentsCount))
+	fs.BoolVar(&verbose, "v", false, "enable info logs")
+	rest, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	// 回复内容可以不加引号。
+	if action == "reply" && len(rest) > want {
+		rest = append(rest[:2], strings.Join(rest[2:], " "))
+	}
+	if len(rest) != want {
+		return usage
+	}
+	target, err := publisher.ParseCommentTarget(rest[0])
+	if err != nil {
+		return invalidf("%v", err)
+	}
+	var commentID int64
+	if want > 1 {
+		if commentID, err = strconv.ParseInt(rest[1], 10, 64); err != nil || commentID <= 0 {
+			return invalidf("invalid comment id %q", rest[1])
+		}
+	}
+	commentsKind, err := publisher.ParseCommentsKind(*kind)
+	if err != nil {
+		return invalidf("%v", err)
+	}
+	if *count < 1 || *count > publisher.MaxCommentsCount {
+		return invalidf("--count must be between 1 and %d", publisher.MaxCommentsCount)
+	}
+	cfg, err := publisher.LoadConfig(publisher.ResolveConfigPath(*configPath))
+	if err != nil {
+		return err
+	}
+	p, err := publisher.New(cfg, nil, verbose, log.Default())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	switch action {
+	case "list":
+		comments, total, err := p.ListComments(ctx, target, commentsKind, *offset, *count)
+		if err != nil {
+			return err
+		}
+		output(map[string]any{"article": target.String(), "comments": comments, "total": total}, func() {
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tTIME\tELECTED\tCOMMENT\tREPLY")
+			for _, c := range comments {
+				elected, reply := "", ""
+				if c.Elected {
+					elected = "yes"
+				}
+				if c.Reply != nil {
+					reply = truncate(c.Reply.Content, 30)
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", c.ID, c.CreateTime.Format("2006-01-02 15:04"), elected, truncate(c.Content, 40), reply)
+			}
+			w.Flush()
+			fmt.Fprintf(os.Stderr, "%d-%d of %d comments\n", min(*offset+1, total), *offset+len(comments), total)
+		})
+		return nil
+	case "open", "close":
+		err = p.SetCommentsOpen(ctx, target, action == "open")
+	case "elect", "unelect":
+		err = p.ElectComment(ctx, target, commentID, action == "elect")
+	case "reply":
+		err = p.ReplyComment(ctx, target, commentID, rest[2])
+	}
+	if err != nil {
+		return err
+	}
+	detail := action + " comments"
+	if commentID != 0 {
+		detail = fmt.Sprintf("%s comment %d", action, commentID)
+	}
+	if action == "reply" {
+		detail += ": " + truncate(rest[2], 200)
+	}
+	recordCLIAudit(cfg, publisher.AuditEntry{Action: publisher.AuditCommentModerated, Target: "article:" + target.String(), Detail: detail})
+	output(map[string]any{"article": target.String(), "action": action, "comment_id": commentID}, func() {
+		fmt.Printf("%s: %s done\n", target, action)
+	})
+	return nil
+}
+
+// runMassSend 群发草稿：send / preview / status / list。群发无法撤回推送，send 需要 --yes-i-mean-it。
+func runMassSend(args []string) error {
+	usage := fmt.Errorf("usage: %s masssend send <media_id> [--tag id|name] [--ignore-reprint] --yes-i-mean-it | preview <media_id> (--to wxname | --openid id) | status <msg_id> | list [--status s]", os.Args[0])
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		return usage
+	}
+	action := args[0]
+	var argsUsage, desc string
+	switch action {
+	case "send":
+		argsUsage, desc = "<media_id> [flags]", "Mass send a draft to all followers, or to the followers with --tag. Messages cannot be\n"+
+			"recalled once pushed, so without --yes-i-mean-it only the draft titles are shown.\n"+
+			"Use 'masssend preview' first to check the layout on a phone."
+	case "preview":
+		argsUsage, desc = "<media_id> (--to wxname | --openid id)", "Send a draft to a follower's phone for preview (same as preview --media-id)."
+	case "status":
+		argsUsage, desc = "<msg_id>", "Show the WeChat send status of a mass message: SEND_SUCCESS, SENDING, SEND_FAIL or DELETE."
+	case "list":
+		argsUsage, desc = "[flags]", "List mass sends requested on the server or sent with 'masssend send'."
+	default:
+		return usage
+	}
+	fs := newFlagSet("masssend "+action, argsUsage, desc)
+	configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+	tagRef := fs.String("tag", "", "only send to followers with this tag, by id or name (send only, default all followers; see 'tag list')")
+	ignoreReprint := fs.Bool("ignore-reprint", false, "continue when WeChat judges the article a reprint (send only)")
+	confirmed := fs.Bool("yes-i-mean-it", false, "actually send; messages cannot be recalled (send only)")
+	to := fs.String("to", "", "WeChat ID of the receiver, who must follow the account (preview only)")
+	openid := fs.String("openid", "", "openid of the receiver (preview only, used when --to is empty)")
+	status := fs.String("status", "", "filter by status: pending, sending, sent, failed or canceled (list only)")
+	fs.BoolVar(&verbose, "v", false, "enable info logs")
+	rest, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	want := 1
+	if action == "list" {
+		want = 0
+	}
+	if len(rest) != want {
+		return usage
+	}
+	cfg, err := publisher.LoadConfig(publisher.ResolveConfigPath(*configPath))
+	if err != nil {
+		return err
+	}
+	store := publisher.NewMassSendStore(cfg.MassSendPath)
+	if action == "list" {
+		items, err := store.List(*status)
+		if err != nil {
+			return err
+		}
+		output(map[string]any{"mass_sends": items}, func() {
+			if len(items) == 0 {
+				fmt.Fprintln(os.Stderr, "no mass sends")
+				return
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tCREATED\tSTATUS\tMSG_ID\tBY\tTITLE")
+			for _, it := range items {
+				msgID := "-"
+				if it.MsgID != 0 {
+					msgID = strconv.FormatInt(it.MsgID, 10)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", it.ID, it.CreatedAt.Local().Format("2006-01-02 15:04"), it.Status, msgID, it.RequestedBy, truncate(it.Title, 40))
+			}
+			w.Flush()
+		})
+		return nil
+	}
+	p, err := publisher.New(cfg, nil, verbose, log.Default())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	switch action {
+	case "preview":
+		if *to == "" && *openid == "" {
+			return invalidf("--to or --openid is required")
+		}
+		if err := p.SendPreview(ctx, rest[0], *to, *openid); err != nil {
+			return err
+		}
+		output(map[string]any{"media_id": rest[0], "to": *to, "openid": *openid, "sent": true}, func() { fmt.Printf("sent preview of %s\n", rest[0]) })
+		return nil
+	case "status":
+		msgID, err := strconv.ParseInt(rest[0], 10, 64)
+		if err != nil || msgID <= 0 {
+			return invalidf("invalid msg_id %q", rest[0])
+		}
+		sendStatus, err := p.MassStatus(ctx, msgID)
+		if err != nil {
+			return err
+		}
+		output(map[string]any{"msg_id": msgID, "msg_status": sendStatus}, func() { fmt.Println(sendStatus) })
+		return nil
+	}
+
+	mediaID := rest[0]
+	articles, err := p.GetDraft(ctx, mediaID)
+	if err != nil {
+		return fmt.Errorf("media_id must be a draft in the draft box: %w", err)
+	}
+	titles := make([]string, 0, len(articles))
+	for _, a := range articles {
+		titles = append(titles, a.Title)
+	}
+	title := strings.Join(titles, " / ")
+	audience, tag := "all followers", publisher.UserTag{}
+	if *tagRef != "" {
+		if tag, err = p.ResolveTag(ctx, *tagRef); err != nil {
+			return cliTagError(err)
+		}
+		audience = fmt.Sprintf("%d followers with tag %s (id %d)", tag.Count, tag.Name, tag.ID)
+	}
+	if !*confirmed {
+		fmt.Fprintf(os.Stderr, "would mass send %s to %s:\n", mediaID, audience)
+		for _, t := range titles {
+			fmt.Fprintf(os.Stderr, "  %s\n", t)
+		}
+		return invalidf("refusing to mass send without --yes-i-mean-it; pushed messages cannot be recalled")
+	}
+	item, err := store.Add(publisher.MassSendRequest{MediaID: mediaID, Title: title, TagID: tag.ID, TagName: tag.Name, IgnoreReprint: *ignoreReprint, Status: publisher.MassSendSending, RequestedBy: cliUser(), ApprovedBy: cliUser()})
+	if err != nil {
+		return err
+	}
+	res, sendErr := p.MassSend(ctx, publisher.MassSendParams{MediaID: mediaID, TagID: tag.ID, IgnoreReprint: *ignoreReprint, ClientMsgID: "masssend-" + item.ID})
+	if _, err := store.Update(item.ID, func(it *publisher.MassSendRequest) error {
+		if sendErr != nil {
+			it.Status, it.Error = publisher.MassSendFailed, sendErr.Error()
+			return nil
+		}
+		it.Status, it.MsgID, it.MsgDataID = publisher.MassSendSent, res.MsgID, res.MsgDataID
+		return nil
+	}); err != nil {
+		log.Printf("[cli] record mass send failed: %v", err)
+	}
+	result := "sent"
+	if sendErr != nil {
+		result = "failed"
+	}
+	recordCLIAudit(cfg, publisher.AuditEntry{Action: publisher.AuditMassSendApproved, Detail: fmt.Sprintf("mass send %s of %s: %s", item.ID, mediaID, result)})
+	if sendErr != nil {
+		return sendErr
+	}
+	output(map[string]any{"id": item.ID, "media_id": mediaID, "msg_id": res.MsgID, "msg_data_id": res.MsgDataID}, func() {
+		fmt.Printf("mass sent %s to %s\nmsg_id: %d\nmsg_data_id: %d\n", mediaID, audience, res.MsgID, res.MsgDataID)
+	})
+	return nil
+}
+
+// runTag 管理用户标签：list / create / rename / delete / add / remove，群发时可用 masssend send --tag 只发给带标签的粉丝。
+func runTag(args []string) error {
+	usage := fmt.Errorf("usage: %s tag list | create <name> | rename <tag> <name> | delete <tag> [--yes] | add <tag> <openid>... | remove <tag> <openid>...", os.Args[0])
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		return usage
+	}
+	action := args[0]
+	argsUsage, want := "<tag>", 1
+	var desc string
+	switch action {
+	case "list":
+		argsUsage, want, desc = "[flags]", 0, "List user tags of the account with the number of followers of each."
+	case "create":
+		argsUsage, desc = "<name>", "Create a user tag (at most 30 characters)."
+	case "rename":
+		argsUsage, want, desc = "<tag> <name>", 2, "Rename a user tag."
+	case "delete":
+		argsUsage, desc = "<tag> [--yes]", "Delete a user tag; followers lose the tag. Tags 0-2 are reserved by WeChat."
+	case "add":
+		argsUsage, want, desc = "<tag> <openid>...", -1, "Tag followers by openid."
+	case "remove":
+		argsUsage, want, desc = "<tag> <openid>...", -1, "Remove the tag from followers by openid."
+	default:
+		return usage
+	}
+	if want != 0 && action != "create" {
+		desc += "\n<tag> is the tag id or name shown by 'tag list'."
+	}
+	fs := newFlagSet("tag "+action, argsUsage, desc)
+	configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+	yes := fs.Bool("yes", false, "do not ask for confirmation (delete only)")
+	fs.BoolVar(&verbose, "v", false, "enable info logs")
+	rest, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if (want >= 0 && len(rest) != want) || (want < 0 && len(rest) < 2) {
+		return usage
+	}
+	cfg, err := publisher.LoadConfig(publisher.ResolveConfigPath(*configPath))
+	if err != nil {
+		return err
+	}
+	p, err := publisher.New(cfg, nil, verbose, log.Default())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	switch action {
+	case "list":
+		tags, err := p.ListTags(ctx)
+		if err != nil {
+			return err
+		}
+		output(map[string]any{"tags": tags}, func() {
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tFOLLOWERS\tNAME")
+			for _, t := range tags {
+				fmt.Fprintf(w, "%d\t%d\t%s\n", t.ID, t.Count, t.Name)
+			}
+			w.Flush()
+		})
+		return nil
+	case "create":
+		tag, err := p.CreateTag(ctx, rest[0])
+		if err != nil {
+			return cliTagError(err)
+		}
+		recordCLIAudit(cfg, publisher.AuditEntry{Action: publisher.AuditTagChanged, Target: fmt.Sprintf("tag:%d", tag.ID), Detail: "create " + tag.Name})
+		output(tag, func() { fmt.Printf("created tag %s (id %d)\n", tag.Name, tag.ID) })
+		return nil
+	}
+
+	tag, err := p.ResolveTag(ctx, rest[0])
+	if err != nil {
+		return cliTagError(err)
+	}
+	var detail string
+	switch action {
+	case "rename":
+		detail, err = "rename to "+rest[1], p.RenameTag(ctx, tag.ID, rest[1])
+	case "delete":
+		if !*yes {
+			fmt.Fprintf(os.Stderr, "delete tag %s (id %d, %d followers)? [y/N]: ", tag.Name, tag.ID, tag.Count)
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+				return errors.New("aborted")
+			}
+		}
+		detail, err = "delete", p.DeleteTag(ctx, tag.ID)
+	case "add", "remove":
+		detail, err = fmt.Sprintf("%s %d users", action, len(rest)-1), p.TagUsers(ctx, tag.ID, rest[1:], action == "remove")
+	}
+	if err != nil {
+		return cliTagError(err)
+	}
+	recordCLIAudit(cfg, publisher.AuditEntry{Action: publisher.AuditTagChanged, Target: fmt.Sprintf("tag:%d", tag.ID), Detail: detail})
+	output(map[string]any{"id": tag.ID, "name": tag.Name, "action": action}, func() {
+		fmt.Printf("tag %s (id %d): %s done\n", tag.Name, tag.ID, action)
+	})
+	return nil
+}
+
+// cliTagError 把标签参数错误转为用法错误（退出码 3）。
+func cliTagError(err error) error {
+	if errors.Is(err, publisher.ErrInvalidTag) {
+		return invalidf("%v", err)
+	}
+	return err
+}
+
+// runQuota 查询接口调用额度，或在达到上限（45009）时把调用次数清零。
+func runQuota(args []string) error {
+	usage := fmt.Errorf("usage: %s quota show [--api /cgi-bin/draft/add,...] | clear [--yes]", os.Args[0])
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		return usage
+	}
+	action := args[0]
+	var desc string
+	switch action {
+	case "show":
+		desc = "Show today's call quota of WeChat APIs (daily limit, used and remaining)."
+	case "clear":
+		desc = "Reset the call count of all WeChat APIs of the account after hitting the quota\n(errcode 45009). WeChat allows 10 resets a month."
+	default:
+		return usage
+	}
+	fs := newFlagSet("quota "+action, "[flags]", desc)
+	configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+	apis := fs.String("api", "", "comma-separated API paths to show (show only, default the publish, upload and mass send APIs)")
+	yes := fs.Bool("yes", false, "do not ask for confirmation (clear only)")
+	fs.BoolVar(&verbose, "v", false, "enable info logs")
+	rest, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return usage
+	}
+	cfg, err := publisher.LoadConfig(publisher.ResolveConfigPath(*configPath))
+	if err != nil {
+		return err
+	}
+	p, err := publisher.New(cfg, nil, verbose, log.Default())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if action == "show" {
+		var paths []string
+		for _, a := range strings.Split(*apis, ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				paths = append(paths, a)
+			}
+		}
+		quotas := p.QuotaReport(ctx, paths)
+		output(map[string]any{"quotas": quotas}, func() {
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "API\tDAILY_LIMIT\tUSED\tREMAIN\tERROR")
+			for _, q := range quotas {
+				if q.Error != "" {
+					fmt.Fprintf(w, "%s\t-\t-\t-\t%s\n", q.Path, q.Error)
+					continue
+				}
+				fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", q.Path, q.DailyLimit, q.Used, q.Remain)
+			}
+			w.Flush()
+		})
+		return nil
+	}
+
+	if !*yes {
+		fmt.Fprintf(os.Stderr, "reset the API call count of %s? WeChat allows 10 resets a month [y/N]: ", cfg.AppID)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return errors.New("aborted")
+		}
+	}
+	if err := p.ClearQuota(ctx); err != nil {
+		return err
+	}
+	recordCLIAudit(cfg, publisher.AuditEntry{Action: publisher.AuditQuotaCleared, Target: "account:" + cfg.AppID, Detail: "clear api quota"})
+	output(map[string]any{"cleared": true}, func() { fmt.Println("API call quota cleared") })
+	return nil
+}
+
+// previewPage 为本地预览页面，宽度与手机端公众号文章接近。
+const previewPage = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>%s</title>
+<style>body{max-width:677px;margin:0 auto;padding:20px 16px;font-family:-apple-system,BlinkMacSystemFont,"PingFang SC","Microsoft YaHei",sans-serif;color:#333}h1.rich_media_title{font-size:22px;line-height:1.4;margin-bottom:14px}img{max-width:100%%}</style>
+</head>
+<body>
+<h1 class="rich_media_title">%s</h1>
+%s
+</body>
+</html>
+`
+
+// runPreview 在本地渲染发布时的 HTML，或把草稿发送到手机上预览。
+func runPreview(args []string) error {
+	if len(args) > 0 && args[0] == "send" {
+		return runPreviewSend(args[1:])
+	}
+	fs := newFlagSet("preview", "--md article.md [--out preview.html] | --media-id <id> (--to wxname | --openid id)",
+		"Render a markdown article to the HTML sent to WeChat (images stay local), or with\n--profile to HTML for pasting into Zhihu, Juejin or Knowledge Planet (zsxq); or send an\nexisting draft to a follower's phone for preview.")
+	configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+	mdPath := fs.String("md", "", "markdown file to render locally")
+	title := fs.String("title", "", "title shown above the rendered article (default file name)")
+	out := fs.String("out", "", "output html path, - for stdout (default next to the markdown file)")
+	allowHTML := fs.Bool("allow-html", false, "keep raw HTML in the markdown (same as config.allow_html)")
+	profile := fs.String("profile", "", "render for another platform instead of WeChat: zhihu, juejin or zsxq (local images are embedded)")
+	mediaID := fs.String("media-id", "", "draft media_id to send for preview")
+	to := fs.String("to", "", "WeChat ID of the receiver, who must follow the account")
+	openid := fs.String("openid", "", "openid of the receiver (used when --to is empty)")
+	fs.BoolVar(&verbose, "v", false, "enable info logs")
+	fs.Parse(args)
+
+	switch {
+	case *mdPath != "" && *mediaID != "":
+		return invalidf("--md and --media-id cannot be used together")
+	case *mdPath != "":
+		data, err := os.ReadFile(*mdPath)
+		if err != nil {
+			return err
+		}
+		if *title == "" {
+			*title = strings.TrimSuffix(filepath.Base(*mdPath), filepath.Ext(*mdPath))
+		}
+		var page string
+		if *profile != "" {
+			if !slices.Contains(publisher.CrossPostProfiles, *profile) {
+				return invalidf("--profile must be one of %s", strings.Join(publisher.CrossPostProfiles, ", "))
+			}
+			opts := publisher.CrossPostOptions{AllowHTML: *allowHTML, BaseDir: filepath.Dir(*mdPath)}
+			if page, err = publisher.CrossPostPage(*profile, *title, string(data), opts); err != nil {
+				return err
+			}
+		} else {
+			content, err := publisher.RenderHTML(string(data), *allowHTML)
+			if err != nil {
+				return err
+			}
+			page = fmt.Sprintf(previewPage, html.EscapeString(*title), html.EscapeString(*title), content)
+		}
+		if *out == "-" {
+			output(map[string]any{"html": page}, func() { fmt.Print(page) })
+			return nil
+		}
+		if *out == "" {
+			suffix := ".preview.html"
+			if *profile != "" {
+				suffix = "." + *profile + ".html"
+			}
+			*out = strings.TrimSuffix(*mdPath, filepath.Ext(*mdPath)) + suffix
+		}
+		if err := os.WriteFile(*out, []byte(page), 0o644); err != nil {
+			return err
+		}
+		output(map[string]any{"path": *out}, func() { fmt.Println(*out) })
+		return nil
+	case *mediaID != "":
+		if *to == "" && *openid == "" {
+			return invalidf("--to or --openid is required with --media-id")
+		}
+		p, err := newPublisher(*configPath)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := p.SendPreview(ctx, *mediaID, *to, *openid); err != nil {
+			return err
+		}
+		output(map[string]any{"media_id": *mediaID, "to": *to, "openid": *openid, "sent": true}, func() { fmt.Printf("sent preview of %s\n", *mediaID) })
+		return nil
+	}
+	fs.Usage()
+	return invalidf("--md or --media-id is required")
+}
+
+// runPreviewSend 把草稿发送到指定微信号或 openid 的手机上预览，发布或群发前核对排版。
+func runPreviewSend(args []string) error {
+	fs := newFlagSet("preview send", "<media_id> (--to wxname | --openid id)",
+		"Send a draft to a follower's phone to check the exact rendering before publishing.\n"+
+			"The receiver must follow the account; --to takes a WeChat ID, --openid an openid.")
+	configPath := fs.String("config", "config/config.json", "path to config file (.json, .yaml or .toml)")
+	to := fs.String("to", "", "WeChat ID of the receiver")
+	openid := fs.String("openid", "", "openid of the receiver (used when --to is empty)")
+	fs.BoolVar(&verbose, "v", false, "enable info logs")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		fs.Usage()
+		return invalidf("exactly one media_id is required")
+	}
+	if *to == "" && *openid == "" {
+		return invalidf("--to or --openid is required")
+	}
+	p, err := newPublisher(*configPath)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := p.SendPreview(ctx, rest[0], *to, *openid); err != nil {
+		return err
+	}
+	receiver := *to
+	if receiver == "" {
+		receiver = *openid
+	}
+	output(map[string]any{"media_id": rest[0], "to": *to, "openid": *openid, "sent": true}, func() { fmt.Printf("sent preview of %s to %s\n", rest[0], receiver) })
+	return nil
+}